@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/forge"
+	"github.com/darkLord19/foglet/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	forgeKindFlag      string
+	forgeURLFlag       string
+	forgeWithTokenFlag bool
+	forgeJSONFlag      bool
+)
+
+var forgeCmd = &cobra.Command{
+	Use:   "forge",
+	Short: "Configure GitLab, Gitea and GitHub Enterprise hosts",
+	Long: `Configure the code hosts Fog imports repositories from and opens pull
+requests on. github.com needs no configuration. gitlab.com, gitea.com and
+codeberg.org are recognised by name; self-hosted forges must be added.
+
+GitLab hosts use the glab CLI and its login (glab auth login --hostname).
+Gitea hosts use an access token, read from stdin with --with-token.`,
+}
+
+var forgeAddCmd = &cobra.Command{
+	Use:   "add <host>",
+	Short: "Add or update a forge host",
+	Long: `Add or update a forge host. The token is stored encrypted.
+
+Example:
+  fog forge add gitlab.acme.dev --kind gitlab
+  fog forge add git.acme.dev --kind gitea --url https://git.acme.dev:3000 --with-token < token.txt`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runForgeAdd(args[0], os.Stdin); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var forgeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configured forge hosts",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runForgeList(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var forgeRemoveCmd = &cobra.Command{
+	Use:   "rm <host>",
+	Short: "Remove a forge host and its token",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runForgeRemove(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	forgeAddCmd.Flags().StringVar(&forgeKindFlag, "kind", "", "Forge kind: github, gitlab or gitea (required)")
+	forgeAddCmd.Flags().StringVar(&forgeURLFlag, "url", "", "Web root of the forge when it is not https://<host>")
+	forgeAddCmd.Flags().BoolVar(&forgeWithTokenFlag, "with-token", false, "Read an access token from stdin")
+	_ = forgeAddCmd.MarkFlagRequired("kind")
+	forgeListCmd.Flags().BoolVar(&forgeJSONFlag, "json", false, "Output JSON")
+
+	forgeCmd.AddCommand(forgeAddCmd)
+	forgeCmd.AddCommand(forgeListCmd)
+	forgeCmd.AddCommand(forgeRemoveCmd)
+	rootCmd.AddCommand(forgeCmd)
+}
+
+func runForgeAdd(host string, stdin io.Reader) error {
+	kind := strings.ToLower(strings.TrimSpace(forgeKindFlag))
+	if !forge.ValidKind(kind) {
+		return fmt.Errorf("invalid forge kind %q (use github, gitlab or gitea)", forgeKindFlag)
+	}
+	token := ""
+	if forgeWithTokenFlag {
+		var err error
+		if token, err = readToken(stdin); err != nil {
+			return err
+		}
+	}
+
+	store, err := openStateStore()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	if kind == forge.KindGitea && token == "" {
+		if _, found, err := store.GetForgeToken(host); err != nil || !found {
+			return fmt.Errorf("gitea hosts need an access token: pipe it to --with-token")
+		}
+	}
+	if err := store.SetForgeHost(state.ForgeHost{Host: host, Kind: kind, BaseURL: forgeURLFlag}, token); err != nil {
+		return err
+	}
+	fmt.Printf("Configured %s as %s\n", strings.ToLower(strings.TrimSpace(host)), kind)
+	return nil
+}
+
+// readToken reads one line from stdin.
+func readToken(stdin io.Reader) (string, error) {
+	line, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("read token from stdin: %w", err)
+	}
+	token := strings.TrimSpace(line)
+	if token == "" {
+		return "", fmt.Errorf("no token on stdin")
+	}
+	return token, nil
+}
+
+func runForgeList() error {
+	store, err := openStateStore()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	hosts, err := store.ListForgeHosts()
+	if err != nil {
+		return err
+	}
+
+	if forgeJSONFlag {
+		data, err := json.MarshalIndent(hosts, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(hosts) == 0 {
+		fmt.Println("No forge hosts configured")
+		return nil
+	}
+	fmt.Printf("%-30s %-8s %-6s %s\n", "HOST", "KIND", "TOKEN", "URL")
+	fmt.Println(strings.Repeat("-", 80))
+	for _, h := range hosts {
+		fmt.Printf("%-30s %-8s %-6t %s\n", h.Host, h.Kind, h.HasToken, h.BaseURL)
+	}
+	return nil
+}
+
+func runForgeRemove(host string) error {
+	store, err := openStateStore()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	if err := store.DeleteForgeHost(host); err != nil {
+		return err
+	}
+	fmt.Printf("Removed %s\n", strings.ToLower(strings.TrimSpace(host)))
+	return nil
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadToken(t *testing.T) {
+	token, err := readToken(strings.NewReader("  tok-123\n"))
+	if err != nil || token != "tok-123" {
+		t.Fatalf("unexpected token %q err=%v", token, err)
+	}
+	if _, err := readToken(strings.NewReader("\n")); err == nil {
+		t.Fatal("expected error for empty stdin")
+	}
+}
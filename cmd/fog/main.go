@@ -32,6 +32,8 @@ var (
 	flagAsync       bool
 	flagJSON        bool
 	flagPRTitle     string
+	flagTemplate    string
+	flagVars        []string
 )
 
 func main() {
@@ -59,7 +61,10 @@ Example:
     --tool claude \
     --prompt "Add OTP login using Redis" \
     --commit \
-    --pr`,
+    --pr
+
+  fog run --repo owner/repo --branch bump-cobra \
+    --template bump-dep --var dep=cobra`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := runTask(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -104,7 +109,9 @@ func init() {
 	runCmd.Flags().StringVar(&flagBranch, "branch", "", "Branch name (required)")
 	runCmd.Flags().StringVar(&flagRepo, "repo", "", "Target repository (owner/repo; imported automatically when missing)")
 	runCmd.Flags().StringVar(&flagTool, "tool", "", "AI tool to use (cursor, claude, gemini, aider)")
-	runCmd.Flags().StringVar(&flagPrompt, "prompt", "", "Task prompt (required)")
+	runCmd.Flags().StringVar(&flagPrompt, "prompt", "", "Task prompt (required unless --template is set)")
+	runCmd.Flags().StringVar(&flagTemplate, "template", "", "Render the prompt from a stored template (see fog templates)")
+	runCmd.Flags().StringArrayVar(&flagVars, "var", nil, "Template variable as name=value (repeatable)")
 	runCmd.Flags().BoolVar(&flagCommit, "commit", false, "Commit changes after AI completes")
 	runCmd.Flags().BoolVar(&flagPR, "pr", false, "Create pull request")
 	runCmd.Flags().StringVar(&flagPRTitle, "pr-title", "", "Pull request title (requires --pr)")
@@ -115,7 +122,6 @@ func init() {
 	runCmd.Flags().BoolVar(&flagAsync, "async", false, "Run asynchronously")
 
 	runCmd.MarkFlagRequired("branch")
-	runCmd.MarkFlagRequired("prompt")
 
 	// list command flags
 	listCmd.Flags().BoolVar(&flagJSON, "json", false, "Output as JSON")
@@ -140,6 +146,11 @@ func runTask() error {
 	}
 	defer func() { _ = stateStore.Close() }()
 
+	prompt, err := resolveRunPrompt(stateStore, flagPrompt, flagTemplate, flagVars)
+	if err != nil {
+		return err
+	}
+
 	resolvedTool, err := toolcfg.ResolveTool(flagTool, stateStore, "cli")
 	if err != nil {
 		return err
@@ -170,7 +181,7 @@ func runTask() error {
 		ID:        uuid.New().String(),
 		State:     task.StateCreated,
 		Branch:    flagBranch,
-		Prompt:    flagPrompt,
+		Prompt:    prompt,
 		AITool:    resolvedTool,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -185,6 +196,9 @@ func runTask() error {
 			PRTitle:     flagPRTitle,
 		},
 	}
+	if strings.TrimSpace(flagTemplate) != "" {
+		t.Metadata = map[string]any{"template": strings.TrimSpace(flagTemplate)}
+	}
 
 	fmt.Printf("Starting task %s\n", t.ID)
 	fmt.Printf("Branch: %s\n", t.Branch)
@@ -209,6 +223,26 @@ func runTask() error {
 	return nil
 }
 
+// resolveRunPrompt returns the task prompt: the rendered template when one
+// is named (with any --prompt appended), otherwise --prompt itself.
+func resolveRunPrompt(store *state.Store, prompt, template string, vars []string) (string, error) {
+	prompt = strings.TrimSpace(prompt)
+	if strings.TrimSpace(template) == "" {
+		if len(vars) > 0 {
+			return "", fmt.Errorf("--var requires --template")
+		}
+		if prompt == "" {
+			return "", fmt.Errorf("--prompt or --template is required")
+		}
+		return prompt, nil
+	}
+	values, err := parseTemplateVars(vars)
+	if err != nil {
+		return "", err
+	}
+	return store.RenderTemplate(template, values, prompt)
+}
+
 func listTasks() error {
 	fogHome, err := env.FogHome()
 	if err != nil {
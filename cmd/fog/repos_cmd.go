@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -19,9 +20,13 @@ import (
 var (
 	reposJSONFlag   bool
 	reposSelectFlag string
+	reposHostFlag   string
 	gitRunner       = runGitCommand
 )
 
+// cloneRepoFunc makes a bare clone of repo at destPath.
+type cloneRepoFunc func(repo ghcli.Repo, destPath string) error
+
 var reposCmd = &cobra.Command{
 	Use:   "repos",
 	Short: "Manage Fog repositories",
@@ -29,7 +34,7 @@ var reposCmd = &cobra.Command{
 
 var reposDiscoverCmd = &cobra.Command{
 	Use:   "discover",
-	Short: "List repositories accessible by GitHub CLI",
+	Short: "List repositories accessible on GitHub or another forge",
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := runReposDiscover(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -40,7 +45,10 @@ var reposDiscoverCmd = &cobra.Command{
 
 var reposImportCmd = &cobra.Command{
 	Use:   "import",
-	Short: "Select and register repositories from GitHub using gh CLI",
+	Short: "Select and register repositories from GitHub or another forge",
+	Long: `Select and register repositories. GitHub repos are cloned with gh;
+pass --host to import from a GitLab or Gitea host configured with
+` + "`fog forge add`" + `.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := runReposImport(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -62,7 +70,10 @@ var reposListCmd = &cobra.Command{
 
 func init() {
 	reposDiscoverCmd.Flags().BoolVar(&reposJSONFlag, "json", false, "Output JSON")
-	reposImportCmd.Flags().StringVar(&reposSelectFlag, "select", "", "Comma-separated GitHub full names to import (e.g. org/repo,org/repo2)")
+	reposImportCmd.Flags().StringVar(&reposSelectFlag, "select", "", "Comma-separated full names to import (e.g. org/repo,org/repo2)")
+	for _, cmd := range []*cobra.Command{reposDiscoverCmd, reposImportCmd} {
+		cmd.Flags().StringVar(&reposHostFlag, "host", "", "Forge host to use instead of github.com (see `fog forge add`)")
+	}
 
 	reposCmd.AddCommand(reposDiscoverCmd)
 	reposCmd.AddCommand(reposImportCmd)
@@ -71,7 +82,7 @@ func init() {
 }
 
 func runReposDiscover() error {
-	repos, err := discoverGitHubRepos()
+	repos, _, err := discoverRepos(context.Background(), reposHostFlag)
 	if err != nil {
 		return err
 	}
@@ -86,7 +97,7 @@ func runReposDiscover() error {
 	}
 
 	if len(repos) == 0 {
-		fmt.Println("No accessible repositories found via gh CLI")
+		fmt.Printf("No accessible repositories found on %s\n", forgeHostLabel(reposHostFlag))
 		return nil
 	}
 
@@ -100,7 +111,7 @@ func runReposDiscover() error {
 }
 
 func runReposImport() error {
-	repos, err := discoverGitHubRepos()
+	repos, clone, err := discoverRepos(context.Background(), reposHostFlag)
 	if err != nil {
 		return err
 	}
@@ -148,7 +159,7 @@ func runReposImport() error {
 		barePath := filepath.Join(repoDir, "repo.git")
 		basePath := filepath.Join(repoDir, "base")
 
-		if err := ensureBareRepoInitialized(repo, barePath, basePath); err != nil {
+		if err := ensureBareRepoInitialized(repo, barePath, basePath, clone); err != nil {
 			return err
 		}
 
@@ -201,6 +212,47 @@ func runReposList() error {
 	return nil
 }
 
+// discoverRepos lists the repos accessible on a forge host and returns how
+// to clone them. An empty host means github.com through gh.
+func discoverRepos(ctx context.Context, host string) ([]ghcli.Repo, cloneRepoFunc, error) {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if host == "" || host == "github.com" {
+		repos, err := discoverGitHubRepos()
+		return repos, cloneWithGh, err
+	}
+	r, closeStore, err := newStateRunner()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer closeStore()
+	f, err := r.ForgeForHost(host)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := f.Check(ctx); err != nil {
+		return nil, nil, err
+	}
+	repos, err := f.DiscoverRepos(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	clone := func(repo ghcli.Repo, destPath string) error {
+		return f.CloneRepo(ctx, repo, destPath)
+	}
+	return repos, clone, nil
+}
+
+func forgeHostLabel(host string) string {
+	if strings.TrimSpace(host) == "" {
+		return "github.com via gh CLI"
+	}
+	return strings.TrimSpace(host)
+}
+
+func cloneWithGh(repo ghcli.Repo, destPath string) error {
+	return cloneGhRepoFn(repo.NameWithOwner, destPath)
+}
+
 func discoverGitHubRepos() ([]ghcli.Repo, error) {
 	if !isGhAvailableFn() {
 		return nil, fmt.Errorf("gh CLI invalid or not found")
@@ -211,9 +263,9 @@ func discoverGitHubRepos() ([]ghcli.Repo, error) {
 	return discoverGhReposFn()
 }
 
-func ensureBareRepoInitialized(repo ghcli.Repo, barePath, basePath string) error {
+func ensureBareRepoInitialized(repo ghcli.Repo, barePath, basePath string, clone cloneRepoFunc) error {
 	if _, err := os.Stat(barePath); errorsIsNotExist(err) {
-		if err := cloneGhRepoFn(repo.NameWithOwner, barePath); err != nil {
+		if err := clone(repo, barePath); err != nil {
 			return fmt.Errorf("clone bare repository %s: %w", repo.NameWithOwner, err)
 		}
 	} else if err != nil {
@@ -339,13 +391,15 @@ func repoAlias(repo ghcli.Repo) string {
 	return repo.NameWithOwner
 }
 
+// repoHost returns the forge host of a repo URL, without any port so it
+// matches the host forges are configured under.
 func repoHost(cloneURL string) string {
 	u, err := url.Parse(cloneURL)
 	if err != nil {
 		return "github.com"
 	}
-	if u.Host == "" {
+	if u.Hostname() == "" {
 		return "github.com"
 	}
-	return u.Host
+	return strings.ToLower(u.Hostname())
 }
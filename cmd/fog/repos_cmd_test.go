@@ -90,7 +90,7 @@ func TestEnsureBareRepoInitialized(t *testing.T) {
 		return nil
 	}
 
-	if err := ensureBareRepoInitialized(repo, barePath, basePath); err != nil {
+	if err := ensureBareRepoInitialized(repo, barePath, basePath, cloneWithGh); err != nil {
 		t.Fatalf("ensureBareRepoInitialized failed: %v", err)
 	}
 
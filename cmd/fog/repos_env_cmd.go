@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	fogenv "github.com/darkLord19/foglet/internal/env"
+	"github.com/darkLord19/foglet/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reposEnvSecretFlag bool
+	reposEnvJSONFlag   bool
+)
+
+var reposEnvCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Manage environment variables injected into a repository's runs",
+}
+
+var reposEnvSetCmd = &cobra.Command{
+	Use:   "set <repo> NAME[=VALUE]",
+	Short: "Set an environment variable for a repository's runs",
+	Long: `Set an environment variable for a repository's setup, validate and AI
+tool processes. Values are stored encrypted. With --secret the value is also
+masked in run output and never shown again. When VALUE is omitted it is read
+from stdin, which keeps secrets out of shell history.
+
+Example:
+  fog repos env set acme/api DATABASE_URL=postgres://localhost/test
+  fog repos env set acme/api OPENAI_API_KEY --secret < key.txt`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runReposEnvSet(args[0], args[1], os.Stdin); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var reposEnvListCmd = &cobra.Command{
+	Use:   "list <repo>",
+	Short: "List a repository's environment variables",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runReposEnvList(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var reposEnvUnsetCmd = &cobra.Command{
+	Use:   "unset <repo> NAME",
+	Short: "Remove an environment variable from a repository",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runReposEnvUnset(args[0], args[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	reposEnvSetCmd.Flags().BoolVar(&reposEnvSecretFlag, "secret", false, "Mask the value in run output and listings")
+	reposEnvListCmd.Flags().BoolVar(&reposEnvJSONFlag, "json", false, "Output JSON")
+
+	reposEnvCmd.AddCommand(reposEnvSetCmd)
+	reposEnvCmd.AddCommand(reposEnvListCmd)
+	reposEnvCmd.AddCommand(reposEnvUnsetCmd)
+	reposCmd.AddCommand(reposEnvCmd)
+}
+
+func openRepoStore(repoName string) (*state.Store, error) {
+	fogHome, err := fogenv.FogHome()
+	if err != nil {
+		return nil, err
+	}
+	store, err := state.NewStore(fogHome)
+	if err != nil {
+		return nil, err
+	}
+	if _, found, err := store.GetRepoByName(repoName); err != nil || !found {
+		_ = store.Close()
+		if err == nil {
+			err = fmt.Errorf("repo %q not found", repoName)
+		}
+		return nil, err
+	}
+	return store, nil
+}
+
+// parseEnvAssignment splits NAME=VALUE; without "=" the value is read from
+// stdin (one line, trailing newline removed).
+func parseEnvAssignment(arg string, stdin io.Reader) (string, string, error) {
+	name, value, ok := strings.Cut(arg, "=")
+	name = strings.TrimSpace(name)
+	if err := state.ValidateEnvName(name); err != nil {
+		return "", "", err
+	}
+	if ok {
+		return name, value, nil
+	}
+	line, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", "", fmt.Errorf("read value from stdin: %w", err)
+	}
+	value = strings.TrimRight(line, "\r\n")
+	if value == "" {
+		return "", "", fmt.Errorf("no value for %s: use NAME=VALUE or pipe it on stdin", name)
+	}
+	return name, value, nil
+}
+
+func runReposEnvSet(repoName, arg string, stdin io.Reader) error {
+	name, value, err := parseEnvAssignment(arg, stdin)
+	if err != nil {
+		return err
+	}
+	store, err := openRepoStore(repoName)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	if err := store.SetRepoEnv(repoName, name, value, reposEnvSecretFlag); err != nil {
+		return err
+	}
+	fmt.Printf("Set %s for %s\n", name, repoName)
+	return nil
+}
+
+func runReposEnvList(repoName string) error {
+	store, err := openRepoStore(repoName)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	vars, err := store.ListRepoEnv(repoName)
+	if err != nil {
+		return err
+	}
+	for i := range vars {
+		if vars[i].Secret {
+			vars[i].Value = ""
+		}
+	}
+
+	if reposEnvJSONFlag {
+		data, err := json.MarshalIndent(vars, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(vars) == 0 {
+		fmt.Printf("No environment variables for %s\n", repoName)
+		return nil
+	}
+	fmt.Printf("%-32s %s\n", "NAME", "VALUE")
+	fmt.Println(strings.Repeat("-", 70))
+	for _, v := range vars {
+		value := v.Value
+		if v.Secret {
+			value = "(secret)"
+		}
+		fmt.Printf("%-32s %s\n", v.Name, value)
+	}
+	return nil
+}
+
+func runReposEnvUnset(repoName, name string) error {
+	store, err := openRepoStore(repoName)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	if err := store.UnsetRepoEnv(repoName, name); err != nil {
+		return err
+	}
+	fmt.Printf("Unset %s for %s\n", name, repoName)
+	return nil
+}
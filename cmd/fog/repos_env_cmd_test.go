@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseEnvAssignment(t *testing.T) {
+	name, value, err := parseEnvAssignment("DATABASE_URL=postgres://h/db?a=b", strings.NewReader(""))
+	if err != nil || name != "DATABASE_URL" || value != "postgres://h/db?a=b" {
+		t.Fatalf("unexpected inline parse: %q %q %v", name, value, err)
+	}
+
+	name, value, err = parseEnvAssignment("API_KEY", strings.NewReader("sk-from-stdin\n"))
+	if err != nil || name != "API_KEY" || value != "sk-from-stdin" {
+		t.Fatalf("unexpected stdin parse: %q %q %v", name, value, err)
+	}
+
+	if _, _, err := parseEnvAssignment("API_KEY", strings.NewReader("")); err == nil {
+		t.Fatal("expected error for missing value")
+	}
+	if _, _, err := parseEnvAssignment("BAD-NAME=x", strings.NewReader("")); err == nil {
+		t.Fatal("expected error for invalid name")
+	}
+}
@@ -125,7 +125,7 @@ func ensureRepoRegisteredForRun(repoName string, store *state.Store, fogHome str
 	barePath := filepath.Join(repoDir, "repo.git")
 	basePath := filepath.Join(repoDir, "base")
 
-	if err := ensureBareRepoInitialized(match, barePath, basePath); err != nil {
+	if err := ensureBareRepoInitialized(match, barePath, basePath, cloneWithGh); err != nil {
 		return state.Repo{}, err
 	}
 
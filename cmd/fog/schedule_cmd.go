@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scheduleRepoFlag     string
+	scheduleCronFlag     string
+	schedulePromptFlag   string
+	scheduleTemplateFlag string
+	scheduleVarsFlag     []string
+	scheduleToolFlag     string
+	scheduleModelFlag    string
+	scheduleAutoPRFlag   bool
+	scheduleDisabledFlag bool
+	scheduleJSONFlag     bool
+	scheduleLimitFlag    int
+)
+
+var scheduleCmd = &cobra.Command{
+	Use:     "schedule",
+	Aliases: []string{"schedules"},
+	Short:   "Manage recurring sessions run by fogd",
+}
+
+var scheduleAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Create a recurring session",
+	Long: `Create a recurring session. fogd starts a new session on a fresh branch
+each time the cron expression matches (daemon local time). An activation is
+skipped while the session from the previous one is still running.
+
+Example:
+  fog schedule add nightly-deps --repo acme/api --cron "0 3 * * 1-5" \
+    --prompt "Update minor dependency versions and fix any breakage" --autopr
+  fog schedule add weekly-bump --repo acme/api --cron @weekly \
+    --template bump-dep --var dep=cobra`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runScheduleAdd(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var scheduleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List schedules",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runScheduleList(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var scheduleRmCmd = &cobra.Command{
+	Use:     "rm <id|name>",
+	Aliases: []string{"delete"},
+	Short:   "Delete a schedule and its history",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runScheduleRm(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var scheduleEnableCmd = &cobra.Command{
+	Use:   "enable <id|name>",
+	Short: "Resume a schedule",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runScheduleSetEnabled(args[0], true); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var scheduleDisableCmd = &cobra.Command{
+	Use:   "disable <id|name>",
+	Short: "Pause a schedule",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runScheduleSetEnabled(args[0], false); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var scheduleHistoryCmd = &cobra.Command{
+	Use:   "history <id|name>",
+	Short: "Show past activations and the sessions they started",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runScheduleHistory(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	scheduleAddCmd.Flags().StringVar(&scheduleRepoFlag, "repo", "", "Managed repository (owner/repo)")
+	scheduleAddCmd.Flags().StringVar(&scheduleCronFlag, "cron", "", "Cron expression, e.g. \"0 9 * * 1-5\" or @daily")
+	scheduleAddCmd.Flags().StringVar(&schedulePromptFlag, "prompt", "", "Task prompt (required unless --template is set)")
+	scheduleAddCmd.Flags().StringVar(&scheduleTemplateFlag, "template", "", "Render the prompt from a stored template on each run")
+	scheduleAddCmd.Flags().StringArrayVar(&scheduleVarsFlag, "var", nil, "Template variable as name=value (repeatable)")
+	scheduleAddCmd.Flags().StringVar(&scheduleToolFlag, "tool", "", "AI tool to use (defaults to the configured default)")
+	scheduleAddCmd.Flags().StringVar(&scheduleModelFlag, "model", "", "Model for the AI tool")
+	scheduleAddCmd.Flags().BoolVar(&scheduleAutoPRFlag, "autopr", false, "Open a draft PR for each run")
+	scheduleAddCmd.Flags().BoolVar(&scheduleDisabledFlag, "disabled", false, "Create the schedule paused")
+	_ = scheduleAddCmd.MarkFlagRequired("repo")
+	_ = scheduleAddCmd.MarkFlagRequired("cron")
+
+	scheduleListCmd.Flags().BoolVar(&scheduleJSONFlag, "json", false, "Output JSON")
+	scheduleHistoryCmd.Flags().BoolVar(&scheduleJSONFlag, "json", false, "Output JSON")
+	scheduleHistoryCmd.Flags().IntVar(&scheduleLimitFlag, "limit", 20, "Number of activations to show")
+
+	scheduleCmd.AddCommand(scheduleAddCmd)
+	scheduleCmd.AddCommand(scheduleListCmd)
+	scheduleCmd.AddCommand(scheduleRmCmd)
+	scheduleCmd.AddCommand(scheduleEnableCmd)
+	scheduleCmd.AddCommand(scheduleDisableCmd)
+	scheduleCmd.AddCommand(scheduleHistoryCmd)
+	rootCmd.AddCommand(scheduleCmd)
+}
+
+func runScheduleAdd(name string) error {
+	vars, err := parseTemplateVars(scheduleVarsFlag)
+	if err != nil {
+		return err
+	}
+	store, err := openRepoStore(strings.TrimSpace(scheduleRepoFlag))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	schedule, err := store.CreateSchedule(state.Schedule{
+		ID:       uuid.New().String(),
+		Name:     name,
+		RepoName: strings.TrimSpace(scheduleRepoFlag),
+		Cron:     scheduleCronFlag,
+		Prompt:   schedulePromptFlag,
+		Template: scheduleTemplateFlag,
+		Vars:     vars,
+		Tool:     scheduleToolFlag,
+		Model:    scheduleModelFlag,
+		AutoPR:   scheduleAutoPRFlag,
+		Enabled:  !scheduleDisabledFlag,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created schedule %s (%s)\n", schedule.Name, schedule.ID)
+	if schedule.NextRunAt != nil {
+		fmt.Printf("Next run: %s\n", schedule.NextRunAt.Local().Format("2006-01-02 15:04 MST"))
+	}
+	return nil
+}
+
+func runScheduleList() error {
+	store, err := openStateStore()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	schedules, err := store.ListSchedules()
+	if err != nil {
+		return err
+	}
+	if scheduleJSONFlag {
+		data, err := json.MarshalIndent(schedules, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	if len(schedules) == 0 {
+		fmt.Println("No schedules")
+		return nil
+	}
+	fmt.Printf("%-20s %-24s %-16s %-18s %s\n", "NAME", "REPO", "CRON", "NEXT RUN", "LAST RUN")
+	fmt.Println(strings.Repeat("-", 100))
+	for _, schedule := range schedules {
+		next := "paused"
+		if schedule.Enabled {
+			next = formatScheduleTime(schedule.NextRunAt)
+		}
+		fmt.Printf("%-20s %-24s %-16s %-18s %s\n",
+			schedule.Name,
+			schedule.RepoName,
+			schedule.Cron,
+			next,
+			formatScheduleTime(schedule.LastRunAt))
+	}
+	return nil
+}
+
+func runScheduleRm(idOrName string) error {
+	store, err := openStateStore()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	schedule, err := lookupSchedule(store, idOrName)
+	if err != nil {
+		return err
+	}
+	if err := store.DeleteSchedule(schedule.ID); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted schedule %s\n", schedule.Name)
+	return nil
+}
+
+func runScheduleSetEnabled(idOrName string, enabled bool) error {
+	store, err := openStateStore()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	schedule, err := lookupSchedule(store, idOrName)
+	if err != nil {
+		return err
+	}
+	schedule, err = store.SetScheduleEnabled(schedule.ID, enabled)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		fmt.Printf("Paused schedule %s\n", schedule.Name)
+		return nil
+	}
+	fmt.Printf("Resumed schedule %s (next run: %s)\n", schedule.Name, formatScheduleTime(schedule.NextRunAt))
+	return nil
+}
+
+func runScheduleHistory(idOrName string) error {
+	store, err := openStateStore()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	schedule, err := lookupSchedule(store, idOrName)
+	if err != nil {
+		return err
+	}
+	runs, err := store.ListScheduleRuns(schedule.ID, scheduleLimitFlag)
+	if err != nil {
+		return err
+	}
+	if scheduleJSONFlag {
+		data, err := json.MarshalIndent(runs, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	if len(runs) == 0 {
+		fmt.Printf("Schedule %s has not run yet\n", schedule.Name)
+		return nil
+	}
+	fmt.Printf("%-18s %-8s %-36s %-12s %s\n", "FIRED", "STATUS", "SESSION", "RUN STATE", "ERROR")
+	fmt.Println(strings.Repeat("-", 100))
+	for _, run := range runs {
+		fmt.Printf("%-18s %-8s %-36s %-12s %s\n",
+			run.FiredAt.Local().Format("2006-01-02 15:04"),
+			run.Status,
+			run.SessionID,
+			run.RunState,
+			run.Error)
+	}
+	return nil
+}
+
+func lookupSchedule(store *state.Store, idOrName string) (state.Schedule, error) {
+	schedule, found, err := store.GetSchedule(idOrName)
+	if err != nil {
+		return state.Schedule{}, err
+	}
+	if !found {
+		return state.Schedule{}, fmt.Errorf("schedule %q not found", idOrName)
+	}
+	return schedule, nil
+}
+
+func formatScheduleTime(ts *time.Time) string {
+	if ts == nil {
+		return "-"
+	}
+	return ts.Local().Format("2006-01-02 15:04")
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	fogenv "github.com/darkLord19/foglet/internal/env"
+	"github.com/darkLord19/foglet/internal/runner"
+	"github.com/darkLord19/foglet/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	gcOlderThanFlag      string
+	gcMergedFlag         bool
+	gcClosedFlag         bool
+	gcMinSizeFlag        string
+	gcArchivedFlag       bool
+	gcRepoFlag           string
+	gcDeleteBranchesFlag bool
+	gcForceFlag          bool
+	gcDryRunFlag         bool
+	gcJSONFlag           bool
+)
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "Manage Fog sessions",
+}
+
+var sessionsGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Delete sessions and worktrees matching retention rules",
+	Long: `Delete idle sessions, their runs and their worktrees when they match
+any of the given retention rules. Busy sessions are always kept.
+
+Example:
+  fog sessions gc --older-than 30d --merged --dry-run
+  fog sessions gc --closed --min-size 2GB --delete-branches`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runSessionsGC(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	sessionsGCCmd.Flags().StringVar(&gcOlderThanFlag, "older-than", "", "Collect sessions inactive for longer than this (e.g. 72h, 30d)")
+	sessionsGCCmd.Flags().BoolVar(&gcMergedFlag, "merged", false, "Collect sessions whose pull request was merged")
+	sessionsGCCmd.Flags().BoolVar(&gcClosedFlag, "closed", false, "Collect sessions whose pull request was closed without merging")
+	sessionsGCCmd.Flags().StringVar(&gcMinSizeFlag, "min-size", "", "Collect sessions whose worktrees use at least this much disk (e.g. 500MB, 2GB)")
+	sessionsGCCmd.Flags().BoolVar(&gcArchivedFlag, "archived", false, "Collect archived sessions")
+	sessionsGCCmd.Flags().StringVar(&gcRepoFlag, "repo", "", "Only collect sessions of this repository")
+	sessionsGCCmd.Flags().BoolVar(&gcDeleteBranchesFlag, "delete-branches", false, "Also delete the sessions' local branches")
+	sessionsGCCmd.Flags().BoolVar(&gcForceFlag, "force", false, "Remove dirty worktrees and unmerged branches")
+	sessionsGCCmd.Flags().BoolVar(&gcDryRunFlag, "dry-run", false, "Only list what would be deleted")
+	sessionsGCCmd.Flags().BoolVar(&gcJSONFlag, "json", false, "Output JSON")
+
+	sessionsCmd.AddCommand(sessionsGCCmd)
+	rootCmd.AddCommand(sessionsCmd)
+}
+
+func runSessionsGC() error {
+	policy := runner.SessionGCPolicy{
+		MergedPR:       gcMergedFlag,
+		ClosedPR:       gcClosedFlag,
+		Archived:       gcArchivedFlag,
+		Repo:           gcRepoFlag,
+		DeleteBranches: gcDeleteBranchesFlag,
+		Force:          gcForceFlag,
+		DryRun:         gcDryRunFlag,
+	}
+	var err error
+	if policy.OlderThan, err = parseAge(gcOlderThanFlag); err != nil {
+		return err
+	}
+	if policy.MinSizeBytes, err = parseByteSize(gcMinSizeFlag); err != nil {
+		return err
+	}
+
+	fogHome, err := fogenv.FogHome()
+	if err != nil {
+		return err
+	}
+	store, err := state.NewStore(fogHome)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	r, err := runner.New("", fogHome)
+	if err != nil {
+		return err
+	}
+	r.SetStateStore(store)
+
+	results, err := r.CollectSessionGarbage(context.Background(), policy)
+	if err != nil {
+		return err
+	}
+
+	if gcJSONFlag {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No sessions matched")
+		return nil
+	}
+
+	verb := "Deleted"
+	if policy.DryRun {
+		verb = "Would delete"
+	}
+	var freed int64
+	failed := 0
+	fmt.Printf("%-36s %-30s %-10s %s\n", "SESSION", "BRANCH", "SIZE", "REASONS")
+	fmt.Println(strings.Repeat("-", 100))
+	for _, result := range results {
+		reasons := strings.Join(result.Reasons, ", ")
+		if result.Error != "" {
+			failed++
+			reasons += " (error: " + result.Error + ")"
+		} else {
+			freed += result.SizeBytes
+		}
+		fmt.Printf("%-36s %-30s %-10s %s\n", result.Session.ID, result.Session.Branch, formatByteSize(result.SizeBytes), reasons)
+	}
+	fmt.Printf("\n%s %d session(s), %s of worktrees\n", verb, len(results)-failed, formatByteSize(freed))
+	if failed > 0 {
+		return fmt.Errorf("%d session(s) could not be deleted", failed)
+	}
+	return nil
+}
+
+// parseAge parses a Go duration, additionally accepting whole days ("30d").
+func parseAge(raw string) (time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(raw, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid age %q", raw)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid age %q", raw)
+	}
+	return d, nil
+}
+
+var byteSizeUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// parseByteSize parses sizes like "512MB" or "2GB" (binary units).
+func parseByteSize(raw string) (int64, error) {
+	raw = strings.ToUpper(strings.TrimSpace(raw))
+	if raw == "" {
+		return 0, nil
+	}
+	number, multiplier := raw, int64(1)
+	for _, unit := range byteSizeUnits {
+		if trimmed, ok := strings.CutSuffix(raw, unit.suffix); ok {
+			number, multiplier = strings.TrimSpace(trimmed), unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", raw)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+func formatByteSize(n int64) string {
+	for _, unit := range byteSizeUnits {
+		if n >= unit.size && unit.size > 1 {
+			return fmt.Sprintf("%.1f%s", float64(n)/float64(unit.size), unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%dB", n)
+}
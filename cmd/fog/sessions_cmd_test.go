@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	cases := map[string]time.Duration{
+		"":    0,
+		"72h": 72 * time.Hour,
+		"30d": 30 * 24 * time.Hour,
+	}
+	for raw, want := range cases {
+		got, err := parseAge(raw)
+		if err != nil {
+			t.Fatalf("parseAge(%q) returned error: %v", raw, err)
+		}
+		if got != want {
+			t.Fatalf("parseAge(%q) mismatch: got %v want %v", raw, got, want)
+		}
+	}
+	for _, raw := range []string{"soon", "-1d", "0d", "-5h"} {
+		if _, err := parseAge(raw); err == nil {
+			t.Fatalf("expected parseAge(%q) to fail", raw)
+		}
+	}
+}
+
+func TestParseAndFormatByteSize(t *testing.T) {
+	cases := map[string]int64{
+		"":      0,
+		"512":   512,
+		"1kb":   1 << 10,
+		"500MB": 500 << 20,
+		"1.5GB": 3 << 29,
+	}
+	for raw, want := range cases {
+		got, err := parseByteSize(raw)
+		if err != nil {
+			t.Fatalf("parseByteSize(%q) returned error: %v", raw, err)
+		}
+		if got != want {
+			t.Fatalf("parseByteSize(%q) mismatch: got %d want %d", raw, got, want)
+		}
+	}
+	if _, err := parseByteSize("lots"); err == nil {
+		t.Fatal("expected invalid size to fail")
+	}
+	if got := formatByteSize(3 << 29); got != "1.5GB" {
+		t.Fatalf("formatByteSize mismatch: got %q", got)
+	}
+	if got := formatByteSize(12); got != "12B" {
+		t.Fatalf("formatByteSize mismatch: got %q", got)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	fogenv "github.com/darkLord19/foglet/internal/env"
+	"github.com/darkLord19/foglet/internal/runner"
+	"github.com/darkLord19/foglet/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	prBodyDryRunFlag bool
+	prMergeMethod    string
+	prJSONFlag       bool
+)
+
+var sessionsPRCmd = &cobra.Command{
+	Use:   "pr",
+	Short: "Manage a session's pull request",
+	Long: `Check, publish and merge the pull request of a session on its forge.
+
+Reviewers, labels and the default merge method come from the pr section of
+the repo's .fog.yaml.`,
+}
+
+var sessionsPRStatusCmd = &cobra.Command{
+	Use:   "status <session-id>",
+	Short: "Refresh and show the pull request status",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runSessionsPRAction(func(ctx context.Context, r *runner.Runner) (state.Session, error) {
+			return r.RefreshPRStatus(ctx, args[0])
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var sessionsPRReadyCmd = &cobra.Command{
+	Use:   "ready <session-id>",
+	Short: "Mark the pull request ready for review",
+	Long: `Take the session's draft pull request out of draft, then request the
+reviewers and add the labels listed under pr.reviewers and pr.labels in
+.fog.yaml.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runSessionsPRAction(func(ctx context.Context, r *runner.Runner) (state.Session, error) {
+			return r.MarkPRReady(ctx, args[0])
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var sessionsPRBodyCmd = &cobra.Command{
+	Use:   "body <session-id>",
+	Short: "Regenerate the pull request description",
+	Long: `Rebuild the pull request description from the prompts and commit messages
+of every run in the session, below the project's pr.template.
+
+Example:
+  fog sessions pr body 1f0c... --dry-run`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runSessionsPRBody(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var sessionsPRMergeCmd = &cobra.Command{
+	Use:   "merge <session-id>",
+	Short: "Merge the pull request once checks pass",
+	Long: `Merge the session's pull request. Fog refuses while the session has a run in
+progress, the PR is a draft or conflicting, or its checks are failing or
+pending. The method defaults to pr.merge_method in .fog.yaml, then squash.
+
+Example:
+  fog sessions pr merge 1f0c... --method rebase`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runSessionsPRAction(func(ctx context.Context, r *runner.Runner) (state.Session, error) {
+			return r.MergeSessionPR(ctx, args[0], prMergeMethod)
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	sessionsPRCmd.PersistentFlags().BoolVar(&prJSONFlag, "json", false, "Output JSON")
+	sessionsPRBodyCmd.Flags().BoolVar(&prBodyDryRunFlag, "dry-run", false, "Print the description without editing the PR")
+	sessionsPRMergeCmd.Flags().StringVar(&prMergeMethod, "method", "", "Merge method: squash, rebase or merge")
+
+	sessionsPRCmd.AddCommand(sessionsPRStatusCmd)
+	sessionsPRCmd.AddCommand(sessionsPRReadyCmd)
+	sessionsPRCmd.AddCommand(sessionsPRBodyCmd)
+	sessionsPRCmd.AddCommand(sessionsPRMergeCmd)
+	sessionsCmd.AddCommand(sessionsPRCmd)
+}
+
+// newStateRunner returns a runner backed by the Fog state store and a func
+// that closes the store.
+func newStateRunner() (*runner.Runner, func(), error) {
+	fogHome, err := fogenv.FogHome()
+	if err != nil {
+		return nil, nil, err
+	}
+	store, err := state.NewStore(fogHome)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := runner.New("", fogHome)
+	if err != nil {
+		_ = store.Close()
+		return nil, nil, err
+	}
+	r.SetStateStore(store)
+	return r, func() { _ = store.Close() }, nil
+}
+
+func runSessionsPRAction(action func(ctx context.Context, r *runner.Runner) (state.Session, error)) error {
+	r, closeStore, err := newStateRunner()
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	session, err := action(context.Background(), r)
+	if err != nil {
+		return err
+	}
+	if prJSONFlag {
+		data, err := json.MarshalIndent(session, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Printf("PR: %s\n", session.PRURL)
+	fmt.Printf("Session: %s (%s)\n", session.ID, session.Status)
+	if pr := session.PR; pr != nil {
+		prState := pr.State
+		if pr.Draft && pr.State == "OPEN" {
+			prState = "DRAFT"
+		}
+		fmt.Printf("State: %s\n", prState)
+		if pr.Mergeable != "" {
+			fmt.Printf("Mergeable: %s\n", pr.Mergeable)
+		}
+		if pr.ReviewDecision != "" {
+			fmt.Printf("Review: %s\n", pr.ReviewDecision)
+		}
+		if pr.Checks.State != "" {
+			fmt.Printf("Checks: %s (%d passed, %d failed, %d pending)\n",
+				pr.Checks.State, pr.Checks.Passed, pr.Checks.Failed, pr.Checks.Pending)
+		}
+	}
+	return nil
+}
+
+func runSessionsPRBody(sessionID string) error {
+	r, closeStore, err := newStateRunner()
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	body, err := r.UpdatePRBody(context.Background(), sessionID, prBodyDryRunFlag)
+	if err != nil {
+		return err
+	}
+	if prJSONFlag {
+		data, err := json.MarshalIndent(map[string]string{"session_id": strings.TrimSpace(sessionID), "body": body}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	if !prBodyDryRunFlag {
+		fmt.Println("Updated pull request description:")
+		fmt.Println()
+	}
+	fmt.Println(body)
+	return nil
+}
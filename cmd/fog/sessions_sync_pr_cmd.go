@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	fogenv "github.com/darkLord19/foglet/internal/env"
+	"github.com/darkLord19/foglet/internal/runner"
+	"github.com/darkLord19/foglet/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncPRDryRunFlag bool
+	syncPRJSONFlag   bool
+)
+
+var sessionsSyncPRCmd = &cobra.Command{
+	Use:   "sync-pr <session-id>",
+	Short: "Turn new PR review comments and failing checks into a follow-up run",
+	Long: `Fetch review comments, review summaries, conversation comments and failing
+check annotations for the session's pull request (via gh api) and send the ones
+not addressed yet to the session's tool as a follow-up run. Feedback handed to
+a run that fails or is interrupted is picked up again by the next sync, up to
+3 runs per item.
+
+Example:
+  fog sessions sync-pr 1f0c... --dry-run
+  fog sessions sync-pr 1f0c...`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runSessionsSyncPR(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	sessionsSyncPRCmd.Flags().BoolVar(&syncPRDryRunFlag, "dry-run", false, "Print the follow-up prompt without starting a run")
+	sessionsSyncPRCmd.Flags().BoolVar(&syncPRJSONFlag, "json", false, "Output JSON")
+
+	sessionsCmd.AddCommand(sessionsSyncPRCmd)
+}
+
+func runSessionsSyncPR(sessionID string) error {
+	fogHome, err := fogenv.FogHome()
+	if err != nil {
+		return err
+	}
+	store, err := state.NewStore(fogHome)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	r, err := runner.New("", fogHome)
+	if err != nil {
+		return err
+	}
+	r.SetStateStore(store)
+
+	result, runErr := r.SyncPRFeedback(context.Background(), sessionID, runner.PRSyncOptions{DryRun: syncPRDryRunFlag})
+	if runErr != nil && result.SessionID == "" {
+		return runErr
+	}
+
+	if syncPRJSONFlag {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return runErr
+	}
+
+	if result.Prompt == "" {
+		fmt.Printf("No new feedback on %s\n", result.PRURL)
+		return nil
+	}
+	fmt.Printf("%d new review comment(s), %d failing check(s) on %s\n", len(result.Comments), len(result.FailedChecks), result.PRURL)
+	if syncPRDryRunFlag {
+		fmt.Println()
+		fmt.Println(result.Prompt)
+		return nil
+	}
+	if result.Run != nil {
+		fmt.Printf("Run: %s\n", result.Run.ID)
+		fmt.Printf("State: %s\n", result.Run.State)
+		if result.Run.CommitSHA != "" {
+			fmt.Printf("Commit: %s\n", result.Run.CommitSHA)
+		}
+	}
+	return runErr
+}
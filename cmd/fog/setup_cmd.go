@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
 	"slices"
 	"strings"
 
@@ -30,7 +31,7 @@ var setupCmd = &cobra.Command{
 }
 
 func init() {
-	setupCmd.Flags().StringVar(&setupDefaultToolFlag, "default-tool", "", "Default AI tool (cursor, claude, gemini, aider)")
+	setupCmd.Flags().StringVar(&setupDefaultToolFlag, "default-tool", "", "Default AI tool (cursor, claude, gemini, aider, or a custom tool)")
 	rootCmd.AddCommand(setupCmd)
 }
 
@@ -55,9 +56,13 @@ func runSetup() error {
 	}
 	fmt.Println("GitHub CLI is installed and authenticated.")
 
+	if err := checkCustomTools(fogHome); err != nil {
+		return err
+	}
+
 	available := availableTools()
 	if len(available) == 0 {
-		return fmt.Errorf("no supported AI tools found in PATH (expected cursor, claude, gemini, or aider)")
+		return fmt.Errorf("no supported AI tools found in PATH (expected cursor, claude, gemini, aider, or a custom tool in %s)", filepath.Join(fogHome, ai.CustomToolsDir))
 	}
 
 	defaultTool, err := chooseDefaultTool(available, setupDefaultToolFlag)
@@ -74,6 +79,23 @@ func runSetup() error {
 	return nil
 }
 
+// checkCustomTools validates the custom adapter definitions under FOG_HOME
+// and reports whether each one's binary is installed.
+func checkCustomTools(fogHome string) error {
+	defs, err := ai.ReloadCustomTools()
+	if err != nil {
+		return fmt.Errorf("invalid custom tool definitions in %s: %w", filepath.Join(fogHome, ai.CustomToolsDir), err)
+	}
+	for _, def := range defs {
+		status := "binary not found"
+		if tool, err := ai.GetTool(def.Name); err == nil && tool.IsAvailable() {
+			status = "available"
+		}
+		fmt.Printf("Custom tool %s (%s): %s\n", def.Name, def.Command, status)
+	}
+	return nil
+}
+
 func availableTools() []string {
 	names := ai.AvailableToolNames()
 	out := make([]string, 0, len(names))
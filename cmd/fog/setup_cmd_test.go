@@ -1,6 +1,13 @@
 package main
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/ai"
+)
 
 func TestChooseDefaultToolRequested(t *testing.T) {
 	tool, err := chooseDefaultTool([]string{"cursor", "claude"}, "claude")
@@ -28,3 +35,30 @@ func TestChooseDefaultToolSingleOption(t *testing.T) {
 		t.Fatalf("tool mismatch: got %q want %q", tool, "aider")
 	}
 }
+
+func TestCheckCustomToolsRejectsInvalidDefinitions(t *testing.T) {
+	fogHome := t.TempDir()
+	toolsDir := filepath.Join(fogHome, ai.CustomToolsDir)
+	if err := os.MkdirAll(toolsDir, 0o755); err != nil {
+		t.Fatalf("mkdir tools dir failed: %v", err)
+	}
+	t.Setenv("FOG_HOME", fogHome)
+	t.Cleanup(func() { _, _ = ai.ReloadCustomTools() })
+
+	valid := `{"name":"opencode","command":"opencode","args":["run","{{prompt}}"]}`
+	if err := os.WriteFile(filepath.Join(toolsDir, "opencode.json"), []byte(valid), 0o644); err != nil {
+		t.Fatalf("write definition failed: %v", err)
+	}
+	if err := checkCustomTools(fogHome); err != nil {
+		t.Fatalf("expected valid definitions to pass: %v", err)
+	}
+
+	invalid := `{"name":"goose","command":"goose","args":["run"]}`
+	if err := os.WriteFile(filepath.Join(toolsDir, "goose.json"), []byte(invalid), 0o644); err != nil {
+		t.Fatalf("write definition failed: %v", err)
+	}
+	err := checkCustomTools(fogHome)
+	if err == nil || !strings.Contains(err.Error(), "goose.json") {
+		t.Fatalf("expected error naming goose.json, got %v", err)
+	}
+}
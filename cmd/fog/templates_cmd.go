@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	fogenv "github.com/darkLord19/foglet/internal/env"
+	"github.com/darkLord19/foglet/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	templatesDescriptionFlag string
+	templatesBodyFlag        string
+	templatesFileFlag        string
+	templatesJSONFlag        bool
+)
+
+var templatesCmd = &cobra.Command{
+	Use:     "templates",
+	Aliases: []string{"template"},
+	Short:   "Manage reusable prompt templates",
+}
+
+var templatesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List prompt templates",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runTemplatesList(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var templatesShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Print a prompt template",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runTemplatesShow(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var templatesSetCmd = &cobra.Command{
+	Use:     "set <name>",
+	Aliases: []string{"add"},
+	Short:   "Create or replace a prompt template",
+	Long: `Create or replace a prompt template. Placeholders are written {{name}}
+or {{name|default}} and filled with --var name=value when the template is used.
+The body comes from --body, --file, or stdin.
+
+Example:
+  fog templates set bump-dep --description "Bump a dependency" \
+    --body "Bump {{dep}} to {{version|the latest release}} and fix any breakage."
+  fog run --repo acme/api --branch bump-cobra --template bump-dep --var dep=cobra`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runTemplatesSet(args[0], os.Stdin); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var templatesRmCmd = &cobra.Command{
+	Use:     "rm <name>",
+	Aliases: []string{"delete"},
+	Short:   "Delete a prompt template",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runTemplatesRm(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	templatesListCmd.Flags().BoolVar(&templatesJSONFlag, "json", false, "Output JSON")
+	templatesSetCmd.Flags().StringVar(&templatesDescriptionFlag, "description", "", "Short description")
+	templatesSetCmd.Flags().StringVar(&templatesBodyFlag, "body", "", "Template body")
+	templatesSetCmd.Flags().StringVar(&templatesFileFlag, "file", "", "Read the template body from a file")
+
+	templatesCmd.AddCommand(templatesListCmd)
+	templatesCmd.AddCommand(templatesShowCmd)
+	templatesCmd.AddCommand(templatesSetCmd)
+	templatesCmd.AddCommand(templatesRmCmd)
+	rootCmd.AddCommand(templatesCmd)
+}
+
+func openStateStore() (*state.Store, error) {
+	fogHome, err := fogenv.FogHome()
+	if err != nil {
+		return nil, err
+	}
+	return state.NewStore(fogHome)
+}
+
+// parseTemplateVars turns repeated name=value flags into a map.
+func parseTemplateVars(pairs []string) (map[string]string, error) {
+	vars := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --var %q: use name=value", pair)
+		}
+		if _, dup := vars[name]; dup {
+			return nil, fmt.Errorf("duplicate --var %q", name)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+func templateBody(stdin io.Reader) (string, error) {
+	switch {
+	case templatesBodyFlag != "" && templatesFileFlag != "":
+		return "", fmt.Errorf("use either --body or --file, not both")
+	case templatesBodyFlag != "":
+		return templatesBodyFlag, nil
+	case templatesFileFlag != "":
+		data, err := os.ReadFile(templatesFileFlag)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return "", fmt.Errorf("read template from stdin: %w", err)
+	}
+	return string(data), nil
+}
+
+func runTemplatesList() error {
+	store, err := openStateStore()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	templates, err := store.ListTemplates()
+	if err != nil {
+		return err
+	}
+	if templatesJSONFlag {
+		data, err := json.MarshalIndent(templates, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	if len(templates) == 0 {
+		fmt.Println("No templates")
+		return nil
+	}
+	fmt.Printf("%-24s %-28s %s\n", "NAME", "VARS", "DESCRIPTION")
+	fmt.Println(strings.Repeat("-", 80))
+	for _, tmpl := range templates {
+		fmt.Printf("%-24s %-28s %s\n", tmpl.Name, strings.Join(tmpl.Vars, ","), tmpl.Description)
+	}
+	return nil
+}
+
+func runTemplatesShow(name string) error {
+	store, err := openStateStore()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	tmpl, found, err := store.GetTemplate(name)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("template %q not found", name)
+	}
+	if tmpl.Description != "" {
+		fmt.Printf("# %s\n\n", tmpl.Description)
+	}
+	fmt.Println(tmpl.Body)
+	return nil
+}
+
+func runTemplatesSet(name string, stdin io.Reader) error {
+	body, err := templateBody(stdin)
+	if err != nil {
+		return err
+	}
+	store, err := openStateStore()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	tmpl, err := store.SaveTemplate(name, templatesDescriptionFlag, body)
+	if err != nil {
+		return err
+	}
+	if len(tmpl.Vars) == 0 {
+		fmt.Printf("Saved template %s\n", tmpl.Name)
+		return nil
+	}
+	fmt.Printf("Saved template %s (vars: %s)\n", tmpl.Name, strings.Join(tmpl.Vars, ", "))
+	return nil
+}
+
+func runTemplatesRm(name string) error {
+	store, err := openStateStore()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	if err := store.DeleteTemplate(name); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted template %s\n", name)
+	return nil
+}
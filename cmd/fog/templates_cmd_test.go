@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestParseTemplateVars(t *testing.T) {
+	vars, err := parseTemplateVars([]string{"dep=cobra", "query=a=b", "empty="})
+	if err != nil {
+		t.Fatalf("parse vars failed: %v", err)
+	}
+	if vars["dep"] != "cobra" || vars["query"] != "a=b" || vars["empty"] != "" || len(vars) != 3 {
+		t.Fatalf("unexpected vars: %v", vars)
+	}
+
+	for _, bad := range [][]string{{"novalue"}, {"=x"}, {"a=1", "a=2"}} {
+		if _, err := parseTemplateVars(bad); err == nil {
+			t.Fatalf("expected error for %v", bad)
+		}
+	}
+}
@@ -4,17 +4,25 @@ import type {
     CancelResponse,
     CreateSessionPayload,
     CreateSessionResponse,
+    CreateEnsemblePayload,
+    DeleteSessionResponse,
     DiffResult,
+    Ensemble,
+    EnsembleDetail,
     DiscoveredRepo,
     FollowupResponse,
     ImportResponse,
     OpenResponse,
+    PromptTemplate,
     Repo,
+    RepoConfig,
+    RepoEnvVar,
     RunEvent,
     SessionDetail,
     SessionSummary,
     Settings,
     UpdateSettingsPayload,
+    UsageSummary,
     Branch,
     GhStatus,
 } from "./types";
@@ -113,28 +121,72 @@ export async function updateSettings(
     });
 }
 
+export async function fetchUsageStats(): Promise<UsageSummary[]> {
+    return fetchJSON<UsageSummary[]>("/api/stats/usage");
+}
+
 export async function fetchRepos(): Promise<Repo[]> {
     return fetchJSON<Repo[]>("/api/repos");
 }
 
-export async function discoverRepos(): Promise<DiscoveredRepo[]> {
-    return fetchJSON<DiscoveredRepo[]>("/api/repos/discover", {
+export async function discoverRepos(host?: string): Promise<DiscoveredRepo[]> {
+    const query = host ? "?host=" + encodeURIComponent(host) : "";
+    return fetchJSON<DiscoveredRepo[]>("/api/repos/discover" + query, {
         method: "POST",
     });
 }
 
 export async function importRepos(
     repos: string[],
+    host?: string,
 ): Promise<ImportResponse> {
     return fetchJSON<ImportResponse>("/api/repos/import", {
         method: "POST",
         headers: { "Content-Type": "application/json" },
-        body: JSON.stringify({ repos }),
+        body: JSON.stringify(host ? { repos, host } : { repos }),
+    });
+}
+
+function repoPath(repoName: string): string {
+    return "/api/repos/" + repoName.split("/").map(encodeURIComponent).join("/");
+}
+
+export async function fetchRepoConfig(repoName: string): Promise<RepoConfig> {
+    return fetchJSON<RepoConfig>(repoPath(repoName) + "/config");
+}
+
+export async function fetchRepoEnv(repoName: string): Promise<RepoEnvVar[]> {
+    return fetchJSON<RepoEnvVar[]>(repoPath(repoName) + "/env");
+}
+
+export async function setRepoEnv(
+    repoName: string,
+    name: string,
+    value: string,
+    secret: boolean,
+): Promise<RepoEnvVar> {
+    return fetchJSON<RepoEnvVar>(repoPath(repoName) + "/env", {
+        method: "PUT",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ name, value, secret }),
     });
 }
 
-export async function fetchSessions(): Promise<SessionSummary[]> {
-    return fetchJSON<SessionSummary[]>("/api/sessions");
+export async function unsetRepoEnv(
+    repoName: string,
+    name: string,
+): Promise<void> {
+    await fetchJSON<unknown>(
+        repoPath(repoName) + "/env/" + encodeURIComponent(name),
+        { method: "DELETE" },
+    );
+}
+
+export async function fetchSessions(
+    archived?: "include" | "only",
+): Promise<SessionSummary[]> {
+    const query = archived ? "?archived=" + archived : "";
+    return fetchJSON<SessionSummary[]>("/api/sessions" + query);
 }
 
 export async function fetchBranches(repoName: string): Promise<Branch[]> {
@@ -161,6 +213,31 @@ export async function createSession(
     });
 }
 
+export async function fetchTemplates(): Promise<PromptTemplate[]> {
+    return fetchJSON<PromptTemplate[]>("/api/templates");
+}
+
+export async function saveTemplate(
+    name: string,
+    body: string,
+    description = "",
+): Promise<PromptTemplate> {
+    return fetchJSON<PromptTemplate>(
+        "/api/templates/" + encodeURIComponent(name),
+        {
+            method: "PUT",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify({ body, description }),
+        },
+    );
+}
+
+export async function deleteTemplate(name: string): Promise<void> {
+    await fetchJSON<unknown>("/api/templates/" + encodeURIComponent(name), {
+        method: "DELETE",
+    });
+}
+
 export async function followUp(
     sessionID: string,
     prompt: string,
@@ -189,6 +266,40 @@ export async function forkSession(
     );
 }
 
+export async function createEnsemble(
+    payload: CreateEnsemblePayload,
+): Promise<Ensemble> {
+    return fetchJSON<Ensemble>("/api/ensembles", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify(payload),
+    });
+}
+
+export async function fetchEnsembles(): Promise<Ensemble[]> {
+    return fetchJSON<Ensemble[]>("/api/ensembles");
+}
+
+export async function fetchEnsemble(ensembleID: string): Promise<EnsembleDetail> {
+    return fetchJSON<EnsembleDetail>(
+        "/api/ensembles/" + encodeURIComponent(ensembleID),
+    );
+}
+
+export async function promoteEnsembleWinner(
+    ensembleID: string,
+    sessionID: string,
+): Promise<Ensemble> {
+    return fetchJSON<Ensemble>(
+        "/api/ensembles/" + encodeURIComponent(ensembleID) + "/promote",
+        {
+            method: "POST",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify({ session_id: sessionID }),
+        },
+    );
+}
+
 export async function cancelSession(
     sessionID: string,
 ): Promise<CancelResponse> {
@@ -198,6 +309,76 @@ export async function cancelSession(
     );
 }
 
+export async function archiveSession(
+    sessionID: string,
+    archived: boolean,
+): Promise<SessionSummary> {
+    return fetchJSON<SessionSummary>(
+        "/api/sessions/" +
+            encodeURIComponent(sessionID) +
+            (archived ? "/archive" : "/unarchive"),
+        { method: "POST" },
+    );
+}
+
+export async function refreshSessionPR(
+    sessionID: string,
+): Promise<SessionSummary> {
+    return fetchJSON<SessionSummary>(
+        "/api/sessions/" + encodeURIComponent(sessionID) + "/pr/refresh",
+        { method: "POST" },
+    );
+}
+
+export async function markSessionPRReady(
+    sessionID: string,
+): Promise<SessionSummary> {
+    return fetchJSON<SessionSummary>(
+        "/api/sessions/" + encodeURIComponent(sessionID) + "/pr/ready",
+        { method: "POST" },
+    );
+}
+
+export async function updateSessionPRBody(
+    sessionID: string,
+    dryRun = false,
+): Promise<{ session_id: string; body: string }> {
+    return fetchJSON<{ session_id: string; body: string }>(
+        "/api/sessions/" +
+            encodeURIComponent(sessionID) +
+            "/pr/body" +
+            (dryRun ? "?dry_run=1" : ""),
+        { method: "POST" },
+    );
+}
+
+export async function mergeSessionPR(
+    sessionID: string,
+    method?: "squash" | "rebase" | "merge",
+): Promise<SessionSummary> {
+    return fetchJSON<SessionSummary>(
+        "/api/sessions/" +
+            encodeURIComponent(sessionID) +
+            "/pr/merge" +
+            (method ? "?method=" + method : ""),
+        { method: "POST" },
+    );
+}
+
+export async function deleteSession(
+    sessionID: string,
+    options: { deleteBranch?: boolean; force?: boolean } = {},
+): Promise<DeleteSessionResponse> {
+    const params = new URLSearchParams();
+    if (options.deleteBranch) params.set("delete_branch", "1");
+    if (options.force) params.set("force", "1");
+    const query = params.toString() ? "?" + params.toString() : "";
+    return fetchJSON<DeleteSessionResponse>(
+        "/api/sessions/" + encodeURIComponent(sessionID) + query,
+        { method: "DELETE" },
+    );
+}
+
 export async function fetchDiff(sessionID: string): Promise<DiffResult> {
     return fetchJSON<DiffResult>(
         "/api/sessions/" + encodeURIComponent(sessionID) + "/diff",
@@ -13,9 +13,25 @@ export interface SessionSummary {
     busy: boolean;
     created_at: string;
     updated_at: string;
+    archived_at?: string;
+    pr?: PRStatus;
     latest_run?: RunSummary;
 }
 
+export interface PRStatus {
+    state: "OPEN" | "CLOSED" | "MERGED";
+    draft: boolean;
+    mergeable?: string;
+    review_decision?: string;
+    checks: {
+        state?: "PASSING" | "FAILING" | "PENDING";
+        passed: number;
+        failed: number;
+        pending: number;
+    };
+    synced_at?: string;
+}
+
 export interface RunSummary {
     id: string;
     session_id: string;
@@ -28,6 +44,35 @@ export interface RunSummary {
     created_at: string;
     updated_at: string;
     completed_at?: string;
+    queue_position?: number;
+    priority?: number;
+    usage?: RunUsage;
+    sandbox?: string;
+    template?: string;
+}
+
+export interface RunUsage {
+    model?: string;
+    input_tokens: number;
+    output_tokens: number;
+    cache_read_tokens: number;
+    cache_creation_tokens: number;
+    cost_usd: number;
+    duration_ms: number;
+    num_turns: number;
+}
+
+export interface UsageSummary {
+    repo_name: string;
+    tool: string;
+    runs: number;
+    input_tokens: number;
+    output_tokens: number;
+    cache_read_tokens: number;
+    cache_creation_tokens: number;
+    cost_usd: number;
+    duration_ms: number;
+    num_turns: number;
 }
 
 export interface RunEvent {
@@ -44,6 +89,54 @@ export interface SessionDetail {
     runs: RunSummary[];
 }
 
+export interface EnsembleMember {
+    session_id: string;
+    tool: string;
+    model?: string;
+}
+
+export interface Ensemble {
+    id: string;
+    repo_name: string;
+    source_session_id?: string;
+    prompt: string;
+    status: string;
+    winner_session_id?: string;
+    created_at: string;
+    updated_at: string;
+    members: EnsembleMember[];
+}
+
+export interface EnsembleMemberResult extends EnsembleMember {
+    session?: SessionSummary;
+    latest_run?: RunSummary;
+    validation?: "passed" | "failed";
+    duration_ms: number;
+    stat: string;
+    patch: string;
+    diff_error?: string;
+}
+
+export interface EnsembleDetail extends Ensemble {
+    results: EnsembleMemberResult[];
+}
+
+export interface CreateEnsemblePayload {
+    source_session_id: string;
+    prompt: string;
+    variants: { tool: string; model?: string }[];
+    branch_name?: string;
+    setup_cmd?: string;
+    validate?: boolean;
+    validate_cmd?: string;
+    fix_attempts?: number;
+    base_branch?: string;
+    priority?: number;
+    timeout?: string;
+    max_tokens?: number;
+    max_cost_usd?: number;
+}
+
 export interface DiffResult {
     base_branch: string;
     branch: string;
@@ -63,6 +156,22 @@ export interface Settings {
     gh_authenticated: boolean;
     onboarding_required: boolean;
     available_tools: string[];
+    max_concurrent_runs: number;
+    max_concurrent_runs_per_repo: number;
+    max_concurrent_runs_per_tool: number;
+    run_timeout: string;
+    run_max_tokens: number;
+    run_max_cost_usd: number;
+    sandbox: SandboxSettings;
+    repo_sandbox?: Record<string, SandboxSettings>;
+    sandbox_backends: string[];
+    redact_patterns: string[];
+}
+
+export interface SandboxSettings {
+    backend?: string;
+    no_network?: boolean;
+    writable_dirs?: string[];
 }
 
 export interface UpdateSettingsPayload {
@@ -87,6 +196,30 @@ export interface Repo {
     created_at?: string;
 }
 
+export interface ProjectConfig {
+    setup?: string;
+    validate?: string;
+    tools?: string[];
+    model?: string;
+    base_branch?: string;
+    prompt_preamble?: string;
+    pr?: { template?: string };
+    commit?: { style?: string };
+}
+
+export interface RepoConfig {
+    repo: string;
+    file?: string;
+    config: ProjectConfig;
+}
+
+export interface RepoEnvVar {
+    name: string;
+    value?: string;
+    secret: boolean;
+    updated_at?: string;
+}
+
 export interface DiscoveredRepo {
     id: string;
     name: string;
@@ -118,10 +251,22 @@ export interface CreateSessionPayload {
     setup_cmd?: string;
     validate?: boolean;
     validate_cmd?: string;
+    fix_attempts?: number;
     base_branch?: string;
     commit_msg?: string;
     async?: boolean;
     pr_title?: string;
+    template?: string;
+    vars?: Record<string, string>;
+}
+
+export interface PromptTemplate {
+    name: string;
+    description?: string;
+    body: string;
+    vars: string[];
+    created_at?: string;
+    updated_at?: string;
 }
 
 export interface CreateSessionResponse {
@@ -141,6 +286,11 @@ export interface CancelResponse {
     run_id: string;
 }
 
+export interface DeleteSessionResponse {
+    status: string;
+    session_id: string;
+}
+
 export interface OpenResponse {
     status: string;
     editor: string;
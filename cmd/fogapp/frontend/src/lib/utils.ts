@@ -31,6 +31,16 @@ export function relativeTime(value?: string): string {
     return `${days}d ago`;
 }
 
+export function formatTokens(value: number): string {
+    if (value >= 1_000_000) return `${(value / 1_000_000).toFixed(1)}M`;
+    if (value >= 1_000) return `${(value / 1_000).toFixed(1)}k`;
+    return String(value);
+}
+
+export function formatCost(value: number): string {
+    return `$${value.toFixed(value < 1 ? 4 : 2)}`;
+}
+
 // Aliases used by components
 export const formatRelativeTime = relativeTime;
 export const truncatePrompt = firstPromptLine;
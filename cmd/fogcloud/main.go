@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -25,6 +26,8 @@ var (
 	flagSlackSigning      string
 	flagSlackScopes       string
 	flagPairCodeTTL       time.Duration
+	flagJobLeaseTTL       time.Duration
+	flagJobMaxAttempts    int
 )
 
 func main() {
@@ -59,6 +62,8 @@ func init() {
 	rootCmd.Flags().StringVar(&flagSlackSigning, "slack-signing-secret", "", "Slack signing secret (required)")
 	rootCmd.Flags().StringVar(&flagSlackScopes, "slack-scopes", "app_mentions:read,chat:write", "Comma-separated Slack OAuth bot scopes")
 	rootCmd.Flags().DurationVar(&flagPairCodeTTL, "pair-code-ttl", 10*time.Minute, "Pairing code TTL")
+	rootCmd.Flags().DurationVar(&flagJobLeaseTTL, "job-lease-ttl", cloud.DefaultJobLeaseTTL, "How long a claimed job stays leased to a device without a heartbeat")
+	rootCmd.Flags().IntVar(&flagJobMaxAttempts, "job-max-attempts", cloud.DefaultJobMaxAttempts, "Deliveries of a job before it is abandoned")
 	rootCmd.AddCommand(versionCmd)
 }
 
@@ -89,6 +94,8 @@ func runServer() error {
 		PublicURL:      strings.TrimSpace(flagPublicURL),
 		Scopes:         scopes,
 		PairingCodeTTL: flagPairCodeTTL,
+		JobLeaseTTL:    flagJobLeaseTTL,
+		JobMaxAttempts: flagJobMaxAttempts,
 	})
 	if err != nil {
 		return err
@@ -96,11 +103,13 @@ func runServer() error {
 
 	mux := http.NewServeMux()
 	server.RegisterRoutes(mux)
+	go server.RunJobReaper(context.Background(), 0)
 
 	addr := fmt.Sprintf(":%d", flagPort)
 	log.Printf("Starting fogcloud on %s", addr)
 	log.Printf("Health: http://localhost:%d/health", flagPort)
 	log.Printf("Slack install: %s/slack/install", strings.TrimRight(strings.TrimSpace(flagPublicURL), "/"))
+	log.Printf("Slack interactivity: %s/slack/interactivity", strings.TrimRight(strings.TrimSpace(flagPublicURL), "/"))
 	return http.ListenAndServe(addr, mux)
 }
 
@@ -32,6 +32,11 @@ var (
 	flagSlackApp    string
 	flagCloudURL    string
 	flagCloudPoll   time.Duration
+	flagCloudJobs   int
+	flagResume      bool
+	flagSchedule    time.Duration
+	flagPRSync      time.Duration
+	flagPRStatus    time.Duration
 )
 
 func main() {
@@ -68,7 +73,12 @@ func init() {
 	rootCmd.Flags().StringVar(&flagSlackBot, "slack-bot-token", "", "Slack bot token (xoxb-..., required for socket mode)")
 	rootCmd.Flags().StringVar(&flagSlackApp, "slack-app-token", "", "Slack app token (xapp-..., required for socket mode)")
 	rootCmd.Flags().StringVar(&flagCloudURL, "cloud-url", "", "Fog cloud base URL for distributed Slack relay (optional)")
-	rootCmd.Flags().DurationVar(&flagCloudPoll, "cloud-poll-interval", 2*time.Second, "Fog cloud relay polling interval")
+	rootCmd.Flags().DurationVar(&flagCloudPoll, "cloud-poll-interval", 2*time.Second, "Fog cloud relay polling interval while the push channel is unavailable")
+	rootCmd.Flags().IntVar(&flagCloudJobs, "cloud-max-jobs", 2, "Maximum Fog cloud jobs handled concurrently; a job frees its slot once its run has started")
+	rootCmd.Flags().BoolVar(&flagResume, "resume-interrupted", false, "Re-queue runs interrupted by a previous fogd exit when their AI conversation can be resumed")
+	rootCmd.Flags().DurationVar(&flagSchedule, "schedule-interval", runner.DefaultScheduleInterval, "How often to check for due schedules (0 disables the scheduler)")
+	rootCmd.Flags().DurationVar(&flagPRSync, "pr-sync-interval", 0, "Poll open session PRs for new review comments and failing checks at this interval and run follow-ups (0 disables)")
+	rootCmd.Flags().DurationVar(&flagPRStatus, "pr-status-interval", runner.DefaultPRStatusInterval, "How often to refresh session PR state, checks and reviews from the forge (0 disables)")
 
 	rootCmd.AddCommand(versionCmd)
 }
@@ -119,6 +129,39 @@ func runDaemon() error {
 	apiServer := api.New(r, stateStore, flagPort)
 	apiServer.RegisterRoutes(mux)
 
+	// Reconcile runs orphaned by a previous fogd process before taking new work.
+	report, err := r.RecoverInterruptedRuns(runner.RecoveryOptions{Resume: flagResume})
+	if err != nil {
+		return fmt.Errorf("recover interrupted runs: %w", err)
+	}
+	if len(report.InterruptedRuns) > 0 || len(report.ReleasedSessions) > 0 {
+		log.Printf("Recovered after restart: %d interrupted run(s), %d resumed, %d session(s) released\n",
+			len(report.InterruptedRuns), len(report.ResumedRuns), len(report.ReleasedSessions))
+	}
+
+	if flagSchedule > 0 {
+		go func() {
+			if err := r.RunSchedules(daemonCtx, flagSchedule); err != nil {
+				log.Printf("Scheduler stopped: %v", err)
+			}
+		}()
+	}
+	if flagPRStatus > 0 {
+		go func() {
+			if err := r.RunPRStatusSync(daemonCtx, flagPRStatus); err != nil {
+				log.Printf("PR status sync stopped: %v", err)
+			}
+		}()
+	}
+	if flagPRSync > 0 {
+		go func() {
+			if err := r.RunPRSync(daemonCtx, flagPRSync); err != nil {
+				log.Printf("PR sync stopped: %v", err)
+			}
+		}()
+		log.Printf("PR feedback sync enabled (every %s)", flagPRSync)
+	}
+
 	// Register Slack integration if enabled
 	if flagEnableSlack {
 		mode := strings.ToLower(strings.TrimSpace(flagSlackMode))
@@ -184,7 +227,8 @@ func runDaemon() error {
 				return err
 			}
 			relay, err := cloudrelay.New(client, r, stateStore, cloudrelay.RelayConfig{
-				PollInterval: flagCloudPoll,
+				PollInterval:      flagCloudPoll,
+				MaxConcurrentJobs: flagCloudJobs,
 			})
 			if err != nil {
 				return err
@@ -39,7 +39,7 @@ func (c *ClaudeCode) ExecuteStream(ctx context.Context, req ExecuteRequest, onCh
 	}
 
 	streamArgs := append(append([]string{}, args...), "--output-format", "stream-json")
-	output, conversationID, err := runJSONStreamingCommand(ctx, req.Workdir, cmdName, streamArgs, onChunk)
+	output, conversationID, usage, err := runJSONStreamingCommand(ctx, req, cmdName, streamArgs, onChunk, decodeClaudeEvents)
 
 	if err != nil && (looksLikeUnsupportedFlag(output) || strings.TrimSpace(output) == "") {
 		plainOutput, plainErr := runPlainStreamingCommand(ctx, req.Workdir, cmdName, args, onChunk)
@@ -48,6 +48,7 @@ func (c *ClaudeCode) ExecuteStream(ctx context.Context, req ExecuteRequest, onCh
 			Output:         strings.TrimSpace(plainOutput),
 			Error:          plainErr,
 			ConversationID: conversationID,
+			Usage:          usage,
 		}
 		if plainErr != nil {
 			return result, plainErr
@@ -60,6 +61,7 @@ func (c *ClaudeCode) ExecuteStream(ctx context.Context, req ExecuteRequest, onCh
 		Output:         strings.TrimSpace(output),
 		Error:          err,
 		ConversationID: conversationID,
+		Usage:          usage,
 	}
 	if err != nil {
 		return result, err
@@ -31,12 +31,13 @@ func (c *Cursor) ExecuteStream(ctx context.Context, req ExecuteRequest, onChunk
 	}
 
 	streamArgs := buildCursorHeadlessArgs(req, true)
-	streamOutput, conversationID, streamErr := runJSONStreamingCommand(ctx, req.Workdir, cmdName, streamArgs, onChunk)
+	streamOutput, conversationID, usage, streamErr := runJSONStreamingCommand(ctx, req, cmdName, streamArgs, onChunk, decodeCursorEvents)
 	if streamErr == nil {
 		return &Result{
 			Success:        true,
 			Output:         strings.TrimSpace(streamOutput),
 			ConversationID: conversationID,
+			Usage:          usage,
 		}, nil
 	}
 
@@ -48,6 +49,7 @@ func (c *Cursor) ExecuteStream(ctx context.Context, req ExecuteRequest, onChunk
 			Output:         strings.TrimSpace(plainOutput),
 			Error:          plainErr,
 			ConversationID: conversationID,
+			Usage:          usage,
 		}, plainErr
 	}
 
@@ -56,6 +58,7 @@ func (c *Cursor) ExecuteStream(ctx context.Context, req ExecuteRequest, onChunk
 		Output:         strings.TrimSpace(streamOutput),
 		Error:          streamErr,
 		ConversationID: conversationID,
+		Usage:          usage,
 	}, streamErr
 }
 
@@ -0,0 +1,370 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	fogenv "github.com/darkLord19/foglet/internal/env"
+)
+
+// CustomToolsDir is the directory under FOG_HOME holding custom adapter
+// definitions, one JSON file per tool.
+const CustomToolsDir = "tools"
+
+// Output modes for custom tools.
+const (
+	OutputPlain     = "plain"
+	OutputJSONLines = "jsonl"
+)
+
+// Placeholders expanded in custom tool argument templates.
+const (
+	placeholderPrompt     = "{{prompt}}"
+	placeholderModel      = "{{model}}"
+	placeholderSessionID  = "{{session_id}}"
+	placeholderModelArgs  = "{{model_args}}"
+	placeholderResumeArgs = "{{resume_args}}"
+)
+
+var customToolNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// ToolDefinition declares a custom AI tool adapter.
+//
+// Args is the argument template for one execution and must reference
+// {{prompt}}. ModelArgs and ResumeArgs are only added when a model or a
+// conversation to resume is set; they expand at a {{model_args}} /
+// {{resume_args}} element when present, otherwise just before the prompt.
+//
+// With Output "jsonl" every stdout line is parsed as JSON: TextPath and
+// SessionIDPath are dot-separated paths to the assistant text and the
+// conversation id, and TextMatch optionally restricts text extraction to
+// lines whose paths equal the given values. Fallbacks are tried in order
+// when the tool rejects a flag (or prints nothing) and fails.
+type ToolDefinition struct {
+	Name          string             `json:"name"`
+	Command       string             `json:"command"`
+	Args          []string           `json:"args"`
+	ModelArgs     []string           `json:"model_args,omitempty"`
+	ResumeArgs    []string           `json:"resume_args,omitempty"`
+	Output        string             `json:"output,omitempty"`
+	TextPath      string             `json:"text_path,omitempty"`
+	TextMatch     map[string]string  `json:"text_match,omitempty"`
+	SessionIDPath string             `json:"session_id_path,omitempty"`
+	Fallbacks     []ToolFallbackArgs `json:"fallbacks,omitempty"`
+}
+
+// ToolFallbackArgs is an alternative invocation for older tool versions.
+type ToolFallbackArgs struct {
+	Args   []string `json:"args"`
+	Output string   `json:"output,omitempty"`
+}
+
+// Validate reports the first problem with a definition.
+func (d ToolDefinition) Validate() error {
+	name := strings.TrimSpace(d.Name)
+	if name == "" {
+		return errors.New("name cannot be empty")
+	}
+	if !customToolNamePattern.MatchString(name) {
+		return fmt.Errorf("name %q must be lowercase letters, digits, '.', '_' or '-'", name)
+	}
+	if isBuiltinToolName(name) {
+		return fmt.Errorf("name %q conflicts with a built-in tool", name)
+	}
+	if strings.TrimSpace(d.Command) == "" {
+		return errors.New("command cannot be empty")
+	}
+	if err := validateArgsTemplate("args", d.Args, d.Output); err != nil {
+		return err
+	}
+	if len(d.ModelArgs) > 0 && !containsPlaceholder(d.ModelArgs, placeholderModel) {
+		return fmt.Errorf("model_args must reference %s", placeholderModel)
+	}
+	if len(d.ResumeArgs) > 0 && !containsPlaceholder(d.ResumeArgs, placeholderSessionID) {
+		return fmt.Errorf("resume_args must reference %s", placeholderSessionID)
+	}
+	if mode := outputMode(d.Output); mode == OutputJSONLines && strings.TrimSpace(d.TextPath) == "" {
+		return errors.New("text_path is required for jsonl output")
+	}
+	for i, fallback := range d.Fallbacks {
+		if err := validateArgsTemplate(fmt.Sprintf("fallbacks[%d].args", i), fallback.Args, fallback.Output); err != nil {
+			return err
+		}
+		if outputMode(fallback.Output) == OutputJSONLines && strings.TrimSpace(d.TextPath) == "" {
+			return fmt.Errorf("fallbacks[%d]: text_path is required for jsonl output", i)
+		}
+	}
+	return nil
+}
+
+func validateArgsTemplate(field string, args []string, output string) error {
+	if !containsPlaceholder(args, placeholderPrompt) {
+		return fmt.Errorf("%s must reference %s", field, placeholderPrompt)
+	}
+	if mode := strings.TrimSpace(output); mode != "" && mode != OutputPlain && mode != OutputJSONLines {
+		return fmt.Errorf("output %q must be %q or %q", mode, OutputPlain, OutputJSONLines)
+	}
+	return nil
+}
+
+func containsPlaceholder(args []string, placeholder string) bool {
+	for _, arg := range args {
+		if strings.Contains(arg, placeholder) {
+			return true
+		}
+	}
+	return false
+}
+
+func outputMode(output string) string {
+	if strings.TrimSpace(output) == OutputJSONLines {
+		return OutputJSONLines
+	}
+	return OutputPlain
+}
+
+// LoadToolDefinitions reads every *.json definition in dir. A missing
+// directory yields no definitions. Invalid files are reported in the
+// returned error while valid ones are still returned.
+func LoadToolDefinitions(dir string) ([]ToolDefinition, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("list custom tools: %w", err)
+	}
+	sort.Strings(paths)
+
+	defs := make([]ToolDefinition, 0, len(paths))
+	seen := make(map[string]string, len(paths))
+	var errs []error
+	for _, path := range paths {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("read %s: %w", path, err))
+			continue
+		}
+		var def ToolDefinition
+		if err := json.Unmarshal(raw, &def); err != nil {
+			errs = append(errs, fmt.Errorf("parse %s: %w", path, err))
+			continue
+		}
+		def.Name = strings.TrimSpace(def.Name)
+		if err := def.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s: %w", path, err))
+			continue
+		}
+		if other, dup := seen[def.Name]; dup {
+			errs = append(errs, fmt.Errorf("invalid %s: tool %q already defined in %s", path, def.Name, other))
+			continue
+		}
+		seen[def.Name] = path
+		defs = append(defs, def)
+	}
+	return defs, errors.Join(errs...)
+}
+
+var customTools struct {
+	once sync.Once
+	mu   sync.RWMutex
+	defs map[string]ToolDefinition
+}
+
+// ReloadCustomTools (re)loads custom adapters from $FOG_HOME/tools and
+// returns the loaded definitions together with any validation errors.
+func ReloadCustomTools() ([]ToolDefinition, error) {
+	customTools.once.Do(func() {})
+	return loadCustomTools()
+}
+
+func loadCustomTools() ([]ToolDefinition, error) {
+	fogHome, err := fogenv.FogHome()
+	if err != nil {
+		return nil, err
+	}
+	defs, loadErr := LoadToolDefinitions(filepath.Join(fogHome, CustomToolsDir))
+
+	byName := make(map[string]ToolDefinition, len(defs))
+	for _, def := range defs {
+		byName[def.Name] = def
+	}
+	customTools.mu.Lock()
+	customTools.defs = byName
+	customTools.mu.Unlock()
+	return defs, loadErr
+}
+
+func lookupCustomTool(name string) (ToolDefinition, bool) {
+	customTools.once.Do(func() { _, _ = loadCustomTools() })
+	customTools.mu.RLock()
+	defer customTools.mu.RUnlock()
+	def, ok := customTools.defs[name]
+	return def, ok
+}
+
+func customToolNames() []string {
+	customTools.once.Do(func() { _, _ = loadCustomTools() })
+	customTools.mu.RLock()
+	defer customTools.mu.RUnlock()
+	names := make([]string, 0, len(customTools.defs))
+	for name := range customTools.defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CustomTool runs a config-defined adapter.
+type CustomTool struct {
+	def ToolDefinition
+}
+
+// NewCustomTool returns an adapter for a validated definition.
+func NewCustomTool(def ToolDefinition) (*CustomTool, error) {
+	if err := def.Validate(); err != nil {
+		return nil, fmt.Errorf("custom tool %q: %w", def.Name, err)
+	}
+	return &CustomTool{def: def}, nil
+}
+
+func (c *CustomTool) Name() string {
+	return c.def.Name
+}
+
+func (c *CustomTool) IsAvailable() bool {
+	return commandExists(c.def.Command)
+}
+
+func (c *CustomTool) Execute(ctx context.Context, workdir, prompt string) (*Result, error) {
+	return c.ExecuteStream(ctx, ExecuteRequest{
+		Workdir: workdir,
+		Prompt:  prompt,
+	}, nil)
+}
+
+func (c *CustomTool) ExecuteStream(ctx context.Context, req ExecuteRequest, onChunk func(string)) (*Result, error) {
+	cmdName := commandPath(c.def.Command)
+	if cmdName == "" {
+		return nil, fmt.Errorf("%s not available", c.def.Name)
+	}
+
+	attempts := append([]ToolFallbackArgs{{Args: c.def.Args, Output: c.def.Output}}, c.def.Fallbacks...)
+	var result *Result
+	var err error
+	for _, attempt := range attempts {
+		args := c.expandArgs(attempt.Args, req)
+		if outputMode(attempt.Output) == OutputJSONLines {
+			parser := newStreamJSONParser(onChunk)
+			parser.extractText = c.extractText
+			parser.extractSession = c.extractSessionID
+			var output, conversationID string
+			var usage Usage
+			output, conversationID, usage, err = runStreamParser(ctx, req, cmdName, args, parser)
+			result = &Result{Success: err == nil, Output: output, Error: err, ConversationID: conversationID, Usage: usage}
+		} else {
+			var output string
+			output, err = runPlainStreamingCommand(ctx, req.Workdir, cmdName, args, onChunk)
+			result = &Result{Success: err == nil, Output: output, Error: err}
+		}
+		if err == nil || !(looksLikeUnsupportedFlag(result.Output) || result.Output == "") {
+			break
+		}
+	}
+	return result, err
+}
+
+func (c *CustomTool) expandArgs(template []string, req ExecuteRequest) []string {
+	model := strings.TrimSpace(req.Model)
+	conversationID := strings.TrimSpace(req.ConversationID)
+	var modelArgs, resumeArgs []string
+	if model != "" {
+		modelArgs = c.def.ModelArgs
+	}
+	if conversationID != "" {
+		resumeArgs = c.def.ResumeArgs
+	}
+
+	replacer := strings.NewReplacer(
+		placeholderPrompt, strings.TrimSpace(req.Prompt),
+		placeholderModel, model,
+		placeholderSessionID, conversationID,
+	)
+	expand := func(values []string) []string {
+		out := make([]string, 0, len(values))
+		for _, value := range values {
+			out = append(out, replacer.Replace(value))
+		}
+		return out
+	}
+
+	placeModel := !containsPlaceholder(template, placeholderModelArgs)
+	placeResume := !containsPlaceholder(template, placeholderResumeArgs)
+	args := make([]string, 0, len(template)+len(modelArgs)+len(resumeArgs))
+	for _, arg := range template {
+		switch {
+		case arg == placeholderModelArgs:
+			args = append(args, expand(modelArgs)...)
+			continue
+		case arg == placeholderResumeArgs:
+			args = append(args, expand(resumeArgs)...)
+			continue
+		case strings.Contains(arg, placeholderPrompt):
+			if placeModel {
+				args = append(args, expand(modelArgs)...)
+				placeModel = false
+			}
+			if placeResume {
+				args = append(args, expand(resumeArgs)...)
+				placeResume = false
+			}
+		}
+		args = append(args, replacer.Replace(arg))
+	}
+	return args
+}
+
+func (c *CustomTool) extractText(payload map[string]any) string {
+	for path, want := range c.def.TextMatch {
+		value, _ := lookupPath(payload, path).(string)
+		if value != want {
+			return ""
+		}
+	}
+	return flattenText(lookupPath(payload, c.def.TextPath), 6)
+}
+
+func (c *CustomTool) extractSessionID(payload map[string]any) string {
+	if strings.TrimSpace(c.def.SessionIDPath) == "" {
+		return extractConversationID(payload)
+	}
+	value, _ := lookupPath(payload, c.def.SessionIDPath).(string)
+	return strings.TrimSpace(value)
+}
+
+// lookupPath resolves a dot-separated path ("item.text") in a JSON object.
+func lookupPath(payload map[string]any, path string) any {
+	var current any = payload
+	for _, key := range strings.Split(strings.TrimSpace(path), ".") {
+		node, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		current = node[key]
+	}
+	return current
+}
+
+func isBuiltinToolName(name string) bool {
+	switch normalizeToolName(name) {
+	case "cursor", "claude", "gemini", "aider":
+		return true
+	default:
+		return false
+	}
+}
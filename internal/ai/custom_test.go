@@ -0,0 +1,179 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestToolDefinitionValidate(t *testing.T) {
+	valid := ToolDefinition{Name: "codex", Command: "codex", Args: []string{"exec", "{{prompt}}"}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected valid definition, got %v", err)
+	}
+
+	cases := map[string]ToolDefinition{
+		"empty name":       {Command: "x", Args: []string{"{{prompt}}"}},
+		"bad name":         {Name: "My Tool", Command: "x", Args: []string{"{{prompt}}"}},
+		"builtin name":     {Name: "claude", Command: "x", Args: []string{"{{prompt}}"}},
+		"no command":       {Name: "x", Args: []string{"{{prompt}}"}},
+		"no prompt":        {Name: "x", Command: "x", Args: []string{"run"}},
+		"bad output":       {Name: "x", Command: "x", Args: []string{"{{prompt}}"}, Output: "xml"},
+		"jsonl no path":    {Name: "x", Command: "x", Args: []string{"{{prompt}}"}, Output: OutputJSONLines},
+		"model no holder":  {Name: "x", Command: "x", Args: []string{"{{prompt}}"}, ModelArgs: []string{"--model"}},
+		"resume no holder": {Name: "x", Command: "x", Args: []string{"{{prompt}}"}, ResumeArgs: []string{"--resume"}},
+		"fallback prompt":  {Name: "x", Command: "x", Args: []string{"{{prompt}}"}, Fallbacks: []ToolFallbackArgs{{Args: []string{"run"}}}},
+	}
+	for name, def := range cases {
+		if err := def.Validate(); err == nil {
+			t.Fatalf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestCustomToolExpandArgs(t *testing.T) {
+	tool := &CustomTool{def: ToolDefinition{
+		Name:       "codex",
+		Command:    "codex",
+		Args:       []string{"exec", "{{resume_args}}", "--json", "{{prompt}}"},
+		ModelArgs:  []string{"-m", "{{model}}"},
+		ResumeArgs: []string{"resume", "{{session_id}}"},
+	}}
+
+	got := tool.expandArgs(tool.def.Args, ExecuteRequest{Prompt: " fix it ", Model: "o3", ConversationID: "th-1"})
+	want := []string{"exec", "resume", "th-1", "--json", "-m", "o3", "fix it"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected args: got %q want %q", got, want)
+	}
+
+	got = tool.expandArgs(tool.def.Args, ExecuteRequest{Prompt: "fix it"})
+	want = []string{"exec", "--json", "fix it"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected args without model/resume: got %q want %q", got, want)
+	}
+}
+
+func TestCustomToolFromFogHomeWithFakeBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tool script requires a POSIX shell")
+	}
+
+	binDir := t.TempDir()
+	script := `#!/bin/sh
+json=0
+for arg in "$@"; do
+  [ "$arg" = "--json" ] && json=1
+  last="$arg"
+done
+if [ "$json" = 1 ]; then
+  echo '{"type":"thread.started","thread_id":"th-42"}'
+  echo '{"type":"item.completed","item":{"type":"reasoning","text":"thinking"}}'
+  printf '{"type":"item.completed","item":{"type":"agent_message","text":"did: %s"}}\n' "$last"
+  exit 0
+fi
+echo "plain output"
+`
+	if err := os.WriteFile(filepath.Join(binDir, "fake-codex"), []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake binary failed: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	fogHome := t.TempDir()
+	toolsDir := filepath.Join(fogHome, CustomToolsDir)
+	if err := os.MkdirAll(toolsDir, 0o755); err != nil {
+		t.Fatalf("mkdir tools dir failed: %v", err)
+	}
+	definition := `{
+  "name": "codex",
+  "command": "fake-codex",
+  "args": ["exec", "--json", "{{prompt}}"],
+  "resume_args": ["resume", "{{session_id}}"],
+  "output": "jsonl",
+  "text_path": "item.text",
+  "text_match": {"item.type": "agent_message"},
+  "session_id_path": "thread_id"
+}`
+	if err := os.WriteFile(filepath.Join(toolsDir, "codex.json"), []byte(definition), 0o644); err != nil {
+		t.Fatalf("write definition failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(toolsDir, "broken.json"), []byte(`{"name":"broken"}`), 0o644); err != nil {
+		t.Fatalf("write broken definition failed: %v", err)
+	}
+	t.Setenv("FOG_HOME", fogHome)
+	t.Cleanup(func() { _, _ = ReloadCustomTools() })
+
+	defs, err := ReloadCustomTools()
+	if err == nil || !strings.Contains(err.Error(), "broken.json") {
+		t.Fatalf("expected validation error for broken.json, got %v", err)
+	}
+	if len(defs) != 1 || defs[0].Name != "codex" {
+		t.Fatalf("unexpected loaded definitions: %+v", defs)
+	}
+	if !slices.Contains(AvailableToolNames(), "codex") {
+		t.Fatalf("custom tool missing from available names: %v", AvailableToolNames())
+	}
+
+	tool, err := GetTool("codex")
+	if err != nil {
+		t.Fatalf("GetTool returned error: %v", err)
+	}
+	if !tool.IsAvailable() {
+		t.Fatal("expected fake binary to be available")
+	}
+	var chunks []string
+	result, err := ExecuteWithOptionalStream(context.Background(), tool, ExecuteRequest{
+		Workdir: t.TempDir(),
+		Prompt:  "add tests",
+	}, func(chunk string) { chunks = append(chunks, chunk) })
+	if err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	if result.Output != "did: add tests" || result.ConversationID != "th-42" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if len(chunks) != 1 {
+		t.Fatalf("only matching lines should stream: %+v", chunks)
+	}
+}
+
+func TestCustomToolFallsBackOnUnsupportedFlag(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tool script requires a POSIX shell")
+	}
+
+	binDir := t.TempDir()
+	script := `#!/bin/sh
+if [ "$1" = "--json" ]; then
+  echo "error: unknown flag: --json"
+  exit 2
+fi
+echo "ok: $1"
+`
+	binary := filepath.Join(binDir, "old-tool")
+	if err := os.WriteFile(binary, []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake binary failed: %v", err)
+	}
+
+	tool, err := NewCustomTool(ToolDefinition{
+		Name:      "old-tool",
+		Command:   binary,
+		Args:      []string{"--json", "{{prompt}}"},
+		Output:    OutputJSONLines,
+		TextPath:  "text",
+		Fallbacks: []ToolFallbackArgs{{Args: []string{"{{prompt}}"}}},
+	})
+	if err != nil {
+		t.Fatalf("new custom tool failed: %v", err)
+	}
+	result, err := tool.Execute(context.Background(), t.TempDir(), "hello")
+	if err != nil {
+		t.Fatalf("expected fallback to succeed, got %v", err)
+	}
+	if result.Output != "ok: hello" {
+		t.Fatalf("unexpected fallback output: %q", result.Output)
+	}
+}
@@ -0,0 +1,212 @@
+package ai
+
+import "strings"
+
+// EventKind classifies one structured event in an agent transcript.
+type EventKind string
+
+const (
+	EventAssistantText EventKind = "assistant_text"
+	EventToolUse       EventKind = "tool_use"
+	EventToolResult    EventKind = "tool_result"
+	EventFileEdit      EventKind = "file_edit"
+	EventShellCommand  EventKind = "shell_command"
+	EventError         EventKind = "tool_error"
+)
+
+// Event is one step of an agent transcript decoded from a tool's
+// stream-json output. File edits and shell commands are tool calls that
+// are promoted to their own kinds so they can be rendered without knowing
+// each tool's naming.
+type Event struct {
+	Kind      EventKind      `json:"kind"`
+	Text      string         `json:"text,omitempty"`
+	ToolName  string         `json:"tool_name,omitempty"`
+	ToolUseID string         `json:"tool_use_id,omitempty"`
+	Input     map[string]any `json:"input,omitempty"`
+	Path      string         `json:"path,omitempty"`
+	Command   string         `json:"command,omitempty"`
+	IsError   bool           `json:"is_error,omitempty"`
+}
+
+// eventDecoder turns one stream-json payload into transcript events.
+type eventDecoder func(payload map[string]any) []Event
+
+var (
+	shellToolNames = map[string]bool{
+		"bash":              true,
+		"shell":             true,
+		"run_shell_command": true,
+		"run_terminal_cmd":  true,
+	}
+	fileEditToolNames = map[string]bool{
+		"edit":         true,
+		"multiedit":    true,
+		"write":        true,
+		"notebookedit": true,
+		"write_file":   true,
+		"replace":      true,
+		"edit_file":    true,
+		"delete":       true,
+	}
+)
+
+// toolCallEvent classifies a tool invocation.
+func toolCallEvent(name, id string, input map[string]any) Event {
+	event := Event{
+		Kind:      EventToolUse,
+		ToolName:  strings.TrimSpace(name),
+		ToolUseID: strings.TrimSpace(id),
+		Input:     input,
+		Path:      firstString(input, "file_path", "path", "notebook_path", "absolute_path", "target_file"),
+	}
+	key := strings.ToLower(event.ToolName)
+	switch {
+	case shellToolNames[key]:
+		event.Kind = EventShellCommand
+		event.Command = firstString(input, "command", "cmd")
+	case fileEditToolNames[key]:
+		event.Kind = EventFileEdit
+	}
+	return event
+}
+
+func eventType(payload map[string]any) string {
+	return strings.ToLower(strings.TrimSpace(firstString(payload, "type", "event_type", "event")))
+}
+
+func contentBlocks(payload map[string]any) []map[string]any {
+	message, _ := payload["message"].(map[string]any)
+	if message == nil {
+		return nil
+	}
+	raw, _ := message["content"].([]any)
+	blocks := make([]map[string]any, 0, len(raw))
+	for _, item := range raw {
+		if block, ok := item.(map[string]any); ok {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// decodeClaudeEvents handles Claude Code stream-json: assistant messages
+// carry text and tool_use blocks, user messages carry tool_result blocks.
+func decodeClaudeEvents(payload map[string]any) []Event {
+	var events []Event
+	switch eventType(payload) {
+	case "assistant":
+		for _, block := range contentBlocks(payload) {
+			switch firstString(block, "type") {
+			case "text":
+				if text := firstString(block, "text"); text != "" {
+					events = append(events, Event{Kind: EventAssistantText, Text: text})
+				}
+			case "tool_use":
+				input, _ := block["input"].(map[string]any)
+				events = append(events, toolCallEvent(firstString(block, "name"), firstString(block, "id"), input))
+			}
+		}
+	case "user":
+		for _, block := range contentBlocks(payload) {
+			if firstString(block, "type") != "tool_result" {
+				continue
+			}
+			isError, _ := block["is_error"].(bool)
+			events = append(events, Event{
+				Kind:      EventToolResult,
+				ToolUseID: firstString(block, "tool_use_id"),
+				Text:      strings.TrimSpace(flattenText(block["content"], 4)),
+				IsError:   isError,
+			})
+		}
+	case "result":
+		isError, _ := payload["is_error"].(bool)
+		subtype := firstString(payload, "subtype")
+		if isError || strings.HasPrefix(subtype, "error") {
+			text := firstString(payload, "result", "error")
+			if text == "" {
+				text = subtype
+			}
+			events = append(events, Event{Kind: EventError, Text: text})
+		}
+	}
+	return events
+}
+
+// decodeGeminiEvents handles Gemini CLI stream-json, which emits flat
+// message, tool_use, tool_result and error events.
+func decodeGeminiEvents(payload map[string]any) []Event {
+	switch eventType(payload) {
+	case "message":
+		if strings.EqualFold(firstString(payload, "role"), "assistant") {
+			if text := flattenText(payload["content"], 4); strings.TrimSpace(text) != "" {
+				return []Event{{Kind: EventAssistantText, Text: text}}
+			}
+		}
+	case "tool_use":
+		input, _ := payload["parameters"].(map[string]any)
+		return []Event{toolCallEvent(firstString(payload, "tool_name", "name"), firstString(payload, "tool_id", "id"), input)}
+	case "tool_result":
+		isError := strings.EqualFold(firstString(payload, "status"), "error")
+		text := firstString(payload, "output")
+		if errNode, ok := payload["error"].(map[string]any); ok {
+			isError = true
+			text = firstString(errNode, "message")
+		}
+		return []Event{{Kind: EventToolResult, ToolUseID: firstString(payload, "tool_id", "id"), Text: text, IsError: isError}}
+	case "error":
+		return []Event{{Kind: EventError, Text: firstString(payload, "message")}}
+	case "result":
+		if strings.EqualFold(firstString(payload, "status"), "error") {
+			text := "gemini reported an error"
+			if errNode, ok := payload["error"].(map[string]any); ok {
+				if message := firstString(errNode, "message"); message != "" {
+					text = message
+				}
+			}
+			return []Event{{Kind: EventError, Text: text}}
+		}
+	}
+	return nil
+}
+
+// decodeCursorEvents handles Cursor Agent stream-json. Tool calls arrive as
+// tool_call events whose payload is keyed by the call kind, for example
+// {"shellToolCall":{"args":{...}}}.
+func decodeCursorEvents(payload map[string]any) []Event {
+	switch eventType(payload) {
+	case "assistant":
+		var events []Event
+		for _, block := range contentBlocks(payload) {
+			if text := firstString(block, "text"); text != "" && firstString(block, "type") == "text" {
+				events = append(events, Event{Kind: EventAssistantText, Text: text})
+			}
+		}
+		return events
+	case "tool_call":
+		call, _ := payload["tool_call"].(map[string]any)
+		for key, raw := range call {
+			body, _ := raw.(map[string]any)
+			name := strings.TrimSuffix(key, "ToolCall")
+			id := firstString(payload, "call_id")
+			switch firstString(payload, "subtype") {
+			case "started":
+				args, _ := body["args"].(map[string]any)
+				return []Event{toolCallEvent(name, id, args)}
+			case "completed":
+				result, _ := body["result"].(map[string]any)
+				_, failed := result["error"]
+				if _, rejected := result["rejected"]; rejected {
+					failed = true
+				}
+				return []Event{{Kind: EventToolResult, ToolName: name, ToolUseID: id, Text: strings.TrimSpace(flattenText(result, 4)), IsError: failed}}
+			}
+		}
+	case "result":
+		if isError, _ := payload["is_error"].(bool); isError {
+			return []Event{{Kind: EventError, Text: firstString(payload, "result", "error")}}
+		}
+	}
+	return nil
+}
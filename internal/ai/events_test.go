@@ -0,0 +1,107 @@
+package ai
+
+import "testing"
+
+func decodeLines(decode eventDecoder, lines ...string) []Event {
+	var events []Event
+	parser := newStreamJSONParser(nil)
+	parser.decode = decode
+	parser.onEvent = func(event Event) { events = append(events, event) }
+	for _, line := range lines {
+		parser.Feed([]byte(line + "\n"))
+	}
+	parser.Close()
+	return events
+}
+
+func TestDecodeClaudeEvents(t *testing.T) {
+	events := decodeLines(decodeClaudeEvents,
+		`{"type":"system","subtype":"init","session_id":"s-1"}`,
+		`{"type":"assistant","message":{"id":"m1","content":[{"type":"text","text":"Reading the handler."},{"type":"tool_use","id":"toolu_1","name":"Read","input":{"file_path":"/repo/main.go"}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"toolu_1","content":[{"type":"text","text":"package main"}]}]}}`,
+		`{"type":"assistant","message":{"id":"m2","content":[{"type":"tool_use","id":"toolu_2","name":"Edit","input":{"file_path":"/repo/main.go","old_string":"a","new_string":"b"}}]}}`,
+		`{"type":"assistant","message":{"id":"m3","content":[{"type":"tool_use","id":"toolu_3","name":"Bash","input":{"command":"go test ./..."}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"toolu_3","content":"FAIL","is_error":true}]}}`,
+		`{"type":"result","subtype":"error_max_turns","is_error":true}`,
+	)
+
+	want := []EventKind{EventAssistantText, EventToolUse, EventToolResult, EventFileEdit, EventShellCommand, EventToolResult, EventError}
+	if len(events) != len(want) {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	for i, kind := range want {
+		if events[i].Kind != kind {
+			t.Fatalf("event %d: got %s want %s", i, events[i].Kind, kind)
+		}
+	}
+	if events[1].ToolName != "Read" || events[1].Path != "/repo/main.go" {
+		t.Fatalf("unexpected tool_use: %+v", events[1])
+	}
+	if events[2].ToolUseID != "toolu_1" || events[2].Text != "package main" {
+		t.Fatalf("unexpected tool_result: %+v", events[2])
+	}
+	if events[3].Path != "/repo/main.go" || events[3].Input["new_string"] != "b" {
+		t.Fatalf("unexpected file_edit: %+v", events[3])
+	}
+	if events[4].Command != "go test ./..." {
+		t.Fatalf("unexpected shell_command: %+v", events[4])
+	}
+	if !events[5].IsError || events[6].Text != "error_max_turns" {
+		t.Fatalf("unexpected error events: %+v %+v", events[5], events[6])
+	}
+}
+
+func TestDecodeGeminiEvents(t *testing.T) {
+	events := decodeLines(decodeGeminiEvents,
+		`{"type":"init","session_id":"g-1","model":"gemini-2.5-pro"}`,
+		`{"type":"message","role":"user","content":"fix it"}`,
+		`{"type":"message","role":"assistant","content":"On it","delta":true}`,
+		`{"type":"tool_use","tool_name":"write_file","tool_id":"t1","parameters":{"file_path":"a.txt","content":"x"}}`,
+		`{"type":"tool_result","tool_id":"t1","status":"success","output":"wrote a.txt"}`,
+		`{"type":"tool_use","tool_name":"run_shell_command","tool_id":"t2","parameters":{"command":"make"}}`,
+		`{"type":"tool_result","tool_id":"t2","status":"error","error":{"message":"exit 2"}}`,
+		`{"type":"error","severity":"warning","message":"loop detected"}`,
+	)
+
+	want := []EventKind{EventAssistantText, EventFileEdit, EventToolResult, EventShellCommand, EventToolResult, EventError}
+	if len(events) != len(want) {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	for i, kind := range want {
+		if events[i].Kind != kind {
+			t.Fatalf("event %d: got %s want %s", i, events[i].Kind, kind)
+		}
+	}
+	if events[1].Path != "a.txt" || events[3].Command != "make" {
+		t.Fatalf("unexpected tool calls: %+v %+v", events[1], events[3])
+	}
+	if !events[4].IsError || events[4].Text != "exit 2" || events[5].Text != "loop detected" {
+		t.Fatalf("unexpected errors: %+v %+v", events[4], events[5])
+	}
+}
+
+func TestDecodeCursorEvents(t *testing.T) {
+	events := decodeLines(decodeCursorEvents,
+		`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Running tests"}]}}`,
+		`{"type":"tool_call","subtype":"started","call_id":"c1","tool_call":{"shellToolCall":{"args":{"command":"npm test"}}}}`,
+		`{"type":"tool_call","subtype":"completed","call_id":"c1","tool_call":{"shellToolCall":{"args":{"command":"npm test"},"result":{"success":{"stdout":"ok"}}}}}`,
+		`{"type":"tool_call","subtype":"started","call_id":"c2","tool_call":{"editToolCall":{"args":{"path":"src/app.ts"}}}}`,
+		`{"type":"tool_call","subtype":"completed","call_id":"c2","tool_call":{"editToolCall":{"result":{"error":{"message":"conflict"}}}}}`,
+	)
+
+	want := []EventKind{EventAssistantText, EventShellCommand, EventToolResult, EventFileEdit, EventToolResult}
+	if len(events) != len(want) {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	for i, kind := range want {
+		if events[i].Kind != kind {
+			t.Fatalf("event %d: got %s want %s", i, events[i].Kind, kind)
+		}
+	}
+	if events[1].Command != "npm test" || events[3].Path != "src/app.ts" {
+		t.Fatalf("unexpected tool calls: %+v %+v", events[1], events[3])
+	}
+	if events[2].IsError || events[2].Text != "ok" || !events[4].IsError {
+		t.Fatalf("unexpected tool results: %+v %+v", events[2], events[4])
+	}
+}
@@ -31,19 +31,20 @@ func (g *Gemini) ExecuteStream(ctx context.Context, req ExecuteRequest, onChunk
 	}
 
 	streamArgs := buildGeminiHeadlessArgs(req, true, true)
-	streamOutput, conversationID, streamErr := runJSONStreamingCommand(ctx, req.Workdir, cmdName, streamArgs, onChunk)
+	streamOutput, conversationID, usage, streamErr := runJSONStreamingCommand(ctx, req, cmdName, streamArgs, onChunk, decodeGeminiEvents)
 	if streamErr == nil {
 		return &Result{
 			Success:        true,
 			Output:         strings.TrimSpace(streamOutput),
 			ConversationID: conversationID,
+			Usage:          usage,
 		}, nil
 	}
 
 	if looksLikeUnsupportedFlag(streamOutput) || streamOutput == "" {
 		// Retry without --yolo, which is not supported by all versions.
 		retryArgs := buildGeminiHeadlessArgs(req, true, false)
-		retryOutput, retryConversationID, retryErr := runJSONStreamingCommand(ctx, req.Workdir, cmdName, retryArgs, onChunk)
+		retryOutput, retryConversationID, retryUsage, retryErr := runJSONStreamingCommand(ctx, req, cmdName, retryArgs, onChunk, decodeGeminiEvents)
 		if retryErr == nil {
 			if retryConversationID == "" {
 				retryConversationID = conversationID
@@ -52,6 +53,7 @@ func (g *Gemini) ExecuteStream(ctx context.Context, req ExecuteRequest, onChunk
 				Success:        true,
 				Output:         strings.TrimSpace(retryOutput),
 				ConversationID: retryConversationID,
+				Usage:          retryUsage,
 			}, nil
 		}
 		if conversationID == "" {
@@ -69,6 +71,7 @@ func (g *Gemini) ExecuteStream(ctx context.Context, req ExecuteRequest, onChunk
 			Output:         strings.TrimSpace(plainOutput),
 			Error:          plainErr,
 			ConversationID: conversationID,
+			Usage:          usage,
 		}, plainErr
 	}
 
@@ -77,6 +80,7 @@ func (g *Gemini) ExecuteStream(ctx context.Context, req ExecuteRequest, onChunk
 		Output:         strings.TrimSpace(streamOutput),
 		Error:          streamErr,
 		ConversationID: conversationID,
+		Usage:          usage,
 	}, streamErr
 }
 
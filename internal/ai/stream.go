@@ -14,10 +14,21 @@ type streamJSONParser struct {
 	output         bytes.Buffer
 	conversationID string
 	onChunk        func(string)
+	usage          *usageTracker
+	onUsage        func(Usage)
+	decode         eventDecoder
+	onEvent        func(Event)
+	extractText    func(map[string]any) string
+	extractSession func(map[string]any) string
 }
 
 func newStreamJSONParser(onChunk func(string)) *streamJSONParser {
-	return &streamJSONParser{onChunk: onChunk}
+	return &streamJSONParser{
+		onChunk:        onChunk,
+		usage:          newUsageTracker(),
+		extractText:    extractStreamText,
+		extractSession: extractConversationID,
+	}
 }
 
 func (p *streamJSONParser) Feed(chunk []byte) {
@@ -65,10 +76,18 @@ func (p *streamJSONParser) processLine(line string) {
 	}
 
 	if p.conversationID == "" {
-		p.conversationID = extractConversationID(payload)
+		p.conversationID = p.extractSession(payload)
+	}
+	if p.usage.observe(payload) && p.onUsage != nil {
+		p.onUsage(p.usage.total())
+	}
+	if p.decode != nil && p.onEvent != nil {
+		for _, event := range p.decode(payload) {
+			p.onEvent(event)
+		}
 	}
 
-	text := extractStreamText(payload)
+	text := p.extractText(payload)
 	if strings.TrimSpace(text) == "" {
 		return
 	}
@@ -86,16 +105,28 @@ func (p *streamJSONParser) ConversationID() string {
 	return strings.TrimSpace(p.conversationID)
 }
 
-func runJSONStreamingCommand(ctx context.Context, workdir, cmdName string, args []string, onChunk func(string)) (output, conversationID string, err error) {
+func (p *streamJSONParser) Usage() Usage {
+	return p.usage.total()
+}
+
+func runJSONStreamingCommand(ctx context.Context, req ExecuteRequest, cmdName string, args []string, onChunk func(string), decode eventDecoder) (output, conversationID string, usage Usage, err error) {
 	parser := newStreamJSONParser(onChunk)
-	raw, err := proc.RunStreaming(ctx, workdir, cmdName, parser.Feed, args...)
+	parser.decode = decode
+	return runStreamParser(ctx, req, cmdName, args, parser)
+}
+
+// runStreamParser runs a command and feeds its stdout through parser.
+func runStreamParser(ctx context.Context, req ExecuteRequest, cmdName string, args []string, parser *streamJSONParser) (output, conversationID string, usage Usage, err error) {
+	parser.onUsage = req.OnUsage
+	parser.onEvent = req.OnEvent
+	raw, err := proc.RunStreaming(ctx, req.Workdir, cmdName, parser.Feed, args...)
 	parser.Close()
 
 	output = parser.Output()
 	if output == "" {
 		output = strings.TrimSpace(string(raw))
 	}
-	return output, parser.ConversationID(), err
+	return output, parser.ConversationID(), parser.Usage(), err
 }
 
 func runPlainStreamingCommand(ctx context.Context, workdir, cmdName string, args []string, onChunk func(string)) (string, error) {
@@ -51,3 +51,67 @@ func TestStreamJSONParserPreservesLeadingSpacesAcrossChunks(t *testing.T) {
 		t.Fatalf("unexpected streamed chunks: %+v", chunks)
 	}
 }
+
+func TestStreamJSONParserReportsClaudeUsage(t *testing.T) {
+	var reports []Usage
+	parser := newStreamJSONParser(nil)
+	parser.onUsage = func(u Usage) { reports = append(reports, u) }
+
+	parser.Feed([]byte(`{"type":"assistant","message":{"id":"msg_1","content":[{"type":"text","text":"a"}],"usage":{"input_tokens":10,"output_tokens":5,"cache_read_input_tokens":900}}}` + "\n"))
+	// A second content block of the same message repeats its usage.
+	parser.Feed([]byte(`{"type":"assistant","message":{"id":"msg_1","content":[{"type":"text","text":"b"}],"usage":{"input_tokens":10,"output_tokens":5,"cache_read_input_tokens":900}}}` + "\n"))
+	parser.Feed([]byte(`{"type":"assistant","message":{"id":"msg_2","content":[{"type":"text","text":"c"}],"usage":{"input_tokens":20,"output_tokens":7}}}` + "\n"))
+	parser.Feed([]byte(`{"type":"result","total_cost_usd":0.25,"usage":{"input_tokens":31,"output_tokens":12,"cache_read_input_tokens":900}}` + "\n"))
+	parser.Close()
+
+	if len(reports) != 3 {
+		t.Fatalf("unexpected usage reports: %+v", reports)
+	}
+	if got := reports[1].TotalTokens(); got != 42 {
+		t.Fatalf("unexpected running total: got %d want 42", got)
+	}
+	final := reports[2]
+	if final.TotalTokens() != 43 || final.CacheReadTokens != 900 || final.CostUSD != 0.25 {
+		t.Fatalf("unexpected final usage: %+v", final)
+	}
+}
+
+func TestStreamJSONParserReportsGeminiStats(t *testing.T) {
+	var last Usage
+	parser := newStreamJSONParser(nil)
+	parser.onUsage = func(u Usage) { last = u }
+
+	parser.Feed([]byte(`{"type":"message","role":"assistant","content":"done"}` + "\n"))
+	parser.Feed([]byte(`{"type":"result","status":"success","stats":{"total_tokens":150,"input_tokens":100,"output_tokens":50}}` + "\n"))
+	parser.Close()
+
+	if last.InputTokens != 100 || last.OutputTokens != 50 {
+		t.Fatalf("unexpected gemini usage: %+v", last)
+	}
+}
+
+func TestStreamJSONParserReportsRunMetadata(t *testing.T) {
+	parser := newStreamJSONParser(nil)
+	parser.Feed([]byte(`{"type":"system","subtype":"init","session_id":"sess-1","model":"claude-sonnet-4-5"}` + "\n"))
+	parser.Feed([]byte(`{"type":"assistant","message":{"id":"msg_1","model":"claude-haiku-4-5","content":[{"type":"text","text":"a"}],"usage":{"input_tokens":10,"output_tokens":5}}}` + "\n"))
+	parser.Feed([]byte(`{"type":"assistant","message":{"id":"msg_2","content":[{"type":"text","text":"b"}],"usage":{"input_tokens":10,"output_tokens":5}}}` + "\n"))
+	if got := parser.Usage(); got.Model != "claude-sonnet-4-5" || got.NumTurns != 2 {
+		t.Fatalf("unexpected running metadata: %+v", got)
+	}
+
+	parser.Feed([]byte(`{"type":"result","duration_ms":4200,"num_turns":3,"total_cost_usd":0.01,"usage":{"input_tokens":20,"output_tokens":10}}` + "\n"))
+	parser.Close()
+
+	got := parser.Usage()
+	if got.Model != "claude-sonnet-4-5" || got.DurationMS != 4200 || got.NumTurns != 3 || got.CostUSD != 0.01 {
+		t.Fatalf("unexpected final metadata: %+v", got)
+	}
+
+	gemini := newStreamJSONParser(nil)
+	gemini.Feed([]byte(`{"type":"init","session_id":"g-1","model":"gemini-2.5-pro"}` + "\n"))
+	gemini.Feed([]byte(`{"type":"result","status":"success","stats":{"input_tokens":100,"output_tokens":50,"duration_ms":900}}` + "\n"))
+	gemini.Close()
+	if got := gemini.Usage(); got.Model != "gemini-2.5-pro" || got.DurationMS != 900 || got.InputTokens != 100 {
+		t.Fatalf("unexpected gemini metadata: %+v", got)
+	}
+}
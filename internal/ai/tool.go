@@ -30,6 +30,13 @@ type ExecuteRequest struct {
 	Prompt         string
 	Model          string
 	ConversationID string
+	// OnUsage, when set, receives cumulative usage each time a tool's
+	// streamed output reports new token or cost figures.
+	OnUsage func(Usage)
+	// OnEvent, when set, receives structured transcript events (text, tool
+	// calls, file edits, shell commands, errors) decoded from the tool's
+	// stream-json output.
+	OnEvent func(Event)
 }
 
 // Result contains the AI execution result
@@ -38,6 +45,9 @@ type Result struct {
 	Output         string
 	Error          error
 	ConversationID string
+	// Usage is the token, cost and model accounting parsed from the tool's
+	// structured output. It is zero for tools without stream-json support.
+	Usage Usage
 }
 
 // GetTool returns an AI tool by name
@@ -52,6 +62,9 @@ func GetTool(name string) (Tool, error) {
 	case "aider":
 		return &Aider{}, nil
 	default:
+		if def, ok := lookupCustomTool(normalizeToolName(name)); ok {
+			return &CustomTool{def: def}, nil
+		}
 		return nil, fmt.Errorf("unknown AI tool: %s", name)
 	}
 }
@@ -83,9 +96,10 @@ func DetectTool(preferred string) (Tool, error) {
 	return nil, fmt.Errorf("no AI tool available")
 }
 
-// AvailableToolNames returns canonical tool names supported by Fog.
+// AvailableToolNames returns canonical tool names supported by Fog: the
+// built-in adapters followed by custom adapters from $FOG_HOME/tools.
 func AvailableToolNames() []string {
-	return []string{"cursor", "claude", "gemini", "aider"}
+	return append([]string{"cursor", "claude", "gemini", "aider"}, customToolNames()...)
 }
 
 // ExecuteWithOptionalStream runs a tool and streams chunks when supported.
@@ -0,0 +1,173 @@
+package ai
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Usage is the token and cost accounting a tool reports for one execution.
+// Values are cumulative for the execution so far.
+type Usage struct {
+	InputTokens         int64
+	OutputTokens        int64
+	CacheReadTokens     int64
+	CacheCreationTokens int64
+	CostUSD             float64
+
+	// Model is the model the tool reported serving the execution with.
+	Model string
+	// DurationMS is the tool's own wall time, when reported.
+	DurationMS int64
+	// NumTurns counts assistant turns (model round trips).
+	NumTurns int
+}
+
+// TotalTokens returns input (including cache writes) plus output tokens.
+// Cache reads are excluded: the cached prefix is re-read on every turn and
+// would dwarf the real usage.
+func (u Usage) TotalTokens() int64 {
+	return u.InputTokens + u.CacheCreationTokens + u.OutputTokens
+}
+
+func (u Usage) add(other Usage) Usage {
+	return Usage{
+		InputTokens:         u.InputTokens + other.InputTokens,
+		OutputTokens:        u.OutputTokens + other.OutputTokens,
+		CacheReadTokens:     u.CacheReadTokens + other.CacheReadTokens,
+		CacheCreationTokens: u.CacheCreationTokens + other.CacheCreationTokens,
+		CostUSD:             u.CostUSD + other.CostUSD,
+	}
+}
+
+// usageTracker accumulates usage from stream-json events.
+//
+// Claude Code reports usage per assistant message (repeated for every
+// content block of the same message) and a final authoritative total on the
+// "result" event together with total_cost_usd, duration_ms and num_turns.
+// Gemini reports totals under "stats" on its result event. Both announce the
+// model on their init event.
+type usageTracker struct {
+	perMessage map[string]Usage
+	order      []string
+	final      *Usage
+
+	model      string
+	durationMS int64
+	numTurns   int
+}
+
+func newUsageTracker() *usageTracker {
+	return &usageTracker{perMessage: make(map[string]Usage)}
+}
+
+// observe folds one stream event into the tracker and reports whether the
+// running total changed.
+func (t *usageTracker) observe(payload map[string]any) bool {
+	before := t.total()
+
+	message, _ := payload["message"].(map[string]any)
+	if t.model == "" {
+		t.model = firstString(payload, "model")
+		if t.model == "" && message != nil {
+			t.model = firstString(message, "model")
+		}
+	}
+
+	eventType := strings.ToLower(strings.TrimSpace(firstString(payload, "type", "event_type", "event")))
+	if eventType == "result" {
+		stats, _ := payload["stats"].(map[string]any)
+		if value, ok := firstNumber(payload, "duration_ms"); ok {
+			t.durationMS = int64(value)
+		} else if value, ok := firstNumber(stats, "duration_ms"); ok {
+			t.durationMS = int64(value)
+		}
+		if value, ok := firstNumber(payload, "num_turns"); ok {
+			t.numTurns = int(value)
+		}
+
+		usage, ok := usageFromMap(payload["usage"])
+		if !ok {
+			usage, ok = usageFromMap(payload["stats"])
+		}
+		cost, hasCost := firstNumber(payload, "total_cost_usd", "cost_usd")
+		if ok || hasCost {
+			if !ok {
+				usage = t.total()
+			}
+			if hasCost {
+				usage.CostUSD = cost
+			}
+			t.final = &usage
+		}
+		return t.total() != before
+	}
+
+	if message == nil {
+		return t.total() != before
+	}
+	usage, ok := usageFromMap(message["usage"])
+	if !ok {
+		return t.total() != before
+	}
+	id := strings.TrimSpace(firstString(message, "id"))
+	if id == "" {
+		id = "#" + strconv.Itoa(len(t.order))
+	}
+	if _, seen := t.perMessage[id]; !seen {
+		t.order = append(t.order, id)
+	}
+	t.perMessage[id] = usage
+	return t.total() != before
+}
+
+func (t *usageTracker) total() Usage {
+	var sum Usage
+	if t.final != nil {
+		sum = *t.final
+	} else {
+		for _, id := range t.order {
+			sum = sum.add(t.perMessage[id])
+		}
+	}
+	sum.Model = t.model
+	sum.DurationMS = t.durationMS
+	sum.NumTurns = t.numTurns
+	if sum.NumTurns == 0 {
+		sum.NumTurns = len(t.order)
+	}
+	return sum
+}
+
+func usageFromMap(raw any) (Usage, bool) {
+	node, ok := raw.(map[string]any)
+	if !ok {
+		return Usage{}, false
+	}
+	var usage Usage
+	found := false
+	assign := func(dst *int64, keys ...string) {
+		if value, ok := firstNumber(node, keys...); ok {
+			*dst = int64(value)
+			found = true
+		}
+	}
+	assign(&usage.InputTokens, "input_tokens", "inputTokens", "prompt_tokens", "promptTokenCount")
+	assign(&usage.OutputTokens, "output_tokens", "outputTokens", "completion_tokens", "candidatesTokenCount")
+	assign(&usage.CacheReadTokens, "cache_read_input_tokens", "cached_tokens", "cachedContentTokenCount")
+	assign(&usage.CacheCreationTokens, "cache_creation_input_tokens")
+	return usage, found
+}
+
+func firstNumber(payload map[string]any, keys ...string) (float64, bool) {
+	for _, key := range keys {
+		switch value := payload[key].(type) {
+		case float64:
+			return value, true
+		case int:
+			return float64(value), true
+		case int64:
+			return float64(value), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,292 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/ai"
+	"github.com/darkLord19/foglet/internal/runner"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// CreateEnsembleRequest is the payload for POST /api/ensembles.
+type CreateEnsembleRequest struct {
+	SourceSessionID string                   `json:"source_session_id"`
+	Prompt          string                   `json:"prompt"`
+	BranchName      string                   `json:"branch_name,omitempty"`
+	Variants        []EnsembleVariantRequest `json:"variants"`
+	SetupCmd        string                   `json:"setup_cmd,omitempty"`
+	Validate        *bool                    `json:"validate,omitempty"`
+	ValidateCmd     string                   `json:"validate_cmd,omitempty"`
+	BaseBranch      string                   `json:"base_branch,omitempty"`
+	Priority        int                      `json:"priority,omitempty"`
+	Timeout         string                   `json:"timeout,omitempty"`
+	MaxTokens       int64                    `json:"max_tokens,omitempty"`
+	MaxCostUSD      float64                  `json:"max_cost_usd,omitempty"`
+	FixAttempts     int                      `json:"fix_attempts,omitempty"`
+}
+
+// EnsembleVariantRequest is one tool/model combination in an ensemble.
+type EnsembleVariantRequest struct {
+	Tool  string `json:"tool"`
+	Model string `json:"model,omitempty"`
+}
+
+// PromoteEnsembleRequest is the payload for POST /api/ensembles/{id}/promote.
+type PromoteEnsembleRequest struct {
+	SessionID string `json:"session_id"`
+}
+
+type ensembleDetailResponse struct {
+	state.Ensemble
+	Results []ensembleMemberResult `json:"results"`
+}
+
+// ensembleMemberResult is one column of the side-by-side comparison.
+type ensembleMemberResult struct {
+	state.EnsembleMember
+	Session    *state.Session `json:"session,omitempty"`
+	LatestRun  *state.Run     `json:"latest_run,omitempty"`
+	Validation string         `json:"validation,omitempty"`
+	DurationMS int64          `json:"duration_ms"`
+	Stat       string         `json:"stat"`
+	Patch      string         `json:"patch"`
+	DiffError  string         `json:"diff_error,omitempty"`
+}
+
+func (s *Server) handleEnsembles(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.listEnsembles(w)
+	case http.MethodPost:
+		s.createEnsemble(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleEnsembleDetail(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/ensembles/"), "/")
+	parts := strings.Split(path, "/")
+	ensembleID := strings.TrimSpace(parts[0])
+	if ensembleID == "" {
+		http.Error(w, "ensemble ID required", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		s.getEnsemble(w, ensembleID)
+	case len(parts) == 2 && parts[1] == "promote" && r.Method == http.MethodPost:
+		s.promoteEnsemble(w, r, ensembleID)
+	case len(parts) <= 2:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) listEnsembles(w http.ResponseWriter) {
+	ensembles, err := s.runner.ListEnsembles()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(ensembles)
+}
+
+func (s *Server) createEnsemble(w http.ResponseWriter, r *http.Request) {
+	var req CreateEnsembleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.SourceSessionID = strings.TrimSpace(req.SourceSessionID)
+	req.Prompt = strings.TrimSpace(req.Prompt)
+	switch {
+	case req.SourceSessionID == "":
+		http.Error(w, "source_session_id is required", http.StatusBadRequest)
+		return
+	case req.Prompt == "":
+		http.Error(w, "prompt is required", http.StatusBadRequest)
+		return
+	case len(req.Variants) < 2:
+		http.Error(w, "at least two variants are required", http.StatusBadRequest)
+		return
+	}
+	if err := validateShellCommand(req.ValidateCmd); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limits, err := parseRunLimits(req.Timeout, req.MaxTokens, req.MaxCostUSD)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateFixAttempts(req.FixAttempts, req.Validate != nil && *req.Validate, req.ValidateCmd); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sourceSession, found, err := s.runner.GetSession(req.SourceSessionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+
+	baseBranch, err := s.resolveBranchName(sourceSession.WorktreePath, req.BranchName, req.Prompt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	variants := make([]runner.EnsembleVariant, 0, len(req.Variants))
+	for _, variant := range req.Variants {
+		tool := strings.TrimSpace(variant.Tool)
+		if tool == "" {
+			http.Error(w, "variant tool is required", http.StatusBadRequest)
+			return
+		}
+		if _, err := ai.GetTool(tool); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		model := strings.TrimSpace(variant.Model)
+		suffix := slugifyPrompt(strings.TrimSpace(tool + " " + model))
+		branch, err := uniqueBranchName(sourceSession.WorktreePath, baseBranch+"-"+suffix)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, prior := range variants {
+			if prior.Branch == branch {
+				http.Error(w, "duplicate variant "+tool+" "+model, http.StatusBadRequest)
+				return
+			}
+		}
+		variants = append(variants, runner.EnsembleVariant{Tool: tool, Model: model, Branch: branch})
+	}
+
+	opts := runner.EnsembleOptions{
+		Prompt:      req.Prompt,
+		Variants:    variants,
+		SetupCmd:    strings.TrimSpace(req.SetupCmd),
+		ValidateCmd: strings.TrimSpace(req.ValidateCmd),
+		BaseBranch:  strings.TrimSpace(req.BaseBranch),
+		Priority:    req.Priority,
+		Timeout:     limits.Timeout,
+		MaxTokens:   limits.MaxTokens,
+		MaxCostUSD:  limits.MaxCostUSD,
+		FixAttempts: req.FixAttempts,
+	}
+	if req.Validate != nil {
+		opts.HasValidate = true
+		opts.Validate = *req.Validate
+	}
+	ensemble, err := s.runner.StartEnsemble(sourceSession.ID, opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(ensemble)
+}
+
+func (s *Server) getEnsemble(w http.ResponseWriter, ensembleID string) {
+	ensemble, found, err := s.runner.GetEnsemble(ensembleID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "ensemble not found", http.StatusNotFound)
+		return
+	}
+
+	repo, _, err := s.stateStore.GetRepoByName(ensemble.RepoName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	results := make([]ensembleMemberResult, 0, len(ensemble.Members))
+	for _, member := range ensemble.Members {
+		results = append(results, s.ensembleMemberResult(member, repo))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(ensembleDetailResponse{
+		Ensemble: ensemble,
+		Results:  results,
+	})
+}
+
+func (s *Server) ensembleMemberResult(member state.EnsembleMember, repo state.Repo) ensembleMemberResult {
+	result := ensembleMemberResult{EnsembleMember: member}
+	session, found, err := s.stateStore.GetSession(member.SessionID)
+	if err != nil || !found {
+		result.DiffError = "session not found"
+		return result
+	}
+	result.Session = &session
+
+	if latest, found, err := s.stateStore.GetLatestRun(session.ID); err == nil && found {
+		result.LatestRun = &latest
+		end := time.Now().UTC()
+		if latest.CompletedAt != nil {
+			end = *latest.CompletedAt
+		}
+		result.DurationMS = end.Sub(latest.CreatedAt).Milliseconds()
+		if event, found, err := s.stateStore.GetLatestRunEvent(latest.ID, "validate"); err == nil && found {
+			result.Validation = event.Data
+		}
+	}
+
+	diff, err := s.runner.SessionDiff(context.Background(), session, repo)
+	if err != nil {
+		result.DiffError = err.Error()
+		return result
+	}
+	result.Stat = diff.Stat
+	result.Patch = diff.Patch
+	return result
+}
+
+func (s *Server) promoteEnsemble(w http.ResponseWriter, r *http.Request, ensembleID string) {
+	var req PromoteEnsembleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.SessionID = strings.TrimSpace(req.SessionID)
+	if req.SessionID == "" {
+		http.Error(w, "session_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if _, found, err := s.runner.GetEnsemble(ensembleID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if !found {
+		http.Error(w, "ensemble not found", http.StatusNotFound)
+		return
+	}
+
+	ensemble, err := s.runner.PromoteEnsembleWinner(ensembleID, req.SessionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(ensemble)
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestHandleCreateEnsembleValidatesRequest(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+
+	cases := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "source", body: `{"prompt":"p","variants":[{"tool":"claude"},{"tool":"codex"}]}`, want: http.StatusBadRequest},
+		{name: "variants", body: `{"source_session_id":"session-1","prompt":"p","variants":[{"tool":"claude"}]}`, want: http.StatusBadRequest},
+		{name: "tool", body: `{"source_session_id":"session-1","prompt":"p","variants":[{"tool":"claude"},{"tool":"nope"}]}`, want: http.StatusBadRequest},
+		{name: "missing", body: `{"source_session_id":"missing","prompt":"p","variants":[{"tool":"claude"},{"tool":"codex"}]}`, want: http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/ensembles", bytes.NewBufferString(tc.body))
+			w := httptest.NewRecorder()
+			srv.handleEnsembles(w, req)
+			if w.Code != tc.want {
+				t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, tc.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleEnsembleDetailAndPromote(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+	seedEnsembleFixture(t, srv)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/ensembles/ens-1", nil)
+	w := httptest.NewRecorder()
+	srv.handleEnsembleDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var detail ensembleDetailResponse
+	if err := json.NewDecoder(w.Body).Decode(&detail); err != nil {
+		t.Fatalf("decode ensemble failed: %v", err)
+	}
+	if len(detail.Results) != 2 {
+		t.Fatalf("unexpected results: %+v", detail.Results)
+	}
+	first := detail.Results[0]
+	if first.SessionID != "session-1" || first.Validation != "passed" || first.LatestRun == nil || first.Session == nil {
+		t.Fatalf("unexpected first result: %+v", first)
+	}
+	second := detail.Results[1]
+	if second.Validation != "failed" || second.LatestRun == nil || second.LatestRun.State != "FAILED" {
+		t.Fatalf("unexpected second result: %+v", second)
+	}
+	if second.DurationMS < 90000 || second.DurationMS > 100000 {
+		t.Fatalf("unexpected second duration: %d", second.DurationMS)
+	}
+	// The fixture worktree is not a git checkout, so the diff is reported per member.
+	if first.DiffError == "" {
+		t.Fatal("expected diff error for fixture worktree")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/ensembles/ens-1/promote", bytes.NewBufferString(`{"session_id":"session-x"}`))
+	w = httptest.NewRecorder()
+	srv.handleEnsembleDetail(w, req)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("non-member promotion: got %d want %d body=%s", w.Code, http.StatusConflict, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/ensembles/ens-1/promote", bytes.NewBufferString(`{"session_id":"session-1"}`))
+	w = httptest.NewRecorder()
+	srv.handleEnsembleDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var promoted state.Ensemble
+	if err := json.NewDecoder(w.Body).Decode(&promoted); err != nil {
+		t.Fatalf("decode promoted ensemble failed: %v", err)
+	}
+	if promoted.Status != state.EnsembleStatusPromoted || promoted.WinnerSessionID != "session-1" {
+		t.Fatalf("unexpected promoted ensemble: %+v", promoted)
+	}
+	loser, _, err := srv.stateStore.GetSession("session-2")
+	if err != nil {
+		t.Fatalf("get losing session failed: %v", err)
+	}
+	if loser.ArchivedAt == nil {
+		t.Fatal("expected losing session to be archived")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/ensembles", nil)
+	w = httptest.NewRecorder()
+	srv.handleEnsembles(w, req)
+	var ensembles []state.Ensemble
+	if err := json.NewDecoder(w.Body).Decode(&ensembles); err != nil {
+		t.Fatalf("decode ensembles failed: %v", err)
+	}
+	if len(ensembles) != 1 || ensembles[0].WinnerSessionID != "session-1" {
+		t.Fatalf("unexpected ensembles: %+v", ensembles)
+	}
+}
+
+func TestHandleEnsembleDetailNotFound(t *testing.T) {
+	srv := newTestServer(t)
+	req := httptest.NewRequest(http.MethodGet, "/api/ensembles/missing", nil)
+	w := httptest.NewRecorder()
+	srv.handleEnsembleDetail(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status: got %d want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func seedEnsembleFixture(t *testing.T, srv *Server) {
+	t.Helper()
+	now := time.Now().UTC()
+	if err := srv.stateStore.CreateSession(state.Session{
+		ID:           "session-2",
+		RepoName:     "acme/api",
+		Branch:       "team/add-otp-login-codex",
+		WorktreePath: "/tmp/acme-api/worktree-2",
+		Tool:         "codex",
+		Status:       "FAILED",
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	if err := srv.stateStore.CreateRun(state.Run{
+		ID:           "run-2",
+		SessionID:    "session-2",
+		Prompt:       "add otp login",
+		WorktreePath: "/tmp/acme-api/worktree-2",
+		State:        "CREATED",
+		CreatedAt:    now.Add(-90 * time.Second),
+		UpdatedAt:    now,
+	}); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+	if err := srv.stateStore.CompleteRun("run-2", "FAILED", "", "", "validate: exit status 1"); err != nil {
+		t.Fatalf("complete run failed: %v", err)
+	}
+	if err := srv.stateStore.AppendRunEvent(state.RunEvent{RunID: "run-2", Type: "validate", Message: "Validation failed", Data: "failed"}); err != nil {
+		t.Fatalf("append validate event failed: %v", err)
+	}
+	if err := srv.stateStore.AppendRunEvent(state.RunEvent{RunID: "run-1", Type: "validate", Message: "Validation passed", Data: "passed"}); err != nil {
+		t.Fatalf("append validate event failed: %v", err)
+	}
+
+	if err := srv.stateStore.CreateEnsemble(state.Ensemble{
+		ID:              "ens-1",
+		RepoName:        "acme/api",
+		SourceSessionID: "session-0",
+		Prompt:          "add otp login",
+		Status:          state.EnsembleStatusRunning,
+	}); err != nil {
+		t.Fatalf("create ensemble failed: %v", err)
+	}
+	for _, member := range []state.EnsembleMember{
+		{SessionID: "session-1", Tool: "claude", Model: "sonnet"},
+		{SessionID: "session-2", Tool: "codex"},
+	} {
+		if err := srv.stateStore.AddEnsembleMember("ens-1", member); err != nil {
+			t.Fatalf("add ensemble member failed: %v", err)
+		}
+	}
+}
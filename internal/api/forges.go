@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/forge"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// SaveForgeRequest is the payload for POST /api/forges. Token is write-only
+// and kept when omitted on update.
+type SaveForgeRequest struct {
+	Host    string `json:"host"`
+	Kind    string `json:"kind"`
+	BaseURL string `json:"base_url,omitempty"`
+	Token   string `json:"token,omitempty"`
+}
+
+func (s *Server) handleForges(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		hosts, err := s.stateStore.ListForgeHosts()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(hosts)
+	case http.MethodPost:
+		var req SaveForgeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.saveForge(w, req)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleForgeDetail(w http.ResponseWriter, r *http.Request) {
+	host := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/forges/"), "/")
+	if host == "" || strings.Contains(host, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := s.stateStore.DeleteForgeHost(host); err != nil {
+		status := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "deleted", "host": host})
+}
+
+func (s *Server) saveForge(w http.ResponseWriter, req SaveForgeRequest) {
+	kind := strings.ToLower(strings.TrimSpace(req.Kind))
+	if !forge.ValidKind(kind) {
+		http.Error(w, fmt.Sprintf("invalid forge kind %q (use github, gitlab or gitea)", req.Kind), http.StatusBadRequest)
+		return
+	}
+	host := state.ForgeHost{Host: req.Host, Kind: kind, BaseURL: req.BaseURL}
+	if err := s.stateStore.SetForgeHost(host, req.Token); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	saved, _, err := s.stateStore.GetForgeHost(req.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(saved)
+}
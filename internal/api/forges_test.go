@@ -0,0 +1,102 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/ghcli"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestHandleForgesLifecycle(t *testing.T) {
+	srv := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/forges", bytes.NewBufferString(`{"host":"git.acme.dev","kind":"bitbucket"}`))
+	w := httptest.NewRecorder()
+	srv.handleForges(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown kind, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/forges", bytes.NewBufferString(`{"host":"git.acme.dev","kind":"gitea","token":"secret"}`))
+	w = httptest.NewRecorder()
+	srv.handleForges(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("save forge failed: %d body=%s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "secret") {
+		t.Fatalf("token leaked in response: %s", w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/forges", nil)
+	w = httptest.NewRecorder()
+	srv.handleForges(w, req)
+	var hosts []state.ForgeHost
+	if err := json.NewDecoder(w.Body).Decode(&hosts); err != nil {
+		t.Fatalf("decode forges failed: %v", err)
+	}
+	if len(hosts) != 1 || hosts[0].Kind != "gitea" || !hosts[0].HasToken {
+		t.Fatalf("unexpected forges: %+v", hosts)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/forges/git.acme.dev", nil)
+	w = httptest.NewRecorder()
+	srv.handleForgeDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("delete forge failed: %d body=%s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	srv.handleForgeDetail(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 on second delete, got %d", w.Code)
+	}
+}
+
+func TestHandleDiscoverReposFromGitea(t *testing.T) {
+	gitea := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.RequestURI() {
+		case "/api/v1/user":
+			_, _ = io.WriteString(w, `{"login":"me"}`)
+		case "/api/v1/user/repos?limit=50&page=1":
+			_, _ = io.WriteString(w, `[{"name":"api","full_name":"acme/api","html_url":"https://git.acme.dev/acme/api","default_branch":"main","owner":{"login":"acme"}}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(gitea.Close)
+
+	srv := newTestServer(t)
+	if err := srv.stateStore.SetForgeHost(state.ForgeHost{Host: "git.acme.dev", Kind: "gitea", BaseURL: gitea.URL}, "secret"); err != nil {
+		t.Fatalf("set forge host failed: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/repos/discover?host=git.acme.dev", nil)
+	w := httptest.NewRecorder()
+	srv.handleDiscoverRepos(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("discover failed: %d body=%s", w.Code, w.Body.String())
+	}
+	var repos []ghcli.Repo
+	if err := json.NewDecoder(w.Body).Decode(&repos); err != nil {
+		t.Fatalf("decode repos failed: %v", err)
+	}
+	if len(repos) != 1 || repos[0].NameWithOwner != "acme/api" {
+		t.Fatalf("unexpected repos: %+v", repos)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/repos/import", bytes.NewBufferString(`{"host":"git.acme.dev","repos":["acme/web"]}`))
+	w = httptest.NewRecorder()
+	srv.handleImportRepos(w, req)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "not accessible on git.acme.dev") {
+		t.Fatalf("expected inaccessible repo error, got %d body=%s", w.Code, w.Body.String())
+	}
+}
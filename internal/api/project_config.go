@@ -0,0 +1,32 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+
+	"github.com/darkLord19/foglet/internal/projectcfg"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// RepoConfigResponse is a repo's project file as Fog resolved it.
+type RepoConfigResponse struct {
+	Repo string `json:"repo"`
+	// File is the project file name, empty when the repo has none.
+	File   string            `json:"file,omitempty"`
+	Config projectcfg.Config `json:"config"`
+}
+
+func (s *Server) getRepoConfig(w http.ResponseWriter, repo state.Repo) {
+	cfg, err := projectcfg.Load(repo.BaseWorktreePath)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	resp := RepoConfigResponse{Repo: repo.Name, Config: cfg}
+	if cfg.Path != "" {
+		resp.File = filepath.Base(cfg.Path)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestHandleRepoConfig(t *testing.T) {
+	srv := newTestServer(t)
+	base := t.TempDir()
+	if _, err := srv.stateStore.UpsertRepo(state.Repo{
+		Name:             "acme/api",
+		URL:              "https://github.com/acme/api.git",
+		Host:             "github.com",
+		BarePath:         "/tmp/acme/api/repo.git",
+		BaseWorktreePath: base,
+	}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/repos/acme/api/config", nil)
+		w := httptest.NewRecorder()
+		srv.handleRepoDetail(w, req)
+		return w
+	}
+
+	w := get()
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status without project file: %d body=%s", w.Code, w.Body.String())
+	}
+	var resp RepoConfigResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response failed: %v", err)
+	}
+	if resp.File != "" || resp.Config.Setup != "" {
+		t.Fatalf("expected empty config, got %+v", resp)
+	}
+
+	content := "setup: go mod download\ntools:\n  - claude\npr:\n  template: |\n    ## Summary\n"
+	if err := os.WriteFile(filepath.Join(base, ".fog.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write project file failed: %v", err)
+	}
+	w = get()
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d body=%s", w.Code, w.Body.String())
+	}
+	resp = RepoConfigResponse{}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response failed: %v", err)
+	}
+	if resp.File != ".fog.yaml" || resp.Config.Setup != "go mod download" || resp.Config.PR.Template != "## Summary" {
+		t.Fatalf("unexpected config: %+v", resp)
+	}
+
+	if err := os.WriteFile(filepath.Join(base, ".fog.yaml"), []byte("setup: [unterminated\n"), 0o644); err != nil {
+		t.Fatalf("write project file failed: %v", err)
+	}
+	if w = get(); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for malformed project file, got %d", w.Code)
+	}
+}
+
+func TestCreateSessionAppliesProjectModelToDefaultTool(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tool script requires a POSIX shell")
+	}
+	binDir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		"echo changed > model.txt\n" +
+		`echo '{"type":"message","role":"assistant","content":"done"}'` + "\n"
+	if err := os.WriteFile(filepath.Join(binDir, "claude"), []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake claude failed: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	srv := newTestServer(t)
+	repoPath, defaultBranch := initTestGitRepoWithFeatureBranch(t)
+	if err := os.WriteFile(filepath.Join(repoPath, ".fog.yaml"), []byte("tools: [claude, gemini]\nmodel: opus\n"), 0o644); err != nil {
+		t.Fatalf("write project file failed: %v", err)
+	}
+	if _, err := srv.stateStore.UpsertRepo(state.Repo{
+		Name:             "acme/api",
+		URL:              "https://github.com/acme/api.git",
+		Host:             "github.com",
+		BarePath:         filepath.Join(repoPath, ".git"),
+		BaseWorktreePath: repoPath,
+		DefaultBranch:    defaultBranch,
+	}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+
+	create := func(body string) state.Session {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/sessions", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		srv.handleSessions(w, req)
+		if w.Code/100 != 2 {
+			t.Fatalf("create session failed: %d body=%s", w.Code, w.Body.String())
+		}
+		var resp createSessionResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode response failed: %v", err)
+		}
+		return resp.Session
+	}
+
+	// The desktop always names a tool; naming the project's default tool
+	// still picks up the project model.
+	if got := create(`{"repo":"acme/api","prompt":"add model.txt","tool":"claude","branch_name":"fog/model-default","async":false}`); got.Model != "opus" {
+		t.Fatalf("expected project model for the default tool, got %q", got.Model)
+	}
+	if got := create(`{"repo":"acme/api","prompt":"add model.txt","tool":"claude","model":"haiku","branch_name":"fog/model-request","async":false}`); got.Model != "haiku" {
+		t.Fatalf("expected request model to win, got %q", got.Model)
+	}
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// SetRepoEnvRequest sets one env var for a repo's runs.
+type SetRepoEnvRequest struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Secret bool   `json:"secret"`
+}
+
+// handleRepoDetail routes /api/repos/{name}/... where the repo name itself
+// contains a slash (owner/repo).
+func (s *Server) handleRepoDetail(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/repos/"), "/")
+	parts := strings.Split(path, "/")
+
+	repo, rest, err := s.resolveRepoPath(parts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if repo == nil {
+		http.Error(w, "repo not found", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case len(rest) == 1 && rest[0] == "config" && r.Method == http.MethodGet:
+		s.getRepoConfig(w, *repo)
+	case len(rest) == 1 && rest[0] == "env" && r.Method == http.MethodGet:
+		s.listRepoEnv(w, repo.Name)
+	case len(rest) == 1 && rest[0] == "env" && r.Method == http.MethodPut:
+		s.setRepoEnv(w, r, repo.Name)
+	case len(rest) == 2 && rest[0] == "env" && r.Method == http.MethodDelete:
+		s.unsetRepoEnv(w, repo.Name, rest[1])
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+// resolveRepoPath finds the longest leading run of path parts naming a
+// managed repo and returns it with the remaining parts.
+func (s *Server) resolveRepoPath(parts []string) (*state.Repo, []string, error) {
+	for i := len(parts); i > 0; i-- {
+		repo, found, err := s.stateStore.GetRepoByName(strings.Join(parts[:i], "/"))
+		if err != nil {
+			return nil, nil, err
+		}
+		if found {
+			return &repo, parts[i:], nil
+		}
+	}
+	return nil, nil, nil
+}
+
+func (s *Server) listRepoEnv(w http.ResponseWriter, repoName string) {
+	vars, err := s.stateStore.ListRepoEnv(repoName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	out := make([]state.RepoEnvVar, 0, len(vars))
+	for _, v := range vars {
+		out = append(out, maskRepoEnvVar(v))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func (s *Server) setRepoEnv(w http.ResponseWriter, r *http.Request, repoName string) {
+	var req SetRepoEnvRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.stateStore.SetRepoEnv(repoName, req.Name, req.Value, req.Secret); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(maskRepoEnvVar(state.RepoEnvVar{
+		Name:   strings.TrimSpace(req.Name),
+		Value:  req.Value,
+		Secret: req.Secret,
+	}))
+}
+
+func (s *Server) unsetRepoEnv(w http.ResponseWriter, repoName, name string) {
+	if err := s.stateStore.UnsetRepoEnv(repoName, name); err != nil {
+		status := http.StatusBadRequest
+		if strings.Contains(err.Error(), "not found") {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "deleted", "name": name})
+}
+
+// maskRepoEnvVar hides secret values; they are write-only over the API.
+func maskRepoEnvVar(v state.RepoEnvVar) state.RepoEnvVar {
+	if v.Secret {
+		v.Value = ""
+	}
+	return v
+}
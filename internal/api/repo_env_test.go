@@ -0,0 +1,80 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestHandleRepoEnvLifecycle(t *testing.T) {
+	srv := newTestServer(t)
+	if _, err := srv.stateStore.UpsertRepo(state.Repo{
+		Name:             "acme/api",
+		URL:              "https://github.com/acme/api.git",
+		Host:             "github.com",
+		BarePath:         "/tmp/acme/api/repo.git",
+		BaseWorktreePath: "/tmp/acme/api/base",
+	}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+
+	for _, body := range []string{
+		`{"name":"API_KEY","value":"sk-secret","secret":true}`,
+		`{"name":"DATABASE_URL","value":"postgres://localhost/test"}`,
+	} {
+		req := httptest.NewRequest(http.MethodPut, "/api/repos/acme/api/env", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		srv.handleRepoDetail(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("set env failed: %d body=%s", w.Code, w.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/repos/acme/api/env", nil)
+	w := httptest.NewRecorder()
+	srv.handleRepoDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("list env failed: %d body=%s", w.Code, w.Body.String())
+	}
+	var vars []state.RepoEnvVar
+	if err := json.NewDecoder(w.Body).Decode(&vars); err != nil {
+		t.Fatalf("decode env failed: %v", err)
+	}
+	if len(vars) != 2 || vars[0].Name != "API_KEY" || vars[0].Value != "" || !vars[0].Secret {
+		t.Fatalf("secret value should be masked: %+v", vars)
+	}
+	if vars[1].Value != "postgres://localhost/test" {
+		t.Fatalf("plain value should be returned: %+v", vars[1])
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/api/repos/acme/api/env", bytes.NewBufferString(`{"name":"BAD-NAME","value":"x"}`))
+	w = httptest.NewRecorder()
+	srv.handleRepoDetail(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid name, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/repos/acme/api/env/API_KEY", nil)
+	w = httptest.NewRecorder()
+	srv.handleRepoDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unset env failed: %d body=%s", w.Code, w.Body.String())
+	}
+	req = httptest.NewRequest(http.MethodDelete, "/api/repos/acme/api/env/API_KEY", nil)
+	w = httptest.NewRecorder()
+	srv.handleRepoDetail(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 unsetting twice, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/repos/acme/missing/env", nil)
+	w = httptest.NewRecorder()
+	srv.handleRepoDetail(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown repo, got %d", w.Code)
+	}
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -31,8 +32,13 @@ var (
 
 type importReposRequest struct {
 	Repos []string `json:"repos"`
+	// Host imports from a forge other than github.com; see /api/forges.
+	Host string `json:"host,omitempty"`
 }
 
+// cloneRepoFunc makes a bare clone of repo at destPath.
+type cloneRepoFunc func(repo ghcli.Repo, destPath string) error
+
 type importReposResponse struct {
 	Imported []string `json:"imported"`
 }
@@ -59,19 +65,9 @@ func (s *Server) handleDiscoverRepos(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// No token required, relies on gh CLI authentication
-	if !isGhAvailableFn() {
-		http.Error(w, "gh CLI is not installed", http.StatusServiceUnavailable)
-		return
-	}
-	if !isGhAuthenticatedFn() {
-		http.Error(w, "gh CLI is not authenticated", http.StatusUnauthorized)
-		return
-	}
-
-	repos, err := discoverReposFn()
+	repos, _, status, err := s.discoverForgeRepos(r.Context(), r.URL.Query().Get("host"))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), status)
 		return
 	}
 
@@ -95,19 +91,9 @@ func (s *Server) handleImportRepos(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// No token check needed, relies on gh CLI auth which is checked in discover/import steps implicitly or explicitly
-	if !isGhAvailableFn() {
-		http.Error(w, "gh CLI is not installed", http.StatusServiceUnavailable)
-		return
-	}
-	if !isGhAuthenticatedFn() {
-		http.Error(w, "gh CLI is not authenticated", http.StatusUnauthorized)
-		return
-	}
-
-	discovered, err := discoverReposFn()
+	discovered, clone, status, err := s.discoverForgeRepos(r.Context(), req.Host)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), status)
 		return
 	}
 	available := make(map[string]ghcli.Repo, len(discovered))
@@ -128,7 +114,7 @@ func (s *Server) handleImportRepos(w http.ResponseWriter, r *http.Request) {
 		}
 		repo, ok := available[fullName]
 		if !ok {
-			http.Error(w, fmt.Sprintf("repo %q is not accessible via gh CLI", fullName), http.StatusBadRequest)
+			http.Error(w, fmt.Sprintf("repo %q is not accessible on %s", fullName, forgeHostLabel(req.Host)), http.StatusBadRequest)
 			return
 		}
 		selected = append(selected, repo)
@@ -140,7 +126,7 @@ func (s *Server) handleImportRepos(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	imported, err := importReposFn(fogHome, s.stateStore, selected)
+	imported, err := importReposFn(fogHome, s.stateStore, selected, clone)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -152,11 +138,62 @@ func (s *Server) handleImportRepos(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// discoverForgeRepos lists the repos accessible on a forge host and returns
+// how to clone them, or an HTTP status for the error. An empty host means
+// github.com through gh.
+func (s *Server) discoverForgeRepos(ctx context.Context, host string) ([]ghcli.Repo, cloneRepoFunc, int, error) {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if host == "" || host == "github.com" {
+		// No token required, relies on gh CLI authentication
+		if !isGhAvailableFn() {
+			return nil, nil, http.StatusServiceUnavailable, errors.New("gh CLI is not installed")
+		}
+		if !isGhAuthenticatedFn() {
+			return nil, nil, http.StatusUnauthorized, errors.New("gh CLI is not authenticated")
+		}
+		repos, err := discoverReposFn()
+		if err != nil {
+			return nil, nil, http.StatusInternalServerError, err
+		}
+		return repos, cloneWithGh, http.StatusOK, nil
+	}
+
+	if s.runner == nil {
+		return nil, nil, http.StatusServiceUnavailable, errors.New("runner not configured")
+	}
+	f, err := s.runner.ForgeForHost(host)
+	if err != nil {
+		return nil, nil, http.StatusBadRequest, err
+	}
+	if err := f.Check(ctx); err != nil {
+		return nil, nil, http.StatusServiceUnavailable, err
+	}
+	repos, err := f.DiscoverRepos(ctx)
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, err
+	}
+	clone := func(repo ghcli.Repo, destPath string) error {
+		return f.CloneRepo(ctx, repo, destPath)
+	}
+	return repos, clone, http.StatusOK, nil
+}
+
+func forgeHostLabel(host string) string {
+	if strings.TrimSpace(host) == "" {
+		return "github.com via gh CLI"
+	}
+	return strings.TrimSpace(host)
+}
+
 func discoverGitHubRepos() ([]ghcli.Repo, error) {
 	return ghcli.DiscoverRepos()
 }
 
-func importSelectedRepos(fogHome string, store *state.Store, repos []ghcli.Repo) ([]string, error) {
+func cloneWithGh(repo ghcli.Repo, destPath string) error {
+	return ghcliCloneRepoFn(repo.NameWithOwner, destPath)
+}
+
+func importSelectedRepos(fogHome string, store *state.Store, repos []ghcli.Repo, clone cloneRepoFunc) ([]string, error) {
 	managedReposDir := fogenv.ManagedReposDir(fogHome)
 	if err := os.MkdirAll(managedReposDir, 0o755); err != nil {
 		return nil, fmt.Errorf("create managed repos dir: %w", err)
@@ -192,7 +229,7 @@ func importSelectedRepos(fogHome string, store *state.Store, repos []ghcli.Repo)
 			barePath := filepath.Join(repoDir, "repo.git")
 			basePath := filepath.Join(repoDir, "base")
 
-			if err := ensureBareRepoInitialized(repo, barePath, basePath); err != nil {
+			if err := ensureBareRepoInitialized(repo, barePath, basePath, clone); err != nil {
 				return err
 			}
 
@@ -284,11 +321,9 @@ func splitRepoFullName(fullName string) (owner string, name string, err error) {
 	return owner, name, nil
 }
 
-func ensureBareRepoInitialized(repo ghcli.Repo, barePath, basePath string) error {
+func ensureBareRepoInitialized(repo ghcli.Repo, barePath, basePath string, cloneRepo cloneRepoFunc) error {
 	clone := func() error {
-		// Use gh repo clone via ghcli package
-		// We pass FullName (owner/repo)
-		if err := ghcliCloneRepoFn(repo.NameWithOwner, barePath); err != nil {
+		if err := cloneRepo(repo, barePath); err != nil {
 			return fmt.Errorf("clone bare repository %s: %w", repo.NameWithOwner, err)
 		}
 		return nil
@@ -366,15 +401,17 @@ func errorsIsNotExist(err error) bool {
 	return err != nil && os.IsNotExist(err)
 }
 
+// repoHost returns the forge host of a repo URL, without any port so it
+// matches the host forges are configured under.
 func repoHost(cloneURL string) string {
 	u, err := url.Parse(cloneURL)
 	if err != nil {
 		return "github.com"
 	}
-	if u.Host == "" {
+	if u.Hostname() == "" {
 		return "github.com"
 	}
-	return u.Host
+	return strings.ToLower(u.Hostname())
 }
 
 func verifyGitRepo(path string) error {
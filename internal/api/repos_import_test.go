@@ -60,7 +60,7 @@ func TestImportSelectedRepos_Parallel(t *testing.T) {
 
 	// Run import
 	start := time.Now()
-	imported, err := importReposFn(tmpHome, store, repos)
+	imported, err := importReposFn(tmpHome, store, repos, cloneWithGh)
 	if err != nil {
 		t.Fatalf("importReposFn failed: %v", err)
 	}
@@ -138,7 +138,7 @@ func TestImportSelectedRepos_Interrupted_InvalidRepo(t *testing.T) {
 	}
 
 	// Run import
-	imported, err := importReposFn(tmpHome, store, []ghcli.Repo{repo})
+	imported, err := importReposFn(tmpHome, store, []ghcli.Repo{repo}, cloneWithGh)
 	if err != nil {
 		t.Fatalf("importReposFn failed: %v", err)
 	}
@@ -205,7 +205,7 @@ func TestImportSelectedRepos_Interrupted_ValidRepo(t *testing.T) {
 	}
 
 	// Run import
-	imported, err := importReposFn(tmpHome, store, []ghcli.Repo{repo})
+	imported, err := importReposFn(tmpHome, store, []ghcli.Repo{repo}, cloneWithGh)
 	if err != nil {
 		t.Fatalf("importReposFn failed: %v", err)
 	}
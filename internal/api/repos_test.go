@@ -161,7 +161,7 @@ func TestHandleImportRepos(t *testing.T) {
 		}, nil
 	}
 
-	importReposFn = func(fogHome string, store *state.Store, repos []ghcli.Repo) ([]string, error) {
+	importReposFn = func(fogHome string, store *state.Store, repos []ghcli.Repo, clone cloneRepoFunc) ([]string, error) {
 		if len(repos) != 1 || repos[0].NameWithOwner != "acme/api" {
 			t.Fatalf("unexpected import repos input: %+v", repos)
 		}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/proc"
+	"github.com/darkLord19/foglet/internal/runner"
+)
+
+// checkSandbox verifies a sandbox backend can run here; tests replace it.
+var checkSandbox = proc.CheckSandbox
+
+// SandboxSettings configures the run sandbox globally or for one repo. Nil
+// fields inherit the global value; an empty writable_dirs list clears it.
+type SandboxSettings struct {
+	Backend      *string  `json:"backend,omitempty"`
+	NoNetwork    *bool    `json:"no_network,omitempty"`
+	WritableDirs []string `json:"writable_dirs,omitempty"`
+}
+
+// updateSandboxSettings stores the global and per-repo sandbox settings of req.
+func (s *Server) updateSandboxSettings(req UpdateSettingsRequest) error {
+	if req.Sandbox != nil {
+		if err := s.storeSandboxSettings("", req.Sandbox); err != nil {
+			return err
+		}
+	}
+	for repoName, settings := range req.RepoSandbox {
+		repoName = strings.TrimSpace(repoName)
+		if repoName == "" {
+			return fmt.Errorf("repo_sandbox: repo name cannot be empty")
+		}
+		if settings == nil {
+			for _, key := range []string{runner.SettingSandbox, runner.SettingSandboxNoNetwork, runner.SettingSandboxWritableDirs} {
+				if err := s.stateStore.DeleteSetting(runner.RepoSettingKey(key, repoName)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := s.storeSandboxSettings(repoName, settings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storeSandboxSettings validates and persists the set fields of settings,
+// globally when repoName is empty.
+func (s *Server) storeSandboxSettings(repoName string, settings *SandboxSettings) error {
+	key := func(name string) string {
+		if repoName == "" {
+			return name
+		}
+		return runner.RepoSettingKey(name, repoName)
+	}
+	if settings.Backend != nil {
+		backend := strings.TrimSpace(*settings.Backend)
+		if backend == "" {
+			backend = proc.SandboxOff
+		}
+		if err := checkSandbox(backend); err != nil {
+			return err
+		}
+		if err := s.stateStore.SetSetting(key(runner.SettingSandbox), backend); err != nil {
+			return err
+		}
+	}
+	if settings.NoNetwork != nil {
+		value := "false"
+		if *settings.NoNetwork {
+			value = "true"
+		}
+		if err := s.stateStore.SetSetting(key(runner.SettingSandboxNoNetwork), value); err != nil {
+			return err
+		}
+	}
+	if settings.WritableDirs != nil {
+		dirs := make([]string, 0, len(settings.WritableDirs))
+		for _, dir := range settings.WritableDirs {
+			dir = strings.TrimSpace(dir)
+			if dir == "" {
+				continue
+			}
+			if strings.ContainsAny(dir, ",\n") {
+				return fmt.Errorf("sandbox writable dir %q cannot contain commas or newlines", dir)
+			}
+			if !filepath.IsAbs(dir) && !strings.HasPrefix(dir, "~/") {
+				return fmt.Errorf("sandbox writable dir %q must be absolute or start with ~/", dir)
+			}
+			dirs = append(dirs, dir)
+		}
+		if err := s.stateStore.SetSetting(key(runner.SettingSandboxWritableDirs), strings.Join(dirs, ",")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sandboxSettings returns the global sandbox settings and the per-repo
+// overrides stored for managed repos.
+func (s *Server) sandboxSettings() (SandboxSettings, map[string]SandboxSettings) {
+	global := s.runner.SandboxConfig("")
+	out := SandboxSettings{
+		Backend:      &global.Backend,
+		NoNetwork:    &global.NoNetwork,
+		WritableDirs: global.WritableDirs,
+	}
+
+	repos, err := s.stateStore.ListRepos()
+	if err != nil {
+		return out, nil
+	}
+	perRepo := make(map[string]SandboxSettings)
+	for _, repo := range repos {
+		var settings SandboxSettings
+		set := false
+		if raw, found, err := s.stateStore.GetSetting(runner.RepoSettingKey(runner.SettingSandbox, repo.Name)); err == nil && found {
+			settings.Backend = &raw
+			set = true
+		}
+		if raw, found, err := s.stateStore.GetSetting(runner.RepoSettingKey(runner.SettingSandboxNoNetwork, repo.Name)); err == nil && found {
+			noNetwork := raw == "true"
+			settings.NoNetwork = &noNetwork
+			set = true
+		}
+		if raw, found, err := s.stateStore.GetSetting(runner.RepoSettingKey(runner.SettingSandboxWritableDirs, repo.Name)); err == nil && found {
+			settings.WritableDirs = runner.ParseSandboxDirs(raw)
+			set = true
+		}
+		if set {
+			perRepo[repo.Name] = settings
+		}
+	}
+	return out, perRepo
+}
+
+// availableSandboxBackends lists the sandbox backends usable on this machine.
+func availableSandboxBackends() []string {
+	backends := []string{proc.SandboxOff}
+	if checkSandbox(proc.SandboxBwrap) == nil {
+		backends = append(backends, proc.SandboxBwrap)
+	}
+	return backends
+}
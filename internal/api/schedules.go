@@ -0,0 +1,218 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+	"github.com/google/uuid"
+)
+
+// CreateScheduleRequest is the payload for POST /api/schedules.
+type CreateScheduleRequest struct {
+	Name     string            `json:"name"`
+	Repo     string            `json:"repo"`
+	Cron     string            `json:"cron"`
+	Prompt   string            `json:"prompt,omitempty"`
+	Template string            `json:"template,omitempty"`
+	Vars     map[string]string `json:"vars,omitempty"`
+	Tool     string            `json:"tool,omitempty"`
+	Model    string            `json:"model,omitempty"`
+	AutoPR   bool              `json:"autopr,omitempty"`
+	Enabled  *bool             `json:"enabled,omitempty"`
+}
+
+// UpdateScheduleRequest is the payload for PATCH /api/schedules/{id}.
+type UpdateScheduleRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// ScheduleDetail is a schedule with its recent activations.
+type ScheduleDetail struct {
+	state.Schedule
+	Runs []state.ScheduleRun `json:"runs"`
+}
+
+func (s *Server) handleSchedules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.listSchedules(w)
+	case http.MethodPost:
+		s.createSchedule(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleScheduleDetail(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/schedules/"), "/")
+	parts := strings.Split(path, "/")
+	id := strings.TrimSpace(parts[0])
+	if id == "" {
+		http.Error(w, "schedule ID required", http.StatusBadRequest)
+		return
+	}
+
+	schedule, found, err := s.stateStore.GetSchedule(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "schedule not found", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		s.getSchedule(w, schedule)
+	case len(parts) == 1 && r.Method == http.MethodPatch:
+		s.updateSchedule(w, r, schedule)
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		s.deleteSchedule(w, schedule)
+	case len(parts) == 2 && parts[1] == "runs" && r.Method == http.MethodGet:
+		s.listScheduleRuns(w, r, schedule)
+	case len(parts) == 2 && parts[1] == "runs" && r.Method == http.MethodPost:
+		s.triggerSchedule(w, schedule)
+	case len(parts) <= 2:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) listSchedules(w http.ResponseWriter) {
+	schedules, err := s.stateStore.ListSchedules()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(schedules)
+}
+
+func (s *Server) createSchedule(w http.ResponseWriter, r *http.Request) {
+	var req CreateScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Repo = strings.TrimSpace(req.Repo)
+	if req.Repo == "" {
+		http.Error(w, "repo is required", http.StatusBadRequest)
+		return
+	}
+	if _, found, err := s.stateStore.GetRepoByName(req.Repo); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if !found {
+		http.Error(w, fmt.Sprintf("unknown repo: %s", req.Repo), http.StatusBadRequest)
+		return
+	}
+
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	schedule, err := s.stateStore.CreateSchedule(state.Schedule{
+		ID:       uuid.New().String(),
+		Name:     req.Name,
+		RepoName: req.Repo,
+		Cron:     req.Cron,
+		Prompt:   req.Prompt,
+		Template: req.Template,
+		Vars:     req.Vars,
+		Tool:     req.Tool,
+		Model:    req.Model,
+		AutoPR:   req.AutoPR,
+		Enabled:  enabled,
+	})
+	if err != nil {
+		status := http.StatusBadRequest
+		if strings.Contains(err.Error(), "already exists") {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(schedule)
+}
+
+func (s *Server) getSchedule(w http.ResponseWriter, schedule state.Schedule) {
+	runs, err := s.stateStore.ListScheduleRuns(schedule.ID, 20)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(ScheduleDetail{Schedule: schedule, Runs: runs})
+}
+
+func (s *Server) updateSchedule(w http.ResponseWriter, r *http.Request, schedule state.Schedule) {
+	var req UpdateScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Enabled == nil {
+		http.Error(w, "enabled is required", http.StatusBadRequest)
+		return
+	}
+	updated, err := s.stateStore.SetScheduleEnabled(schedule.ID, *req.Enabled)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(updated)
+}
+
+func (s *Server) deleteSchedule(w http.ResponseWriter, schedule state.Schedule) {
+	if err := s.stateStore.DeleteSchedule(schedule.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "deleted", "id": schedule.ID})
+}
+
+func (s *Server) listScheduleRuns(w http.ResponseWriter, r *http.Request, schedule state.Schedule) {
+	limit := 50
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	runs, err := s.stateStore.ListScheduleRuns(schedule.ID, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(runs)
+}
+
+// triggerSchedule fires a schedule immediately without moving its next
+// activation. Skip-if-running still applies.
+func (s *Server) triggerSchedule(w http.ResponseWriter, schedule state.Schedule) {
+	run, err := s.runner.FireSchedule(schedule, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	status := http.StatusAccepted
+	if run.Status == state.ScheduleRunSkipped {
+		status = http.StatusConflict
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(run)
+}
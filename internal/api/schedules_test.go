@@ -0,0 +1,112 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestHandleSchedulesLifecycle(t *testing.T) {
+	srv := newTestServer(t)
+	if _, err := srv.stateStore.UpsertRepo(state.Repo{
+		Name:             "acme/api",
+		URL:              "https://github.com/acme/api.git",
+		Host:             "github.com",
+		BarePath:         "/tmp/acme/api/repo.git",
+		BaseWorktreePath: t.TempDir(),
+	}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/schedules", bytes.NewBufferString(`{"name":"nightly-deps","repo":"acme/api","cron":"0 3 * * *","prompt":"Update dependencies"}`))
+	w := httptest.NewRecorder()
+	srv.handleSchedules(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("create schedule failed: %d body=%s", w.Code, w.Body.String())
+	}
+	var created state.Schedule
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("decode schedule failed: %v", err)
+	}
+	if created.ID == "" || !created.Enabled || created.NextRunAt == nil {
+		t.Fatalf("unexpected schedule: %+v", created)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/schedules", bytes.NewBufferString(`{"name":"nightly-deps","repo":"acme/api","cron":"0 3 * * *","prompt":"Again"}`))
+	w = httptest.NewRecorder()
+	srv.handleSchedules(w, req)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for duplicate name, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/schedules", bytes.NewBufferString(`{"name":"bad","repo":"acme/api","cron":"61 * * * *","prompt":"x"}`))
+	w = httptest.NewRecorder()
+	srv.handleSchedules(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid cron, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/schedules", bytes.NewBufferString(`{"name":"other","repo":"acme/web","cron":"@daily","prompt":"x"}`))
+	w = httptest.NewRecorder()
+	srv.handleSchedules(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown repo, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPatch, "/api/schedules/nightly-deps", bytes.NewBufferString(`{"enabled":false}`))
+	w = httptest.NewRecorder()
+	srv.handleScheduleDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("disable schedule failed: %d body=%s", w.Code, w.Body.String())
+	}
+	var updated state.Schedule
+	if err := json.NewDecoder(w.Body).Decode(&updated); err != nil {
+		t.Fatalf("decode schedule failed: %v", err)
+	}
+	if updated.Enabled || updated.NextRunAt != nil {
+		t.Fatalf("expected disabled schedule without next run, got %+v", updated)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/schedules/"+created.ID, nil)
+	w = httptest.NewRecorder()
+	srv.handleScheduleDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("get schedule failed: %d body=%s", w.Code, w.Body.String())
+	}
+	var detail ScheduleDetail
+	if err := json.NewDecoder(w.Body).Decode(&detail); err != nil {
+		t.Fatalf("decode schedule detail failed: %v", err)
+	}
+	if detail.Name != "nightly-deps" || detail.Runs == nil {
+		t.Fatalf("unexpected schedule detail: %+v", detail)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/schedules", nil)
+	w = httptest.NewRecorder()
+	srv.handleSchedules(w, req)
+	var schedules []state.Schedule
+	if err := json.NewDecoder(w.Body).Decode(&schedules); err != nil {
+		t.Fatalf("decode schedules failed: %v", err)
+	}
+	if len(schedules) != 1 {
+		t.Fatalf("expected 1 schedule, got %d", len(schedules))
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/schedules/nightly-deps", nil)
+	w = httptest.NewRecorder()
+	srv.handleScheduleDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("delete schedule failed: %d body=%s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/schedules/nightly-deps", nil)
+	w = httptest.NewRecorder()
+	srv.handleScheduleDetail(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", w.Code)
+	}
+}
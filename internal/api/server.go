@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -45,11 +46,21 @@ func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/tasks/", s.handleTaskDetail)
 	mux.HandleFunc("/api/sessions", s.handleSessions)
 	mux.HandleFunc("/api/sessions/", s.handleSessionDetail)
+	mux.HandleFunc("/api/ensembles", s.handleEnsembles)
+	mux.HandleFunc("/api/ensembles/", s.handleEnsembleDetail)
 	mux.HandleFunc("/api/repos", s.handleRepos)
+	mux.HandleFunc("/api/repos/", s.handleRepoDetail)
 	mux.HandleFunc("/api/repos/branches", s.handleListBranches)
 	mux.HandleFunc("/api/repos/discover", s.handleDiscoverRepos)
 	mux.HandleFunc("/api/repos/import", s.handleImportRepos)
+	mux.HandleFunc("/api/forges", s.handleForges)
+	mux.HandleFunc("/api/forges/", s.handleForgeDetail)
 	mux.HandleFunc("/api/settings", s.handleSettings)
+	mux.HandleFunc("/api/templates", s.handleTemplates)
+	mux.HandleFunc("/api/templates/", s.handleTemplateDetail)
+	mux.HandleFunc("/api/schedules", s.handleSchedules)
+	mux.HandleFunc("/api/schedules/", s.handleScheduleDetail)
+	mux.HandleFunc("/api/stats/usage", s.handleUsageStats)
 	mux.HandleFunc("/api/gh/status", s.handleGhStatus)
 	mux.HandleFunc("/api/cloud", s.handleCloud)
 	mux.HandleFunc("/api/cloud/pair", s.handleCloudPair)
@@ -212,6 +223,29 @@ type SettingsResponse struct {
 	GhAuthenticated    bool              `json:"gh_authenticated"`
 	OnboardingRequired bool              `json:"onboarding_required"`
 	AvailableTools     []string          `json:"available_tools"`
+
+	MaxConcurrentRuns        int `json:"max_concurrent_runs"`
+	MaxConcurrentRunsPerRepo int `json:"max_concurrent_runs_per_repo"`
+	MaxConcurrentRunsPerTool int `json:"max_concurrent_runs_per_tool"`
+
+	RunTimeout    string                   `json:"run_timeout"`
+	RunMaxTokens  int64                    `json:"run_max_tokens"`
+	RunMaxCostUSD float64                  `json:"run_max_cost_usd"`
+	RepoRunLimits map[string]RepoRunLimits `json:"repo_run_limits,omitempty"`
+
+	Sandbox          SandboxSettings            `json:"sandbox"`
+	RepoSandbox      map[string]SandboxSettings `json:"repo_sandbox,omitempty"`
+	SandboxAvailable []string                   `json:"sandbox_backends"`
+
+	RedactPatterns []string `json:"redact_patterns"`
+}
+
+// RepoRunLimits holds per-repo run limit overrides. Nil fields inherit the
+// global value.
+type RepoRunLimits struct {
+	Timeout    *string  `json:"timeout,omitempty"`
+	MaxTokens  *int64   `json:"max_tokens,omitempty"`
+	MaxCostUSD *float64 `json:"max_cost_usd,omitempty"`
 }
 
 type UpdateSettingsRequest struct {
@@ -221,6 +255,24 @@ type UpdateSettingsRequest struct {
 	DefaultAutoPR *bool             `json:"default_autopr"`
 	DefaultNotify *bool             `json:"default_notify"`
 	BranchPrefix  *string           `json:"branch_prefix"`
+
+	MaxConcurrentRuns        *int `json:"max_concurrent_runs"`
+	MaxConcurrentRunsPerRepo *int `json:"max_concurrent_runs_per_repo"`
+	MaxConcurrentRunsPerTool *int `json:"max_concurrent_runs_per_tool"`
+
+	RunTimeout    *string  `json:"run_timeout"`
+	RunMaxTokens  *int64   `json:"run_max_tokens"`
+	RunMaxCostUSD *float64 `json:"run_max_cost_usd"`
+	// RepoRunLimits sets per-repo overrides; a null entry clears them.
+	RepoRunLimits map[string]*RepoRunLimits `json:"repo_run_limits"`
+
+	Sandbox *SandboxSettings `json:"sandbox"`
+	// RepoSandbox sets per-repo sandbox overrides; a null entry clears them.
+	RepoSandbox map[string]*SandboxSettings `json:"repo_sandbox"`
+
+	// RedactPatterns replaces the custom redaction patterns; an empty list
+	// clears them.
+	RedactPatterns *[]string `json:"redact_patterns"`
 }
 
 func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
@@ -263,6 +315,23 @@ func (s *Server) getSettings(w http.ResponseWriter) {
 		resp.BranchPrefix = prefix
 	}
 
+	limits := s.runner.ConcurrencyLimits()
+	resp.MaxConcurrentRuns = limits.Global
+	resp.MaxConcurrentRunsPerRepo = limits.PerRepo
+	resp.MaxConcurrentRunsPerTool = limits.PerTool
+
+	runLimits := s.runner.RunLimits("")
+	resp.RunTimeout = runLimits.Timeout.String()
+	resp.RunMaxTokens = runLimits.MaxTokens
+	resp.RunMaxCostUSD = runLimits.MaxCostUSD
+	resp.RepoRunLimits = s.repoRunLimits()
+	resp.Sandbox, resp.RepoSandbox = s.sandboxSettings()
+	resp.SandboxAvailable = availableSandboxBackends()
+	resp.RedactPatterns, _ = s.stateStore.RedactPatterns()
+	if resp.RedactPatterns == nil {
+		resp.RedactPatterns = []string{}
+	}
+
 	resp.GhInstalled = ghcli.IsGhAvailable()
 	if resp.GhInstalled {
 		resp.GhAuthenticated = ghcli.IsGhAuthenticated()
@@ -345,9 +414,146 @@ func (s *Server) updateSettings(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	limits := []struct {
+		key   string
+		value *int
+	}{
+		{key: runner.SettingMaxConcurrentRuns, value: req.MaxConcurrentRuns},
+		{key: runner.SettingMaxConcurrentRunsPerRepo, value: req.MaxConcurrentRunsPerRepo},
+		{key: runner.SettingMaxConcurrentRunsPerTool, value: req.MaxConcurrentRunsPerTool},
+	}
+	limitsChanged := false
+	for _, limit := range limits {
+		if limit.value == nil {
+			continue
+		}
+		if *limit.value < 0 {
+			http.Error(w, limit.key+" cannot be negative", http.StatusBadRequest)
+			return
+		}
+		if err := s.stateStore.SetSetting(limit.key, strconv.Itoa(*limit.value)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		limitsChanged = true
+	}
+	if limitsChanged {
+		s.runner.RescheduleRuns()
+	}
+
+	if err := s.updateRunLimits(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.updateSandboxSettings(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.RedactPatterns != nil {
+		if err := s.stateStore.SetRedactPatterns(*req.RedactPatterns); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	s.getSettings(w)
 }
 
+func (s *Server) updateRunLimits(req UpdateSettingsRequest) error {
+	global := RepoRunLimits{Timeout: req.RunTimeout, MaxTokens: req.RunMaxTokens, MaxCostUSD: req.RunMaxCostUSD}
+	if err := s.storeRunLimits("", &global); err != nil {
+		return err
+	}
+	for repoName, limits := range req.RepoRunLimits {
+		repoName = strings.TrimSpace(repoName)
+		if repoName == "" {
+			return fmt.Errorf("repo_run_limits: repo name cannot be empty")
+		}
+		if limits == nil {
+			for _, key := range []string{runner.SettingRunTimeout, runner.SettingRunMaxTokens, runner.SettingRunMaxCostUSD} {
+				if err := s.stateStore.DeleteSetting(runner.RepoSettingKey(key, repoName)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := s.storeRunLimits(repoName, limits); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storeRunLimits validates and persists the set fields of limits, globally
+// when repoName is empty.
+func (s *Server) storeRunLimits(repoName string, limits *RepoRunLimits) error {
+	key := func(name string) string {
+		if repoName == "" {
+			return name
+		}
+		return runner.RepoSettingKey(name, repoName)
+	}
+	if limits.Timeout != nil {
+		timeout, err := runner.ParseRunTimeout(*limits.Timeout)
+		if err != nil {
+			return err
+		}
+		if err := s.stateStore.SetSetting(key(runner.SettingRunTimeout), timeout.String()); err != nil {
+			return err
+		}
+	}
+	if limits.MaxTokens != nil {
+		if *limits.MaxTokens < 0 {
+			return fmt.Errorf("%s cannot be negative", key(runner.SettingRunMaxTokens))
+		}
+		if err := s.stateStore.SetSetting(key(runner.SettingRunMaxTokens), strconv.FormatInt(*limits.MaxTokens, 10)); err != nil {
+			return err
+		}
+	}
+	if limits.MaxCostUSD != nil {
+		if *limits.MaxCostUSD < 0 {
+			return fmt.Errorf("%s cannot be negative", key(runner.SettingRunMaxCostUSD))
+		}
+		if err := s.stateStore.SetSetting(key(runner.SettingRunMaxCostUSD), strconv.FormatFloat(*limits.MaxCostUSD, 'f', -1, 64)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// repoRunLimits returns the per-repo overrides stored for managed repos.
+func (s *Server) repoRunLimits() map[string]RepoRunLimits {
+	repos, err := s.stateStore.ListRepos()
+	if err != nil {
+		return nil
+	}
+	out := make(map[string]RepoRunLimits)
+	for _, repo := range repos {
+		var limits RepoRunLimits
+		set := false
+		if raw, found, err := s.stateStore.GetSetting(runner.RepoSettingKey(runner.SettingRunTimeout, repo.Name)); err == nil && found {
+			limits.Timeout = &raw
+			set = true
+		}
+		if raw, found, err := s.stateStore.GetSetting(runner.RepoSettingKey(runner.SettingRunMaxTokens, repo.Name)); err == nil && found {
+			if value, err := strconv.ParseInt(raw, 10, 64); err == nil {
+				limits.MaxTokens = &value
+				set = true
+			}
+		}
+		if raw, found, err := s.stateStore.GetSetting(runner.RepoSettingKey(runner.SettingRunMaxCostUSD, repo.Name)); err == nil && found {
+			if value, err := strconv.ParseFloat(raw, 64); err == nil {
+				limits.MaxCostUSD = &value
+				set = true
+			}
+		}
+		if set {
+			out[repo.Name] = limits
+		}
+	}
+	return out
+}
+
 func detectAvailableTools() []string {
 	names := ai.AvailableToolNames()
 	out := make([]string, 0, len(names))
@@ -396,7 +602,7 @@ func WithCORS(next http.Handler) http.Handler {
 		allowed := allowedCORSOrigin(origin)
 		if allowed != "" {
 			w.Header().Set("Access-Control-Allow-Origin", allowed)
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 			w.Header().Set("Vary", "Origin")
 		}
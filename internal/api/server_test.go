@@ -89,6 +89,130 @@ func TestHandleSettingsPut(t *testing.T) {
 	}
 }
 
+func TestHandleSettingsConcurrencyLimits(t *testing.T) {
+	srv := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(`{"max_concurrent_runs":3,"max_concurrent_runs_per_repo":1}`))
+	w := httptest.NewRecorder()
+	srv.handleSettings(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var resp SettingsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response failed: %v", err)
+	}
+	if resp.MaxConcurrentRuns != 3 || resp.MaxConcurrentRunsPerRepo != 1 || resp.MaxConcurrentRunsPerTool != 0 {
+		t.Fatalf("unexpected concurrency limits: %+v", resp)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(`{"max_concurrent_runs_per_tool":-1}`))
+	w = httptest.NewRecorder()
+	srv.handleSettings(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status for negative cap: got %d want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHandleSettingsSandbox(t *testing.T) {
+	srv := newTestServer(t)
+	if _, err := srv.stateStore.UpsertRepo(state.Repo{
+		Name:             "acme/api",
+		URL:              "https://github.com/acme/api.git",
+		Host:             "github.com",
+		Owner:            "acme",
+		Repo:             "api",
+		BarePath:         "/tmp/acme/api/repo.git",
+		BaseWorktreePath: "/tmp/acme/api/base",
+		DefaultBranch:    "main",
+	}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	prev := checkSandbox
+	checkSandbox = func(backend string) error {
+		if backend == "bwrap" || backend == "off" {
+			return nil
+		}
+		return prev(backend)
+	}
+	t.Cleanup(func() { checkSandbox = prev })
+
+	body := `{"sandbox":{"backend":"off","writable_dirs":["~/.cache"]},"repo_sandbox":{"acme/api":{"backend":"bwrap","no_network":true}}}`
+	req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	srv.handleSettings(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var resp SettingsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response failed: %v", err)
+	}
+	if resp.Sandbox.Backend == nil || *resp.Sandbox.Backend != "off" || len(resp.Sandbox.WritableDirs) != 1 {
+		t.Fatalf("unexpected global sandbox: %+v", resp.Sandbox)
+	}
+	repo, ok := resp.RepoSandbox["acme/api"]
+	if !ok || repo.Backend == nil || *repo.Backend != "bwrap" || repo.NoNetwork == nil || !*repo.NoNetwork {
+		t.Fatalf("unexpected repo sandbox: %+v", resp.RepoSandbox)
+	}
+	cfg := srv.runner.SandboxConfig("acme/api")
+	if cfg.Backend != "bwrap" || !cfg.NoNetwork || len(cfg.WritableDirs) != 1 || cfg.WritableDirs[0] != "~/.cache" {
+		t.Fatalf("unexpected effective sandbox: %+v", cfg)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(`{"repo_sandbox":{"acme/api":null}}`))
+	w = httptest.NewRecorder()
+	srv.handleSettings(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status clearing override: %d body=%s", w.Code, w.Body.String())
+	}
+	if cfg := srv.runner.SandboxConfig("acme/api"); cfg.Backend != "off" {
+		t.Fatalf("expected repo override cleared, got %+v", cfg)
+	}
+
+	for _, body := range []string{
+		`{"sandbox":{"backend":"docker"}}`,
+		`{"sandbox":{"writable_dirs":["relative/cache"]}}`,
+	} {
+		req = httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+		w = httptest.NewRecorder()
+		srv.handleSettings(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestHandleSettingsRedactPatterns(t *testing.T) {
+	srv := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(`{"redact_patterns":["ACME-[0-9]{6}"]}`))
+	w := httptest.NewRecorder()
+	srv.handleSettings(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var resp SettingsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response failed: %v", err)
+	}
+	if len(resp.RedactPatterns) != 1 || resp.RedactPatterns[0] != "ACME-[0-9]{6}" {
+		t.Fatalf("unexpected redact patterns: %v", resp.RedactPatterns)
+	}
+	if got := srv.stateStore.Redact("id ACME-123456"); got != "id [REDACTED]" {
+		t.Fatalf("pattern not applied: %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(`{"redact_patterns":["("]}`))
+	w = httptest.NewRecorder()
+	srv.handleSettings(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid pattern, got %d", w.Code)
+	}
+}
+
 func newTestServer(t *testing.T) *Server {
 	t.Helper()
 
@@ -1,10 +1,12 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -13,6 +15,7 @@ import (
 	"github.com/darkLord19/foglet/internal/ai"
 	"github.com/darkLord19/foglet/internal/editor"
 	"github.com/darkLord19/foglet/internal/git"
+	"github.com/darkLord19/foglet/internal/projectcfg"
 	"github.com/darkLord19/foglet/internal/runner"
 	"github.com/darkLord19/foglet/internal/state"
 	"github.com/darkLord19/foglet/internal/toolcfg"
@@ -40,19 +43,28 @@ func validateShellCommand(cmd string) error {
 
 // CreateSessionRequest is the payload for POST /api/sessions.
 type CreateSessionRequest struct {
-	Repo        string `json:"repo"`
-	Tool        string `json:"tool,omitempty"`
-	Model       string `json:"model,omitempty"`
-	Prompt      string `json:"prompt"`
-	BranchName  string `json:"branch_name,omitempty"`
-	AutoPR      *bool  `json:"autopr,omitempty"`
-	SetupCmd    string `json:"setup_cmd,omitempty"`
-	Validate    bool   `json:"validate,omitempty"`
-	ValidateCmd string `json:"validate_cmd,omitempty"`
-	BaseBranch  string `json:"base_branch,omitempty"`
-	CommitMsg   string `json:"commit_msg,omitempty"`
-	Async       *bool  `json:"async,omitempty"`
-	PRTitle     string `json:"pr_title,omitempty"`
+	Repo        string  `json:"repo"`
+	Tool        string  `json:"tool,omitempty"`
+	Model       string  `json:"model,omitempty"`
+	Prompt      string  `json:"prompt"`
+	BranchName  string  `json:"branch_name,omitempty"`
+	AutoPR      *bool   `json:"autopr,omitempty"`
+	SetupCmd    string  `json:"setup_cmd,omitempty"`
+	Validate    *bool   `json:"validate,omitempty"`
+	ValidateCmd string  `json:"validate_cmd,omitempty"`
+	BaseBranch  string  `json:"base_branch,omitempty"`
+	CommitMsg   string  `json:"commit_msg,omitempty"`
+	Async       *bool   `json:"async,omitempty"`
+	PRTitle     string  `json:"pr_title,omitempty"`
+	Priority    int     `json:"priority,omitempty"`
+	Timeout     string  `json:"timeout,omitempty"`
+	MaxTokens   int64   `json:"max_tokens,omitempty"`
+	MaxCostUSD  float64 `json:"max_cost_usd,omitempty"`
+	FixAttempts int     `json:"fix_attempts,omitempty"`
+	// Template renders the prompt from a stored template with Vars; Prompt,
+	// if also set, is appended as extra instructions.
+	Template string            `json:"template,omitempty"`
+	Vars     map[string]string `json:"vars,omitempty"`
 }
 
 // FollowUpRunRequest is the payload for POST /api/sessions/{id}/runs.
@@ -61,20 +73,30 @@ type FollowUpRunRequest struct {
 	Async  *bool  `json:"async,omitempty"`
 }
 
+// ReorderQueueRequest is the payload for PUT /api/sessions/{id}/queue.
+type ReorderQueueRequest struct {
+	RunIDs []string `json:"run_ids"`
+}
+
 // ForkSessionRequest is the payload for POST /api/sessions/{id}/fork.
 type ForkSessionRequest struct {
-	Prompt      string `json:"prompt"`
-	BranchName  string `json:"branch_name,omitempty"`
-	Tool        string `json:"tool,omitempty"`
-	Model       string `json:"model,omitempty"`
-	AutoPR      *bool  `json:"autopr,omitempty"`
-	SetupCmd    string `json:"setup_cmd,omitempty"`
-	Validate    bool   `json:"validate,omitempty"`
-	ValidateCmd string `json:"validate_cmd,omitempty"`
-	BaseBranch  string `json:"base_branch,omitempty"`
-	CommitMsg   string `json:"commit_msg,omitempty"`
-	Async       *bool  `json:"async,omitempty"`
-	PRTitle     string `json:"pr_title,omitempty"`
+	Prompt      string  `json:"prompt"`
+	BranchName  string  `json:"branch_name,omitempty"`
+	Tool        string  `json:"tool,omitempty"`
+	Model       string  `json:"model,omitempty"`
+	AutoPR      *bool   `json:"autopr,omitempty"`
+	SetupCmd    string  `json:"setup_cmd,omitempty"`
+	Validate    *bool   `json:"validate,omitempty"`
+	ValidateCmd string  `json:"validate_cmd,omitempty"`
+	BaseBranch  string  `json:"base_branch,omitempty"`
+	CommitMsg   string  `json:"commit_msg,omitempty"`
+	Async       *bool   `json:"async,omitempty"`
+	PRTitle     string  `json:"pr_title,omitempty"`
+	Priority    int     `json:"priority,omitempty"`
+	Timeout     string  `json:"timeout,omitempty"`
+	MaxTokens   int64   `json:"max_tokens,omitempty"`
+	MaxCostUSD  float64 `json:"max_cost_usd,omitempty"`
+	FixAttempts int     `json:"fix_attempts,omitempty"`
 }
 
 type createSessionResponse struct {
@@ -98,18 +120,10 @@ type sessionSummary struct {
 	LatestRun *state.Run `json:"latest_run,omitempty"`
 }
 
-type sessionDiffResponse struct {
-	BaseBranch   string `json:"base_branch"`
-	Branch       string `json:"branch"`
-	WorktreePath string `json:"worktree_path"`
-	Stat         string `json:"stat"`
-	Patch        string `json:"patch"`
-}
-
 func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		s.listSessions(w)
+		s.listSessions(w, r)
 	case http.MethodPost:
 		s.createSession(w, r)
 	default:
@@ -132,11 +146,14 @@ func (s *Server) handleSessionDetail(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(parts) == 1 {
-		if r.Method != http.MethodGet {
+		switch r.Method {
+		case http.MethodGet:
+			s.getSession(w, sessionID)
+		case http.MethodDelete:
+			s.deleteSession(w, r, sessionID)
+		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
 		}
-		s.getSession(w, sessionID)
 		return
 	}
 
@@ -156,6 +173,35 @@ func (s *Server) handleSessionDetail(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	if len(parts) >= 2 && parts[1] == "queue" {
+		switch {
+		case len(parts) == 2 && r.Method == http.MethodGet:
+			s.listSessionQueue(w, sessionID)
+			return
+		case len(parts) == 2 && r.Method == http.MethodPut:
+			s.reorderSessionQueue(w, r, sessionID)
+			return
+		case len(parts) == 3 && r.Method == http.MethodDelete:
+			s.dropQueuedRun(w, sessionID, parts[2])
+			return
+		}
+	}
+	if len(parts) == 3 && parts[1] == "pr" {
+		switch {
+		case parts[2] == "refresh" && r.Method == http.MethodPost:
+			s.refreshSessionPR(w, r, sessionID)
+			return
+		case parts[2] == "ready" && r.Method == http.MethodPost:
+			s.markSessionPRReady(w, r, sessionID)
+			return
+		case parts[2] == "body" && r.Method == http.MethodPost:
+			s.updateSessionPRBody(w, r, sessionID)
+			return
+		case parts[2] == "merge" && r.Method == http.MethodPost:
+			s.mergeSessionPR(w, r, sessionID)
+			return
+		}
+	}
 	if len(parts) == 2 {
 		switch {
 		case parts[1] == "cancel" && r.Method == http.MethodPost:
@@ -170,13 +216,32 @@ func (s *Server) handleSessionDetail(w http.ResponseWriter, r *http.Request) {
 		case parts[1] == "open" && r.Method == http.MethodPost:
 			s.openSessionWorktree(w, sessionID)
 			return
+		case parts[1] == "archive" && r.Method == http.MethodPost:
+			s.archiveSession(w, sessionID, true)
+			return
+		case parts[1] == "unarchive" && r.Method == http.MethodPost:
+			s.archiveSession(w, sessionID, false)
+			return
+		case parts[1] == "sync-pr" && r.Method == http.MethodPost:
+			s.syncSessionPR(w, r, sessionID)
+			return
 		}
 	}
 
 	http.NotFound(w, r)
 }
 
-func (s *Server) listSessions(w http.ResponseWriter) {
+func (s *Server) listSessions(w http.ResponseWriter, r *http.Request) {
+	// Archived sessions are hidden unless ?archived=include (all sessions)
+	// or ?archived=only (archived sessions alone) is passed.
+	archived := strings.TrimSpace(r.URL.Query().Get("archived"))
+	switch archived {
+	case "", "include", "only":
+	default:
+		http.Error(w, "archived must be include or only", http.StatusBadRequest)
+		return
+	}
+
 	sessions, err := s.runner.ListSessions()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -185,6 +250,10 @@ func (s *Server) listSessions(w http.ResponseWriter) {
 
 	out := make([]sessionSummary, 0, len(sessions))
 	for _, sess := range sessions {
+		isArchived := sess.ArchivedAt != nil
+		if (archived == "" && isArchived) || (archived == "only" && !isArchived) {
+			continue
+		}
 		var latest *state.Run
 		if run, found, err := s.stateStore.GetLatestRun(sess.ID); err == nil && found {
 			runCopy := run
@@ -209,6 +278,15 @@ func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
 
 	req.Repo = strings.TrimSpace(req.Repo)
 	req.Prompt = strings.TrimSpace(req.Prompt)
+	req.Template = strings.TrimSpace(req.Template)
+	if req.Template != "" {
+		prompt, err := s.stateStore.RenderTemplate(req.Template, req.Vars, req.Prompt)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Prompt = prompt
+	}
 	if req.Repo == "" || req.Prompt == "" {
 		http.Error(w, "repo and prompt are required", http.StatusBadRequest)
 		return
@@ -217,6 +295,15 @@ func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	limits, err := parseRunLimits(req.Timeout, req.MaxTokens, req.MaxCostUSD)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateFixAttempts(req.FixAttempts, req.Validate != nil && *req.Validate, req.ValidateCmd); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	repo, found, err := s.stateStore.GetRepoByName(req.Repo)
 	if err != nil {
@@ -228,11 +315,24 @@ func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tool, err := toolcfg.ResolveTool(req.Tool, s.stateStore, "api")
+	project, err := projectcfg.Load(repo.BaseWorktreePath)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	requestedTool := strings.TrimSpace(req.Tool)
+	if requestedTool == "" {
+		requestedTool = project.DefaultTool()
+	}
+	tool, err := toolcfg.ResolveTool(requestedTool, s.stateStore, "api")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !project.AllowsTool(tool) {
+		http.Error(w, fmt.Sprintf("tool %q is not allowed by %s", tool, filepath.Base(project.Path)), http.StatusBadRequest)
+		return
+	}
 
 	branch, err := s.resolveBranchName(repo.BaseWorktreePath, req.BranchName, req.Prompt)
 	if err != nil {
@@ -250,6 +350,9 @@ func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
 	}
 
 	baseBranch := strings.TrimSpace(req.BaseBranch)
+	if baseBranch == "" {
+		baseBranch = project.BaseBranch
+	}
 	if baseBranch == "" {
 		baseBranch = strings.TrimSpace(repo.DefaultBranch)
 	}
@@ -266,11 +369,20 @@ func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
 		Prompt:      req.Prompt,
 		AutoPR:      autoPR,
 		SetupCmd:    strings.TrimSpace(req.SetupCmd),
-		Validate:    req.Validate,
 		ValidateCmd: strings.TrimSpace(req.ValidateCmd),
 		BaseBranch:  baseBranch,
 		CommitMsg:   strings.TrimSpace(req.CommitMsg),
 		PRTitle:     strings.TrimSpace(req.PRTitle),
+		Priority:    req.Priority,
+		Timeout:     limits.Timeout,
+		MaxTokens:   limits.MaxTokens,
+		MaxCostUSD:  limits.MaxCostUSD,
+		FixAttempts: req.FixAttempts,
+		Template:    req.Template,
+	}
+	if req.Validate != nil {
+		opts.HasValidate = true
+		opts.Validate = *req.Validate
 	}
 
 	if async {
@@ -367,11 +479,15 @@ func (s *Server) createFollowUpRun(w http.ResponseWriter, r *http.Request, sessi
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		status := "accepted"
+		if run.State == "QUEUED" {
+			status = "queued"
+		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusAccepted)
 		_ = json.NewEncoder(w).Encode(map[string]string{
 			"run_id":  run.ID,
-			"status":  "accepted",
+			"status":  status,
 			"session": run.SessionID,
 		})
 		return
@@ -382,6 +498,72 @@ func (s *Server) createFollowUpRun(w http.ResponseWriter, r *http.Request, sessi
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	if run.State == "QUEUED" {
+		w.WriteHeader(http.StatusAccepted)
+	}
+	_ = json.NewEncoder(w).Encode(run)
+}
+
+func (s *Server) listSessionQueue(w http.ResponseWriter, sessionID string) {
+	_, found, err := s.runner.GetSession(sessionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+
+	runs, err := s.runner.ListQueuedRuns(sessionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(runs)
+}
+
+func (s *Server) reorderSessionQueue(w http.ResponseWriter, r *http.Request, sessionID string) {
+	var req ReorderQueueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, found, err := s.runner.GetSession(sessionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+
+	runs, err := s.runner.ReorderSessionQueue(sessionID, req.RunIDs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(runs)
+}
+
+func (s *Server) dropQueuedRun(w http.ResponseWriter, sessionID, runID string) {
+	run, err := s.runner.DropQueuedRun(sessionID, runID)
+	if err != nil {
+		status := http.StatusBadRequest
+		if strings.Contains(strings.ToLower(err.Error()), "not found") {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(run)
 }
@@ -401,6 +583,15 @@ func (s *Server) createForkSession(w http.ResponseWriter, r *http.Request, sourc
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	limits, err := parseRunLimits(req.Timeout, req.MaxTokens, req.MaxCostUSD)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateFixAttempts(req.FixAttempts, req.Validate != nil && *req.Validate, req.ValidateCmd); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	sourceSession, found, err := s.runner.GetSession(sourceSessionID)
 	if err != nil {
@@ -437,16 +628,24 @@ func (s *Server) createForkSession(w http.ResponseWriter, r *http.Request, sourc
 		Tool:        tool,
 		Model:       strings.TrimSpace(req.Model),
 		SetupCmd:    strings.TrimSpace(req.SetupCmd),
-		Validate:    req.Validate,
 		ValidateCmd: strings.TrimSpace(req.ValidateCmd),
 		BaseBranch:  strings.TrimSpace(req.BaseBranch),
 		CommitMsg:   strings.TrimSpace(req.CommitMsg),
 		PRTitle:     strings.TrimSpace(req.PRTitle),
+		Priority:    req.Priority,
+		Timeout:     limits.Timeout,
+		MaxTokens:   limits.MaxTokens,
+		MaxCostUSD:  limits.MaxCostUSD,
+		FixAttempts: req.FixAttempts,
 	}
 	if req.AutoPR != nil {
 		opts.HasAutoPR = true
 		opts.AutoPR = *req.AutoPR
 	}
+	if req.Validate != nil {
+		opts.HasValidate = true
+		opts.Validate = *req.Validate
+	}
 	if strings.TrimSpace(opts.Tool) == "" {
 		opts.Tool = sourceSession.Tool
 	}
@@ -608,9 +807,42 @@ func (s *Server) streamRunEvents(w http.ResponseWriter, r *http.Request, session
 	}
 }
 
+// parseRunLimits validates per-request run limit overrides.
+func parseRunLimits(timeout string, maxTokens int64, maxCostUSD float64) (runner.RunLimits, error) {
+	var limits runner.RunLimits
+	if strings.TrimSpace(timeout) != "" {
+		value, err := runner.ParseRunTimeout(timeout)
+		if err != nil {
+			return runner.RunLimits{}, err
+		}
+		limits.Timeout = value
+	}
+	if maxTokens < 0 {
+		return runner.RunLimits{}, fmt.Errorf("max_tokens cannot be negative")
+	}
+	if maxCostUSD < 0 {
+		return runner.RunLimits{}, fmt.Errorf("max_cost_usd cannot be negative")
+	}
+	limits.MaxTokens = maxTokens
+	limits.MaxCostUSD = maxCostUSD
+	return limits, nil
+}
+
+func validateFixAttempts(attempts int, validate bool, validateCmd string) error {
+	switch {
+	case attempts < 0:
+		return fmt.Errorf("fix_attempts cannot be negative")
+	case attempts > runner.MaxFixAttempts:
+		return fmt.Errorf("fix_attempts cannot exceed %d", runner.MaxFixAttempts)
+	case attempts > 0 && (!validate || strings.TrimSpace(validateCmd) == ""):
+		return fmt.Errorf("fix_attempts requires validate and validate_cmd")
+	}
+	return nil
+}
+
 func isTerminalRunState(stateName string) bool {
 	switch strings.TrimSpace(stateName) {
-	case "COMPLETED", "FAILED", "CANCELLED":
+	case "COMPLETED", "FAILED", "CANCELLED", "INTERRUPTED", "TIMED_OUT", "BUDGET_EXCEEDED":
 		return true
 	default:
 		return false
@@ -635,6 +867,137 @@ func (s *Server) cancelSessionRun(w http.ResponseWriter, sessionID string) {
 	})
 }
 
+func (s *Server) deleteSession(w http.ResponseWriter, r *http.Request, sessionID string) {
+	query := r.URL.Query()
+	err := s.runner.DeleteSession(sessionID, runner.DeleteSessionOptions{
+		DeleteBranch: queryFlag(query.Get("delete_branch")),
+		Force:        queryFlag(query.Get("force")),
+	})
+	if err != nil {
+		status := http.StatusConflict
+		if strings.Contains(strings.ToLower(err.Error()), "not found") {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"status":     "deleted",
+		"session_id": sessionID,
+	})
+}
+
+func (s *Server) archiveSession(w http.ResponseWriter, sessionID string, archived bool) {
+	session, err := s.runner.ArchiveSession(sessionID, archived)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if strings.Contains(strings.ToLower(err.Error()), "not found") {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(session)
+}
+
+// refreshSessionPR re-reads the session's PR status from GitHub.
+func (s *Server) refreshSessionPR(w http.ResponseWriter, r *http.Request, sessionID string) {
+	session, err := s.runner.RefreshPRStatus(r.Context(), sessionID)
+	if err != nil {
+		status := http.StatusBadRequest
+		if strings.Contains(strings.ToLower(err.Error()), "not found") {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(session)
+}
+
+func (s *Server) markSessionPRReady(w http.ResponseWriter, r *http.Request, sessionID string) {
+	session, err := s.runner.MarkPRReady(r.Context(), sessionID)
+	if err != nil {
+		status := http.StatusBadRequest
+		if strings.Contains(strings.ToLower(err.Error()), "not found") {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(session)
+}
+
+// updateSessionPRBody regenerates the PR description from the session's
+// runs. ?dry_run=1 returns it without editing the PR.
+func (s *Server) updateSessionPRBody(w http.ResponseWriter, r *http.Request, sessionID string) {
+	body, err := s.runner.UpdatePRBody(r.Context(), sessionID, queryFlag(r.URL.Query().Get("dry_run")))
+	if err != nil {
+		status := http.StatusBadRequest
+		if strings.Contains(strings.ToLower(err.Error()), "not found") {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"session_id": sessionID,
+		"body":       body,
+	})
+}
+
+// mergeSessionPR merges the session's PR with ?method=squash|rebase|merge,
+// defaulting to the project's pr.merge_method.
+func (s *Server) mergeSessionPR(w http.ResponseWriter, r *http.Request, sessionID string) {
+	session, err := s.runner.MergeSessionPR(r.Context(), sessionID, r.URL.Query().Get("method"))
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, runner.ErrPRNotMergeable):
+			status = http.StatusConflict
+		case strings.Contains(strings.ToLower(err.Error()), "not found"):
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(session)
+}
+
+// syncSessionPR turns new review comments and failing checks on the
+// session's pull request into a follow-up run. ?dry_run=1 only returns the
+// prompt that would be sent.
+func (s *Server) syncSessionPR(w http.ResponseWriter, r *http.Request, sessionID string) {
+	result, err := s.runner.SyncPRFeedback(r.Context(), sessionID, runner.PRSyncOptions{
+		DryRun: queryFlag(r.URL.Query().Get("dry_run")),
+		Async:  true,
+	})
+	if err != nil {
+		status := http.StatusBadRequest
+		if strings.Contains(strings.ToLower(err.Error()), "not found") {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if result.Run != nil {
+		w.WriteHeader(http.StatusAccepted)
+	}
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// queryFlag reports whether a boolean query parameter is set ("1", "true").
+func queryFlag(raw string) bool {
+	value, err := strconv.ParseBool(strings.TrimSpace(raw))
+	return err == nil && value
+}
+
 func (s *Server) getSessionDiff(w http.ResponseWriter, sessionID string) {
 	session, found, err := s.runner.GetSession(sessionID)
 	if err != nil {
@@ -656,39 +1019,14 @@ func (s *Server) getSessionDiff(w http.ResponseWriter, sessionID string) {
 		return
 	}
 
-	worktreePath := strings.TrimSpace(session.WorktreePath)
-	if latest, found, err := s.stateStore.GetLatestRun(session.ID); err == nil && found && strings.TrimSpace(latest.WorktreePath) != "" {
-		worktreePath = strings.TrimSpace(latest.WorktreePath)
-	}
-	if worktreePath == "" {
-		http.Error(w, "session has no worktree path", http.StatusBadRequest)
-		return
-	}
-
-	baseBranch := strings.TrimSpace(repo.DefaultBranch)
-	if baseBranch == "" {
-		baseBranch = "main"
-	}
-	diffRef := fmt.Sprintf("%s...%s", baseBranch, session.Branch)
-	statOut, err := runGitInWorktree(worktreePath, "diff", "--stat", "--no-color", diffRef)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-	patchOut, err := runGitInWorktree(worktreePath, "diff", "--no-color", diffRef)
+	diff, err := s.runner.SessionDiff(context.Background(), session, repo)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(sessionDiffResponse{
-		BaseBranch:   baseBranch,
-		Branch:       session.Branch,
-		WorktreePath: worktreePath,
-		Stat:         strings.TrimSpace(statOut),
-		Patch:        strings.TrimSpace(patchOut),
-	})
+	_ = json.NewEncoder(w).Encode(diff)
 }
 
 func (s *Server) openSessionWorktree(w http.ResponseWriter, sessionID string) {
@@ -741,16 +1079,6 @@ func preferredEditorForTool(toolName string) string {
 	}
 }
 
-func runGitInWorktree(worktreePath string, args ...string) (string, error) {
-	cmd := exec.Command("git", args...)
-	cmd.Dir = worktreePath
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("git %s failed: %w\n%s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
-	}
-	return string(out), nil
-}
-
 func (s *Server) resolveBranchName(repoPath, requested, prompt string) (string, error) {
 	requested = strings.TrimSpace(requested)
 	if requested != "" {
@@ -771,6 +1099,12 @@ func (s *Server) resolveBranchName(repoPath, requested, prompt string) (string,
 		baseBranch = strings.Trim(baseBranch[:255], "/.-")
 	}
 
+	return uniqueBranchName(repoPath, baseBranch)
+}
+
+// uniqueBranchName returns baseBranch, or baseBranch with a numeric suffix
+// when a branch of that name already exists in repoPath.
+func uniqueBranchName(repoPath, baseBranch string) (string, error) {
 	g := git.New(repoPath)
 
 	truncateWithSuffix := func(base, suffix string) string {
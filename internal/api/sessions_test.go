@@ -37,6 +37,97 @@ func TestHandleSessionsGet(t *testing.T) {
 	}
 }
 
+func TestHandleSessionArchiveRoutesAndListFilter(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/sessions/session-1/archive", nil)
+	w := httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("archive: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var archived state.Session
+	if err := json.NewDecoder(w.Body).Decode(&archived); err != nil {
+		t.Fatalf("decode archived session failed: %v", err)
+	}
+	if archived.ArchivedAt == nil {
+		t.Fatal("expected archived_at in response")
+	}
+
+	for query, want := range map[string]int{"": 0, "?archived=include": 1, "?archived=only": 1} {
+		req = httptest.NewRequest(http.MethodGet, "/api/sessions"+query, nil)
+		w = httptest.NewRecorder()
+		srv.handleSessions(w, req)
+		var sessions []state.Session
+		if err := json.NewDecoder(w.Body).Decode(&sessions); err != nil {
+			t.Fatalf("decode sessions %q failed: %v", query, err)
+		}
+		if len(sessions) != want {
+			t.Fatalf("list %q: got %d sessions want %d", query, len(sessions), want)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/sessions?archived=bogus", nil)
+	w = httptest.NewRecorder()
+	srv.handleSessions(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("bogus filter: got %d want %d", w.Code, http.StatusBadRequest)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/sessions/session-1/unarchive", nil)
+	w = httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unarchive: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/sessions/missing/archive", nil)
+	w = httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("archive missing: got %d want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandleDeleteSession(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+	if err := srv.stateStore.SetSessionBusy("session-1", true); err != nil {
+		t.Fatalf("set busy failed: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/sessions/session-1", nil)
+	w := httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("busy delete: got %d want %d body=%s", w.Code, http.StatusConflict, w.Body.String())
+	}
+
+	if err := srv.stateStore.SetSessionBusy("session-1", false); err != nil {
+		t.Fatalf("clear busy failed: %v", err)
+	}
+	req = httptest.NewRequest(http.MethodDelete, "/api/sessions/session-1?delete_branch=1", nil)
+	w = httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("delete: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if _, found, err := srv.stateStore.GetSession("session-1"); err != nil || found {
+		t.Fatalf("expected session to be deleted, found=%v err=%v", found, err)
+	}
+	if _, found, err := srv.stateStore.GetRun("run-1"); err != nil || found {
+		t.Fatalf("expected run to be deleted, found=%v err=%v", found, err)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/sessions/session-1", nil)
+	w = httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("delete missing: got %d want %d", w.Code, http.StatusNotFound)
+	}
+}
+
 func TestHandleCreateSessionRequiresRepoAndPrompt(t *testing.T) {
 	srv := newTestServer(t)
 	body := bytes.NewBufferString(`{"prompt":"hello"}`)
@@ -118,6 +209,54 @@ func TestHandleSessionStreamRoute(t *testing.T) {
 	}
 }
 
+func TestHandleSessionQueueRoutes(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+	if _, err := srv.stateStore.EnqueueRun(state.Run{
+		ID:           "run-queued",
+		SessionID:    "session-1",
+		Prompt:       "next step",
+		WorktreePath: "/tmp/acme-api/worktree",
+	}); err != nil {
+		t.Fatalf("enqueue run failed: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/sessions/session-1/queue", nil)
+	w := httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var queued []state.Run
+	if err := json.NewDecoder(w.Body).Decode(&queued); err != nil {
+		t.Fatalf("decode queue failed: %v", err)
+	}
+	if len(queued) != 1 || queued[0].ID != "run-queued" || queued[0].QueuePosition != 1 {
+		t.Fatalf("unexpected queue payload: %+v", queued)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/api/sessions/session-1/queue", bytes.NewBufferString(`{"run_ids":["missing"]}`))
+	w = httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected reorder status: got %d want %d body=%s", w.Code, http.StatusBadRequest, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/sessions/session-1/queue/run-queued", nil)
+	w = httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected drop status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/sessions/session-1/queue/run-queued", nil)
+	w = httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected second drop status: got %d want %d body=%s", w.Code, http.StatusNotFound, w.Body.String())
+	}
+}
+
 func TestHandleCreateFollowUpRunRequiresPrompt(t *testing.T) {
 	srv := newTestServer(t)
 	req := httptest.NewRequest(http.MethodPost, "/api/sessions/abc/runs", bytes.NewBufferString(`{}`))
@@ -271,3 +410,62 @@ func TestResolveBranchName_Unique(t *testing.T) {
 	}
 	t.Logf("Resolved unique branch: %s", uniqueName)
 }
+
+func TestHandleSessionSyncPRErrors(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/sessions/session-1/sync-pr", nil)
+	w := httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "no pull request") {
+		t.Fatalf("sync without PR: got %d body=%s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/sessions/missing/sync-pr", nil)
+	w = httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("sync unknown session: got %d want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandleSessionPRRefreshRequiresPR(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/sessions/session-1/pr/refresh", nil)
+	w := httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "no pull request") {
+		t.Fatalf("refresh without PR: got %d body=%s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/sessions/session-1/pr/refresh", nil)
+	w = httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("GET refresh: got %d want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandleSessionPRActionsRequirePR(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+
+	for _, action := range []string{"ready", "body?dry_run=1", "merge?method=squash"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/sessions/session-1/pr/"+action, nil)
+		w := httptest.NewRecorder()
+		srv.handleSessionDetail(w, req)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "no pull request") {
+			t.Fatalf("%s without PR: got %d body=%s", action, w.Code, w.Body.String())
+		}
+
+		req = httptest.NewRequest(http.MethodPost, "/api/sessions/missing/pr/"+action, nil)
+		w = httptest.NewRecorder()
+		srv.handleSessionDetail(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("%s unknown session: got %d want %d", action, w.Code, http.StatusNotFound)
+		}
+	}
+}
@@ -34,3 +34,25 @@ func TestValidateShellCommand(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateFixAttempts(t *testing.T) {
+	cases := []struct {
+		attempts    int
+		validate    bool
+		validateCmd string
+		ok          bool
+	}{
+		{attempts: 0, ok: true},
+		{attempts: 3, validate: true, validateCmd: "go test ./...", ok: true},
+		{attempts: 3, validate: true, ok: false},
+		{attempts: 3, validateCmd: "go test ./...", ok: false},
+		{attempts: -1, ok: false},
+		{attempts: 11, validate: true, validateCmd: "go test ./...", ok: false},
+	}
+	for _, tc := range cases {
+		err := validateFixAttempts(tc.attempts, tc.validate, tc.validateCmd)
+		if (err == nil) != tc.ok {
+			t.Errorf("validateFixAttempts(%d, %v, %q) = %v, want ok=%v", tc.attempts, tc.validate, tc.validateCmd, err, tc.ok)
+		}
+	}
+}
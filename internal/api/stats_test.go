@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestHandleUsageStats(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+	usage := state.RunUsage{Model: "sonnet", InputTokens: 120, OutputTokens: 30, CostUSD: 0.42, DurationMS: 900, NumTurns: 2}
+	if err := srv.stateStore.SetRunUsage("run-1", usage); err != nil {
+		t.Fatalf("set run usage failed: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/stats/usage", nil)
+	w := httptest.NewRecorder()
+	srv.handleUsageStats(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d body=%s", w.Code, w.Body.String())
+	}
+	var summaries []state.UsageSummary
+	if err := json.NewDecoder(w.Body).Decode(&summaries); err != nil {
+		t.Fatalf("decode response failed: %v", err)
+	}
+	if len(summaries) != 1 || summaries[0].RepoName != "acme/api" || summaries[0].Tool != "claude" || summaries[0].CostUSD != 0.42 {
+		t.Fatalf("unexpected usage summaries: %+v", summaries)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/sessions/session-1/runs", nil)
+	w = httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected runs status: %d body=%s", w.Code, w.Body.String())
+	}
+	var runs []state.Run
+	if err := json.NewDecoder(w.Body).Decode(&runs); err != nil {
+		t.Fatalf("decode runs failed: %v", err)
+	}
+	if len(runs) != 1 || runs[0].Usage == nil || *runs[0].Usage != usage {
+		t.Fatalf("runs should include usage: %+v", runs)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/stats/usage", nil)
+	w = httptest.NewRecorder()
+	srv.handleUsageStats(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+}
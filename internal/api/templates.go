@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// SaveTemplateRequest is the payload for POST /api/templates and
+// PUT /api/templates/{name}.
+type SaveTemplateRequest struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Body        string `json:"body"`
+}
+
+// RenderTemplateRequest is the payload for POST /api/templates/{name}/render.
+type RenderTemplateRequest struct {
+	Vars   map[string]string `json:"vars,omitempty"`
+	Prompt string            `json:"prompt,omitempty"`
+}
+
+func (s *Server) handleTemplates(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.listTemplates(w)
+	case http.MethodPost:
+		var req SaveTemplateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.saveTemplate(w, req)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleTemplateDetail(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/templates/"), "/")
+	parts := strings.Split(path, "/")
+	name := strings.TrimSpace(parts[0])
+	if name == "" {
+		http.Error(w, "template name required", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		s.getTemplate(w, name)
+	case len(parts) == 1 && r.Method == http.MethodPut:
+		var req SaveTemplateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Name = name
+		s.saveTemplate(w, req)
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		s.deleteTemplate(w, name)
+	case len(parts) == 2 && parts[1] == "render" && r.Method == http.MethodPost:
+		s.renderTemplate(w, r, name)
+	case len(parts) <= 2:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) listTemplates(w http.ResponseWriter) {
+	templates, err := s.stateStore.ListTemplates()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(templates)
+}
+
+func (s *Server) getTemplate(w http.ResponseWriter, name string) {
+	tmpl, found, err := s.stateStore.GetTemplate(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "template not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(tmpl)
+}
+
+func (s *Server) saveTemplate(w http.ResponseWriter, req SaveTemplateRequest) {
+	tmpl, err := s.stateStore.SaveTemplate(req.Name, req.Description, req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(tmpl)
+}
+
+func (s *Server) deleteTemplate(w http.ResponseWriter, name string) {
+	if err := s.stateStore.DeleteTemplate(name); err != nil {
+		status := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "deleted", "name": name})
+}
+
+// renderTemplate previews a prompt without starting a session.
+func (s *Server) renderTemplate(w http.ResponseWriter, r *http.Request, name string) {
+	var req RenderTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	prompt, err := s.stateStore.RenderTemplate(name, req.Vars, req.Prompt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"prompt": prompt})
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestHandleTemplatesLifecycle(t *testing.T) {
+	srv := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/templates", bytes.NewBufferString(`{"name":"add-tests","description":"Cover a package","body":"Add tests for {{pkg}}."}`))
+	w := httptest.NewRecorder()
+	srv.handleTemplates(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("create template failed: %d body=%s", w.Code, w.Body.String())
+	}
+	var tmpl state.PromptTemplate
+	if err := json.NewDecoder(w.Body).Decode(&tmpl); err != nil {
+		t.Fatalf("decode template failed: %v", err)
+	}
+	if tmpl.Name != "add-tests" || len(tmpl.Vars) != 1 || tmpl.Vars[0] != "pkg" {
+		t.Fatalf("unexpected template: %+v", tmpl)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/api/templates/add-tests", bytes.NewBufferString(`{"body":"Add table tests for {{pkg}}."}`))
+	w = httptest.NewRecorder()
+	srv.handleTemplateDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update template failed: %d body=%s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/templates/add-tests/render", bytes.NewBufferString(`{"vars":{"pkg":"internal/api"}}`))
+	w = httptest.NewRecorder()
+	srv.handleTemplateDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("render template failed: %d body=%s", w.Code, w.Body.String())
+	}
+	var rendered map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&rendered); err != nil {
+		t.Fatalf("decode render failed: %v", err)
+	}
+	if rendered["prompt"] != "Add table tests for internal/api." {
+		t.Fatalf("unexpected rendered prompt: %q", rendered["prompt"])
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/templates/add-tests/render", bytes.NewBufferString(`{}`))
+	w = httptest.NewRecorder()
+	srv.handleTemplateDetail(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for missing var, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/templates", nil)
+	w = httptest.NewRecorder()
+	srv.handleTemplates(w, req)
+	var list []state.PromptTemplate
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("decode list failed: %v", err)
+	}
+	if len(list) != 1 || list[0].Description != "" {
+		t.Fatalf("unexpected templates: %+v", list)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/templates/add-tests", nil)
+	w = httptest.NewRecorder()
+	srv.handleTemplateDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("delete template failed: %d body=%s", w.Code, w.Body.String())
+	}
+	req = httptest.NewRequest(http.MethodGet, "/api/templates/add-tests", nil)
+	w = httptest.NewRecorder()
+	srv.handleTemplateDetail(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", w.Code)
+	}
+}
+
+func TestCreateSessionRejectsUnknownTemplate(t *testing.T) {
+	srv := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/sessions", bytes.NewBufferString(`{"repo":"acme/api","template":"missing"}`))
+	w := httptest.NewRecorder()
+	srv.handleSessions(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown template, got %d body=%s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,240 @@
+package cloud
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Frame types of the device channel. A device sends one claim per free job
+// slot; the server answers each claim with a job frame as soon as a job is
+// queued for the device. Heartbeat, progress and complete frames mirror the
+// HTTP device endpoints and are answered with an ack carrying the same ID.
+const (
+	ChannelClaim     = "claim"
+	ChannelJob       = "job"
+	ChannelHeartbeat = "heartbeat"
+	ChannelProgress  = "progress"
+	ChannelComplete  = "complete"
+	ChannelAck       = "ack"
+)
+
+// ChannelPingInterval is how often the server pings connected devices.
+// Devices treat a connection silent for longer than twice this as dropped.
+const ChannelPingInterval = 30 * time.Second
+
+// ChannelMessage is one JSON frame on the device channel. Payload holds the
+// body of the matching HTTP endpoint; Status and Error report the outcome
+// in acks with the HTTP status the endpoint would have returned.
+type ChannelMessage struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	JobID   string          `json:"job_id,omitempty"`
+	Job     *Job            `json:"job,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Status  int             `json:"status,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+var channelUpgrader = websocket.Upgrader{
+	// Devices authenticate with their token, not cookies, so cross-origin
+	// handshakes carry no ambient credentials.
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// deviceConn is one connected device channel.
+type deviceConn struct {
+	deviceID string
+	conn     *websocket.Conn
+	writeMu  sync.Mutex
+	// wake is signalled when a job may be available for the device.
+	wake chan struct{}
+
+	mu     sync.Mutex
+	claims int
+}
+
+func (c *deviceConn) write(msg ChannelMessage) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return c.conn.WriteJSON(msg)
+}
+
+func (c *deviceConn) signal() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// handleDeviceConnect upgrades an authenticated device to the push channel.
+func (s *Server) handleDeviceConnect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	deviceID, token, err := deviceAuthFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err := s.store.AuthenticateDevice(deviceID, token); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	conn, err := channelUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade already replied to the client.
+		return
+	}
+
+	dc := &deviceConn{deviceID: deviceID, conn: conn, wake: make(chan struct{}, 1)}
+	s.addDeviceConn(dc)
+	defer s.removeDeviceConn(dc)
+	defer func() { _ = conn.Close() }()
+
+	done := make(chan struct{})
+	go s.pushJobs(dc, done)
+	defer close(done)
+
+	readTimeout := 2 * s.cfg.ChannelPingInterval
+	_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(readTimeout))
+	})
+	for {
+		var msg ChannelMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+		if msg.Type == ChannelClaim {
+			dc.mu.Lock()
+			dc.claims++
+			dc.mu.Unlock()
+			dc.signal()
+			continue
+		}
+		if err := dc.write(s.handleChannelRequest(deviceID, msg)); err != nil {
+			return
+		}
+	}
+}
+
+// handleChannelRequest runs a heartbeat, progress or complete frame and
+// returns its ack.
+func (s *Server) handleChannelRequest(deviceID string, msg ChannelMessage) ChannelMessage {
+	ack := ChannelMessage{Type: ChannelAck, ID: msg.ID, JobID: msg.JobID, Status: http.StatusOK}
+	var err error
+	switch msg.Type {
+	case ChannelHeartbeat:
+		var req jobHeartbeatRequest
+		if len(msg.Payload) > 0 {
+			err = json.Unmarshal(msg.Payload, &req)
+		}
+		if err == nil {
+			_, err = s.store.HeartbeatJob(msg.JobID, deviceID, req.Attempt, s.cfg.JobLeaseTTL)
+		}
+	case ChannelProgress:
+		var req jobProgressRequest
+		if err = json.Unmarshal(msg.Payload, &req); err == nil {
+			_, err = s.reportJobProgress(msg.JobID, deviceID, req)
+		}
+	case ChannelComplete:
+		var req jobCompleteRequest
+		if err = json.Unmarshal(msg.Payload, &req); err == nil {
+			_, err = s.completeJob(msg.JobID, deviceID, req)
+		}
+	default:
+		ack.Status, ack.Error = http.StatusNotFound, "unknown frame type "+msg.Type
+		return ack
+	}
+	switch {
+	case errors.Is(err, ErrJobLeaseLost):
+		ack.Status, ack.Error = http.StatusConflict, err.Error()
+	case err != nil:
+		ack.Status, ack.Error = http.StatusBadRequest, err.Error()
+	}
+	return ack
+}
+
+// pushJobs claims queued jobs for outstanding device claims whenever the
+// device is woken, and keeps the connection alive with pings.
+func (s *Server) pushJobs(dc *deviceConn, done <-chan struct{}) {
+	ping := time.NewTicker(s.cfg.ChannelPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ping.C:
+			dc.writeMu.Lock()
+			err := dc.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+			dc.writeMu.Unlock()
+			if err != nil {
+				_ = dc.conn.Close()
+				return
+			}
+			continue
+		case <-dc.wake:
+		}
+
+		for {
+			dc.mu.Lock()
+			claims := dc.claims
+			dc.mu.Unlock()
+			if claims == 0 {
+				break
+			}
+			job, found, err := s.store.ClaimNextJob(dc.deviceID, s.cfg.JobLeaseTTL)
+			if err != nil {
+				log.Printf("device %s: claim job: %v", dc.deviceID, err)
+				break
+			}
+			if !found {
+				break
+			}
+			dc.mu.Lock()
+			dc.claims--
+			dc.mu.Unlock()
+			if err := dc.write(ChannelMessage{Type: ChannelJob, JobID: job.ID, Job: &job}); err != nil {
+				// The job stays claimed; its lease expiry re-queues it.
+				_ = dc.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) addDeviceConn(dc *deviceConn) {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	if s.deviceConns[dc.deviceID] == nil {
+		s.deviceConns[dc.deviceID] = make(map[*deviceConn]struct{})
+	}
+	s.deviceConns[dc.deviceID][dc] = struct{}{}
+}
+
+func (s *Server) removeDeviceConn(dc *deviceConn) {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	delete(s.deviceConns[dc.deviceID], dc)
+	if len(s.deviceConns[dc.deviceID]) == 0 {
+		delete(s.deviceConns, dc.deviceID)
+	}
+}
+
+// wakeDevice tells the device's open channels that a job may be queued.
+func (s *Server) wakeDevice(deviceID string) {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	for dc := range s.deviceConns[deviceID] {
+		dc.signal()
+	}
+}
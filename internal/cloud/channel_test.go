@@ -0,0 +1,114 @@
+package cloud
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestDeviceChannelPushesJobsAndAcksRequests(t *testing.T) {
+	store := newCloudStore(t)
+	defer func() { _ = store.Close() }()
+
+	pairReq, err := store.CreatePairingRequest("T1", "U1", "C1", "111.222", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("create pairing request failed: %v", err)
+	}
+	claim, err := store.ClaimPairingRequest(pairReq.Code, "device-a", "")
+	if err != nil {
+		t.Fatalf("claim pairing failed: %v", err)
+	}
+
+	server, err := NewServer(store, Config{
+		ClientID:      "cid",
+		ClientSecret:  "secret",
+		SigningSecret: "signing-secret",
+		PublicURL:     "https://fogcloud.example",
+	})
+	if err != nil {
+		t.Fatalf("new server failed: %v", err)
+	}
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+	httpServer := newHTTPTestServerOrSkip(t, mux)
+	defer httpServer.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/v1/device/connect"
+	dial := func(token string) (*websocket.Conn, *http.Response, error) {
+		header := http.Header{}
+		header.Set("X-Fog-Device-ID", "device-a")
+		header.Set("Authorization", "Bearer "+token)
+		return websocket.DefaultDialer.Dial(wsURL, header)
+	}
+
+	if _, resp, err := dial("wrong"); err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for bad token, got %v", err)
+	}
+	conn, _, err := dial(claim.DeviceToken)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	read := func() ChannelMessage {
+		t.Helper()
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var msg ChannelMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("read frame failed: %v", err)
+		}
+		return msg
+	}
+
+	// The claim is held until a job is queued, then answered right away.
+	if err := conn.WriteJSON(ChannelMessage{Type: ChannelClaim}); err != nil {
+		t.Fatalf("write claim failed: %v", err)
+	}
+	job, err := store.EnqueueJob(Job{
+		DeviceID:    "device-a",
+		TeamID:      "T1",
+		ChannelID:   "C1",
+		RootTS:      "111.222",
+		SlackUserID: "U1",
+		Kind:        jobKindStartSession,
+		Repo:        "acme/api",
+		Prompt:      "add otp",
+	})
+	if err != nil {
+		t.Fatalf("enqueue job failed: %v", err)
+	}
+	server.wakeDevice("device-a")
+	pushed := read()
+	if pushed.Type != ChannelJob || pushed.Job == nil || pushed.Job.ID != job.ID || pushed.Job.State != jobStateClaimed {
+		t.Fatalf("unexpected pushed frame: %+v", pushed)
+	}
+
+	requests := []struct {
+		msg    ChannelMessage
+		status int
+	}{
+		{ChannelMessage{Type: ChannelHeartbeat, ID: "1", JobID: job.ID}, http.StatusOK},
+		{ChannelMessage{Type: ChannelProgress, ID: "2", JobID: job.ID, Payload: []byte(`{"phase":"AI_RUNNING"}`)}, http.StatusOK},
+		{ChannelMessage{Type: ChannelComplete, ID: "3", JobID: job.ID, Payload: []byte(`{"success":true,"branch":"fog/otp"}`)}, http.StatusOK},
+		{ChannelMessage{Type: ChannelHeartbeat, ID: "4", JobID: job.ID}, http.StatusConflict},
+	}
+	for _, req := range requests {
+		if err := conn.WriteJSON(req.msg); err != nil {
+			t.Fatalf("write %s failed: %v", req.msg.Type, err)
+		}
+		ack := read()
+		if ack.Type != ChannelAck || ack.ID != req.msg.ID || ack.Status != req.status {
+			t.Fatalf("unexpected ack for %s: %+v", req.msg.Type, ack)
+		}
+	}
+
+	got, _, err := store.GetJob(job.ID)
+	if err != nil {
+		t.Fatalf("get job failed: %v", err)
+	}
+	if got.State != jobStateCompleted || got.Phase != "AI_RUNNING" || got.Branch != "fog/otp" {
+		t.Fatalf("unexpected job after channel requests: %+v", got)
+	}
+}
@@ -2,6 +2,7 @@ package cloud
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
@@ -10,12 +11,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/darkLord19/foglet/internal/slackblocks"
+	"github.com/darkLord19/foglet/internal/slackcmd"
 )
 
 const (
@@ -37,6 +42,13 @@ type Config struct {
 	APIBaseURL     string
 	StateTTL       time.Duration
 	PairingCodeTTL time.Duration
+
+	// JobLeaseTTL is how long a device holds a claimed job between
+	// heartbeats; JobMaxAttempts caps deliveries before a job is abandoned.
+	JobLeaseTTL    time.Duration
+	JobMaxAttempts int
+	// ChannelPingInterval is how often connected devices are pinged.
+	ChannelPingInterval time.Duration
 }
 
 // Server provides multi-tenant Slack install/event handling and device routing APIs.
@@ -47,6 +59,9 @@ type Server struct {
 
 	stateMu     sync.Mutex
 	oauthStates map[string]time.Time
+
+	connMu      sync.Mutex
+	deviceConns map[string]map[*deviceConn]struct{}
 }
 
 // NewServer creates a new fog cloud server.
@@ -83,12 +98,22 @@ func NewServer(store *Store, cfg Config) (*Server, error) {
 	if cfg.PairingCodeTTL <= 0 {
 		cfg.PairingCodeTTL = 10 * time.Minute
 	}
+	if cfg.JobLeaseTTL <= 0 {
+		cfg.JobLeaseTTL = DefaultJobLeaseTTL
+	}
+	if cfg.JobMaxAttempts <= 0 {
+		cfg.JobMaxAttempts = DefaultJobMaxAttempts
+	}
+	if cfg.ChannelPingInterval <= 0 {
+		cfg.ChannelPingInterval = ChannelPingInterval
+	}
 
 	return &Server{
 		store:       store,
 		cfg:         cfg,
 		httpClient:  cfg.HTTPClient,
 		oauthStates: make(map[string]time.Time),
+		deviceConns: make(map[string]map[*deviceConn]struct{}),
 	}, nil
 }
 
@@ -97,10 +122,12 @@ func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/slack/install", s.handleInstall)
 	mux.HandleFunc("/slack/oauth/callback", s.handleOAuthCallback)
 	mux.HandleFunc("/slack/events", s.handleEvents)
+	mux.HandleFunc("/slack/interactivity", s.handleInteractivity)
 	mux.HandleFunc("/v1/pair/claim", s.handlePairClaim)
 	mux.HandleFunc("/v1/pair/unpair", s.handlePairUnpair)
 	mux.HandleFunc("/v1/device/jobs/claim", s.handleDeviceClaimJob)
 	mux.HandleFunc("/v1/device/jobs/", s.handleDeviceJobDetail)
+	mux.HandleFunc("/v1/device/connect", s.handleDeviceConnect)
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
@@ -218,6 +245,93 @@ func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleInteractivity handles clicks on the buttons of run messages. Each
+// button queues the session command it names on the clicking user's paired
+// device, in the thread of the message.
+func (s *Server) handleInteractivity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 2<<20))
+	if err != nil {
+		http.Error(w, "read body failed", http.StatusBadRequest)
+		return
+	}
+	if err := verifySlackSignature(s.cfg.SigningSecret, r.Header, body, time.Now().UTC()); err != nil {
+		http.Error(w, "invalid slack signature", http.StatusUnauthorized)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid form body", http.StatusBadRequest)
+		return
+	}
+	in, err := slackblocks.ParseInteraction([]byte(form.Get("payload")))
+	if err != nil {
+		// Slack sends other interactions (and clicks on link buttons) here
+		// too; they need no reply.
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	// Slack expects an answer within three seconds, so the job is queued
+	// and announced after the response has gone out.
+	w.WriteHeader(http.StatusOK)
+	go func() {
+		if err := s.handleRunAction(in); err != nil {
+			log.Printf("slack interaction %s: %v", in.ActionID, err)
+		}
+	}()
+}
+
+func (s *Server) handleRunAction(in slackblocks.Interaction) error {
+	kind, ok := slackblocks.CommandFor(in.ActionID)
+	if !ok {
+		return fmt.Errorf("unknown action %q", in.ActionID)
+	}
+	deviceID, paired, err := s.store.GetPairing(in.TeamID, in.UserID)
+	if err != nil {
+		return err
+	}
+	if !paired {
+		return s.postEphemeral(in.TeamID, in.ChannelID, in.UserID, "Fog is not paired yet. Mention @fog to get a pairing code.")
+	}
+	sessionID := strings.TrimSpace(in.SessionID)
+	if sessionID == "" {
+		if sessionID, _, err = s.store.GetThreadSession(in.TeamID, in.ChannelID, in.ThreadTS); err != nil {
+			return err
+		}
+	}
+	if sessionID == "" {
+		return s.postEphemeral(in.TeamID, in.ChannelID, in.UserID, "No Fog session is linked to this thread.")
+	}
+	// Anyone in the channel can click the buttons, but the session only
+	// exists on the device of the user who started it.
+	owner, found, err := s.store.GetSessionDevice(in.TeamID, sessionID)
+	if err != nil {
+		return err
+	}
+	if !found || owner != deviceID {
+		return s.postEphemeral(in.TeamID, in.ChannelID, in.UserID, "This session runs on another user's Fog device, so only they can use its buttons.")
+	}
+	job, err := s.store.EnqueueJob(Job{
+		DeviceID:    deviceID,
+		TeamID:      in.TeamID,
+		ChannelID:   in.ChannelID,
+		RootTS:      in.ThreadTS,
+		SlackUserID: in.UserID,
+		Kind:        kind,
+		SessionID:   sessionID,
+	})
+	if err != nil {
+		return err
+	}
+	s.wakeDevice(deviceID)
+	text := fmt.Sprintf("🚀 <@%s> queued `%s` on their paired Fog device (job `%s`).", in.UserID, strings.ReplaceAll(kind, "_", " "), job.ID)
+	return s.postMessage(in.TeamID, in.ChannelID, in.ThreadTS, text)
+}
+
 func (s *Server) handleAppMention(teamID string, event slackInnerEvent) error {
 	if strings.TrimSpace(event.Channel) == "" || strings.TrimSpace(event.User) == "" {
 		return nil
@@ -261,7 +375,37 @@ func (s *Server) handleAppMention(teamID string, event slackInnerEvent) error {
 		SlackUserID: event.User,
 	}
 
-	if isFollowUp {
+	cmd, isCommand, parseErr := slackcmd.Parse(rawPrompt)
+	if parseErr != nil {
+		_ = s.postMessage(teamID, event.Channel, rootTS, "❌ "+parseErr.Error())
+		return nil
+	}
+
+	switch {
+	case isCommand && cmd.Action == slackcmd.ActionHelp:
+		return s.postMessage(teamID, event.Channel, rootTS, slackcmd.Usage)
+	case isCommand:
+		if cmd.SessionID == "" && cmd.NeedsSession() {
+			sessionID, found, getErr := s.store.GetThreadSession(teamID, event.Channel, rootTS)
+			if getErr != nil {
+				return getErr
+			}
+			if !found {
+				_ = s.postMessage(teamID, event.Channel, rootTS, "❌ No Fog session found for this thread. Pass a session ID; `sessions` lists them.")
+				return nil
+			}
+			cmd.SessionID = sessionID
+		}
+		job.Kind = cmd.Action
+		job.SessionID = cmd.SessionID
+		job.MergeMethod = cmd.MergeMethod
+		job.BranchName = cmd.Branch
+		job.Tool = cmd.Tool
+		job.Model = cmd.Model
+		// Jobs always carry a prompt; commands other than fork keep their
+		// text for the record.
+		job.Prompt = fallback(cmd.Prompt, rawPrompt)
+	case isFollowUp:
 		prompt, parseErr := normalizeFollowUpPrompt(rawPrompt)
 		if parseErr != nil {
 			_ = s.postMessage(teamID, event.Channel, rootTS, "❌ "+parseErr.Error())
@@ -278,7 +422,7 @@ func (s *Server) handleAppMention(teamID string, event slackInnerEvent) error {
 		job.Kind = jobKindFollowUp
 		job.SessionID = sessionID
 		job.Prompt = prompt
-	} else {
+	default:
 		parsed, parseErr := parseCommandText(rawPrompt)
 		if parseErr != nil {
 			_ = s.postMessage(teamID, event.Channel, rootTS, "❌ "+parseErr.Error())
@@ -298,6 +442,7 @@ func (s *Server) handleAppMention(teamID string, event slackInnerEvent) error {
 	if err != nil {
 		return err
 	}
+	s.wakeDevice(deviceID)
 	text := fmt.Sprintf("🚀 Queued on your paired Fog device (job `%s`).", enqueued.ID)
 	return s.postMessage(teamID, event.Channel, rootTS, text)
 }
@@ -375,7 +520,7 @@ func (s *Server) handleDeviceClaimJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	job, found, err := s.store.ClaimNextJob(deviceID)
+	job, found, err := s.store.ClaimNextJob(deviceID, s.cfg.JobLeaseTTL)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -395,7 +540,13 @@ func (s *Server) handleDeviceJobDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	parts := strings.Split(path, "/")
-	if len(parts) != 2 || parts[1] != "complete" || r.Method != http.MethodPost {
+	if len(parts) != 2 || r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	switch parts[1] {
+	case "complete", "heartbeat", "progress":
+	default:
 		http.NotFound(w, r)
 		return
 	}
@@ -414,25 +565,64 @@ func (s *Server) handleDeviceJobDetail(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
-
-	var req struct {
-		Success   bool   `json:"success"`
-		Error     string `json:"error,omitempty"`
-		SessionID string `json:"session_id,omitempty"`
-		RunID     string `json:"run_id,omitempty"`
-		Branch    string `json:"branch,omitempty"`
-		PRURL     string `json:"pr_url,omitempty"`
-		CommitSHA string `json:"commit_sha,omitempty"`
-		CommitMsg string `json:"commit_msg,omitempty"`
+	switch parts[1] {
+	case "heartbeat":
+		s.heartbeatJob(w, r, jobID, deviceID)
+		return
+	case "progress":
+		s.recordJobProgress(w, r, jobID, deviceID)
+		return
 	}
+
+	var req jobCompleteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "invalid json", http.StatusBadRequest)
 		return
 	}
+	job, err := s.completeJob(jobID, deviceID, req)
+	if errors.Is(err, ErrJobLeaseLost) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"status": "ok",
+		"job_id": job.ID,
+	})
+}
+
+// jobCompleteRequest is the body of a job completion, over HTTP or the
+// device channel.
+type jobCompleteRequest struct {
+	// Attempt is the claim the result belongs to (the job's attempts when
+	// the device claimed it); zero skips the check.
+	Attempt   int    `json:"attempt,omitempty"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+	RunID     string `json:"run_id,omitempty"`
+	Branch    string `json:"branch,omitempty"`
+	PRURL     string `json:"pr_url,omitempty"`
+	CommitSHA string `json:"commit_sha,omitempty"`
+	CommitMsg string `json:"commit_msg,omitempty"`
+	// Message is the result of a session command, posted instead of the
+	// run summary.
+	Message string `json:"message,omitempty"`
+	// Validation and DiffStat describe the finished run: the result of its
+	// validation ("passed", "failed" or empty) and the branch's diff stat.
+	Validation string `json:"validation,omitempty"`
+	DiffStat   string `json:"diff_stat,omitempty"`
+}
 
+// completeJob stores a job result and reports it in the Slack thread.
+func (s *Server) completeJob(jobID, deviceID string, req jobCompleteRequest) (Job, error) {
 	job, err := s.store.CompleteJob(JobCompletion{
 		JobID:     jobID,
 		DeviceID:  deviceID,
+		Attempt:   req.Attempt,
 		Success:   req.Success,
 		Error:     req.Error,
 		SessionID: req.SessionID,
@@ -443,31 +633,221 @@ func (s *Server) handleDeviceJobDetail(w http.ResponseWriter, r *http.Request) {
 		CommitMsg: req.CommitMsg,
 	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+		return Job{}, err
 	}
 
 	if req.Success && strings.TrimSpace(job.Kind) == jobKindStartSession && strings.TrimSpace(job.SessionID) != "" {
 		_ = s.store.UpsertThreadSession(job.TeamID, job.ChannelID, job.RootTS, job.SessionID)
 	}
+	// A fork asked for outside a session thread starts one for the fork.
+	if req.Success && strings.TrimSpace(job.Kind) == jobKindFork && strings.TrimSpace(job.SessionID) != "" {
+		if _, found, err := s.store.GetThreadSession(job.TeamID, job.ChannelID, job.RootTS); err == nil && !found {
+			_ = s.store.UpsertThreadSession(job.TeamID, job.ChannelID, job.RootTS, job.SessionID)
+		}
+	}
 
-	if req.Success {
+	switch {
+	case req.Success && strings.TrimSpace(req.Message) != "":
+		_ = s.postMessage(job.TeamID, job.ChannelID, job.RootTS, req.Message)
+	case jobRunsTask(job.Kind) && strings.TrimSpace(job.SessionID) != "":
+		run := slackblocks.Run{
+			SessionID:  job.SessionID,
+			RunID:      job.RunID,
+			Branch:     fallback(job.Branch, job.BranchName),
+			Succeeded:  req.Success,
+			Error:      fallback(job.Error, req.Error),
+			CommitSHA:  req.CommitSHA,
+			PRURL:      job.PRURL,
+			Validation: req.Validation,
+			DiffStat:   req.DiffStat,
+		}
+		_ = s.postBlocks(job.TeamID, job.ChannelID, job.RootTS, run.Summary(), slackblocks.RunBlocks(run))
+	case req.Success:
 		text := fmt.Sprintf("✅ Completed on branch `%s`.", fallback(job.Branch, job.BranchName))
 		if strings.TrimSpace(job.PRURL) != "" {
 			text += "\nPR: " + job.PRURL
 		}
 		_ = s.postMessage(job.TeamID, job.ChannelID, job.RootTS, text)
-	} else {
-		errText := fallback(job.Error, req.Error)
-		_ = s.postMessage(job.TeamID, job.ChannelID, job.RootTS, "❌ Task failed: "+fallback(errText, "unknown error"))
+	default:
+		errText := fallback(fallback(job.Error, req.Error), "unknown error")
+		if !jobRunsTask(job.Kind) {
+			_ = s.postMessage(job.TeamID, job.ChannelID, job.RootTS, "❌ "+errText)
+			break
+		}
+		_ = s.postMessage(job.TeamID, job.ChannelID, job.RootTS, "❌ Task failed: "+errText)
 	}
+	return job, nil
+}
 
-	writeJSON(w, http.StatusOK, map[string]string{
-		"status": "ok",
-		"job_id": job.ID,
+// jobRunsTask reports whether a job kind runs the AI tool, as opposed to a
+// session command that only inspects or acts on a session.
+func jobRunsTask(kind string) bool {
+	switch strings.TrimSpace(kind) {
+	case jobKindStartSession, jobKindFollowUp, jobKindFork, jobKindRetry:
+		return true
+	default:
+		return false
+	}
+}
+
+// jobHeartbeatRequest is the optional body of a heartbeat, over HTTP or the
+// device channel.
+type jobHeartbeatRequest struct {
+	Attempt int `json:"attempt,omitempty"`
+}
+
+// heartbeatJob extends the lease of a running job. 409 tells the device the
+// job was taken back (or already finished) and its result will be rejected.
+func (s *Server) heartbeatJob(w http.ResponseWriter, r *http.Request, jobID, deviceID string) {
+	var req jobHeartbeatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	job, err := s.store.HeartbeatJob(jobID, deviceID, req.Attempt, s.cfg.JobLeaseTTL)
+	if errors.Is(err, ErrJobLeaseLost) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":           "ok",
+		"job_id":           job.ID,
+		"lease_expires_at": job.LeaseExpiresAt,
 	})
 }
 
+// recordJobProgress stores a progress update from the device and relays it
+// to the job's Slack thread. Like heartbeats, 409 means the job was taken
+// back or already finished.
+func (s *Server) recordJobProgress(w http.ResponseWriter, r *http.Request, jobID, deviceID string) {
+	var req jobProgressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	job, err := s.reportJobProgress(jobID, deviceID, req)
+	if errors.Is(err, ErrJobLeaseLost) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":           "ok",
+		"job_id":           job.ID,
+		"lease_expires_at": job.LeaseExpiresAt,
+	})
+}
+
+// jobProgressRequest is the body of a progress update, over HTTP or the
+// device channel.
+type jobProgressRequest struct {
+	Attempt   int    `json:"attempt,omitempty"`
+	Phase     string `json:"phase"`
+	Message   string `json:"message,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+	RunID     string `json:"run_id,omitempty"`
+	Branch    string `json:"branch,omitempty"`
+	PRURL     string `json:"pr_url,omitempty"`
+}
+
+func (s *Server) reportJobProgress(jobID, deviceID string, req jobProgressRequest) (Job, error) {
+	job, err := s.store.RecordJobProgress(JobProgress{
+		JobID:     jobID,
+		DeviceID:  deviceID,
+		Attempt:   req.Attempt,
+		Phase:     req.Phase,
+		SessionID: req.SessionID,
+		RunID:     req.RunID,
+		Branch:    req.Branch,
+		PRURL:     req.PRURL,
+	}, s.cfg.JobLeaseTTL)
+	if err != nil {
+		return Job{}, err
+	}
+	text := progressText(job, req.Message)
+	switch {
+	case text == "":
+	case job.Phase == "AI_RUNNING":
+		// The run is long from here on; offer to stop it.
+		_ = s.postBlocks(job.TeamID, job.ChannelID, job.RootTS, text, slackblocks.RunningBlocks(text, job.SessionID))
+	default:
+		_ = s.postMessage(job.TeamID, job.ChannelID, job.RootTS, text)
+	}
+	return job, nil
+}
+
+// progressText renders a progress phase as a Slack thread message. Phases
+// are the runner's run states plus VALIDATION_FAILED; other phases, such as
+// STARTED for session commands, post the device's message.
+func progressText(job Job, message string) string {
+	message = strings.TrimSpace(message)
+	switch strings.TrimSpace(job.Phase) {
+	case "QUEUED":
+		return "⏳ Queued behind the session's active run."
+	case "SETUP":
+		return fmt.Sprintf("⚙️ Setting up branch `%s`.", fallback(job.Branch, job.BranchName))
+	case "AI_RUNNING":
+		return "🤖 AI tool is working on it."
+	case "VALIDATING":
+		return "🧪 Validating changes."
+	case "VALIDATION_FAILED":
+		return "⚠️ " + fallback(message, "Validation failed.")
+	case "COMMITTED":
+		return "📦 Committing changes."
+	case "PR_CREATED":
+		return "🔗 Draft PR created: " + job.PRURL
+	default:
+		return message
+	}
+}
+
+// RunJobReaper takes back jobs whose device stopped heartbeating, every
+// interval until ctx is cancelled. Jobs are re-delivered up to
+// JobMaxAttempts times; abandoned jobs are reported in their Slack thread.
+func (s *Server) RunJobReaper(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = s.cfg.JobLeaseTTL / 4
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.reapExpiredJobs(); err != nil {
+			log.Printf("job reaper: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) reapExpiredJobs() error {
+	expired, err := s.store.ExpireJobLeases(s.cfg.JobMaxAttempts)
+	if err != nil {
+		return err
+	}
+	for _, job := range expired {
+		if !job.Abandoned {
+			log.Printf("job %s: lease expired, re-queued (attempt %d of %d)", job.ID, job.Attempts, s.cfg.JobMaxAttempts)
+			s.wakeDevice(job.DeviceID)
+			continue
+		}
+		text := fmt.Sprintf("⚠️ Job `%s` was abandoned: your Fog device stopped responding after %d attempt(s). Make sure it is awake and online, then try again.", job.ID, job.Attempts)
+		if err := s.postMessage(job.TeamID, job.ChannelID, job.RootTS, text); err != nil {
+			log.Printf("job %s: post abandoned message: %v", job.ID, err)
+		}
+	}
+	return nil
+}
+
 func deviceAuthFromRequest(r *http.Request) (string, string, error) {
 	deviceID := strings.TrimSpace(r.Header.Get("X-Fog-Device-ID"))
 	if deviceID == "" {
@@ -542,6 +922,11 @@ func (s *Server) postEphemeral(teamID, channelID, userID, text string) error {
 }
 
 func (s *Server) postMessage(teamID, channelID, threadTS, text string) error {
+	return s.postBlocks(teamID, channelID, threadTS, text, nil)
+}
+
+// postBlocks posts a Block Kit message; text is its notification fallback.
+func (s *Server) postBlocks(teamID, channelID, threadTS, text string, blocks []slackblocks.Block) error {
 	inst, found, err := s.store.GetInstallation(teamID)
 	if err != nil {
 		return err
@@ -550,13 +935,16 @@ func (s *Server) postMessage(teamID, channelID, threadTS, text string) error {
 		return fmt.Errorf("installation not found for team %s", teamID)
 	}
 
-	payload := map[string]string{
+	payload := map[string]any{
 		"channel": channelID,
 		"text":    text,
 	}
 	if strings.TrimSpace(threadTS) != "" {
 		payload["thread_ts"] = threadTS
 	}
+	if len(blocks) > 0 {
+		payload["blocks"] = blocks
+	}
 
 	body, err := json.Marshal(payload)
 	if err != nil {
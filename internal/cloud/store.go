@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/darkLord19/foglet/internal/slackcmd"
 	"github.com/google/uuid"
 	_ "modernc.org/sqlite"
 )
@@ -20,6 +21,11 @@ const (
 	defaultKeyName = "cloud.key"
 )
 
+// ErrJobLeaseLost is returned for heartbeats, progress and completions of a
+// job the device no longer holds: it finished, or its lease expired and it
+// was taken back, possibly for a later attempt.
+var ErrJobLeaseLost = errors.New("job lease lost")
+
 // Store persists multi-tenant Slack routing metadata.
 type Store struct {
 	db  *sql.DB
@@ -63,6 +69,24 @@ const (
 
 	jobKindStartSession = "start_session"
 	jobKindFollowUp     = "follow_up"
+	// Session command kinds are the slackcmd actions; the device runs them
+	// against an existing session, except list_sessions.
+	jobKindListSessions = slackcmd.ActionSessions
+	jobKindStatus       = slackcmd.ActionStatus
+	jobKindCancel       = slackcmd.ActionCancel
+	jobKindDiff         = slackcmd.ActionDiff
+	jobKindFork         = slackcmd.ActionFork
+	jobKindRetry        = slackcmd.ActionRetry
+	jobKindPRCreate     = slackcmd.ActionPRCreate
+	jobKindPROpen       = slackcmd.ActionPROpen
+	jobKindPRMerge      = slackcmd.ActionPRMerge
+
+	// DefaultJobLeaseTTL is how long a claimed job stays with its device
+	// without a heartbeat.
+	DefaultJobLeaseTTL = 2 * time.Minute
+	// DefaultJobMaxAttempts is how many times a job is delivered before it
+	// is abandoned.
+	DefaultJobMaxAttempts = 3
 )
 
 // Job is a queued message routing unit for one paired device.
@@ -91,12 +115,31 @@ type Job struct {
 	UpdatedAt   time.Time
 	ClaimedAt   *time.Time
 	CompletedAt *time.Time
+	// Attempts counts deliveries to the device; LeaseExpiresAt is when a
+	// claimed job without heartbeats is taken back.
+	Attempts       int
+	LeaseExpiresAt *time.Time
+	// Phase is the last progress phase the device reported for this
+	// delivery.
+	Phase string
+	// MergeMethod is the requested merge method of pr_merge jobs.
+	MergeMethod string
+}
+
+// ExpiredJob is a claimed job whose lease ran out, in its new state:
+// queued again, or failed once it used up its attempts.
+type ExpiredJob struct {
+	Job
+	Abandoned bool
 }
 
 // JobCompletion stores completion payload submitted by device runtime.
 type JobCompletion struct {
-	JobID     string
-	DeviceID  string
+	JobID    string
+	DeviceID string
+	// Attempt is the delivery the result belongs to (Job.Attempts when it
+	// was claimed). Zero skips the check, for devices that predate it.
+	Attempt   int
 	Success   bool
 	Error     string
 	SessionID string
@@ -107,6 +150,20 @@ type JobCompletion struct {
 	CommitMsg string
 }
 
+// JobProgress is an intermediate update submitted by the device while a
+// job runs. Empty fields leave the stored values unchanged.
+type JobProgress struct {
+	JobID    string
+	DeviceID string
+	// Attempt is checked like JobCompletion.Attempt.
+	Attempt   int
+	Phase     string
+	SessionID string
+	RunID     string
+	Branch    string
+	PRURL     string
+}
+
 // NewStore opens or creates cloud sqlite state in dataDir.
 func NewStore(dataDir string) (*Store, error) {
 	if err := os.MkdirAll(dataDir, 0o755); err != nil {
@@ -120,7 +177,11 @@ func NewStore(dataDir string) (*Store, error) {
 	}
 
 	dbPath := filepath.Join(dataDir, defaultDBName)
-	db, err := sql.Open("sqlite", dbPath)
+	// Pragmas go in the DSN so every pooled connection gets them. Immediate
+	// transactions take the write lock up front, so concurrent job claims
+	// wait on busy_timeout instead of failing when a read upgrades to a write.
+	dsn := dbPath + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate"
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("open sqlite: %w", err)
 	}
@@ -221,6 +282,33 @@ func (s *Store) init() error {
 			return fmt.Errorf("init schema: %w", err)
 		}
 	}
+	return s.ensureJobsSchema()
+}
+
+func (s *Store) ensureJobsSchema() error {
+	columns := []struct {
+		name string
+		ddl  string
+	}{
+		{name: "attempts", ddl: `ALTER TABLE jobs ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0`},
+		{name: "lease_expires_at", ddl: `ALTER TABLE jobs ADD COLUMN lease_expires_at TEXT`},
+		{name: "phase", ddl: `ALTER TABLE jobs ADD COLUMN phase TEXT NOT NULL DEFAULT ''`},
+		{name: "merge_method", ddl: `ALTER TABLE jobs ADD COLUMN merge_method TEXT NOT NULL DEFAULT ''`},
+	}
+	for _, column := range columns {
+		var count int
+		if err := s.db.QueryRow(
+			`SELECT COUNT(1) FROM pragma_table_info('jobs') WHERE name = ?`, column.name,
+		).Scan(&count); err != nil {
+			return fmt.Errorf("check jobs.%s column: %w", column.name, err)
+		}
+		if count > 0 {
+			continue
+		}
+		if _, err := s.db.Exec(column.ddl); err != nil {
+			return fmt.Errorf("add jobs.%s column: %w", column.name, err)
+		}
+	}
 	return nil
 }
 
@@ -700,6 +788,31 @@ func (s *Store) GetThreadSession(teamID, channelID, rootTS string) (string, bool
 	return sessionID, true, nil
 }
 
+// GetSessionDevice returns the device of the latest job for a session in a
+// workspace, which is the device the session lives on.
+func (s *Store) GetSessionDevice(teamID, sessionID string) (string, bool, error) {
+	teamID = strings.TrimSpace(teamID)
+	sessionID = strings.TrimSpace(sessionID)
+	if teamID == "" || sessionID == "" {
+		return "", false, errors.New("team_id and session_id are required")
+	}
+	var deviceID string
+	err := s.db.QueryRow(
+		`SELECT device_id FROM jobs
+		  WHERE team_id = ? AND session_id = ?
+		  ORDER BY created_at DESC
+		  LIMIT 1`,
+		teamID, sessionID,
+	).Scan(&deviceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("get session device: %w", err)
+	}
+	return deviceID, true, nil
+}
+
 // EnqueueJob inserts one new queued job for a paired device.
 func (s *Store) EnqueueJob(job Job) (Job, error) {
 	job.DeviceID = strings.TrimSpace(job.DeviceID)
@@ -715,13 +828,19 @@ func (s *Store) EnqueueJob(job Job) (Job, error) {
 	job.CommitMsg = strings.TrimSpace(job.CommitMsg)
 	job.Prompt = strings.TrimSpace(job.Prompt)
 	job.SessionID = strings.TrimSpace(job.SessionID)
+	job.MergeMethod = strings.TrimSpace(job.MergeMethod)
 	if job.ID == "" {
 		job.ID = uuid.NewString()
 	}
 	if job.State == "" {
 		job.State = jobStateQueued
 	}
-	if job.DeviceID == "" || job.TeamID == "" || job.ChannelID == "" || job.RootTS == "" || job.SlackUserID == "" || job.Kind == "" || job.Prompt == "" {
+	if job.DeviceID == "" || job.TeamID == "" || job.ChannelID == "" || job.RootTS == "" || job.SlackUserID == "" || job.Kind == "" {
+		return Job{}, errors.New("job missing required fields")
+	}
+	// Session commands from buttons carry no text; a fork without a prompt
+	// reuses the session's latest one.
+	if job.Prompt == "" && (job.Kind == jobKindStartSession || job.Kind == jobKindFollowUp) {
 		return Job{}, errors.New("job missing required fields")
 	}
 	switch job.Kind {
@@ -729,10 +848,11 @@ func (s *Store) EnqueueJob(job Job) (Job, error) {
 		if job.Repo == "" {
 			return Job{}, errors.New("start_session requires repo")
 		}
-	case jobKindFollowUp:
+	case jobKindFollowUp, jobKindStatus, jobKindCancel, jobKindDiff, jobKindFork, jobKindRetry, jobKindPRCreate, jobKindPROpen, jobKindPRMerge:
 		if job.SessionID == "" {
-			return Job{}, errors.New("follow_up requires session_id")
+			return Job{}, fmt.Errorf("%s requires session_id", job.Kind)
 		}
+	case jobKindListSessions:
 	default:
 		return Job{}, fmt.Errorf("unknown job kind %q", job.Kind)
 	}
@@ -747,8 +867,8 @@ func (s *Store) EnqueueJob(job Job) (Job, error) {
 		`INSERT INTO jobs(
 			id, device_id, team_id, channel_id, root_ts, slack_user_id, kind, repo, tool, model, autopr,
 			branch_name, commit_msg, prompt, session_id, run_id, branch, pr_url, state, error,
-			created_at, updated_at, claimed_at, completed_at, commit_sha, commit_msg_result
-		) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULL, NULL, '', '')`,
+			created_at, updated_at, claimed_at, completed_at, commit_sha, commit_msg_result, merge_method
+		) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULL, NULL, '', '', ?)`,
 		job.ID,
 		job.DeviceID,
 		job.TeamID,
@@ -771,6 +891,7 @@ func (s *Store) EnqueueJob(job Job) (Job, error) {
 		"",
 		job.CreatedAt.Format(time.RFC3339Nano),
 		job.UpdatedAt.Format(time.RFC3339Nano),
+		job.MergeMethod,
 	)
 	if err != nil {
 		return Job{}, fmt.Errorf("enqueue job: %w", err)
@@ -778,12 +899,18 @@ func (s *Store) EnqueueJob(job Job) (Job, error) {
 	return job, nil
 }
 
-// ClaimNextJob atomically transitions the oldest queued job for device to claimed.
-func (s *Store) ClaimNextJob(deviceID string) (Job, bool, error) {
+// ClaimNextJob atomically transitions the next queued job for device to
+// claimed, leased for leaseTTL. Cancel jobs go first and quick read-only
+// commands next, so they are not stuck behind queued runs; otherwise jobs
+// are claimed oldest first.
+func (s *Store) ClaimNextJob(deviceID string, leaseTTL time.Duration) (Job, bool, error) {
 	deviceID = strings.TrimSpace(deviceID)
 	if deviceID == "" {
 		return Job{}, false, errors.New("device_id is required")
 	}
+	if leaseTTL <= 0 {
+		leaseTTL = DefaultJobLeaseTTL
+	}
 	for range 4 {
 		tx, err := s.db.Begin()
 		if err != nil {
@@ -794,10 +921,19 @@ func (s *Store) ClaimNextJob(deviceID string) (Job, bool, error) {
 		err = tx.QueryRow(
 			`SELECT id FROM jobs
 			  WHERE device_id = ? AND state = ?
-			  ORDER BY created_at ASC
+			  ORDER BY CASE
+			             WHEN kind = ? THEN 0
+			             WHEN kind IN (?, ?, ?) THEN 1
+			             ELSE 2
+			           END,
+			           created_at ASC
 			  LIMIT 1`,
 			deviceID,
 			jobStateQueued,
+			jobKindCancel,
+			jobKindListSessions,
+			jobKindStatus,
+			jobKindDiff,
 		).Scan(&jobID)
 		if errors.Is(err, sql.ErrNoRows) {
 			_ = tx.Rollback()
@@ -808,13 +944,15 @@ func (s *Store) ClaimNextJob(deviceID string) (Job, bool, error) {
 			return Job{}, false, fmt.Errorf("claim select: %w", err)
 		}
 
+		now := time.Now().UTC()
 		res, err := tx.Exec(
 			`UPDATE jobs
-			    SET state = ?, claimed_at = ?, updated_at = ?
+			    SET state = ?, claimed_at = ?, updated_at = ?, attempts = attempts + 1, lease_expires_at = ?
 			  WHERE id = ? AND state = ?`,
 			jobStateClaimed,
-			nowRFC3339Nano(),
-			nowRFC3339Nano(),
+			now.Format(time.RFC3339Nano),
+			now.Format(time.RFC3339Nano),
+			now.Add(leaseTTL).Format(time.RFC3339Nano),
 			jobID,
 			jobStateQueued,
 		)
@@ -849,7 +987,207 @@ func (s *Store) ClaimNextJob(deviceID string) (Job, bool, error) {
 	return Job{}, false, nil
 }
 
-// CompleteJob stores terminal status and execution outputs.
+// HeartbeatJob extends the lease of a job the device still holds for the
+// given attempt (zero for any attempt).
+func (s *Store) HeartbeatJob(jobID, deviceID string, attempt int, leaseTTL time.Duration) (Job, error) {
+	jobID = strings.TrimSpace(jobID)
+	deviceID = strings.TrimSpace(deviceID)
+	if jobID == "" || deviceID == "" {
+		return Job{}, errors.New("job_id and device_id are required")
+	}
+	if leaseTTL <= 0 {
+		leaseTTL = DefaultJobLeaseTTL
+	}
+	now := time.Now().UTC()
+	res, err := s.db.Exec(
+		`UPDATE jobs
+		    SET lease_expires_at = ?, updated_at = ?
+		  WHERE id = ? AND device_id = ? AND state = ? AND (? = 0 OR attempts = ?)`,
+		now.Add(leaseTTL).Format(time.RFC3339Nano),
+		now.Format(time.RFC3339Nano),
+		jobID,
+		deviceID,
+		jobStateClaimed,
+		attempt, attempt,
+	)
+	if err != nil {
+		return Job{}, fmt.Errorf("heartbeat job: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return Job{}, fmt.Errorf("rows affected: %w", err)
+	}
+	if rows == 0 {
+		return Job{}, ErrJobLeaseLost
+	}
+	job, found, err := s.GetJob(jobID)
+	if err != nil {
+		return Job{}, err
+	}
+	if !found {
+		return Job{}, errors.New("job not found after update")
+	}
+	return job, nil
+}
+
+// RecordJobProgress stores a progress update for a job claimed by the
+// device. Progress doubles as a heartbeat and renews the lease.
+func (s *Store) RecordJobProgress(progress JobProgress, leaseTTL time.Duration) (Job, error) {
+	progress.JobID = strings.TrimSpace(progress.JobID)
+	progress.DeviceID = strings.TrimSpace(progress.DeviceID)
+	progress.Phase = strings.TrimSpace(progress.Phase)
+	if progress.JobID == "" || progress.DeviceID == "" {
+		return Job{}, errors.New("job_id and device_id are required")
+	}
+	if progress.Phase == "" {
+		return Job{}, errors.New("phase is required")
+	}
+	if leaseTTL <= 0 {
+		leaseTTL = DefaultJobLeaseTTL
+	}
+	now := time.Now().UTC()
+	res, err := s.db.Exec(
+		`UPDATE jobs
+		    SET phase = ?,
+		        session_id = COALESCE(NULLIF(?, ''), session_id),
+		        run_id = COALESCE(NULLIF(?, ''), run_id),
+		        branch = COALESCE(NULLIF(?, ''), branch),
+		        pr_url = COALESCE(NULLIF(?, ''), pr_url),
+		        lease_expires_at = ?,
+		        updated_at = ?
+		  WHERE id = ? AND device_id = ? AND state = ? AND (? = 0 OR attempts = ?)`,
+		progress.Phase,
+		strings.TrimSpace(progress.SessionID),
+		strings.TrimSpace(progress.RunID),
+		strings.TrimSpace(progress.Branch),
+		strings.TrimSpace(progress.PRURL),
+		now.Add(leaseTTL).Format(time.RFC3339Nano),
+		now.Format(time.RFC3339Nano),
+		progress.JobID,
+		progress.DeviceID,
+		jobStateClaimed,
+		progress.Attempt, progress.Attempt,
+	)
+	if err != nil {
+		return Job{}, fmt.Errorf("record job progress: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return Job{}, fmt.Errorf("rows affected: %w", err)
+	}
+	if rows == 0 {
+		return Job{}, ErrJobLeaseLost
+	}
+	job, found, err := s.GetJob(progress.JobID)
+	if err != nil {
+		return Job{}, err
+	}
+	if !found {
+		return Job{}, errors.New("job not found after update")
+	}
+	return job, nil
+}
+
+// ExpireJobLeases takes back claimed jobs whose lease ran out: they are
+// queued again until they reach maxAttempts deliveries, then failed.
+func (s *Store) ExpireJobLeases(maxAttempts int) ([]ExpiredJob, error) {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultJobMaxAttempts
+	}
+	rows, err := s.db.Query(
+		`SELECT id, attempts, lease_expires_at FROM jobs WHERE state = ?`,
+		jobStateClaimed,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list claimed jobs: %w", err)
+	}
+	type claimedJob struct {
+		id       string
+		attempts int
+		lease    string
+	}
+	now := time.Now().UTC()
+	var expired []claimedJob
+	for rows.Next() {
+		var (
+			job      claimedJob
+			leaseRaw sql.NullString
+		)
+		if err := rows.Scan(&job.id, &job.attempts, &leaseRaw); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("scan claimed job: %w", err)
+		}
+		// Jobs claimed before leases existed have none and are left alone.
+		if !leaseRaw.Valid {
+			continue
+		}
+		lease, err := time.Parse(time.RFC3339Nano, leaseRaw.String)
+		if err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("parse lease_expires_at: %w", err)
+		}
+		if lease.Before(now) {
+			job.lease = leaseRaw.String
+			expired = append(expired, job)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, fmt.Errorf("iterate claimed jobs: %w", err)
+	}
+	_ = rows.Close()
+
+	out := make([]ExpiredJob, 0, len(expired))
+	for _, job := range expired {
+		abandoned := job.attempts >= maxAttempts
+		var res sql.Result
+		if abandoned {
+			res, err = s.db.Exec(
+				`UPDATE jobs
+				    SET state = ?, error = ?, lease_expires_at = NULL, completed_at = ?, updated_at = ?
+				  WHERE id = ? AND state = ? AND lease_expires_at = ?`,
+				jobStateFailed,
+				fmt.Sprintf("device stopped responding (%d attempts)", job.attempts),
+				nowRFC3339Nano(),
+				nowRFC3339Nano(),
+				job.id,
+				jobStateClaimed,
+				job.lease,
+			)
+		} else {
+			res, err = s.db.Exec(
+				`UPDATE jobs
+				    SET state = ?, claimed_at = NULL, lease_expires_at = NULL, phase = '', updated_at = ?
+				  WHERE id = ? AND state = ? AND lease_expires_at = ?`,
+				jobStateQueued,
+				nowRFC3339Nano(),
+				job.id,
+				jobStateClaimed,
+				job.lease,
+			)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("expire job %s: %w", job.id, err)
+		}
+		// A heartbeat or completion that raced the sweep wins.
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			continue
+		}
+		updated, found, err := s.GetJob(job.id)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			out = append(out, ExpiredJob{Job: updated, Abandoned: abandoned})
+		}
+	}
+	return out, nil
+}
+
+// CompleteJob stores terminal status and execution outputs. A result for a
+// job the device no longer holds (its lease expired and the job was
+// re-queued, or a later attempt claimed it) fails with ErrJobLeaseLost so
+// it cannot overwrite the current attempt.
 func (s *Store) CompleteJob(in JobCompletion) (Job, error) {
 	in.JobID = strings.TrimSpace(in.JobID)
 	in.DeviceID = strings.TrimSpace(in.DeviceID)
@@ -883,8 +1221,9 @@ func (s *Store) CompleteJob(in JobCompletion) (Job, error) {
 		        commit_sha = CASE WHEN ? = '' THEN commit_sha ELSE ? END,
 		        commit_msg_result = CASE WHEN ? = '' THEN commit_msg_result ELSE ? END,
 		        completed_at = ?,
-		        updated_at = ?
-		  WHERE id = ? AND device_id = ?`,
+		        updated_at = ?,
+		        lease_expires_at = NULL
+		  WHERE id = ? AND device_id = ? AND state = ? AND (? = 0 OR attempts = ?)`,
 		nextState,
 		in.Error,
 		in.SessionID, in.SessionID,
@@ -897,6 +1236,8 @@ func (s *Store) CompleteJob(in JobCompletion) (Job, error) {
 		nowRFC3339Nano(),
 		in.JobID,
 		in.DeviceID,
+		jobStateClaimed,
+		in.Attempt, in.Attempt,
 	)
 	if err != nil {
 		return Job{}, fmt.Errorf("complete job: %w", err)
@@ -906,7 +1247,14 @@ func (s *Store) CompleteJob(in JobCompletion) (Job, error) {
 		return Job{}, fmt.Errorf("rows affected: %w", err)
 	}
 	if rows == 0 {
-		return Job{}, errors.New("job not found for device")
+		current, found, err := s.GetJob(in.JobID)
+		if err != nil {
+			return Job{}, err
+		}
+		if !found || current.DeviceID != in.DeviceID {
+			return Job{}, errors.New("job not found for device")
+		}
+		return Job{}, ErrJobLeaseLost
 	}
 
 	job, found, err := s.GetJob(in.JobID)
@@ -939,10 +1287,11 @@ func getJobTx(q queryRower, jobID string) (Job, bool, error) {
 	var updatedAtRaw string
 	var claimedAtRaw sql.NullString
 	var completedAtRaw sql.NullString
+	var leaseRaw sql.NullString
 	err := q.QueryRow(
 		`SELECT id, device_id, team_id, channel_id, root_ts, slack_user_id, kind, repo, tool, model, autopr,
 		        branch_name, commit_msg, prompt, session_id, run_id, branch, pr_url, state, error,
-		        created_at, updated_at, claimed_at, completed_at
+		        created_at, updated_at, claimed_at, completed_at, attempts, lease_expires_at, phase, merge_method
 		   FROM jobs WHERE id = ?`,
 		jobID,
 	).Scan(
@@ -970,6 +1319,10 @@ func getJobTx(q queryRower, jobID string) (Job, bool, error) {
 		&updatedAtRaw,
 		&claimedAtRaw,
 		&completedAtRaw,
+		&job.Attempts,
+		&leaseRaw,
+		&job.Phase,
+		&job.MergeMethod,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		return Job{}, false, nil
@@ -1001,6 +1354,13 @@ func getJobTx(q queryRower, jobID string) (Job, bool, error) {
 		}
 		job.CompletedAt = &completedAt
 	}
+	if leaseRaw.Valid {
+		lease, err := time.Parse(time.RFC3339Nano, leaseRaw.String)
+		if err != nil {
+			return Job{}, false, fmt.Errorf("parse lease_expires_at: %w", err)
+		}
+		job.LeaseExpiresAt = &lease
+	}
 	return job, true, nil
 }
 
@@ -1,6 +1,7 @@
 package cloud
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -213,7 +214,7 @@ func TestEnqueueClaimAndCompleteJob(t *testing.T) {
 		t.Fatalf("unexpected initial job state: %q", job.State)
 	}
 
-	claimed, found, err := store.ClaimNextJob("device-a")
+	claimed, found, err := store.ClaimNextJob("device-a", time.Minute)
 	if err != nil {
 		t.Fatalf("claim next job failed: %v", err)
 	}
@@ -257,3 +258,223 @@ func newTestStore(t *testing.T) *Store {
 	}
 	return store
 }
+
+func TestJobLeaseExpiryRequeuesThenAbandons(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	req, err := store.CreatePairingRequest("T1", "U1", "C1", "111.222", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("create pairing request failed: %v", err)
+	}
+	if _, err := store.ClaimPairingRequest(req.Code, "device-a", ""); err != nil {
+		t.Fatalf("claim pairing failed: %v", err)
+	}
+	job, err := store.EnqueueJob(Job{
+		DeviceID:    "device-a",
+		TeamID:      "T1",
+		ChannelID:   "C1",
+		RootTS:      "111.222",
+		SlackUserID: "U1",
+		Kind:        jobKindFollowUp,
+		SessionID:   "session-1",
+		Prompt:      "add tests",
+	})
+	if err != nil {
+		t.Fatalf("enqueue job failed: %v", err)
+	}
+
+	claimed, _, err := store.ClaimNextJob("device-a", time.Millisecond)
+	if err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	if claimed.Attempts != 1 || claimed.LeaseExpiresAt == nil {
+		t.Fatalf("expected first leased attempt, got %+v", claimed)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	expired, err := store.ExpireJobLeases(2)
+	if err != nil {
+		t.Fatalf("expire leases failed: %v", err)
+	}
+	if len(expired) != 1 || expired[0].Abandoned || expired[0].State != jobStateQueued || expired[0].LeaseExpiresAt != nil {
+		t.Fatalf("expected job re-queued, got %+v", expired)
+	}
+	if _, err := store.HeartbeatJob(job.ID, "device-a", 0, time.Minute); !errors.Is(err, ErrJobLeaseLost) {
+		t.Fatalf("expected heartbeat on re-queued job to fail, got %v", err)
+	}
+
+	claimed, _, err = store.ClaimNextJob("device-a", time.Minute)
+	if err != nil || claimed.ID != job.ID || claimed.Attempts != 2 {
+		t.Fatalf("expected re-delivery as attempt 2, got %+v err=%v", claimed, err)
+	}
+	if _, err := store.HeartbeatJob(job.ID, "device-b", 0, time.Minute); !errors.Is(err, ErrJobLeaseLost) {
+		t.Fatalf("expected heartbeat from another device to fail, got %v", err)
+	}
+	// A live lease is left alone.
+	if expired, err := store.ExpireJobLeases(2); err != nil || len(expired) != 0 {
+		t.Fatalf("expected no expiry while leased, got %+v err=%v", expired, err)
+	}
+
+	if _, err := store.HeartbeatJob(job.ID, "device-a", 0, time.Millisecond); err != nil {
+		t.Fatalf("heartbeat failed: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	expired, err = store.ExpireJobLeases(2)
+	if err != nil {
+		t.Fatalf("expire leases failed: %v", err)
+	}
+	if len(expired) != 1 || !expired[0].Abandoned || expired[0].State != jobStateFailed || expired[0].CompletedAt == nil {
+		t.Fatalf("expected job abandoned, got %+v", expired)
+	}
+	if _, found, _ := store.ClaimNextJob("device-a", time.Minute); found {
+		t.Fatal("abandoned job must not be delivered again")
+	}
+}
+
+func TestClaimNextJobPrefersCancelAndQuickCommands(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	req, err := store.CreatePairingRequest("T1", "U1", "C1", "111.222", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("create pairing request failed: %v", err)
+	}
+	if _, err := store.ClaimPairingRequest(req.Code, "device-a", ""); err != nil {
+		t.Fatalf("claim pairing failed: %v", err)
+	}
+	for _, job := range []Job{
+		{Kind: jobKindStartSession, Repo: "owner/repo", Prompt: "implement auth"},
+		{Kind: jobKindFollowUp, SessionID: "session-1", Prompt: "add tests"},
+		{Kind: jobKindStatus, SessionID: "session-1"},
+		{Kind: jobKindCancel, SessionID: "session-1"},
+	} {
+		job.DeviceID, job.TeamID, job.ChannelID, job.RootTS, job.SlackUserID = "device-a", "T1", "C1", "111.222", "U1"
+		if _, err := store.EnqueueJob(job); err != nil {
+			t.Fatalf("enqueue %s failed: %v", job.Kind, err)
+		}
+	}
+
+	var kinds []string
+	for {
+		claimed, found, err := store.ClaimNextJob("device-a", time.Minute)
+		if err != nil {
+			t.Fatalf("claim failed: %v", err)
+		}
+		if !found {
+			break
+		}
+		kinds = append(kinds, claimed.Kind)
+	}
+	if got := strings.Join(kinds, ","); got != "cancel,status,start_session,follow_up" {
+		t.Fatalf("unexpected claim order: %s", got)
+	}
+}
+
+func TestCompleteJobRejectsLostLease(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	req, err := store.CreatePairingRequest("T1", "U1", "C1", "111.222", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("create pairing request failed: %v", err)
+	}
+	if _, err := store.ClaimPairingRequest(req.Code, "device-a", ""); err != nil {
+		t.Fatalf("claim pairing failed: %v", err)
+	}
+	job, err := store.EnqueueJob(Job{
+		DeviceID:    "device-a",
+		TeamID:      "T1",
+		ChannelID:   "C1",
+		RootTS:      "111.222",
+		SlackUserID: "U1",
+		Kind:        jobKindFollowUp,
+		SessionID:   "session-1",
+		Prompt:      "add tests",
+	})
+	if err != nil {
+		t.Fatalf("enqueue job failed: %v", err)
+	}
+	if _, _, err := store.ClaimNextJob("device-a", time.Millisecond); err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := store.ExpireJobLeases(3); err != nil {
+		t.Fatalf("expire leases failed: %v", err)
+	}
+
+	stale := JobCompletion{JobID: job.ID, DeviceID: "device-a", Attempt: 1, Success: true}
+	if _, err := store.CompleteJob(stale); !errors.Is(err, ErrJobLeaseLost) {
+		t.Fatalf("expected ErrJobLeaseLost for re-queued job, got %v", err)
+	}
+	if _, _, err := store.ClaimNextJob("device-a", time.Minute); err != nil {
+		t.Fatalf("re-claim failed: %v", err)
+	}
+	if _, err := store.CompleteJob(stale); !errors.Is(err, ErrJobLeaseLost) {
+		t.Fatalf("expected ErrJobLeaseLost for the earlier attempt, got %v", err)
+	}
+	if _, err := store.HeartbeatJob(job.ID, "device-a", 1, time.Minute); !errors.Is(err, ErrJobLeaseLost) {
+		t.Fatalf("expected heartbeat for the earlier attempt to fail, got %v", err)
+	}
+	if _, err := store.CompleteJob(JobCompletion{JobID: job.ID, DeviceID: "device-b", Attempt: 2, Success: true}); err == nil || errors.Is(err, ErrJobLeaseLost) {
+		t.Fatalf("expected not found for another device, got %v", err)
+	}
+
+	done, err := store.CompleteJob(JobCompletion{JobID: job.ID, DeviceID: "device-a", Attempt: 2, Success: true})
+	if err != nil || done.State != jobStateCompleted {
+		t.Fatalf("expected current attempt to complete, got %+v err=%v", done, err)
+	}
+	if _, err := store.CompleteJob(JobCompletion{JobID: job.ID, DeviceID: "device-a", Attempt: 2, Error: "late"}); !errors.Is(err, ErrJobLeaseLost) {
+		t.Fatalf("expected a second completion to be rejected, got %v", err)
+	}
+}
+
+func TestRecordJobProgressRequiresClaimAndKeepsKnownFields(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	req, err := store.CreatePairingRequest("T1", "U1", "C1", "111.222", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("create pairing request failed: %v", err)
+	}
+	if _, err := store.ClaimPairingRequest(req.Code, "device-a", ""); err != nil {
+		t.Fatalf("claim pairing failed: %v", err)
+	}
+	job, err := store.EnqueueJob(Job{
+		DeviceID:    "device-a",
+		TeamID:      "T1",
+		ChannelID:   "C1",
+		RootTS:      "111.222",
+		SlackUserID: "U1",
+		Kind:        jobKindStartSession,
+		Repo:        "acme/api",
+		Prompt:      "add otp",
+	})
+	if err != nil {
+		t.Fatalf("enqueue job failed: %v", err)
+	}
+
+	progress := JobProgress{JobID: job.ID, DeviceID: "device-a", Phase: "SETUP", SessionID: "sess-1", Branch: "fog/otp"}
+	if _, err := store.RecordJobProgress(progress, time.Minute); !errors.Is(err, ErrJobLeaseLost) {
+		t.Fatalf("expected ErrJobLeaseLost for queued job, got %v", err)
+	}
+	if _, _, err := store.ClaimNextJob("device-a", time.Millisecond); err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	if _, err := store.RecordJobProgress(progress, time.Minute); err != nil {
+		t.Fatalf("record progress failed: %v", err)
+	}
+	got, err := store.RecordJobProgress(JobProgress{JobID: job.ID, DeviceID: "device-a", Phase: "PR_CREATED", PRURL: "https://github.com/acme/api/pull/7"}, time.Minute)
+	if err != nil {
+		t.Fatalf("record progress failed: %v", err)
+	}
+	if got.Phase != "PR_CREATED" || got.SessionID != "sess-1" || got.Branch != "fog/otp" || got.PRURL != "https://github.com/acme/api/pull/7" {
+		t.Fatalf("unexpected job after progress: %+v", got)
+	}
+	if got.LeaseExpiresAt == nil || time.Until(*got.LeaseExpiresAt) < 30*time.Second {
+		t.Fatalf("expected progress to renew the lease, got %v", got.LeaseExpiresAt)
+	}
+	if _, err := store.RecordJobProgress(JobProgress{JobID: job.ID, DeviceID: "device-b", Phase: "SETUP"}, time.Minute); !errors.Is(err, ErrJobLeaseLost) {
+		t.Fatalf("expected ErrJobLeaseLost for another device, got %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package cloudrelay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/cloud"
+	"github.com/gorilla/websocket"
+)
+
+// ErrChannelClosed is returned by Channel calls once the connection has
+// dropped; callers fall back to the HTTP device API.
+var ErrChannelClosed = errors.New("cloud channel closed")
+
+// Channel is a device's WebSocket push channel to fogcloud. Jobs arrive as
+// soon as they are queued, one per Claim; heartbeats, progress and
+// completions travel back over the same connection.
+type Channel struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+	jobs    chan cloud.Job
+	done    chan struct{}
+	once    sync.Once
+
+	mu      sync.Mutex
+	pending map[string]chan cloud.ChannelMessage
+	nextID  int64
+}
+
+// Connect opens the push channel. It fails against fogcloud versions
+// without one, in which case the device keeps polling.
+func (c *Client) Connect(ctx context.Context) (*Channel, error) {
+	if err := c.requireDeviceAuth(); err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	header.Set("X-Fog-Device-ID", c.deviceID)
+	header.Set("Authorization", "Bearer "+c.deviceToken)
+	conn, resp, err := c.dialer.DialContext(ctx, channelURL(c.baseURL), header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("connect cloud channel: %s", resp.Status)
+		}
+		return nil, fmt.Errorf("connect cloud channel: %w", err)
+	}
+	ch := &Channel{
+		conn:    conn,
+		jobs:    make(chan cloud.Job, 1),
+		done:    make(chan struct{}),
+		pending: make(map[string]chan cloud.ChannelMessage),
+	}
+	go ch.readLoop()
+	return ch, nil
+}
+
+func channelURL(baseURL string) string {
+	switch {
+	case strings.HasPrefix(baseURL, "https://"):
+		baseURL = "wss://" + strings.TrimPrefix(baseURL, "https://")
+	case strings.HasPrefix(baseURL, "http://"):
+		baseURL = "ws://" + strings.TrimPrefix(baseURL, "http://")
+	}
+	return baseURL + "/v1/device/connect"
+}
+
+// Jobs delivers pushed jobs, one per Claim.
+func (ch *Channel) Jobs() <-chan cloud.Job {
+	return ch.jobs
+}
+
+// Done is closed when the connection drops.
+func (ch *Channel) Done() <-chan struct{} {
+	return ch.done
+}
+
+// Close closes the connection.
+func (ch *Channel) Close() error {
+	ch.shutdown()
+	return ch.conn.Close()
+}
+
+// Claim asks fogcloud for the next job. The job is pushed on Jobs as soon
+// as one is queued for the device.
+func (ch *Channel) Claim() error {
+	return ch.write(cloud.ChannelMessage{Type: cloud.ChannelClaim})
+}
+
+// Heartbeat extends the lease of the given attempt of a claimed job.
+func (ch *Channel) Heartbeat(ctx context.Context, jobID string, attempt int) error {
+	body, _ := json.Marshal(heartbeatPayload{Attempt: attempt})
+	return ch.request(ctx, cloud.ChannelMessage{Type: cloud.ChannelHeartbeat, JobID: jobID, Payload: body})
+}
+
+// ReportProgress sends an intermediate update for a claimed job.
+func (ch *Channel) ReportProgress(ctx context.Context, jobID string, payload ProgressPayload) error {
+	body, _ := json.Marshal(payload)
+	return ch.request(ctx, cloud.ChannelMessage{Type: cloud.ChannelProgress, JobID: jobID, Payload: body})
+}
+
+// CompleteJob reports the result of a claimed job.
+func (ch *Channel) CompleteJob(ctx context.Context, jobID string, payload CompletePayload) error {
+	body, _ := json.Marshal(payload)
+	return ch.request(ctx, cloud.ChannelMessage{Type: cloud.ChannelComplete, JobID: jobID, Payload: body})
+}
+
+// request sends a frame and waits for its ack.
+func (ch *Channel) request(ctx context.Context, msg cloud.ChannelMessage) error {
+	msg.JobID = strings.TrimSpace(msg.JobID)
+	if msg.JobID == "" {
+		return errors.New("job id is required")
+	}
+	ackCh := make(chan cloud.ChannelMessage, 1)
+	ch.mu.Lock()
+	ch.nextID++
+	msg.ID = strconv.FormatInt(ch.nextID, 10)
+	ch.pending[msg.ID] = ackCh
+	ch.mu.Unlock()
+	defer func() {
+		ch.mu.Lock()
+		delete(ch.pending, msg.ID)
+		ch.mu.Unlock()
+	}()
+
+	if err := ch.write(msg); err != nil {
+		return err
+	}
+	select {
+	case ack := <-ackCh:
+		if ack.Status/100 != 2 {
+			err := fmt.Errorf("cloud api error: %s", fallbackError(ack.Error, http.StatusText(ack.Status)))
+			if ack.Status == http.StatusConflict {
+				return fmt.Errorf("%w: %v", ErrLeaseLost, err)
+			}
+			return err
+		}
+		return nil
+	case <-ch.done:
+		return ErrChannelClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (ch *Channel) write(msg cloud.ChannelMessage) error {
+	ch.writeMu.Lock()
+	defer ch.writeMu.Unlock()
+	select {
+	case <-ch.done:
+		return ErrChannelClosed
+	default:
+	}
+	_ = ch.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if err := ch.conn.WriteJSON(msg); err != nil {
+		ch.shutdown()
+		return ErrChannelClosed
+	}
+	return nil
+}
+
+func (ch *Channel) readLoop() {
+	defer ch.shutdown()
+	readTimeout := 2 * cloud.ChannelPingInterval
+	_ = ch.conn.SetReadDeadline(time.Now().Add(readTimeout))
+	ch.conn.SetPingHandler(func(data string) error {
+		_ = ch.conn.SetReadDeadline(time.Now().Add(readTimeout))
+		return ch.conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(10*time.Second))
+	})
+	for {
+		var msg cloud.ChannelMessage
+		if err := ch.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		_ = ch.conn.SetReadDeadline(time.Now().Add(readTimeout))
+		switch msg.Type {
+		case cloud.ChannelJob:
+			if msg.Job == nil {
+				continue
+			}
+			select {
+			case ch.jobs <- *msg.Job:
+			case <-ch.done:
+				return
+			}
+		case cloud.ChannelAck:
+			ch.mu.Lock()
+			ackCh := ch.pending[msg.ID]
+			ch.mu.Unlock()
+			if ackCh != nil {
+				ackCh <- msg
+			}
+		}
+	}
+}
+
+func (ch *Channel) shutdown() {
+	ch.once.Do(func() {
+		close(ch.done)
+		_ = ch.conn.Close()
+	})
+}
@@ -0,0 +1,213 @@
+package cloudrelay
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/cloud"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestChannelDeliversJobsAndFallsBackToHTTP(t *testing.T) {
+	store, err := cloud.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new cloud store failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	pairReq, err := store.CreatePairingRequest("T1", "U1", "C1", "111.222", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("create pairing request failed: %v", err)
+	}
+	claim, err := store.ClaimPairingRequest(pairReq.Code, "device-a", "")
+	if err != nil {
+		t.Fatalf("claim pairing failed: %v", err)
+	}
+	job, err := store.EnqueueJob(cloud.Job{
+		DeviceID:    "device-a",
+		TeamID:      "T1",
+		ChannelID:   "C1",
+		RootTS:      "111.222",
+		SlackUserID: "U1",
+		Kind:        "start_session",
+		Repo:        "acme/api",
+		Prompt:      "add otp",
+	})
+	if err != nil {
+		t.Fatalf("enqueue job failed: %v", err)
+	}
+
+	server, err := cloud.NewServer(store, cloud.Config{
+		ClientID:      "cid",
+		ClientSecret:  "secret",
+		SigningSecret: "signing-secret",
+		PublicURL:     "https://fogcloud.example",
+	})
+	if err != nil {
+		t.Fatalf("new server failed: %v", err)
+	}
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+	httpServer := httptest.NewServer(mux)
+	defer httpServer.Close()
+
+	client, err := NewClient(ClientConfig{BaseURL: httpServer.URL, DeviceID: "device-a", DeviceToken: claim.DeviceToken})
+	if err != nil {
+		t.Fatalf("new client failed: %v", err)
+	}
+	ctx := context.Background()
+	ch, err := client.Connect(ctx)
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	if err := ch.Claim(); err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	select {
+	case pushed := <-ch.Jobs():
+		if pushed.ID != job.ID {
+			t.Fatalf("unexpected pushed job %s", pushed.ID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for pushed job")
+	}
+	if err := ch.Heartbeat(ctx, job.ID, 1); err != nil {
+		t.Fatalf("channel heartbeat failed: %v", err)
+	}
+	if err := ch.Heartbeat(ctx, job.ID, 2); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost for another attempt, got %v", err)
+	}
+	if err := ch.ReportProgress(ctx, job.ID, ProgressPayload{Phase: "AI_RUNNING"}); err != nil {
+		t.Fatalf("channel progress failed: %v", err)
+	}
+
+	// Once the channel drops, job calls go over HTTP.
+	r := &Relay{client: client}
+	r.channel.Store(ch)
+	_ = ch.Close()
+	if err := ch.Heartbeat(ctx, job.ID, 1); !errors.Is(err, ErrChannelClosed) {
+		t.Fatalf("expected ErrChannelClosed, got %v", err)
+	}
+	err = r.callJobAPI(func(api jobAPI) error {
+		return api.CompleteJob(ctx, job.ID, CompletePayload{Attempt: 1, Success: true, Branch: "fog/otp"})
+	})
+	if err != nil {
+		t.Fatalf("complete over fallback failed: %v", err)
+	}
+	if err := client.CompleteJob(ctx, job.ID, CompletePayload{Attempt: 1, Success: true}); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost completing a finished job, got %v", err)
+	}
+	got, _, err := store.GetJob(job.ID)
+	if err != nil {
+		t.Fatalf("get job failed: %v", err)
+	}
+	if got.State != "completed" || got.Phase != "AI_RUNNING" {
+		t.Fatalf("unexpected job state: %+v", got)
+	}
+}
+
+func TestRelayRunProcessesPushedJobs(t *testing.T) {
+	store, err := cloud.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new cloud store failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	pairReq, err := store.CreatePairingRequest("T1", "U1", "C1", "111.222", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("create pairing request failed: %v", err)
+	}
+	claim, err := store.ClaimPairingRequest(pairReq.Code, "device-a", "")
+	if err != nil {
+		t.Fatalf("claim pairing failed: %v", err)
+	}
+	var jobIDs []string
+	for i := 0; i < 2; i++ {
+		job, err := store.EnqueueJob(cloud.Job{
+			DeviceID:    "device-a",
+			TeamID:      "T1",
+			ChannelID:   "C1",
+			RootTS:      "111.222",
+			SlackUserID: "U1",
+			Kind:        "start_session",
+			Repo:        "acme/missing",
+			Prompt:      "noop",
+		})
+		if err != nil {
+			t.Fatalf("enqueue job failed: %v", err)
+		}
+		jobIDs = append(jobIDs, job.ID)
+	}
+
+	server, err := cloud.NewServer(store, cloud.Config{
+		ClientID:      "cid",
+		ClientSecret:  "secret",
+		SigningSecret: "signing-secret",
+		PublicURL:     "https://fogcloud.example",
+	})
+	if err != nil {
+		t.Fatalf("new server failed: %v", err)
+	}
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/device/jobs/claim" {
+			t.Error("relay polled while the push channel was connected")
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer httpServer.Close()
+
+	client, err := NewClient(ClientConfig{BaseURL: httpServer.URL, DeviceID: "device-a", DeviceToken: claim.DeviceToken})
+	if err != nil {
+		t.Fatalf("new client failed: %v", err)
+	}
+	stateStore, err := state.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new state store failed: %v", err)
+	}
+	defer func() { _ = stateStore.Close() }()
+	r := &Relay{client: client, stateStore: stateStore, pollInterval: time.Hour, maxConcurrent: 2, progressInterval: time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = r.Run(ctx)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for _, id := range jobIDs {
+		for {
+			job, _, err := store.GetJob(id)
+			if err != nil {
+				t.Fatalf("get job failed: %v", err)
+			}
+			if job.State == "failed" {
+				if job.Error != "unknown repo: acme/missing" {
+					t.Fatalf("unexpected job error: %q", job.Error)
+				}
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("job %s not completed, state %s", id, job.State)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	cancel()
+	<-done
+}
+
+func TestChannelURL(t *testing.T) {
+	cases := map[string]string{
+		"https://fog.example.com": "wss://fog.example.com/v1/device/connect",
+		"http://localhost:9090":   "ws://localhost:9090/v1/device/connect",
+	}
+	for base, want := range cases {
+		if got := channelURL(base); got != want {
+			t.Errorf("channelURL(%q) = %q, want %q", base, got, want)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/darkLord19/foglet/internal/cloud"
+	"github.com/gorilla/websocket"
 )
 
 type ClientConfig struct {
@@ -26,6 +27,7 @@ type Client struct {
 	deviceID    string
 	deviceToken string
 	httpClient  *http.Client
+	dialer      *websocket.Dialer
 }
 
 type PairClaimResponse struct {
@@ -35,7 +37,16 @@ type PairClaimResponse struct {
 	DeviceToken string `json:"device_token,omitempty"`
 }
 
+// ErrLeaseLost is returned by job calls the cloud rejects because the device
+// no longer holds the job: its lease expired and the job was re-queued or
+// claimed again, or it already finished. Nothing more should be reported
+// for that attempt.
+var ErrLeaseLost = errors.New("cloud job lease lost")
+
 type CompletePayload struct {
+	// Attempt is the claim being reported (cloud.Job.Attempts), so a result
+	// from an attempt the cloud gave up on cannot overwrite a later one.
+	Attempt   int    `json:"attempt,omitempty"`
 	Success   bool   `json:"success"`
 	Error     string `json:"error,omitempty"`
 	SessionID string `json:"session_id,omitempty"`
@@ -44,6 +55,25 @@ type CompletePayload struct {
 	PRURL     string `json:"pr_url,omitempty"`
 	CommitSHA string `json:"commit_sha,omitempty"`
 	CommitMsg string `json:"commit_msg,omitempty"`
+	// Message is the result of a session command that started no run.
+	Message string `json:"message,omitempty"`
+	// Validation ("passed", "failed" or empty) and DiffStat describe the
+	// finished run for its Slack message.
+	Validation string `json:"validation,omitempty"`
+	DiffStat   string `json:"diff_stat,omitempty"`
+}
+
+// ProgressPayload is an intermediate job update. Phase is a run state,
+// VALIDATION_FAILED, or STARTED when a session command started a run; the
+// other fields are sent once known.
+type ProgressPayload struct {
+	Attempt   int    `json:"attempt,omitempty"`
+	Phase     string `json:"phase"`
+	Message   string `json:"message,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+	RunID     string `json:"run_id,omitempty"`
+	Branch    string `json:"branch,omitempty"`
+	PRURL     string `json:"pr_url,omitempty"`
 }
 
 func NewClient(cfg ClientConfig) (*Client, error) {
@@ -61,6 +91,7 @@ func NewClient(cfg ClientConfig) (*Client, error) {
 		deviceID:    cfg.DeviceID,
 		deviceToken: cfg.DeviceToken,
 		httpClient:  cfg.HTTPClient,
+		dialer:      websocket.DefaultDialer,
 	}, nil
 }
 
@@ -190,7 +221,63 @@ func (c *Client) CompleteJob(ctx context.Context, jobID string, payload Complete
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode/100 != 2 {
-		return decodeAPIError(resp)
+		return decodeJobAPIError(resp)
+	}
+	return nil
+}
+
+// ReportProgress sends an intermediate update for a claimed job, which the
+// cloud relays to the Slack thread. It also renews the job lease.
+func (c *Client) ReportProgress(ctx context.Context, jobID string, payload ProgressPayload) error {
+	if err := c.requireDeviceAuth(); err != nil {
+		return err
+	}
+	jobID = strings.TrimSpace(jobID)
+	if jobID == "" {
+		return errors.New("job id is required")
+	}
+	body, _ := json.Marshal(payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/device/jobs/"+jobID+"/progress", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.addDeviceAuthHeaders(req)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return decodeJobAPIError(resp)
+	}
+	return nil
+}
+
+// Heartbeat extends the lease of the given attempt of a claimed job. It fails
+// with ErrLeaseLost once the cloud has re-queued or abandoned the job.
+func (c *Client) Heartbeat(ctx context.Context, jobID string, attempt int) error {
+	if err := c.requireDeviceAuth(); err != nil {
+		return err
+	}
+	jobID = strings.TrimSpace(jobID)
+	if jobID == "" {
+		return errors.New("job id is required")
+	}
+	body, _ := json.Marshal(heartbeatPayload{Attempt: attempt})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/device/jobs/"+jobID+"/heartbeat", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.addDeviceAuthHeaders(req)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return decodeJobAPIError(resp)
 	}
 	return nil
 }
@@ -222,3 +309,18 @@ func decodeAPIError(resp *http.Response) error {
 	}
 	return fmt.Errorf("cloud api error: %s", msg)
 }
+
+// heartbeatPayload is the body of a heartbeat.
+type heartbeatPayload struct {
+	Attempt int `json:"attempt,omitempty"`
+}
+
+// decodeJobAPIError is decodeAPIError for calls on a claimed job, where 409
+// means the lease was lost.
+func decodeJobAPIError(resp *http.Response) error {
+	err := decodeAPIError(resp)
+	if resp.StatusCode == http.StatusConflict {
+		return fmt.Errorf("%w: %v", ErrLeaseLost, err)
+	}
+	return err
+}
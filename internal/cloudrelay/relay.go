@@ -7,25 +7,61 @@ import (
 	"log"
 	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/darkLord19/foglet/internal/cloud"
 	"github.com/darkLord19/foglet/internal/runner"
+	"github.com/darkLord19/foglet/internal/sessioncmd"
+	"github.com/darkLord19/foglet/internal/slackcmd"
 	"github.com/darkLord19/foglet/internal/state"
-	"github.com/darkLord19/foglet/internal/toolcfg"
+	"github.com/darkLord19/foglet/internal/task"
 )
 
 var nonBranchSlugChar = regexp.MustCompile(`[^a-z0-9]+`)
 
 type RelayConfig struct {
 	PollInterval time.Duration
+	// MaxConcurrentJobs caps how many claimed jobs are handled at once. A
+	// job gives its slot back once the run it started is under way; runs
+	// themselves are capped by the runner's scheduler.
+	MaxConcurrentJobs int
+	// ProgressInterval is how often a running job is checked for progress
+	// to report to the cloud.
+	ProgressInterval time.Duration
 }
 
 type Relay struct {
-	client       *Client
-	runner       *runner.Runner
-	stateStore   *state.Store
-	pollInterval time.Duration
+	client           *Client
+	runner           *runner.Runner
+	stateStore       *state.Store
+	pollInterval     time.Duration
+	maxConcurrent    int
+	progressInterval time.Duration
+	// channel is the connected push channel, nil while polling.
+	channel atomic.Pointer[Channel]
+	// leases maps the jobLeaseKey of each running job to the cancel func
+	// of its context, called with ErrLeaseLost once the cloud rejects it.
+	leases sync.Map
+	// slotReleases maps the jobLeaseKey of each job holding a relay slot to
+	// the func that frees it.
+	slotReleases sync.Map
+
+	jobLocksMu sync.Mutex
+	jobLocks   map[string]*jobLock
+}
+
+// jobLeaseKey identifies one delivery of a cloud job.
+type jobLeaseKey struct {
+	jobID   string
+	attempt int
+}
+
+// jobLock serialises the deliveries of one cloud job on this device.
+type jobLock struct {
+	mu      sync.Mutex
+	waiters int
 }
 
 func New(client *Client, run *runner.Runner, stateStore *state.Store, cfg RelayConfig) (*Relay, error) {
@@ -41,66 +77,318 @@ func New(client *Client, run *runner.Runner, stateStore *state.Store, cfg RelayC
 	if cfg.PollInterval <= 0 {
 		cfg.PollInterval = 2 * time.Second
 	}
+	if cfg.MaxConcurrentJobs <= 0 {
+		cfg.MaxConcurrentJobs = 2
+	}
+	if cfg.ProgressInterval <= 0 {
+		cfg.ProgressInterval = time.Second
+	}
 	return &Relay{
-		client:       client,
-		runner:       run,
-		stateStore:   stateStore,
-		pollInterval: cfg.PollInterval,
+		client:           client,
+		runner:           run,
+		stateStore:       stateStore,
+		pollInterval:     cfg.PollInterval,
+		maxConcurrent:    cfg.MaxConcurrentJobs,
+		progressInterval: cfg.ProgressInterval,
 	}, nil
 }
 
+// maxChannelBackoff caps the polling period between push channel
+// reconnect attempts.
+const maxChannelBackoff = time.Minute
+
+// Run claims and processes jobs until ctx is cancelled, handling up to
+// MaxConcurrentJobs of them at once. Jobs are pushed over the cloud
+// channel while it is connected; when it is unavailable or drops, the relay
+// polls over HTTP between reconnect attempts. A job is only claimed when a
+// slot is free, so excess jobs stay queued in the cloud. Jobs that start a
+// run free their slot once the run is under way, so long runs cannot keep
+// a cancel or status request from being claimed.
 func (r *Relay) Run(ctx context.Context) error {
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	slots := make(chan struct{}, r.maxConcurrent)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	backoff := time.Second
 	for {
+		ch, err := r.client.Connect(ctx)
+		if err == nil {
+			connectedAt := time.Now()
+			r.serveChannel(ctx, ch, slots, &wg)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if time.Since(connectedAt) > maxChannelBackoff {
+				backoff = time.Second
+			}
+			log.Printf("cloud relay: push channel dropped, polling for %s", backoff)
+		} else if ctx.Err() == nil {
+			log.Printf("cloud relay: push channel unavailable (%v), polling for %s", err, backoff)
+		}
+
+		r.poll(ctx, slots, &wg, time.Now().Add(backoff))
 		if ctx.Err() != nil {
 			return nil
 		}
+		if backoff < maxChannelBackoff {
+			backoff *= 2
+		}
+	}
+}
 
-		processed, err := r.processOne(ctx)
-		if err != nil {
-			log.Printf("cloud relay error: %v", err)
+// serveChannel claims jobs over the push channel until it drops.
+func (r *Relay) serveChannel(ctx context.Context, ch *Channel, slots chan struct{}, wg *sync.WaitGroup) {
+	r.channel.Store(ch)
+	defer r.channel.Store(nil)
+	defer func() { _ = ch.Close() }()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch.Done():
+			return
+		case slots <- struct{}{}:
+		}
+		if err := ch.Claim(); err != nil {
+			<-slots
+			return
+		}
+		select {
+		case job := <-ch.Jobs():
+			r.startJob(ctx, job, slots, wg)
+		case <-ctx.Done():
+			<-slots
+			return
+		case <-ch.Done():
+			// A job pushed just before the drop is still ours to run.
 			select {
-			case <-ctx.Done():
-				return nil
-			case <-time.After(r.pollInterval):
+			case job := <-ch.Jobs():
+				r.startJob(ctx, job, slots, wg)
+			default:
+				<-slots
 			}
-			continue
+			return
 		}
-		if processed {
+	}
+}
+
+// poll claims jobs over HTTP every poll interval until the deadline.
+func (r *Relay) poll(ctx context.Context, slots chan struct{}, wg *sync.WaitGroup, until time.Time) {
+	for {
+		wait := time.Until(until)
+		if wait <= 0 {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+			return
+		case slots <- struct{}{}:
+		}
+
+		job, found, err := r.client.ClaimJob(ctx)
+		if err == nil && found {
+			r.startJob(ctx, job, slots, wg)
 			continue
 		}
+		<-slots
+		if err != nil && ctx.Err() == nil {
+			log.Printf("cloud relay error: %v", err)
+		}
 		select {
 		case <-ctx.Done():
-			return nil
+			return
 		case <-time.After(r.pollInterval):
 		}
 	}
 }
 
-func (r *Relay) processOne(ctx context.Context) (bool, error) {
-	job, found, err := r.client.ClaimJob(ctx)
+// startJob processes a claimed job in the background, holding its slot
+// until the job completes or its run is under way.
+func (r *Relay) startJob(ctx context.Context, job cloud.Job, slots chan struct{}, wg *sync.WaitGroup) {
+	key := jobLeaseKey{jobID: job.ID, attempt: job.Attempts}
+	release := sync.OnceFunc(func() { <-slots })
+	r.slotReleases.Store(key, release)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer release()
+		defer r.slotReleases.Delete(key)
+		if err := r.processJob(ctx, job); err != nil {
+			log.Printf("cloud relay job %s: %v", job.ID, err)
+		}
+	}()
+}
+
+// jobAPI is the per-job half of the device API, served by both the push
+// channel and plain HTTP.
+type jobAPI interface {
+	Heartbeat(ctx context.Context, jobID string, attempt int) error
+	ReportProgress(ctx context.Context, jobID string, payload ProgressPayload) error
+	CompleteJob(ctx context.Context, jobID string, payload CompletePayload) error
+}
+
+// callJobAPI sends a job call over the push channel when it is connected,
+// and over HTTP when it is not or drops mid-call.
+func (r *Relay) callJobAPI(call func(jobAPI) error) error {
+	if ch := r.channel.Load(); ch != nil {
+		if err := call(ch); !errors.Is(err, ErrChannelClosed) {
+			return err
+		}
+	}
+	return call(r.client)
+}
+
+// processJob runs a claimed job and reports its result. When the cloud
+// rejects a heartbeat or progress update because the lease was lost, the
+// job's context is cancelled with ErrLeaseLost and nothing more is reported
+// for this attempt; the run itself carries on and a re-delivery picks up
+// its result.
+func (r *Relay) processJob(ctx context.Context, job cloud.Job) error {
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	key := jobLeaseKey{jobID: job.ID, attempt: job.Attempts}
+	r.leases.Store(key, cancel)
+	defer r.leases.Delete(key)
+
+	hbCtx, stopHeartbeat := context.WithCancel(jobCtx)
+	go r.heartbeat(hbCtx, job)
+	payload := r.runJob(jobCtx, job)
+	stopHeartbeat()
+	if ctx.Err() != nil {
+		// Shutting down: the run is interrupted with fogd, and the job
+		// lease expiry hands the job back to the cloud.
+		return nil
+	}
+	if errors.Is(context.Cause(jobCtx), ErrLeaseLost) {
+		log.Printf("cloud relay job %s: lease of attempt %d lost, not reporting its result", job.ID, job.Attempts)
+		return nil
+	}
+	payload.Attempt = job.Attempts
+	return r.callJobAPI(func(api jobAPI) error {
+		return api.CompleteJob(ctx, job.ID, payload)
+	})
+}
+
+// heartbeat keeps the lease of a claimed job alive until ctx is cancelled,
+// so the cloud does not hand the job to another attempt while it runs.
+func (r *Relay) heartbeat(ctx context.Context, job cloud.Job) {
+	interval := heartbeatInterval(job.LeaseExpiresAt, time.Now())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := r.callJobAPI(func(api jobAPI) error {
+				return api.Heartbeat(ctx, job.ID, job.Attempts)
+			})
+			if errors.Is(err, ErrLeaseLost) {
+				r.loseLease(job)
+				return
+			}
+			if err != nil && ctx.Err() == nil {
+				log.Printf("cloud relay heartbeat for job %s failed: %v", job.ID, err)
+			}
+		}
+	}
+}
+
+// heartbeatInterval renews a lease three times per TTL so a single failed
+// heartbeat does not let it expire.
+func heartbeatInterval(leaseExpiresAt *time.Time, now time.Time) time.Duration {
+	if leaseExpiresAt == nil {
+		return 30 * time.Second
+	}
+	interval := leaseExpiresAt.Sub(now) / 3
+	if interval < time.Second {
+		return time.Second
+	}
+	return interval
+}
+
+// releaseSlot frees the relay slot held by job, if any.
+func (r *Relay) releaseSlot(job cloud.Job) {
+	if release, ok := r.slotReleases.Load(jobLeaseKey{jobID: job.ID, attempt: job.Attempts}); ok {
+		release.(func())()
+	}
+}
+
+// loseLease cancels the running attempt of job with ErrLeaseLost.
+func (r *Relay) loseLease(job cloud.Job) {
+	if cancel, ok := r.leases.Load(jobLeaseKey{jobID: job.ID, attempt: job.Attempts}); ok {
+		cancel.(context.CancelCauseFunc)(ErrLeaseLost)
+	}
+}
+
+// runJob runs a job at most once per cloud job ID. A re-delivered job
+// reports the session run or command result of the first delivery instead
+// of starting another; deliveries of the same job wait for each other so a
+// second one cannot slip in before the first has recorded what it did.
+func (r *Relay) runJob(ctx context.Context, job cloud.Job) CompletePayload {
+	unlock := r.lockJob(job.ID)
+	defer unlock()
+	done, found, err := r.stateStore.GetCloudJob(job.ID)
 	if err != nil {
-		return false, err
+		return CompletePayload{Success: false, Error: err.Error()}
 	}
 	if !found {
-		return false, nil
+		return r.handleJob(ctx, job)
+	}
+	if done.RunID != "" {
+		return r.awaitRun(ctx, job, done.SessionID, done.RunID)
+	}
+	return CompletePayload{Success: true, SessionID: done.SessionID, Message: done.Message}
+}
+
+// recordJob stores what a job did for runJob. A failure only costs
+// idempotency, so it is logged.
+func (r *Relay) recordJob(job cloud.Job, sessionID, runID, message string) {
+	err := r.stateStore.RecordCloudJob(state.CloudJob{JobID: job.ID, SessionID: sessionID, RunID: runID, Message: message})
+	if err != nil {
+		log.Printf("cloud relay job %s: record result: %v", job.ID, err)
+	}
+}
+
+// lockJob locks the deliveries of a job ID and returns the unlock func.
+func (r *Relay) lockJob(jobID string) func() {
+	r.jobLocksMu.Lock()
+	if r.jobLocks == nil {
+		r.jobLocks = map[string]*jobLock{}
+	}
+	l := r.jobLocks[jobID]
+	if l == nil {
+		l = &jobLock{}
+		r.jobLocks[jobID] = l
 	}
+	l.waiters++
+	r.jobLocksMu.Unlock()
 
-	payload := r.handleJob(job)
-	if err := r.client.CompleteJob(ctx, job.ID, payload); err != nil {
-		return true, err
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		r.jobLocksMu.Lock()
+		if l.waiters--; l.waiters == 0 {
+			delete(r.jobLocks, jobID)
+		}
+		r.jobLocksMu.Unlock()
 	}
-	return true, nil
 }
 
-func (r *Relay) handleJob(job cloud.Job) CompletePayload {
+func (r *Relay) handleJob(ctx context.Context, job cloud.Job) CompletePayload {
 	switch strings.TrimSpace(job.Kind) {
 	case "start_session":
-		return r.handleStartSession(job)
+		return r.handleStartSession(ctx, job)
 	case "follow_up":
-		return r.handleFollowUp(job)
+		return r.handleFollowUp(ctx, job)
+	case slackcmd.ActionSessions, slackcmd.ActionStatus, slackcmd.ActionCancel, slackcmd.ActionDiff,
+		slackcmd.ActionFork, slackcmd.ActionRetry, slackcmd.ActionPRCreate, slackcmd.ActionPROpen, slackcmd.ActionPRMerge:
+		return r.handleSessionCommand(ctx, job)
 	default:
 		return CompletePayload{
 			Success: false,
@@ -109,7 +397,7 @@ func (r *Relay) handleJob(job cloud.Job) CompletePayload {
 	}
 }
 
-func (r *Relay) handleStartSession(job cloud.Job) CompletePayload {
+func (r *Relay) handleStartSession(ctx context.Context, job cloud.Job) CompletePayload {
 	repo, found, err := r.stateStore.GetRepoByName(strings.TrimSpace(job.Repo))
 	if err != nil {
 		return CompletePayload{Success: false, Error: err.Error()}
@@ -121,73 +409,199 @@ func (r *Relay) handleStartSession(job cloud.Job) CompletePayload {
 		return CompletePayload{Success: false, Error: fmt.Sprintf("repo %s has no base worktree path", repo.Name)}
 	}
 
-	tool, err := toolcfg.ResolveTool(strings.TrimSpace(job.Tool), r.stateStore, "cloud")
+	branch, err := r.resolveBranchName(strings.TrimSpace(job.BranchName), strings.TrimSpace(job.Prompt))
 	if err != nil {
 		return CompletePayload{Success: false, Error: err.Error()}
 	}
-	branch, err := r.resolveBranchName(strings.TrimSpace(job.BranchName), strings.TrimSpace(job.Prompt))
+
+	session, run, err := r.runner.StartSessionAsync(runner.StartSessionOptions{
+		RepoName:  repo.Name,
+		RepoPath:  repo.BaseWorktreePath,
+		Branch:    branch,
+		Tool:      strings.TrimSpace(job.Tool),
+		Model:     strings.TrimSpace(job.Model),
+		Prompt:    strings.TrimSpace(job.Prompt),
+		AutoPR:    job.AutoPR,
+		CommitMsg: strings.TrimSpace(job.CommitMsg),
+	})
 	if err != nil {
 		return CompletePayload{Success: false, Error: err.Error()}
 	}
+	r.recordJob(job, session.ID, run.ID, "")
+	return r.awaitRun(ctx, job, session.ID, run.ID)
+}
 
-	baseBranch := strings.TrimSpace(repo.DefaultBranch)
-	if baseBranch == "" {
-		baseBranch = "main"
+// handleSessionCommand runs a session command. Fork and retry start a run,
+// which is reported like any other; the rest complete with a message.
+func (r *Relay) handleSessionCommand(ctx context.Context, job cloud.Job) CompletePayload {
+	cmd := slackcmd.Command{
+		Action:      strings.TrimSpace(job.Kind),
+		SessionID:   strings.TrimSpace(job.SessionID),
+		MergeMethod: strings.TrimSpace(job.MergeMethod),
+		Branch:      strings.TrimSpace(job.BranchName),
+		Tool:        strings.TrimSpace(job.Tool),
+		Model:       strings.TrimSpace(job.Model),
 	}
-
-	session, run, err := r.runner.StartSession(runner.StartSessionOptions{
-		RepoName:   repo.Name,
-		RepoPath:   repo.BaseWorktreePath,
-		Branch:     branch,
-		Tool:       tool,
-		Model:      strings.TrimSpace(job.Model),
-		Prompt:     strings.TrimSpace(job.Prompt),
-		AutoPR:     job.AutoPR,
-		BaseBranch: baseBranch,
-		CommitMsg:  strings.TrimSpace(job.CommitMsg),
-	})
+	if cmd.Action == slackcmd.ActionFork {
+		cmd.Prompt = strings.TrimSpace(job.Prompt)
+	}
+	res, err := sessioncmd.Run(ctx, r.runner, r.stateStore, cmd)
 	if err != nil {
 		return CompletePayload{Success: false, Error: err.Error()}
 	}
-	return CompletePayload{
-		Success:   true,
-		SessionID: session.ID,
-		RunID:     run.ID,
-		Branch:    session.Branch,
-		PRURL:     strings.TrimSpace(session.PRURL),
-		CommitSHA: strings.TrimSpace(run.CommitSHA),
-		CommitMsg: strings.TrimSpace(run.CommitMsg),
+	r.recordJob(job, res.SessionID, res.RunID, res.Text)
+	if res.RunID == "" {
+		return CompletePayload{Success: true, SessionID: res.SessionID, Message: res.Text}
 	}
+	r.reportProgress(ctx, job, ProgressPayload{Phase: "STARTED", Message: res.Text, SessionID: res.SessionID, RunID: res.RunID})
+	return r.awaitRun(ctx, job, res.SessionID, res.RunID)
 }
 
-func (r *Relay) handleFollowUp(job cloud.Job) CompletePayload {
+func (r *Relay) handleFollowUp(ctx context.Context, job cloud.Job) CompletePayload {
 	sessionID := strings.TrimSpace(job.SessionID)
 	if sessionID == "" {
 		return CompletePayload{Success: false, Error: "missing session_id"}
 	}
-	run, err := r.runner.ContinueSession(sessionID, strings.TrimSpace(job.Prompt))
-	session, _, _ := r.runner.GetSession(sessionID)
+	run, err := r.runner.ContinueSessionAsync(sessionID, strings.TrimSpace(job.Prompt))
 	if err != nil {
+		session, _, _ := r.runner.GetSession(sessionID)
 		return CompletePayload{
 			Success:   false,
 			Error:     err.Error(),
 			SessionID: sessionID,
-			RunID:     strings.TrimSpace(run.ID),
 			Branch:    strings.TrimSpace(session.Branch),
 			PRURL:     strings.TrimSpace(session.PRURL),
-			CommitSHA: strings.TrimSpace(run.CommitSHA),
-			CommitMsg: strings.TrimSpace(run.CommitMsg),
 		}
 	}
-	return CompletePayload{
-		Success:   true,
-		SessionID: sessionID,
+	r.recordJob(job, sessionID, run.ID, "")
+	return r.awaitRun(ctx, job, sessionID, run.ID)
+}
+
+// awaitRun polls a run until it finishes, reporting phase changes, draft PR
+// creation and validation failures to the cloud on the way, and returns the
+// completion payload of the finished run. The job's relay slot is freed
+// first: following a run does not count against MaxConcurrentJobs.
+func (r *Relay) awaitRun(ctx context.Context, job cloud.Job, sessionID, runID string) CompletePayload {
+	r.releaseSlot(job)
+	ticker := time.NewTicker(r.progressInterval)
+	defer ticker.Stop()
+
+	var (
+		lastPhase        string
+		lastPREvent      int64
+		lastValidateFail int64
+	)
+	for {
+		session, run, err := r.loadSessionRun(sessionID, runID)
+		if err != nil {
+			return CompletePayload{Success: false, Error: err.Error(), SessionID: sessionID, RunID: runID}
+		}
+		if task.State(run.State).IsTerminal() {
+			payload := runPayload(session, run)
+			r.describeRun(ctx, &payload, session, run)
+			return payload
+		}
+
+		progress := ProgressPayload{
+			SessionID: session.ID,
+			RunID:     run.ID,
+			Branch:    strings.TrimSpace(session.Branch),
+			PRURL:     strings.TrimSpace(session.PRURL),
+		}
+		if event, found, err := r.stateStore.GetLatestRunEvent(run.ID, "validate"); err == nil && found && event.ID > lastValidateFail {
+			lastValidateFail = event.ID
+			if event.Data == "failed" {
+				progress.Phase, progress.Message = "VALIDATION_FAILED", event.Message
+				r.reportProgress(ctx, job, progress)
+			}
+		}
+		if event, found, err := r.stateStore.GetLatestRunEvent(run.ID, "pr"); err == nil && found && event.ID > lastPREvent {
+			lastPREvent = event.ID
+			progress.Phase, progress.Message = string(task.StatePRCreated), event.Message
+			r.reportProgress(ctx, job, progress)
+		}
+		if run.State != lastPhase && run.State != string(task.StateCreated) {
+			lastPhase = run.State
+			progress.Phase, progress.Message = run.State, ""
+			r.reportProgress(ctx, job, progress)
+		}
+
+		select {
+		case <-ctx.Done():
+			return CompletePayload{Success: false, Error: ctx.Err().Error(), SessionID: sessionID, RunID: runID}
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Relay) loadSessionRun(sessionID, runID string) (state.Session, state.Run, error) {
+	session, found, err := r.stateStore.GetSession(sessionID)
+	if err != nil {
+		return state.Session{}, state.Run{}, err
+	}
+	if !found {
+		return state.Session{}, state.Run{}, fmt.Errorf("session %q disappeared", sessionID)
+	}
+	run, found, err := r.stateStore.GetRun(runID)
+	if err != nil {
+		return state.Session{}, state.Run{}, err
+	}
+	if !found {
+		return state.Session{}, state.Run{}, fmt.Errorf("run %q disappeared", runID)
+	}
+	return session, run, nil
+}
+
+func (r *Relay) reportProgress(ctx context.Context, job cloud.Job, payload ProgressPayload) {
+	payload.Attempt = job.Attempts
+	err := r.callJobAPI(func(api jobAPI) error {
+		return api.ReportProgress(ctx, job.ID, payload)
+	})
+	if errors.Is(err, ErrLeaseLost) {
+		r.loseLease(job)
+		return
+	}
+	if err != nil && ctx.Err() == nil {
+		log.Printf("cloud relay progress for job %s failed: %v", job.ID, err)
+	}
+}
+
+func runPayload(session state.Session, run state.Run) CompletePayload {
+	payload := CompletePayload{
+		Success:   run.State == string(task.StateCompleted),
+		SessionID: session.ID,
 		RunID:     run.ID,
 		Branch:    strings.TrimSpace(session.Branch),
 		PRURL:     strings.TrimSpace(session.PRURL),
 		CommitSHA: strings.TrimSpace(run.CommitSHA),
 		CommitMsg: strings.TrimSpace(run.CommitMsg),
 	}
+	if !payload.Success {
+		payload.Error = fallbackError(run.Error, strings.ToLower(run.State))
+	}
+	return payload
+}
+
+// describeRun adds the run's validation result and the branch's diff stat
+// to its completion. Both are best effort.
+func (r *Relay) describeRun(ctx context.Context, payload *CompletePayload, session state.Session, run state.Run) {
+	if event, found, err := r.stateStore.GetLatestRunEvent(run.ID, "validate"); err == nil && found {
+		payload.Validation = event.Data
+	}
+	repo, found, err := r.stateStore.GetRepoByName(session.RepoName)
+	if err != nil || !found {
+		return
+	}
+	if diff, err := r.runner.SessionDiff(ctx, session, repo); err == nil {
+		payload.DiffStat = diff.Summary()
+	}
+}
+
+func fallbackError(msg, alt string) string {
+	if msg = strings.TrimSpace(msg); msg != "" {
+		return msg
+	}
+	return alt
 }
 
 func (r *Relay) resolveBranchName(requested, prompt string) (string, error) {
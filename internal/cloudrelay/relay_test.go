@@ -1,10 +1,17 @@
 package cloudrelay
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/darkLord19/foglet/internal/cloud"
+	"github.com/darkLord19/foglet/internal/runner"
 	"github.com/darkLord19/foglet/internal/state"
 )
 
@@ -49,7 +56,7 @@ func TestResolveBranchNameRequestedValidation(t *testing.T) {
 
 func TestHandleUnknownJobKind(t *testing.T) {
 	r := &Relay{}
-	out := r.handleJob(cloud.Job{Kind: "unknown"})
+	out := r.handleJob(context.Background(), cloud.Job{Kind: "unknown"})
 	if out.Success {
 		t.Fatal("expected unknown kind to fail")
 	}
@@ -57,3 +64,319 @@ func TestHandleUnknownJobKind(t *testing.T) {
 		t.Fatalf("unexpected error: %q", out.Error)
 	}
 }
+
+func TestHandleSessionCommandJobs(t *testing.T) {
+	store, err := state.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new state store failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	run, err := runner.New("", t.TempDir())
+	if err != nil {
+		t.Fatalf("new runner failed: %v", err)
+	}
+	run.SetStateStore(store)
+	if _, err := store.UpsertRepo(state.Repo{Name: "acme/api", URL: "https://github.com/acme/api.git", Host: "github.com", Owner: "acme", Repo: "api", BarePath: "/tmp/repo.git", BaseWorktreePath: "/tmp/base", DefaultBranch: "main"}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	if err := store.CreateSession(state.Session{ID: "sess-1", RepoName: "acme/api", Branch: "fog/otp", WorktreePath: "/tmp/wt", Tool: "claude", Status: "COMPLETED"}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	r := &Relay{runner: run, stateStore: store}
+	ctx := context.Background()
+
+	out := r.handleJob(ctx, cloud.Job{Kind: "list_sessions"})
+	if !out.Success || !strings.Contains(out.Message, "fog/otp") {
+		t.Fatalf("unexpected list_sessions payload: %+v", out)
+	}
+	out = r.handleJob(ctx, cloud.Job{Kind: "status", SessionID: "sess"})
+	if !out.Success || out.SessionID != "sess-1" || !strings.Contains(out.Message, "COMPLETED") {
+		t.Fatalf("unexpected status payload: %+v", out)
+	}
+	out = r.handleJob(ctx, cloud.Job{Kind: "pr_merge", SessionID: "sess-1", MergeMethod: "squash"})
+	if out.Success || !strings.Contains(out.Error, "has no pull request") {
+		t.Fatalf("unexpected pr_merge payload: %+v", out)
+	}
+}
+
+func TestHeartbeatInterval(t *testing.T) {
+	now := time.Now()
+	lease := now.Add(90 * time.Second)
+	if got := heartbeatInterval(&lease, now); got != 30*time.Second {
+		t.Fatalf("expected a third of the lease, got %s", got)
+	}
+	short := now.Add(time.Second)
+	if got := heartbeatInterval(&short, now); got != time.Second {
+		t.Fatalf("expected 1s floor, got %s", got)
+	}
+	if got := heartbeatInterval(nil, now); got != 30*time.Second {
+		t.Fatalf("expected default interval without lease, got %s", got)
+	}
+}
+
+func TestAwaitRunReportsProgress(t *testing.T) {
+	store, err := state.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new state store failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	if _, err := store.UpsertRepo(state.Repo{Name: "acme/api", URL: "https://github.com/acme/api.git", Host: "github.com", Owner: "acme", Repo: "api", BarePath: "/tmp/repo.git", BaseWorktreePath: "/tmp/base", DefaultBranch: "main"}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	if err := store.CreateSession(state.Session{ID: "sess-1", RepoName: "acme/api", Branch: "fog/otp", WorktreePath: "/tmp/wt", Tool: "claude", AutoPR: true, Status: "SETUP"}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	if err := store.CreateRun(state.Run{ID: "run-1", SessionID: "sess-1", Prompt: "add otp", WorktreePath: "/tmp/wt", State: "SETUP"}); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+
+	// The fake cloud advances the run after each report, so every poll of
+	// awaitRun observes exactly one new step.
+	var got []ProgressPayload
+	cloudSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/device/jobs/job-1/progress" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+			return
+		}
+		var payload ProgressPayload
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		got = append(got, payload)
+		switch payload.Phase {
+		case "SETUP":
+			_ = store.SetRunState("run-1", "AI_RUNNING")
+		case "AI_RUNNING":
+			_ = store.AppendRunEvent(state.RunEvent{RunID: "run-1", Type: "validate", Message: "Validation failed: exit 1", Data: "failed"})
+			_ = store.SetRunState("run-1", "VALIDATING")
+		case "VALIDATING":
+			_ = store.SetSessionPRURL("sess-1", "https://github.com/acme/api/pull/7")
+			_ = store.AppendRunEvent(state.RunEvent{RunID: "run-1", Type: "pr", Message: "Draft PR created"})
+		case "PR_CREATED":
+			_ = store.CompleteRun("run-1", "COMPLETED", "abc123", "feat: add otp", "")
+		}
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer cloudSrv.Close()
+
+	client, err := NewClient(ClientConfig{BaseURL: cloudSrv.URL, DeviceID: "device-a", DeviceToken: "token"})
+	if err != nil {
+		t.Fatalf("new client failed: %v", err)
+	}
+	run, err := runner.New("", t.TempDir())
+	if err != nil {
+		t.Fatalf("new runner failed: %v", err)
+	}
+	run.SetStateStore(store)
+	r := &Relay{client: client, runner: run, stateStore: store, progressInterval: time.Millisecond}
+	out := r.awaitRun(context.Background(), cloud.Job{ID: "job-1"}, "sess-1", "run-1")
+
+	var phases []string
+	for _, p := range got {
+		phases = append(phases, p.Phase)
+	}
+	if want := "SETUP AI_RUNNING VALIDATION_FAILED VALIDATING PR_CREATED"; strings.Join(phases, " ") != want {
+		t.Fatalf("unexpected phases: got %q want %q", strings.Join(phases, " "), want)
+	}
+	if got[2].Message != "Validation failed: exit 1" || got[4].PRURL != "https://github.com/acme/api/pull/7" {
+		t.Fatalf("unexpected progress payloads: %+v", got)
+	}
+	if !out.Success || out.CommitSHA != "abc123" || out.PRURL != "https://github.com/acme/api/pull/7" || out.Branch != "fog/otp" || out.Validation != "failed" {
+		t.Fatalf("unexpected completion payload: %+v", out)
+	}
+}
+
+func TestRunPayloadReportsFailedRun(t *testing.T) {
+	out := runPayload(state.Session{ID: "sess-1", Branch: "fog/otp"}, state.Run{ID: "run-1", State: "TIMED_OUT"})
+	if out.Success || out.Error != "timed_out" || out.RunID != "run-1" {
+		t.Fatalf("unexpected payload: %+v", out)
+	}
+}
+
+func TestRunJobReusesRecordedResult(t *testing.T) {
+	store, err := state.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new state store failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	run, err := runner.New("", t.TempDir())
+	if err != nil {
+		t.Fatalf("new runner failed: %v", err)
+	}
+	run.SetStateStore(store)
+	if _, err := store.UpsertRepo(state.Repo{Name: "acme/api", URL: "https://github.com/acme/api.git", Host: "github.com", Owner: "acme", Repo: "api", BarePath: "/tmp/repo.git", BaseWorktreePath: "/tmp/base", DefaultBranch: "main"}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	if err := store.CreateSession(state.Session{ID: "sess-1", RepoName: "acme/api", Branch: "fog/otp", WorktreePath: "/tmp/wt", Tool: "claude", Status: "COMPLETED"}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	if err := store.CreateRun(state.Run{ID: "run-1", SessionID: "sess-1", Prompt: "add otp", WorktreePath: "/tmp/wt", State: "SETUP"}); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+	if err := store.CompleteRun("run-1", "COMPLETED", "abc123", "feat: add otp", ""); err != nil {
+		t.Fatalf("complete run failed: %v", err)
+	}
+	r := &Relay{runner: run, stateStore: store, progressInterval: time.Millisecond}
+	ctx := context.Background()
+
+	// A command job records its answer, and a re-delivery repeats it
+	// without running the command again.
+	first := r.runJob(ctx, cloud.Job{ID: "job-status", Kind: "status", SessionID: "sess-1", Attempts: 1})
+	if !first.Success || !strings.Contains(first.Message, "COMPLETED") {
+		t.Fatalf("unexpected status payload: %+v", first)
+	}
+	if err := store.DeleteSession("sess-1"); err != nil {
+		t.Fatalf("delete session failed: %v", err)
+	}
+	again := r.runJob(ctx, cloud.Job{ID: "job-status", Kind: "status", SessionID: "sess-1", Attempts: 2})
+	if again != first {
+		t.Fatalf("expected recorded result %+v, got %+v", first, again)
+	}
+
+	// A job that started a run reports that run again instead of forking
+	// another session.
+	if err := store.CreateSession(state.Session{ID: "sess-2", RepoName: "acme/api", Branch: "fog/fork", WorktreePath: "/tmp/wt2", Tool: "claude", Status: "COMPLETED"}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	if err := store.CreateRun(state.Run{ID: "run-2", SessionID: "sess-2", Prompt: "add otp", WorktreePath: "/tmp/wt2", State: "SETUP"}); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+	if err := store.CompleteRun("run-2", "COMPLETED", "def456", "feat: fork", ""); err != nil {
+		t.Fatalf("complete run failed: %v", err)
+	}
+	if err := store.RecordCloudJob(state.CloudJob{JobID: "job-fork", SessionID: "sess-2", RunID: "run-2"}); err != nil {
+		t.Fatalf("record cloud job failed: %v", err)
+	}
+	out := r.runJob(ctx, cloud.Job{ID: "job-fork", Kind: "fork", SessionID: "missing", Attempts: 2})
+	if !out.Success || out.SessionID != "sess-2" || out.RunID != "run-2" || out.CommitSHA != "def456" {
+		t.Fatalf("unexpected re-delivered fork payload: %+v", out)
+	}
+}
+
+func TestProcessJobStopsReportingAfterLeaseLost(t *testing.T) {
+	store, err := state.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new state store failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	if _, err := store.UpsertRepo(state.Repo{Name: "acme/api", URL: "https://github.com/acme/api.git", Host: "github.com", Owner: "acme", Repo: "api", BarePath: "/tmp/repo.git", BaseWorktreePath: "/tmp/base", DefaultBranch: "main"}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	if err := store.CreateSession(state.Session{ID: "sess-1", RepoName: "acme/api", Branch: "fog/otp", WorktreePath: "/tmp/wt", Tool: "claude", Status: "AI_RUNNING"}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	if err := store.CreateRun(state.Run{ID: "run-1", SessionID: "sess-1", Prompt: "add otp", WorktreePath: "/tmp/wt", State: "AI_RUNNING"}); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+	if err := store.RecordCloudJob(state.CloudJob{JobID: "job-1", SessionID: "sess-1", RunID: "run-1"}); err != nil {
+		t.Fatalf("record cloud job failed: %v", err)
+	}
+
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+	cloudSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Attempt int `json:"attempt"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		calls = append(calls, r.URL.Path)
+		mu.Unlock()
+		if payload.Attempt != 1 {
+			t.Errorf("expected attempt 1 on %s, got %d", r.URL.Path, payload.Attempt)
+		}
+		http.Error(w, "job lease lost", http.StatusConflict)
+	}))
+	defer cloudSrv.Close()
+
+	client, err := NewClient(ClientConfig{BaseURL: cloudSrv.URL, DeviceID: "device-a", DeviceToken: "token"})
+	if err != nil {
+		t.Fatalf("new client failed: %v", err)
+	}
+	r := &Relay{client: client, stateStore: store, progressInterval: time.Millisecond}
+	done := make(chan error, 1)
+	go func() {
+		done <- r.processJob(context.Background(), cloud.Job{ID: "job-1", Kind: "follow_up", Attempts: 1})
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("process job failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("processJob kept running after the lease was lost")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) != 1 || calls[0] != "/v1/device/jobs/job-1/progress" {
+		t.Fatalf("expected a single rejected progress call and no completion, got %v", calls)
+	}
+}
+
+func TestStartJobFreesSlotOnceRunIsUnderWay(t *testing.T) {
+	store, err := state.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new state store failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	if _, err := store.UpsertRepo(state.Repo{Name: "acme/api", URL: "https://github.com/acme/api.git", Host: "github.com", Owner: "acme", Repo: "api", BarePath: "/tmp/repo.git", BaseWorktreePath: "/tmp/base", DefaultBranch: "main"}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	if err := store.CreateSession(state.Session{ID: "sess-1", RepoName: "acme/api", Branch: "fog/otp", WorktreePath: "/tmp/wt", Tool: "claude", Status: "AI_RUNNING"}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	if err := store.CreateRun(state.Run{ID: "run-1", SessionID: "sess-1", Prompt: "add otp", WorktreePath: "/tmp/wt", State: "AI_RUNNING"}); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+	if err := store.RecordCloudJob(state.CloudJob{JobID: "job-1", SessionID: "sess-1", RunID: "run-1"}); err != nil {
+		t.Fatalf("record cloud job failed: %v", err)
+	}
+
+	completed := make(chan struct{})
+	cloudSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/device/jobs/job-1/complete" {
+			close(completed)
+		}
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer cloudSrv.Close()
+
+	client, err := NewClient(ClientConfig{BaseURL: cloudSrv.URL, DeviceID: "device-a", DeviceToken: "token"})
+	if err != nil {
+		t.Fatalf("new client failed: %v", err)
+	}
+	run, err := runner.New("", t.TempDir())
+	if err != nil {
+		t.Fatalf("new runner failed: %v", err)
+	}
+	run.SetStateStore(store)
+	r := &Relay{client: client, runner: run, stateStore: store, progressInterval: time.Millisecond}
+
+	slots := make(chan struct{}, 1)
+	slots <- struct{}{}
+	var wg sync.WaitGroup
+	r.startJob(context.Background(), cloud.Job{ID: "job-1", Kind: "follow_up", Attempts: 1}, slots, &wg)
+
+	// With the only slot free again, a cancel job can be claimed while the
+	// run is still going.
+	select {
+	case slots <- struct{}{}:
+		<-slots
+	case <-time.After(5 * time.Second):
+		t.Fatal("job kept its slot while following its run")
+	}
+
+	if err := store.CompleteRun("run-1", "COMPLETED", "abc123", "feat: add otp", ""); err != nil {
+		t.Fatalf("complete run failed: %v", err)
+	}
+	select {
+	case <-completed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job was not completed after its run finished")
+	}
+	wg.Wait()
+	if len(slots) != 0 {
+		t.Fatalf("slot released twice or leaked: %d held", len(slots))
+	}
+}
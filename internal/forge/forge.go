@@ -0,0 +1,169 @@
+// Package forge abstracts the code hosts Fog imports repositories from and
+// opens pull requests on: GitHub through gh, GitLab through glab and Gitea
+// through its REST API.
+package forge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/ghcli"
+)
+
+// Forge kinds.
+const (
+	KindGitHub = "github"
+	KindGitLab = "gitlab"
+	KindGitea  = "gitea"
+)
+
+// ErrUnsupported is returned for actions a forge does not implement.
+var ErrUnsupported = errors.New("not supported by this forge")
+
+// Shapes shared by every forge. GitHub's are the reference; other forges
+// fill in what they have.
+type (
+	Repo       = ghcli.Repo
+	PRStatus   = ghcli.PRStatus
+	PREdit     = ghcli.PREdit
+	PRFeedback = ghcli.PRFeedback
+)
+
+// NewPR describes a pull (merge) request to open.
+type NewPR struct {
+	Title string
+	Body  string
+	Base  string
+	Head  string
+	Draft bool
+}
+
+// Forge is a code host. repoPath is a local checkout of the repository the
+// pull request belongs to; prURL is the pull request's web URL.
+type Forge interface {
+	Kind() string
+	Host() string
+	// Check reports why the forge cannot be used, e.g. a missing CLI or
+	// credentials.
+	Check(ctx context.Context) error
+	DiscoverRepos(ctx context.Context) ([]Repo, error)
+	// CloneRepo makes a bare clone of repo at destPath.
+	CloneRepo(ctx context.Context, repo Repo, destPath string) error
+	// GitEnv returns "KEY=value" entries that authenticate git against the
+	// forge (clone, push); nil when git is expected to be set up already.
+	GitEnv() []string
+
+	CreatePR(ctx context.Context, repoPath string, pr NewPR) (string, error)
+	PRState(ctx context.Context, repoPath, prURL string) (string, error)
+	PRStatus(ctx context.Context, repoPath, prURL string) (PRStatus, error)
+	PRFeedback(ctx context.Context, repoPath, prURL string) (PRFeedback, error)
+	MarkPRReady(ctx context.Context, repoPath, prURL string) error
+	EditPR(ctx context.Context, repoPath, prURL string, edit PREdit) error
+	MergePR(ctx context.Context, repoPath, prURL, method string) error
+}
+
+// Config selects and configures the forge for one host.
+type Config struct {
+	Host string
+	Kind string
+	// BaseURL is the web root of a self-hosted forge; defaults to
+	// https://<host>.
+	BaseURL string
+	// Token authenticates API and git requests for forges without a CLI
+	// (Gitea).
+	Token string
+}
+
+// ValidKind reports whether kind names a supported forge.
+func ValidKind(kind string) bool {
+	switch kind {
+	case KindGitHub, KindGitLab, KindGitea:
+		return true
+	}
+	return false
+}
+
+// KindForHost guesses the forge of a host nobody configured: well-known
+// public hosts map to their forge, anything else is assumed to be GitHub
+// Enterprise reachable through gh.
+func KindForHost(host string) string {
+	switch strings.ToLower(strings.TrimSpace(host)) {
+	case "gitlab.com":
+		return KindGitLab
+	case "gitea.com", "codeberg.org":
+		return KindGitea
+	}
+	return KindGitHub
+}
+
+// New returns the forge described by cfg. An empty Kind is guessed from the
+// host.
+func New(cfg Config) (Forge, error) {
+	cfg.Host = strings.ToLower(strings.TrimSpace(cfg.Host))
+	cfg.Kind = strings.ToLower(strings.TrimSpace(cfg.Kind))
+	if cfg.Host == "" {
+		cfg.Host = "github.com"
+	}
+	if cfg.Kind == "" {
+		cfg.Kind = KindForHost(cfg.Host)
+	}
+	baseURL := strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/")
+	if baseURL == "" {
+		baseURL = "https://" + cfg.Host
+	}
+	switch cfg.Kind {
+	case KindGitHub:
+		return &GitHub{host: cfg.Host}, nil
+	case KindGitLab:
+		return &GitLab{host: cfg.Host}, nil
+	case KindGitea:
+		return newGitea(cfg.Host, baseURL, strings.TrimSpace(cfg.Token)), nil
+	}
+	return nil, fmt.Errorf("unknown forge kind %q (use github, gitlab or gitea)", cfg.Kind)
+}
+
+// HostOf returns the host of a repository or pull request URL, including
+// scp-style git remotes (git@host:owner/repo.git).
+func HostOf(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		return strings.ToLower(u.Hostname())
+	}
+	if at := strings.Index(rawURL, "@"); at >= 0 {
+		if host, _, ok := strings.Cut(rawURL[at+1:], ":"); ok {
+			return strings.ToLower(host)
+		}
+	}
+	return ""
+}
+
+// draftTitlePrefix matches the title prefixes GitLab and Gitea use to mark
+// work in progress.
+var draftTitlePrefix = regexp.MustCompile(`(?i)^\s*(\[draft\]|\(draft\)|draft:|draft\s+-|\[wip\]|wip:)\s*`)
+
+// isDraftTitle reports whether title marks a draft.
+func isDraftTitle(title string) bool {
+	return draftTitlePrefix.MatchString(title)
+}
+
+// stripDraftTitle removes a draft prefix from title.
+func stripDraftTitle(title string) string {
+	return strings.TrimSpace(draftTitlePrefix.ReplaceAllString(title, ""))
+}
+
+// checksFromCounts fills in the overall checks state.
+func checksFromCounts(checks ghcli.CheckSummary) ghcli.CheckSummary {
+	switch {
+	case checks.Failed > 0:
+		checks.State = ghcli.ChecksFailing
+	case checks.Pending > 0:
+		checks.State = ghcli.ChecksPending
+	case checks.Passed > 0:
+		checks.State = ghcli.ChecksPassing
+	}
+	return checks
+}
@@ -0,0 +1,58 @@
+package forge
+
+import "testing"
+
+func TestNewSelectsForgeByKindAndHost(t *testing.T) {
+	cases := []struct {
+		cfg  Config
+		kind string
+		host string
+	}{
+		{Config{}, KindGitHub, "github.com"},
+		{Config{Host: "GitLab.com"}, KindGitLab, "gitlab.com"},
+		{Config{Host: "codeberg.org"}, KindGitea, "codeberg.org"},
+		{Config{Host: "github.acme.dev"}, KindGitHub, "github.acme.dev"},
+		{Config{Host: "git.acme.dev", Kind: "Gitea"}, KindGitea, "git.acme.dev"},
+	}
+	for _, tc := range cases {
+		f, err := New(tc.cfg)
+		if err != nil {
+			t.Fatalf("New(%+v) failed: %v", tc.cfg, err)
+		}
+		if f.Kind() != tc.kind || f.Host() != tc.host {
+			t.Fatalf("New(%+v) = %s %s, want %s %s", tc.cfg, f.Kind(), f.Host(), tc.kind, tc.host)
+		}
+	}
+	if _, err := New(Config{Host: "x", Kind: "bitbucket"}); err == nil {
+		t.Fatal("expected unknown kind error")
+	}
+}
+
+func TestHostOf(t *testing.T) {
+	cases := map[string]string{
+		"https://gitlab.acme.dev/acme/api":           "gitlab.acme.dev",
+		"https://git.acme.dev:3000/acme/api/pulls/4": "git.acme.dev",
+		"git@github.com:acme/api.git":                "github.com",
+		"ssh://git@git.acme.dev:2222/acme/api.git":   "git.acme.dev",
+		"": "",
+	}
+	for raw, want := range cases {
+		if got := HostOf(raw); got != want {
+			t.Errorf("HostOf(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestDraftTitle(t *testing.T) {
+	for _, title := range []string{"Draft: Add OTP", "[Draft] Add OTP", "WIP: Add OTP", "[wip] Add OTP"} {
+		if !isDraftTitle(title) {
+			t.Errorf("%q should be a draft title", title)
+		}
+		if got := stripDraftTitle(title); got != "Add OTP" {
+			t.Errorf("stripDraftTitle(%q) = %q", title, got)
+		}
+	}
+	if isDraftTitle("Drafting rules: Add OTP") {
+		t.Error("plain title detected as draft")
+	}
+}
@@ -0,0 +1,70 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/proc"
+)
+
+// procRun runs CLI commands (git, glab); tests replace it.
+var procRun = proc.Run
+
+// gitConfigEnv turns git config key/value pairs into GIT_CONFIG_* entries,
+// which apply to one git invocation without touching any config file.
+func gitConfigEnv(pairs ...[2]string) []string {
+	env := []string{"GIT_CONFIG_COUNT=" + strconv.Itoa(len(pairs))}
+	for i, pair := range pairs {
+		env = append(env,
+			fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", i, pair[0]),
+			fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", i, pair[1]),
+		)
+	}
+	return env
+}
+
+// cloneBare makes a full bare clone of cloneURL. Unlike gh clones it skips
+// the blob filter: git fetches filtered blobs lazily, later, from commands
+// that do not carry the forge credentials in env.
+func cloneBare(ctx context.Context, cloneURL, destPath string, env []string) error {
+	output, err := procRun(proc.WithEnv(ctx, env), "", "git", "clone", "--bare", cloneURL, destPath)
+	if err != nil {
+		return fmt.Errorf("git clone %s failed: %w%s", cloneURL, err, outputSuffix(output))
+	}
+	return nil
+}
+
+// remoteRepoPath returns the repository path (owner/repo, or
+// group/subgroup/project) of the origin remote of the checkout at repoPath.
+func remoteRepoPath(ctx context.Context, repoPath string) (string, error) {
+	output, err := procRun(ctx, repoPath, "git", "remote", "get-url", "origin")
+	if err != nil {
+		return "", fmt.Errorf("git remote get-url origin failed: %w%s", err, outputSuffix(output))
+	}
+	remote := strings.TrimSpace(string(output))
+	path := ""
+	if u, err := url.Parse(remote); err == nil && u.Host != "" {
+		path = u.Path
+	} else if _, rest, ok := strings.Cut(remote, ":"); ok {
+		path = rest
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if !strings.Contains(path, "/") {
+		return "", fmt.Errorf("cannot parse repository path from remote %q", remote)
+	}
+	return path, nil
+}
+
+func outputSuffix(output []byte) string {
+	msg := strings.TrimSpace(string(output))
+	if len(msg) > 4096 {
+		msg = msg[:4096] + "..."
+	}
+	if msg == "" {
+		return ""
+	}
+	return "\n" + msg
+}
@@ -0,0 +1,369 @@
+package forge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/ghcli"
+)
+
+// giteaPageSize is the page size requested from list endpoints; Gitea caps
+// it at its configured maximum (50 by default).
+const giteaPageSize = 50
+
+// Gitea talks to a Gitea (or Forgejo) instance through its REST API with a
+// personal access token.
+type Gitea struct {
+	host    string
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func newGitea(host, baseURL, token string) *Gitea {
+	return &Gitea{
+		host:    host,
+		baseURL: baseURL,
+		token:   token,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (g *Gitea) Kind() string { return KindGitea }
+func (g *Gitea) Host() string { return g.host }
+
+func (g *Gitea) Check(ctx context.Context) error {
+	if g.token == "" {
+		return fmt.Errorf("no Gitea token configured for %s; run `fog forge add %s --kind gitea`", g.host, g.host)
+	}
+	return g.do(ctx, http.MethodGet, "/user", nil, nil)
+}
+
+// do sends one API request and decodes the JSON response into out.
+func (g *Gitea) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, g.baseURL+"/api/v1"+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if g.token != "" {
+		req.Header.Set("Authorization", "token "+g.token)
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("gitea %s %s: %w", method, path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if err != nil {
+		return fmt.Errorf("gitea %s %s: %w", method, path, err)
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		msg := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			msg = apiErr.Message
+		}
+		return fmt.Errorf("gitea %s %s failed: %s: %s", method, path, resp.Status, msg)
+	}
+	if out == nil || len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decode gitea %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+func (g *Gitea) DiscoverRepos(ctx context.Context) ([]Repo, error) {
+	var repos []Repo
+	for page := 1; ; page++ {
+		var batch []struct {
+			ID            int64  `json:"id"`
+			Name          string `json:"name"`
+			FullName      string `json:"full_name"`
+			HTMLURL       string `json:"html_url"`
+			Private       bool   `json:"private"`
+			DefaultBranch string `json:"default_branch"`
+			Owner         struct {
+				Login string `json:"login"`
+			} `json:"owner"`
+		}
+		path := fmt.Sprintf("/user/repos?limit=%d&page=%d", giteaPageSize, page)
+		if err := g.do(ctx, http.MethodGet, path, nil, &batch); err != nil {
+			return nil, err
+		}
+		for _, r := range batch {
+			repo := Repo{
+				ID:            strconv.FormatInt(r.ID, 10),
+				Name:          r.Name,
+				NameWithOwner: r.FullName,
+				URL:           r.HTMLURL,
+				IsPrivate:     r.Private,
+			}
+			repo.DefaultBranchRef.Name = r.DefaultBranch
+			repo.Owner.Login = r.Owner.Login
+			repos = append(repos, repo)
+		}
+		if len(batch) < giteaPageSize {
+			return repos, nil
+		}
+	}
+}
+
+func (g *Gitea) CloneRepo(ctx context.Context, repo Repo, destPath string) error {
+	return cloneBare(ctx, repo.URL, destPath, g.GitEnv())
+}
+
+// GitEnv sends the token with git's HTTP requests to the forge only.
+func (g *Gitea) GitEnv() []string {
+	if g.token == "" {
+		return nil
+	}
+	return gitConfigEnv([2]string{"http." + g.baseURL + "/.extraHeader", "Authorization: token " + g.token})
+}
+
+func (g *Gitea) CreatePR(ctx context.Context, repoPath string, pr NewPR) (string, error) {
+	path, err := remoteRepoPath(ctx, repoPath)
+	if err != nil {
+		return "", err
+	}
+	title := pr.Title
+	if pr.Draft && !isDraftTitle(title) {
+		title = "WIP: " + title
+	}
+	var created struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := g.do(ctx, http.MethodPost, "/repos/"+path+"/pulls", map[string]string{
+		"head":  pr.Head,
+		"base":  pr.Base,
+		"title": title,
+		"body":  pr.Body,
+	}, &created); err != nil {
+		return "", err
+	}
+	return created.HTMLURL, nil
+}
+
+// giteaPull is the part of a pull request Fog reads.
+type giteaPull struct {
+	Title     string `json:"title"`
+	State     string `json:"state"`
+	Merged    bool   `json:"merged"`
+	Mergeable bool   `json:"mergeable"`
+	Head      struct {
+		SHA string `json:"sha"`
+	} `json:"head"`
+}
+
+// pullPath parses https://<host>/<owner>/<repo>/pulls/<number> into the
+// API path of the pull request and its repository.
+func (g *Gitea) pullPath(prURL string) (repoPath string, number int, err error) {
+	u, parseErr := url.Parse(strings.TrimSpace(prURL))
+	if parseErr != nil || u.Host == "" {
+		return "", 0, fmt.Errorf("invalid pull request URL %q", prURL)
+	}
+	// Instances served under a sub-path carry it in the base URL.
+	path := strings.Trim(u.Path, "/")
+	if base, err := url.Parse(g.baseURL); err == nil {
+		path = strings.TrimPrefix(path, strings.Trim(base.Path, "/")+"/")
+	}
+	parts := strings.Split(path, "/")
+	if len(parts) != 4 || parts[2] != "pulls" {
+		return "", 0, fmt.Errorf("invalid pull request URL %q", prURL)
+	}
+	number, err = strconv.Atoi(parts[3])
+	if err != nil || number <= 0 {
+		return "", 0, fmt.Errorf("invalid pull request URL %q", prURL)
+	}
+	return "/repos/" + parts[0] + "/" + parts[1], number, nil
+}
+
+func (g *Gitea) getPull(ctx context.Context, prURL string) (string, int, giteaPull, error) {
+	repoPath, number, err := g.pullPath(prURL)
+	if err != nil {
+		return "", 0, giteaPull{}, err
+	}
+	var pull giteaPull
+	if err := g.do(ctx, http.MethodGet, fmt.Sprintf("%s/pulls/%d", repoPath, number), nil, &pull); err != nil {
+		return "", 0, giteaPull{}, err
+	}
+	return repoPath, number, pull, nil
+}
+
+func giteaState(pull giteaPull) string {
+	switch {
+	case pull.Merged:
+		return "MERGED"
+	case pull.State == "open":
+		return "OPEN"
+	}
+	return "CLOSED"
+}
+
+func (g *Gitea) PRState(ctx context.Context, repoPath, prURL string) (string, error) {
+	_, _, pull, err := g.getPull(ctx, prURL)
+	if err != nil {
+		return "", err
+	}
+	return giteaState(pull), nil
+}
+
+func (g *Gitea) PRStatus(ctx context.Context, repoPath, prURL string) (PRStatus, error) {
+	apiRepo, _, pull, err := g.getPull(ctx, prURL)
+	if err != nil {
+		return PRStatus{}, err
+	}
+	status := PRStatus{
+		State:   giteaState(pull),
+		IsDraft: isDraftTitle(pull.Title),
+	}
+	if status.State != "OPEN" {
+		return status, nil
+	}
+	status.Mergeable = "CONFLICTING"
+	if pull.Mergeable {
+		status.Mergeable = "MERGEABLE"
+	}
+	if pull.Head.SHA == "" {
+		return status, nil
+	}
+	var combined struct {
+		Statuses []struct {
+			Status string `json:"status"`
+		} `json:"statuses"`
+	}
+	if err := g.do(ctx, http.MethodGet, apiRepo+"/commits/"+pull.Head.SHA+"/status", nil, &combined); err != nil {
+		return PRStatus{}, err
+	}
+	for _, s := range combined.Statuses {
+		switch s.Status {
+		case "success", "warning":
+			status.Checks.Passed++
+		case "pending":
+			status.Checks.Pending++
+		default:
+			status.Checks.Failed++
+		}
+	}
+	status.Checks = checksFromCounts(status.Checks)
+	return status, nil
+}
+
+func (g *Gitea) PRFeedback(ctx context.Context, repoPath, prURL string) (PRFeedback, error) {
+	return PRFeedback{}, fmt.Errorf("pull request feedback sync: %w", ErrUnsupported)
+}
+
+func (g *Gitea) MarkPRReady(ctx context.Context, repoPath, prURL string) error {
+	apiRepo, number, pull, err := g.getPull(ctx, prURL)
+	if err != nil {
+		return err
+	}
+	if !isDraftTitle(pull.Title) {
+		return nil
+	}
+	return g.do(ctx, http.MethodPatch, fmt.Sprintf("%s/pulls/%d", apiRepo, number),
+		map[string]string{"title": stripDraftTitle(pull.Title)}, nil)
+}
+
+// EditPR updates the body, requests reviewers (users, or org/team for
+// teams) and adds labels by name.
+func (g *Gitea) EditPR(ctx context.Context, repoPath, prURL string, edit PREdit) error {
+	apiRepo, number, err := g.pullPath(prURL)
+	if err != nil {
+		return err
+	}
+	if body := strings.TrimSpace(edit.Body); body != "" {
+		if err := g.do(ctx, http.MethodPatch, fmt.Sprintf("%s/pulls/%d", apiRepo, number),
+			map[string]string{"body": body}, nil); err != nil {
+			return err
+		}
+	}
+	if len(edit.Reviewers) > 0 {
+		users, teams := []string{}, []string{}
+		for _, reviewer := range edit.Reviewers {
+			if _, team, ok := strings.Cut(reviewer, "/"); ok {
+				teams = append(teams, team)
+			} else {
+				users = append(users, reviewer)
+			}
+		}
+		if err := g.do(ctx, http.MethodPost, fmt.Sprintf("%s/pulls/%d/requested_reviewers", apiRepo, number),
+			map[string][]string{"reviewers": users, "team_reviewers": teams}, nil); err != nil {
+			return err
+		}
+	}
+	if len(edit.Labels) > 0 {
+		ids, err := g.labelIDs(ctx, apiRepo, edit.Labels)
+		if err != nil {
+			return err
+		}
+		if err := g.do(ctx, http.MethodPost, fmt.Sprintf("%s/issues/%d/labels", apiRepo, number),
+			map[string][]int64{"labels": ids}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Gitea) labelIDs(ctx context.Context, apiRepo string, names []string) ([]int64, error) {
+	byName := map[string]int64{}
+	for page := 1; ; page++ {
+		var batch []struct {
+			ID   int64  `json:"id"`
+			Name string `json:"name"`
+		}
+		if err := g.do(ctx, http.MethodGet, fmt.Sprintf("%s/labels?limit=%d&page=%d", apiRepo, giteaPageSize, page), nil, &batch); err != nil {
+			return nil, err
+		}
+		for _, label := range batch {
+			byName[label.Name] = label.ID
+		}
+		if len(batch) < giteaPageSize {
+			break
+		}
+	}
+	ids := make([]int64, 0, len(names))
+	for _, name := range names {
+		id, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("label %q not found in %s", name, strings.TrimPrefix(apiRepo, "/repos/"))
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (g *Gitea) MergePR(ctx context.Context, repoPath, prURL, method string) error {
+	if !ghcli.ValidMergeMethod(method) {
+		return fmt.Errorf("invalid merge method %q (use squash, rebase or merge)", method)
+	}
+	apiRepo, number, err := g.pullPath(prURL)
+	if err != nil {
+		return err
+	}
+	return g.do(ctx, http.MethodPost, fmt.Sprintf("%s/pulls/%d/merge", apiRepo, number),
+		map[string]string{"Do": method}, nil)
+}
@@ -0,0 +1,141 @@
+package forge
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/ghcli"
+)
+
+// fakeGitea is a stand-in Gitea API that serves canned GET responses and
+// records write requests.
+type fakeGitea struct {
+	mu     sync.Mutex
+	get    map[string]string
+	writes []string
+}
+
+func (f *fakeGitea) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "token secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = io.WriteString(w, `{"message":"token is required"}`)
+		return
+	}
+	path := strings.TrimPrefix(r.URL.RequestURI(), "/api/v1")
+	if r.Method == http.MethodGet {
+		body, ok := f.get[path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, body)
+		return
+	}
+	data, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	f.writes = append(f.writes, r.Method+" "+path+" "+strings.TrimSpace(string(data)))
+	f.mu.Unlock()
+	if r.Method == http.MethodPost && strings.HasSuffix(path, "/pulls") {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{"number": 4, "html_url": "https://git.acme.dev/acme/api/pulls/4"}`)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func newFakeGitea(t *testing.T, get map[string]string) (*fakeGitea, *Gitea) {
+	t.Helper()
+	fake := &fakeGitea{get: get}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	return fake, newGitea("git.acme.dev", srv.URL, "secret")
+}
+
+func TestGiteaDiscoverRepos(t *testing.T) {
+	_, gt := newFakeGitea(t, map[string]string{
+		"/user":                       `{"login":"me"}`,
+		"/user/repos?limit=50&page=1": `[{"id": 3, "name": "api", "full_name": "acme/api", "html_url": "https://git.acme.dev/acme/api", "private": true, "default_branch": "main", "owner": {"login": "acme"}}]`,
+	})
+	ctx := context.Background()
+	if err := gt.Check(ctx); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	repos, err := gt.DiscoverRepos(ctx)
+	if err != nil {
+		t.Fatalf("discover failed: %v", err)
+	}
+	if len(repos) != 1 || repos[0].NameWithOwner != "acme/api" || !repos[0].IsPrivate || repos[0].DefaultBranchRef.Name != "main" {
+		t.Fatalf("unexpected repos: %+v", repos)
+	}
+
+	gt.token = "wrong"
+	if err := gt.Check(ctx); err == nil || !strings.Contains(err.Error(), "token is required") {
+		t.Fatalf("expected auth error, got %v", err)
+	}
+	if env := gt.GitEnv(); len(env) != 3 || env[2] != "GIT_CONFIG_VALUE_0=Authorization: token wrong" {
+		t.Fatalf("unexpected git env: %v", env)
+	}
+}
+
+func TestGiteaPullRequestLifecycle(t *testing.T) {
+	origRun := procRun
+	t.Cleanup(func() { procRun = origRun })
+	procRun = func(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+		return []byte("https://git.acme.dev/acme/api.git\n"), nil
+	}
+
+	fake, gt := newFakeGitea(t, map[string]string{
+		"/repos/acme/api/pulls/4":                `{"title": "WIP: Add OTP", "state": "open", "merged": false, "mergeable": true, "head": {"sha": "abc"}}`,
+		"/repos/acme/api/commits/abc/status":     `{"state": "pending", "statuses": [{"status": "success"}, {"status": "pending"}]}`,
+		"/repos/acme/api/labels?limit=50&page=1": `[{"id": 9, "name": "fog"}, {"id": 10, "name": "bug"}]`,
+	})
+	ctx := context.Background()
+	const prURL = "https://git.acme.dev/acme/api/pulls/4"
+
+	url, err := gt.CreatePR(ctx, "/wt", NewPR{Title: "Add OTP", Body: "body", Base: "main", Head: "fog/otp", Draft: true})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if url != prURL {
+		t.Fatalf("unexpected url %q", url)
+	}
+
+	status, err := gt.PRStatus(ctx, "/wt", prURL)
+	if err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	want := PRStatus{State: "OPEN", IsDraft: true, Mergeable: "MERGEABLE", Checks: ghcli.CheckSummary{State: ghcli.ChecksPending, Passed: 1, Pending: 1}}
+	if status != want {
+		t.Fatalf("unexpected status: got %+v want %+v", status, want)
+	}
+
+	if err := gt.MarkPRReady(ctx, "/wt", prURL); err != nil {
+		t.Fatalf("ready failed: %v", err)
+	}
+	if err := gt.EditPR(ctx, "/wt", prURL, PREdit{Body: "new body", Reviewers: []string{"alice", "acme/core"}, Labels: []string{"fog"}}); err != nil {
+		t.Fatalf("edit failed: %v", err)
+	}
+	if err := gt.EditPR(ctx, "/wt", prURL, PREdit{Labels: []string{"missing"}}); err == nil {
+		t.Fatal("expected unknown label error")
+	}
+	if err := gt.MergePR(ctx, "/wt", prURL, ghcli.MergeMethodRebase); err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+
+	wantWrites := []string{
+		`POST /repos/acme/api/pulls {"base":"main","body":"body","head":"fog/otp","title":"WIP: Add OTP"}`,
+		`PATCH /repos/acme/api/pulls/4 {"title":"Add OTP"}`,
+		`PATCH /repos/acme/api/pulls/4 {"body":"new body"}`,
+		`POST /repos/acme/api/pulls/4/requested_reviewers {"reviewers":["alice"],"team_reviewers":["core"]}`,
+		`POST /repos/acme/api/issues/4/labels {"labels":[9]}`,
+		`POST /repos/acme/api/pulls/4/merge {"Do":"rebase"}`,
+	}
+	if strings.Join(fake.writes, "\n") != strings.Join(wantWrites, "\n") {
+		t.Fatalf("unexpected writes:\n got %s\nwant %s", strings.Join(fake.writes, "\n"), strings.Join(wantWrites, "\n"))
+	}
+}
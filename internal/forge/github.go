@@ -0,0 +1,65 @@
+package forge
+
+import (
+	"context"
+	"errors"
+
+	"github.com/darkLord19/foglet/internal/ghcli"
+)
+
+// GitHub talks to github.com or GitHub Enterprise through the gh CLI.
+type GitHub struct {
+	host string
+}
+
+func (g *GitHub) Kind() string { return KindGitHub }
+func (g *GitHub) Host() string { return g.host }
+
+func (g *GitHub) Check(ctx context.Context) error {
+	if !ghcli.IsGhAvailable() {
+		return ghcli.ErrGhNotFound
+	}
+	if !ghcli.IsGhAuthenticated() {
+		return errors.New("gh CLI not authenticated; run `gh auth login`")
+	}
+	return nil
+}
+
+func (g *GitHub) DiscoverRepos(ctx context.Context) ([]Repo, error) {
+	return ghcli.DiscoverRepos()
+}
+
+func (g *GitHub) CloneRepo(ctx context.Context, repo Repo, destPath string) error {
+	return ghcli.CloneRepo(repo.NameWithOwner, destPath)
+}
+
+// GitEnv is nil: gh configures itself as git's credential helper.
+func (g *GitHub) GitEnv() []string { return nil }
+
+func (g *GitHub) CreatePR(ctx context.Context, repoPath string, pr NewPR) (string, error) {
+	return ghcli.CreatePRWithContext(ctx, repoPath, pr.Title, pr.Body, pr.Base, pr.Head, pr.Draft)
+}
+
+func (g *GitHub) PRState(ctx context.Context, repoPath, prURL string) (string, error) {
+	return ghcli.PRState(ctx, repoPath, prURL)
+}
+
+func (g *GitHub) PRStatus(ctx context.Context, repoPath, prURL string) (PRStatus, error) {
+	return ghcli.GetPRStatus(ctx, repoPath, prURL)
+}
+
+func (g *GitHub) PRFeedback(ctx context.Context, repoPath, prURL string) (PRFeedback, error) {
+	return ghcli.FetchPRFeedback(ctx, repoPath, prURL)
+}
+
+func (g *GitHub) MarkPRReady(ctx context.Context, repoPath, prURL string) error {
+	return ghcli.MarkPRReady(ctx, repoPath, prURL)
+}
+
+func (g *GitHub) EditPR(ctx context.Context, repoPath, prURL string, edit PREdit) error {
+	return ghcli.EditPR(ctx, repoPath, prURL, edit)
+}
+
+func (g *GitHub) MergePR(ctx context.Context, repoPath, prURL, method string) error {
+	return ghcli.MergePR(ctx, repoPath, prURL, method)
+}
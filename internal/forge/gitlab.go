@@ -0,0 +1,365 @@
+package forge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/ghcli"
+)
+
+// ErrGlabNotFound is returned when the glab CLI is not installed.
+var ErrGlabNotFound = errors.New("glab CLI not found")
+
+// glabPathFn locates glab; tests replace it.
+var glabPathFn = func() string {
+	path, _ := exec.LookPath("glab")
+	return path
+}
+
+// GitLab talks to gitlab.com or a self-hosted GitLab through the glab CLI.
+// Merge requests are handled through `glab api` so every call targets the
+// forge's host regardless of the checkout's remotes.
+type GitLab struct {
+	host string
+}
+
+func (g *GitLab) Kind() string { return KindGitLab }
+func (g *GitLab) Host() string { return g.host }
+
+func (g *GitLab) Check(ctx context.Context) error {
+	glab := glabPathFn()
+	if glab == "" {
+		return ErrGlabNotFound
+	}
+	if output, err := procRun(ctx, "", glab, "auth", "status", "--hostname", g.host); err != nil {
+		return fmt.Errorf("glab CLI not authenticated for %s; run `glab auth login --hostname %s`%s", g.host, g.host, outputSuffix(output))
+	}
+	return nil
+}
+
+// api runs `glab api` against the forge host. fields are sent as string
+// parameters of the request body.
+func (g *GitLab) api(ctx context.Context, method, endpoint string, fields map[string]string, paginate bool) ([]byte, error) {
+	glab := glabPathFn()
+	if glab == "" {
+		return nil, ErrGlabNotFound
+	}
+	args := []string{"api", "--hostname", g.host, "--method", method}
+	if paginate {
+		args = append(args, "--paginate")
+	}
+	args = append(args, endpoint)
+	for _, key := range sortedKeys(fields) {
+		args = append(args, "--raw-field", key+"="+fields[key])
+	}
+	output, err := procRun(ctx, "", glab, args...)
+	if err != nil {
+		return nil, fmt.Errorf("glab api %s %s failed: %w%s", method, endpoint, err, outputSuffix(output))
+	}
+	return output, nil
+}
+
+func (g *GitLab) DiscoverRepos(ctx context.Context) ([]Repo, error) {
+	output, err := g.api(ctx, "GET", "projects?membership=true&archived=false&per_page=100", nil, true)
+	if err != nil {
+		return nil, err
+	}
+	type project struct {
+		ID                int64  `json:"id"`
+		Path              string `json:"path"`
+		PathWithNamespace string `json:"path_with_namespace"`
+		WebURL            string `json:"web_url"`
+		Visibility        string `json:"visibility"`
+		DefaultBranch     string `json:"default_branch"`
+		Namespace         struct {
+			FullPath string `json:"full_path"`
+		} `json:"namespace"`
+	}
+	// --paginate prints one JSON array per page.
+	var repos []Repo
+	dec := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var page []project
+		if err := dec.Decode(&page); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("decode glab api projects: %w", err)
+		}
+		for _, p := range page {
+			// Fog names repos owner/repo; projects in nested groups do not fit.
+			if strings.Count(p.PathWithNamespace, "/") != 1 {
+				continue
+			}
+			repo := Repo{
+				ID:            strconv.FormatInt(p.ID, 10),
+				Name:          p.Path,
+				NameWithOwner: p.PathWithNamespace,
+				URL:           p.WebURL,
+				IsPrivate:     p.Visibility != "public",
+			}
+			repo.DefaultBranchRef.Name = p.DefaultBranch
+			repo.Owner.Login = p.Namespace.FullPath
+			repos = append(repos, repo)
+		}
+	}
+	return repos, nil
+}
+
+func (g *GitLab) CloneRepo(ctx context.Context, repo Repo, destPath string) error {
+	return cloneBare(ctx, repo.URL, destPath, g.GitEnv())
+}
+
+// GitEnv makes glab git's credential helper for the forge host.
+func (g *GitLab) GitEnv() []string {
+	return gitConfigEnv([2]string{"credential.https://" + g.host + ".helper", "!glab auth git-credential"})
+}
+
+func (g *GitLab) CreatePR(ctx context.Context, repoPath string, pr NewPR) (string, error) {
+	path, err := remoteRepoPath(ctx, repoPath)
+	if err != nil {
+		return "", err
+	}
+	title := pr.Title
+	if pr.Draft && !isDraftTitle(title) {
+		title = "Draft: " + title
+	}
+	output, err := g.api(ctx, "POST", "projects/"+url.PathEscape(path)+"/merge_requests", map[string]string{
+		"source_branch":        pr.Head,
+		"target_branch":        pr.Base,
+		"title":                title,
+		"description":          pr.Body,
+		"remove_source_branch": "false",
+	}, false)
+	if err != nil {
+		return "", err
+	}
+	var mr struct {
+		WebURL string `json:"web_url"`
+	}
+	if err := json.Unmarshal(output, &mr); err != nil {
+		return "", fmt.Errorf("parse merge request: %w", err)
+	}
+	return mr.WebURL, nil
+}
+
+// gitlabMR is the part of a merge request Fog reads.
+type gitlabMR struct {
+	IID                 int    `json:"iid"`
+	Title               string `json:"title"`
+	State               string `json:"state"`
+	Draft               bool   `json:"draft"`
+	WorkInProgress      bool   `json:"work_in_progress"`
+	MergeStatus         string `json:"merge_status"`
+	DetailedMergeStatus string `json:"detailed_merge_status"`
+	HasConflicts        bool   `json:"has_conflicts"`
+	HeadPipeline        *struct {
+		Status string `json:"status"`
+	} `json:"head_pipeline"`
+	Reviewers []struct {
+		ID int64 `json:"id"`
+	} `json:"reviewers"`
+}
+
+// mrEndpoint parses https://<host>/<project>/-/merge_requests/<iid>.
+func (g *GitLab) mrEndpoint(prURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(prURL))
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid merge request URL %q", prURL)
+	}
+	project, iid, ok := strings.Cut(strings.Trim(u.Path, "/"), "/-/merge_requests/")
+	if !ok || project == "" {
+		return "", fmt.Errorf("invalid merge request URL %q", prURL)
+	}
+	n, err := strconv.Atoi(strings.Trim(iid, "/"))
+	if err != nil || n <= 0 {
+		return "", fmt.Errorf("invalid merge request URL %q", prURL)
+	}
+	return fmt.Sprintf("projects/%s/merge_requests/%d", url.PathEscape(project), n), nil
+}
+
+func (g *GitLab) getMR(ctx context.Context, prURL string) (string, gitlabMR, error) {
+	endpoint, err := g.mrEndpoint(prURL)
+	if err != nil {
+		return "", gitlabMR{}, err
+	}
+	output, err := g.api(ctx, "GET", endpoint, nil, false)
+	if err != nil {
+		return "", gitlabMR{}, err
+	}
+	var mr gitlabMR
+	if err := json.Unmarshal(output, &mr); err != nil {
+		return "", gitlabMR{}, fmt.Errorf("parse merge request: %w", err)
+	}
+	return endpoint, mr, nil
+}
+
+func gitlabState(state string) string {
+	switch state {
+	case "opened":
+		return "OPEN"
+	case "merged":
+		return "MERGED"
+	case "closed", "locked":
+		return "CLOSED"
+	}
+	return strings.ToUpper(state)
+}
+
+func (g *GitLab) PRState(ctx context.Context, repoPath, prURL string) (string, error) {
+	_, mr, err := g.getMR(ctx, prURL)
+	if err != nil {
+		return "", err
+	}
+	return gitlabState(mr.State), nil
+}
+
+func (g *GitLab) PRStatus(ctx context.Context, repoPath, prURL string) (PRStatus, error) {
+	_, mr, err := g.getMR(ctx, prURL)
+	if err != nil {
+		return PRStatus{}, err
+	}
+	status := PRStatus{
+		State:   gitlabState(mr.State),
+		IsDraft: mr.Draft || mr.WorkInProgress,
+	}
+	switch {
+	case mr.HasConflicts || mr.DetailedMergeStatus == "conflict" || mr.MergeStatus == "cannot_be_merged":
+		status.Mergeable = "CONFLICTING"
+	case mr.MergeStatus == "can_be_merged":
+		status.Mergeable = "MERGEABLE"
+	default:
+		status.Mergeable = "UNKNOWN"
+	}
+	// GitLab reports one pipeline for the head commit rather than
+	// individual checks.
+	if mr.HeadPipeline != nil {
+		switch mr.HeadPipeline.Status {
+		case "success", "skipped", "manual":
+			status.Checks.Passed = 1
+		case "failed", "canceled":
+			status.Checks.Failed = 1
+		default:
+			status.Checks.Pending = 1
+		}
+	}
+	status.Checks = checksFromCounts(status.Checks)
+	return status, nil
+}
+
+func (g *GitLab) PRFeedback(ctx context.Context, repoPath, prURL string) (PRFeedback, error) {
+	return PRFeedback{}, fmt.Errorf("merge request feedback sync: %w", ErrUnsupported)
+}
+
+func (g *GitLab) MarkPRReady(ctx context.Context, repoPath, prURL string) error {
+	endpoint, mr, err := g.getMR(ctx, prURL)
+	if err != nil {
+		return err
+	}
+	if !isDraftTitle(mr.Title) {
+		return nil
+	}
+	_, err = g.api(ctx, "PUT", endpoint, map[string]string{"title": stripDraftTitle(mr.Title)}, false)
+	return err
+}
+
+// EditPR updates the description, adds reviewers (GitLab users) and adds
+// labels. GitLab replaces the reviewer list on update, so existing
+// reviewers are kept.
+func (g *GitLab) EditPR(ctx context.Context, repoPath, prURL string, edit PREdit) error {
+	endpoint, err := g.mrEndpoint(prURL)
+	if err != nil {
+		return err
+	}
+	fields := map[string]string{}
+	if body := strings.TrimSpace(edit.Body); body != "" {
+		fields["description"] = body
+	}
+	if len(edit.Labels) > 0 {
+		fields["add_labels"] = strings.Join(edit.Labels, ",")
+	}
+	query := url.Values{}
+	if len(edit.Reviewers) > 0 {
+		_, mr, err := g.getMR(ctx, prURL)
+		if err != nil {
+			return err
+		}
+		for _, reviewer := range mr.Reviewers {
+			query.Add("reviewer_ids[]", strconv.FormatInt(reviewer.ID, 10))
+		}
+		for _, username := range edit.Reviewers {
+			id, err := g.userID(ctx, username)
+			if err != nil {
+				return err
+			}
+			query.Add("reviewer_ids[]", strconv.FormatInt(id, 10))
+		}
+	}
+	if len(fields) == 0 && len(query) == 0 {
+		return nil
+	}
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	_, err = g.api(ctx, "PUT", endpoint, fields, false)
+	return err
+}
+
+func (g *GitLab) userID(ctx context.Context, username string) (int64, error) {
+	if strings.Contains(username, "/") {
+		return 0, fmt.Errorf("reviewer %q: GitLab reviewers must be users", username)
+	}
+	output, err := g.api(ctx, "GET", "users?username="+url.QueryEscape(username), nil, false)
+	if err != nil {
+		return 0, err
+	}
+	var users []struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.Unmarshal(output, &users); err != nil {
+		return 0, fmt.Errorf("parse users: %w", err)
+	}
+	if len(users) == 0 {
+		return 0, fmt.Errorf("GitLab user %q not found", username)
+	}
+	return users[0].ID, nil
+}
+
+// MergePR merges with squash or a merge commit. Whether GitLab rebases is a
+// project setting, so the rebase method is not offered.
+func (g *GitLab) MergePR(ctx context.Context, repoPath, prURL, method string) error {
+	endpoint, err := g.mrEndpoint(prURL)
+	if err != nil {
+		return err
+	}
+	fields := map[string]string{}
+	switch method {
+	case ghcli.MergeMethodSquash:
+		fields["squash"] = "true"
+	case ghcli.MergeMethodMerge:
+	case ghcli.MergeMethodRebase:
+		return fmt.Errorf("rebase merge on GitLab follows the project's merge method; use squash or merge: %w", ErrUnsupported)
+	default:
+		return fmt.Errorf("invalid merge method %q (use squash, rebase or merge)", method)
+	}
+	_, err = g.api(ctx, "PUT", endpoint+"/merge", fields, false)
+	return err
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
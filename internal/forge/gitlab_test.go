@@ -0,0 +1,135 @@
+package forge
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/ghcli"
+)
+
+// stubGlab replaces glab and git with canned responses keyed by the
+// command line (args joined by spaces) and records every call.
+func stubGlab(t *testing.T, responses map[string]string) *[][]string {
+	t.Helper()
+	origRun, origPath := procRun, glabPathFn
+	t.Cleanup(func() { procRun, glabPathFn = origRun, origPath })
+
+	glabPathFn = func() string { return "glab" }
+	var calls [][]string
+	procRun = func(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+		calls = append(calls, append([]string{name}, args...))
+		key := name + " " + strings.Join(args, " ")
+		for prefix, response := range responses {
+			if strings.HasPrefix(key, prefix) {
+				return []byte(response), nil
+			}
+		}
+		return nil, nil
+	}
+	return &calls
+}
+
+func TestGitLabDiscoverReposSkipsNestedGroups(t *testing.T) {
+	stubGlab(t, map[string]string{
+		"glab api --hostname gitlab.acme.dev --method GET --paginate projects": `[
+			{"id": 7, "path": "api", "path_with_namespace": "acme/api", "web_url": "https://gitlab.acme.dev/acme/api", "visibility": "private", "default_branch": "main", "namespace": {"full_path": "acme"}}
+		][
+			{"id": 8, "path": "web", "path_with_namespace": "acme/frontend/web", "web_url": "https://gitlab.acme.dev/acme/frontend/web", "visibility": "public", "default_branch": "main", "namespace": {"full_path": "acme/frontend"}}
+		]`,
+	})
+
+	repos, err := (&GitLab{host: "gitlab.acme.dev"}).DiscoverRepos(context.Background())
+	if err != nil {
+		t.Fatalf("discover failed: %v", err)
+	}
+	if len(repos) != 1 {
+		t.Fatalf("expected 1 repo, got %+v", repos)
+	}
+	repo := repos[0]
+	if repo.NameWithOwner != "acme/api" || repo.URL != "https://gitlab.acme.dev/acme/api" || !repo.IsPrivate ||
+		repo.DefaultBranchRef.Name != "main" || repo.Owner.Login != "acme" {
+		t.Fatalf("unexpected repo: %+v", repo)
+	}
+}
+
+func TestGitLabCreatePROpensDraftMergeRequest(t *testing.T) {
+	calls := stubGlab(t, map[string]string{
+		"git remote get-url origin": "git@gitlab.acme.dev:acme/api.git\n",
+		"glab api":                  `{"iid": 4, "web_url": "https://gitlab.acme.dev/acme/api/-/merge_requests/4"}`,
+	})
+
+	url, err := (&GitLab{host: "gitlab.acme.dev"}).CreatePR(context.Background(), "/wt", NewPR{
+		Title: "Add OTP", Body: "body", Base: "main", Head: "fog/otp", Draft: true,
+	})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if url != "https://gitlab.acme.dev/acme/api/-/merge_requests/4" {
+		t.Fatalf("unexpected url %q", url)
+	}
+	want := []string{"glab", "api", "--hostname", "gitlab.acme.dev", "--method", "POST", "projects/acme%2Fapi/merge_requests",
+		"--raw-field", "description=body",
+		"--raw-field", "remove_source_branch=false",
+		"--raw-field", "source_branch=fog/otp",
+		"--raw-field", "target_branch=main",
+		"--raw-field", "title=Draft: Add OTP",
+	}
+	if got := (*calls)[1]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected glab call:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestGitLabPRStatusAndMerge(t *testing.T) {
+	const mrURL = "https://gitlab.acme.dev/acme/api/-/merge_requests/4"
+	calls := stubGlab(t, map[string]string{
+		"glab api --hostname gitlab.acme.dev --method GET projects/acme%2Fapi/merge_requests/4": `{
+			"iid": 4, "title": "Draft: Add OTP", "state": "opened", "draft": true,
+			"merge_status": "can_be_merged", "head_pipeline": {"status": "failed"}, "reviewers": [{"id": 3}]
+		}`,
+		"glab api --hostname gitlab.acme.dev --method GET users?username=alice": `[{"id": 11}]`,
+	})
+	gl := &GitLab{host: "gitlab.acme.dev"}
+	ctx := context.Background()
+
+	status, err := gl.PRStatus(ctx, "/wt", mrURL)
+	if err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	want := PRStatus{State: "OPEN", IsDraft: true, Mergeable: "MERGEABLE", Checks: ghcli.CheckSummary{State: ghcli.ChecksFailing, Failed: 1}}
+	if status != want {
+		t.Fatalf("unexpected status: got %+v want %+v", status, want)
+	}
+
+	*calls = nil
+	if err := gl.MarkPRReady(ctx, "/wt", mrURL); err != nil {
+		t.Fatalf("ready failed: %v", err)
+	}
+	if got := (*calls)[len(*calls)-1]; got[5] != "PUT" || got[len(got)-1] != "title=Add OTP" {
+		t.Fatalf("unexpected ready call: %v", got)
+	}
+
+	*calls = nil
+	if err := gl.EditPR(ctx, "/wt", mrURL, PREdit{Reviewers: []string{"alice"}, Labels: []string{"fog"}}); err != nil {
+		t.Fatalf("edit failed: %v", err)
+	}
+	last := (*calls)[len(*calls)-1]
+	if last[6] != "projects/acme%2Fapi/merge_requests/4?reviewer_ids%5B%5D=3&reviewer_ids%5B%5D=11" || last[len(last)-1] != "add_labels=fog" {
+		t.Fatalf("unexpected edit call: %v", last)
+	}
+	if err := gl.EditPR(ctx, "/wt", mrURL, PREdit{Reviewers: []string{"acme/core"}}); err == nil {
+		t.Fatal("expected error for group reviewer")
+	}
+
+	*calls = nil
+	if err := gl.MergePR(ctx, "/wt", mrURL, ghcli.MergeMethodSquash); err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if got := (*calls)[0]; got[6] != "projects/acme%2Fapi/merge_requests/4/merge" || got[len(got)-1] != "squash=true" {
+		t.Fatalf("unexpected merge call: %v", got)
+	}
+	if err := gl.MergePR(ctx, "/wt", mrURL, ghcli.MergeMethodRebase); err == nil {
+		t.Fatal("expected rebase to be unsupported")
+	}
+}
@@ -0,0 +1,269 @@
+package ghcli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PR comment kinds.
+const (
+	CommentKindReview       = "review"         // review summary body
+	CommentKindReviewInline = "review_comment" // comment on a diff line
+	CommentKindConversation = "comment"        // comment on the PR conversation
+)
+
+// PRComment is one piece of human (or bot) feedback on a pull request.
+type PRComment struct {
+	ID     int64  `json:"id"`
+	Kind   string `json:"kind"`
+	Author string `json:"author"`
+	Body   string `json:"body"`
+	// State is the review state (CHANGES_REQUESTED, COMMENTED, ...) for
+	// review summaries.
+	State     string    `json:"state,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Line      int       `json:"line,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CheckAnnotation is one annotation attached to a check run.
+type CheckAnnotation struct {
+	Path      string `json:"path,omitempty"`
+	StartLine int    `json:"start_line,omitempty"`
+	Level     string `json:"level,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Message   string `json:"message"`
+}
+
+// FailedCheck is a check run on the PR head commit that did not pass.
+type FailedCheck struct {
+	ID          int64             `json:"id"`
+	Name        string            `json:"name"`
+	Conclusion  string            `json:"conclusion"`
+	URL         string            `json:"url,omitempty"`
+	Summary     string            `json:"summary,omitempty"`
+	Annotations []CheckAnnotation `json:"annotations,omitempty"`
+}
+
+// PRFeedback is the review feedback and failing checks of a pull request.
+type PRFeedback struct {
+	HeadSHA      string        `json:"head_sha"`
+	Comments     []PRComment   `json:"comments"`
+	FailedChecks []FailedCheck `json:"failed_checks"`
+}
+
+// PRRef identifies a pull request parsed from its URL.
+type PRRef struct {
+	Host   string
+	Owner  string
+	Repo   string
+	Number int
+}
+
+// ParsePRURL parses https://<host>/<owner>/<repo>/pull/<number>.
+func ParsePRURL(prURL string) (PRRef, error) {
+	u, err := url.Parse(strings.TrimSpace(prURL))
+	if err != nil || u.Host == "" {
+		return PRRef{}, fmt.Errorf("invalid pull request URL %q", prURL)
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 4 || parts[2] != "pull" {
+		return PRRef{}, fmt.Errorf("invalid pull request URL %q", prURL)
+	}
+	number, err := strconv.Atoi(parts[3])
+	if err != nil || number <= 0 {
+		return PRRef{}, fmt.Errorf("invalid pull request URL %q", prURL)
+	}
+	return PRRef{Host: u.Host, Owner: parts[0], Repo: parts[1], Number: number}, nil
+}
+
+// failingConclusions are check run conclusions reported as failures.
+var failingConclusions = map[string]bool{
+	"failure":         true,
+	"timed_out":       true,
+	"action_required": true,
+}
+
+// FetchPRFeedback loads review comments, review summaries, conversation
+// comments and failing check runs (with their annotations) for a pull
+// request through gh api.
+func FetchPRFeedback(ctx context.Context, repoPath, prURL string) (PRFeedback, error) {
+	gh := ghPathFn()
+	if gh == "" {
+		return PRFeedback{}, ErrGhNotFound
+	}
+	ref, err := ParsePRURL(prURL)
+	if err != nil {
+		return PRFeedback{}, err
+	}
+	api := func(endpoint, jq string, paginate bool) ([]byte, error) {
+		args := []string{"api", "--hostname", ref.Host, endpoint}
+		if paginate {
+			args = append(args, "--paginate")
+		}
+		args = append(args, "--jq", jq)
+		output, err := procRun(ctx, repoPath, gh, args...)
+		if err != nil {
+			msg := strings.TrimSpace(string(output))
+			if msg != "" {
+				msg = "\n" + msg
+			}
+			return nil, fmt.Errorf("gh api %s failed: %w%s", endpoint, err, msg)
+		}
+		return output, nil
+	}
+	repoAPI := fmt.Sprintf("repos/%s/%s", ref.Owner, ref.Repo)
+	pullAPI := fmt.Sprintf("%s/pulls/%d", repoAPI, ref.Number)
+
+	feedback := PRFeedback{Comments: []PRComment{}, FailedChecks: []FailedCheck{}}
+
+	head, err := api(pullAPI, ".head.sha", false)
+	if err != nil {
+		return PRFeedback{}, err
+	}
+	feedback.HeadSHA = strings.TrimSpace(string(head))
+
+	var inline []struct {
+		ID        int64     `json:"id"`
+		Body      string    `json:"body"`
+		Path      string    `json:"path"`
+		Line      int       `json:"line"`
+		URL       string    `json:"html_url"`
+		CreatedAt time.Time `json:"created_at"`
+		User      struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	}
+	if err := apiStream(api, pullAPI+"/comments", ".[]", &inline); err != nil {
+		return PRFeedback{}, err
+	}
+	for _, c := range inline {
+		feedback.Comments = append(feedback.Comments, PRComment{
+			ID: c.ID, Kind: CommentKindReviewInline, Author: c.User.Login, Body: c.Body,
+			Path: c.Path, Line: c.Line, URL: c.URL, CreatedAt: c.CreatedAt,
+		})
+	}
+
+	var reviews []struct {
+		ID          int64     `json:"id"`
+		Body        string    `json:"body"`
+		State       string    `json:"state"`
+		URL         string    `json:"html_url"`
+		SubmittedAt time.Time `json:"submitted_at"`
+		User        struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	}
+	if err := apiStream(api, pullAPI+"/reviews", ".[]", &reviews); err != nil {
+		return PRFeedback{}, err
+	}
+	for _, rv := range reviews {
+		// Approvals and empty summaries carry nothing to act on; inline
+		// comments of the review are listed separately.
+		if strings.TrimSpace(rv.Body) == "" || rv.State == "APPROVED" || rv.State == "PENDING" {
+			continue
+		}
+		feedback.Comments = append(feedback.Comments, PRComment{
+			ID: rv.ID, Kind: CommentKindReview, Author: rv.User.Login, Body: rv.Body,
+			State: rv.State, URL: rv.URL, CreatedAt: rv.SubmittedAt,
+		})
+	}
+
+	var conversation []struct {
+		ID        int64     `json:"id"`
+		Body      string    `json:"body"`
+		URL       string    `json:"html_url"`
+		CreatedAt time.Time `json:"created_at"`
+		User      struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	}
+	if err := apiStream(api, fmt.Sprintf("%s/issues/%d/comments", repoAPI, ref.Number), ".[]", &conversation); err != nil {
+		return PRFeedback{}, err
+	}
+	for _, c := range conversation {
+		feedback.Comments = append(feedback.Comments, PRComment{
+			ID: c.ID, Kind: CommentKindConversation, Author: c.User.Login, Body: c.Body,
+			URL: c.URL, CreatedAt: c.CreatedAt,
+		})
+	}
+
+	if feedback.HeadSHA == "" {
+		return feedback, nil
+	}
+	var checks []struct {
+		ID         int64  `json:"id"`
+		Name       string `json:"name"`
+		Status     string `json:"status"`
+		Conclusion string `json:"conclusion"`
+		URL        string `json:"html_url"`
+		Output     struct {
+			Title            string `json:"title"`
+			Summary          string `json:"summary"`
+			AnnotationsCount int    `json:"annotations_count"`
+		} `json:"output"`
+	}
+	if err := apiStream(api, fmt.Sprintf("%s/commits/%s/check-runs", repoAPI, feedback.HeadSHA), ".check_runs[]", &checks); err != nil {
+		return PRFeedback{}, err
+	}
+	for _, c := range checks {
+		if c.Status != "completed" || !failingConclusions[c.Conclusion] {
+			continue
+		}
+		failed := FailedCheck{
+			ID:         c.ID,
+			Name:       c.Name,
+			Conclusion: c.Conclusion,
+			URL:        c.URL,
+			Summary:    strings.TrimSpace(c.Output.Title + "\n" + c.Output.Summary),
+		}
+		if c.Output.AnnotationsCount > 0 {
+			var annotations []struct {
+				Path      string `json:"path"`
+				StartLine int    `json:"start_line"`
+				Level     string `json:"annotation_level"`
+				Title     string `json:"title"`
+				Message   string `json:"message"`
+			}
+			if err := apiStream(api, fmt.Sprintf("%s/check-runs/%d/annotations", repoAPI, c.ID), ".[]", &annotations); err != nil {
+				return PRFeedback{}, err
+			}
+			for _, a := range annotations {
+				failed.Annotations = append(failed.Annotations, CheckAnnotation{
+					Path: a.Path, StartLine: a.StartLine, Level: a.Level, Title: a.Title, Message: a.Message,
+				})
+			}
+		}
+		feedback.FailedChecks = append(feedback.FailedChecks, failed)
+	}
+	return feedback, nil
+}
+
+// apiStream runs a paginated gh api call whose jq filter emits one JSON
+// object per line and appends the decoded objects to out.
+func apiStream[T any](api func(endpoint, jq string, paginate bool) ([]byte, error), endpoint, jq string, out *[]T) error {
+	output, err := api(endpoint, jq, true)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var item T
+		if err := dec.Decode(&item); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("decode gh api %s: %w", endpoint, err)
+		}
+		*out = append(*out, item)
+	}
+}
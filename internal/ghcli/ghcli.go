@@ -362,3 +362,182 @@ func CreatePRWithContext(ctx context.Context, repoPath, title, body, base, head
 
 	return strings.TrimSpace(string(output)), nil
 }
+
+// PRState returns the state of a pull request (OPEN, CLOSED or MERGED).
+func PRState(ctx context.Context, repoPath, prURL string) (string, error) {
+	gh := ghPathFn()
+	if gh == "" {
+		return "", ErrGhNotFound
+	}
+
+	output, err := procRun(ctx, repoPath, gh, "pr", "view", prURL, "--json", "state", "--jq", ".state")
+	if err != nil {
+		msg := strings.TrimSpace(string(output))
+		if msg != "" {
+			msg = "\n" + msg
+		}
+		return "", fmt.Errorf("gh pr view %s failed: %w%s", prURL, err, msg)
+	}
+
+	return strings.ToUpper(strings.TrimSpace(string(output))), nil
+}
+
+// PRStatus is a pull request's lifecycle state as reported by gh pr view.
+type PRStatus struct {
+	State          string       `json:"state"`
+	IsDraft        bool         `json:"isDraft"`
+	Mergeable      string       `json:"mergeable"`
+	ReviewDecision string       `json:"reviewDecision"`
+	Checks         CheckSummary `json:"-"`
+}
+
+// Check summary states.
+const (
+	ChecksPassing = "PASSING"
+	ChecksFailing = "FAILING"
+	ChecksPending = "PENDING"
+)
+
+// CheckSummary counts the checks on a pull request's head commit. State is
+// FAILING when any check failed, PENDING when any is still running,
+// PASSING when all passed, and empty when there are no checks.
+type CheckSummary struct {
+	State   string
+	Passed  int
+	Failed  int
+	Pending int
+}
+
+// GetPRStatus returns the state, draft flag, mergeability, review decision
+// and check summary of a pull request.
+func GetPRStatus(ctx context.Context, repoPath, prURL string) (PRStatus, error) {
+	gh := ghPathFn()
+	if gh == "" {
+		return PRStatus{}, ErrGhNotFound
+	}
+
+	output, err := procRun(ctx, repoPath, gh, "pr", "view", prURL, "--json", "state,isDraft,mergeable,reviewDecision,statusCheckRollup")
+	if err != nil {
+		msg := strings.TrimSpace(string(output))
+		if msg != "" {
+			msg = "\n" + msg
+		}
+		return PRStatus{}, fmt.Errorf("gh pr view %s failed: %w%s", prURL, err, msg)
+	}
+
+	var payload struct {
+		PRStatus
+		StatusCheckRollup []struct {
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+			State      string `json:"state"`
+		} `json:"statusCheckRollup"`
+	}
+	if err := json.Unmarshal(output, &payload); err != nil {
+		return PRStatus{}, fmt.Errorf("parse gh pr view output: %w", err)
+	}
+	status := payload.PRStatus
+	status.State = strings.ToUpper(status.State)
+	for _, check := range payload.StatusCheckRollup {
+		// Check runs report status + conclusion; commit statuses report state.
+		result := strings.ToUpper(check.Conclusion)
+		if check.State != "" {
+			result = strings.ToUpper(check.State)
+		} else if !strings.EqualFold(check.Status, "COMPLETED") {
+			result = "PENDING"
+		}
+		switch result {
+		case "SUCCESS", "NEUTRAL", "SKIPPED":
+			status.Checks.Passed++
+		case "PENDING", "EXPECTED", "":
+			status.Checks.Pending++
+		default:
+			status.Checks.Failed++
+		}
+	}
+	switch {
+	case status.Checks.Failed > 0:
+		status.Checks.State = ChecksFailing
+	case status.Checks.Pending > 0:
+		status.Checks.State = ChecksPending
+	case status.Checks.Passed > 0:
+		status.Checks.State = ChecksPassing
+	}
+	return status, nil
+}
+
+// Merge methods accepted by MergePR.
+const (
+	MergeMethodSquash = "squash"
+	MergeMethodRebase = "rebase"
+	MergeMethodMerge  = "merge"
+)
+
+// ValidMergeMethod reports whether method is a merge method MergePR accepts.
+func ValidMergeMethod(method string) bool {
+	switch method {
+	case MergeMethodSquash, MergeMethodRebase, MergeMethodMerge:
+		return true
+	}
+	return false
+}
+
+// PREdit describes changes to an existing pull request. Empty fields are
+// left untouched.
+type PREdit struct {
+	Body      string
+	Reviewers []string
+	Labels    []string
+}
+
+// MarkPRReady marks a draft pull request as ready for review.
+func MarkPRReady(ctx context.Context, repoPath, prURL string) error {
+	return runPR(ctx, repoPath, "ready", prURL)
+}
+
+// EditPR updates the body of a pull request and requests reviewers and
+// adds labels on it.
+func EditPR(ctx context.Context, repoPath, prURL string, edit PREdit) error {
+	args := []string{prURL}
+	if body := strings.TrimSpace(edit.Body); body != "" {
+		args = append(args, "--body", body)
+	}
+	if len(edit.Reviewers) > 0 {
+		args = append(args, "--add-reviewer", strings.Join(edit.Reviewers, ","))
+	}
+	if len(edit.Labels) > 0 {
+		args = append(args, "--add-label", strings.Join(edit.Labels, ","))
+	}
+	if len(args) == 1 {
+		return nil
+	}
+	return runPR(ctx, repoPath, "edit", args...)
+}
+
+// MergePR merges a pull request with the given method (squash, rebase or
+// merge). Branches are left in place: the session worktree still has the
+// head branch checked out.
+func MergePR(ctx context.Context, repoPath, prURL, method string) error {
+	if !ValidMergeMethod(method) {
+		return fmt.Errorf("invalid merge method %q (use squash, rebase or merge)", method)
+	}
+	return runPR(ctx, repoPath, "merge", prURL, "--"+method)
+}
+
+// runPR runs a gh pr subcommand, folding its output into the error.
+func runPR(ctx context.Context, repoPath, subcommand string, args ...string) error {
+	gh := ghPathFn()
+	if gh == "" {
+		return ErrGhNotFound
+	}
+
+	output, err := procRun(ctx, repoPath, gh, append([]string{"pr", subcommand}, args...)...)
+	if err != nil {
+		msg := strings.TrimSpace(string(output))
+		if msg != "" {
+			msg = "\n" + msg
+		}
+		return fmt.Errorf("gh pr %s failed: %w%s", subcommand, err, msg)
+	}
+	return nil
+}
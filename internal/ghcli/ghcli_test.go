@@ -3,6 +3,7 @@ package ghcli
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -386,3 +387,216 @@ func TestHelperProcess(t *testing.T) {
 		os.Exit(2)
 	}
 }
+
+func TestPRStateBuildsArgs(t *testing.T) {
+	origProcRun := procRun
+	origPath := ghPathFn
+	t.Cleanup(func() {
+		procRun = origProcRun
+		ghPathFn = origPath
+	})
+
+	ghPathFn = func() string { return "/test/gh" }
+
+	var gotArgs []string
+	procRun = func(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+		gotArgs = append([]string(nil), args...)
+		return []byte("MERGED\n"), nil
+	}
+
+	got, err := PRState(context.Background(), "/repo", "https://github.com/acme/api/pull/7")
+	if err != nil {
+		t.Fatalf("PRState returned error: %v", err)
+	}
+	if got != "MERGED" {
+		t.Fatalf("unexpected PR state: got %q", got)
+	}
+	wantArgs := []string{"pr", "view", "https://github.com/acme/api/pull/7", "--json", "state", "--jq", ".state"}
+	if !reflect.DeepEqual(gotArgs, wantArgs) {
+		t.Fatalf("unexpected args: got %v want %v", gotArgs, wantArgs)
+	}
+}
+
+func TestParsePRURL(t *testing.T) {
+	ref, err := ParsePRURL("https://github.example.com/acme/api/pull/42/files")
+	if err != nil {
+		t.Fatalf("ParsePRURL returned error: %v", err)
+	}
+	want := PRRef{Host: "github.example.com", Owner: "acme", Repo: "api", Number: 42}
+	if ref != want {
+		t.Fatalf("unexpected ref: got %+v want %+v", ref, want)
+	}
+	for _, bad := range []string{"", "acme/api#42", "https://github.com/acme/api/issues/42", "https://github.com/acme/api/pull/x"} {
+		if _, err := ParsePRURL(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestFetchPRFeedbackCollectsCommentsAndFailedChecks(t *testing.T) {
+	origProcRun := procRun
+	origPath := ghPathFn
+	t.Cleanup(func() {
+		procRun = origProcRun
+		ghPathFn = origPath
+	})
+
+	ghPathFn = func() string { return "/test/gh" }
+
+	responses := map[string]string{
+		"repos/acme/api/pulls/7":          "abc123\n",
+		"repos/acme/api/pulls/7/comments": `{"id":11,"body":"Use a constant here","path":"main.go","line":12,"html_url":"u11","created_at":"2026-10-01T10:00:00Z","user":{"login":"alice"}}` + "\n",
+		"repos/acme/api/pulls/7/reviews": `{"id":21,"body":"Needs tests","state":"CHANGES_REQUESTED","submitted_at":"2026-10-01T10:05:00Z","user":{"login":"alice"}}` + "\n" +
+			`{"id":22,"body":"LGTM","state":"APPROVED","submitted_at":"2026-10-01T10:06:00Z","user":{"login":"bob"}}` + "\n" +
+			`{"id":23,"body":"","state":"COMMENTED","submitted_at":"2026-10-01T10:07:00Z","user":{"login":"bob"}}` + "\n",
+		"repos/acme/api/issues/7/comments": `{"id":31,"body":"Also update the docs","created_at":"2026-10-01T11:00:00Z","user":{"login":"carol"}}` + "\n",
+		"repos/acme/api/commits/abc123/check-runs": `{"id":41,"name":"test","status":"completed","conclusion":"failure","output":{"title":"2 tests failed","summary":"","annotations_count":1}}` + "\n" +
+			`{"id":42,"name":"lint","status":"completed","conclusion":"success","output":{"annotations_count":0}}` + "\n" +
+			`{"id":43,"name":"e2e","status":"in_progress","conclusion":null,"output":{"annotations_count":0}}` + "\n",
+		"repos/acme/api/check-runs/41/annotations": `{"path":"api_test.go","start_line":30,"annotation_level":"failure","message":"expected 200, got 500"}` + "\n",
+	}
+	var calls [][]string
+	procRun = func(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+		calls = append(calls, append([]string(nil), args...))
+		if len(args) < 4 || args[0] != "api" || args[1] != "--hostname" || args[2] != "github.com" {
+			return nil, fmt.Errorf("unexpected args %v", args)
+		}
+		out, ok := responses[args[3]]
+		if !ok {
+			return nil, fmt.Errorf("unexpected endpoint %s", args[3])
+		}
+		return []byte(out), nil
+	}
+
+	feedback, err := FetchPRFeedback(context.Background(), "/repo", "https://github.com/acme/api/pull/7")
+	if err != nil {
+		t.Fatalf("FetchPRFeedback returned error: %v", err)
+	}
+	if feedback.HeadSHA != "abc123" {
+		t.Fatalf("unexpected head sha %q", feedback.HeadSHA)
+	}
+	var ids []int64
+	for _, c := range feedback.Comments {
+		ids = append(ids, c.ID)
+	}
+	if !reflect.DeepEqual(ids, []int64{11, 21, 31}) {
+		t.Fatalf("unexpected comment ids: %v", ids)
+	}
+	if c := feedback.Comments[0]; c.Kind != CommentKindReviewInline || c.Author != "alice" || c.Path != "main.go" || c.Line != 12 {
+		t.Fatalf("unexpected inline comment: %+v", c)
+	}
+	if len(feedback.FailedChecks) != 1 {
+		t.Fatalf("expected 1 failed check, got %+v", feedback.FailedChecks)
+	}
+	check := feedback.FailedChecks[0]
+	if check.Name != "test" || check.Summary != "2 tests failed" || len(check.Annotations) != 1 || check.Annotations[0].StartLine != 30 {
+		t.Fatalf("unexpected failed check: %+v", check)
+	}
+	if got := calls[1]; !reflect.DeepEqual(got[4:], []string{"--paginate", "--jq", ".[]"}) {
+		t.Fatalf("expected paginated jq call, got %v", got)
+	}
+}
+
+func TestGetPRStatusSummarizesChecks(t *testing.T) {
+	origProcRun := procRun
+	origPath := ghPathFn
+	t.Cleanup(func() {
+		procRun = origProcRun
+		ghPathFn = origPath
+	})
+
+	ghPathFn = func() string { return "/test/gh" }
+
+	var gotArgs []string
+	procRun = func(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+		gotArgs = append([]string(nil), args...)
+		return []byte(`{"state":"OPEN","isDraft":true,"mergeable":"MERGEABLE","reviewDecision":"CHANGES_REQUESTED","statusCheckRollup":[
+			{"__typename":"CheckRun","name":"test","status":"COMPLETED","conclusion":"FAILURE"},
+			{"__typename":"CheckRun","name":"lint","status":"COMPLETED","conclusion":"SUCCESS"},
+			{"__typename":"CheckRun","name":"e2e","status":"IN_PROGRESS","conclusion":""},
+			{"__typename":"StatusContext","context":"ci/legacy","state":"SUCCESS"}
+		]}`), nil
+	}
+
+	got, err := GetPRStatus(context.Background(), "/repo", "https://github.com/acme/api/pull/7")
+	if err != nil {
+		t.Fatalf("GetPRStatus returned error: %v", err)
+	}
+	want := PRStatus{
+		State:          "OPEN",
+		IsDraft:        true,
+		Mergeable:      "MERGEABLE",
+		ReviewDecision: "CHANGES_REQUESTED",
+		Checks:         CheckSummary{State: ChecksFailing, Passed: 2, Failed: 1, Pending: 1},
+	}
+	if got != want {
+		t.Fatalf("unexpected status: got %+v want %+v", got, want)
+	}
+	wantArgs := []string{"pr", "view", "https://github.com/acme/api/pull/7", "--json", "state,isDraft,mergeable,reviewDecision,statusCheckRollup"}
+	if !reflect.DeepEqual(gotArgs, wantArgs) {
+		t.Fatalf("unexpected args: got %v want %v", gotArgs, wantArgs)
+	}
+}
+
+func TestPRActionsBuildArgs(t *testing.T) {
+	origProcRun := procRun
+	origPath := ghPathFn
+	t.Cleanup(func() {
+		procRun = origProcRun
+		ghPathFn = origPath
+	})
+
+	ghPathFn = func() string { return "/test/gh" }
+
+	var calls [][]string
+	procRun = func(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+		calls = append(calls, append([]string(nil), args...))
+		return nil, nil
+	}
+
+	const url = "https://github.com/acme/api/pull/7"
+	ctx := context.Background()
+	if err := MarkPRReady(ctx, "/repo", url); err != nil {
+		t.Fatalf("MarkPRReady returned error: %v", err)
+	}
+	if err := EditPR(ctx, "/repo", url, PREdit{Body: "body", Reviewers: []string{"alice", "acme/core"}, Labels: []string{"fog"}}); err != nil {
+		t.Fatalf("EditPR returned error: %v", err)
+	}
+	if err := EditPR(ctx, "/repo", url, PREdit{}); err != nil {
+		t.Fatalf("EditPR with no changes returned error: %v", err)
+	}
+	if err := MergePR(ctx, "/repo", url, MergeMethodSquash); err != nil {
+		t.Fatalf("MergePR returned error: %v", err)
+	}
+	if err := MergePR(ctx, "/repo", url, "fast-forward"); err == nil {
+		t.Fatal("expected invalid merge method error")
+	}
+
+	want := [][]string{
+		{"pr", "ready", url},
+		{"pr", "edit", url, "--body", "body", "--add-reviewer", "alice,acme/core", "--add-label", "fog"},
+		{"pr", "merge", url, "--squash"},
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("unexpected calls: got %v want %v", calls, want)
+	}
+}
+
+func TestMergePRWrapsOutput(t *testing.T) {
+	origProcRun := procRun
+	origPath := ghPathFn
+	t.Cleanup(func() {
+		procRun = origProcRun
+		ghPathFn = origPath
+	})
+
+	ghPathFn = func() string { return "/test/gh" }
+	procRun = func(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+		return []byte("Pull request is not mergeable\n"), errors.New("exit status 1")
+	}
+
+	err := MergePR(context.Background(), "/repo", "https://github.com/acme/api/pull/7", MergeMethodRebase)
+	if err == nil || !strings.Contains(err.Error(), "gh pr merge failed") || !strings.Contains(err.Error(), "not mergeable") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -76,6 +76,17 @@ func (g *Git) BranchExists(branch string) bool {
 	return err == nil
 }
 
+// DeleteBranch deletes a local branch. Without force git refuses to delete
+// a branch that is not fully merged.
+func (g *Git) DeleteBranch(branch string, force bool) error {
+	flag := "-d"
+	if force {
+		flag = "-D"
+	}
+	_, err := g.exec("branch", flag, branch)
+	return err
+}
+
 // ListBranches returns a list of local branches.
 func (g *Git) ListBranches() ([]string, error) {
 	out, err := g.exec("branch", "--list", "--format=%(refname:short)")
@@ -32,6 +32,40 @@ func TestBranchExistsAndAddWorktreeNewBranch(t *testing.T) {
 	}
 }
 
+func TestRemoveWorktreeAndDeleteBranch(t *testing.T) {
+	repo := initGitRepo(t)
+	g := New(repo)
+
+	wtPath := filepath.Join(filepath.Dir(repo), "feature-wt")
+	if err := g.AddWorktreeNewBranch(wtPath, "feature", "main"); err != nil {
+		t.Fatalf("AddWorktreeNewBranch failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(wtPath, "extra.txt"), []byte("x\n"), 0o644); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+	runGit(t, wtPath, "add", "extra.txt")
+	runGit(t, wtPath, "commit", "-m", "extra")
+
+	if err := g.DeleteBranch("feature", false); err == nil {
+		t.Fatal("expected deleting a checked-out branch to fail")
+	}
+	if err := g.RemoveWorktree(wtPath, false); err != nil {
+		t.Fatalf("RemoveWorktree failed: %v", err)
+	}
+	if _, err := os.Stat(wtPath); !os.IsNotExist(err) {
+		t.Fatalf("expected worktree path to be removed: %v", err)
+	}
+	if err := g.DeleteBranch("feature", false); err == nil {
+		t.Fatal("expected deleting an unmerged branch without force to fail")
+	}
+	if err := g.DeleteBranch("feature", true); err != nil {
+		t.Fatalf("DeleteBranch with force failed: %v", err)
+	}
+	if g.BranchExists("feature") {
+		t.Fatal("expected feature branch to be deleted")
+	}
+}
+
 func initGitRepo(t *testing.T) string {
 	t.Helper()
 
@@ -0,0 +1,34 @@
+package proc
+
+import (
+	"context"
+	"os"
+)
+
+type envKey struct{}
+
+// WithEnv returns a context whose Run and RunStreaming calls add env
+// ("KEY=value" entries) to the inherited process environment. Later entries
+// override earlier ones and the parent environment.
+func WithEnv(ctx context.Context, env []string) context.Context {
+	if len(env) == 0 {
+		return ctx
+	}
+	merged := append(append([]string(nil), contextEnv(ctx)...), env...)
+	return context.WithValue(ctx, envKey{}, merged)
+}
+
+func contextEnv(ctx context.Context) []string {
+	env, _ := ctx.Value(envKey{}).([]string)
+	return env
+}
+
+// commandEnv returns the environment for a command started with ctx, or nil
+// to inherit the parent environment unchanged.
+func commandEnv(ctx context.Context) []string {
+	env := contextEnv(ctx)
+	if len(env) == 0 {
+		return nil
+	}
+	return append(os.Environ(), env...)
+}
@@ -20,8 +20,14 @@ func Run(ctx context.Context, dir, name string, args ...string) ([]byte, error)
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	name, args, err := sandboxCommand(ctx, dir, name, args)
+	if err != nil {
+		return nil, err
+	}
+
 	cmd := exec.CommandContext(ctx, name, args...)
 	cmd.Dir = dir
+	cmd.Env = commandEnv(ctx)
 	out, err := cmd.CombinedOutput()
 	if ctx.Err() != nil {
 		return out, fmt.Errorf("%w: %v", ErrCanceled, ctx.Err())
@@ -38,8 +44,14 @@ func RunStreaming(ctx context.Context, dir, name string, onChunk func([]byte), a
 		return nil, fmt.Errorf("%w: %v", ErrCanceled, err)
 	}
 
+	name, args, err := sandboxCommand(ctx, dir, name, args)
+	if err != nil {
+		return nil, err
+	}
+
 	cmd := exec.CommandContext(ctx, name, args...)
 	cmd.Dir = dir
+	cmd.Env = commandEnv(ctx)
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -76,8 +88,9 @@ func RunStreaming(ctx context.Context, dir, name string, onChunk func([]byte), a
 	go streamPipeNonUnix(stdout, appendChunk, &wg, readerErrCh)
 	go streamPipeNonUnix(stderr, appendChunk, &wg, readerErrCh)
 
-	waitErr := cmd.Wait()
+	// Wait closes the pipes, so the readers must drain them first.
 	wg.Wait()
+	waitErr := cmd.Wait()
 	close(readerErrCh)
 
 	var readerErr error
@@ -0,0 +1,74 @@
+package proc
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunStreamingKeepsOutputOfFastExitingCommand(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not installed")
+	}
+
+	for i := 0; i < 20; i++ {
+		var mu sync.Mutex
+		var streamed strings.Builder
+		out, err := RunStreaming(context.Background(), t.TempDir(), "sh", func(chunk []byte) {
+			mu.Lock()
+			streamed.Write(chunk)
+			mu.Unlock()
+		}, "-c", "echo out; echo err >&2")
+		if err != nil {
+			t.Fatalf("run %d failed: %v", i, err)
+		}
+		for _, want := range []string{"out\n", "err\n"} {
+			if !strings.Contains(string(out), want) {
+				t.Fatalf("run %d: output %q missing %q", i, out, want)
+			}
+		}
+		mu.Lock()
+		got := streamed.String()
+		mu.Unlock()
+		if got != string(out) {
+			t.Fatalf("run %d: streamed %q, returned %q", i, got, out)
+		}
+	}
+}
+
+func TestRunStreamingReportsCommandFailure(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not installed")
+	}
+
+	out, err := RunStreaming(context.Background(), t.TempDir(), "sh", nil, "-c", "echo boom; exit 3")
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Fatalf("expected exit status 3, got %v", err)
+	}
+	if string(out) != "boom\n" {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func TestRunStreamingStopsOnCancel(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not installed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := RunStreaming(ctx, t.TempDir(), "sh", nil, "-c", "sleep 30")
+	if !errors.Is(err, ErrCanceled) {
+		t.Fatalf("expected ErrCanceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("cancel took %s", elapsed)
+	}
+}
@@ -26,8 +26,14 @@ func Run(ctx context.Context, dir, name string, args ...string) ([]byte, error)
 		return nil, fmt.Errorf("%w: %v", ErrCanceled, err)
 	}
 
+	name, args, err := sandboxCommand(ctx, dir, name, args)
+	if err != nil {
+		return nil, err
+	}
+
 	cmd := exec.Command(name, args...)
 	cmd.Dir = dir
+	cmd.Env = commandEnv(ctx)
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 
 	var out bytes.Buffer
@@ -67,8 +73,14 @@ func RunStreaming(ctx context.Context, dir, name string, onChunk func([]byte), a
 		return nil, fmt.Errorf("%w: %v", ErrCanceled, err)
 	}
 
+	name, args, err := sandboxCommand(ctx, dir, name, args)
+	if err != nil {
+		return nil, err
+	}
+
 	cmd := exec.Command(name, args...)
 	cmd.Dir = dir
+	cmd.Env = commandEnv(ctx)
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 
 	stdout, err := cmd.StdoutPipe()
@@ -106,8 +118,11 @@ func RunStreaming(ctx context.Context, dir, name string, onChunk func([]byte), a
 	go streamPipe(stdout, appendChunk, &wg, readerErrCh)
 	go streamPipe(stderr, appendChunk, &wg, readerErrCh)
 
+	// Wait closes the pipes, so it must not run until both readers have
+	// drained them; otherwise trailing output is lost.
 	done := make(chan error, 1)
 	go func() {
+		wg.Wait()
 		done <- cmd.Wait()
 	}()
 
@@ -125,7 +140,6 @@ func RunStreaming(ctx context.Context, dir, name string, onChunk func([]byte), a
 		waitErr = fmt.Errorf("%w: %v", ErrCanceled, ctx.Err())
 	}
 
-	wg.Wait()
 	close(readerErrCh)
 
 	var readerErr error
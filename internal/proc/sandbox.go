@@ -0,0 +1,128 @@
+package proc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Sandbox backends.
+const (
+	SandboxOff   = "off"
+	SandboxBwrap = "bwrap"
+)
+
+// ErrSandboxUnavailable is returned when a sandbox is requested but its
+// backend cannot run on this machine. Commands never fall back to running
+// unconfined.
+var ErrSandboxUnavailable = errors.New("sandbox unavailable")
+
+// Sandbox confines a command: the filesystem is read-only apart from
+// WritableDirs and a private /tmp, and the network is unshared when
+// NoNetwork is set.
+type Sandbox struct {
+	Backend      string
+	WritableDirs []string
+	NoNetwork    bool
+}
+
+type sandboxKey struct{}
+
+// WithSandbox returns a context whose Run and RunStreaming calls execute
+// inside sb. A disabled sandbox leaves ctx unchanged.
+func WithSandbox(ctx context.Context, sb Sandbox) context.Context {
+	if !sb.Enabled() {
+		return ctx
+	}
+	return context.WithValue(ctx, sandboxKey{}, sb)
+}
+
+// Enabled reports whether sb confines commands at all.
+func (sb Sandbox) Enabled() bool {
+	backend := strings.TrimSpace(sb.Backend)
+	return backend != "" && backend != SandboxOff
+}
+
+// String describes the sandbox for run records, e.g. "bwrap (no network)".
+func (sb Sandbox) String() string {
+	if !sb.Enabled() {
+		return SandboxOff
+	}
+	if sb.NoNetwork {
+		return sb.Backend + " (no network)"
+	}
+	return sb.Backend
+}
+
+// CheckSandbox reports whether backend can be used on this machine.
+func CheckSandbox(backend string) error {
+	switch strings.TrimSpace(backend) {
+	case "", SandboxOff:
+		return nil
+	case SandboxBwrap:
+		if runtime.GOOS != "linux" {
+			return fmt.Errorf("%w: bwrap requires linux", ErrSandboxUnavailable)
+		}
+		if _, err := exec.LookPath("bwrap"); err != nil {
+			return fmt.Errorf("%w: bwrap not found in PATH", ErrSandboxUnavailable)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown sandbox backend %q", backend)
+	}
+}
+
+// sandboxCommand rewrites name/args to run inside the context's sandbox,
+// if any.
+func sandboxCommand(ctx context.Context, dir, name string, args []string) (string, []string, error) {
+	sb, ok := ctx.Value(sandboxKey{}).(Sandbox)
+	if !ok || !sb.Enabled() {
+		return name, args, nil
+	}
+	if err := CheckSandbox(sb.Backend); err != nil {
+		return "", nil, err
+	}
+	// Resolve the binary outside the sandbox so PATH lookups behave the same.
+	if resolved, err := exec.LookPath(name); err == nil {
+		name = resolved
+	}
+	bwrap, err := exec.LookPath("bwrap")
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrSandboxUnavailable, err)
+	}
+	return bwrap, bwrapArgs(sb, dir, name, args), nil
+}
+
+func bwrapArgs(sb Sandbox, dir, name string, args []string) []string {
+	out := []string{
+		"--ro-bind", "/", "/",
+		"--dev", "/dev",
+		"--proc", "/proc",
+		"--tmpfs", "/tmp",
+	}
+	// Binds come after the /tmp tmpfs so worktrees under /tmp stay visible.
+	for _, writable := range sb.WritableDirs {
+		writable = filepath.Clean(strings.TrimSpace(writable))
+		if writable == "." || writable == "/" {
+			continue
+		}
+		if _, err := os.Stat(writable); err != nil {
+			continue
+		}
+		out = append(out, "--bind", writable, writable)
+	}
+	if sb.NoNetwork {
+		out = append(out, "--unshare-net")
+	}
+	out = append(out, "--die-with-parent")
+	if strings.TrimSpace(dir) != "" {
+		out = append(out, "--chdir", dir)
+	}
+	out = append(out, "--", name)
+	return append(out, args...)
+}
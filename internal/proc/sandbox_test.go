@@ -0,0 +1,98 @@
+package proc
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestBwrapArgs(t *testing.T) {
+	worktree := t.TempDir()
+	sb := Sandbox{
+		Backend:      SandboxBwrap,
+		WritableDirs: []string{worktree, filepath.Join(worktree, "missing"), "/"},
+		NoNetwork:    true,
+	}
+
+	got := bwrapArgs(sb, worktree, "/usr/bin/sh", []string{"-c", "make test"})
+	want := []string{
+		"--ro-bind", "/", "/",
+		"--dev", "/dev",
+		"--proc", "/proc",
+		"--tmpfs", "/tmp",
+		"--bind", worktree, worktree,
+		"--unshare-net",
+		"--die-with-parent",
+		"--chdir", worktree,
+		"--", "/usr/bin/sh", "-c", "make test",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected bwrap args:\ngot  %v\nwant %v", got, want)
+	}
+}
+
+func TestSandboxDisabledLeavesCommandUntouched(t *testing.T) {
+	ctx := WithSandbox(context.Background(), Sandbox{Backend: SandboxOff})
+	name, args, err := sandboxCommand(ctx, "/tmp", "git", []string{"status"})
+	if err != nil {
+		t.Fatalf("sandboxCommand returned error: %v", err)
+	}
+	if name != "git" || !reflect.DeepEqual(args, []string{"status"}) {
+		t.Fatalf("unexpected command: %s %v", name, args)
+	}
+	if got := (Sandbox{}).String(); got != SandboxOff {
+		t.Fatalf("unexpected description: %q", got)
+	}
+	if got := (Sandbox{Backend: SandboxBwrap, NoNetwork: true}).String(); got != "bwrap (no network)" {
+		t.Fatalf("unexpected description: %q", got)
+	}
+}
+
+func TestCheckSandboxRejectsUnknownBackend(t *testing.T) {
+	if err := CheckSandbox("docker-ish"); err == nil {
+		t.Fatal("expected unknown backend to fail")
+	}
+	if err := CheckSandbox(""); err != nil {
+		t.Fatalf("empty backend should be allowed: %v", err)
+	}
+}
+
+func TestRunFailsClosedWhenSandboxUnavailable(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	ctx := WithSandbox(context.Background(), Sandbox{Backend: SandboxBwrap})
+	_, err := Run(ctx, t.TempDir(), "true")
+	if !errors.Is(err, ErrSandboxUnavailable) {
+		t.Fatalf("expected ErrSandboxUnavailable, got %v", err)
+	}
+}
+
+func TestRunInBwrapSandboxLimitsWrites(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("bwrap sandbox requires linux")
+	}
+	if _, err := exec.LookPath("bwrap"); err != nil {
+		t.Skip("bwrap not installed")
+	}
+	worktree := t.TempDir()
+	outside := t.TempDir()
+	ctx := WithSandbox(context.Background(), Sandbox{Backend: SandboxBwrap, WritableDirs: []string{worktree}})
+
+	if out, err := Run(ctx, worktree, "sh", "-c", "echo ok > inside.txt"); err != nil {
+		if strings.Contains(string(out), "No permissions") || strings.Contains(string(out), "Operation not permitted") {
+			t.Skipf("bwrap cannot create namespaces here: %s", out)
+		}
+		t.Fatalf("write inside worktree failed: %v\n%s", err, out)
+	}
+	if _, err := os.Stat(filepath.Join(worktree, "inside.txt")); err != nil {
+		t.Fatalf("expected file written inside the worktree: %v", err)
+	}
+	if _, err := Run(ctx, worktree, "sh", "-c", "echo no > "+filepath.Join(outside, "outside.txt")); err == nil {
+		t.Fatal("expected write outside the sandbox to fail")
+	}
+}
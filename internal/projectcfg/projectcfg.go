@@ -0,0 +1,169 @@
+// Package projectcfg loads the project file a repository commits to
+// configure Fog runs (.fog.yaml).
+package projectcfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// FileNames lists the project files Fog looks for, in order.
+var FileNames = []string{".fog.yaml", ".fog.yml", ".fog.json"}
+
+// Config is a repository's project configuration. Every field is optional;
+// request options override it and it overrides global settings.
+type Config struct {
+	// Setup and Validate are shell commands run before and after the AI
+	// tool. Declaring Validate turns validation on by default.
+	Setup    string `json:"setup,omitempty"`
+	Validate string `json:"validate,omitempty"`
+	// FixAttempts is how many times a failed Validate is fed back to the
+	// tool before the run fails, for first runs and follow-ups alike.
+	FixAttempts int `json:"fix_attempts,omitempty"`
+	// Tools restricts which AI tools may run in the repo; the first entry
+	// is the default tool.
+	Tools []string `json:"tools,omitempty"`
+	// Model is the default model for the default tool.
+	Model      string `json:"model,omitempty"`
+	BaseBranch string `json:"base_branch,omitempty"`
+	// PromptPreamble is prepended to the prompt of a session's first run.
+	PromptPreamble string       `json:"prompt_preamble,omitempty"`
+	PR             PRConfig     `json:"pr,omitempty"`
+	Commit         CommitConfig `json:"commit,omitempty"`
+
+	// Path is the file the config was loaded from; empty when the repo has
+	// no project file.
+	Path string `json:"-"`
+}
+
+// PRConfig configures pull requests opened by Fog.
+type PRConfig struct {
+	// Template is the pull request body; Fog appends session details.
+	Template string `json:"template,omitempty"`
+	// Reviewers and Labels are requested and added when the PR is marked
+	// ready for review. Reviewers may be users or org/team slugs.
+	Reviewers []string `json:"reviewers,omitempty"`
+	Labels    []string `json:"labels,omitempty"`
+	// MergeMethod is the default method for merging the PR: squash, rebase
+	// or merge.
+	MergeMethod string `json:"merge_method,omitempty"`
+}
+
+// MaxFixAttempts caps fix_attempts.
+const MaxFixAttempts = 10
+
+// Merge methods accepted in pr.merge_method.
+var mergeMethods = []string{"squash", "rebase", "merge"}
+
+// CommitConfig configures commit messages.
+type CommitConfig struct {
+	// Style describes the commit message format, e.g. "conventional" or
+	// "imperative subject, no prefix, reference the ticket".
+	Style string `json:"style,omitempty"`
+}
+
+// Load reads the project file from dir. A missing file yields an empty
+// Config; a malformed one is an error.
+func Load(dir string) (Config, error) {
+	if strings.TrimSpace(dir) == "" {
+		return Config{}, nil
+	}
+	for _, name := range FileNames {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return Config{}, fmt.Errorf("read %s: %w", name, err)
+		}
+		cfg, err := Parse(name, data)
+		if err != nil {
+			return Config{}, err
+		}
+		cfg.Path = path
+		return cfg, nil
+	}
+	return Config{}, nil
+}
+
+// Parse decodes a project file; name selects JSON or YAML by extension.
+// Unknown keys are rejected so typos do not silently disable settings.
+func Parse(name string, data []byte) (Config, error) {
+	jsonData := data
+	if filepath.Ext(name) != ".json" {
+		doc, err := parseYAML(data)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse %s: %w", name, err)
+		}
+		if _, ok := doc.(map[string]any); !ok {
+			return Config{}, fmt.Errorf("parse %s: top level must be a mapping", name)
+		}
+		if jsonData, err = json.Marshal(doc); err != nil {
+			return Config{}, fmt.Errorf("parse %s: %w", name, err)
+		}
+	}
+
+	var cfg Config
+	dec := json.NewDecoder(bytes.NewReader(jsonData))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("parse %s: %w", name, err)
+	}
+	cfg.normalize()
+	if cfg.FixAttempts < 0 || cfg.FixAttempts > MaxFixAttempts {
+		return Config{}, fmt.Errorf("parse %s: fix_attempts must be between 0 and %d", name, MaxFixAttempts)
+	}
+	if cfg.FixAttempts > 0 && cfg.Validate == "" {
+		return Config{}, fmt.Errorf("parse %s: fix_attempts requires validate", name)
+	}
+	if cfg.PR.MergeMethod != "" && !slices.Contains(mergeMethods, cfg.PR.MergeMethod) {
+		return Config{}, fmt.Errorf("parse %s: pr.merge_method must be one of %s", name, strings.Join(mergeMethods, ", "))
+	}
+	return cfg, nil
+}
+
+func (c *Config) normalize() {
+	c.Setup = strings.TrimSpace(c.Setup)
+	c.Validate = strings.TrimSpace(c.Validate)
+	c.Model = strings.TrimSpace(c.Model)
+	c.BaseBranch = strings.TrimSpace(c.BaseBranch)
+	c.PromptPreamble = strings.TrimSpace(c.PromptPreamble)
+	c.PR.Template = strings.TrimSpace(c.PR.Template)
+	c.PR.Reviewers = uniqueTrimmed(c.PR.Reviewers)
+	c.PR.Labels = uniqueTrimmed(c.PR.Labels)
+	c.PR.MergeMethod = strings.ToLower(strings.TrimSpace(c.PR.MergeMethod))
+	c.Commit.Style = strings.TrimSpace(c.Commit.Style)
+	c.Tools = uniqueTrimmed(c.Tools)
+}
+
+// uniqueTrimmed trims values and drops empty and repeated ones in place.
+func uniqueTrimmed(values []string) []string {
+	out := values[:0]
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" && !slices.Contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// DefaultTool returns the first allowed tool, or "" when tools are not
+// restricted.
+func (c Config) DefaultTool() string {
+	if len(c.Tools) == 0 {
+		return ""
+	}
+	return c.Tools[0]
+}
+
+// AllowsTool reports whether tool may run in the repo.
+func (c Config) AllowsTool(tool string) bool {
+	return len(c.Tools) == 0 || slices.Contains(c.Tools, strings.TrimSpace(tool))
+}
@@ -0,0 +1,174 @@
+package projectcfg
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleYAML = `# Fog project configuration
+setup: npm ci
+validate: "npm test -- --ci"   # run in CI mode
+fix_attempts: 2
+tools: [claude, 'gemini']
+model: sonnet
+base_branch: develop
+prompt_preamble: |
+  You are working in the acme API.
+  Never edit generated files under gen/.
+
+pr:
+  template: |
+    ## Summary
+
+    ## Testing
+  reviewers: [alice, " acme/core"]
+  labels:
+    - fog
+    - fog
+  merge_method: Squash
+commit:
+  style: >
+    Conventional Commits,
+    scope required
+`
+
+func TestParseYAML(t *testing.T) {
+	cfg, err := Parse(".fog.yaml", []byte(sampleYAML))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	want := Config{
+		Setup:          "npm ci",
+		Validate:       "npm test -- --ci",
+		FixAttempts:    2,
+		Tools:          []string{"claude", "gemini"},
+		Model:          "sonnet",
+		BaseBranch:     "develop",
+		PromptPreamble: "You are working in the acme API.\nNever edit generated files under gen/.",
+		PR: PRConfig{
+			Template:    "## Summary\n\n## Testing",
+			Reviewers:   []string{"alice", "acme/core"},
+			Labels:      []string{"fog"},
+			MergeMethod: "squash",
+		},
+		Commit: CommitConfig{Style: "Conventional Commits, scope required"},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Fatalf("unexpected config:\n got %#v\nwant %#v", cfg, want)
+	}
+	if cfg.DefaultTool() != "claude" || !cfg.AllowsTool("gemini") || cfg.AllowsTool("aider") {
+		t.Fatalf("unexpected tool policy: %+v", cfg.Tools)
+	}
+}
+
+func TestParseYAMLBlockSequence(t *testing.T) {
+	cfg, err := Parse(".fog.yml", []byte("tools:\n- aider\n-   claude\nsetup: make deps\n"))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Tools, []string{"aider", "claude"}) || cfg.Setup != "make deps" {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestParseRejectsInvalidFiles(t *testing.T) {
+	cases := map[string]string{
+		"unknown key":     "setpu: npm ci\n",
+		"bad indent":      "pr:\n  template: x\n   extra: y\n",
+		"not a mapping":   "- claude\n",
+		"tab indent":      "pr:\n\ttemplate: x\n",
+		"duplicate key":   "setup: a\nsetup: b\n",
+		"wrong type":      "tools: claude\n",
+		"unterminated":    "setup: \"npm ci\n",
+		"missing colon":   "setup npm ci\n",
+		"nested unknown":  "pr:\n  title: x\n",
+		"merge method":    "pr:\n  merge_method: fast-forward\n",
+		"fix attempts":    "validate: make test\nfix_attempts: 11\n",
+		"fix no validate": "fix_attempts: 1\n",
+	}
+	for name, doc := range cases {
+		if _, err := Parse(".fog.yaml", []byte(doc)); err == nil {
+			t.Errorf("%s: expected error for %q", name, doc)
+		}
+	}
+}
+
+func TestParseYAMLScalars(t *testing.T) {
+	doc, err := parseYAML([]byte(strings.Join([]string{
+		`a: "tab\there # not a comment"`,
+		`b: 'it''s'`,
+		`c: true`,
+		`d: 42`,
+		`e: ~`,
+		`f: http://example.com:8080/x#frag`,
+		`j: echo it's done # trailing comment`,
+		`g: |-`,
+		`  keep`,
+		`    indented`,
+		`h: >`,
+		`  one`,
+		`  two`,
+		``,
+		`  three`,
+		`i:`,
+		`  - name: x`,
+		`    value: 1`,
+		`  - plain`,
+	}, "\n")))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	want := map[string]any{
+		"a": "tab\there # not a comment",
+		"b": "it's",
+		"c": true,
+		"d": 42,
+		"e": nil,
+		"f": "http://example.com:8080/x#frag",
+		"g": "keep\n  indented",
+		"h": "one two\nthree\n",
+		"i": []any{map[string]any{"name": "x", "value": 1}, "plain"},
+		"j": "echo it's done",
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Fatalf("unexpected document:\n got %#v\nwant %#v", doc, want)
+	}
+}
+
+func TestParseYAMLFlowMappingsAndAnchors(t *testing.T) {
+	cfg, err := Parse(".fog.yaml", []byte("setup: &cmd make deps\nvalidate: *cmd\npr: {template: x, labels: [fog]}\n"))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if cfg.Setup != "make deps" || cfg.Validate != "make deps" || cfg.PR.Template != "x" || !reflect.DeepEqual(cfg.PR.Labels, []string{"fog"}) {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestLoadPrefersYAMLAndToleratesMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := Load(dir)
+	if err != nil || cfg.Path != "" || cfg.Setup != "" {
+		t.Fatalf("expected empty config without a project file: %+v err=%v", cfg, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ".fog.json"), []byte(`{"setup":"from json"}`), 0o644); err != nil {
+		t.Fatalf("write json failed: %v", err)
+	}
+	cfg, err = Load(dir)
+	if err != nil || cfg.Setup != "from json" {
+		t.Fatalf("expected json config: %+v err=%v", cfg, err)
+	}
+
+	yamlPath := filepath.Join(dir, ".fog.yaml")
+	if err := os.WriteFile(yamlPath, []byte("setup: from yaml\n"), 0o644); err != nil {
+		t.Fatalf("write yaml failed: %v", err)
+	}
+	cfg, err = Load(dir)
+	if err != nil || cfg.Setup != "from yaml" || cfg.Path != yamlPath {
+		t.Fatalf("expected yaml config to win: %+v err=%v", cfg, err)
+	}
+}
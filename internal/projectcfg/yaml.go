@@ -0,0 +1,19 @@
+package projectcfg
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// parseYAML decodes a YAML project file into generic values (mappings with
+// string keys become map[string]any) so Parse can validate it through the
+// same JSON decoding as .fog.json. An empty document is an empty mapping.
+func parseYAML(data []byte) (any, error) {
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return map[string]any{}, nil
+	}
+	return doc, nil
+}
@@ -0,0 +1,73 @@
+package redact
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata golden files")
+
+func TestDefaultPatternsCorpus(t *testing.T) {
+	input, err := os.ReadFile(filepath.Join("testdata", "corpus.txt"))
+	if err != nil {
+		t.Fatalf("read corpus failed: %v", err)
+	}
+	got := New(nil, DefaultPatterns).Redact(string(input))
+
+	goldenPath := filepath.Join("testdata", "corpus.golden")
+	if *update {
+		if err := os.WriteFile(goldenPath, []byte(got), 0o644); err != nil {
+			t.Fatalf("write golden failed: %v", err)
+		}
+	}
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("read golden failed: %v", err)
+	}
+	if got != string(want) {
+		t.Fatalf("redacted corpus mismatch (run with -update to accept):\n%s", got)
+	}
+}
+
+func TestRedactValues(t *testing.T) {
+	r := New([]string{"abc", "s3cr3t-value", "s3cr3t-value-long", "s3cr3t-value"}, nil)
+	got := r.Redact("a=s3cr3t-value-long b=s3cr3t-value c=abc")
+	if got != "a=[REDACTED] b=[REDACTED] c=abc" {
+		t.Fatalf("unexpected redaction: %q", got)
+	}
+
+	var zero *Redactor
+	if zero.Redact("s3cr3t-value") != "s3cr3t-value" {
+		t.Fatal("nil redactor should leave text unchanged")
+	}
+	if got := zero.With([]string{"s3cr3t-value"}).Redact("x s3cr3t-value"); got != "x [REDACTED]" {
+		t.Fatalf("unexpected redaction from nil With: %q", got)
+	}
+}
+
+func TestCustomPatternSecretGroup(t *testing.T) {
+	patterns, err := CompilePatterns([]string{"", `ACME-[0-9]{6}`, `internal_token=(?P<secret>\w+)`})
+	if err != nil {
+		t.Fatalf("compile patterns failed: %v", err)
+	}
+	got := New(nil, patterns).Redact("id ACME-123456 internal_token=abc123 ACME-12")
+	if got != "id [REDACTED] internal_token=[REDACTED] ACME-12" {
+		t.Fatalf("unexpected redaction: %q", got)
+	}
+
+	if _, err := CompilePatterns([]string{"("}); err == nil || !strings.Contains(err.Error(), "invalid redaction pattern") {
+		t.Fatalf("expected invalid pattern error, got %v", err)
+	}
+}
+
+func TestRedactPatternOverlappingMatches(t *testing.T) {
+	pattern := regexp.MustCompile(`tok-(?P<secret>[a-z]+)`)
+	got := New(nil, []*regexp.Regexp{pattern}).Redact("tok-abc tok-def")
+	if got != "tok-[REDACTED] tok-[REDACTED]" {
+		t.Fatalf("unexpected redaction: %q", got)
+	}
+}
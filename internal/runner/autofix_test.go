@@ -0,0 +1,136 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestExecuteSessionRunAutoFixesFailedValidation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tool script requires a POSIX shell")
+	}
+	// The fake aider writes "broken" on its first call and "fixed" after
+	// that, logging every prompt it receives.
+	binDir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		"for arg in \"$@\"; do last=\"$arg\"; done\n" +
+		"printf '%s\\n---\\n' \"$last\" >> \"$FAKE_AIDER_LOG\"\n" +
+		"if [ -f result.txt ]; then echo fixed > result.txt; else echo broken > result.txt; fi\n" +
+		"echo 'done <commit_message>fix: make result pass</commit_message>'\n"
+	if err := os.WriteFile(filepath.Join(binDir, "aider"), []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake aider failed: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cases := []struct {
+		name        string
+		validateCmd string
+		fixAttempts int
+		wantState   string
+		wantFixes   int
+	}{
+		{name: "fixed", validateCmd: "grep -q fixed result.txt", fixAttempts: 2, wantState: "COMPLETED", wantFixes: 1},
+		{name: "exhausted", validateCmd: "grep -q never result.txt", fixAttempts: 2, wantState: "FAILED", wantFixes: 2},
+		{name: "disabled", validateCmd: "grep -q fixed result.txt", fixAttempts: 0, wantState: "FAILED", wantFixes: 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			logPath := filepath.Join(t.TempDir(), "prompts.log")
+			t.Setenv("FAKE_AIDER_LOG", logPath)
+
+			r, st := newLimitsTestRunner(t)
+			repo := initGitRepo(t, "main")
+			session := state.Session{
+				ID:           "session-1",
+				RepoName:     "acme/api",
+				Branch:       "main",
+				WorktreePath: repo,
+				Tool:         "aider",
+				Status:       "CREATED",
+				Busy:         true,
+			}
+			if err := st.CreateSession(session); err != nil {
+				t.Fatalf("create session failed: %v", err)
+			}
+			run := state.Run{ID: "run-1", SessionID: session.ID, Prompt: "write result.txt", WorktreePath: repo, State: "CREATED"}
+			if err := st.CreateRun(run); err != nil {
+				t.Fatalf("create run failed: %v", err)
+			}
+
+			err := r.executeSessionRun(session, run, sessionRunOptions{
+				Prompt:      run.Prompt,
+				Validate:    true,
+				ValidateCmd: tc.validateCmd,
+				BaseBranch:  "main",
+				FixAttempts: tc.fixAttempts,
+			})
+			if (err == nil) != (tc.wantState == "COMPLETED") {
+				t.Fatalf("unexpected run error: %v", err)
+			}
+
+			got, _, err := st.GetRun(run.ID)
+			if err != nil {
+				t.Fatalf("get run failed: %v", err)
+			}
+			if got.State != tc.wantState {
+				t.Fatalf("unexpected run state: got %q want %q (error=%q)", got.State, tc.wantState, got.Error)
+			}
+
+			events, err := st.ListRunEvents(run.ID, 500)
+			if err != nil {
+				t.Fatalf("list events failed: %v", err)
+			}
+			fixes := 0
+			var validations []string
+			for _, event := range events {
+				switch event.Type {
+				case "fix_attempt":
+					fixes++
+				case "validate":
+					validations = append(validations, event.Data)
+				}
+			}
+			if fixes != tc.wantFixes {
+				t.Fatalf("unexpected fix attempts: got %d want %d (events=%+v)", fixes, tc.wantFixes, events)
+			}
+			if len(validations) != tc.wantFixes+1 {
+				t.Fatalf("expected one validation per attempt, got %v", validations)
+			}
+
+			logged, err := os.ReadFile(logPath)
+			if err != nil {
+				t.Fatalf("read prompt log failed: %v", err)
+			}
+			prompts := strings.Split(strings.TrimSuffix(string(logged), "---\n"), "---\n")
+			if len(prompts) != tc.wantFixes+1 {
+				t.Fatalf("unexpected tool invocations: %d", len(prompts))
+			}
+			if tc.wantFixes > 0 {
+				fix := prompts[1]
+				if !strings.Contains(fix, tc.validateCmd) || !strings.Contains(fix, "Original task:\nwrite result.txt") {
+					t.Fatalf("fix prompt should carry the failed command and task: %q", fix)
+				}
+			}
+
+			switch tc.name {
+			case "fixed":
+				if validations[len(validations)-1] != validationPassed || got.CommitSHA == "" {
+					t.Fatalf("expected a passing, committed run: %+v %v", got, validations)
+				}
+			case "exhausted":
+				if !strings.Contains(got.Error, "still failing after 2 fix attempts") || got.CommitSHA == "" {
+					t.Fatalf("exhausted run should fail with its last attempt committed: %+v", got)
+				}
+			case "disabled":
+				if got.CommitSHA != "" {
+					t.Fatalf("run without auto-fix should not commit on failure: %+v", got)
+				}
+			}
+		})
+	}
+}
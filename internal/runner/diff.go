@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/proc"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// SessionDiff is a session branch diffed against its repo's default branch.
+type SessionDiff struct {
+	BaseBranch   string `json:"base_branch"`
+	Branch       string `json:"branch"`
+	WorktreePath string `json:"worktree_path"`
+	Stat         string `json:"stat"`
+	Patch        string `json:"patch"`
+}
+
+// Summary returns the last line of the diff stat, e.g.
+// "3 files changed, 10 insertions(+), 2 deletions(-)".
+func (d SessionDiff) Summary() string {
+	stat := strings.TrimSpace(d.Stat)
+	if stat == "" {
+		return "no changes"
+	}
+	lines := strings.Split(stat, "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// SessionDiff diffs a session branch against the repo default branch, using
+// the worktree of the session's latest run.
+func (r *Runner) SessionDiff(ctx context.Context, session state.Session, repo state.Repo) (SessionDiff, error) {
+	worktreePath := strings.TrimSpace(session.WorktreePath)
+	if r.state != nil {
+		if latest, found, err := r.state.GetLatestRun(session.ID); err == nil && found && strings.TrimSpace(latest.WorktreePath) != "" {
+			worktreePath = strings.TrimSpace(latest.WorktreePath)
+		}
+	}
+	if worktreePath == "" {
+		return SessionDiff{}, errors.New("session has no worktree path")
+	}
+
+	baseBranch := strings.TrimSpace(repo.DefaultBranch)
+	if baseBranch == "" {
+		baseBranch = "main"
+	}
+	diffRef := fmt.Sprintf("%s...%s", baseBranch, session.Branch)
+	statOut, err := proc.Run(ctx, worktreePath, "git", "diff", "--stat", "--no-color", diffRef)
+	if err != nil {
+		return SessionDiff{}, fmt.Errorf("git diff --stat failed: %w", withOutput(err, statOut))
+	}
+	patchOut, err := proc.Run(ctx, worktreePath, "git", "diff", "--no-color", diffRef)
+	if err != nil {
+		return SessionDiff{}, fmt.Errorf("git diff failed: %w", withOutput(err, patchOut))
+	}
+
+	return SessionDiff{
+		BaseBranch:   baseBranch,
+		Branch:       session.Branch,
+		WorktreePath: worktreePath,
+		Stat:         strings.TrimSpace(string(statOut)),
+		Patch:        strings.TrimSpace(string(patchOut)),
+	}, nil
+}
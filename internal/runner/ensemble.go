@@ -0,0 +1,244 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+	"github.com/google/uuid"
+	"golang.org/x/sync/errgroup"
+)
+
+// EnsembleVariant is one tool/model combination in an ensemble. Branch is
+// the new branch the variant's fork session works on.
+type EnsembleVariant struct {
+	Tool   string
+	Model  string
+	Branch string
+}
+
+// EnsembleOptions configures an ensemble: the same prompt forked from one
+// source session once per variant.
+type EnsembleOptions struct {
+	Prompt      string
+	Variants    []EnsembleVariant
+	SetupCmd    string
+	Validate    bool
+	HasValidate bool
+	ValidateCmd string
+	BaseBranch  string
+	Priority    int
+	Timeout     time.Duration
+	MaxTokens   int64
+	MaxCostUSD  float64
+	FixAttempts int
+}
+
+// StartEnsemble forks one session per variant from the source session and
+// starts all of them in the background. Ensemble members never open pull
+// requests on their own; the promoted winner can be continued normally.
+func (r *Runner) StartEnsemble(sourceSessionID string, opts EnsembleOptions) (state.Ensemble, error) {
+	if r.state == nil {
+		return state.Ensemble{}, errors.New("state store not configured")
+	}
+
+	sourceSessionID = strings.TrimSpace(sourceSessionID)
+	opts.Prompt = strings.TrimSpace(opts.Prompt)
+	switch {
+	case sourceSessionID == "":
+		return state.Ensemble{}, errors.New("source session id is required")
+	case opts.Prompt == "":
+		return state.Ensemble{}, errors.New("prompt is required")
+	case len(opts.Variants) < 2:
+		return state.Ensemble{}, errors.New("ensemble needs at least two variants")
+	}
+	seenBranches := make(map[string]bool, len(opts.Variants))
+	for i, variant := range opts.Variants {
+		variant.Tool = strings.TrimSpace(variant.Tool)
+		variant.Model = strings.TrimSpace(variant.Model)
+		variant.Branch = strings.TrimSpace(variant.Branch)
+		switch {
+		case variant.Tool == "":
+			return state.Ensemble{}, fmt.Errorf("variant %d: tool is required", i+1)
+		case variant.Branch == "":
+			return state.Ensemble{}, fmt.Errorf("variant %d: branch is required", i+1)
+		case seenBranches[variant.Branch]:
+			return state.Ensemble{}, fmt.Errorf("variant %d: duplicate branch %q", i+1, variant.Branch)
+		}
+		seenBranches[variant.Branch] = true
+		opts.Variants[i] = variant
+	}
+
+	// Fork preparation runs the AI tool to summarize the source session, so
+	// do it for all variants at once. Worktree creation below stays serial
+	// because concurrent `git worktree add` calls contend for the same locks.
+	startOpts := make([]StartSessionOptions, len(opts.Variants))
+	var sourceSession state.Session
+	var g errgroup.Group
+	for i, variant := range opts.Variants {
+		g.Go(func() error {
+			prepared, source, err := r.prepareForkSession(sourceSessionID, ForkSessionOptions{
+				Branch:      variant.Branch,
+				Prompt:      opts.Prompt,
+				Tool:        variant.Tool,
+				Model:       variant.Model,
+				HasAutoPR:   true,
+				SetupCmd:    opts.SetupCmd,
+				Validate:    opts.Validate,
+				HasValidate: opts.HasValidate,
+				ValidateCmd: opts.ValidateCmd,
+				BaseBranch:  opts.BaseBranch,
+				Priority:    opts.Priority,
+				Timeout:     opts.Timeout,
+				MaxTokens:   opts.MaxTokens,
+				MaxCostUSD:  opts.MaxCostUSD,
+				FixAttempts: opts.FixAttempts,
+			})
+			if err != nil {
+				return fmt.Errorf("variant %s: %w", variantLabel(variant), err)
+			}
+			startOpts[i] = prepared
+			if i == 0 {
+				sourceSession = source
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return state.Ensemble{}, err
+	}
+
+	ensemble := state.Ensemble{
+		ID:              uuid.New().String(),
+		RepoName:        sourceSession.RepoName,
+		SourceSessionID: sourceSession.ID,
+		Prompt:          opts.Prompt,
+		Status:          state.EnsembleStatusRunning,
+	}
+	if err := r.state.CreateEnsemble(ensemble); err != nil {
+		return state.Ensemble{}, err
+	}
+
+	for i, variant := range opts.Variants {
+		session, run, err := r.StartSessionAsync(startOpts[i])
+		if err != nil {
+			return r.loadEnsemble(ensemble.ID, fmt.Errorf("start variant %s: %w", variantLabel(variant), err))
+		}
+		r.annotateForkRun(run.ID, sourceSession)
+		_ = r.state.AppendRunEvent(state.RunEvent{
+			RunID:   run.ID,
+			Type:    "ensemble",
+			Message: fmt.Sprintf("Ensemble %s variant %d of %d (%s)", ensemble.ID, i+1, len(opts.Variants), variantLabel(variant)),
+			Data:    ensemble.ID,
+		})
+		if err := r.state.AddEnsembleMember(ensemble.ID, state.EnsembleMember{
+			SessionID: session.ID,
+			Tool:      session.Tool,
+			Model:     session.Model,
+		}); err != nil {
+			return r.loadEnsemble(ensemble.ID, err)
+		}
+	}
+	return r.loadEnsemble(ensemble.ID, nil)
+}
+
+// GetEnsemble returns one ensemble with its members.
+func (r *Runner) GetEnsemble(id string) (state.Ensemble, bool, error) {
+	if r.state == nil {
+		return state.Ensemble{}, false, errors.New("state store not configured")
+	}
+	return r.state.GetEnsemble(id)
+}
+
+// ListEnsembles returns all ensembles, newest first.
+func (r *Runner) ListEnsembles() ([]state.Ensemble, error) {
+	if r.state == nil {
+		return nil, errors.New("state store not configured")
+	}
+	return r.state.ListEnsembles()
+}
+
+// PromoteEnsembleWinner marks one member session as the ensemble winner and
+// archives the others, canceling any of them that are still running.
+func (r *Runner) PromoteEnsembleWinner(ensembleID, sessionID string) (state.Ensemble, error) {
+	if r.state == nil {
+		return state.Ensemble{}, errors.New("state store not configured")
+	}
+	ensembleID = strings.TrimSpace(ensembleID)
+	sessionID = strings.TrimSpace(sessionID)
+	if sessionID == "" {
+		return state.Ensemble{}, errors.New("winner session id is required")
+	}
+
+	ensemble, found, err := r.state.GetEnsemble(ensembleID)
+	if err != nil {
+		return state.Ensemble{}, err
+	}
+	if !found {
+		return state.Ensemble{}, fmt.Errorf("ensemble %q not found", ensembleID)
+	}
+	if ensemble.WinnerSessionID != "" {
+		return state.Ensemble{}, fmt.Errorf("ensemble %q already promoted session %q", ensembleID, ensemble.WinnerSessionID)
+	}
+	isMember := false
+	for _, member := range ensemble.Members {
+		if member.SessionID == sessionID {
+			isMember = true
+			break
+		}
+	}
+	if !isMember {
+		return state.Ensemble{}, fmt.Errorf("session %q is not part of ensemble %q", sessionID, ensembleID)
+	}
+	winner, found, err := r.state.GetSession(sessionID)
+	if err != nil {
+		return state.Ensemble{}, err
+	}
+	if !found {
+		return state.Ensemble{}, fmt.Errorf("session %q not found", sessionID)
+	}
+	if winner.Busy {
+		return state.Ensemble{}, fmt.Errorf("session %q is still running", sessionID)
+	}
+
+	if err := r.state.SetEnsembleWinner(ensemble.ID, sessionID); err != nil {
+		return state.Ensemble{}, err
+	}
+	for _, member := range ensemble.Members {
+		if member.SessionID == sessionID {
+			continue
+		}
+		if session, found, err := r.state.GetSession(member.SessionID); err == nil && found && session.Busy {
+			_, _ = r.CancelSessionLatestRun(member.SessionID)
+		}
+		if err := r.state.SetSessionArchived(member.SessionID, true); err != nil {
+			return state.Ensemble{}, err
+		}
+	}
+	if latest, found, err := r.state.GetLatestRun(sessionID); err == nil && found {
+		_ = r.state.AppendRunEvent(state.RunEvent{
+			RunID:   latest.ID,
+			Type:    "ensemble_promoted",
+			Message: fmt.Sprintf("Promoted as winner of ensemble %s", ensemble.ID),
+			Data:    ensemble.ID,
+		})
+	}
+	return r.loadEnsemble(ensemble.ID, nil)
+}
+
+func (r *Runner) loadEnsemble(id string, cause error) (state.Ensemble, error) {
+	ensemble, _, err := r.state.GetEnsemble(id)
+	if cause != nil {
+		return ensemble, cause
+	}
+	return ensemble, err
+}
+
+func variantLabel(variant EnsembleVariant) string {
+	if variant.Model == "" {
+		return variant.Tool
+	}
+	return variant.Tool + "/" + variant.Model
+}
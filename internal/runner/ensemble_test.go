@@ -0,0 +1,144 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestStartEnsembleValidatesVariants(t *testing.T) {
+	r, _ := newLimitsTestRunner(t)
+
+	cases := []struct {
+		name string
+		opts EnsembleOptions
+		want string
+	}{
+		{name: "prompt", opts: EnsembleOptions{Variants: []EnsembleVariant{{Tool: "claude", Branch: "a"}, {Tool: "codex", Branch: "b"}}}, want: "prompt is required"},
+		{name: "single", opts: EnsembleOptions{Prompt: "p", Variants: []EnsembleVariant{{Tool: "claude", Branch: "a"}}}, want: "at least two variants"},
+		{name: "tool", opts: EnsembleOptions{Prompt: "p", Variants: []EnsembleVariant{{Tool: "claude", Branch: "a"}, {Branch: "b"}}}, want: "variant 2: tool is required"},
+		{name: "duplicate", opts: EnsembleOptions{Prompt: "p", Variants: []EnsembleVariant{{Tool: "claude", Branch: "a"}, {Tool: "codex", Branch: "a"}}}, want: "duplicate branch"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := r.StartEnsemble("session-1", tc.opts)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expected %q error, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestStartEnsembleRunsVariantsAndPromotesWinner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tool script requires a POSIX shell")
+	}
+	binDir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		"echo changed > ensemble.txt\n" +
+		`echo '{"type":"message","role":"assistant","content":"done <commit_message>feat: ensemble change</commit_message>"}'` + "\n"
+	// The AI package caches resolved binaries per process, so this test
+	// fakes gemini to stay independent of the fake claude in limits_test.go.
+	if err := os.WriteFile(filepath.Join(binDir, "gemini"), []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake gemini failed: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	r, st := newLimitsTestRunner(t)
+	repo := initGitRepo(t, "main")
+	if err := st.CreateSession(state.Session{
+		ID:           "session-1",
+		RepoName:     "acme/api",
+		Branch:       "main",
+		WorktreePath: repo,
+		Tool:         "gemini",
+		Status:       "COMPLETED",
+	}); err != nil {
+		t.Fatalf("create source session failed: %v", err)
+	}
+
+	ensemble, err := r.StartEnsemble("session-1", EnsembleOptions{
+		Prompt:      "add ensemble.txt",
+		Validate:    true,
+		ValidateCmd: "test -f ensemble.txt",
+		BaseBranch:  "main",
+		Variants: []EnsembleVariant{
+			{Tool: "gemini", Model: "gemini-2.5-pro", Branch: "fog/ensemble-pro"},
+			{Tool: "gemini", Model: "gemini-2.5-flash", Branch: "fog/ensemble-flash"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("start ensemble failed: %v", err)
+	}
+	if ensemble.Status != state.EnsembleStatusRunning || ensemble.SourceSessionID != "session-1" || len(ensemble.Members) != 2 {
+		t.Fatalf("unexpected ensemble: %+v", ensemble)
+	}
+	if ensemble.Members[0].Model != "gemini-2.5-pro" || ensemble.Members[1].Model != "gemini-2.5-flash" {
+		t.Fatalf("unexpected member order: %+v", ensemble.Members)
+	}
+
+	for _, member := range ensemble.Members {
+		session := waitForIdleSession(t, st, member.SessionID)
+		latest, _, err := st.GetLatestRun(session.ID)
+		if err != nil {
+			t.Fatalf("get latest run failed: %v", err)
+		}
+		if latest.State != "COMPLETED" {
+			t.Fatalf("member %s did not complete: %+v", member.SessionID, latest)
+		}
+		event, found, err := st.GetLatestRunEvent(latest.ID, "validate")
+		if err != nil || !found || event.Data != validationPassed {
+			t.Fatalf("expected passed validate event: found=%v err=%v event=%+v", found, err, event)
+		}
+	}
+
+	winner := ensemble.Members[1].SessionID
+	promoted, err := r.PromoteEnsembleWinner(ensemble.ID, winner)
+	if err != nil {
+		t.Fatalf("promote winner failed: %v", err)
+	}
+	if promoted.Status != state.EnsembleStatusPromoted || promoted.WinnerSessionID != winner {
+		t.Fatalf("unexpected promoted ensemble: %+v", promoted)
+	}
+	loser, _, err := st.GetSession(ensemble.Members[0].SessionID)
+	if err != nil {
+		t.Fatalf("get loser failed: %v", err)
+	}
+	if loser.ArchivedAt == nil {
+		t.Fatal("expected losing session to be archived")
+	}
+	winnerSession, _, err := st.GetSession(winner)
+	if err != nil {
+		t.Fatalf("get winner failed: %v", err)
+	}
+	if winnerSession.ArchivedAt != nil {
+		t.Fatal("winner should not be archived")
+	}
+
+	if _, err := r.PromoteEnsembleWinner(ensemble.ID, winner); err == nil {
+		t.Fatal("expected second promotion to fail")
+	}
+}
+
+func waitForIdleSession(t *testing.T, st *state.Store, sessionID string) state.Session {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		session, found, err := st.GetSession(sessionID)
+		if err != nil || !found {
+			t.Fatalf("get session %s failed: found=%v err=%v", sessionID, found, err)
+		}
+		if !session.Busy {
+			return session
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("session %s still busy", sessionID)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
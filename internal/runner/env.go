@@ -0,0 +1,22 @@
+package runner
+
+import "fmt"
+
+// repoRunEnv returns the repo's env vars as "KEY=value" entries for run
+// processes, plus the secret values to redact from the run's output.
+func (r *Runner) repoRunEnv(repoName string) (env []string, secrets []string, err error) {
+	if r == nil || r.state == nil {
+		return nil, nil, nil
+	}
+	vars, err := r.state.ListRepoEnv(repoName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load env vars: %w", err)
+	}
+	for _, v := range vars {
+		env = append(env, v.Name+"="+v.Value)
+		if v.Secret {
+			secrets = append(secrets, v.Value)
+		}
+	}
+	return env, secrets, nil
+}
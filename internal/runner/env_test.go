@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestExecuteSessionRunInjectsAndRedactsRepoEnv(t *testing.T) {
+	r, st := newLimitsTestRunner(t)
+	repo := initGitRepo(t, "main")
+	if err := st.SetRepoEnv("acme/api", "API_KEY", "sk-live-abcdef123456", true); err != nil {
+		t.Fatalf("set secret failed: %v", err)
+	}
+	if err := st.SetRepoEnv("acme/api", "DB_NAME", "fog_test_db", false); err != nil {
+		t.Fatalf("set env failed: %v", err)
+	}
+	session := state.Session{ID: "session-1", RepoName: "acme/api", Branch: "main", WorktreePath: repo, Tool: "claude", Status: "CREATED", Busy: true}
+	if err := st.CreateSession(session); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	run := state.Run{ID: "run-1", SessionID: session.ID, Prompt: "p", WorktreePath: repo, State: "CREATED"}
+	if err := st.CreateRun(run); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+
+	err := r.executeSessionRun(session, run, sessionRunOptions{
+		Prompt:     run.Prompt,
+		BaseBranch: "main",
+		SetupCmd:   `echo "key=$API_KEY db=$DB_NAME"; exit 1`,
+	})
+	if err == nil {
+		t.Fatal("expected setup to fail")
+	}
+
+	got, _, err := st.GetRun(run.ID)
+	if err != nil {
+		t.Fatalf("get run failed: %v", err)
+	}
+	if !strings.Contains(got.Error, "key=[REDACTED] db=fog_test_db") {
+		t.Fatalf("expected env injected and secret redacted in run error, got %q", got.Error)
+	}
+	events, err := st.ListRunEvents(run.ID, 100)
+	if err != nil {
+		t.Fatalf("list events failed: %v", err)
+	}
+	for _, event := range events {
+		if strings.Contains(event.Message+event.Data, "sk-live-abcdef123456") {
+			t.Fatalf("secret leaked into %s event: %+v", event.Type, event)
+		}
+	}
+}
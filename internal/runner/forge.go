@@ -0,0 +1,43 @@
+package runner
+
+import (
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/forge"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// ForgeForHost returns the forge serving host as configured with
+// `fog forge add`. Unconfigured hosts get the forge guessed from their name,
+// which is GitHub through gh for anything unknown.
+func (r *Runner) ForgeForHost(host string) (forge.Forge, error) {
+	cfg := forge.Config{Host: host}
+	if r.state != nil && strings.TrimSpace(host) != "" {
+		fh, found, err := r.state.GetForgeHost(host)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			cfg.Kind = fh.Kind
+			cfg.BaseURL = fh.BaseURL
+		}
+		token, _, err := r.state.GetForgeToken(host)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Token = token
+	}
+	return forge.New(cfg)
+}
+
+// sessionForge returns the forge of a session's repo, falling back to the
+// host of its pull request URL for repos imported before hosts were tracked.
+func (r *Runner) sessionForge(session state.Session) (forge.Forge, error) {
+	host := forge.HostOf(session.PRURL)
+	if r.state != nil {
+		if repo, found, err := r.state.GetRepoByName(session.RepoName); err == nil && found && strings.TrimSpace(repo.Host) != "" {
+			host = repo.Host
+		}
+	}
+	return r.ForgeForHost(host)
+}
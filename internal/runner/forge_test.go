@@ -0,0 +1,49 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/forge"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestSessionForgeUsesRepoHostConfig(t *testing.T) {
+	r, st := newLimitsTestRunner(t)
+	if _, err := st.UpsertRepo(state.Repo{
+		Name:             "acme/web",
+		URL:              "https://git.acme.dev/acme/web.git",
+		Host:             "git.acme.dev",
+		BarePath:         "/tmp/acme-web/repo.git",
+		BaseWorktreePath: "/tmp/acme-web/base",
+	}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	if err := st.SetForgeHost(state.ForgeHost{Host: "git.acme.dev", Kind: forge.KindGitea}, "secret"); err != nil {
+		t.Fatalf("set forge host failed: %v", err)
+	}
+
+	cases := []struct {
+		session state.Session
+		kind    string
+		host    string
+	}{
+		{state.Session{RepoName: "acme/api"}, forge.KindGitHub, "github.com"},
+		{state.Session{RepoName: "acme/web"}, forge.KindGitea, "git.acme.dev"},
+		// Unknown repos fall back to the host of the PR URL.
+		{state.Session{RepoName: "gone/repo", PRURL: "https://gitlab.com/gone/repo/-/merge_requests/1"}, forge.KindGitLab, "gitlab.com"},
+	}
+	for _, tc := range cases {
+		f, err := r.sessionForge(tc.session)
+		if err != nil {
+			t.Fatalf("sessionForge(%s) failed: %v", tc.session.RepoName, err)
+		}
+		if f.Kind() != tc.kind || f.Host() != tc.host {
+			t.Fatalf("sessionForge(%s) = %s %s, want %s %s", tc.session.RepoName, f.Kind(), f.Host(), tc.kind, tc.host)
+		}
+	}
+	// The stored token authenticates git for the Gitea host.
+	f, _ := r.ForgeForHost("git.acme.dev")
+	if env := f.GitEnv(); len(env) == 0 {
+		t.Fatal("expected git auth env for configured gitea host")
+	}
+}
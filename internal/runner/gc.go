@@ -0,0 +1,367 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/forge"
+	"github.com/darkLord19/foglet/internal/git"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// prStateFn looks up a pull request state; tests replace it to avoid the
+// forge.
+var prStateFn = forge.Forge.PRState
+
+// DeleteSessionOptions controls what DeleteSession removes besides the
+// session's rows.
+type DeleteSessionOptions struct {
+	// DeleteBranch also deletes the session's local branch.
+	DeleteBranch bool
+	// Force removes dirty worktrees and unmerged branches.
+	Force bool
+}
+
+// SessionGCPolicy selects sessions for garbage collection. A session is
+// collected when it matches any enabled rule; busy sessions never are.
+type SessionGCPolicy struct {
+	// OlderThan matches sessions not updated within the duration.
+	OlderThan time.Duration
+	// MergedPR and ClosedPR match sessions whose pull request is in that
+	// state on GitHub.
+	MergedPR bool
+	ClosedPR bool
+	// MinSizeBytes matches sessions whose worktrees use at least this much disk.
+	MinSizeBytes int64
+	// Archived matches archived sessions.
+	Archived bool
+	// Repo restricts collection to one repository when set.
+	Repo string
+
+	DeleteBranches bool
+	Force          bool
+	DryRun         bool
+}
+
+// SessionGCResult reports one session selected by a GC policy.
+type SessionGCResult struct {
+	Session   state.Session `json:"session"`
+	Reasons   []string      `json:"reasons"`
+	SizeBytes int64         `json:"size_bytes"`
+	Deleted   bool          `json:"deleted"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// ArchiveSession archives or unarchives a session.
+func (r *Runner) ArchiveSession(id string, archived bool) (state.Session, error) {
+	if r.state == nil {
+		return state.Session{}, errors.New("state store not configured")
+	}
+	id = strings.TrimSpace(id)
+	if err := r.state.SetSessionArchived(id, archived); err != nil {
+		return state.Session{}, err
+	}
+	session, found, err := r.state.GetSession(id)
+	if err != nil {
+		return state.Session{}, err
+	}
+	if !found {
+		return state.Session{}, fmt.Errorf("session %q disappeared", id)
+	}
+	return session, nil
+}
+
+// DeleteSession removes an idle session's worktrees, optionally its local
+// branch, and then its rows. Worktrees are only removed through git and only
+// when git knows them as worktrees of the session's repository, so a stale
+// path can never take unrelated files with it.
+//
+// The session is claimed for the whole delete, so follow-ups sent meanwhile
+// are queued instead of running in a worktree that is being removed; they
+// are dropped with the session, or started if the delete fails.
+func (r *Runner) DeleteSession(id string, opts DeleteSessionOptions) (retErr error) {
+	if r.state == nil {
+		return errors.New("state store not configured")
+	}
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return errors.New("session id is required")
+	}
+
+	session, found, err := r.state.GetSession(id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("session %q not found", id)
+	}
+	if err := r.claimSessionForDelete(session.ID); err != nil {
+		return err
+	}
+	deleted := false
+	defer func() {
+		if deleted {
+			return
+		}
+		if err := r.releaseSession(session.ID); err != nil && retErr == nil {
+			retErr = err
+		}
+	}()
+
+	repo, found, err := r.state.GetRepoByName(session.RepoName)
+	if err != nil {
+		return err
+	}
+	if found {
+		if err := r.removeSessionWorktrees(session, repo, opts.Force); err != nil {
+			return err
+		}
+		if opts.DeleteBranch {
+			if err := r.deleteSessionBranch(session, repo, opts.Force); err != nil {
+				return err
+			}
+		}
+	} else if paths := r.sessionWorktreePaths(session); len(existingPaths(paths)) > 0 {
+		return fmt.Errorf("repo %q not found; cannot remove worktrees of session %q", session.RepoName, id)
+	}
+
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+	if err := r.state.DeleteSession(session.ID); err != nil {
+		return err
+	}
+	deleted = true
+	return nil
+}
+
+// claimSessionForDelete marks an idle session busy so no run can start in
+// it. Sessions with queued runs are refused rather than dropping the runs.
+func (r *Runner) claimSessionForDelete(sessionID string) error {
+	r.mu.Lock()
+	_, active := r.active[sessionID]
+	r.mu.Unlock()
+	if active {
+		return fmt.Errorf("session %q is busy", sessionID)
+	}
+
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+	claimed, err := r.state.TryClaimSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return fmt.Errorf("session %q is busy", sessionID)
+	}
+	queued, err := r.state.ListQueuedRuns(sessionID)
+	if err == nil && len(queued) > 0 {
+		err = fmt.Errorf("session %q has %d queued run(s); drop them first", sessionID, len(queued))
+	}
+	if err != nil {
+		_ = r.state.SetSessionBusy(sessionID, false)
+		return err
+	}
+	return nil
+}
+
+// CollectSessionGarbage selects sessions matching policy and, unless
+// DryRun is set, deletes them. Failures are reported per session.
+func (r *Runner) CollectSessionGarbage(ctx context.Context, policy SessionGCPolicy) ([]SessionGCResult, error) {
+	if r.state == nil {
+		return nil, errors.New("state store not configured")
+	}
+	policy.Repo = strings.TrimSpace(policy.Repo)
+	switch {
+	case policy.OlderThan < 0:
+		return nil, errors.New("older-than cannot be negative")
+	case policy.MinSizeBytes < 0:
+		return nil, errors.New("min size cannot be negative")
+	case policy.OlderThan == 0 && !policy.MergedPR && !policy.ClosedPR && policy.MinSizeBytes == 0 && !policy.Archived:
+		return nil, errors.New("gc policy needs at least one rule")
+	}
+
+	sessions, err := r.state.ListSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	results := make([]SessionGCResult, 0)
+	for _, session := range sessions {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		if session.Busy || (policy.Repo != "" && session.RepoName != policy.Repo) {
+			continue
+		}
+
+		var reasons []string
+		if policy.OlderThan > 0 && now.Sub(session.UpdatedAt) > policy.OlderThan {
+			reasons = append(reasons, fmt.Sprintf("inactive since %s", session.UpdatedAt.Format(time.DateOnly)))
+		}
+		if policy.Archived && session.ArchivedAt != nil {
+			reasons = append(reasons, "archived")
+		}
+		if (policy.MergedPR || policy.ClosedPR) && session.PRURL != "" {
+			prState, err := r.sessionPRState(ctx, session)
+			if err == nil {
+				switch {
+				case policy.MergedPR && prState == "MERGED":
+					reasons = append(reasons, "pull request merged")
+				case policy.ClosedPR && prState == "CLOSED":
+					reasons = append(reasons, "pull request closed")
+				}
+			}
+		}
+		var size int64
+		if policy.MinSizeBytes > 0 || len(reasons) > 0 {
+			size = worktreesSize(existingPaths(r.sessionWorktreePaths(session)))
+		}
+		if policy.MinSizeBytes > 0 && size >= policy.MinSizeBytes {
+			reasons = append(reasons, fmt.Sprintf("worktree uses %d bytes", size))
+		}
+		if len(reasons) == 0 {
+			continue
+		}
+
+		result := SessionGCResult{Session: session, Reasons: reasons, SizeBytes: size}
+		if !policy.DryRun {
+			err := r.DeleteSession(session.ID, DeleteSessionOptions{
+				DeleteBranch: policy.DeleteBranches,
+				Force:        policy.Force,
+			})
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Deleted = true
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (r *Runner) removeSessionWorktrees(session state.Session, repo state.Repo, force bool) error {
+	paths := existingPaths(r.sessionWorktreePaths(session))
+	if len(paths) == 0 {
+		return nil
+	}
+	g := git.New(repo.BaseWorktreePath)
+	if !g.IsRepo() {
+		return fmt.Errorf("repo %q base worktree is not a git repository: %s", repo.Name, repo.BaseWorktreePath)
+	}
+	worktrees, err := g.ListWorktrees()
+	if err != nil {
+		return fmt.Errorf("list worktrees for %q: %w", repo.Name, err)
+	}
+
+	for _, path := range paths {
+		if samePath(path, repo.BaseWorktreePath) {
+			continue
+		}
+		for _, wt := range worktrees {
+			if !samePath(path, wt.Path) {
+				continue
+			}
+			if err := g.RemoveWorktree(wt.Path, force); err != nil {
+				return fmt.Errorf("remove worktree %s: %w", path, err)
+			}
+			break
+		}
+	}
+	_, _ = g.PruneWorktrees(false)
+	return nil
+}
+
+func (r *Runner) deleteSessionBranch(session state.Session, repo state.Repo, force bool) error {
+	branch := strings.TrimSpace(session.Branch)
+	if branch == "" || branch == repo.DefaultBranch {
+		return nil
+	}
+	g := git.New(repo.BaseWorktreePath)
+	if !g.BranchExists(branch) {
+		return nil
+	}
+
+	// Forks and ensembles never share branches, but a session imported
+	// onto an existing branch might; keep branches someone still uses.
+	sessions, err := r.state.ListSessions()
+	if err != nil {
+		return err
+	}
+	for _, other := range sessions {
+		if other.ID != session.ID && other.RepoName == session.RepoName && other.Branch == branch {
+			return nil
+		}
+	}
+
+	if err := g.DeleteBranch(branch, force); err != nil {
+		return fmt.Errorf("delete branch %s: %w", branch, err)
+	}
+	return nil
+}
+
+// sessionWorktreePaths returns every worktree a session or its runs used.
+func (r *Runner) sessionWorktreePaths(session state.Session) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	add := func(path string) {
+		path = strings.TrimSpace(path)
+		if path == "" || seen[path] {
+			return
+		}
+		seen[path] = true
+		paths = append(paths, path)
+	}
+	add(session.WorktreePath)
+	if runs, err := r.state.ListRuns(session.ID); err == nil {
+		for _, run := range runs {
+			add(run.WorktreePath)
+		}
+	}
+	return paths
+}
+
+func existingPaths(paths []string) []string {
+	out := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			out = append(out, path)
+		}
+	}
+	return out
+}
+
+func worktreesSize(paths []string) int64 {
+	var total int64
+	for _, root := range paths {
+		_ = filepath.WalkDir(root, func(_ string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.Type().IsRegular() {
+				if info, err := d.Info(); err == nil {
+					total += info.Size()
+				}
+			}
+			return nil
+		})
+	}
+	return total
+}
+
+func samePath(a, b string) bool {
+	if resolved, err := filepath.EvalSymlinks(a); err == nil {
+		a = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(b); err == nil {
+		b = resolved
+	}
+	return filepath.Clean(a) == filepath.Clean(b)
+}
@@ -0,0 +1,210 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/forge"
+	"github.com/darkLord19/foglet/internal/git"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestDeleteSessionRemovesWorktreeBranchAndRows(t *testing.T) {
+	r, st := newLimitsTestRunner(t)
+	repo := newGCTestRepo(t, st)
+	wtPath := addGCTestSession(t, st, repo, "session-1", "fog/delete-me", time.Now().UTC(), "")
+
+	busy := state.Session{ID: "session-busy", RepoName: "acme/api", Branch: "fog/busy", WorktreePath: wtPath, Tool: "claude", Status: "AI_RUNNING", Busy: true}
+	if err := st.CreateSession(busy); err != nil {
+		t.Fatalf("create busy session failed: %v", err)
+	}
+	if err := r.DeleteSession(busy.ID, DeleteSessionOptions{}); err == nil || !strings.Contains(err.Error(), "busy") {
+		t.Fatalf("expected busy session to be refused, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(wtPath, "dirty.txt"), []byte("x\n"), 0o644); err != nil {
+		t.Fatalf("write dirty file failed: %v", err)
+	}
+	if err := r.DeleteSession("session-1", DeleteSessionOptions{DeleteBranch: true}); err == nil {
+		t.Fatal("expected dirty worktree to block deletion without force")
+	}
+	if got, found, _ := st.GetSession("session-1"); !found || got.Busy {
+		t.Fatalf("session rows should survive a failed delete and be released: found=%v %+v", found, got)
+	}
+
+	if err := r.DeleteSession("session-1", DeleteSessionOptions{DeleteBranch: true, Force: true}); err != nil {
+		t.Fatalf("delete session failed: %v", err)
+	}
+	if _, err := os.Stat(wtPath); !os.IsNotExist(err) {
+		t.Fatalf("expected worktree to be removed, stat err=%v", err)
+	}
+	if git.New(repo).BranchExists("fog/delete-me") {
+		t.Fatal("expected session branch to be deleted")
+	}
+	if _, found, _ := st.GetSession("session-1"); found {
+		t.Fatal("expected session rows to be deleted")
+	}
+	if _, err := os.Stat(filepath.Join(repo, "README.md")); err != nil {
+		t.Fatalf("base worktree must be untouched: %v", err)
+	}
+}
+
+func TestDeleteSessionHoldsSessionAgainstFollowUps(t *testing.T) {
+	r, st := newLimitsTestRunner(t)
+	repo := newGCTestRepo(t, st)
+	addGCTestSession(t, st, repo, "session-1", "fog/delete-me", time.Now().UTC(), "")
+
+	if err := r.claimSessionForDelete("session-1"); err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	_, run, _, err := r.prepareFollowUpRun("session-1", "one more thing")
+	if err != nil {
+		t.Fatalf("follow-up failed: %v", err)
+	}
+	if run.State != "QUEUED" {
+		t.Fatalf("follow-up during a delete should be queued, got %q", run.State)
+	}
+	if err := st.SetSessionBusy("session-1", false); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+
+	if err := r.DeleteSession("session-1", DeleteSessionOptions{Force: true}); err == nil || !strings.Contains(err.Error(), "queued") {
+		t.Fatalf("expected session with queued runs to be refused, got %v", err)
+	}
+	if got, _, _ := st.GetSession("session-1"); got.Busy {
+		t.Fatal("refused delete should release the session")
+	}
+	if _, err := r.DropQueuedRun("session-1", run.ID); err != nil {
+		t.Fatalf("drop queued run failed: %v", err)
+	}
+	if err := r.DeleteSession("session-1", DeleteSessionOptions{Force: true}); err != nil {
+		t.Fatalf("delete session failed: %v", err)
+	}
+}
+
+func TestCollectSessionGarbageAppliesPolicies(t *testing.T) {
+	origPRState := prStateFn
+	t.Cleanup(func() { prStateFn = origPRState })
+	prStateFn = func(_ forge.Forge, ctx context.Context, repoPath, prURL string) (string, error) {
+		if strings.HasSuffix(prURL, "/1") {
+			return "MERGED", nil
+		}
+		return "OPEN", nil
+	}
+
+	r, st := newLimitsTestRunner(t)
+	repo := newGCTestRepo(t, st)
+	now := time.Now().UTC()
+	oldPath := addGCTestSession(t, st, repo, "session-old", "fog/old", now.Add(-60*24*time.Hour), "")
+	mergedPath := addGCTestSession(t, st, repo, "session-merged", "fog/merged", now, "https://github.com/acme/api/pull/1")
+	openPath := addGCTestSession(t, st, repo, "session-open", "fog/open", now, "https://github.com/acme/api/pull/2")
+
+	if _, err := r.CollectSessionGarbage(context.Background(), SessionGCPolicy{}); err == nil {
+		t.Fatal("expected policy without rules to fail")
+	}
+
+	policy := SessionGCPolicy{OlderThan: 30 * 24 * time.Hour, MergedPR: true, DeleteBranches: true, DryRun: true}
+	results, err := r.CollectSessionGarbage(context.Background(), policy)
+	if err != nil {
+		t.Fatalf("dry-run gc failed: %v", err)
+	}
+	if got := gcResultIDs(results); got != "session-merged,session-old" {
+		t.Fatalf("unexpected dry-run candidates: %s", got)
+	}
+	for _, result := range results {
+		if result.Deleted || len(result.Reasons) == 0 || result.SizeBytes == 0 {
+			t.Fatalf("unexpected dry-run result: %+v", result)
+		}
+	}
+	if _, err := os.Stat(oldPath); err != nil {
+		t.Fatalf("dry run must not remove worktrees: %v", err)
+	}
+
+	policy.DryRun = false
+	results, err = r.CollectSessionGarbage(context.Background(), policy)
+	if err != nil {
+		t.Fatalf("gc failed: %v", err)
+	}
+	if got := gcResultIDs(results); got != "session-merged,session-old" {
+		t.Fatalf("unexpected gc results: %s", got)
+	}
+	for _, result := range results {
+		if !result.Deleted {
+			t.Fatalf("expected session to be deleted: %+v", result)
+		}
+	}
+	for _, path := range []string{oldPath, mergedPath} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be removed, stat err=%v", path, err)
+		}
+	}
+	if _, err := os.Stat(openPath); err != nil {
+		t.Fatalf("open session worktree must be kept: %v", err)
+	}
+	sessions, err := st.ListSessions()
+	if err != nil {
+		t.Fatalf("list sessions failed: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != "session-open" {
+		t.Fatalf("unexpected remaining sessions: %+v", sessions)
+	}
+
+	results, err = r.CollectSessionGarbage(context.Background(), SessionGCPolicy{MinSizeBytes: 1 << 40, DryRun: true})
+	if err != nil {
+		t.Fatalf("size gc failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Fatalf("expected no oversized sessions, got %+v", results)
+	}
+}
+
+func newGCTestRepo(t *testing.T, st *state.Store) string {
+	t.Helper()
+	repo := initGitRepo(t, "main")
+	if _, err := st.UpsertRepo(state.Repo{
+		Name:             "acme/api",
+		URL:              "https://github.com/acme/api.git",
+		Host:             "github.com",
+		BarePath:         filepath.Join(filepath.Dir(repo), "repo.git"),
+		BaseWorktreePath: repo,
+		DefaultBranch:    "main",
+	}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	return repo
+}
+
+func addGCTestSession(t *testing.T, st *state.Store, repo, id, branch string, updatedAt time.Time, prURL string) string {
+	t.Helper()
+	wtPath := filepath.Join(filepath.Dir(repo), "worktrees", id)
+	runGit(t, repo, "worktree", "add", "-b", branch, wtPath, "main")
+	if err := st.CreateSession(state.Session{
+		ID:           id,
+		RepoName:     "acme/api",
+		Branch:       branch,
+		WorktreePath: wtPath,
+		Tool:         "claude",
+		PRURL:        prURL,
+		Status:       "COMPLETED",
+		CreatedAt:    updatedAt,
+		UpdatedAt:    updatedAt,
+	}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	if err := st.CreateRun(state.Run{ID: id + "-run", SessionID: id, Prompt: "p", WorktreePath: wtPath, State: "COMPLETED"}); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+	return wtPath
+}
+
+func gcResultIDs(results []SessionGCResult) string {
+	ids := make([]string, 0, len(results))
+	for _, result := range results {
+		ids = append(ids, result.Session.ID)
+	}
+	return strings.Join(ids, ",")
+}
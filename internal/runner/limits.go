@@ -0,0 +1,108 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/ai"
+)
+
+// Settings keys for per-run limits. Each key may also be stored per repo as
+// RepoSettingKey(key, repoName), which overrides the global value.
+const (
+	SettingRunTimeout    = "run_timeout"
+	SettingRunMaxTokens  = "run_max_tokens"
+	SettingRunMaxCostUSD = "run_max_cost_usd"
+)
+
+// DefaultRunTimeout bounds a run when no timeout is configured.
+const DefaultRunTimeout = time.Hour
+
+var (
+	errRunTimedOut    = errors.New("run timed out")
+	errBudgetExceeded = errors.New("run budget exceeded")
+)
+
+// RunLimits bounds one run. Zero values disable the corresponding limit.
+type RunLimits struct {
+	Timeout    time.Duration
+	MaxTokens  int64
+	MaxCostUSD float64
+}
+
+// RepoSettingKey returns the settings key for a per-repo override.
+func RepoSettingKey(key, repoName string) string {
+	return key + ":" + strings.TrimSpace(repoName)
+}
+
+// RunLimits returns the limits configured for runs in a repo, applying the
+// repo override on top of the global defaults.
+func (r *Runner) RunLimits(repoName string) RunLimits {
+	limits := RunLimits{Timeout: DefaultRunTimeout}
+	if r == nil || r.state == nil {
+		return limits
+	}
+	for _, key := range []string{SettingRunTimeout, RepoSettingKey(SettingRunTimeout, repoName)} {
+		if raw, found, err := r.state.GetSetting(key); err == nil && found {
+			if value, err := ParseRunTimeout(raw); err == nil {
+				limits.Timeout = value
+			}
+		}
+	}
+	for _, key := range []string{SettingRunMaxTokens, RepoSettingKey(SettingRunMaxTokens, repoName)} {
+		if raw, found, err := r.state.GetSetting(key); err == nil && found {
+			if value, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64); err == nil && value >= 0 {
+				limits.MaxTokens = value
+			}
+		}
+	}
+	for _, key := range []string{SettingRunMaxCostUSD, RepoSettingKey(SettingRunMaxCostUSD, repoName)} {
+		if raw, found, err := r.state.GetSetting(key); err == nil && found {
+			if value, err := strconv.ParseFloat(strings.TrimSpace(raw), 64); err == nil && value >= 0 {
+				limits.MaxCostUSD = value
+			}
+		}
+	}
+	return limits
+}
+
+// ParseRunTimeout parses a Go duration ("45m", "2h"). "0" disables the timeout.
+func ParseRunTimeout(raw string) (time.Duration, error) {
+	value, err := time.ParseDuration(strings.TrimSpace(raw))
+	if err != nil {
+		return 0, fmt.Errorf("invalid run timeout %q: %w", raw, err)
+	}
+	if value < 0 {
+		return 0, fmt.Errorf("run timeout %q cannot be negative", raw)
+	}
+	return value, nil
+}
+
+// resolveRunLimits applies per-request overrides on top of configured limits.
+func (r *Runner) resolveRunLimits(repoName string, override RunLimits) RunLimits {
+	limits := r.RunLimits(repoName)
+	if override.Timeout > 0 {
+		limits.Timeout = override.Timeout
+	}
+	if override.MaxTokens > 0 {
+		limits.MaxTokens = override.MaxTokens
+	}
+	if override.MaxCostUSD > 0 {
+		limits.MaxCostUSD = override.MaxCostUSD
+	}
+	return limits
+}
+
+// checkBudget returns a budget error once usage crosses a limit.
+func (l RunLimits) checkBudget(usage ai.Usage) error {
+	if l.MaxTokens > 0 && usage.TotalTokens() > l.MaxTokens {
+		return fmt.Errorf("%w: used %d tokens (limit %d)", errBudgetExceeded, usage.TotalTokens(), l.MaxTokens)
+	}
+	if l.MaxCostUSD > 0 && usage.CostUSD > l.MaxCostUSD {
+		return fmt.Errorf("%w: cost $%.4f (limit $%.4f)", errBudgetExceeded, usage.CostUSD, l.MaxCostUSD)
+	}
+	return nil
+}
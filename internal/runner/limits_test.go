@@ -0,0 +1,219 @@
+package runner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/ai"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestRunLimitsResolution(t *testing.T) {
+	r, st := newLimitsTestRunner(t)
+
+	if got := r.RunLimits("acme/api"); got.Timeout != DefaultRunTimeout || got.MaxTokens != 0 || got.MaxCostUSD != 0 {
+		t.Fatalf("unexpected default limits: %+v", got)
+	}
+
+	settings := map[string]string{
+		SettingRunTimeout:   "30m",
+		SettingRunMaxTokens: "100000",
+		RepoSettingKey(SettingRunTimeout, "acme/api"):    "2h",
+		RepoSettingKey(SettingRunMaxCostUSD, "acme/api"): "1.5",
+	}
+	for key, value := range settings {
+		if err := st.SetSetting(key, value); err != nil {
+			t.Fatalf("set %s failed: %v", key, err)
+		}
+	}
+
+	got := r.RunLimits("acme/api")
+	if got.Timeout != 2*time.Hour || got.MaxTokens != 100000 || got.MaxCostUSD != 1.5 {
+		t.Fatalf("unexpected repo limits: %+v", got)
+	}
+	if other := r.RunLimits("acme/web"); other.Timeout != 30*time.Minute || other.MaxCostUSD != 0 {
+		t.Fatalf("unexpected limits for repo without override: %+v", other)
+	}
+
+	resolved := r.resolveRunLimits("acme/api", RunLimits{Timeout: time.Minute})
+	if resolved.Timeout != time.Minute || resolved.MaxTokens != 100000 {
+		t.Fatalf("per-request override not applied: %+v", resolved)
+	}
+}
+
+func TestRunLimitsCheckBudget(t *testing.T) {
+	limits := RunLimits{MaxTokens: 1000, MaxCostUSD: 0.5}
+	if err := limits.checkBudget(ai.Usage{InputTokens: 600, OutputTokens: 400}); err != nil {
+		t.Fatalf("usage at the limit should pass: %v", err)
+	}
+	if err := limits.checkBudget(ai.Usage{InputTokens: 600, OutputTokens: 401}); !errors.Is(err, errBudgetExceeded) {
+		t.Fatalf("expected token budget error, got %v", err)
+	}
+	if err := limits.checkBudget(ai.Usage{CostUSD: 0.51}); !errors.Is(err, errBudgetExceeded) {
+		t.Fatalf("expected cost budget error, got %v", err)
+	}
+}
+
+func TestRecordRunUsageSkipsToolsWithoutUsage(t *testing.T) {
+	r, st := newLimitsTestRunner(t)
+	if err := st.CreateSession(state.Session{
+		ID:           "session-1",
+		RepoName:     "acme/api",
+		Branch:       "fog/test",
+		WorktreePath: "/tmp/wt",
+		Tool:         "aider",
+		Status:       "AI_RUNNING",
+	}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	for _, id := range []string{"run-1", "run-2"} {
+		if err := st.CreateRun(state.Run{ID: id, SessionID: "session-1", Prompt: "p", WorktreePath: "/tmp/wt", State: "AI_RUNNING"}); err != nil {
+			t.Fatalf("create run failed: %v", err)
+		}
+	}
+
+	r.recordRunUsage("run-1", "sonnet", ai.Usage{}, time.Second)
+	r.recordRunUsage("run-2", "sonnet", ai.Usage{NumTurns: 1}, time.Second)
+
+	got, _, err := st.GetRun("run-1")
+	if err != nil {
+		t.Fatalf("get run failed: %v", err)
+	}
+	if got.Usage != nil {
+		t.Fatalf("run without reported usage should have none recorded: %+v", got.Usage)
+	}
+	got, _, err = st.GetRun("run-2")
+	if err != nil {
+		t.Fatalf("get run failed: %v", err)
+	}
+	if got.Usage == nil || got.Usage.Model != "sonnet" || got.Usage.DurationMS != 1000 {
+		t.Fatalf("reported usage should be recorded with fallbacks: %+v", got.Usage)
+	}
+}
+
+func TestExecuteSessionRunEnforcesLimits(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tool script requires a POSIX shell")
+	}
+	binDir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		`echo '{"type":"assistant","message":{"id":"msg_1","content":[{"type":"text","text":"working"}],"usage":{"input_tokens":500,"output_tokens":100}}}'` + "\n" +
+		"sleep 5\n"
+	if err := os.WriteFile(filepath.Join(binDir, "claude"), []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake claude failed: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cases := []struct {
+		name   string
+		limits RunLimits
+		state  string
+		event  string
+	}{
+		{name: "timeout", limits: RunLimits{Timeout: 300 * time.Millisecond}, state: "TIMED_OUT", event: "timed_out"},
+		{name: "budget", limits: RunLimits{MaxTokens: 100}, state: "BUDGET_EXCEEDED", event: "budget_exceeded"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, st := newLimitsTestRunner(t)
+			session := state.Session{
+				ID:           "session-1",
+				RepoName:     "acme/api",
+				Branch:       "fog/test",
+				WorktreePath: t.TempDir(),
+				Tool:         "claude",
+				Status:       "CREATED",
+				Busy:         true,
+			}
+			if err := st.CreateSession(session); err != nil {
+				t.Fatalf("create session failed: %v", err)
+			}
+			run := state.Run{
+				ID:           "run-1",
+				SessionID:    session.ID,
+				Prompt:       "loop forever",
+				WorktreePath: session.WorktreePath,
+				State:        "CREATED",
+			}
+			if err := st.CreateRun(run); err != nil {
+				t.Fatalf("create run failed: %v", err)
+			}
+
+			started := time.Now()
+			err := r.executeSessionRun(session, run, sessionRunOptions{
+				Prompt:     run.Prompt,
+				BaseBranch: "main",
+				Limits:     tc.limits,
+			})
+			if err == nil {
+				t.Fatal("expected run to be stopped by its limit")
+			}
+			if elapsed := time.Since(started); elapsed > 4*time.Second {
+				t.Fatalf("tool was not killed promptly: %s", elapsed)
+			}
+
+			got, _, err := st.GetRun(run.ID)
+			if err != nil {
+				t.Fatalf("get run failed: %v", err)
+			}
+			if got.State != tc.state {
+				t.Fatalf("unexpected run state: got %q want %q (error=%q)", got.State, tc.state, got.Error)
+			}
+			if got.Usage == nil || got.Usage.InputTokens != 500 || got.Usage.OutputTokens != 100 || got.Usage.DurationMS <= 0 {
+				t.Fatalf("usage should be recorded for stopped runs: %+v", got.Usage)
+			}
+			events, err := st.ListRunEvents(run.ID, 50)
+			if err != nil {
+				t.Fatalf("list events failed: %v", err)
+			}
+			found := false
+			for _, event := range events {
+				if event.Type == tc.event {
+					found = true
+				}
+			}
+			if !found {
+				t.Fatalf("expected %s event, got %+v", tc.event, events)
+			}
+			gotSession, _, err := st.GetSession(session.ID)
+			if err != nil {
+				t.Fatalf("get session failed: %v", err)
+			}
+			if gotSession.Busy || gotSession.Status != tc.state {
+				t.Fatalf("unexpected session after limit: %+v", gotSession)
+			}
+			if tc.state == "BUDGET_EXCEEDED" && !strings.Contains(got.Error, "600 tokens") {
+				t.Fatalf("budget error should report usage: %q", got.Error)
+			}
+		})
+	}
+}
+
+func newLimitsTestRunner(t *testing.T) (*Runner, *state.Store) {
+	t.Helper()
+	r, err := New(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("new runner failed: %v", err)
+	}
+	st, err := state.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new state store failed: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	r.SetStateStore(st)
+	if _, err := st.UpsertRepo(state.Repo{
+		Name:             "acme/api",
+		URL:              "https://github.com/acme/api.git",
+		Host:             "github.com",
+		BarePath:         "/tmp/acme-api/repo.git",
+		BaseWorktreePath: "/tmp/acme-api/base",
+	}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	return r, st
+}
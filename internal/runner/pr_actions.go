@@ -0,0 +1,244 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/forge"
+	"github.com/darkLord19/foglet/internal/ghcli"
+	"github.com/darkLord19/foglet/internal/proc"
+	"github.com/darkLord19/foglet/internal/projectcfg"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// Pull request actions; tests replace them to avoid the forge.
+var (
+	prCreateFn = createForgePR
+	prReadyFn  = forge.Forge.MarkPRReady
+	prEditFn   = forge.Forge.EditPR
+	prMergeFn  = forge.Forge.MergePR
+)
+
+// ErrPRNotMergeable is returned by MergeSessionPR when the pull request is
+// not in a state Fog will merge.
+var ErrPRNotMergeable = errors.New("pull request cannot be merged")
+
+// CreateSessionPR pushes the branch of a session that has no pull request
+// yet and opens a draft PR for it, titled after the first run that
+// committed and described from all its runs. It is how sessions started
+// without PR mode get one later.
+func (r *Runner) CreateSessionPR(ctx context.Context, sessionID string) (state.Session, error) {
+	if r.state == nil {
+		return state.Session{}, errors.New("state store not configured")
+	}
+	session, found, err := r.state.GetSession(strings.TrimSpace(sessionID))
+	if err != nil {
+		return state.Session{}, err
+	}
+	if !found {
+		return state.Session{}, fmt.Errorf("session %q not found", sessionID)
+	}
+	if pr := strings.TrimSpace(session.PRURL); pr != "" {
+		return state.Session{}, fmt.Errorf("session %q already has a pull request: %s", session.ID, pr)
+	}
+	if session.Busy {
+		return state.Session{}, fmt.Errorf("session %q has a run in progress", session.ID)
+	}
+	runs, err := r.state.ListRuns(session.ID)
+	if err != nil {
+		return state.Session{}, err
+	}
+	// Runs are listed newest first.
+	var first, latest state.Run
+	for _, run := range runs {
+		if strings.TrimSpace(run.CommitSHA) == "" {
+			continue
+		}
+		if latest.ID == "" {
+			latest = run
+		}
+		first = run
+	}
+	if first.ID == "" {
+		return state.Session{}, fmt.Errorf("session %q has no commits to open a pull request for", session.ID)
+	}
+
+	opts := r.followUpRunOptions(session, "")
+	f, err := r.sessionForge(session)
+	if err != nil {
+		return state.Session{}, err
+	}
+	if err := r.pushBranch(proc.WithEnv(ctx, f.GitEnv()), session.WorktreePath, session.Branch, true); err != nil {
+		return state.Session{}, err
+	}
+	prURL, err := prCreateFn(f, ctx, session.WorktreePath, forge.NewPR{
+		Title: resolvePRTitle("", first.Prompt),
+		Body:  sessionPRBody(opts.PRTemplate, session, runs),
+		Base:  opts.BaseBranch,
+		Head:  session.Branch,
+		Draft: true,
+	})
+	if err != nil {
+		return state.Session{}, err
+	}
+	if err := r.state.SetSessionPRURL(session.ID, prURL); err != nil {
+		return state.Session{}, err
+	}
+	// Seed the PR status until the first sync reads it from the forge.
+	_ = r.state.SetSessionPRStatus(session.ID, state.PRStatus{State: "OPEN", Draft: true})
+	_ = r.state.AppendRunEvent(state.RunEvent{
+		RunID:   latest.ID,
+		Type:    "pr",
+		Message: "Draft PR created: " + prURL,
+	})
+	session, _, err = r.state.GetSession(session.ID)
+	return session, err
+}
+
+// createForgePR checks the forge CLI before creating a pull request so a
+// missing login is reported as such.
+func createForgePR(f forge.Forge, ctx context.Context, workdir string, pr forge.NewPR) (string, error) {
+	if err := f.Check(ctx); err != nil {
+		return "", err
+	}
+	return f.CreatePR(ctx, workdir, pr)
+}
+
+// MarkPRReady takes a session's draft pull request out of draft, requests
+// the reviewers and adds the labels listed in the repo's project file, and
+// returns the session with its refreshed PR status.
+func (r *Runner) MarkPRReady(ctx context.Context, sessionID string) (state.Session, error) {
+	session, err := r.sessionWithPR(sessionID)
+	if err != nil {
+		return state.Session{}, err
+	}
+	f, err := r.sessionForge(session)
+	if err != nil {
+		return state.Session{}, err
+	}
+	if err := prReadyFn(f, ctx, session.WorktreePath, session.PRURL); err != nil {
+		return state.Session{}, err
+	}
+	project := r.sessionProjectConfig(session)
+	if err := prEditFn(f, ctx, session.WorktreePath, session.PRURL, forge.PREdit{
+		Reviewers: project.PR.Reviewers,
+		Labels:    project.PR.Labels,
+	}); err != nil {
+		return state.Session{}, err
+	}
+	return r.refreshSessionPR(ctx, session)
+}
+
+// UpdatePRBody regenerates the description of a session's pull request
+// from the prompts and commit messages of all its runs and returns it. With
+// dryRun the PR is left untouched.
+func (r *Runner) UpdatePRBody(ctx context.Context, sessionID string, dryRun bool) (string, error) {
+	session, err := r.sessionWithPR(sessionID)
+	if err != nil {
+		return "", err
+	}
+	runs, err := r.state.ListRuns(session.ID)
+	if err != nil {
+		return "", err
+	}
+	body := sessionPRBody(r.sessionProjectConfig(session).PR.Template, session, runs)
+	if dryRun {
+		return body, nil
+	}
+	f, err := r.sessionForge(session)
+	if err != nil {
+		return "", err
+	}
+	if err := prEditFn(f, ctx, session.WorktreePath, session.PRURL, forge.PREdit{Body: body}); err != nil {
+		return "", err
+	}
+	return body, nil
+}
+
+// MergeSessionPR merges a session's pull request once it is open, out of
+// draft, free of conflicts and its checks pass. An empty method uses the
+// project's pr.merge_method, then squash. The session moves to MERGED.
+func (r *Runner) MergeSessionPR(ctx context.Context, sessionID, method string) (state.Session, error) {
+	session, err := r.sessionWithPR(sessionID)
+	if err != nil {
+		return state.Session{}, err
+	}
+	method = strings.ToLower(strings.TrimSpace(method))
+	if method == "" {
+		method = r.sessionProjectConfig(session).PR.MergeMethod
+	}
+	if method == "" {
+		method = ghcli.MergeMethodSquash
+	}
+	if !ghcli.ValidMergeMethod(method) {
+		return state.Session{}, fmt.Errorf("invalid merge method %q (use squash, rebase or merge)", method)
+	}
+	// A run may still push commits the checks have not seen.
+	if session.Busy {
+		return state.Session{}, fmt.Errorf("%w: session %q has a run in progress", ErrPRNotMergeable, session.ID)
+	}
+
+	session, err = r.refreshSessionPR(ctx, session)
+	if err != nil {
+		return state.Session{}, err
+	}
+	if err := checkMergeable(session.PR); err != nil {
+		return state.Session{}, err
+	}
+	f, err := r.sessionForge(session)
+	if err != nil {
+		return state.Session{}, err
+	}
+	if err := prMergeFn(f, ctx, session.WorktreePath, session.PRURL, method); err != nil {
+		return state.Session{}, err
+	}
+	return r.refreshSessionPR(ctx, session)
+}
+
+// checkMergeable reports why a pull request should not be merged yet.
+// Repos without checks are mergeable.
+func checkMergeable(pr *state.PRStatus) error {
+	switch {
+	case pr == nil:
+		return fmt.Errorf("%w: status unknown", ErrPRNotMergeable)
+	case pr.State != "OPEN":
+		return fmt.Errorf("%w: it is %s", ErrPRNotMergeable, strings.ToLower(pr.State))
+	case pr.Draft:
+		return fmt.Errorf("%w: it is a draft; mark it ready for review first", ErrPRNotMergeable)
+	case pr.Mergeable == "CONFLICTING":
+		return fmt.Errorf("%w: it has merge conflicts", ErrPRNotMergeable)
+	case pr.Checks.State == ghcli.ChecksFailing:
+		return fmt.Errorf("%w: %d check(s) failing", ErrPRNotMergeable, pr.Checks.Failed)
+	case pr.Checks.State == ghcli.ChecksPending:
+		return fmt.Errorf("%w: %d check(s) pending", ErrPRNotMergeable, pr.Checks.Pending)
+	}
+	return nil
+}
+
+// sessionWithPR loads a session that has a pull request.
+func (r *Runner) sessionWithPR(sessionID string) (state.Session, error) {
+	if r.state == nil {
+		return state.Session{}, errors.New("state store not configured")
+	}
+	session, found, err := r.state.GetSession(strings.TrimSpace(sessionID))
+	if err != nil {
+		return state.Session{}, err
+	}
+	if !found {
+		return state.Session{}, fmt.Errorf("session %q not found", sessionID)
+	}
+	if strings.TrimSpace(session.PRURL) == "" {
+		return state.Session{}, fmt.Errorf("session %q has no pull request", session.ID)
+	}
+	return session, nil
+}
+
+// sessionProjectConfig loads the project file of the session's repo. A
+// broken file only loses PR conventions here, so it is not an error.
+func (r *Runner) sessionProjectConfig(session state.Session) projectcfg.Config {
+	repo, _, _ := r.state.GetRepoByName(session.RepoName)
+	project, _ := projectcfg.Load(repo.BaseWorktreePath)
+	return project
+}
@@ -0,0 +1,251 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/forge"
+	"github.com/darkLord19/foglet/internal/ghcli"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func newPRActionsTestRunner(t *testing.T) (*Runner, *state.Store) {
+	t.Helper()
+	r, st := newLimitsTestRunner(t)
+	base := t.TempDir()
+	project := "pr:\n  template: \"## Summary\"\n  reviewers: [alice, acme/core]\n  labels: [fog]\n  merge_method: rebase\n"
+	if err := os.WriteFile(filepath.Join(base, ".fog.yaml"), []byte(project), 0o644); err != nil {
+		t.Fatalf("write project file failed: %v", err)
+	}
+	if _, err := st.UpsertRepo(state.Repo{
+		Name:             "acme/api",
+		URL:              "https://github.com/acme/api.git",
+		Host:             "github.com",
+		BarePath:         "/tmp/acme-api/repo.git",
+		BaseWorktreePath: base,
+	}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	now := time.Now().UTC()
+	if err := st.CreateSession(state.Session{
+		ID:           "session-1",
+		RepoName:     "acme/api",
+		Branch:       "fog/otp",
+		WorktreePath: "/tmp/wt",
+		Tool:         "claude",
+		PRURL:        "https://github.com/acme/api/pull/7",
+		Status:       "COMPLETED",
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	runs := []state.Run{
+		{ID: "run-1", Prompt: "Add OTP login", CommitSHA: "0123456789abcdef", CommitMsg: "Add OTP login\n\nSends codes by SMS."},
+		{ID: "run-2", Prompt: "Explain the flow", State: "COMPLETED"},
+		{ID: "run-3", Prompt: "Rate limit OTP requests", CommitSHA: "fedcba9876543210", CommitMsg: "Rate limit OTP requests"},
+	}
+	for i, run := range runs {
+		run.SessionID = "session-1"
+		run.WorktreePath = "/tmp/wt"
+		run.State = "COMPLETED"
+		run.CreatedAt = now.Add(time.Duration(i) * time.Second)
+		if err := st.CreateRun(run); err != nil {
+			t.Fatalf("create run failed: %v", err)
+		}
+	}
+	return r, st
+}
+
+func TestMarkPRReadyRequestsConfiguredReviewers(t *testing.T) {
+	r, _ := newPRActionsTestRunner(t)
+
+	origReady, origEdit, origStatus := prReadyFn, prEditFn, prStatusFn
+	t.Cleanup(func() { prReadyFn, prEditFn, prStatusFn = origReady, origEdit, origStatus })
+
+	var readied string
+	var edit ghcli.PREdit
+	prReadyFn = func(_ forge.Forge, ctx context.Context, repoPath, prURL string) error {
+		readied = prURL
+		return nil
+	}
+	prEditFn = func(_ forge.Forge, ctx context.Context, repoPath, prURL string, e ghcli.PREdit) error {
+		edit = e
+		return nil
+	}
+	prStatusFn = func(_ forge.Forge, ctx context.Context, repoPath, prURL string) (ghcli.PRStatus, error) {
+		return ghcli.PRStatus{State: "OPEN"}, nil
+	}
+
+	session, err := r.MarkPRReady(context.Background(), "session-1")
+	if err != nil {
+		t.Fatalf("mark ready failed: %v", err)
+	}
+	if readied != "https://github.com/acme/api/pull/7" {
+		t.Fatalf("unexpected ready call: %q", readied)
+	}
+	want := ghcli.PREdit{Reviewers: []string{"alice", "acme/core"}, Labels: []string{"fog"}}
+	if !reflect.DeepEqual(edit, want) {
+		t.Fatalf("unexpected edit: got %+v want %+v", edit, want)
+	}
+	if session.PR == nil || session.PR.Draft {
+		t.Fatalf("expected refreshed non-draft PR, got %+v", session.PR)
+	}
+
+	if _, err := r.MarkPRReady(context.Background(), "missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
+func TestUpdatePRBodyListsCommittedRuns(t *testing.T) {
+	r, _ := newPRActionsTestRunner(t)
+
+	origEdit := prEditFn
+	t.Cleanup(func() { prEditFn = origEdit })
+	var edited string
+	prEditFn = func(_ forge.Forge, ctx context.Context, repoPath, prURL string, e ghcli.PREdit) error {
+		edited = e.Body
+		return nil
+	}
+
+	body, err := r.UpdatePRBody(context.Background(), "session-1", true)
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if edited != "" {
+		t.Fatalf("dry run should not edit the PR, got %q", edited)
+	}
+	for _, want := range []string{
+		"## Summary\n\n---\n\nGenerated by Fog session",
+		"1. Add OTP login (0123456)\n   Sends codes by SMS.\n   Prompt:\n   > Add OTP login",
+		"2. Rate limit OTP requests (fedcba9)",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("body missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "Explain the flow") {
+		t.Fatalf("runs without commits should be left out:\n%s", body)
+	}
+
+	if _, err := r.UpdatePRBody(context.Background(), "session-1", false); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if edited != body {
+		t.Fatalf("unexpected edited body:\n%s", edited)
+	}
+}
+
+func TestCreateSessionPRPushesAndOpensDraft(t *testing.T) {
+	r, st := newPRActionsTestRunner(t)
+
+	worktree := initGitRepo(t, "main")
+	origin := filepath.Join(t.TempDir(), "origin.git")
+	runGit(t, worktree, "init", "--bare", origin)
+	runGit(t, worktree, "remote", "add", "origin", origin)
+	runGit(t, worktree, "checkout", "-b", "fog/otp-fork")
+
+	if err := st.CreateSession(state.Session{
+		ID:           "session-2",
+		RepoName:     "acme/api",
+		Branch:       "fog/otp-fork",
+		WorktreePath: worktree,
+		Tool:         "claude",
+		Status:       "COMPLETED",
+	}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	if _, err := r.CreateSessionPR(context.Background(), "session-2"); err == nil || !strings.Contains(err.Error(), "no commits") {
+		t.Fatalf("expected no commits error, got %v", err)
+	}
+	if err := st.CreateRun(state.Run{ID: "run-4", SessionID: "session-2", Prompt: "Add OTP fallback", WorktreePath: worktree, State: "COMPLETED", CommitSHA: "abc1234", CommitMsg: "Add OTP fallback"}); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+
+	origCreate := prCreateFn
+	t.Cleanup(func() { prCreateFn = origCreate })
+	var created forge.NewPR
+	prCreateFn = func(_ forge.Forge, ctx context.Context, workdir string, pr forge.NewPR) (string, error) {
+		created = pr
+		return "https://github.com/acme/api/pull/8", nil
+	}
+
+	session, err := r.CreateSessionPR(context.Background(), "session-2")
+	if err != nil {
+		t.Fatalf("create PR failed: %v", err)
+	}
+	if session.PRURL != "https://github.com/acme/api/pull/8" || session.PR == nil || !session.PR.Draft {
+		t.Fatalf("unexpected session PR: %q %+v", session.PRURL, session.PR)
+	}
+	if created.Title != "feat: Add OTP fallback" || created.Head != "fog/otp-fork" || created.Base != "main" || !created.Draft {
+		t.Fatalf("unexpected PR: %+v", created)
+	}
+	if !strings.HasPrefix(created.Body, "## Summary") {
+		t.Fatalf("body should use the project template:\n%s", created.Body)
+	}
+	runGit(t, origin, "rev-parse", "--verify", "refs/heads/fog/otp-fork")
+
+	if _, err := r.CreateSessionPR(context.Background(), "session-2"); err == nil || !strings.Contains(err.Error(), "already has a pull request") {
+		t.Fatalf("expected existing PR error, got %v", err)
+	}
+}
+
+func TestMergeSessionPRRequiresPassingChecks(t *testing.T) {
+	r, st := newPRActionsTestRunner(t)
+
+	origMerge, origStatus := prMergeFn, prStatusFn
+	t.Cleanup(func() { prMergeFn, prStatusFn = origMerge, origStatus })
+
+	status := ghcli.PRStatus{State: "OPEN", Mergeable: "MERGEABLE", Checks: ghcli.CheckSummary{State: ghcli.ChecksFailing, Failed: 1}}
+	prStatusFn = func(_ forge.Forge, ctx context.Context, repoPath, prURL string) (ghcli.PRStatus, error) {
+		return status, nil
+	}
+	var method string
+	prMergeFn = func(_ forge.Forge, ctx context.Context, repoPath, prURL, m string) error {
+		method = m
+		status = ghcli.PRStatus{State: "MERGED"}
+		return nil
+	}
+
+	if _, err := r.MergeSessionPR(context.Background(), "session-1", ""); !errors.Is(err, ErrPRNotMergeable) {
+		t.Fatalf("expected not mergeable error for failing checks, got %v", err)
+	}
+	status.Checks = ghcli.CheckSummary{}
+	status.IsDraft = true
+	if _, err := r.MergeSessionPR(context.Background(), "session-1", ""); !errors.Is(err, ErrPRNotMergeable) {
+		t.Fatalf("expected not mergeable error for draft, got %v", err)
+	}
+	status.IsDraft = false
+	if _, err := r.MergeSessionPR(context.Background(), "session-1", "fast-forward"); err == nil {
+		t.Fatal("expected invalid merge method error")
+	}
+	if err := st.SetSessionBusy("session-1", true); err != nil {
+		t.Fatalf("set busy failed: %v", err)
+	}
+	if _, err := r.MergeSessionPR(context.Background(), "session-1", ""); !errors.Is(err, ErrPRNotMergeable) {
+		t.Fatalf("expected not mergeable error for busy session, got %v", err)
+	}
+	if err := st.SetSessionBusy("session-1", false); err != nil {
+		t.Fatalf("clear busy failed: %v", err)
+	}
+	if method != "" {
+		t.Fatalf("merge should not have run, got method %q", method)
+	}
+
+	session, err := r.MergeSessionPR(context.Background(), "session-1", "")
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if method != ghcli.MergeMethodRebase {
+		t.Fatalf("expected project merge method rebase, got %q", method)
+	}
+	if session.Status != state.SessionStatusMerged || session.PR.State != "MERGED" {
+		t.Fatalf("expected merged session, got status=%s pr=%+v", session.Status, session.PR)
+	}
+}
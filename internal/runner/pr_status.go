@@ -0,0 +1,178 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/forge"
+	"github.com/darkLord19/foglet/internal/ghcli"
+	"github.com/darkLord19/foglet/internal/state"
+	"github.com/darkLord19/foglet/internal/task"
+)
+
+// prStatusFn loads pull request status; tests replace it to avoid the forge.
+var prStatusFn = forge.Forge.PRStatus
+
+// DefaultPRStatusInterval is how often fogd refreshes the status of open
+// session pull requests.
+const DefaultPRStatusInterval = 5 * time.Minute
+
+// RefreshPRStatus syncs the state of a session's pull request from GitHub
+// and moves an idle session to MERGED or CLOSED when its PR is. A reopened
+// PR returns the session to the state of its latest run.
+func (r *Runner) RefreshPRStatus(ctx context.Context, sessionID string) (state.Session, error) {
+	session, err := r.sessionWithPR(sessionID)
+	if err != nil {
+		return state.Session{}, err
+	}
+	return r.refreshSessionPR(ctx, session)
+}
+
+func (r *Runner) refreshSessionPR(ctx context.Context, session state.Session) (state.Session, error) {
+	f, err := r.sessionForge(session)
+	if err != nil {
+		return session, err
+	}
+	status, err := prStatusFn(f, ctx, session.WorktreePath, session.PRURL)
+	if err != nil {
+		return session, err
+	}
+	syncedAt := time.Now().UTC()
+	pr := state.PRStatus{
+		State:          status.State,
+		Draft:          status.IsDraft,
+		Mergeable:      status.Mergeable,
+		ReviewDecision: status.ReviewDecision,
+		Checks: state.PRChecks{
+			State:   status.Checks.State,
+			Passed:  status.Checks.Passed,
+			Failed:  status.Checks.Failed,
+			Pending: status.Checks.Pending,
+		},
+		SyncedAt: &syncedAt,
+	}
+	if err := r.state.SetSessionPRStatus(session.ID, pr); err != nil {
+		return session, err
+	}
+	if err := r.applyPRTransition(session, pr.State); err != nil {
+		return session, err
+	}
+	updated, found, err := r.state.GetSession(session.ID)
+	if err != nil {
+		return session, err
+	}
+	if !found {
+		return session, fmt.Errorf("session %q disappeared", session.ID)
+	}
+	return updated, nil
+}
+
+// applyPRTransition updates the session status to follow its PR. Busy
+// sessions are left alone; their run owns the status until it finishes and
+// the next sync applies the transition.
+func (r *Runner) applyPRTransition(session state.Session, prState string) error {
+	if session.Busy {
+		return nil
+	}
+	var target string
+	switch prState {
+	case "MERGED":
+		target = state.SessionStatusMerged
+	case "CLOSED":
+		target = state.SessionStatusClosed
+	case "OPEN":
+		if session.Status != state.SessionStatusMerged && session.Status != state.SessionStatusClosed {
+			return nil
+		}
+		target = string(task.StateCompleted)
+		if latest, found, err := r.state.GetLatestRun(session.ID); err == nil && found {
+			target = latest.State
+		}
+	default:
+		return nil
+	}
+	if session.Status == target {
+		return nil
+	}
+	return r.state.UpdateSessionStatus(session.ID, target)
+}
+
+// RunPRStatusSync refreshes the PR status of every unarchived session whose
+// PR is not merged yet, until ctx is cancelled.
+func (r *Runner) RunPRStatusSync(ctx context.Context, interval time.Duration) error {
+	if r.state == nil {
+		return errors.New("state store not configured")
+	}
+	if interval <= 0 {
+		interval = DefaultPRStatusInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.refreshOpenPRs(ctx); err != nil {
+			log.Printf("pr status: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Runner) refreshOpenPRs(ctx context.Context) error {
+	sessions, err := r.state.ListSessions()
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if strings.TrimSpace(session.PRURL) == "" || session.ArchivedAt != nil {
+			continue
+		}
+		// A merged PR cannot change state again.
+		if session.PR != nil && session.PR.State == "MERGED" {
+			continue
+		}
+		if _, err := r.refreshSessionPR(ctx, session); err != nil {
+			// Sessions on a forge whose CLI is missing wait until it is installed.
+			if errors.Is(err, ghcli.ErrGhNotFound) || errors.Is(err, forge.ErrGlabNotFound) {
+				continue
+			}
+			log.Printf("pr status %s: %v", session.ID, err)
+		}
+	}
+	return nil
+}
+
+// sessionPRState returns the PR state used for cleanup decisions. A merged
+// PR is final, so the synced state is trusted; anything else is looked up
+// again and falls back to the synced state when the forge is unreachable.
+func (r *Runner) sessionPRState(ctx context.Context, session state.Session) (string, error) {
+	if session.PR != nil && session.PR.State == "MERGED" {
+		return session.PR.State, nil
+	}
+	prState, err := r.lookupPRState(ctx, session)
+	if err != nil {
+		if session.PR != nil {
+			return session.PR.State, nil
+		}
+		return "", err
+	}
+	return prState, nil
+}
+
+// lookupPRState asks the session's forge for the state of its pull request.
+func (r *Runner) lookupPRState(ctx context.Context, session state.Session) (string, error) {
+	f, err := r.sessionForge(session)
+	if err != nil {
+		return "", err
+	}
+	return prStateFn(f, ctx, session.WorktreePath, session.PRURL)
+}
@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/forge"
+	"github.com/darkLord19/foglet/internal/ghcli"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestRefreshPRStatusDrivesSessionStatus(t *testing.T) {
+	r, st := newLimitsTestRunner(t)
+	if err := st.CreateSession(state.Session{
+		ID:           "session-1",
+		RepoName:     "acme/api",
+		Branch:       "fog/otp",
+		WorktreePath: "/tmp/wt",
+		Tool:         "claude",
+		PRURL:        "https://github.com/acme/api/pull/7",
+		Status:       "COMPLETED",
+		CreatedAt:    time.Now().UTC(),
+		UpdatedAt:    time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	if err := st.CreateRun(state.Run{ID: "run-1", SessionID: "session-1", Prompt: "p", WorktreePath: "/tmp/wt", State: "COMPLETED"}); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+
+	origStatus := prStatusFn
+	t.Cleanup(func() { prStatusFn = origStatus })
+	next := ghcli.PRStatus{State: "OPEN", IsDraft: true, Mergeable: "MERGEABLE", Checks: ghcli.CheckSummary{State: ghcli.ChecksPending, Pending: 2}}
+	prStatusFn = func(_ forge.Forge, ctx context.Context, repoPath, prURL string) (ghcli.PRStatus, error) {
+		return next, nil
+	}
+
+	session, err := r.RefreshPRStatus(context.Background(), "session-1")
+	if err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if session.Status != "COMPLETED" || session.PR == nil || !session.PR.Draft || session.PR.Checks.Pending != 2 {
+		t.Fatalf("unexpected session after open sync: status=%s pr=%+v", session.Status, session.PR)
+	}
+
+	next = ghcli.PRStatus{State: "MERGED", Checks: ghcli.CheckSummary{State: ghcli.ChecksPassing, Passed: 2}}
+	session, err = r.RefreshPRStatus(context.Background(), "session-1")
+	if err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if session.Status != state.SessionStatusMerged || session.PR.State != "MERGED" {
+		t.Fatalf("expected merged session, got status=%s pr=%+v", session.Status, session.PR)
+	}
+
+	// Reopening returns the session to its latest run state.
+	next = ghcli.PRStatus{State: "OPEN"}
+	session, err = r.RefreshPRStatus(context.Background(), "session-1")
+	if err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if session.Status != "COMPLETED" {
+		t.Fatalf("expected reopened session to be COMPLETED, got %s", session.Status)
+	}
+
+	if err := st.SetSessionBusy("session-1", true); err != nil {
+		t.Fatalf("set busy failed: %v", err)
+	}
+	next = ghcli.PRStatus{State: "CLOSED"}
+	session, err = r.RefreshPRStatus(context.Background(), "session-1")
+	if err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if session.Status != "COMPLETED" || session.PR.State != "CLOSED" {
+		t.Fatalf("busy session should keep its status, got status=%s pr=%+v", session.Status, session.PR)
+	}
+}
+
+func TestSessionPRStateTrustsMergedAndFallsBack(t *testing.T) {
+	r, _ := newLimitsTestRunner(t)
+
+	origState := prStateFn
+	t.Cleanup(func() { prStateFn = origState })
+	calls := 0
+	prStateFn = func(_ forge.Forge, ctx context.Context, repoPath, prURL string) (string, error) {
+		calls++
+		return "", errors.New("offline")
+	}
+
+	merged := state.Session{ID: "s1", PRURL: "https://github.com/acme/api/pull/1", PR: &state.PRStatus{State: "MERGED"}}
+	if got, err := r.sessionPRState(context.Background(), merged); err != nil || got != "MERGED" || calls != 0 {
+		t.Fatalf("expected cached MERGED without lookup, got %q err=%v calls=%d", got, err, calls)
+	}
+
+	closed := state.Session{ID: "s2", PRURL: "https://github.com/acme/api/pull/2", PR: &state.PRStatus{State: "CLOSED"}}
+	if got, err := r.sessionPRState(context.Background(), closed); err != nil || got != "CLOSED" || calls != 1 {
+		t.Fatalf("expected fallback to synced CLOSED, got %q err=%v calls=%d", got, err, calls)
+	}
+
+	unsynced := state.Session{ID: "s3", PRURL: "https://github.com/acme/api/pull/3"}
+	if _, err := r.sessionPRState(context.Background(), unsynced); err == nil {
+		t.Fatal("expected lookup error without synced state")
+	}
+}
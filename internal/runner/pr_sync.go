@@ -0,0 +1,261 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/forge"
+	"github.com/darkLord19/foglet/internal/ghcli"
+	"github.com/darkLord19/foglet/internal/state"
+	"github.com/darkLord19/foglet/internal/task"
+)
+
+// prFeedbackFn loads pull request feedback; tests replace it to avoid the
+// forge.
+var prFeedbackFn = forge.Forge.PRFeedback
+
+// Limits that keep a feedback prompt readable when a PR has a long thread
+// or a check with hundreds of annotations.
+const (
+	maxFeedbackBodyChars   = 2000
+	maxFeedbackAnnotations = 20
+)
+
+// PRSyncOptions controls SyncPRFeedback.
+type PRSyncOptions struct {
+	// DryRun builds the follow-up prompt without starting a run or marking
+	// feedback as addressed.
+	DryRun bool
+	// Async starts the follow-up run in the background.
+	Async bool
+}
+
+// PRSyncResult reports the unaddressed feedback found on a session's pull
+// request and the follow-up run created for it, if any.
+type PRSyncResult struct {
+	SessionID    string              `json:"session_id"`
+	PRURL        string              `json:"pr_url"`
+	Comments     []ghcli.PRComment   `json:"comments"`
+	FailedChecks []ghcli.FailedCheck `json:"failed_checks"`
+	Prompt       string              `json:"prompt,omitempty"`
+	Run          *state.Run          `json:"run,omitempty"`
+}
+
+// SyncPRFeedback fetches review comments and failing checks for the
+// session's pull request and turns the ones not yet handed to a run into a
+// follow-up run on the same session. Feedback handed to a run that later
+// fails is picked up again by the next sync, up to
+// state.MaxPRFeedbackAttempts runs per item.
+func (r *Runner) SyncPRFeedback(ctx context.Context, sessionID string, opts PRSyncOptions) (PRSyncResult, error) {
+	if r.state == nil {
+		return PRSyncResult{}, errors.New("state store not configured")
+	}
+	session, found, err := r.state.GetSession(strings.TrimSpace(sessionID))
+	if err != nil {
+		return PRSyncResult{}, err
+	}
+	if !found {
+		return PRSyncResult{}, fmt.Errorf("session %q not found", sessionID)
+	}
+	if strings.TrimSpace(session.PRURL) == "" {
+		return PRSyncResult{}, fmt.Errorf("session %q has no pull request", session.ID)
+	}
+
+	f, err := r.sessionForge(session)
+	if err != nil {
+		return PRSyncResult{}, err
+	}
+	feedback, err := prFeedbackFn(f, ctx, session.WorktreePath, session.PRURL)
+	if err != nil {
+		return PRSyncResult{}, err
+	}
+	addressed, err := r.state.AddressedPRFeedback(session.ID)
+	if err != nil {
+		return PRSyncResult{}, err
+	}
+
+	result := PRSyncResult{
+		SessionID:    session.ID,
+		PRURL:        session.PRURL,
+		Comments:     []ghcli.PRComment{},
+		FailedChecks: []ghcli.FailedCheck{},
+	}
+	var keys []string
+	for _, comment := range feedback.Comments {
+		key := comment.Kind + ":" + strconv.FormatInt(comment.ID, 10)
+		if addressed[key] || strings.TrimSpace(comment.Body) == "" {
+			continue
+		}
+		result.Comments = append(result.Comments, comment)
+		keys = append(keys, key)
+	}
+	for _, check := range feedback.FailedChecks {
+		key := "check:" + strconv.FormatInt(check.ID, 10)
+		if addressed[key] {
+			continue
+		}
+		result.FailedChecks = append(result.FailedChecks, check)
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	result.Prompt = prFeedbackPrompt(session.PRURL, result.Comments, result.FailedChecks)
+	if opts.DryRun {
+		return result, nil
+	}
+
+	session, run, execOpts, err := r.prepareFollowUpRun(session.ID, result.Prompt)
+	if err != nil {
+		return PRSyncResult{}, err
+	}
+	if err := r.state.RecordPRFeedback(session.ID, run.ID, keys); err != nil {
+		return PRSyncResult{}, err
+	}
+	_ = r.state.AppendRunEvent(state.RunEvent{
+		RunID:   run.ID,
+		Type:    "pr_feedback",
+		Message: fmt.Sprintf("Addressing %d review comment(s) and %d failing check(s) from %s", len(result.Comments), len(result.FailedChecks), session.PRURL),
+	})
+
+	switch {
+	case run.State == string(task.StateQueued):
+	case opts.Async:
+		go func(s state.Session, ru state.Run, eo sessionRunOptions) {
+			_ = r.executeSessionRun(s, ru, eo)
+		}(session, run, execOpts)
+	default:
+		runErr := r.executeSessionRun(session, run, execOpts)
+		updated, found, err := r.state.GetRun(run.ID)
+		if err != nil {
+			return PRSyncResult{}, err
+		}
+		if found {
+			run = updated
+		}
+		result.Run = &run
+		return result, runErr
+	}
+	result.Run = &run
+	return result, nil
+}
+
+// RunPRSync periodically syncs feedback for every idle, unarchived session
+// whose pull request is still open, until ctx is cancelled.
+func (r *Runner) RunPRSync(ctx context.Context, interval time.Duration) error {
+	if r.state == nil {
+		return errors.New("state store not configured")
+	}
+	if interval <= 0 {
+		return errors.New("pr sync interval must be positive")
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		r.syncOpenPRs(ctx)
+	}
+}
+
+func (r *Runner) syncOpenPRs(ctx context.Context) {
+	sessions, err := r.state.ListSessions()
+	if err != nil {
+		log.Printf("pr sync: %v", err)
+		return
+	}
+	for _, session := range sessions {
+		if ctx.Err() != nil {
+			return
+		}
+		// Busy sessions are left alone so feedback is not queued behind a
+		// run that may already address it.
+		if session.Busy || session.ArchivedAt != nil || strings.TrimSpace(session.PRURL) == "" {
+			continue
+		}
+		prState, err := r.lookupPRState(ctx, session)
+		if err != nil {
+			log.Printf("pr sync %s: %v", session.ID, err)
+			continue
+		}
+		if prState != "OPEN" {
+			continue
+		}
+		result, err := r.SyncPRFeedback(ctx, session.ID, PRSyncOptions{Async: true})
+		if errors.Is(err, forge.ErrUnsupported) {
+			continue
+		}
+		if err != nil {
+			log.Printf("pr sync %s: %v", session.ID, err)
+			continue
+		}
+		if result.Run != nil {
+			log.Printf("pr sync %s: follow-up run %s for %d comment(s), %d failing check(s)",
+				session.ID, result.Run.ID, len(result.Comments), len(result.FailedChecks))
+		}
+	}
+}
+
+// prFeedbackPrompt turns review feedback into follow-up instructions.
+func prFeedbackPrompt(prURL string, comments []ghcli.PRComment, checks []ghcli.FailedCheck) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Address the following feedback on pull request %s.\n", prURL)
+	b.WriteString("Make the requested changes. If you disagree with a comment, leave the code as is and explain why in your final message.\n")
+
+	if len(comments) > 0 {
+		b.WriteString("\nReview comments:\n")
+		for i, c := range comments {
+			fmt.Fprintf(&b, "%d. @%s", i+1, c.Author)
+			switch {
+			case c.Path != "" && c.Line > 0:
+				fmt.Fprintf(&b, " on %s:%d", c.Path, c.Line)
+			case c.Path != "":
+				fmt.Fprintf(&b, " on %s", c.Path)
+			case c.Kind == ghcli.CommentKindReview && c.State == "CHANGES_REQUESTED":
+				b.WriteString(" (requested changes)")
+			}
+			b.WriteString(":\n")
+			for _, line := range strings.Split(truncate(c.Body, maxFeedbackBodyChars), "\n") {
+				b.WriteString("   " + line + "\n")
+			}
+		}
+	}
+
+	if len(checks) > 0 {
+		b.WriteString("\nFailing checks:\n")
+		for _, c := range checks {
+			fmt.Fprintf(&b, "- %s (%s)", c.Name, c.Conclusion)
+			if c.Summary != "" {
+				b.WriteString(": " + firstLine(c.Summary))
+			}
+			b.WriteString("\n")
+			for i, a := range c.Annotations {
+				if i == maxFeedbackAnnotations {
+					fmt.Fprintf(&b, "  - ... %d more\n", len(c.Annotations)-i)
+					break
+				}
+				location := a.Path
+				if a.StartLine > 0 {
+					location = fmt.Sprintf("%s:%d", a.Path, a.StartLine)
+				}
+				message := truncate(strings.TrimSpace(a.Title+" "+a.Message), maxFeedbackBodyChars)
+				fmt.Fprintf(&b, "  - %s: %s\n", location, strings.ReplaceAll(message, "\n", " "))
+			}
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(line)
+}
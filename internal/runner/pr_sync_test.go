@@ -0,0 +1,84 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/forge"
+	"github.com/darkLord19/foglet/internal/ghcli"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestSyncPRFeedbackQueuesFollowUpOnce(t *testing.T) {
+	r, st := newLimitsTestRunner(t)
+	if err := st.CreateSession(state.Session{
+		ID:           "session-1",
+		RepoName:     "acme/api",
+		Branch:       "fog/otp",
+		WorktreePath: "/tmp/wt",
+		Tool:         "claude",
+		PRURL:        "https://github.com/acme/api/pull/7",
+		Status:       "AI_RUNNING",
+		Busy:         true,
+		CreatedAt:    time.Now().UTC(),
+		UpdatedAt:    time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+
+	origFeedback := prFeedbackFn
+	t.Cleanup(func() { prFeedbackFn = origFeedback })
+	prFeedbackFn = func(_ forge.Forge, ctx context.Context, repoPath, prURL string) (ghcli.PRFeedback, error) {
+		return ghcli.PRFeedback{
+			Comments: []ghcli.PRComment{
+				{ID: 11, Kind: ghcli.CommentKindReviewInline, Author: "alice", Body: "Use a constant here", Path: "otp.go", Line: 12},
+				{ID: 21, Kind: ghcli.CommentKindReview, Author: "alice", Body: "Needs tests", State: "CHANGES_REQUESTED"},
+			},
+			FailedChecks: []ghcli.FailedCheck{{
+				ID: 41, Name: "test", Conclusion: "failure", Summary: "2 tests failed",
+				Annotations: []ghcli.CheckAnnotation{{Path: "otp_test.go", StartLine: 30, Message: "expected 200, got 500"}},
+			}},
+		}, nil
+	}
+
+	dry, err := r.SyncPRFeedback(context.Background(), "session-1", PRSyncOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("dry-run sync failed: %v", err)
+	}
+	if dry.Run != nil || len(dry.Comments) != 2 || len(dry.FailedChecks) != 1 {
+		t.Fatalf("unexpected dry-run result: %+v", dry)
+	}
+	for _, want := range []string{"@alice on otp.go:12", "Use a constant here", "(requested changes)", "- test (failure): 2 tests failed", "otp_test.go:30: expected 200, got 500"} {
+		if !strings.Contains(dry.Prompt, want) {
+			t.Fatalf("prompt missing %q:\n%s", want, dry.Prompt)
+		}
+	}
+
+	result, err := r.SyncPRFeedback(context.Background(), "session-1", PRSyncOptions{})
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if result.Run == nil || result.Run.State != "QUEUED" || result.Run.Prompt != dry.Prompt {
+		t.Fatalf("expected queued follow-up with feedback prompt, got %+v", result.Run)
+	}
+
+	again, err := r.SyncPRFeedback(context.Background(), "session-1", PRSyncOptions{})
+	if err != nil {
+		t.Fatalf("second sync failed: %v", err)
+	}
+	if again.Run != nil || len(again.Comments) != 0 || len(again.FailedChecks) != 0 {
+		t.Fatalf("expected no new feedback on second sync, got %+v", again)
+	}
+}
+
+func TestSyncPRFeedbackRequiresPR(t *testing.T) {
+	r, st := newLimitsTestRunner(t)
+	if err := st.CreateSession(state.Session{ID: "session-1", RepoName: "acme/api", Branch: "fog/x", WorktreePath: "/tmp/wt", Tool: "claude", Status: "COMPLETED"}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	if _, err := r.SyncPRFeedback(context.Background(), "session-1", PRSyncOptions{}); err == nil || !strings.Contains(err.Error(), "no pull request") {
+		t.Fatalf("expected no pull request error, got %v", err)
+	}
+}
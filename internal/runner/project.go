@@ -0,0 +1,137 @@
+package runner
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/projectcfg"
+	"github.com/darkLord19/foglet/internal/state"
+	"github.com/darkLord19/foglet/internal/toolcfg"
+)
+
+// applyProjectConfig fills options the request left empty from the repo's
+// project file, then from global settings. Request values always win.
+func (r *Runner) applyProjectConfig(opts *StartSessionOptions) (projectcfg.Config, error) {
+	project, err := projectcfg.Load(opts.RepoPath)
+	if err != nil {
+		return projectcfg.Config{}, fmt.Errorf("load project config: %w", err)
+	}
+
+	if opts.Tool == "" {
+		if tool := project.DefaultTool(); tool != "" {
+			opts.Tool = tool
+		} else if r.state != nil {
+			tool, err := toolcfg.ResolveTool("", r.state, "session")
+			if err != nil {
+				return projectcfg.Config{}, err
+			}
+			opts.Tool = tool
+		}
+	}
+	if opts.Tool != "" && !project.AllowsTool(opts.Tool) {
+		return projectcfg.Config{}, fmt.Errorf("tool %q is not allowed by %s (allowed: %s)",
+			opts.Tool, filepath.Base(project.Path), strings.Join(project.Tools, ", "))
+	}
+	// The project model is meant for the project's default tool, whether
+	// the request named it or left the tool empty.
+	if opts.Model == "" && opts.Tool != "" && opts.Tool == project.DefaultTool() {
+		opts.Model = project.Model
+	}
+
+	if opts.SetupCmd == "" {
+		opts.SetupCmd = project.Setup
+	}
+	if opts.ValidateCmd == "" && project.Validate != "" {
+		opts.ValidateCmd = project.Validate
+		// Declaring validate turns validation on unless the request
+		// explicitly turned it off.
+		if !opts.HasValidate {
+			opts.Validate = true
+		}
+	}
+	if opts.FixAttempts == 0 && opts.Validate && opts.ValidateCmd == project.Validate {
+		opts.FixAttempts = project.FixAttempts
+	}
+	if opts.BaseBranch == "" {
+		opts.BaseBranch = project.BaseBranch
+	}
+	if opts.BaseBranch == "" && r.state != nil && opts.RepoName != "" {
+		if repo, found, err := r.state.GetRepoByName(opts.RepoName); err == nil && found {
+			opts.BaseBranch = strings.TrimSpace(repo.DefaultBranch)
+		}
+	}
+	if opts.BaseBranch == "" {
+		opts.BaseBranch = "main"
+	}
+	return project, nil
+}
+
+// withPromptPreamble prepends the project preamble to a prompt.
+func withPromptPreamble(preamble, prompt string) string {
+	if preamble == "" {
+		return prompt
+	}
+	return preamble + "\n\n" + prompt
+}
+
+// prBody builds the pull request body: the project template, if any,
+// followed by the Fog session details.
+func prBody(template, sessionID, tool, prompt string) string {
+	body := fmt.Sprintf("Generated by Fog session\n\nSession ID: %s\nAI Tool: %s\n\nPrompt:\n%s",
+		sessionID,
+		tool,
+		strings.TrimSpace(prompt),
+	)
+	if template = strings.TrimSpace(template); template != "" {
+		body = template + "\n\n---\n\n" + body
+	}
+	return body
+}
+
+// sessionPRBody rebuilds the pull request body from every run of a session
+// that produced a commit, oldest first, so the description keeps up with
+// follow-ups. Sessions without commits fall back to the first prompt.
+func sessionPRBody(template string, session state.Session, runs []state.Run) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Generated by Fog session\n\nSession ID: %s\nAI Tool: %s\n", session.ID, session.Tool)
+
+	n := 0
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+		if strings.TrimSpace(run.CommitSHA) == "" {
+			continue
+		}
+		n++
+		if n == 1 {
+			b.WriteString("\nChanges:\n")
+		}
+		subject, details, _ := strings.Cut(strings.TrimSpace(run.CommitMsg), "\n")
+		if subject = strings.TrimSpace(subject); subject == "" {
+			subject = firstLine(run.Prompt)
+		}
+		sha := strings.TrimSpace(run.CommitSHA)
+		fmt.Fprintf(&b, "\n%d. %s (%s)\n", n, subject, sha[:min(len(sha), 7)])
+		if details = strings.TrimSpace(details); details != "" {
+			b.WriteString(indent(details, "   ") + "\n")
+		}
+		b.WriteString("   Prompt:\n" + indent(truncate(run.Prompt, maxFeedbackBodyChars), "   > ") + "\n")
+	}
+	if n == 0 && len(runs) > 0 {
+		b.WriteString("\nPrompt:\n" + strings.TrimSpace(runs[len(runs)-1].Prompt) + "\n")
+	}
+
+	body := strings.TrimSpace(b.String())
+	if template = strings.TrimSpace(template); template != "" {
+		body = template + "\n\n---\n\n" + body
+	}
+	return body
+}
+
+func indent(text, prefix string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(prefix+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,142 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestApplyProjectConfig(t *testing.T) {
+	r, st := newLimitsTestRunner(t)
+	if err := st.SetDefaultTool("claude"); err != nil {
+		t.Fatalf("set default tool failed: %v", err)
+	}
+	dir := t.TempDir()
+	writeProjectFile(t, dir, `
+setup: npm ci
+validate: npm test
+fix_attempts: 2
+tools: [cursor, claude]
+model: fast
+base_branch: develop
+`)
+
+	opts := StartSessionOptions{RepoName: "acme/api", RepoPath: dir}
+	project, err := r.applyProjectConfig(&opts)
+	if err != nil {
+		t.Fatalf("apply project config failed: %v", err)
+	}
+	if opts.Tool != "cursor" || opts.Model != "fast" || opts.BaseBranch != "develop" {
+		t.Fatalf("project defaults not applied: %+v", opts)
+	}
+	if opts.SetupCmd != "npm ci" || opts.ValidateCmd != "npm test" || !opts.Validate || opts.FixAttempts != 2 {
+		t.Fatalf("project commands not applied: %+v", opts)
+	}
+	if project.Path == "" {
+		t.Fatal("expected project path to be set")
+	}
+
+	opts = StartSessionOptions{RepoName: "acme/api", RepoPath: dir, Tool: "claude", SetupCmd: "make deps", Validate: true, ValidateCmd: "make check", BaseBranch: "main"}
+	if _, err := r.applyProjectConfig(&opts); err != nil {
+		t.Fatalf("apply project config failed: %v", err)
+	}
+	if opts.Model != "" || opts.SetupCmd != "make deps" || opts.BaseBranch != "main" || opts.FixAttempts != 0 {
+		t.Fatalf("request options should win over project file: %+v", opts)
+	}
+
+	opts = StartSessionOptions{RepoName: "acme/api", RepoPath: dir, Tool: "claude", HasValidate: true}
+	if _, err := r.applyProjectConfig(&opts); err != nil {
+		t.Fatalf("apply project config failed: %v", err)
+	}
+	if opts.Validate || opts.FixAttempts != 0 {
+		t.Fatalf("request should be able to turn project validation off: %+v", opts)
+	}
+
+	opts = StartSessionOptions{RepoName: "acme/api", RepoPath: dir, Tool: "cursor"}
+	if _, err := r.applyProjectConfig(&opts); err != nil {
+		t.Fatalf("apply project config failed: %v", err)
+	}
+	if opts.Model != "fast" {
+		t.Fatalf("project model should apply when the default tool is named: %+v", opts)
+	}
+
+	opts = StartSessionOptions{RepoName: "acme/api", RepoPath: dir, Tool: "gemini"}
+	if _, err := r.applyProjectConfig(&opts); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Fatalf("expected disallowed tool error, got %v", err)
+	}
+
+	writeProjectFile(t, dir, "unknown_key: true\n")
+	opts = StartSessionOptions{RepoName: "acme/api", RepoPath: dir}
+	if _, err := r.applyProjectConfig(&opts); err == nil {
+		t.Fatal("expected error for malformed project file")
+	}
+}
+
+func TestApplyProjectConfigFallsBackToSettings(t *testing.T) {
+	r, st := newLimitsTestRunner(t)
+	if err := st.SetDefaultTool("claude"); err != nil {
+		t.Fatalf("set default tool failed: %v", err)
+	}
+	opts := StartSessionOptions{RepoName: "acme/api", RepoPath: t.TempDir()}
+	if _, err := r.applyProjectConfig(&opts); err != nil {
+		t.Fatalf("apply project config failed: %v", err)
+	}
+	if opts.Tool != "claude" || opts.BaseBranch != "main" || opts.Validate {
+		t.Fatalf("unexpected fallback options: %+v", opts)
+	}
+}
+
+func TestFollowUpRunOptionsUseProjectValidation(t *testing.T) {
+	r, st := newLimitsTestRunner(t)
+	dir := t.TempDir()
+	writeProjectFile(t, dir, "setup: npm ci\nvalidate: npm test\nfix_attempts: 3\nbase_branch: develop\ncommit:\n  style: imperative\n")
+	if _, err := st.UpsertRepo(state.Repo{
+		Name:             "acme/web",
+		URL:              "https://github.com/acme/web.git",
+		Host:             "github.com",
+		BarePath:         "/tmp/acme-web/repo.git",
+		BaseWorktreePath: dir,
+	}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+
+	opts := r.followUpRunOptions(state.Session{ID: "s-1", RepoName: "acme/web"}, "fix bug")
+	if !opts.Validate || opts.ValidateCmd != "npm test" || opts.FixAttempts != 3 || opts.BaseBranch != "develop" || opts.CommitStyle != "imperative" {
+		t.Fatalf("project config not applied to follow-up: %+v", opts)
+	}
+	if opts.SetupCmd != "" {
+		t.Fatalf("follow-ups should reuse the set-up worktree, got setup %q", opts.SetupCmd)
+	}
+
+	writeProjectFile(t, dir, "unknown_key: true\n")
+	opts = r.followUpRunOptions(state.Session{ID: "s-1", RepoName: "acme/web"}, "fix bug")
+	if opts.Validate || opts.FixAttempts != 0 || opts.BaseBranch != "main" {
+		t.Fatalf("broken project file should fall back to defaults: %+v", opts)
+	}
+}
+
+func TestProjectPromptHelpers(t *testing.T) {
+	if got := withPromptPreamble("Follow AGENTS.md.", "fix bug"); got != "Follow AGENTS.md.\n\nfix bug" {
+		t.Fatalf("unexpected prompt: %q", got)
+	}
+	if got := commitMsgInstructionsFor(""); !strings.Contains(got, "Conventional Commits") {
+		t.Fatalf("expected conventional default, got %q", got)
+	}
+	if got := commitMsgInstructionsFor("imperative, no prefix"); !strings.Contains(got, "Commit message style: imperative, no prefix") {
+		t.Fatalf("expected project style, got %q", got)
+	}
+	body := prBody("## Summary\n\n## Testing", "s-1", "claude", "fix bug")
+	if !strings.HasPrefix(body, "## Summary") || !strings.Contains(body, "Session ID: s-1") {
+		t.Fatalf("unexpected PR body: %q", body)
+	}
+}
+
+func writeProjectFile(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, ".fog.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write project file failed: %v", err)
+	}
+}
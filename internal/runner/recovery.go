@@ -0,0 +1,150 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/proc"
+	"github.com/darkLord19/foglet/internal/state"
+	"github.com/darkLord19/foglet/internal/task"
+)
+
+// RecoveryOptions controls startup reconciliation of runs left behind by a
+// previous fogd process.
+type RecoveryOptions struct {
+	// Resume re-queues an interrupted run as a follow-up when the session has
+	// a stored AI conversation to continue.
+	Resume bool
+}
+
+// RecoveryReport summarizes one reconciliation pass.
+type RecoveryReport struct {
+	InterruptedRuns  []string
+	ResumedRuns      []string
+	ReleasedSessions []string
+}
+
+const resumePromptPrefix = "fogd restarted before the previous request finished. Continue where you left off.\n\nPrevious request:\n"
+
+// RecoverInterruptedRuns reconciles persisted state with the (empty) set of
+// in-memory active runs. It must be called once at startup, before any new
+// runs are started: every in-flight run is marked INTERRUPTED, busy sessions
+// are released, and half-staged worktrees are reset. Sessions with queued
+// follow-ups resume draining their queue.
+func (r *Runner) RecoverInterruptedRuns(opts RecoveryOptions) (RecoveryReport, error) {
+	var report RecoveryReport
+	if r.state == nil {
+		return report, errors.New("state store not configured")
+	}
+
+	runs, err := r.state.ListInFlightRuns()
+	if err != nil {
+		return report, err
+	}
+
+	interrupted := make(map[string]state.Run)
+	for _, run := range runs {
+		if err := r.interruptRun(run); err != nil {
+			return report, err
+		}
+		report.InterruptedRuns = append(report.InterruptedRuns, run.ID)
+		// Later runs win so the session resumes its most recent request.
+		interrupted[run.SessionID] = run
+	}
+
+	sessions, err := r.state.ListSessions()
+	if err != nil {
+		return report, err
+	}
+	for _, session := range sessions {
+		run, wasInterrupted := interrupted[session.ID]
+		if !session.Busy && !wasInterrupted {
+			continue
+		}
+		if wasInterrupted {
+			cleanupInterruptedWorktree(session.WorktreePath)
+		}
+		if session.Busy {
+			if err := r.state.SetSessionBusy(session.ID, false); err != nil {
+				return report, err
+			}
+			report.ReleasedSessions = append(report.ReleasedSessions, session.ID)
+		}
+
+		if opts.Resume && wasInterrupted && r.lookupConversationID(session.ID, "") != "" {
+			resumed, err := r.ContinueSessionAsync(session.ID, resumePromptPrefix+run.Prompt)
+			if err != nil {
+				return report, fmt.Errorf("resume session %q: %w", session.ID, err)
+			}
+			_ = r.state.AppendRunEvent(state.RunEvent{
+				RunID:   run.ID,
+				Type:    "resumed",
+				Message: "Resumed as run " + resumed.ID,
+				Data:    resumed.ID,
+			})
+			report.ResumedRuns = append(report.ResumedRuns, resumed.ID)
+			continue
+		}
+
+		if err := r.drainSessionQueue(session.ID); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func (r *Runner) interruptRun(run state.Run) error {
+	message := fmt.Sprintf("interrupted: fogd stopped while run was %s", run.State)
+	_ = r.state.AppendRunEvent(state.RunEvent{
+		RunID:   run.ID,
+		Type:    "interrupted",
+		Message: "Run interrupted by fogd restart",
+		Data:    run.State,
+	})
+	if err := r.state.CompleteRun(run.ID, string(task.StateInterrupted), "", "", message); err != nil {
+		return err
+	}
+	return r.updateSessionStatusIfLatest(run.SessionID, run.ID, string(task.StateInterrupted))
+}
+
+// drainSessionQueue starts the head of an idle session's queue, if any.
+func (r *Runner) drainSessionQueue(sessionID string) error {
+	_, found, err := r.state.NextQueuedRun(sessionID)
+	if err != nil || !found {
+		return err
+	}
+	claimed, err := r.state.TryClaimSession(sessionID)
+	if err != nil || !claimed {
+		return err
+	}
+	return r.releaseSession(sessionID)
+}
+
+// cleanupInterruptedWorktree removes a stale index lock and unstages any
+// half-finished commit so the next run starts from a clean index. Working
+// tree changes are kept.
+func cleanupInterruptedWorktree(worktreePath string) {
+	worktreePath = strings.TrimSpace(worktreePath)
+	if worktreePath == "" {
+		return
+	}
+	if _, err := os.Stat(worktreePath); err != nil {
+		return
+	}
+
+	ctx := context.Background()
+	if out, err := proc.Run(ctx, worktreePath, "git", "rev-parse", "--git-path", "index.lock"); err == nil {
+		lockPath := strings.TrimSpace(string(out))
+		if lockPath != "" {
+			if !filepath.IsAbs(lockPath) {
+				lockPath = filepath.Join(worktreePath, lockPath)
+			}
+			_ = os.Remove(lockPath)
+		}
+	}
+	_, _ = proc.Run(ctx, worktreePath, "git", "reset", "--quiet")
+}
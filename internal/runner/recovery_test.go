@@ -0,0 +1,132 @@
+package runner
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestRecoverInterruptedRuns(t *testing.T) {
+	r, err := New(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("new runner failed: %v", err)
+	}
+	st, err := state.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new state store failed: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	r.SetStateStore(st)
+
+	worktree := initGitRepo(t, "main")
+	if err := os.WriteFile(filepath.Join(worktree, "half.txt"), []byte("partial\n"), 0o644); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+	runGit(t, worktree, "add", "half.txt")
+	lockPath := filepath.Join(worktree, ".git", "index.lock")
+	if err := os.WriteFile(lockPath, nil, 0o644); err != nil {
+		t.Fatalf("write index lock failed: %v", err)
+	}
+
+	if _, err := st.UpsertRepo(state.Repo{
+		Name:             "acme/api",
+		URL:              "https://github.com/acme/api.git",
+		Host:             "github.com",
+		BarePath:         "/tmp/acme-api/repo.git",
+		BaseWorktreePath: "/tmp/acme-api/base",
+	}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	if err := st.CreateSession(state.Session{
+		ID:           "session-1",
+		RepoName:     "acme/api",
+		Branch:       "fog/test",
+		WorktreePath: worktree,
+		Tool:         "claude",
+		Status:       "AI_RUNNING",
+		Busy:         true,
+	}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	now := time.Now().UTC()
+	if err := st.CreateRun(state.Run{
+		ID:           "run-done",
+		SessionID:    "session-1",
+		Prompt:       "first",
+		WorktreePath: worktree,
+		State:        "COMPLETED",
+		CreatedAt:    now.Add(-time.Minute),
+	}); err != nil {
+		t.Fatalf("create completed run failed: %v", err)
+	}
+	if err := st.CreateRun(state.Run{
+		ID:           "run-orphan",
+		SessionID:    "session-1",
+		Prompt:       "second",
+		WorktreePath: worktree,
+		State:        "COMMITTED",
+		CreatedAt:    now,
+	}); err != nil {
+		t.Fatalf("create orphaned run failed: %v", err)
+	}
+
+	report, err := r.RecoverInterruptedRuns(RecoveryOptions{})
+	if err != nil {
+		t.Fatalf("recover failed: %v", err)
+	}
+	if len(report.InterruptedRuns) != 1 || report.InterruptedRuns[0] != "run-orphan" {
+		t.Fatalf("unexpected interrupted runs: %+v", report)
+	}
+	if len(report.ReleasedSessions) != 1 || len(report.ResumedRuns) != 0 {
+		t.Fatalf("unexpected recovery report: %+v", report)
+	}
+
+	run, _, err := st.GetRun("run-orphan")
+	if err != nil {
+		t.Fatalf("get run failed: %v", err)
+	}
+	if run.State != "INTERRUPTED" || run.CompletedAt == nil || !strings.Contains(run.Error, "COMMITTED") {
+		t.Fatalf("unexpected interrupted run: %+v", run)
+	}
+	done, _, err := st.GetRun("run-done")
+	if err != nil {
+		t.Fatalf("get completed run failed: %v", err)
+	}
+	if done.State != "COMPLETED" {
+		t.Fatalf("completed run should be untouched: %+v", done)
+	}
+	events, err := st.ListRunEvents("run-orphan", 10)
+	if err != nil {
+		t.Fatalf("list events failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Type != "interrupted" {
+		t.Fatalf("unexpected run events: %+v", events)
+	}
+
+	session, _, err := st.GetSession("session-1")
+	if err != nil {
+		t.Fatalf("get session failed: %v", err)
+	}
+	if session.Busy || session.Status != "INTERRUPTED" {
+		t.Fatalf("unexpected session after recovery: %+v", session)
+	}
+
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Fatalf("expected stale index.lock to be removed, stat err=%v", err)
+	}
+	staged, err := exec.Command("git", "-C", worktree, "diff", "--cached", "--name-only").CombinedOutput()
+	if err != nil {
+		t.Fatalf("git diff --cached failed: %v\n%s", err, string(staged))
+	}
+	if strings.TrimSpace(string(staged)) != "" {
+		t.Fatalf("expected index to be reset, still staged: %q", string(staged))
+	}
+	if _, err := os.Stat(filepath.Join(worktree, "half.txt")); err != nil {
+		t.Fatalf("working tree changes should be kept: %v", err)
+	}
+}
@@ -25,6 +25,10 @@ type Runner struct {
 	state     *state.Store
 	mu        sync.Mutex
 	active    map[string]*activeRun
+	// queueMu serializes session claim/release so a follow-up cannot be
+	// queued after the active run has already checked for queued work.
+	queueMu   sync.Mutex
+	scheduler *scheduler
 }
 
 // New creates a new runner
@@ -35,17 +39,20 @@ func New(repoPath, configDir string) (*Runner, error) {
 		return nil, err
 	}
 
-	return &Runner{
+	r := &Runner{
 		repoPath:  repoPath,
 		configDir: configDir,
 		taskStore: store,
 		active:    make(map[string]*activeRun),
-	}, nil
+	}
+	r.scheduler = newScheduler(r.ConcurrencyLimits())
+	return r, nil
 }
 
 // SetStateStore sets the state persistence backend used for session workflows.
 func (r *Runner) SetStateStore(store *state.Store) {
 	r.state = store
+	r.RescheduleRuns()
 }
 
 // Execute runs a task
@@ -0,0 +1,100 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/proc"
+)
+
+// Settings keys for the run sandbox. Like run limits, each key may also be
+// stored per repo as RepoSettingKey(key, repoName) to override the global
+// value.
+const (
+	SettingSandbox             = "sandbox"
+	SettingSandboxNoNetwork    = "sandbox_no_network"
+	SettingSandboxWritableDirs = "sandbox_writable_dirs"
+)
+
+// SandboxConfig is the configured sandbox for runs in a repo. WritableDirs
+// lists extra directories (tool state, package caches) that stay writable
+// next to the worktree.
+type SandboxConfig struct {
+	Backend      string
+	NoNetwork    bool
+	WritableDirs []string
+}
+
+// SandboxConfig returns the sandbox configured for runs in a repo, applying
+// the repo override on top of the global settings.
+func (r *Runner) SandboxConfig(repoName string) SandboxConfig {
+	cfg := SandboxConfig{Backend: proc.SandboxOff}
+	if r == nil || r.state == nil {
+		return cfg
+	}
+	for _, key := range []string{SettingSandbox, RepoSettingKey(SettingSandbox, repoName)} {
+		if raw, found, err := r.state.GetSetting(key); err == nil && found && strings.TrimSpace(raw) != "" {
+			cfg.Backend = strings.TrimSpace(raw)
+		}
+	}
+	for _, key := range []string{SettingSandboxNoNetwork, RepoSettingKey(SettingSandboxNoNetwork, repoName)} {
+		if raw, found, err := r.state.GetSetting(key); err == nil && found {
+			cfg.NoNetwork = strings.EqualFold(strings.TrimSpace(raw), "true")
+		}
+	}
+	for _, key := range []string{SettingSandboxWritableDirs, RepoSettingKey(SettingSandboxWritableDirs, repoName)} {
+		if raw, found, err := r.state.GetSetting(key); err == nil && found {
+			cfg.WritableDirs = ParseSandboxDirs(raw)
+		}
+	}
+	return cfg
+}
+
+// ParseSandboxDirs splits a comma- or newline-separated directory list.
+func ParseSandboxDirs(raw string) []string {
+	fields := strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == '\n' })
+	dirs := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); field != "" {
+			dirs = append(dirs, field)
+		}
+	}
+	return dirs
+}
+
+// runSandbox resolves the sandbox for one run: the worktree, its git
+// metadata and the configured directories are writable. It fails when a
+// sandbox is configured but cannot be used, rather than running unconfined.
+func (r *Runner) runSandbox(ctx context.Context, repoName, worktreePath string) (proc.Sandbox, error) {
+	cfg := r.SandboxConfig(repoName)
+	sb := proc.Sandbox{Backend: cfg.Backend, NoNetwork: cfg.NoNetwork}
+	if !sb.Enabled() {
+		return sb, nil
+	}
+	if err := proc.CheckSandbox(sb.Backend); err != nil {
+		return proc.Sandbox{}, err
+	}
+
+	sb.WritableDirs = append(sb.WritableDirs, worktreePath)
+	// Agents commit and inspect history, so the repository's shared git
+	// directory (objects, refs, worktree metadata) must stay writable too.
+	if out, err := proc.Run(ctx, worktreePath, "git", "rev-parse", "--path-format=absolute", "--git-common-dir"); err == nil {
+		if gitDir := strings.TrimSpace(string(out)); gitDir != "" {
+			sb.WritableDirs = append(sb.WritableDirs, gitDir)
+		}
+	}
+	home, _ := os.UserHomeDir()
+	for _, dir := range cfg.WritableDirs {
+		if rest, ok := strings.CutPrefix(dir, "~"); ok && home != "" {
+			dir = filepath.Join(home, rest)
+		}
+		if !filepath.IsAbs(dir) {
+			return proc.Sandbox{}, fmt.Errorf("sandbox writable dir %q must be absolute", dir)
+		}
+		sb.WritableDirs = append(sb.WritableDirs, dir)
+	}
+	return sb, nil
+}
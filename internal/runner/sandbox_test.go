@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/proc"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestSandboxConfigAppliesRepoOverride(t *testing.T) {
+	r, st := newLimitsTestRunner(t)
+	for key, value := range map[string]string{
+		SettingSandbox:             proc.SandboxBwrap,
+		SettingSandboxWritableDirs: "~/.cache/go-build",
+		RepoSettingKey(SettingSandboxNoNetwork, "acme/api"):    "true",
+		RepoSettingKey(SettingSandboxWritableDirs, "acme/api"): "/opt/cache, ~/.npm\n",
+	} {
+		if err := st.SetSetting(key, value); err != nil {
+			t.Fatalf("set %s failed: %v", key, err)
+		}
+	}
+
+	got := r.SandboxConfig("acme/api")
+	want := SandboxConfig{Backend: proc.SandboxBwrap, NoNetwork: true, WritableDirs: []string{"/opt/cache", "~/.npm"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected repo sandbox config: got %+v want %+v", got, want)
+	}
+	other := r.SandboxConfig("acme/web")
+	if other.NoNetwork || !reflect.DeepEqual(other.WritableDirs, []string{"~/.cache/go-build"}) {
+		t.Fatalf("unexpected global sandbox config: %+v", other)
+	}
+}
+
+func TestRunShellWrapsCommandsInSandbox(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("bwrap sandbox requires linux")
+	}
+	// The fake bwrap records its arguments and runs the wrapped command.
+	binDir := t.TempDir()
+	logPath := filepath.Join(t.TempDir(), "bwrap.log")
+	script := "#!/bin/sh\n" +
+		"printf '%s\\n' \"$@\" > " + logPath + "\n" +
+		"while [ \"$1\" != \"--\" ]; do shift; done\nshift\nexec \"$@\"\n"
+	if err := os.WriteFile(filepath.Join(binDir, "bwrap"), []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake bwrap failed: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	r, st := newLimitsTestRunner(t)
+	repo := initGitRepo(t, "main")
+	if err := st.SetSetting(SettingSandbox, proc.SandboxBwrap); err != nil {
+		t.Fatalf("set sandbox failed: %v", err)
+	}
+	if err := st.SetSetting(RepoSettingKey(SettingSandboxNoNetwork, "acme/api"), "true"); err != nil {
+		t.Fatalf("set sandbox network failed: %v", err)
+	}
+
+	sb, err := r.runSandbox(context.Background(), "acme/api", repo)
+	if err != nil {
+		t.Fatalf("runSandbox failed: %v", err)
+	}
+	if sb.String() != "bwrap (no network)" {
+		t.Fatalf("unexpected sandbox: %s", sb)
+	}
+	if err := r.runShell(proc.WithSandbox(context.Background(), sb), repo, "echo ok > sandboxed.txt"); err != nil {
+		t.Fatalf("sandboxed shell failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "sandboxed.txt")); err != nil {
+		t.Fatalf("expected wrapped command to run: %v", err)
+	}
+	logged, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read bwrap log failed: %v", err)
+	}
+	args := string(logged)
+	for _, want := range []string{"--unshare-net", "--bind\n" + repo + "\n" + repo, filepath.Join(repo, ".git"), "--chdir\n" + repo} {
+		if !strings.Contains(args, want) {
+			t.Fatalf("bwrap args missing %q:\n%s", want, args)
+		}
+	}
+}
+
+func TestExecuteSessionRunFailsWhenSandboxUnavailable(t *testing.T) {
+	r, st := newLimitsTestRunner(t)
+	repo := initGitRepo(t, "main")
+	if err := st.SetSetting(RepoSettingKey(SettingSandbox, "acme/api"), proc.SandboxBwrap); err != nil {
+		t.Fatalf("set sandbox failed: %v", err)
+	}
+	session := state.Session{ID: "session-1", RepoName: "acme/api", Branch: "main", WorktreePath: repo, Tool: "claude", Status: "CREATED", Busy: true}
+	if err := st.CreateSession(session); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	run := state.Run{ID: "run-1", SessionID: session.ID, Prompt: "p", WorktreePath: repo, State: "CREATED"}
+	if err := st.CreateRun(run); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+
+	// Without bwrap on PATH the run must fail instead of running unconfined.
+	t.Setenv("PATH", t.TempDir())
+	if err := r.executeSessionRun(session, run, sessionRunOptions{Prompt: run.Prompt, BaseBranch: "main"}); err == nil {
+		t.Fatal("expected run to fail")
+	}
+	got, _, err := st.GetRun(run.ID)
+	if err != nil {
+		t.Fatalf("get run failed: %v", err)
+	}
+	if got.State != "FAILED" || !strings.Contains(got.Error, "sandbox unavailable") {
+		t.Fatalf("unexpected run: %+v", got)
+	}
+}
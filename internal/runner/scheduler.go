@@ -0,0 +1,232 @@
+package runner
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Settings keys for run concurrency caps. A value of 0 disables the cap.
+const (
+	SettingMaxConcurrentRuns        = "max_concurrent_runs"
+	SettingMaxConcurrentRunsPerRepo = "max_concurrent_runs_per_repo"
+	SettingMaxConcurrentRunsPerTool = "max_concurrent_runs_per_tool"
+)
+
+// DefaultMaxConcurrentRuns is the global cap used when none is configured.
+// Runs are not capped by default.
+const DefaultMaxConcurrentRuns = 0
+
+// ConcurrencyLimits caps how many session runs execute at once.
+type ConcurrencyLimits struct {
+	Global  int
+	PerRepo int
+	PerTool int
+}
+
+// ConcurrencyLimits returns the configured run caps.
+func (r *Runner) ConcurrencyLimits() ConcurrencyLimits {
+	limits := ConcurrencyLimits{Global: DefaultMaxConcurrentRuns}
+	if r == nil || r.state == nil {
+		return limits
+	}
+	limits.Global = r.intSetting(SettingMaxConcurrentRuns, limits.Global)
+	limits.PerRepo = r.intSetting(SettingMaxConcurrentRunsPerRepo, 0)
+	limits.PerTool = r.intSetting(SettingMaxConcurrentRunsPerTool, 0)
+	return limits
+}
+
+// RescheduleRuns reloads the concurrency caps from settings and re-evaluates
+// waiting runs. Call it after the caps are written.
+func (r *Runner) RescheduleRuns() {
+	if r == nil || r.scheduler == nil {
+		return
+	}
+	r.scheduler.setLimits(r.ConcurrencyLimits())
+}
+
+func (r *Runner) intSetting(key string, fallback int) int {
+	raw, found, err := r.state.GetSetting(key)
+	if err != nil || !found {
+		return fallback
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || value < 0 {
+		return fallback
+	}
+	return value
+}
+
+// scheduler gates session runs behind global, per-repo and per-tool caps.
+// Waiting runs are served by priority, then by the repo with the fewest
+// running runs, then in arrival order. The caps are cached so dispatching
+// never reads settings while holding the lock.
+type scheduler struct {
+	mu      sync.Mutex
+	limits  ConcurrencyLimits
+	seq     uint64
+	waiting []*runTicket
+	running map[*runTicket]struct{}
+	changed chan struct{}
+}
+
+type runTicket struct {
+	repo     string
+	tool     string
+	priority int
+	seq      uint64
+	granted  bool
+}
+
+func newScheduler(limits ConcurrencyLimits) *scheduler {
+	return &scheduler{
+		limits:  limits,
+		running: make(map[*runTicket]struct{}),
+		changed: make(chan struct{}),
+	}
+}
+
+// acquire blocks until the ticket may run or ctx is done. onWait is called
+// with the ticket's 1-based queue position whenever it changes.
+func (s *scheduler) acquire(ctx context.Context, t *runTicket, onWait func(position int)) error {
+	s.mu.Lock()
+	s.seq++
+	t.seq = s.seq
+	s.waiting = append(s.waiting, t)
+	// Wake other waiters: a higher-priority arrival shifts their position.
+	s.broadcastLocked()
+
+	lastPosition := 0
+	for {
+		s.dispatchLocked()
+		if t.granted {
+			s.mu.Unlock()
+			return nil
+		}
+		position := s.positionLocked(t)
+		changed := s.changed
+		s.mu.Unlock()
+
+		if position != lastPosition && onWait != nil {
+			onWait(position)
+			lastPosition = position
+		}
+
+		select {
+		case <-changed:
+			s.mu.Lock()
+		case <-ctx.Done():
+			s.mu.Lock()
+			if t.granted {
+				delete(s.running, t)
+			} else {
+				s.removeWaitingLocked(t)
+			}
+			s.dispatchLocked()
+			s.broadcastLocked()
+			s.mu.Unlock()
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *scheduler) release(t *runTicket) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, t)
+	s.dispatchLocked()
+	s.broadcastLocked()
+}
+
+func (s *scheduler) setLimits(limits ConcurrencyLimits) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limits = limits
+	s.dispatchLocked()
+	s.broadcastLocked()
+}
+
+// dispatchLocked grants as many waiting tickets as the caps allow.
+func (s *scheduler) dispatchLocked() {
+	if len(s.waiting) == 0 {
+		return
+	}
+	limits := s.limits
+	granted := false
+	for {
+		byRepo, byTool := s.runningCountsLocked()
+		best := -1
+		for i, t := range s.waiting {
+			if limits.Global > 0 && len(s.running) >= limits.Global {
+				break
+			}
+			if limits.PerRepo > 0 && byRepo[t.repo] >= limits.PerRepo {
+				continue
+			}
+			if limits.PerTool > 0 && byTool[t.tool] >= limits.PerTool {
+				continue
+			}
+			if best < 0 || s.lessLocked(t, s.waiting[best], byRepo) {
+				best = i
+			}
+		}
+		if best < 0 {
+			break
+		}
+		t := s.waiting[best]
+		s.waiting = append(s.waiting[:best], s.waiting[best+1:]...)
+		t.granted = true
+		s.running[t] = struct{}{}
+		granted = true
+	}
+	if granted {
+		s.broadcastLocked()
+	}
+}
+
+// lessLocked reports whether a should be scheduled before b.
+func (s *scheduler) lessLocked(a, b *runTicket, byRepo map[string]int) bool {
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	if byRepo[a.repo] != byRepo[b.repo] {
+		return byRepo[a.repo] < byRepo[b.repo]
+	}
+	return a.seq < b.seq
+}
+
+func (s *scheduler) positionLocked(t *runTicket) int {
+	byRepo, _ := s.runningCountsLocked()
+	position := 1
+	for _, other := range s.waiting {
+		if other != t && s.lessLocked(other, t, byRepo) {
+			position++
+		}
+	}
+	return position
+}
+
+func (s *scheduler) runningCountsLocked() (byRepo, byTool map[string]int) {
+	byRepo = make(map[string]int)
+	byTool = make(map[string]int)
+	for t := range s.running {
+		byRepo[t.repo]++
+		byTool[t.tool]++
+	}
+	return byRepo, byTool
+}
+
+func (s *scheduler) removeWaitingLocked(t *runTicket) {
+	for i, other := range s.waiting {
+		if other == t {
+			s.waiting = append(s.waiting[:i], s.waiting[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *scheduler) broadcastLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
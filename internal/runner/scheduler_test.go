@@ -0,0 +1,192 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestSchedulerGlobalCapAndPriority(t *testing.T) {
+	s := newScheduler(ConcurrencyLimits{Global: 1})
+
+	first := &runTicket{repo: "acme/api", tool: "claude"}
+	if err := s.acquire(context.Background(), first, nil); err != nil {
+		t.Fatalf("acquire first failed: %v", err)
+	}
+
+	low := &runTicket{repo: "acme/api", tool: "claude"}
+	high := &runTicket{repo: "acme/api", tool: "claude", priority: 10}
+	lowPositions := make(chan int, 4)
+	lowDone := make(chan error, 1)
+	go func() { lowDone <- s.acquire(context.Background(), low, func(p int) { lowPositions <- p }) }()
+	waitForPosition(t, lowPositions, 1)
+
+	highDone := make(chan error, 1)
+	go func() { highDone <- s.acquire(context.Background(), high, nil) }()
+	// The higher-priority run jumps ahead of the waiting one.
+	waitForPosition(t, lowPositions, 2)
+
+	s.release(first)
+	if err := waitForAcquire(highDone); err != nil {
+		t.Fatalf("acquire high failed: %v", err)
+	}
+	select {
+	case <-lowDone:
+		t.Fatal("low priority run should still be waiting")
+	default:
+	}
+
+	s.release(high)
+	if err := waitForAcquire(lowDone); err != nil {
+		t.Fatalf("acquire low failed: %v", err)
+	}
+}
+
+func TestSchedulerPerRepoCapAndFairness(t *testing.T) {
+	s := newScheduler(ConcurrencyLimits{Global: 2, PerRepo: 1})
+
+	api := &runTicket{repo: "acme/api", tool: "claude"}
+	if err := s.acquire(context.Background(), api, nil); err != nil {
+		t.Fatalf("acquire api failed: %v", err)
+	}
+
+	apiAgain := &runTicket{repo: "acme/api", tool: "claude"}
+	apiDone := make(chan error, 1)
+	go func() { apiDone <- s.acquire(context.Background(), apiAgain, nil) }()
+
+	web := &runTicket{repo: "acme/web", tool: "claude"}
+	if err := waitForAcquire(runAsync(s, web)); err != nil {
+		t.Fatalf("other repo should not wait behind per-repo cap: %v", err)
+	}
+	select {
+	case <-apiDone:
+		t.Fatal("second api run should wait for the per-repo cap")
+	default:
+	}
+
+	s.release(api)
+	if err := waitForAcquire(apiDone); err != nil {
+		t.Fatalf("acquire second api run failed: %v", err)
+	}
+}
+
+func TestSchedulerCancelWhileWaiting(t *testing.T) {
+	s := newScheduler(ConcurrencyLimits{PerTool: 1})
+
+	running := &runTicket{repo: "acme/api", tool: "claude"}
+	if err := s.acquire(context.Background(), running, nil); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waiting := &runTicket{repo: "acme/web", tool: "claude"}
+	done := make(chan error, 1)
+	go func() { done <- s.acquire(ctx, waiting, nil) }()
+	cancel()
+	if err := waitForAcquire(done); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled error, got %v", err)
+	}
+
+	s.mu.Lock()
+	waitingCount := len(s.waiting)
+	s.mu.Unlock()
+	if waitingCount != 0 {
+		t.Fatalf("canceled ticket should leave the queue, %d still waiting", waitingCount)
+	}
+}
+
+func TestConcurrencyLimitsFromSettings(t *testing.T) {
+	r, err := New(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("new runner failed: %v", err)
+	}
+	if got := r.ConcurrencyLimits(); got.Global != DefaultMaxConcurrentRuns || got.PerRepo != 0 || got.PerTool != 0 {
+		t.Fatalf("unexpected default limits without store: %+v", got)
+	}
+
+	st, err := state.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new state store failed: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	r.SetStateStore(st)
+
+	for key, value := range map[string]string{
+		SettingMaxConcurrentRuns:        "4",
+		SettingMaxConcurrentRunsPerRepo: "1",
+		SettingMaxConcurrentRunsPerTool: "bogus",
+	} {
+		if err := st.SetSetting(key, value); err != nil {
+			t.Fatalf("set %s failed: %v", key, err)
+		}
+	}
+	got := r.ConcurrencyLimits()
+	if got.Global != 4 || got.PerRepo != 1 || got.PerTool != 0 {
+		t.Fatalf("unexpected limits: %+v", got)
+	}
+
+	r.scheduler.mu.Lock()
+	cached := r.scheduler.limits
+	r.scheduler.mu.Unlock()
+	if cached.Global != DefaultMaxConcurrentRuns {
+		t.Fatalf("scheduler should keep its cached caps until rescheduled, got %+v", cached)
+	}
+	r.RescheduleRuns()
+	r.scheduler.mu.Lock()
+	cached = r.scheduler.limits
+	r.scheduler.mu.Unlock()
+	if cached != got {
+		t.Fatalf("rescheduling should reload the caps, got %+v want %+v", cached, got)
+	}
+}
+
+func TestSchedulerSetLimitsAdmitsWaitingRuns(t *testing.T) {
+	s := newScheduler(ConcurrencyLimits{Global: 1})
+
+	first := &runTicket{repo: "acme/api", tool: "claude"}
+	if err := s.acquire(context.Background(), first, nil); err != nil {
+		t.Fatalf("first acquire failed: %v", err)
+	}
+	second := &runTicket{repo: "acme/web", tool: "claude"}
+	done := runAsync(s, second)
+	select {
+	case err := <-done:
+		t.Fatalf("second run should wait for the global cap, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.setLimits(ConcurrencyLimits{Global: 2})
+	if err := waitForAcquire(done); err != nil {
+		t.Fatalf("raising the cap should admit the waiting run: %v", err)
+	}
+}
+
+func runAsync(s *scheduler, t *runTicket) chan error {
+	done := make(chan error, 1)
+	go func() { done <- s.acquire(context.Background(), t, nil) }()
+	return done
+}
+
+func waitForAcquire(done chan error) error {
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(2 * time.Second):
+		return errors.New("timed out waiting for scheduler")
+	}
+}
+
+func waitForPosition(t *testing.T, positions chan int, want int) {
+	t.Helper()
+	select {
+	case got := <-positions:
+		if got != want {
+			t.Fatalf("unexpected queue position: got %d want %d", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for queue position %d", want)
+	}
+}
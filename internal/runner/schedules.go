@@ -0,0 +1,154 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// DefaultScheduleInterval is how often fogd checks for due schedules.
+const DefaultScheduleInterval = 30 * time.Second
+
+// RunSchedules starts sessions for due schedules until ctx is cancelled.
+// Schedules are read from the state store on every tick, so changes made
+// through the CLI take effect without restarting fogd. An activation missed
+// while fogd was down fires once on the next tick.
+func (r *Runner) RunSchedules(ctx context.Context, interval time.Duration) error {
+	if r.state == nil {
+		return errors.New("state store not configured")
+	}
+	if interval <= 0 {
+		interval = DefaultScheduleInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		r.fireDueSchedules(time.Now())
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Runner) fireDueSchedules(now time.Time) {
+	due, err := r.state.DueSchedules(now)
+	if err != nil {
+		log.Printf("schedules: %v", err)
+		return
+	}
+	for _, schedule := range due {
+		// Advance before starting so a slow or failing start cannot fire
+		// the same activation twice.
+		if err := r.state.AdvanceSchedule(schedule.ID, now); err != nil {
+			log.Printf("schedule %s: %v", schedule.Name, err)
+			continue
+		}
+		if _, err := r.FireSchedule(schedule, now); err != nil {
+			log.Printf("schedule %s: %v", schedule.Name, err)
+		}
+	}
+}
+
+// FireSchedule starts one session for schedule and records the outcome in
+// its history. The activation is skipped while the session started by the
+// previous activation is still busy.
+func (r *Runner) FireSchedule(schedule state.Schedule, firedAt time.Time) (state.ScheduleRun, error) {
+	if r.state == nil {
+		return state.ScheduleRun{}, errors.New("state store not configured")
+	}
+	record := state.ScheduleRun{ScheduleID: schedule.ID, FiredAt: firedAt}
+
+	busySession, err := r.scheduleBusySession(schedule.ID)
+	if err != nil {
+		return state.ScheduleRun{}, err
+	}
+	if busySession != "" {
+		record.Status = state.ScheduleRunSkipped
+		record.SessionID = busySession
+		record.Error = "previous session still running"
+		return r.state.RecordScheduleRun(record)
+	}
+
+	session, run, err := r.startScheduledSession(schedule, firedAt)
+	if err != nil {
+		record.Status = state.ScheduleRunFailed
+		record.Error = err.Error()
+		if _, recErr := r.state.RecordScheduleRun(record); recErr != nil {
+			return state.ScheduleRun{}, recErr
+		}
+		return record, err
+	}
+	record.Status = state.ScheduleRunStarted
+	record.SessionID = session.ID
+	record.RunID = run.ID
+	return r.state.RecordScheduleRun(record)
+}
+
+// scheduleBusySession returns the session started by the schedule's last
+// activation when it is still running.
+func (r *Runner) scheduleBusySession(scheduleID string) (string, error) {
+	history, err := r.state.ListScheduleRuns(scheduleID, 20)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range history {
+		if entry.Status != state.ScheduleRunStarted || entry.SessionID == "" {
+			continue
+		}
+		session, found, err := r.state.GetSession(entry.SessionID)
+		if err != nil {
+			return "", err
+		}
+		if found && session.Busy {
+			return session.ID, nil
+		}
+		return "", nil
+	}
+	return "", nil
+}
+
+func (r *Runner) startScheduledSession(schedule state.Schedule, firedAt time.Time) (state.Session, state.Run, error) {
+	repo, found, err := r.state.GetRepoByName(schedule.RepoName)
+	if err != nil {
+		return state.Session{}, state.Run{}, err
+	}
+	if !found {
+		return state.Session{}, state.Run{}, fmt.Errorf("unknown repo: %s", schedule.RepoName)
+	}
+
+	prompt := schedule.Prompt
+	if schedule.Template != "" {
+		prompt, err = r.state.RenderTemplate(schedule.Template, schedule.Vars, schedule.Prompt)
+		if err != nil {
+			return state.Session{}, state.Run{}, err
+		}
+	}
+
+	return r.StartSessionAsync(StartSessionOptions{
+		RepoName: repo.Name,
+		RepoPath: repo.BaseWorktreePath,
+		Branch:   r.scheduleBranchName(schedule, firedAt),
+		Tool:     schedule.Tool,
+		Model:    schedule.Model,
+		Prompt:   prompt,
+		AutoPR:   schedule.AutoPR,
+		Template: schedule.Template,
+	})
+}
+
+// scheduleBranchName names the branch for one activation, e.g.
+// fog/update-deps-20261019-0900.
+func (r *Runner) scheduleBranchName(schedule state.Schedule, firedAt time.Time) string {
+	prefix := "fog"
+	if stored, found, err := r.state.GetSetting("branch_prefix"); err == nil && found && strings.TrimSpace(stored) != "" {
+		prefix = strings.Trim(strings.TrimSpace(stored), "/")
+	}
+	return prefix + "/" + schedule.Name + "-" + firedAt.Local().Format("20060102-1504")
+}
@@ -0,0 +1,112 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestFireScheduleSkipsWhilePreviousSessionBusy(t *testing.T) {
+	r, st := newLimitsTestRunner(t)
+	schedule, err := st.CreateSchedule(state.Schedule{
+		ID:       "sched-1",
+		Name:     "update-deps",
+		RepoName: "acme/api",
+		Cron:     "0 9 * * mon",
+		Prompt:   "update go deps",
+		Enabled:  true,
+	})
+	if err != nil {
+		t.Fatalf("create schedule failed: %v", err)
+	}
+	session := state.Session{ID: "session-1", RepoName: "acme/api", Branch: "fog/update-deps-1", WorktreePath: "/tmp/wt", Tool: "claude", Status: "RUNNING", Busy: true}
+	if err := st.CreateSession(session); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	if _, err := st.RecordScheduleRun(state.ScheduleRun{ScheduleID: schedule.ID, Status: state.ScheduleRunStarted, SessionID: session.ID}); err != nil {
+		t.Fatalf("record schedule run failed: %v", err)
+	}
+
+	got, err := r.FireSchedule(schedule, time.Now())
+	if err != nil {
+		t.Fatalf("fire schedule failed: %v", err)
+	}
+	if got.Status != state.ScheduleRunSkipped || got.SessionID != session.ID {
+		t.Fatalf("expected skip while busy, got %+v", got)
+	}
+	history, err := st.ListScheduleRuns(schedule.ID, 10)
+	if err != nil {
+		t.Fatalf("list schedule runs failed: %v", err)
+	}
+	if len(history) != 2 || history[0].Status != state.ScheduleRunSkipped {
+		t.Fatalf("unexpected history: %+v", history)
+	}
+}
+
+func TestFireScheduleRecordsFailure(t *testing.T) {
+	r, st := newLimitsTestRunner(t)
+	schedule, err := st.CreateSchedule(state.Schedule{
+		ID:       "sched-1",
+		Name:     "nightly",
+		RepoName: "acme/missing",
+		Cron:     "@daily",
+		Prompt:   "tidy up",
+		Enabled:  true,
+	})
+	if err != nil {
+		t.Fatalf("create schedule failed: %v", err)
+	}
+	if _, err := r.FireSchedule(schedule, time.Now()); err == nil {
+		t.Fatal("expected error for unknown repo")
+	}
+	history, err := st.ListScheduleRuns(schedule.ID, 10)
+	if err != nil {
+		t.Fatalf("list schedule runs failed: %v", err)
+	}
+	if len(history) != 1 || history[0].Status != state.ScheduleRunFailed || history[0].Error == "" {
+		t.Fatalf("unexpected history: %+v", history)
+	}
+}
+
+func TestFireDueSchedulesAdvancesNextRun(t *testing.T) {
+	r, st := newLimitsTestRunner(t)
+	schedule, err := st.CreateSchedule(state.Schedule{
+		ID:       "sched-1",
+		Name:     "hourly",
+		RepoName: "acme/missing",
+		Cron:     "@hourly",
+		Prompt:   "check",
+		Enabled:  true,
+	})
+	if err != nil {
+		t.Fatalf("create schedule failed: %v", err)
+	}
+	now := schedule.NextRunAt.Add(time.Second)
+	r.fireDueSchedules(now)
+
+	got, _, err := st.GetSchedule(schedule.ID)
+	if err != nil {
+		t.Fatalf("get schedule failed: %v", err)
+	}
+	if got.LastRunAt == nil || got.NextRunAt == nil || !got.NextRunAt.After(now) {
+		t.Fatalf("expected schedule advanced past %s, got %+v", now, got)
+	}
+	if due, _ := st.DueSchedules(now); len(due) != 0 {
+		t.Fatalf("expected nothing due after firing, got %d", len(due))
+	}
+}
+
+func TestScheduleBranchName(t *testing.T) {
+	r, st := newLimitsTestRunner(t)
+	firedAt := time.Date(2026, 10, 19, 9, 0, 0, 0, time.Local)
+	if got := r.scheduleBranchName(state.Schedule{Name: "update-deps"}, firedAt); got != "fog/update-deps-20261019-0900" {
+		t.Fatalf("unexpected branch: %s", got)
+	}
+	if err := st.SetSetting("branch_prefix", "bots"); err != nil {
+		t.Fatalf("set branch prefix failed: %v", err)
+	}
+	if got := r.scheduleBranchName(state.Schedule{Name: "update-deps"}, firedAt); got != "bots/update-deps-20261019-0900" {
+		t.Fatalf("unexpected branch: %s", got)
+	}
+}
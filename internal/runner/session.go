@@ -7,13 +7,15 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/darkLord19/foglet/internal/ai"
-	"github.com/darkLord19/foglet/internal/ghcli"
+	"github.com/darkLord19/foglet/internal/forge"
 	"github.com/darkLord19/foglet/internal/proc"
+	"github.com/darkLord19/foglet/internal/projectcfg"
 	"github.com/darkLord19/foglet/internal/state"
 	"github.com/darkLord19/foglet/internal/task"
 	"github.com/darkLord19/foglet/internal/util"
@@ -22,13 +24,38 @@ import (
 
 var nonWorktreeNameChar = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
 
+// MaxFixAttempts caps the auto-fix attempts a single run may make.
+const MaxFixAttempts = projectcfg.MaxFixAttempts
+
+// Data values of "validate" run events.
+const (
+	validationPassed = "passed"
+	validationFailed = "failed"
+)
+
 const commitMsgInstructions = `
 
 IMPORTANT: Your response MUST end with a suggested git commit message for the changes you made, wrapped in <commit_message> tags.
-Use Conventional Commits style (e.g., <commit_message>feat: add login functionality</commit_message>).
+%s
 Do not include any other text inside the tags.
 `
 
+const conventionalCommitStyle = "Use Conventional Commits style (e.g., <commit_message>feat: add login functionality</commit_message>)."
+
+// commitMsgInstructionsFor returns the commit message request appended to
+// tool prompts, using the project's commit style when one is declared.
+func commitMsgInstructionsFor(style string) string {
+	return fmt.Sprintf(commitMsgInstructions, commitStyleRule(style))
+}
+
+func commitStyleRule(style string) string {
+	style = strings.TrimSpace(style)
+	if style == "" || strings.EqualFold(style, "conventional") {
+		return conventionalCommitStyle
+	}
+	return "Commit message style: " + style
+}
+
 type activeRun struct {
 	sessionID string
 	runID     string
@@ -46,10 +73,22 @@ type StartSessionOptions struct {
 	AutoPR      bool
 	SetupCmd    string
 	Validate    bool
+	HasValidate bool
 	ValidateCmd string
 	BaseBranch  string
 	CommitMsg   string
 	PRTitle     string
+	Priority    int
+	// Timeout, MaxTokens and MaxCostUSD override the configured run limits
+	// for this run when positive.
+	Timeout    time.Duration
+	MaxTokens  int64
+	MaxCostUSD float64
+	// FixAttempts is how many times a failed validation is fed back to the
+	// tool before the run fails. Zero disables auto-fix.
+	FixAttempts int
+	// Template names the prompt template Prompt was rendered from, if any.
+	Template string
 }
 
 // StartSession creates a new session (branch/worktree) and executes the initial prompt.
@@ -75,11 +114,15 @@ func (r *Runner) StartSessionAsync(opts StartSessionOptions) (state.Session, sta
 }
 
 // ContinueSession appends one follow-up run to an existing session.
+// When the session is busy the run is queued and returned in QUEUED state.
 func (r *Runner) ContinueSession(sessionID, prompt string) (state.Run, error) {
 	session, run, execOpts, err := r.prepareFollowUpRun(sessionID, prompt)
 	if err != nil {
 		return state.Run{}, err
 	}
+	if run.State == string(task.StateQueued) {
+		return run, nil
+	}
 	err = r.executeSessionRun(session, run, execOpts)
 	updatedRun, found, runErr := r.state.GetRun(run.ID)
 	if runErr != nil {
@@ -95,11 +138,15 @@ func (r *Runner) ContinueSession(sessionID, prompt string) (state.Run, error) {
 }
 
 // ContinueSessionAsync appends one follow-up run and executes it in the background.
+// When the session is busy the run is queued and started once the session frees up.
 func (r *Runner) ContinueSessionAsync(sessionID, prompt string) (state.Run, error) {
 	session, run, execOpts, err := r.prepareFollowUpRun(sessionID, prompt)
 	if err != nil {
 		return state.Run{}, err
 	}
+	if run.State == string(task.StateQueued) {
+		return run, nil
+	}
 	go func(s state.Session, ru state.Run, eo sessionRunOptions) {
 		_ = r.executeSessionRun(s, ru, eo)
 	}(session, run, execOpts)
@@ -116,10 +163,16 @@ type ForkSessionOptions struct {
 	HasAutoPR   bool
 	SetupCmd    string
 	Validate    bool
+	HasValidate bool
 	ValidateCmd string
 	BaseBranch  string
 	CommitMsg   string
 	PRTitle     string
+	Priority    int
+	Timeout     time.Duration
+	MaxTokens   int64
+	MaxCostUSD  float64
+	FixAttempts int
 }
 
 // ForkSession creates a new session from an existing one and runs immediately.
@@ -168,6 +221,11 @@ func (r *Runner) prepareSession(opts StartSessionOptions) (state.Session, state.
 	opts.BaseBranch = strings.TrimSpace(opts.BaseBranch)
 	opts.CommitMsg = strings.TrimSpace(opts.CommitMsg)
 
+	project, err := r.applyProjectConfig(&opts)
+	if err != nil {
+		return state.Session{}, state.Run{}, sessionRunOptions{}, err
+	}
+
 	switch {
 	case opts.RepoName == "":
 		return state.Session{}, state.Run{}, sessionRunOptions{}, errors.New("repo name is required")
@@ -212,8 +270,10 @@ func (r *Runner) prepareSession(opts StartSessionOptions) (state.Session, state.
 		ID:           runID,
 		SessionID:    session.ID,
 		Prompt:       opts.Prompt,
+		Template:     strings.TrimSpace(opts.Template),
 		WorktreePath: worktreePath,
 		State:        string(task.StateCreated),
+		Priority:     opts.Priority,
 		CreatedAt:    now,
 		UpdatedAt:    now,
 	}
@@ -223,13 +283,22 @@ func (r *Runner) prepareSession(opts StartSessionOptions) (state.Session, state.
 	}
 
 	return session, run, sessionRunOptions{
-		Prompt:      opts.Prompt,
-		SetupCmd:    opts.SetupCmd,
-		Validate:    opts.Validate,
-		ValidateCmd: opts.ValidateCmd,
-		BaseBranch:  opts.BaseBranch,
-		CommitMsg:   opts.CommitMsg,
-		PRTitle:     opts.PRTitle,
+		Prompt:         opts.Prompt,
+		PromptPreamble: project.PromptPreamble,
+		SetupCmd:       opts.SetupCmd,
+		Validate:       opts.Validate,
+		ValidateCmd:    opts.ValidateCmd,
+		BaseBranch:     opts.BaseBranch,
+		CommitMsg:      opts.CommitMsg,
+		CommitStyle:    project.Commit.Style,
+		PRTitle:        opts.PRTitle,
+		PRTemplate:     project.PR.Template,
+		FixAttempts:    opts.FixAttempts,
+		Limits: r.resolveRunLimits(opts.RepoName, RunLimits{
+			Timeout:    opts.Timeout,
+			MaxTokens:  opts.MaxTokens,
+			MaxCostUSD: opts.MaxCostUSD,
+		}),
 	}, nil
 }
 
@@ -253,27 +322,58 @@ func (r *Runner) prepareFollowUpRun(sessionID, prompt string) (state.Session, st
 	if !found {
 		return state.Session{}, state.Run{}, sessionRunOptions{}, fmt.Errorf("session %q not found", sessionID)
 	}
-	if session.Busy {
-		return state.Session{}, state.Run{}, sessionRunOptions{}, fmt.Errorf("session %q is busy", sessionID)
-	}
-	if err := r.state.SetSessionBusy(session.ID, true); err != nil {
-		return state.Session{}, state.Run{}, sessionRunOptions{}, err
-	}
 	worktreePath := strings.TrimSpace(session.WorktreePath)
 	if worktreePath == "" {
-		_ = r.state.SetSessionBusy(session.ID, false)
 		return state.Session{}, state.Run{}, sessionRunOptions{}, fmt.Errorf("session %q has no worktree path", session.ID)
 	}
 
-	runID := uuid.New().String()
+	// Follow-ups keep the priority the session was started with.
+	priority := 0
+	if latest, found, err := r.state.GetLatestRun(session.ID); err == nil && found {
+		priority = latest.Priority
+	}
+
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
 
+	runID := uuid.New().String()
 	now := time.Now().UTC()
+
+	claimed, err := r.state.TryClaimSession(session.ID)
+	if err != nil {
+		return state.Session{}, state.Run{}, sessionRunOptions{}, err
+	}
+	if !claimed {
+		run := state.Run{
+			ID:           runID,
+			SessionID:    session.ID,
+			Prompt:       prompt,
+			WorktreePath: worktreePath,
+			State:        string(task.StateQueued),
+			Priority:     priority,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+		position, err := r.state.EnqueueRun(run)
+		if err != nil {
+			return state.Session{}, state.Run{}, sessionRunOptions{}, err
+		}
+		run.QueuePosition = position
+		_ = r.state.AppendRunEvent(state.RunEvent{
+			RunID:   run.ID,
+			Type:    "queued",
+			Message: fmt.Sprintf("Session busy; queued at position %d", position),
+		})
+		return session, run, sessionRunOptions{}, nil
+	}
+
 	run := state.Run{
 		ID:           runID,
 		SessionID:    session.ID,
 		Prompt:       prompt,
 		WorktreePath: worktreePath,
 		State:        string(task.StateCreated),
+		Priority:     priority,
 		CreatedAt:    now,
 		UpdatedAt:    now,
 	}
@@ -286,15 +386,33 @@ func (r *Runner) prepareFollowUpRun(sessionID, prompt string) (state.Session, st
 		return state.Session{}, state.Run{}, sessionRunOptions{}, err
 	}
 
+	return session, run, r.followUpRunOptions(session, prompt), nil
+}
+
+func (r *Runner) followUpRunOptions(session state.Session, prompt string) sessionRunOptions {
 	repo, _, _ := r.state.GetRepoByName(session.RepoName)
-	baseBranch := strings.TrimSpace(repo.DefaultBranch)
+	// A broken project file should not block follow-ups; it only supplies
+	// validation, auto-fix and PR and commit conventions here. The project's setup
+	// command is not rerun: the session worktree was set up by its first
+	// run and follow-ups keep working in it.
+	project, _ := projectcfg.Load(repo.BaseWorktreePath)
+	baseBranch := project.BaseBranch
+	if baseBranch == "" {
+		baseBranch = strings.TrimSpace(repo.DefaultBranch)
+	}
 	if baseBranch == "" {
 		baseBranch = "main"
 	}
-	return session, run, sessionRunOptions{
-		Prompt:     prompt,
-		BaseBranch: baseBranch,
-	}, nil
+	return sessionRunOptions{
+		Prompt:      prompt,
+		Validate:    project.Validate != "",
+		ValidateCmd: project.Validate,
+		BaseBranch:  baseBranch,
+		CommitStyle: project.Commit.Style,
+		PRTemplate:  project.PR.Template,
+		FixAttempts: project.FixAttempts,
+		Limits:      r.resolveRunLimits(session.RepoName, RunLimits{}),
+	}
 }
 
 func (r *Runner) prepareForkSession(sourceSessionID string, opts ForkSessionOptions) (StartSessionOptions, state.Session, error) {
@@ -391,10 +509,16 @@ func (r *Runner) prepareForkSession(sourceSessionID string, opts ForkSessionOpti
 		AutoPR:      autoPR,
 		SetupCmd:    opts.SetupCmd,
 		Validate:    opts.Validate,
+		HasValidate: opts.HasValidate,
 		ValidateCmd: opts.ValidateCmd,
 		BaseBranch:  baseBranch,
 		CommitMsg:   opts.CommitMsg,
 		PRTitle:     opts.PRTitle,
+		Priority:    opts.Priority,
+		Timeout:     opts.Timeout,
+		MaxTokens:   opts.MaxTokens,
+		MaxCostUSD:  opts.MaxCostUSD,
+		FixAttempts: opts.FixAttempts,
 	}, sourceSession, nil
 }
 
@@ -577,14 +701,142 @@ func (r *Runner) CancelSessionLatestRun(sessionID string) (state.Run, error) {
 	return latest, nil
 }
 
+// ListQueuedRuns returns the runs waiting in a session queue, head first.
+func (r *Runner) ListQueuedRuns(sessionID string) ([]state.Run, error) {
+	if r.state == nil {
+		return nil, errors.New("state store not configured")
+	}
+	return r.state.ListQueuedRuns(sessionID)
+}
+
+// ReorderSessionQueue sets a new execution order for a session's queued runs.
+func (r *Runner) ReorderSessionQueue(sessionID string, runIDs []string) ([]state.Run, error) {
+	if r.state == nil {
+		return nil, errors.New("state store not configured")
+	}
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+
+	if err := r.state.ReorderQueuedRuns(sessionID, runIDs); err != nil {
+		return nil, err
+	}
+	return r.state.ListQueuedRuns(sessionID)
+}
+
+// DropQueuedRun removes a run from a session queue before it starts.
+func (r *Runner) DropQueuedRun(sessionID, runID string) (state.Run, error) {
+	if r.state == nil {
+		return state.Run{}, errors.New("state store not configured")
+	}
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+
+	if err := r.state.RemoveQueuedRun(sessionID, runID, string(task.StateCancelled), "removed from queue"); err != nil {
+		return state.Run{}, err
+	}
+	_ = r.state.AppendRunEvent(state.RunEvent{
+		RunID:   runID,
+		Type:    "dequeued",
+		Message: "Removed from queue by user",
+	})
+	run, found, err := r.state.GetRun(runID)
+	if err != nil {
+		return state.Run{}, err
+	}
+	if !found {
+		return state.Run{}, fmt.Errorf("run %q disappeared", runID)
+	}
+	return run, nil
+}
+
+// releaseSession hands a session to its next queued run, or marks it idle
+// when nothing is waiting.
+func (r *Runner) releaseSession(sessionID string) error {
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+
+	next, found, err := r.state.NextQueuedRun(sessionID)
+	if err != nil || !found {
+		if busyErr := r.state.SetSessionBusy(sessionID, false); busyErr != nil && err == nil {
+			err = busyErr
+		}
+		return err
+	}
+
+	session, found, err := r.state.GetSession(sessionID)
+	if err == nil && !found {
+		err = fmt.Errorf("session %q disappeared", sessionID)
+	}
+	if err == nil {
+		err = r.state.DequeueRun(next.ID, string(task.StateCreated))
+	}
+	if err == nil {
+		err = r.state.UpdateSessionStatus(sessionID, string(task.StateCreated))
+	}
+	if err != nil {
+		_ = r.state.SetSessionBusy(sessionID, false)
+		return err
+	}
+
+	next.State = string(task.StateCreated)
+	next.QueuePosition = 0
+	_ = r.state.AppendRunEvent(state.RunEvent{
+		RunID:   next.ID,
+		Type:    "dequeued",
+		Message: "Starting queued run",
+	})
+	go func(s state.Session, ru state.Run, eo sessionRunOptions) {
+		_ = r.executeSessionRun(s, ru, eo)
+	}(session, next, r.followUpRunOptions(session, next.Prompt))
+	return nil
+}
+
+// acquireRunSlot waits for the scheduler to admit a run under the configured
+// concurrency caps, reporting queue position changes as run events.
+func (r *Runner) acquireRunSlot(ctx context.Context, session state.Session, run state.Run) (func(), error) {
+	ticket := &runTicket{
+		repo:     session.RepoName,
+		tool:     session.Tool,
+		priority: run.Priority,
+	}
+	waited := false
+	err := r.scheduler.acquire(ctx, ticket, func(position int) {
+		waited = true
+		_ = r.state.AppendRunEvent(state.RunEvent{
+			RunID:   run.ID,
+			Type:    "waiting",
+			Message: fmt.Sprintf("Waiting for a run slot (queue position %d)", position),
+			Data:    strconv.Itoa(position),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if waited {
+		_ = r.state.AppendRunEvent(state.RunEvent{
+			RunID:   run.ID,
+			Type:    "scheduled",
+			Message: "Run slot acquired",
+		})
+	}
+	return func() { r.scheduler.release(ticket) }, nil
+}
+
 type sessionRunOptions struct {
-	Prompt      string
-	SetupCmd    string
-	Validate    bool
-	ValidateCmd string
-	BaseBranch  string
-	CommitMsg   string
-	PRTitle     string
+	Prompt string
+	// PromptPreamble is sent to the tool ahead of Prompt but not stored
+	// on the run.
+	PromptPreamble string
+	SetupCmd       string
+	Validate       bool
+	ValidateCmd    string
+	BaseBranch     string
+	CommitMsg      string
+	CommitStyle    string
+	PRTitle        string
+	PRTemplate     string
+	FixAttempts    int
+	Limits         RunLimits
 }
 
 func (r *Runner) executeSessionRun(session state.Session, run state.Run, opts sessionRunOptions) (retErr error) {
@@ -600,44 +852,110 @@ func (r *Runner) executeSessionRun(session state.Session, run state.Run, opts se
 	if strings.TrimSpace(run.WorktreePath) == "" {
 		return errors.New("run worktree path is required")
 	}
-	ctx, cancel := context.WithCancel(context.Background())
-	r.registerActiveRun(session.ID, run.ID, cancel)
+	ctx, cancel := context.WithCancelCause(context.Background())
+	r.registerActiveRun(session.ID, run.ID, func() { cancel(context.Canceled) })
 	defer func() {
 		r.clearActiveRun(session.ID, run.ID)
-		cancel()
-		if err := r.state.SetSessionBusy(session.ID, false); err != nil && retErr == nil {
+		cancel(context.Canceled)
+		if err := r.releaseSession(session.ID); err != nil && retErr == nil {
 			retErr = err
 		}
 	}()
 
+	// Set when work is committed before the run fails, e.g. after auto-fix
+	// attempts are exhausted, so the failed run still points at it.
+	var committedSHA, committedMsg string
 	fail := func(phase string, err error) error {
 		terminalState := string(task.StateFailed)
 		eventType := "error"
 		message := phase + ": " + err.Error()
+		title := "Fog Session Failed"
+		notifyMsg := fmt.Sprintf("Failed on %s (%s): %v", session.Branch, session.RepoName, err)
 		if isCanceledError(err) {
-			terminalState = string(task.StateCancelled)
-			eventType = "cancelled"
-			message = phase + ": canceled"
+			// Timeouts and budget kills surface as a canceled process; the
+			// context cause says which limit fired.
+			switch cause := context.Cause(ctx); {
+			case errors.Is(cause, errRunTimedOut):
+				terminalState = string(task.StateTimedOut)
+				eventType = "timed_out"
+				err = fmt.Errorf("%w after %s", cause, opts.Limits.Timeout)
+				message = phase + ": " + err.Error()
+				title = "Fog Session Timed Out"
+				notifyMsg = fmt.Sprintf("Timed out on %s (%s)", session.Branch, session.RepoName)
+			case errors.Is(cause, errBudgetExceeded):
+				terminalState = string(task.StateBudgetExceeded)
+				eventType = "budget_exceeded"
+				err = cause
+				message = phase + ": " + err.Error()
+				title = "Fog Session Over Budget"
+				notifyMsg = fmt.Sprintf("Budget exceeded on %s (%s): %v", session.Branch, session.RepoName, err)
+			default:
+				terminalState = string(task.StateCancelled)
+				eventType = "cancelled"
+				message = phase + ": canceled"
+				title = "Fog Session Cancelled"
+				notifyMsg = fmt.Sprintf("Cancelled on %s (%s)", session.Branch, session.RepoName)
+			}
 		}
 		_ = r.state.AppendRunEvent(state.RunEvent{
 			RunID:   run.ID,
 			Type:    eventType,
 			Message: message,
 		})
-		_ = r.state.CompleteRun(run.ID, terminalState, "", "", err.Error())
+		_ = r.state.CompleteRun(run.ID, terminalState, committedSHA, committedMsg, err.Error())
 		_ = r.updateSessionStatusIfLatest(session.ID, run.ID, terminalState)
 		if r.notificationsEnabled() {
-			title := "Fog Session Failed"
-			msg := fmt.Sprintf("Failed on %s (%s): %v", session.Branch, session.RepoName, err)
-			if isCanceledError(err) {
-				title = "Fog Session Cancelled"
-				msg = fmt.Sprintf("Cancelled on %s (%s)", session.Branch, session.RepoName)
-			}
-			util.Notify(title, msg, session.ID)
+			util.Notify(title, notifyMsg, session.ID)
 		}
 		return err
 	}
 
+	release, err := r.acquireRunSlot(ctx, session, run)
+	if err != nil {
+		return fail("schedule", err)
+	}
+	defer release()
+
+	// The timeout covers the run itself, not time spent waiting for a slot.
+	if opts.Limits.Timeout > 0 {
+		var stop context.CancelFunc
+		ctx, stop = context.WithTimeoutCause(ctx, opts.Limits.Timeout, errRunTimedOut)
+		defer stop()
+	}
+	ctx, cancelBudget := context.WithCancelCause(ctx)
+	defer cancelBudget(nil)
+	// Budgets cover every tool invocation of the run, including auto-fix attempts.
+	var totalUsage ai.Usage
+	onUsage := func(usage ai.Usage) {
+		if err := opts.Limits.checkBudget(addUsage(totalUsage, usage)); err != nil {
+			cancelBudget(err)
+		}
+	}
+
+	env, secrets, err := r.repoRunEnv(session.RepoName)
+	if err != nil {
+		return fail("env", err)
+	}
+	r.state.SetRunSecrets(run.ID, secrets)
+	defer r.state.ClearRunSecrets(run.ID)
+
+	sandbox, err := r.runSandbox(ctx, session.RepoName, run.WorktreePath)
+	if err != nil {
+		return fail("sandbox", err)
+	}
+	if sandbox.Enabled() {
+		_ = r.state.SetRunSandbox(run.ID, sandbox.String())
+		_ = r.state.AppendRunEvent(state.RunEvent{
+			RunID:   run.ID,
+			Type:    "sandbox",
+			Message: "Running setup, validation and AI tool in sandbox: " + sandbox.String(),
+			Data:    strings.Join(sandbox.WritableDirs, "\n"),
+		})
+	}
+	// Fog's own git steps (commit, push, PR) run outside the sandbox and
+	// without the repo's env vars.
+	toolCtx := proc.WithEnv(proc.WithSandbox(ctx, sandbox), env)
+
 	if opts.SetupCmd != "" {
 		if err := r.setRunPhase(session.ID, run.ID, string(task.StateSetup)); err != nil {
 			return err
@@ -647,62 +965,120 @@ func (r *Runner) executeSessionRun(session state.Session, run state.Run, opts se
 			Type:    "setup",
 			Message: "Running setup command",
 		})
-		if err := r.runShell(ctx, run.WorktreePath, opts.SetupCmd); err != nil {
+		if err := r.runShell(toolCtx, run.WorktreePath, opts.SetupCmd); err != nil {
 			return fail("setup", err)
 		}
 	}
 
-	if err := r.setRunPhase(session.ID, run.ID, string(task.StateAIRunning)); err != nil {
-		return err
-	}
-	_ = r.state.AppendRunEvent(state.RunEvent{
-		RunID:   run.ID,
-		Type:    "ai_start",
-		Message: "Running AI tool",
-	})
-	streamWriter := newRunStreamWriter(r.state, run.ID)
 	conversationID := r.lookupConversationID(session.ID, run.ID)
-	aiOutput, nextConversationID, err := r.runToolWithOptions(
-		ctx,
-		session.Tool,
-		run.WorktreePath,
-		opts.Prompt+commitMsgInstructions,
-		session.Model,
-		conversationID,
-		streamWriter.Append,
-	)
-	streamWriter.Flush()
-	if err != nil {
-		if strings.TrimSpace(aiOutput) != "" {
+	commitInstructions := commitMsgInstructionsFor(opts.CommitStyle)
+	aiStarted := time.Now()
+	runAI := func(prompt, message string) (string, error) {
+		if err := r.setRunPhase(session.ID, run.ID, string(task.StateAIRunning)); err != nil {
+			return "", err
+		}
+		_ = r.state.AppendRunEvent(state.RunEvent{
+			RunID:   run.ID,
+			Type:    "ai_start",
+			Message: message,
+		})
+		streamWriter := newRunStreamWriter(r.state, run.ID)
+		transcript := newRunTranscriptWriter(r.state, run.ID)
+		output, nextConversationID, usage, err := r.runToolWithOptions(toolCtx, session.Tool, ai.ExecuteRequest{
+			Workdir:        run.WorktreePath,
+			Prompt:         prompt + commitInstructions,
+			Model:          session.Model,
+			ConversationID: conversationID,
+			OnUsage:        onUsage,
+			OnEvent:        transcript.Append,
+		}, streamWriter.Append)
+		streamWriter.Flush()
+		transcript.Flush()
+		totalUsage = addUsage(totalUsage, usage)
+		r.recordRunUsage(run.ID, session.Model, totalUsage, time.Since(aiStarted))
+		if err == nil && nextConversationID != "" {
+			conversationID = nextConversationID
+			_ = r.state.AppendRunEvent(state.RunEvent{
+				RunID: run.ID,
+				Type:  "ai_session",
+				Data:  nextConversationID,
+			})
+		}
+		if strings.TrimSpace(output) != "" {
 			_ = r.state.AppendRunEvent(state.RunEvent{
 				RunID:   run.ID,
 				Type:    "ai_output",
-				Message: truncate(aiOutput, 8000),
+				Message: truncate(output, 8000),
 			})
 		}
-		return fail("ai", err)
-	}
-	if nextConversationID != "" {
-		_ = r.state.AppendRunEvent(state.RunEvent{
-			RunID: run.ID,
-			Type:  "ai_session",
-			Data:  nextConversationID,
-		})
+		return output, err
 	}
-	if strings.TrimSpace(aiOutput) != "" {
-		_ = r.state.AppendRunEvent(state.RunEvent{
-			RunID:   run.ID,
-			Type:    "ai_output",
-			Message: truncate(aiOutput, 8000),
-		})
+
+	aiOutput, err := runAI(withPromptPreamble(opts.PromptPreamble, opts.Prompt), "Running AI tool")
+	if err != nil {
+		return fail("ai", err)
 	}
 
 	if opts.Validate && opts.ValidateCmd != "" {
-		if err := r.setRunPhase(session.ID, run.ID, string(task.StateValidating)); err != nil {
-			return err
-		}
-		if err := r.runShell(ctx, run.WorktreePath, opts.ValidateCmd); err != nil {
-			return fail("validate", err)
+		for attempt := 0; ; attempt++ {
+			if err := r.setRunPhase(session.ID, run.ID, string(task.StateValidating)); err != nil {
+				return err
+			}
+			validateErr := r.runShell(toolCtx, run.WorktreePath, opts.ValidateCmd)
+			if validateErr == nil {
+				_ = r.state.AppendRunEvent(state.RunEvent{
+					RunID:   run.ID,
+					Type:    "validate",
+					Message: "Validation passed",
+					Data:    validationPassed,
+				})
+				break
+			}
+			if isCanceledError(validateErr) {
+				return fail("validate", validateErr)
+			}
+			_ = r.state.AppendRunEvent(state.RunEvent{
+				RunID:   run.ID,
+				Type:    "validate",
+				Message: truncate("Validation failed: "+validateErr.Error(), 4000),
+				Data:    validationFailed,
+			})
+
+			if attempt >= opts.FixAttempts {
+				if opts.FixAttempts > 0 {
+					// Keep the last attempt on the branch so it can be
+					// inspected or continued; it is never pushed.
+					validateErr = fmt.Errorf("%w (still failing after %d fix attempts)", validateErr, opts.FixAttempts)
+					sha, msg, changed, err := r.commitSessionChanges(ctx, session.Tool, run.WorktreePath, opts.Prompt, r.runCommitMessage(opts, aiOutput), opts.CommitStyle)
+					if err != nil {
+						return fail("commit", err)
+					}
+					if changed {
+						committedSHA, committedMsg = sha, msg
+					}
+				}
+				return fail("validate", validateErr)
+			}
+
+			_ = r.state.AppendRunEvent(state.RunEvent{
+				RunID:   run.ID,
+				Type:    "fix_attempt",
+				Message: fmt.Sprintf("Auto-fix attempt %d of %d", attempt+1, opts.FixAttempts),
+				Data:    strconv.Itoa(attempt + 1),
+			})
+			fixPrompt := validationFixPrompt(opts.ValidateCmd, validateErr)
+			if conversationID == "" {
+				// Without a resumable conversation the tool starts fresh and
+				// needs the original task for context.
+				fixPrompt = "Original task:\n" + opts.Prompt + "\n\n" + fixPrompt
+			}
+			fixOutput, err := runAI(fixPrompt, fmt.Sprintf("Running AI tool (fix attempt %d)", attempt+1))
+			if err != nil {
+				return fail("ai", err)
+			}
+			if extractCommitMessage(fixOutput) != "" || strings.TrimSpace(aiOutput) == "" {
+				aiOutput = fixOutput
+			}
 		}
 	}
 
@@ -710,12 +1086,7 @@ func (r *Runner) executeSessionRun(session state.Session, run state.Run, opts se
 		return err
 	}
 
-	extractedMsg := opts.CommitMsg
-	if extractedMsg == "" {
-		extractedMsg = extractCommitMessage(aiOutput)
-	}
-
-	commitSHA, commitMsg, changed, err := r.commitSessionChanges(ctx, session.Tool, run.WorktreePath, opts.Prompt, extractedMsg)
+	commitSHA, commitMsg, changed, err := r.commitSessionChanges(ctx, session.Tool, run.WorktreePath, opts.Prompt, r.runCommitMessage(opts, aiOutput), opts.CommitStyle)
 	if err != nil {
 		return fail("commit", err)
 	}
@@ -729,12 +1100,16 @@ func (r *Runner) executeSessionRun(session state.Session, run state.Run, opts se
 
 	// Push only when PR mode is enabled or a PR already exists for this session.
 	if changed && (session.AutoPR || strings.TrimSpace(session.PRURL) != "") {
+		f, err := r.sessionForge(session)
+		if err != nil {
+			return fail("push", err)
+		}
 		setUpstream := strings.TrimSpace(session.PRURL) == ""
-		if err := r.pushBranch(ctx, run.WorktreePath, session.Branch, setUpstream); err != nil {
+		if err := r.pushBranch(proc.WithEnv(ctx, f.GitEnv()), run.WorktreePath, session.Branch, setUpstream); err != nil {
 			return fail("push", err)
 		}
 		if session.AutoPR && strings.TrimSpace(session.PRURL) == "" {
-			prURL, err := r.createDraftPR(ctx, run.WorktreePath, opts.BaseBranch, session.Branch, opts.Prompt, session.Tool, session.ID, opts.PRTitle)
+			prURL, err := r.createDraftPR(ctx, f, run.WorktreePath, opts.BaseBranch, session.Branch, opts.Prompt, session.Tool, session.ID, opts.PRTitle, opts.PRTemplate)
 			if err != nil {
 				return fail("create-pr", err)
 			}
@@ -742,6 +1117,8 @@ func (r *Runner) executeSessionRun(session state.Session, run state.Run, opts se
 				return fail("store-pr", err)
 			}
 			session.PRURL = prURL
+			// Seed the PR status until the first sync reads it from the forge.
+			_ = r.state.SetSessionPRStatus(session.ID, state.PRStatus{State: "OPEN", Draft: true})
 			_ = r.state.AppendRunEvent(state.RunEvent{
 				RunID:   run.ID,
 				Type:    "pr",
@@ -769,31 +1146,25 @@ func (r *Runner) executeSessionRun(session state.Session, run state.Run, opts se
 }
 
 func (r *Runner) runTool(ctx context.Context, toolName, workdir, prompt string) (string, error) {
-	output, _, err := r.runToolWithOptions(ctx, toolName, workdir, prompt, "", "", nil)
+	output, _, _, err := r.runToolWithOptions(ctx, toolName, ai.ExecuteRequest{
+		Workdir: workdir,
+		Prompt:  prompt,
+	}, nil)
 	return output, err
 }
 
-func (r *Runner) runToolWithOptions(
-	ctx context.Context,
-	toolName, workdir, prompt, model, conversationID string,
-	onChunk func(string),
-) (string, string, error) {
+func (r *Runner) runToolWithOptions(ctx context.Context, toolName string, req ai.ExecuteRequest, onChunk func(string)) (string, string, ai.Usage, error) {
 	tool, err := ai.GetTool(toolName)
 	if err != nil {
-		return "", "", err
+		return "", "", ai.Usage{}, err
 	}
 	if !tool.IsAvailable() {
-		return "", "", fmt.Errorf("AI tool %s not available", toolName)
+		return "", "", ai.Usage{}, fmt.Errorf("AI tool %s not available", toolName)
 	}
 
-	result, err := ai.ExecuteWithOptionalStream(ctx, tool, ai.ExecuteRequest{
-		Workdir:        workdir,
-		Prompt:         prompt,
-		Model:          model,
-		ConversationID: conversationID,
-	}, onChunk)
+	result, err := ai.ExecuteWithOptionalStream(ctx, tool, req, onChunk)
 	if result == nil {
-		return "", "", err
+		return "", "", ai.Usage{}, err
 	}
 
 	output := strings.TrimSpace(result.Output)
@@ -802,12 +1173,74 @@ func (r *Runner) runToolWithOptions(
 	// When the tool returns an error, preserve any output so the caller can
 	// persist logs for debugging.
 	if err != nil {
-		return output, nextConversationID, err
+		return output, nextConversationID, result.Usage, err
 	}
 	if !result.Success {
-		return output, nextConversationID, fmt.Errorf("AI execution failed: %s", output)
+		return output, nextConversationID, result.Usage, fmt.Errorf("AI execution failed: %s", output)
+	}
+	return output, nextConversationID, result.Usage, nil
+}
+
+// runCommitMessage is the explicit commit message, or the one the tool suggested.
+func (r *Runner) runCommitMessage(opts sessionRunOptions, aiOutput string) string {
+	if opts.CommitMsg != "" {
+		return opts.CommitMsg
+	}
+	return extractCommitMessage(aiOutput)
+}
+
+// validationFixPrompt asks the tool to repair a failed validation.
+func validationFixPrompt(validateCmd string, validateErr error) string {
+	return fmt.Sprintf(
+		"The validation command `%s` failed after your changes:\n\n%s\n\n"+
+			"Fix the code so the validation command passes. Do not skip, disable or weaken the checks.",
+		validateCmd,
+		truncate(validateErr.Error(), 8000),
+	)
+}
+
+// addUsage sums the usage of two tool invocations of the same run.
+func addUsage(a, b ai.Usage) ai.Usage {
+	model := b.Model
+	if strings.TrimSpace(model) == "" {
+		model = a.Model
+	}
+	return ai.Usage{
+		InputTokens:         a.InputTokens + b.InputTokens,
+		OutputTokens:        a.OutputTokens + b.OutputTokens,
+		CacheReadTokens:     a.CacheReadTokens + b.CacheReadTokens,
+		CacheCreationTokens: a.CacheCreationTokens + b.CacheCreationTokens,
+		CostUSD:             a.CostUSD + b.CostUSD,
+		Model:               model,
+		DurationMS:          a.DurationMS + b.DurationMS,
+		NumTurns:            a.NumTurns + b.NumTurns,
+	}
+}
+
+// recordRunUsage stores the usage a tool reported for a run. The requested
+// model and the measured wall time fill in for tools that report neither.
+func (r *Runner) recordRunUsage(runID, model string, usage ai.Usage, elapsed time.Duration) {
+	if usage == (ai.Usage{}) {
+		// Tools that report no usage (aider, plain-output adapters) leave the
+		// columns NULL so they are not counted as zero-token runs.
+		return
 	}
-	return output, nextConversationID, nil
+	if strings.TrimSpace(usage.Model) == "" {
+		usage.Model = model
+	}
+	if usage.DurationMS <= 0 {
+		usage.DurationMS = elapsed.Milliseconds()
+	}
+	_ = r.state.SetRunUsage(runID, state.RunUsage{
+		Model:               usage.Model,
+		InputTokens:         usage.InputTokens,
+		OutputTokens:        usage.OutputTokens,
+		CacheReadTokens:     usage.CacheReadTokens,
+		CacheCreationTokens: usage.CacheCreationTokens,
+		CostUSD:             usage.CostUSD,
+		DurationMS:          usage.DurationMS,
+		NumTurns:            usage.NumTurns,
+	})
 }
 
 func (r *Runner) runShell(ctx context.Context, workdir, cmdline string) error {
@@ -823,7 +1256,7 @@ func (r *Runner) runShell(ctx context.Context, workdir, cmdline string) error {
 	return nil
 }
 
-func (r *Runner) commitSessionChanges(ctx context.Context, toolName, workdir, prompt, commitMsg string) (sha, finalMsg string, changed bool, err error) {
+func (r *Runner) commitSessionChanges(ctx context.Context, toolName, workdir, prompt, commitMsg, commitStyle string) (sha, finalMsg string, changed bool, err error) {
 	statusOut, err := proc.Run(ctx, workdir, "git", "status", "--porcelain")
 	if err != nil {
 		return "", "", false, fmt.Errorf("git status failed: %w", withOutput(err, statusOut))
@@ -838,7 +1271,7 @@ func (r *Runner) commitSessionChanges(ctx context.Context, toolName, workdir, pr
 
 	finalMsg = strings.TrimSpace(commitMsg)
 	if finalMsg == "" {
-		generated, err := r.generateCommitMessage(ctx, toolName, workdir, prompt)
+		generated, err := r.generateCommitMessage(ctx, toolName, workdir, prompt, commitStyle)
 		if isCanceledError(err) {
 			return "", "", false, err
 		}
@@ -861,7 +1294,7 @@ func (r *Runner) commitSessionChanges(ctx context.Context, toolName, workdir, pr
 	return strings.TrimSpace(string(shaOut)), finalMsg, true, nil
 }
 
-func (r *Runner) generateCommitMessage(ctx context.Context, toolName, workdir, prompt string) (string, error) {
+func (r *Runner) generateCommitMessage(ctx context.Context, toolName, workdir, prompt, style string) (string, error) {
 	summary, err := stagedDiffSummary(ctx, workdir)
 	if err != nil {
 		return "", err
@@ -876,13 +1309,14 @@ func (r *Runner) generateCommitMessage(ctx context.Context, toolName, workdir, p
 	commitPrompt := strings.TrimSpace(fmt.Sprintf(
 		"Generate a git commit message for the staged changes.\n"+
 			"Rules:\n"+
-			"- Use Conventional Commits style.\n"+
+			"- %s\n"+
 			"- Return plain text only.\n"+
 			"- First line <= 72 chars.\n"+
 			"- Optional body allowed.\n"+
 			"- Do not include code fences.\n\n"+
 			"Task prompt:\n%s\n\n"+
 			"Staged changes summary:\n%s\n",
+		commitStyleRule(style),
 		strings.TrimSpace(prompt),
 		summary,
 	))
@@ -992,11 +1426,20 @@ func (w *runStreamWriter) Append(chunk string) {
 	shouldFlush := w.buffer.Len() >= 1000 || time.Since(w.lastFlush) >= 600*time.Millisecond
 	w.mu.Unlock()
 	if shouldFlush {
-		w.Flush()
+		w.flush(false)
 	}
 }
 
+// streamHoldback is how much of an unfinished trailing line an intermediate
+// flush keeps buffered, so secrets are not split across events and slip
+// past redaction.
+const streamHoldback = 512
+
 func (w *runStreamWriter) Flush() {
+	w.flush(true)
+}
+
+func (w *runStreamWriter) flush(final bool) {
 	if w == nil || w.store == nil {
 		return
 	}
@@ -1004,6 +1447,12 @@ func (w *runStreamWriter) Flush() {
 	w.mu.Lock()
 	payload := w.buffer.String()
 	w.buffer.Reset()
+	if !final {
+		if idx := strings.LastIndexByte(payload, '\n'); idx >= 0 && len(payload)-idx-1 <= streamHoldback {
+			w.buffer.WriteString(payload[idx+1:])
+			payload = payload[:idx+1]
+		}
+	}
 	if strings.TrimSpace(payload) != "" {
 		w.lastFlush = time.Now().UTC()
 	}
@@ -1040,18 +1489,17 @@ func (r *Runner) pushBranch(ctx context.Context, workdir, branch string, setUpst
 	return nil
 }
 
-func (r *Runner) createDraftPR(ctx context.Context, workdir, baseBranch, branch, prompt, tool, sessionID, customTitle string) (string, error) {
-	if !ghcli.IsGhAvailable() {
-		return "", fmt.Errorf("gh CLI not available")
+func (r *Runner) createDraftPR(ctx context.Context, f forge.Forge, workdir, baseBranch, branch, prompt, tool, sessionID, customTitle, template string) (string, error) {
+	if err := f.Check(ctx); err != nil {
+		return "", err
 	}
-	title := resolvePRTitle(customTitle, prompt)
-	body := fmt.Sprintf("Generated by Fog session\n\nSession ID: %s\nAI Tool: %s\n\nPrompt:\n%s",
-		sessionID,
-		tool,
-		strings.TrimSpace(prompt),
-	)
-
-	return ghcli.CreatePRWithContext(ctx, workdir, title, body, baseBranch, branch, true)
+	return f.CreatePR(ctx, workdir, forge.NewPR{
+		Title: resolvePRTitle(customTitle, prompt),
+		Body:  prBody(template, sessionID, tool, prompt),
+		Base:  baseBranch,
+		Head:  branch,
+		Draft: true,
+	})
 }
 
 func (r *Runner) setRunPhase(sessionID, runID, phase string) error {
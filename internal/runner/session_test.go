@@ -50,7 +50,7 @@ func TestStartSessionValidatesRequiredFields(t *testing.T) {
 	}
 }
 
-func TestContinueSessionRejectsBusySession(t *testing.T) {
+func TestContinueSessionQueuesOnBusySession(t *testing.T) {
 	r, err := New(t.TempDir(), t.TempDir())
 	if err != nil {
 		t.Fatalf("new runner failed: %v", err)
@@ -90,9 +90,73 @@ func TestContinueSessionRejectsBusySession(t *testing.T) {
 		t.Fatalf("create session failed: %v", err)
 	}
 
-	_, err = r.ContinueSession("session-1", "follow up")
-	if err == nil || !strings.Contains(err.Error(), "is busy") {
-		t.Fatalf("expected busy session error, got %v", err)
+	run, err := r.ContinueSession("session-1", "follow up")
+	if err != nil {
+		t.Fatalf("expected follow-up on busy session to be queued, got %v", err)
+	}
+	if run.State != "QUEUED" || run.QueuePosition != 1 {
+		t.Fatalf("unexpected queued run: %+v", run)
+	}
+
+	queued, err := r.ListQueuedRuns("session-1")
+	if err != nil {
+		t.Fatalf("list queued runs failed: %v", err)
+	}
+	if len(queued) != 1 || queued[0].ID != run.ID {
+		t.Fatalf("unexpected queue contents: %+v", queued)
+	}
+
+	dropped, err := r.DropQueuedRun("session-1", run.ID)
+	if err != nil {
+		t.Fatalf("drop queued run failed: %v", err)
+	}
+	if dropped.State != "CANCELLED" {
+		t.Fatalf("dropped run should be cancelled: %+v", dropped)
+	}
+}
+
+func TestReleaseSessionClearsBusyWhenQueueEmpty(t *testing.T) {
+	r, err := New(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("new runner failed: %v", err)
+	}
+	st, err := state.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new state store failed: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	r.SetStateStore(st)
+
+	if _, err := st.UpsertRepo(state.Repo{
+		Name:             "acme/api",
+		URL:              "https://github.com/acme/api.git",
+		Host:             "github.com",
+		BarePath:         "/tmp/acme-api/repo.git",
+		BaseWorktreePath: "/tmp/acme-api/base",
+	}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	if err := st.CreateSession(state.Session{
+		ID:           "session-1",
+		RepoName:     "acme/api",
+		Branch:       "fog/test",
+		WorktreePath: "/tmp/worktree",
+		Tool:         "claude",
+		Status:       "COMPLETED",
+		Busy:         true,
+	}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+
+	if err := r.releaseSession("session-1"); err != nil {
+		t.Fatalf("release session failed: %v", err)
+	}
+	session, _, err := st.GetSession("session-1")
+	if err != nil {
+		t.Fatalf("get session failed: %v", err)
+	}
+	if session.Busy {
+		t.Fatal("expected session to be idle after release with empty queue")
 	}
 }
 
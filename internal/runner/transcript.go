@@ -0,0 +1,125 @@
+package runner
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/darkLord19/foglet/internal/ai"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+const (
+	transcriptTextLimit  = 4000
+	transcriptInputLimit = 2000
+)
+
+// runTranscriptWriter persists structured agent events as run events whose
+// type is the event kind and whose data is the JSON-encoded event.
+// Consecutive assistant_text events (streamed deltas) are coalesced into one.
+type runTranscriptWriter struct {
+	mu    sync.Mutex
+	store *state.Store
+	runID string
+	text  strings.Builder
+}
+
+func newRunTranscriptWriter(store *state.Store, runID string) *runTranscriptWriter {
+	return &runTranscriptWriter{store: store, runID: runID}
+}
+
+func (w *runTranscriptWriter) Append(event ai.Event) {
+	if w == nil || w.store == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if event.Kind == ai.EventAssistantText {
+		w.text.WriteString(event.Text)
+		return
+	}
+	w.flushTextLocked()
+	w.persist(event)
+}
+
+// Flush persists any buffered assistant text.
+func (w *runTranscriptWriter) Flush() {
+	if w == nil || w.store == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flushTextLocked()
+}
+
+func (w *runTranscriptWriter) flushTextLocked() {
+	text := strings.TrimSpace(w.text.String())
+	w.text.Reset()
+	if text == "" {
+		return
+	}
+	w.persist(ai.Event{Kind: ai.EventAssistantText, Text: text})
+}
+
+func (w *runTranscriptWriter) persist(event ai.Event) {
+	// Redact before encoding: JSON escapes characters such as < > & and
+	// quotes, so a secret containing them no longer matches once marshalled.
+	redact := func(text string) string { return w.store.RedactRunOutput(w.runID, text) }
+	event.Text = truncate(redact(event.Text), transcriptTextLimit)
+	event.Command = redact(event.Command)
+	event.Path = redact(event.Path)
+	if event.Input != nil {
+		event.Input = compactTranscriptValue(event.Input, redact).(map[string]any)
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	_ = w.store.AppendRunEvent(state.RunEvent{
+		RunID:   w.runID,
+		Type:    string(event.Kind),
+		Message: transcriptSummary(event),
+		Data:    string(data),
+	})
+}
+
+// transcriptSummary is the one-line message shown for an event.
+func transcriptSummary(event ai.Event) string {
+	switch event.Kind {
+	case ai.EventShellCommand:
+		return truncate("$ "+event.Command, 200)
+	case ai.EventFileEdit, ai.EventToolUse:
+		return truncate(strings.TrimSpace(event.ToolName+" "+event.Path), 200)
+	case ai.EventToolResult:
+		if event.IsError {
+			return truncate("Tool error: "+event.Text, 200)
+		}
+		return truncate(event.Text, 200)
+	default:
+		return truncate(event.Text, 200)
+	}
+}
+
+// compactTranscriptValue redacts strings inside tool inputs and truncates
+// long ones (file contents, patches) so one event cannot bloat the database.
+func compactTranscriptValue(value any, redact func(string) string) any {
+	switch node := value.(type) {
+	case string:
+		return truncate(redact(node), transcriptInputLimit)
+	case map[string]any:
+		out := make(map[string]any, len(node))
+		for key, child := range node {
+			out[key] = compactTranscriptValue(child, redact)
+		}
+		return out
+	case []any:
+		out := make([]any, len(node))
+		for i, child := range node {
+			out[i] = compactTranscriptValue(child, redact)
+		}
+		return out
+	default:
+		return value
+	}
+}
@@ -0,0 +1,110 @@
+package runner
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/ai"
+	"github.com/darkLord19/foglet/internal/redact"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestRunTranscriptWriterPersistsTypedEvents(t *testing.T) {
+	_, st := newLimitsTestRunner(t)
+	if err := st.CreateSession(state.Session{
+		ID:           "session-1",
+		RepoName:     "acme/api",
+		Branch:       "fog/test",
+		WorktreePath: "/tmp/wt",
+		Tool:         "gemini",
+		Status:       "AI_RUNNING",
+	}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	if err := st.CreateRun(state.Run{ID: "run-1", SessionID: "session-1", Prompt: "p", WorktreePath: "/tmp/wt", State: "AI_RUNNING"}); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+
+	w := newRunTranscriptWriter(st, "run-1")
+	w.Append(ai.Event{Kind: ai.EventAssistantText, Text: "Let me "})
+	w.Append(ai.Event{Kind: ai.EventAssistantText, Text: "fix that."})
+	w.Append(ai.Event{Kind: ai.EventFileEdit, ToolName: "write_file", Path: "main.go", Input: map[string]any{"content": strings.Repeat("x", 5000)}})
+	w.Append(ai.Event{Kind: ai.EventShellCommand, ToolName: "run_shell_command", Command: "go test ./..."})
+	w.Append(ai.Event{Kind: ai.EventAssistantText, Text: "Done."})
+	w.Append(ai.Event{Kind: ai.EventError, Text: "loop detected"})
+	w.Flush()
+
+	events, err := st.ListRunEvents("run-1", 10)
+	if err != nil {
+		t.Fatalf("list events failed: %v", err)
+	}
+	var types []string
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	if got := strings.Join(types, ","); got != "assistant_text,file_edit,shell_command,assistant_text,tool_error" {
+		t.Fatalf("unexpected event types: %s", got)
+	}
+	if events[0].Message != "Let me fix that." {
+		t.Fatalf("assistant text should be coalesced: %q", events[0].Message)
+	}
+	if events[2].Message != "$ go test ./..." {
+		t.Fatalf("unexpected shell summary: %q", events[2].Message)
+	}
+
+	var edit ai.Event
+	if err := json.Unmarshal([]byte(events[1].Data), &edit); err != nil {
+		t.Fatalf("event data should be JSON: %v", err)
+	}
+	if edit.Kind != ai.EventFileEdit || edit.Path != "main.go" {
+		t.Fatalf("unexpected decoded edit: %+v", edit)
+	}
+	if content, _ := edit.Input["content"].(string); len(content) > transcriptInputLimit+3 {
+		t.Fatalf("tool input should be truncated, got %d bytes", len(content))
+	}
+}
+
+func TestRunTranscriptWriterRedactsBeforeEncoding(t *testing.T) {
+	_, st := newLimitsTestRunner(t)
+	if err := st.CreateSession(state.Session{
+		ID:           "session-1",
+		RepoName:     "acme/api",
+		Branch:       "fog/test",
+		WorktreePath: "/tmp/wt",
+		Tool:         "claude",
+		Status:       "AI_RUNNING",
+	}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	if err := st.CreateRun(state.Run{ID: "run-1", SessionID: "session-1", Prompt: "p", WorktreePath: "/tmp/wt", State: "AI_RUNNING"}); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+	secret := `p<a>ss&"wo\rd`
+	st.SetRunSecrets("run-1", []string{secret})
+	defer st.ClearRunSecrets("run-1")
+
+	w := newRunTranscriptWriter(st, "run-1")
+	w.Append(ai.Event{Kind: ai.EventShellCommand, ToolName: "bash", Command: "login " + secret, Input: map[string]any{"command": "login " + secret, "args": []any{secret}}})
+	w.Append(ai.Event{Kind: ai.EventToolResult, Text: "token is " + secret})
+	w.Flush()
+
+	events, err := st.ListRunEvents("run-1", 10)
+	if err != nil {
+		t.Fatalf("list events failed: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	encoded, _ := json.Marshal(secret)
+	for _, event := range events {
+		for _, field := range []string{event.Message, event.Data} {
+			if strings.Contains(field, secret) || strings.Contains(field, strings.Trim(string(encoded), `"`)) {
+				t.Fatalf("secret leaked into %s event: %q", event.Type, field)
+			}
+		}
+		if !strings.Contains(event.Data, redact.Mask) {
+			t.Fatalf("expected %s event data to be masked: %q", event.Type, event.Data)
+		}
+	}
+}
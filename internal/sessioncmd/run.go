@@ -0,0 +1,329 @@
+// Package sessioncmd runs the session commands parsed by slackcmd against
+// the local runner and state store.
+package sessioncmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/runner"
+	"github.com/darkLord19/foglet/internal/slackcmd"
+	"github.com/darkLord19/foglet/internal/state"
+	"github.com/darkLord19/foglet/internal/task"
+	"github.com/google/uuid"
+)
+
+// diffStatLines caps the diff stat shown by the diff command.
+const diffStatLines = 20
+
+// Result is the outcome of a command. RunID is set when the command started
+// a run (fork, retry) so callers can report when it finishes.
+type Result struct {
+	Text      string
+	SessionID string
+	RunID     string
+}
+
+// Run executes a session command.
+func Run(ctx context.Context, r *runner.Runner, store *state.Store, cmd slackcmd.Command) (Result, error) {
+	if r == nil || store == nil {
+		return Result{}, errors.New("runner and state store are required")
+	}
+	switch cmd.Action {
+	case slackcmd.ActionHelp:
+		return Result{Text: slackcmd.Usage}, nil
+	case slackcmd.ActionSessions:
+		return listSessions(store)
+	}
+
+	session, err := ResolveSession(store, cmd.SessionID)
+	if err != nil {
+		return Result{}, err
+	}
+	switch cmd.Action {
+	case slackcmd.ActionStatus:
+		return Result{Text: statusText(store, session), SessionID: session.ID}, nil
+	case slackcmd.ActionCancel:
+		run, err := r.CancelSessionLatestRun(session.ID)
+		if err != nil {
+			return Result{}, err
+		}
+		return Result{
+			Text:      fmt.Sprintf("🛑 Cancellation requested for run `%s` on `%s`.", shortID(run.ID), session.Branch),
+			SessionID: session.ID,
+		}, nil
+	case slackcmd.ActionDiff:
+		return diffSummary(ctx, r, store, session)
+	case slackcmd.ActionRetry:
+		return retry(r, store, session)
+	case slackcmd.ActionFork:
+		return fork(r, store, session, cmd)
+	case slackcmd.ActionPRCreate:
+		updated, err := r.CreateSessionPR(ctx, session.ID)
+		if err != nil {
+			return Result{}, err
+		}
+		return Result{Text: "📝 Draft PR created: " + updated.PRURL, SessionID: session.ID}, nil
+	case slackcmd.ActionPROpen:
+		updated, err := r.MarkPRReady(ctx, session.ID)
+		if err != nil {
+			return Result{}, err
+		}
+		return Result{Text: "✅ PR ready for review: " + updated.PRURL, SessionID: session.ID}, nil
+	case slackcmd.ActionPRMerge:
+		updated, err := r.MergeSessionPR(ctx, session.ID, cmd.MergeMethod)
+		if err != nil {
+			return Result{}, err
+		}
+		return Result{Text: "🎉 PR merged: " + updated.PRURL, SessionID: session.ID}, nil
+	default:
+		return Result{}, fmt.Errorf("unknown session command %q", cmd.Action)
+	}
+}
+
+// ResolveSession finds a session by ID or by an unambiguous ID prefix.
+func ResolveSession(store *state.Store, id string) (state.Session, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return state.Session{}, errors.New("session id is required outside a session thread; use `sessions` to list them")
+	}
+	session, found, err := store.GetSession(id)
+	if err != nil {
+		return state.Session{}, err
+	}
+	if found {
+		return session, nil
+	}
+	sessions, err := store.ListSessions()
+	if err != nil {
+		return state.Session{}, err
+	}
+	var matches []state.Session
+	for _, s := range sessions {
+		if strings.HasPrefix(s.ID, id) {
+			matches = append(matches, s)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return state.Session{}, fmt.Errorf("session %q not found", id)
+	case 1:
+		return matches[0], nil
+	default:
+		return state.Session{}, fmt.Errorf("session prefix %q matches %d sessions", id, len(matches))
+	}
+}
+
+// AwaitRun polls a run until it finishes and returns its final state.
+func AwaitRun(ctx context.Context, store *state.Store, sessionID, runID string, interval time.Duration) (state.Session, state.Run, error) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		run, found, err := store.GetRun(runID)
+		if err != nil {
+			return state.Session{}, state.Run{}, err
+		}
+		if !found {
+			return state.Session{}, state.Run{}, fmt.Errorf("run %q not found", runID)
+		}
+		if task.State(run.State).IsTerminal() {
+			session, _, err := store.GetSession(sessionID)
+			return session, run, err
+		}
+		select {
+		case <-ctx.Done():
+			return state.Session{}, state.Run{}, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// OutcomeText describes a finished run.
+func OutcomeText(session state.Session, run state.Run) string {
+	if run.State != string(task.StateCompleted) {
+		msg := strings.TrimSpace(run.Error)
+		if msg == "" {
+			msg = strings.ToLower(run.State)
+		}
+		return fmt.Sprintf("❌ Run `%s` on `%s` failed: %s", shortID(run.ID), session.Branch, msg)
+	}
+	text := fmt.Sprintf("✅ Run `%s` completed on `%s`", shortID(run.ID), session.Branch)
+	if sha := strings.TrimSpace(run.CommitSHA); sha != "" {
+		text += fmt.Sprintf(" (commit `%s`)", shortID(sha))
+	}
+	if pr := strings.TrimSpace(session.PRURL); pr != "" {
+		text += "\nPR: " + pr
+	}
+	return text
+}
+
+func listSessions(store *state.Store) (Result, error) {
+	sessions, err := store.ListSessions()
+	if err != nil {
+		return Result{}, err
+	}
+	var lines []string
+	for _, s := range sessions {
+		if !isActive(s) {
+			continue
+		}
+		line := fmt.Sprintf("• `%s` %s `%s` — %s", shortID(s.ID), s.RepoName, s.Branch, s.Status)
+		if s.Busy {
+			line += " (running)"
+		}
+		if pr := strings.TrimSpace(s.PRURL); pr != "" {
+			line += " " + pr
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return Result{Text: "No active sessions."}, nil
+	}
+	return Result{Text: "Active sessions:\n" + strings.Join(lines, "\n")}, nil
+}
+
+// isActive reports whether a session is neither archived nor done with its
+// pull request.
+func isActive(s state.Session) bool {
+	if s.ArchivedAt != nil {
+		return false
+	}
+	switch s.Status {
+	case state.SessionStatusMerged, state.SessionStatusClosed:
+		return false
+	default:
+		return true
+	}
+}
+
+func statusText(store *state.Store, session state.Session) string {
+	lines := []string{fmt.Sprintf("*%s* `%s` (session `%s`): %s", session.RepoName, session.Branch, shortID(session.ID), session.Status)}
+	if run, found, err := store.GetLatestRun(session.ID); err == nil && found {
+		lines = append(lines, fmt.Sprintf("Latest run `%s`: %s — %s", shortID(run.ID), run.State, truncate(run.Prompt, 120)))
+		if msg := strings.TrimSpace(run.Error); msg != "" {
+			lines = append(lines, "Error: "+truncate(msg, 300))
+		}
+		if event, found, err := store.GetLatestRunEvent(run.ID, "validate"); err == nil && found {
+			lines = append(lines, "Validation: "+event.Data)
+		}
+	}
+	if pr := strings.TrimSpace(session.PRURL); pr != "" {
+		line := "PR: " + pr
+		if session.PR != nil {
+			desc := session.PR.State
+			if session.PR.Draft {
+				desc += ", draft"
+			}
+			if checks := session.PR.Checks.State; checks != "" {
+				desc += ", checks " + strings.ToLower(checks)
+			}
+			line += " (" + desc + ")"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func diffSummary(ctx context.Context, r *runner.Runner, store *state.Store, session state.Session) (Result, error) {
+	repo, found, err := store.GetRepoByName(session.RepoName)
+	if err != nil {
+		return Result{}, err
+	}
+	if !found {
+		return Result{}, fmt.Errorf("repo %q not found", session.RepoName)
+	}
+	diff, err := r.SessionDiff(ctx, session, repo)
+	if err != nil {
+		return Result{}, err
+	}
+	text := fmt.Sprintf("`%s...%s`: %s", diff.BaseBranch, diff.Branch, diff.Summary())
+	if stat := strings.TrimSpace(diff.Stat); stat != "" {
+		lines := strings.Split(stat, "\n")
+		// The last line is the summary shown above.
+		lines = lines[:len(lines)-1]
+		if len(lines) > diffStatLines {
+			more := len(lines) - diffStatLines
+			lines = append(lines[:diffStatLines], fmt.Sprintf(" … %d more files", more))
+		}
+		if len(lines) > 0 {
+			text += "\n```\n" + strings.Join(lines, "\n") + "\n```"
+		}
+	}
+	return Result{Text: text, SessionID: session.ID}, nil
+}
+
+func retry(r *runner.Runner, store *state.Store, session state.Session) (Result, error) {
+	latest, found, err := store.GetLatestRun(session.ID)
+	if err != nil {
+		return Result{}, err
+	}
+	if !found {
+		return Result{}, fmt.Errorf("session %q has no runs", session.ID)
+	}
+	if !task.State(latest.State).IsTerminal() {
+		return Result{}, fmt.Errorf("latest run `%s` is still %s", shortID(latest.ID), latest.State)
+	}
+	run, err := r.ContinueSessionAsync(session.ID, latest.Prompt)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Text:      fmt.Sprintf("🔁 Retrying run `%s` on `%s` as run `%s`.", shortID(latest.ID), session.Branch, shortID(run.ID)),
+		SessionID: session.ID,
+		RunID:     run.ID,
+	}, nil
+}
+
+func fork(r *runner.Runner, store *state.Store, source state.Session, cmd slackcmd.Command) (Result, error) {
+	branch := strings.TrimSpace(cmd.Branch)
+	if branch == "" {
+		branch = source.Branch + "-fork-" + uuid.NewString()[:6]
+	}
+	prompt := strings.TrimSpace(cmd.Prompt)
+	if prompt == "" {
+		latest, found, err := store.GetLatestRun(source.ID)
+		if err != nil {
+			return Result{}, err
+		}
+		if !found {
+			return Result{}, fmt.Errorf("session %q has no runs; pass a fork prompt", source.ID)
+		}
+		prompt = latest.Prompt
+	}
+	session, run, err := r.ForkSessionAsync(source.ID, runner.ForkSessionOptions{
+		Branch: branch,
+		Prompt: prompt,
+		Tool:   cmd.Tool,
+		Model:  cmd.Model,
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Text:      fmt.Sprintf("🍴 Forked `%s` into `%s` (session `%s`).", source.Branch, session.Branch, shortID(session.ID)),
+		SessionID: session.ID,
+		RunID:     run.ID,
+	}, nil
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
@@ -0,0 +1,135 @@
+package sessioncmd
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/runner"
+	"github.com/darkLord19/foglet/internal/slackcmd"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func newTestRunner(t *testing.T) (*runner.Runner, *state.Store) {
+	t.Helper()
+	store, err := state.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new state store failed: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	r, err := runner.New("", t.TempDir())
+	if err != nil {
+		t.Fatalf("new runner failed: %v", err)
+	}
+	r.SetStateStore(store)
+	if _, err := store.UpsertRepo(state.Repo{Name: "acme/api", URL: "https://github.com/acme/api.git", Host: "github.com", Owner: "acme", Repo: "api", BarePath: "/tmp/repo.git", BaseWorktreePath: "/tmp/base", DefaultBranch: "main"}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	return r, store
+}
+
+func createSession(t *testing.T, store *state.Store, id, branch, status, worktree string) {
+	t.Helper()
+	if err := store.CreateSession(state.Session{ID: id, RepoName: "acme/api", Branch: branch, WorktreePath: worktree, Tool: "claude", Status: status}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+}
+
+func TestRunListsAndResolvesSessions(t *testing.T) {
+	r, store := newTestRunner(t)
+	createSession(t, store, "abc11111-aaaa", "fog/otp", "COMPLETED", "/tmp/wt1")
+	createSession(t, store, "abc22222-bbbb", "fog/sso", "AI_RUNNING", "/tmp/wt2")
+	createSession(t, store, "def33333-cccc", "fog/old", state.SessionStatusMerged, "/tmp/wt3")
+
+	ctx := context.Background()
+	res, err := Run(ctx, r, store, slackcmd.Command{Action: slackcmd.ActionSessions})
+	if err != nil {
+		t.Fatalf("list sessions failed: %v", err)
+	}
+	if !strings.Contains(res.Text, "fog/otp") || !strings.Contains(res.Text, "fog/sso") || strings.Contains(res.Text, "fog/old") {
+		t.Fatalf("unexpected session list:\n%s", res.Text)
+	}
+
+	if _, err := Run(ctx, r, store, slackcmd.Command{Action: slackcmd.ActionStatus, SessionID: "abc"}); err == nil || !strings.Contains(err.Error(), "matches 2 sessions") {
+		t.Fatalf("expected ambiguous prefix error, got %v", err)
+	}
+	if _, err := Run(ctx, r, store, slackcmd.Command{Action: slackcmd.ActionStatus}); err == nil || !strings.Contains(err.Error(), "session id is required") {
+		t.Fatalf("expected missing session error, got %v", err)
+	}
+	if _, err := Run(ctx, r, store, slackcmd.Command{Action: slackcmd.ActionStatus, SessionID: "zzz"}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	if err := store.CreateRun(state.Run{ID: "run-1", SessionID: "abc22222-bbbb", Prompt: "add sso", WorktreePath: "/tmp/wt2", State: "AI_RUNNING"}); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+	if err := store.AppendRunEvent(state.RunEvent{RunID: "run-1", Type: "validate", Data: "passed"}); err != nil {
+		t.Fatalf("append event failed: %v", err)
+	}
+	res, err = Run(ctx, r, store, slackcmd.Command{Action: slackcmd.ActionStatus, SessionID: "abc2"})
+	if err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	if res.SessionID != "abc22222-bbbb" || !strings.Contains(res.Text, "AI_RUNNING — add sso") || !strings.Contains(res.Text, "Validation: passed") {
+		t.Fatalf("unexpected status %+v", res)
+	}
+
+	// A run still in progress can be neither retried nor, without an
+	// active executor, cancelled.
+	if _, err := Run(ctx, r, store, slackcmd.Command{Action: slackcmd.ActionRetry, SessionID: "abc2"}); err == nil || !strings.Contains(err.Error(), "still AI_RUNNING") {
+		t.Fatalf("expected retry to refuse a running run, got %v", err)
+	}
+	if _, err := Run(ctx, r, store, slackcmd.Command{Action: slackcmd.ActionCancel, SessionID: "abc2"}); err == nil || !strings.Contains(err.Error(), "not active") {
+		t.Fatalf("expected cancel error, got %v", err)
+	}
+	if _, err := Run(ctx, r, store, slackcmd.Command{Action: slackcmd.ActionPROpen, SessionID: "abc1"}); err == nil || !strings.Contains(err.Error(), "has no pull request") {
+		t.Fatalf("expected missing PR error, got %v", err)
+	}
+}
+
+func TestRunDiffSummarizesBranch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	r, store := newTestRunner(t)
+	repo := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-b", "main")
+	git("config", "user.email", "test@example.com")
+	git("config", "user.name", "Test User")
+	git("commit", "--allow-empty", "-m", "init")
+	git("checkout", "-b", "fog/otp")
+	if err := os.WriteFile(filepath.Join(repo, "otp.go"), []byte("package otp\n"), 0o644); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+	git("add", ".")
+	git("commit", "-m", "add otp")
+	createSession(t, store, "sess-1", "fog/otp", "COMPLETED", repo)
+
+	res, err := Run(context.Background(), r, store, slackcmd.Command{Action: slackcmd.ActionDiff, SessionID: "sess-1"})
+	if err != nil {
+		t.Fatalf("diff failed: %v", err)
+	}
+	if !strings.Contains(res.Text, "`main...fog/otp`: 1 file changed, 1 insertion(+)") || !strings.Contains(res.Text, "otp.go") {
+		t.Fatalf("unexpected diff summary:\n%s", res.Text)
+	}
+}
+
+func TestTruncateKeepsRunesWhole(t *testing.T) {
+	if got := truncate("fix  the\nlogin — flow", 10); got != "fix the lo…" {
+		t.Fatalf("unexpected truncation: %q", got)
+	}
+	if got := truncate("日本語のテスト", 3); got != "日本語…" {
+		t.Fatalf("unexpected truncation: %q", got)
+	}
+}
@@ -6,7 +6,11 @@ import (
 	"strings"
 )
 
-var optionPattern = regexp.MustCompile(`([a-zA-Z-]+)=('([^']*)'|"([^"]*)"|[^\s]+)`)
+var optionPattern = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9_.-]*)=('([^']*)'|"([^"]*)"|[^\s]+)`)
+
+// templateVarPrefix marks options that fill template variables
+// (var.pkg=internal/api).
+const templateVarPrefix = "var."
 
 type parsedCommand struct {
 	Repo       string
@@ -15,6 +19,8 @@ type parsedCommand struct {
 	AutoPR     bool
 	BranchName string
 	CommitMsg  string
+	Template   string
+	Vars       map[string]string
 	Prompt     string
 }
 
@@ -24,7 +30,7 @@ func parseCommandText(raw string) (*parsedCommand, error) {
 		text = strings.TrimSpace(text[len("@fog"):])
 	}
 	if text == "" {
-		return nil, fmt.Errorf("invalid command format. Use: @fog [repo='' tool='' model='' autopr=true/false branch-name='' commit-msg=''] prompt")
+		return nil, fmt.Errorf("invalid command format. Use: @fog [repo='' tool='' model='' autopr=true/false branch-name='' commit-msg='' template='' var.name=''] prompt")
 	}
 
 	if !strings.HasPrefix(text, "[") {
@@ -37,15 +43,26 @@ func parseCommandText(raw string) (*parsedCommand, error) {
 
 	optsText := strings.TrimSpace(text[1:end])
 	prompt := strings.TrimSpace(text[end+1:])
-	if prompt == "" {
-		return nil, fmt.Errorf("prompt is required")
-	}
 
 	opts, err := parseOptions(optsText)
 	if err != nil {
 		return nil, err
 	}
 
+	template := strings.TrimSpace(opts["template"])
+	vars := map[string]string{}
+	for key, value := range opts {
+		if name, ok := strings.CutPrefix(key, templateVarPrefix); ok {
+			vars[name] = value
+		}
+	}
+	if template == "" && len(vars) > 0 {
+		return nil, fmt.Errorf("var.* options require template")
+	}
+	if prompt == "" && template == "" {
+		return nil, fmt.Errorf("prompt is required")
+	}
+
 	repo := strings.TrimSpace(opts["repo"])
 	if repo == "" {
 		return nil, fmt.Errorf("repo is required")
@@ -71,6 +88,8 @@ func parseCommandText(raw string) (*parsedCommand, error) {
 		AutoPR:     autopr,
 		BranchName: strings.TrimSpace(opts["branch-name"]),
 		CommitMsg:  strings.TrimSpace(opts["commit-msg"]),
+		Template:   template,
+		Vars:       vars,
 		Prompt:     prompt,
 	}, nil
 }
@@ -83,6 +102,7 @@ func parseOptions(input string) (map[string]string, error) {
 		"autopr":      {},
 		"branch-name": {},
 		"commit-msg":  {},
+		"template":    {},
 	}
 
 	matches := optionPattern.FindAllStringSubmatchIndex(input, -1)
@@ -97,9 +117,15 @@ func parseOptions(input string) (map[string]string, error) {
 			return nil, fmt.Errorf("invalid options format near %q", strings.TrimSpace(input[cursor:m[0]]))
 		}
 
-		key := strings.ToLower(input[m[2]:m[3]])
-		if _, ok := allowed[key]; !ok {
-			return nil, fmt.Errorf("unknown option key: %s", key)
+		key := input[m[2]:m[3]]
+		if name, ok := strings.CutPrefix(key, templateVarPrefix); ok && name != "" {
+			// Variable names keep their case.
+			key = templateVarPrefix + name
+		} else {
+			key = strings.ToLower(key)
+			if _, ok := allowed[key]; !ok {
+				return nil, fmt.Errorf("unknown option key: %s", key)
+			}
 		}
 
 		value := input[m[4]:m[5]]
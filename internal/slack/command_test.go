@@ -43,6 +43,26 @@ func TestParseCommandTextInvalidAutoPR(t *testing.T) {
 	}
 }
 
+func TestParseCommandTextTemplate(t *testing.T) {
+	cmd, err := parseCommandText("@fog [repo='acme-api' template=bump-dep var.dep=cobra var.targetVersion='v1.9.0']")
+	if err != nil {
+		t.Fatalf("parseCommandText failed: %v", err)
+	}
+	if cmd.Template != "bump-dep" || cmd.Prompt != "" {
+		t.Fatalf("unexpected parsed command: %+v", cmd)
+	}
+	if len(cmd.Vars) != 2 || cmd.Vars["dep"] != "cobra" || cmd.Vars["targetVersion"] != "v1.9.0" {
+		t.Fatalf("unexpected vars: %v", cmd.Vars)
+	}
+
+	if _, err := parseCommandText("@fog [repo='acme-api' var.dep=cobra] bump it"); err == nil || !strings.Contains(err.Error(), "require template") {
+		t.Fatalf("expected template required error, got: %v", err)
+	}
+	if _, err := parseCommandText("@fog [repo='acme-api']"); err == nil || !strings.Contains(err.Error(), "prompt is required") {
+		t.Fatalf("expected prompt required error, got: %v", err)
+	}
+}
+
 func TestGenerateBranchName(t *testing.T) {
 	branch := generateBranchName("fog", "Add OTP login using Redis")
 	if !strings.HasPrefix(branch, "fog/") {
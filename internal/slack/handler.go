@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/darkLord19/foglet/internal/runner"
+	"github.com/darkLord19/foglet/internal/slackblocks"
+	"github.com/darkLord19/foglet/internal/slackcmd"
 	"github.com/darkLord19/foglet/internal/state"
 	"github.com/darkLord19/foglet/internal/task"
 	"github.com/darkLord19/foglet/internal/toolcfg"
@@ -70,6 +72,23 @@ func (h *Handler) HandleCommand(w http.ResponseWriter, r *http.Request) {
 		ResponseURL: r.FormValue("response_url"),
 	}
 
+	if sc, ok, err := slackcmd.Parse(cmd.Text); ok {
+		if err != nil {
+			h.sendErrorResponse(w, err.Error())
+			return
+		}
+		h.sendCommandAckResponse(w, cmd.Text)
+		// HTTP mode has no interactivity endpoint, so results stay plain
+		// text without buttons.
+		h.runSessionCommand(sc, func(text string, _ []slackblocks.Block) {
+			_ = postWebhookJSON(http.DefaultClient, cmd.ResponseURL, map[string]any{
+				"response_type": "in_channel",
+				"text":          text,
+			})
+		})
+		return
+	}
+
 	parsed, err := parseCommandText(cmd.Text)
 	if err != nil {
 		h.sendErrorResponse(w, err.Error())
@@ -112,6 +131,14 @@ func (h *Handler) buildTask(parsed *parsedCommand) (*task.Task, string, error) {
 		return nil, "", fmt.Errorf("repo %s has no base worktree path", parsed.Repo)
 	}
 
+	if parsed.Template != "" {
+		prompt, err := h.stateStore.RenderTemplate(parsed.Template, parsed.Vars, parsed.Prompt)
+		if err != nil {
+			return nil, "", err
+		}
+		parsed.Prompt = prompt
+	}
+
 	tool, err := toolcfg.ResolveTool(parsed.Tool, h.stateStore, "slack")
 	if err != nil {
 		return nil, "", err
@@ -155,8 +182,14 @@ func (h *Handler) buildTask(parsed *parsedCommand) (*task.Task, string, error) {
 		},
 	}
 
+	if parsed.Model != "" || parsed.Template != "" {
+		t.Metadata = map[string]any{}
+	}
 	if parsed.Model != "" {
-		t.Metadata = map[string]any{"model": parsed.Model}
+		t.Metadata["model"] = parsed.Model
+	}
+	if parsed.Template != "" {
+		t.Metadata["template"] = parsed.Template
 	}
 
 	return t, repo.BaseWorktreePath, nil
@@ -179,6 +212,18 @@ func (h *Handler) sendAckResponse(w http.ResponseWriter, t *task.Task) {
 	_ = json.NewEncoder(w).Encode(response)
 }
 
+// sendCommandAckResponse acknowledges a session command; its result follows
+// on the response URL.
+func (h *Handler) sendCommandAckResponse(w http.ResponseWriter, text string) {
+	response := map[string]any{
+		"response_type": "ephemeral",
+		"text":          fmt.Sprintf("⏳ Running `%s`", strings.TrimSpace(text)),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
 // sendErrorResponse sends an error response.
 func (h *Handler) sendErrorResponse(w http.ResponseWriter, errorMsg string) {
 	response := map[string]any{
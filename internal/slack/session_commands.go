@@ -0,0 +1,89 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/sessioncmd"
+	"github.com/darkLord19/foglet/internal/slackblocks"
+	"github.com/darkLord19/foglet/internal/slackcmd"
+	"github.com/darkLord19/foglet/internal/state"
+	"github.com/darkLord19/foglet/internal/task"
+)
+
+// sessionCommandPollInterval is how often a run started by fork or retry is
+// checked for completion.
+const sessionCommandPollInterval = 2 * time.Second
+
+// postFunc posts a message; blocks are optional and text is their
+// notification fallback. Posters that cannot receive button clicks ignore
+// the blocks.
+type postFunc func(text string, blocks []slackblocks.Block)
+
+// runSessionCommand runs a session command in the background and posts its
+// result. Commands that start a run (fork, retry) post the run's outcome
+// once it finishes.
+func (h *Handler) runSessionCommand(cmd slackcmd.Command, post postFunc) {
+	go func() {
+		ctx := context.Background()
+		res, err := sessioncmd.Run(ctx, h.runner, h.stateStore, cmd)
+		if err != nil {
+			post(fmt.Sprintf("❌ %s", err.Error()), nil)
+			return
+		}
+		if res.RunID == "" {
+			post(res.Text, nil)
+			return
+		}
+		post(res.Text, slackblocks.RunningBlocks(res.Text, res.SessionID))
+		session, run, err := sessioncmd.AwaitRun(ctx, h.stateStore, res.SessionID, res.RunID, sessionCommandPollInterval)
+		if err != nil {
+			post(fmt.Sprintf("❌ %s", err.Error()), nil)
+			return
+		}
+		outcome := h.describeRun(ctx, session, run)
+		post(sessioncmd.OutcomeText(session, run), slackblocks.RunBlocks(outcome))
+	}()
+}
+
+// describeRun collects what the outcome message of a finished run shows.
+// The diff stat is best effort.
+func (h *Handler) describeRun(ctx context.Context, session state.Session, run state.Run) slackblocks.Run {
+	out := slackblocks.Run{
+		SessionID: session.ID,
+		RunID:     run.ID,
+		Branch:    session.Branch,
+		Succeeded: run.State == string(task.StateCompleted),
+		Error:     fallbackText(run.Error, strings.ToLower(run.State)),
+		CommitSHA: run.CommitSHA,
+		PRURL:     session.PRURL,
+	}
+	if event, found, err := h.stateStore.GetLatestRunEvent(run.ID, "validate"); err == nil && found {
+		out.Validation = event.Data
+	}
+	if repo, found, err := h.stateStore.GetRepoByName(session.RepoName); err == nil && found {
+		if diff, err := h.runner.SessionDiff(ctx, session, repo); err == nil {
+			out.DiffStat = diff.Summary()
+		}
+	}
+	return out
+}
+
+// interactionCommand maps a button click to the session command it runs.
+// ok is false for buttons that are not Fog's.
+func interactionCommand(in slackblocks.Interaction) (slackcmd.Command, bool) {
+	action, ok := slackblocks.CommandFor(in.ActionID)
+	if !ok {
+		return slackcmd.Command{}, false
+	}
+	return slackcmd.Command{Action: action, SessionID: in.SessionID}, true
+}
+
+func fallbackText(v, alt string) string {
+	if v = strings.TrimSpace(v); v != "" {
+		return v
+	}
+	return alt
+}
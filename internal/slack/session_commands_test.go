@@ -0,0 +1,68 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/runner"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestHandleCommandRunsSessionCommands(t *testing.T) {
+	store, err := state.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new state store failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	r, err := runner.New("", t.TempDir())
+	if err != nil {
+		t.Fatalf("new runner failed: %v", err)
+	}
+	r.SetStateStore(store)
+	if _, err := store.UpsertRepo(state.Repo{Name: "acme/api", URL: "https://github.com/acme/api.git", Host: "github.com", Owner: "acme", Repo: "api", BarePath: "/tmp/repo.git", BaseWorktreePath: "/tmp/base", DefaultBranch: "main"}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	if err := store.CreateSession(state.Session{ID: "sess-1", RepoName: "acme/api", Branch: "fog/otp", WorktreePath: "/tmp/wt", Tool: "claude", Status: "COMPLETED"}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+
+	posted := make(chan string, 4)
+	responseSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		text, _ := payload["text"].(string)
+		posted <- text
+	}))
+	defer responseSrv.Close()
+
+	h := New(r, store, "")
+	for _, tc := range []struct {
+		text string
+		want string
+	}{
+		{"sessions", "`sess-1` acme/api `fog/otp` — COMPLETED"},
+		{"pr open sess", `❌ session "sess-1" has no pull request`},
+	} {
+		form := url.Values{"text": {tc.text}, "response_url": {responseSrv.URL}}
+		req := httptest.NewRequest(http.MethodPost, "/slack/command", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.HandleCommand(w, req)
+		if !strings.Contains(w.Body.String(), "Running") {
+			t.Fatalf("unexpected ack for %q: %s", tc.text, w.Body.String())
+		}
+		select {
+		case got := <-posted:
+			if !strings.Contains(got, tc.want) {
+				t.Fatalf("unexpected result for %q: %q", tc.text, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %q result", tc.text)
+		}
+	}
+}
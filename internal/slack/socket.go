@@ -13,6 +13,8 @@ import (
 	"time"
 
 	"github.com/darkLord19/foglet/internal/runner"
+	"github.com/darkLord19/foglet/internal/slackblocks"
+	"github.com/darkLord19/foglet/internal/slackcmd"
 	"github.com/darkLord19/foglet/internal/state"
 	"github.com/darkLord19/foglet/internal/task"
 	"github.com/gorilla/websocket"
@@ -116,6 +118,8 @@ func (s *SocketMode) runOnce(ctx context.Context) error {
 			go s.handleSlashEnvelope(envelope.Payload)
 		case "events_api":
 			go s.handleEventsEnvelope(envelope.Payload)
+		case "interactive":
+			go s.handleInteractiveEnvelope(envelope.Payload)
 		case "disconnect":
 			return fmt.Errorf("slack requested disconnect")
 		}
@@ -164,6 +168,24 @@ func (s *SocketMode) handleSlashEnvelope(raw json.RawMessage) {
 		return
 	}
 
+	if cmd, ok, err := slackcmd.Parse(payload.Text); ok {
+		if err != nil {
+			s.sendWebhookError(payload.ResponseURL, err.Error())
+			return
+		}
+		s.handler.runSessionCommand(cmd, func(text string, blocks []slackblocks.Block) {
+			response := map[string]any{
+				"response_type": "in_channel",
+				"text":          text,
+			}
+			if len(blocks) > 0 {
+				response["blocks"] = blocks
+			}
+			_ = postWebhookJSON(s.httpClient, payload.ResponseURL, response)
+		})
+		return
+	}
+
 	parsed, err := parseCommandText(payload.Text)
 	if err != nil {
 		s.sendWebhookError(payload.ResponseURL, err.Error())
@@ -215,6 +237,17 @@ func (s *SocketMode) handleEventsEnvelope(raw json.RawMessage) {
 		return
 	}
 
+	if cmd, ok, err := slackcmd.Parse(prompt); ok {
+		if err != nil {
+			_, _ = s.postMessage(evt.Channel, rootTS, fmt.Sprintf("❌ %s", err.Error()))
+			return
+		}
+		s.handler.runSessionCommand(cmd, func(text string, blocks []slackblocks.Block) {
+			_, _ = s.postBlocks(evt.Channel, rootTS, text, blocks)
+		})
+		return
+	}
+
 	isFollowUp := evt.ThreadTS != "" && evt.ThreadTS != evt.TS
 	if isFollowUp {
 		s.handleThreadFollowUp(evt.Channel, rootTS, prompt)
@@ -240,6 +273,24 @@ func (s *SocketMode) handleEventsEnvelope(raw json.RawMessage) {
 	s.runTaskInThread(evt.Channel, rootTS, repoPath, t)
 }
 
+// handleInteractiveEnvelope runs the session command of a clicked run
+// button and posts its result in the message's thread. Socket Mode
+// envelopes arrive over the authenticated connection, so unlike fogcloud's
+// interactivity endpoint there is no signature to check.
+func (s *SocketMode) handleInteractiveEnvelope(raw json.RawMessage) {
+	in, err := slackblocks.ParseInteraction(raw)
+	if err != nil {
+		return
+	}
+	cmd, ok := interactionCommand(in)
+	if !ok {
+		return
+	}
+	s.handler.runSessionCommand(cmd, func(text string, blocks []slackblocks.Block) {
+		_, _ = s.postBlocks(in.ChannelID, in.ThreadTS, text, blocks)
+	})
+}
+
 func (s *SocketMode) handleThreadFollowUp(channelID, rootTS, rawPrompt string) {
 	prompt, err := normalizeFollowUpPrompt(rawPrompt)
 	if err != nil {
@@ -333,17 +384,25 @@ func postWebhookJSON(client *http.Client, responseURL string, payload map[string
 }
 
 func (s *SocketMode) postMessage(channelID, threadTS, text string) (string, error) {
+	return s.postBlocks(channelID, threadTS, text, nil)
+}
+
+// postBlocks posts a Block Kit message; text is its notification fallback.
+func (s *SocketMode) postBlocks(channelID, threadTS, text string, blocks []slackblocks.Block) (string, error) {
 	if strings.TrimSpace(channelID) == "" || strings.TrimSpace(text) == "" {
 		return "", nil
 	}
 
-	payload := map[string]string{
+	payload := map[string]any{
 		"channel": channelID,
 		"text":    text,
 	}
 	if strings.TrimSpace(threadTS) != "" {
 		payload["thread_ts"] = threadTS
 	}
+	if len(blocks) > 0 {
+		payload["blocks"] = blocks
+	}
 
 	body, err := json.Marshal(payload)
 	if err != nil {
@@ -0,0 +1,176 @@
+// Package slackblocks renders Fog run outcomes as Slack Block Kit messages
+// and decodes the button clicks Slack sends back for them. fogcloud and the
+// local Slack integration share it so the action IDs stay in step.
+package slackblocks
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Action IDs of the run buttons. Each button's value is the session ID.
+const (
+	ActionCancel   = "fog_cancel"
+	ActionRetry    = "fog_retry"
+	ActionPRCreate = "fog_pr_create"
+	ActionFork     = "fog_fork"
+)
+
+// CommandFor returns the session command a button runs. The names match the
+// sessioncmd actions, which double as fogcloud job kinds.
+func CommandFor(actionID string) (string, bool) {
+	switch actionID {
+	case ActionCancel:
+		return "cancel", true
+	case ActionRetry:
+		return "retry", true
+	case ActionPRCreate:
+		return "pr_create", true
+	case ActionFork:
+		return "fork", true
+	default:
+		return "", false
+	}
+}
+
+// Block is one Block Kit block.
+type Block = map[string]any
+
+// Run describes a run for RunBlocks. Validation is the Data of the run's
+// latest "validate" event ("passed" or "failed"), empty when it did not
+// validate; DiffStat is the branch's diff stat summary line.
+type Run struct {
+	SessionID  string
+	RunID      string
+	Branch     string
+	Succeeded  bool
+	Error      string
+	CommitSHA  string
+	PRURL      string
+	Validation string
+	DiffStat   string
+}
+
+// Summary is the plain text form of a finished run, used as the
+// notification fallback of its blocks.
+func (r Run) Summary() string {
+	if !r.Succeeded {
+		return fmt.Sprintf("❌ Run%s on `%s` failed: %s", runLabel(r.RunID), r.Branch, fallback(r.Error, "unknown error"))
+	}
+	text := fmt.Sprintf("✅ Run%s completed on `%s`", runLabel(r.RunID), r.Branch)
+	if pr := strings.TrimSpace(r.PRURL); pr != "" {
+		text += "\nPR: " + pr
+	}
+	return text
+}
+
+// RunBlocks renders a finished run: its outcome, the branch's diff stats,
+// validation status and PR link, and buttons to retry it, fork the session
+// and, when the run committed but the session has no PR, create one.
+func RunBlocks(r Run) []Block {
+	headline := r.Summary()
+	if !r.Succeeded {
+		headline = fmt.Sprintf("❌ Run%s on `%s` failed\n>%s", runLabel(r.RunID), r.Branch, strings.ReplaceAll(truncate(fallback(r.Error, "unknown error"), 500), "\n", "\n>"))
+	} else if i := strings.Index(headline, "\n"); i != -1 {
+		headline = headline[:i]
+	}
+	blocks := []Block{section(headline)}
+
+	fields := []Block{mrkdwn("*Session*\n`" + shortID(r.SessionID) + "`")}
+	if sha := strings.TrimSpace(r.CommitSHA); sha != "" {
+		fields = append(fields, mrkdwn("*Commit*\n`"+shortID(sha)+"`"))
+	}
+	fields = append(fields, mrkdwn("*Validation*\n"+validationText(r.Validation)))
+	if stat := strings.TrimSpace(r.DiffStat); stat != "" {
+		fields = append(fields, mrkdwn("*Changes*\n"+stat))
+	}
+	if pr := strings.TrimSpace(r.PRURL); pr != "" {
+		fields = append(fields, mrkdwn("*Pull request*\n<"+pr+">"))
+	}
+	blocks = append(blocks, Block{"type": "section", "fields": fields})
+
+	if strings.TrimSpace(r.SessionID) == "" {
+		return blocks
+	}
+	buttons := []Block{button(ActionRetry, "Retry", r.SessionID, "")}
+	if r.Succeeded && strings.TrimSpace(r.PRURL) == "" && strings.TrimSpace(r.CommitSHA) != "" {
+		buttons = append(buttons, button(ActionPRCreate, "Create PR", r.SessionID, "primary"))
+	}
+	buttons = append(buttons, button(ActionFork, "Fork", r.SessionID, ""))
+	return append(blocks, actions(buttons))
+}
+
+// RunningBlocks renders a progress message for a run in flight with a button
+// to cancel it.
+func RunningBlocks(text, sessionID string) []Block {
+	blocks := []Block{section(text)}
+	if strings.TrimSpace(sessionID) == "" {
+		return blocks
+	}
+	return append(blocks, actions([]Block{button(ActionCancel, "Cancel", sessionID, "danger")}))
+}
+
+func section(text string) Block {
+	return Block{"type": "section", "text": mrkdwn(text)}
+}
+
+func mrkdwn(text string) Block {
+	return Block{"type": "mrkdwn", "text": text}
+}
+
+func actions(elements []Block) Block {
+	return Block{"type": "actions", "elements": elements}
+}
+
+func button(actionID, label, value, style string) Block {
+	b := Block{
+		"type":      "button",
+		"action_id": actionID,
+		"text":      Block{"type": "plain_text", "text": label},
+		"value":     value,
+	}
+	if style != "" {
+		b["style"] = style
+	}
+	return b
+}
+
+func validationText(v string) string {
+	switch strings.TrimSpace(v) {
+	case "passed":
+		return "✅ passed"
+	case "failed":
+		return "❌ failed"
+	default:
+		return "not run"
+	}
+}
+
+func runLabel(runID string) string {
+	if strings.TrimSpace(runID) == "" {
+		return ""
+	}
+	return " `" + shortID(runID) + "`"
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
+
+func fallback(v, alt string) string {
+	if v = strings.TrimSpace(v); v != "" {
+		return v
+	}
+	return alt
+}
@@ -0,0 +1,102 @@
+package slackblocks
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func actionIDs(blocks []Block) []string {
+	var ids []string
+	for _, b := range blocks {
+		if b["type"] != "actions" {
+			continue
+		}
+		for _, e := range b["elements"].([]Block) {
+			ids = append(ids, e["action_id"].(string))
+		}
+	}
+	return ids
+}
+
+func TestRunBlocksButtons(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		run  Run
+		want string
+	}{
+		{"committed without PR", Run{SessionID: "s", Succeeded: true, CommitSHA: "abc"}, "fog_retry fog_pr_create fog_fork"},
+		{"with PR", Run{SessionID: "s", Succeeded: true, CommitSHA: "abc", PRURL: "https://github.com/acme/api/pull/7"}, "fog_retry fog_fork"},
+		{"no changes", Run{SessionID: "s", Succeeded: true}, "fog_retry fog_fork"},
+		{"failed", Run{SessionID: "s", Error: "boom", CommitSHA: "abc"}, "fog_retry fog_fork"},
+		{"no session", Run{Succeeded: true}, ""},
+	} {
+		if got := strings.Join(actionIDs(RunBlocks(tc.run)), " "); got != tc.want {
+			t.Errorf("%s: got buttons %q want %q", tc.name, got, tc.want)
+		}
+	}
+	if got := strings.Join(actionIDs(RunningBlocks("working", "s")), " "); got != ActionCancel {
+		t.Fatalf("unexpected running buttons: %q", got)
+	}
+}
+
+func TestRunBlocksFields(t *testing.T) {
+	blocks := RunBlocks(Run{
+		SessionID:  "0123456789",
+		RunID:      "run-123456789",
+		Branch:     "fog/otp",
+		Error:      "validation failed\nexit 1",
+		Validation: "failed",
+		DiffStat:   "1 file changed, 2 insertions(+)",
+	})
+	if got := blocks[0]["text"].(Block)["text"]; got != "❌ Run `run-1234` on `fog/otp` failed\n>validation failed\n>exit 1" {
+		t.Fatalf("unexpected headline: %q", got)
+	}
+	raw, _ := json.Marshal(blocks)
+	out := string(raw)
+	for _, want := range []string{
+		"*Validation*\\n❌ failed",
+		"*Changes*\\n1 file changed, 2 insertions(+)",
+		"*Session*\\n`01234567`",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("blocks missing %q: %s", want, out)
+		}
+	}
+}
+
+func TestTruncateKeepsRunesWhole(t *testing.T) {
+	if got := truncate("héllo wörld", 2); got != "hé…" {
+		t.Fatalf("unexpected truncation: %q", got)
+	}
+	if got := truncate("日本語", 3); got != "日本語" {
+		t.Fatalf("short strings should be kept: %q", got)
+	}
+}
+
+func TestParseInteraction(t *testing.T) {
+	payload := `{"type":"block_actions","team":{"id":"T1"},"user":{"id":"U1"},
+		"container":{"channel_id":"C1","message_ts":"2.0"},"message":{"ts":"2.0","thread_ts":"1.0"},
+		"actions":[{"action_id":"other","value":"x"},{"action_id":"fog_retry","value":"sess-1"}]}`
+	in, err := ParseInteraction([]byte(payload))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	want := Interaction{TeamID: "T1", UserID: "U1", ChannelID: "C1", ThreadTS: "1.0", ActionID: ActionRetry, SessionID: "sess-1"}
+	if in != want {
+		t.Fatalf("got %+v want %+v", in, want)
+	}
+	if cmd, _ := CommandFor(in.ActionID); cmd != "retry" {
+		t.Fatalf("unexpected command %q", cmd)
+	}
+
+	for _, bad := range []string{
+		`{"type":"view_submission"}`,
+		`{"type":"block_actions","channel":{"id":"C1"},"message":{"ts":"1.0"},"actions":[{"action_id":"other","value":"x"}]}`,
+		`{"type":"block_actions","actions":[{"action_id":"fog_fork","value":"sess-1"}]}`,
+	} {
+		if _, err := ParseInteraction([]byte(bad)); err == nil {
+			t.Fatalf("expected error for %s", bad)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package slackblocks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Interaction is a click on one of Fog's run buttons, decoded from a
+// block_actions payload.
+type Interaction struct {
+	TeamID    string
+	UserID    string
+	ChannelID string
+	// ThreadTS is the root of the thread the clicked message is in.
+	ThreadTS    string
+	ResponseURL string
+	ActionID    string
+	SessionID   string
+}
+
+type blockActionsPayload struct {
+	Type string `json:"type"`
+	Team struct {
+		ID string `json:"id"`
+	} `json:"team"`
+	User struct {
+		ID string `json:"id"`
+	} `json:"user"`
+	Channel struct {
+		ID string `json:"id"`
+	} `json:"channel"`
+	Container struct {
+		ChannelID string `json:"channel_id"`
+		MessageTS string `json:"message_ts"`
+		ThreadTS  string `json:"thread_ts"`
+	} `json:"container"`
+	Message struct {
+		TS       string `json:"ts"`
+		ThreadTS string `json:"thread_ts"`
+	} `json:"message"`
+	ResponseURL string `json:"response_url"`
+	Actions     []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+}
+
+// ParseInteraction decodes the JSON payload of a Slack interaction and
+// returns the Fog button it clicked.
+func ParseInteraction(payload []byte) (Interaction, error) {
+	var p blockActionsPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return Interaction{}, fmt.Errorf("invalid interaction payload: %w", err)
+	}
+	if p.Type != "block_actions" {
+		return Interaction{}, fmt.Errorf("unsupported interaction type %q", p.Type)
+	}
+	in := Interaction{
+		TeamID:      strings.TrimSpace(p.Team.ID),
+		UserID:      strings.TrimSpace(p.User.ID),
+		ChannelID:   firstNonEmpty(p.Channel.ID, p.Container.ChannelID),
+		ThreadTS:    firstNonEmpty(p.Message.ThreadTS, p.Container.ThreadTS, p.Message.TS, p.Container.MessageTS),
+		ResponseURL: strings.TrimSpace(p.ResponseURL),
+	}
+	for _, action := range p.Actions {
+		if _, ok := CommandFor(action.ActionID); ok {
+			in.ActionID = action.ActionID
+			in.SessionID = strings.TrimSpace(action.Value)
+			break
+		}
+	}
+	switch {
+	case in.ActionID == "":
+		return Interaction{}, errors.New("interaction has no fog action")
+	case in.SessionID == "":
+		return Interaction{}, errors.New("interaction has no session id")
+	case in.ChannelID == "" || in.ThreadTS == "":
+		return Interaction{}, errors.New("interaction has no message")
+	}
+	return in, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
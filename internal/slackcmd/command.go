@@ -0,0 +1,205 @@
+// Package slackcmd parses the session commands Fog accepts from Slack:
+// listing, inspecting, cancelling, retrying and forking sessions and acting
+// on their pull requests. It has no dependencies on the runner so fogcloud
+// and the local Slack integration share one parser; sessioncmd runs the
+// parsed commands on the device.
+package slackcmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Command actions. They double as fogcloud job kinds.
+const (
+	ActionSessions = "list_sessions"
+	ActionStatus   = "status"
+	ActionCancel   = "cancel"
+	ActionDiff     = "diff"
+	ActionFork     = "fork"
+	ActionRetry    = "retry"
+	ActionPRCreate = "pr_create"
+	ActionPROpen   = "pr_open"
+	ActionPRMerge  = "pr_merge"
+	ActionHelp     = "help"
+)
+
+// Usage lists the session commands.
+const Usage = "Session commands:\n" +
+	"• `sessions` lists your active sessions\n" +
+	"• `status [session]` shows the latest run of a session\n" +
+	"• `cancel [session]` cancels the running run\n" +
+	"• `diff [session]` summarizes the branch diff\n" +
+	"• `retry [session]` re-runs the latest prompt\n" +
+	"• `fork [session='' branch-name='' tool='' model=''] prompt` starts a new session from a session's branch\n" +
+	"• `pr create [session]` pushes the branch and opens a draft PR\n" +
+	"• `pr open [session]` marks the draft PR ready for review\n" +
+	"• `pr merge [squash|rebase|merge] [session]` merges the PR\n" +
+	"In a session thread the session can be left out; elsewhere pass its ID or an unambiguous prefix."
+
+var optionPattern = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9_-]*)=('([^']*)'|"([^"]*)"|[^\s]+)`)
+
+// Command is one parsed session command. SessionID is empty when the
+// command names no session; callers fill it from thread context.
+type Command struct {
+	Action      string
+	SessionID   string
+	MergeMethod string
+	// Branch, Tool, Model and Prompt configure a fork. Only a parsed fork
+	// requires a prompt; without one the source's latest prompt is reused.
+	Branch string
+	Tool   string
+	Model  string
+	Prompt string
+}
+
+// NeedsSession reports whether the command acts on one session.
+func (c Command) NeedsSession() bool {
+	switch c.Action {
+	case ActionSessions, ActionHelp:
+		return false
+	default:
+		return true
+	}
+}
+
+// Parse recognizes a session command. ok is false when text is not one, for
+// example a plain follow-up prompt: verbs only match with the exact number
+// of arguments they take, and fork requires its options block.
+func Parse(text string) (cmd Command, ok bool, err error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(strings.ToLower(text), "@fog") {
+		text = strings.TrimSpace(text[len("@fog"):])
+	}
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return Command{}, false, nil
+	}
+	verb, args := strings.ToLower(fields[0]), fields[1:]
+
+	switch verb {
+	case "help":
+		if len(args) == 0 {
+			return Command{Action: ActionHelp}, true, nil
+		}
+	case "sessions":
+		if len(args) == 0 {
+			return Command{Action: ActionSessions}, true, nil
+		}
+	case ActionStatus, ActionCancel, ActionDiff, ActionRetry:
+		if len(args) <= 1 {
+			return Command{Action: verb, SessionID: firstArg(args)}, true, nil
+		}
+	case "pr":
+		return parsePR(args)
+	case ActionFork:
+		rest := strings.TrimSpace(text[len(fields[0]):])
+		if strings.HasPrefix(rest, "[") {
+			cmd, err := parseFork(rest)
+			return cmd, true, err
+		}
+	}
+	return Command{}, false, nil
+}
+
+func parsePR(args []string) (Command, bool, error) {
+	if len(args) == 0 {
+		return Command{}, false, nil
+	}
+	sub, args := strings.ToLower(args[0]), args[1:]
+	switch sub {
+	case "create":
+		if len(args) <= 1 {
+			return Command{Action: ActionPRCreate, SessionID: firstArg(args)}, true, nil
+		}
+	case "open", "ready":
+		if len(args) <= 1 {
+			return Command{Action: ActionPROpen, SessionID: firstArg(args)}, true, nil
+		}
+	case "merge":
+		cmd := Command{Action: ActionPRMerge}
+		if len(args) > 0 && isMergeMethod(args[0]) {
+			cmd.MergeMethod = strings.ToLower(args[0])
+			args = args[1:]
+		}
+		if len(args) <= 1 {
+			cmd.SessionID = firstArg(args)
+			return cmd, true, nil
+		}
+	}
+	return Command{}, false, nil
+}
+
+func parseFork(text string) (Command, error) {
+	end := strings.Index(text, "]")
+	if end == -1 {
+		return Command{}, fmt.Errorf("invalid options block: missing closing ]")
+	}
+	opts, err := parseOptions(strings.TrimSpace(text[1:end]))
+	if err != nil {
+		return Command{}, err
+	}
+	cmd := Command{
+		Action:    ActionFork,
+		SessionID: opts["session"],
+		Branch:    opts["branch-name"],
+		Tool:      opts["tool"],
+		Model:     opts["model"],
+		Prompt:    strings.TrimSpace(text[end+1:]),
+	}
+	if cmd.Prompt == "" {
+		return Command{}, fmt.Errorf("fork prompt is required")
+	}
+	return cmd, nil
+}
+
+func parseOptions(input string) (map[string]string, error) {
+	allowed := map[string]struct{}{
+		"session":     {},
+		"branch-name": {},
+		"tool":        {},
+		"model":       {},
+	}
+
+	options := make(map[string]string)
+	cursor := 0
+	for _, m := range optionPattern.FindAllStringSubmatchIndex(input, -1) {
+		if strings.TrimSpace(input[cursor:m[0]]) != "" {
+			return nil, fmt.Errorf("invalid options format near %q", strings.TrimSpace(input[cursor:m[0]]))
+		}
+		key := strings.ToLower(input[m[2]:m[3]])
+		if _, ok := allowed[key]; !ok {
+			return nil, fmt.Errorf("unknown option key: %s", key)
+		}
+		value := input[m[4]:m[5]]
+		switch {
+		case m[6] != -1:
+			value = input[m[6]:m[7]]
+		case m[8] != -1:
+			value = input[m[8]:m[9]]
+		}
+		options[key] = strings.TrimSpace(value)
+		cursor = m[1]
+	}
+	if strings.TrimSpace(input[cursor:]) != "" {
+		return nil, fmt.Errorf("invalid options format near %q", strings.TrimSpace(input[cursor:]))
+	}
+	return options, nil
+}
+
+func isMergeMethod(value string) bool {
+	switch strings.ToLower(value) {
+	case "squash", "rebase", "merge":
+		return true
+	default:
+		return false
+	}
+}
+
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
@@ -0,0 +1,60 @@
+package slackcmd
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		text string
+		ok   bool
+		want Command
+	}{
+		{text: "sessions", ok: true, want: Command{Action: ActionSessions}},
+		{text: "@fog help", ok: true, want: Command{Action: ActionHelp}},
+		{text: "status", ok: true, want: Command{Action: ActionStatus}},
+		{text: "Cancel abc123", ok: true, want: Command{Action: ActionCancel, SessionID: "abc123"}},
+		{text: "diff abc123", ok: true, want: Command{Action: ActionDiff, SessionID: "abc123"}},
+		{text: "retry", ok: true, want: Command{Action: ActionRetry}},
+		{text: "pr create abc", ok: true, want: Command{Action: ActionPRCreate, SessionID: "abc"}},
+		{text: "pr open", ok: true, want: Command{Action: ActionPROpen}},
+		{text: "pr ready abc", ok: true, want: Command{Action: ActionPROpen, SessionID: "abc"}},
+		{text: "pr merge", ok: true, want: Command{Action: ActionPRMerge}},
+		{text: "pr merge rebase abc", ok: true, want: Command{Action: ActionPRMerge, MergeMethod: "rebase", SessionID: "abc"}},
+		{text: "pr merge abc", ok: true, want: Command{Action: ActionPRMerge, SessionID: "abc"}},
+		{
+			text: "fork [session=abc branch-name='fog/redis' tool=codex] try redis instead",
+			ok:   true,
+			want: Command{Action: ActionFork, SessionID: "abc", Branch: "fog/redis", Tool: "codex", Prompt: "try redis instead"},
+		},
+		{text: "fork [] use a queue", ok: true, want: Command{Action: ActionFork, Prompt: "use a queue"}},
+
+		// Follow-up prompts that happen to start with a verb.
+		{text: "status code should be 404 here"},
+		{text: "fork the parser and add tests"},
+		{text: "diff against main looks wrong, fix it"},
+		{text: "pr description needs examples"},
+		{text: "add a cancel button"},
+		{text: "[repo='a/b'] cancel it"},
+		{text: ""},
+	}
+	for _, tc := range cases {
+		got, ok, err := Parse(tc.text)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tc.text, err)
+		}
+		if ok != tc.ok || got != tc.want {
+			t.Errorf("Parse(%q) = %+v, %v; want %+v, %v", tc.text, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestParseForkErrors(t *testing.T) {
+	for _, text := range []string{
+		"fork [session=abc]",
+		"fork [session=abc repo=x] prompt",
+		"fork [session=abc prompt",
+	} {
+		if _, ok, err := Parse(text); !ok || err == nil {
+			t.Errorf("Parse(%q) = ok %v, err %v; want an error", text, ok, err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package state
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CloudJob records what a fogcloud job did on this device: the session and
+// run it started, or the message a session command answered with. fogcloud
+// may deliver a job again when its lease expires, and the record lets the
+// relay report the first result instead of acting twice.
+type CloudJob struct {
+	JobID     string    `json:"job_id"`
+	SessionID string    `json:"session_id,omitempty"`
+	RunID     string    `json:"run_id,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RecordCloudJob stores the result of a cloud job. The first record of a
+// job wins; later ones are ignored.
+func (s *Store) RecordCloudJob(job CloudJob) error {
+	job.JobID = strings.TrimSpace(job.JobID)
+	if job.JobID == "" {
+		return errors.New("cloud job id cannot be empty")
+	}
+	if _, err := s.db.Exec(
+		`INSERT INTO cloud_jobs(job_id, session_id, run_id, message, created_at) VALUES(?, ?, ?, ?, ?)
+		 ON CONFLICT(job_id) DO NOTHING`,
+		job.JobID,
+		nullIfEmpty(strings.TrimSpace(job.SessionID)),
+		nullIfEmpty(strings.TrimSpace(job.RunID)),
+		nullIfEmpty(job.Message),
+		nowRFC3339Nano(),
+	); err != nil {
+		return fmt.Errorf("record cloud job %q: %w", job.JobID, err)
+	}
+	return nil
+}
+
+// GetCloudJob returns the recorded result of a cloud job. found=false when
+// the job has not run on this device.
+func (s *Store) GetCloudJob(jobID string) (CloudJob, bool, error) {
+	jobID = strings.TrimSpace(jobID)
+	if jobID == "" {
+		return CloudJob{}, false, errors.New("cloud job id cannot be empty")
+	}
+	var (
+		job                       CloudJob
+		sessionID, runID, message sql.NullString
+		createdAt                 string
+	)
+	err := s.db.QueryRow(
+		`SELECT job_id, session_id, run_id, message, created_at FROM cloud_jobs WHERE job_id = ?`, jobID,
+	).Scan(&job.JobID, &sessionID, &runID, &message, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return CloudJob{}, false, nil
+	}
+	if err != nil {
+		return CloudJob{}, false, fmt.Errorf("get cloud job %q: %w", jobID, err)
+	}
+	job.SessionID = sessionID.String
+	job.RunID = runID.String
+	job.Message = message.String
+	job.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+	return job, true, nil
+}
@@ -0,0 +1,28 @@
+package state
+
+import "testing"
+
+func TestRecordCloudJobKeepsFirstResult(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	if _, found, err := store.GetCloudJob("job-1"); err != nil || found {
+		t.Fatalf("expected no record, got found=%v err=%v", found, err)
+	}
+	if err := store.RecordCloudJob(CloudJob{JobID: "job-1", SessionID: "sess-1", RunID: "run-1"}); err != nil {
+		t.Fatalf("record cloud job failed: %v", err)
+	}
+	if err := store.RecordCloudJob(CloudJob{JobID: "job-1", SessionID: "sess-2", RunID: "run-2"}); err != nil {
+		t.Fatalf("second record failed: %v", err)
+	}
+	got, found, err := store.GetCloudJob("job-1")
+	if err != nil || !found {
+		t.Fatalf("get cloud job failed: found=%v err=%v", found, err)
+	}
+	if got.SessionID != "sess-1" || got.RunID != "run-1" || got.Message != "" || got.CreatedAt.IsZero() {
+		t.Fatalf("unexpected record: %+v", got)
+	}
+	if err := store.RecordCloudJob(CloudJob{JobID: " "}); err == nil {
+		t.Fatal("expected error for empty job id")
+	}
+}
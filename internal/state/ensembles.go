@@ -0,0 +1,255 @@
+package state
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Ensemble status values.
+const (
+	EnsembleStatusRunning  = "RUNNING"
+	EnsembleStatusPromoted = "PROMOTED"
+)
+
+// Ensemble groups sessions that ran the same prompt with different
+// tool/model combinations so their results can be compared.
+type Ensemble struct {
+	ID              string           `json:"id"`
+	RepoName        string           `json:"repo_name"`
+	SourceSessionID string           `json:"source_session_id,omitempty"`
+	Prompt          string           `json:"prompt"`
+	Status          string           `json:"status"`
+	WinnerSessionID string           `json:"winner_session_id,omitempty"`
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+	Members         []EnsembleMember `json:"members"`
+}
+
+// EnsembleMember is one session taking part in an ensemble.
+type EnsembleMember struct {
+	SessionID string `json:"session_id"`
+	Tool      string `json:"tool"`
+	Model     string `json:"model,omitempty"`
+}
+
+// CreateEnsemble inserts a new ensemble row. Members are added separately
+// with AddEnsembleMember as their sessions are created.
+func (s *Store) CreateEnsemble(ensemble Ensemble) error {
+	ensemble.ID = strings.TrimSpace(ensemble.ID)
+	ensemble.RepoName = strings.TrimSpace(ensemble.RepoName)
+	ensemble.SourceSessionID = strings.TrimSpace(ensemble.SourceSessionID)
+	ensemble.Prompt = strings.TrimSpace(ensemble.Prompt)
+	ensemble.Status = strings.TrimSpace(ensemble.Status)
+
+	switch {
+	case ensemble.ID == "":
+		return errors.New("ensemble id cannot be empty")
+	case ensemble.RepoName == "":
+		return errors.New("ensemble repo_name cannot be empty")
+	case ensemble.Prompt == "":
+		return errors.New("ensemble prompt cannot be empty")
+	case ensemble.Status == "":
+		return errors.New("ensemble status cannot be empty")
+	}
+
+	now := nowRFC3339Nano()
+	_, err := s.db.Exec(
+		`INSERT INTO ensembles (id, repo_name, source_session_id, prompt, status, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		ensemble.ID,
+		ensemble.RepoName,
+		nullIfEmpty(ensemble.SourceSessionID),
+		ensemble.Prompt,
+		ensemble.Status,
+		now,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("create ensemble %q: %w", ensemble.ID, err)
+	}
+	return nil
+}
+
+// AddEnsembleMember appends a session to an ensemble.
+func (s *Store) AddEnsembleMember(ensembleID string, member EnsembleMember) error {
+	ensembleID = strings.TrimSpace(ensembleID)
+	member.SessionID = strings.TrimSpace(member.SessionID)
+	member.Tool = strings.TrimSpace(member.Tool)
+	member.Model = strings.TrimSpace(member.Model)
+
+	switch {
+	case ensembleID == "":
+		return errors.New("ensemble id cannot be empty")
+	case member.SessionID == "":
+		return errors.New("ensemble member session_id cannot be empty")
+	case member.Tool == "":
+		return errors.New("ensemble member tool cannot be empty")
+	}
+
+	_, err := s.db.Exec(
+		`INSERT INTO ensemble_members (ensemble_id, session_id, tool, model, position)
+		 VALUES (?, ?, ?, ?, (SELECT COALESCE(MAX(position), 0) + 1 FROM ensemble_members WHERE ensemble_id = ?))`,
+		ensembleID,
+		member.SessionID,
+		member.Tool,
+		nullIfEmpty(member.Model),
+		ensembleID,
+	)
+	if err != nil {
+		return fmt.Errorf("add ensemble member %q: %w", ensembleID, err)
+	}
+	return nil
+}
+
+// GetEnsemble returns one ensemble with its members in creation order.
+func (s *Store) GetEnsemble(id string) (Ensemble, bool, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return Ensemble{}, false, errors.New("ensemble id cannot be empty")
+	}
+
+	ensemble, err := scanEnsemble(s.db.QueryRow(
+		`SELECT `+ensembleColumns+`
+		   FROM ensembles
+		  WHERE id = ?`,
+		id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Ensemble{}, false, nil
+	}
+	if err != nil {
+		return Ensemble{}, false, fmt.Errorf("get ensemble %q: %w", id, err)
+	}
+
+	members, err := s.listEnsembleMembers(id)
+	if err != nil {
+		return Ensemble{}, false, err
+	}
+	ensemble.Members = members
+	return ensemble, true, nil
+}
+
+// ListEnsembles returns all ensembles, newest first.
+func (s *Store) ListEnsembles() ([]Ensemble, error) {
+	rows, err := s.db.Query(
+		`SELECT ` + ensembleColumns + `
+		   FROM ensembles
+		  ORDER BY created_at DESC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list ensembles: %w", err)
+	}
+	defer rows.Close()
+
+	ensembles := make([]Ensemble, 0)
+	for rows.Next() {
+		ensemble, err := scanEnsemble(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan ensemble: %w", err)
+		}
+		ensembles = append(ensembles, ensemble)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate ensembles: %w", err)
+	}
+	rows.Close()
+
+	for i := range ensembles {
+		members, err := s.listEnsembleMembers(ensembles[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		ensembles[i].Members = members
+	}
+	return ensembles, nil
+}
+
+// SetEnsembleWinner records the promoted session and marks the ensemble PROMOTED.
+func (s *Store) SetEnsembleWinner(id, sessionID string) error {
+	id = strings.TrimSpace(id)
+	sessionID = strings.TrimSpace(sessionID)
+	switch {
+	case id == "":
+		return errors.New("ensemble id cannot be empty")
+	case sessionID == "":
+		return errors.New("winner session id cannot be empty")
+	}
+
+	res, err := s.db.Exec(
+		`UPDATE ensembles
+		    SET winner_session_id = ?, status = ?, updated_at = ?
+		  WHERE id = ?`,
+		sessionID,
+		EnsembleStatusPromoted,
+		nowRFC3339Nano(),
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("set ensemble winner %q: %w", id, err)
+	}
+	if err := ensureRowsAffected(res, "ensemble "+id); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Store) listEnsembleMembers(ensembleID string) ([]EnsembleMember, error) {
+	rows, err := s.db.Query(
+		`SELECT session_id, tool, COALESCE(model, '')
+		   FROM ensemble_members
+		  WHERE ensemble_id = ?
+		  ORDER BY position ASC`,
+		ensembleID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list ensemble members %q: %w", ensembleID, err)
+	}
+	defer rows.Close()
+
+	members := make([]EnsembleMember, 0)
+	for rows.Next() {
+		var member EnsembleMember
+		if err := rows.Scan(&member.SessionID, &member.Tool, &member.Model); err != nil {
+			return nil, fmt.Errorf("scan ensemble member: %w", err)
+		}
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate ensemble members: %w", err)
+	}
+	return members, nil
+}
+
+const ensembleColumns = `id, repo_name, COALESCE(source_session_id, ''), prompt, status, COALESCE(winner_session_id, ''), created_at, updated_at`
+
+func scanEnsemble(row rowScanner) (Ensemble, error) {
+	var ensemble Ensemble
+	var createdAtRaw string
+	var updatedAtRaw string
+	if err := row.Scan(
+		&ensemble.ID,
+		&ensemble.RepoName,
+		&ensemble.SourceSessionID,
+		&ensemble.Prompt,
+		&ensemble.Status,
+		&ensemble.WinnerSessionID,
+		&createdAtRaw,
+		&updatedAtRaw,
+	); err != nil {
+		return Ensemble{}, err
+	}
+
+	var err error
+	ensemble.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAtRaw)
+	if err != nil {
+		return Ensemble{}, fmt.Errorf("parse ensemble created_at %q: %w", ensemble.ID, err)
+	}
+	ensemble.UpdatedAt, err = time.Parse(time.RFC3339Nano, updatedAtRaw)
+	if err != nil {
+		return Ensemble{}, fmt.Errorf("parse ensemble updated_at %q: %w", ensemble.ID, err)
+	}
+	return ensemble, nil
+}
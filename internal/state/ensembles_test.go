@@ -0,0 +1,173 @@
+package state
+
+import "testing"
+
+func TestEnsembleLifecycle(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedQueueSession(t, store)
+
+	if err := store.CreateEnsemble(Ensemble{
+		ID:              "ens-1",
+		RepoName:        "acme/api",
+		SourceSessionID: "sess-1",
+		Prompt:          "add rate limiting",
+		Status:          EnsembleStatusRunning,
+	}); err != nil {
+		t.Fatalf("create ensemble failed: %v", err)
+	}
+	if err := store.CreateEnsemble(Ensemble{ID: "ens-2", RepoName: "acme/api", Status: EnsembleStatusRunning}); err == nil {
+		t.Fatal("expected ensemble without prompt to fail")
+	}
+
+	seedEnsembleSessions(t, store, "sess-a", "sess-b")
+	for _, member := range []EnsembleMember{
+		{SessionID: "sess-a", Tool: "claude", Model: "sonnet"},
+		{SessionID: "sess-b", Tool: "codex"},
+	} {
+		if err := store.AddEnsembleMember("ens-1", member); err != nil {
+			t.Fatalf("add ensemble member failed: %v", err)
+		}
+	}
+	if err := store.AddEnsembleMember("ens-1", EnsembleMember{SessionID: "sess-c"}); err == nil {
+		t.Fatal("expected member without tool to fail")
+	}
+
+	ensemble, found, err := store.GetEnsemble("ens-1")
+	if err != nil || !found {
+		t.Fatalf("get ensemble failed: found=%v err=%v", found, err)
+	}
+	if ensemble.SourceSessionID != "sess-1" || ensemble.Status != EnsembleStatusRunning || ensemble.WinnerSessionID != "" {
+		t.Fatalf("unexpected ensemble: %+v", ensemble)
+	}
+	if len(ensemble.Members) != 2 || ensemble.Members[0].SessionID != "sess-a" || ensemble.Members[0].Model != "sonnet" || ensemble.Members[1].Tool != "codex" {
+		t.Fatalf("unexpected members: %+v", ensemble.Members)
+	}
+
+	if err := store.SetEnsembleWinner("ens-1", "sess-b"); err != nil {
+		t.Fatalf("set ensemble winner failed: %v", err)
+	}
+	if err := store.SetEnsembleWinner("missing", "sess-b"); err == nil {
+		t.Fatal("expected winner for unknown ensemble to fail")
+	}
+
+	ensembles, err := store.ListEnsembles()
+	if err != nil {
+		t.Fatalf("list ensembles failed: %v", err)
+	}
+	if len(ensembles) != 1 || ensembles[0].Status != EnsembleStatusPromoted || ensembles[0].WinnerSessionID != "sess-b" || len(ensembles[0].Members) != 2 {
+		t.Fatalf("unexpected ensembles: %+v", ensembles)
+	}
+
+	if _, found, err := store.GetEnsemble("missing"); err != nil || found {
+		t.Fatalf("expected missing ensemble, found=%v err=%v", found, err)
+	}
+}
+
+func TestSetSessionArchived(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedQueueSession(t, store)
+
+	if err := store.SetSessionArchived("sess-1", true); err != nil {
+		t.Fatalf("archive session failed: %v", err)
+	}
+	session, _, err := store.GetSession("sess-1")
+	if err != nil {
+		t.Fatalf("get session failed: %v", err)
+	}
+	if session.ArchivedAt == nil {
+		t.Fatal("expected archived_at to be set")
+	}
+
+	if err := store.SetSessionArchived("sess-1", false); err != nil {
+		t.Fatalf("unarchive session failed: %v", err)
+	}
+	sessions, err := store.ListSessions()
+	if err != nil {
+		t.Fatalf("list sessions failed: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ArchivedAt != nil {
+		t.Fatalf("expected unarchived session, got %+v", sessions)
+	}
+
+	if err := store.SetSessionArchived("missing", true); err == nil {
+		t.Fatal("expected archiving unknown session to fail")
+	}
+}
+
+func TestGetLatestRunEvent(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedQueueSession(t, store)
+	if err := store.CreateRun(Run{ID: "run-1", SessionID: "sess-1", Prompt: "p", WorktreePath: "/tmp/wt", State: "COMPLETED"}); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+
+	for _, event := range []RunEvent{
+		{RunID: "run-1", Type: "validate", Data: "failed"},
+		{RunID: "run-1", Type: "ai_output", Message: "done"},
+		{RunID: "run-1", Type: "validate", Data: "passed"},
+	} {
+		if err := store.AppendRunEvent(event); err != nil {
+			t.Fatalf("append run event failed: %v", err)
+		}
+	}
+
+	event, found, err := store.GetLatestRunEvent("run-1", "validate")
+	if err != nil || !found {
+		t.Fatalf("get latest run event failed: found=%v err=%v", found, err)
+	}
+	if event.Data != "passed" {
+		t.Fatalf("expected latest validate event, got %+v", event)
+	}
+	if _, found, err := store.GetLatestRunEvent("run-1", "commit"); err != nil || found {
+		t.Fatalf("expected no commit event, found=%v err=%v", found, err)
+	}
+}
+
+func TestEnsembleMembershipCascadesWithSession(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedQueueSession(t, store)
+	seedEnsembleSessions(t, store, "sess-a", "sess-b")
+
+	if err := store.CreateEnsemble(Ensemble{ID: "ens-1", RepoName: "acme/api", Prompt: "p", Status: EnsembleStatusRunning}); err != nil {
+		t.Fatalf("create ensemble failed: %v", err)
+	}
+	for _, id := range []string{"sess-a", "sess-b"} {
+		if err := store.AddEnsembleMember("ens-1", EnsembleMember{SessionID: id, Tool: "claude"}); err != nil {
+			t.Fatalf("add ensemble member failed: %v", err)
+		}
+	}
+	if err := store.AddEnsembleMember("ens-1", EnsembleMember{SessionID: "sess-missing", Tool: "claude"}); err == nil {
+		t.Fatal("expected member for unknown session to fail")
+	}
+
+	if _, err := store.db.Exec(`DELETE FROM sessions WHERE id = ?`, "sess-a"); err != nil {
+		t.Fatalf("delete session row failed: %v", err)
+	}
+	ensemble, _, err := store.GetEnsemble("ens-1")
+	if err != nil {
+		t.Fatalf("get ensemble failed: %v", err)
+	}
+	if len(ensemble.Members) != 1 || ensemble.Members[0].SessionID != "sess-b" {
+		t.Fatalf("expected only sess-b to remain, got %+v", ensemble.Members)
+	}
+}
+
+func seedEnsembleSessions(t *testing.T, store *Store, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		if err := store.CreateSession(Session{
+			ID:           id,
+			RepoName:     "acme/api",
+			Branch:       "fog/" + id,
+			WorktreePath: "/tmp/" + id,
+			Tool:         "claude",
+			Status:       "COMPLETED",
+		}); err != nil {
+			t.Fatalf("create session %s failed: %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package state
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ForgeHost configures the code host serving repos on Host: its kind
+// (github, gitlab, gitea) and, for self-hosted forges, its web root. The
+// access token, if any, is stored encrypted as a secret.
+type ForgeHost struct {
+	Host      string    `json:"host"`
+	Kind      string    `json:"kind"`
+	BaseURL   string    `json:"base_url,omitempty"`
+	HasToken  bool      `json:"has_token"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func forgeTokenKey(host string) string {
+	return "forge_token:" + host
+}
+
+// SetForgeHost creates or replaces the forge configuration of a host. A
+// non-empty token replaces the stored one; an empty token keeps it.
+func (s *Store) SetForgeHost(host ForgeHost, token string) error {
+	host.Host = strings.ToLower(strings.TrimSpace(host.Host))
+	host.Kind = strings.ToLower(strings.TrimSpace(host.Kind))
+	host.BaseURL = strings.TrimRight(strings.TrimSpace(host.BaseURL), "/")
+	if host.Host == "" {
+		return errors.New("forge host cannot be empty")
+	}
+	if host.Kind == "" {
+		return errors.New("forge kind cannot be empty")
+	}
+	if token = strings.TrimSpace(token); token != "" {
+		if err := s.SaveSecret(forgeTokenKey(host.Host), token); err != nil {
+			return err
+		}
+	}
+	if _, err := s.db.Exec(
+		`INSERT INTO forge_hosts(host, kind, base_url, updated_at) VALUES(?, ?, ?, ?)
+		 ON CONFLICT(host) DO UPDATE SET kind=excluded.kind, base_url=excluded.base_url, updated_at=excluded.updated_at`,
+		host.Host,
+		host.Kind,
+		nullIfEmpty(host.BaseURL),
+		nowRFC3339Nano(),
+	); err != nil {
+		return fmt.Errorf("set forge host %q: %w", host.Host, err)
+	}
+	return nil
+}
+
+// GetForgeHost returns the forge configuration of a host. found=false when
+// the host is not configured.
+func (s *Store) GetForgeHost(host string) (ForgeHost, bool, error) {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if host == "" {
+		return ForgeHost{}, false, errors.New("forge host cannot be empty")
+	}
+	fh, err := s.scanForgeHost(s.db.QueryRow(
+		`SELECT host, kind, base_url, updated_at FROM forge_hosts WHERE host = ?`, host,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ForgeHost{}, false, nil
+	}
+	if err != nil {
+		return ForgeHost{}, false, fmt.Errorf("get forge host %q: %w", host, err)
+	}
+	return fh, true, nil
+}
+
+// ListForgeHosts returns configured forge hosts sorted by host.
+func (s *Store) ListForgeHosts() ([]ForgeHost, error) {
+	rows, err := s.db.Query(`SELECT host, kind, base_url, updated_at FROM forge_hosts ORDER BY host`)
+	if err != nil {
+		return nil, fmt.Errorf("list forge hosts: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	hosts := []ForgeHost{}
+	for rows.Next() {
+		fh, err := s.scanForgeHost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan forge host: %w", err)
+		}
+		hosts = append(hosts, fh)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate forge hosts: %w", err)
+	}
+	return hosts, nil
+}
+
+// GetForgeToken returns the decrypted access token of a host.
+func (s *Store) GetForgeToken(host string) (string, bool, error) {
+	return s.GetSecret(forgeTokenKey(strings.ToLower(strings.TrimSpace(host))))
+}
+
+// DeleteForgeHost removes a host's forge configuration and token.
+func (s *Store) DeleteForgeHost(host string) error {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if host == "" {
+		return errors.New("forge host cannot be empty")
+	}
+	res, err := s.db.Exec(`DELETE FROM forge_hosts WHERE host = ?`, host)
+	if err != nil {
+		return fmt.Errorf("delete forge host %q: %w", host, err)
+	}
+	if err := ensureRowsAffected(res, "forge host "+host); err != nil {
+		return err
+	}
+	return s.DeleteSecret(forgeTokenKey(host))
+}
+
+func (s *Store) scanForgeHost(row rowScanner) (ForgeHost, error) {
+	var (
+		fh        ForgeHost
+		baseURL   sql.NullString
+		updatedAt string
+	)
+	if err := row.Scan(&fh.Host, &fh.Kind, &baseURL, &updatedAt); err != nil {
+		return ForgeHost{}, err
+	}
+	fh.BaseURL = baseURL.String
+	fh.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updatedAt)
+	hasToken, err := s.HasSecret(forgeTokenKey(fh.Host))
+	if err != nil {
+		return ForgeHost{}, err
+	}
+	fh.HasToken = hasToken
+	return fh, nil
+}
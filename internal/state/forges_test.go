@@ -0,0 +1,52 @@
+package state
+
+import "testing"
+
+func TestForgeHostLifecycle(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	if err := store.SetForgeHost(ForgeHost{Host: " Git.Acme.dev ", Kind: "Gitea", BaseURL: "https://git.acme.dev/"}, "tok-1"); err != nil {
+		t.Fatalf("set forge host failed: %v", err)
+	}
+	fh, found, err := store.GetForgeHost("git.acme.dev")
+	if err != nil || !found {
+		t.Fatalf("get forge host failed: found=%v err=%v", found, err)
+	}
+	if fh.Host != "git.acme.dev" || fh.Kind != "gitea" || fh.BaseURL != "https://git.acme.dev" || !fh.HasToken {
+		t.Fatalf("unexpected forge host: %+v", fh)
+	}
+
+	// An empty token keeps the stored one.
+	if err := store.SetForgeHost(ForgeHost{Host: "git.acme.dev", Kind: "gitea"}, ""); err != nil {
+		t.Fatalf("update forge host failed: %v", err)
+	}
+	token, found, err := store.GetForgeToken("git.acme.dev")
+	if err != nil || !found || token != "tok-1" {
+		t.Fatalf("unexpected token %q found=%v err=%v", token, found, err)
+	}
+
+	if err := store.SetForgeHost(ForgeHost{Host: "gitlab.acme.dev", Kind: "gitlab"}, ""); err != nil {
+		t.Fatalf("set gitlab host failed: %v", err)
+	}
+	hosts, err := store.ListForgeHosts()
+	if err != nil {
+		t.Fatalf("list forge hosts failed: %v", err)
+	}
+	if len(hosts) != 2 || hosts[0].Host != "git.acme.dev" || hosts[0].BaseURL != "" || hosts[1].HasToken {
+		t.Fatalf("unexpected forge hosts: %+v", hosts)
+	}
+
+	if err := store.DeleteForgeHost("git.acme.dev"); err != nil {
+		t.Fatalf("delete forge host failed: %v", err)
+	}
+	if _, found, _ := store.GetForgeToken("git.acme.dev"); found {
+		t.Fatal("expected token to be deleted with the host")
+	}
+	if err := store.DeleteForgeHost("git.acme.dev"); err == nil {
+		t.Fatal("expected not found error")
+	}
+	if err := store.SetForgeHost(ForgeHost{Host: "x"}, ""); err == nil {
+		t.Fatal("expected empty kind error")
+	}
+}
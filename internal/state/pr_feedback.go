@@ -0,0 +1,87 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxPRFeedbackAttempts caps how many runs one PR feedback item is handed
+// to. Once reached, the item counts as addressed whatever the last run did.
+const MaxPRFeedbackAttempts = 3
+
+// RecordPRFeedback marks PR feedback items (review comments, failed checks)
+// as handed to runID, replacing any earlier run they were handed to and
+// counting the attempt.
+func (s *Store) RecordPRFeedback(sessionID, runID string, keys []string) error {
+	sessionID = strings.TrimSpace(sessionID)
+	runID = strings.TrimSpace(runID)
+	if sessionID == "" {
+		return errors.New("session id cannot be empty")
+	}
+	if runID == "" {
+		return errors.New("run id cannot be empty")
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin record pr feedback: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := nowRFC3339Nano()
+	for _, key := range keys {
+		if _, err := tx.Exec(
+			`INSERT INTO pr_feedback(session_id, item_key, run_id, created_at) VALUES(?, ?, ?, ?)
+			 ON CONFLICT(session_id, item_key) DO UPDATE SET run_id=excluded.run_id, attempts=pr_feedback.attempts + 1, created_at=excluded.created_at`,
+			sessionID, key, runID, now,
+		); err != nil {
+			return fmt.Errorf("record pr feedback %q: %w", key, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit pr feedback: %w", err)
+	}
+	return nil
+}
+
+// AddressedPRFeedback returns the PR feedback items of a session already
+// handed to a run. Items whose run failed or was interrupted count as
+// unaddressed so the next sync picks them up again, until they have used
+// MaxPRFeedbackAttempts runs. A run that was cancelled, dropped from the
+// queue or stopped by a limit was stopped on purpose and is not retried.
+func (s *Store) AddressedPRFeedback(sessionID string) (map[string]bool, error) {
+	sessionID = strings.TrimSpace(sessionID)
+	if sessionID == "" {
+		return nil, errors.New("session id cannot be empty")
+	}
+	rows, err := s.db.Query(
+		`SELECT f.item_key
+		   FROM pr_feedback f
+		   JOIN runs r ON r.id = f.run_id
+		  WHERE f.session_id = ?
+		    AND (r.state NOT IN ('FAILED', 'INTERRUPTED') OR f.attempts >= ?)`,
+		sessionID,
+		MaxPRFeedbackAttempts,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list pr feedback: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	addressed := map[string]bool{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("scan pr feedback: %w", err)
+		}
+		addressed[key] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate pr feedback: %w", err)
+	}
+	return addressed, nil
+}
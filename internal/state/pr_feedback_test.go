@@ -0,0 +1,116 @@
+package state
+
+import "testing"
+
+func TestAddressedPRFeedbackIgnoresFailedRuns(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedQueueSession(t, store)
+
+	for _, run := range []Run{
+		{ID: "run-ok", SessionID: "sess-1", Prompt: "p", WorktreePath: "/tmp/wt", State: "COMPLETED"},
+		{ID: "run-bad", SessionID: "sess-1", Prompt: "p", WorktreePath: "/tmp/wt", State: "FAILED"},
+	} {
+		if err := store.CreateRun(run); err != nil {
+			t.Fatalf("create run failed: %v", err)
+		}
+	}
+	if err := store.RecordPRFeedback("sess-1", "run-ok", []string{"review_comment:1", "check:9"}); err != nil {
+		t.Fatalf("record feedback failed: %v", err)
+	}
+	if err := store.RecordPRFeedback("sess-1", "run-bad", []string{"comment:2"}); err != nil {
+		t.Fatalf("record feedback failed: %v", err)
+	}
+
+	addressed, err := store.AddressedPRFeedback("sess-1")
+	if err != nil {
+		t.Fatalf("addressed feedback failed: %v", err)
+	}
+	if len(addressed) != 2 || !addressed["review_comment:1"] || !addressed["check:9"] {
+		t.Fatalf("unexpected addressed set: %v", addressed)
+	}
+
+	// Handing the item to a successful run marks it addressed.
+	if err := store.RecordPRFeedback("sess-1", "run-ok", []string{"comment:2"}); err != nil {
+		t.Fatalf("record feedback failed: %v", err)
+	}
+	addressed, err = store.AddressedPRFeedback("sess-1")
+	if err != nil {
+		t.Fatalf("addressed feedback failed: %v", err)
+	}
+	if !addressed["comment:2"] {
+		t.Fatalf("expected comment:2 to be addressed, got %v", addressed)
+	}
+
+	if err := store.DeleteSession("sess-1"); err != nil {
+		t.Fatalf("delete session failed: %v", err)
+	}
+	addressed, err = store.AddressedPRFeedback("sess-1")
+	if err != nil {
+		t.Fatalf("addressed feedback failed: %v", err)
+	}
+	if len(addressed) != 0 {
+		t.Fatalf("expected feedback to be removed with the session, got %v", addressed)
+	}
+}
+
+func TestAddressedPRFeedbackRetriesOnlyFailedRunsUpToCap(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedQueueSession(t, store)
+
+	for _, run := range []Run{
+		{ID: "run-cancelled", SessionID: "sess-1", Prompt: "p", WorktreePath: "/tmp/wt", State: "CANCELLED"},
+		{ID: "run-budget", SessionID: "sess-1", Prompt: "p", WorktreePath: "/tmp/wt", State: "BUDGET_EXCEEDED"},
+		{ID: "run-timeout", SessionID: "sess-1", Prompt: "p", WorktreePath: "/tmp/wt", State: "TIMED_OUT"},
+		{ID: "run-interrupted", SessionID: "sess-1", Prompt: "p", WorktreePath: "/tmp/wt", State: "INTERRUPTED"},
+		{ID: "run-failed", SessionID: "sess-1", Prompt: "p", WorktreePath: "/tmp/wt", State: "FAILED"},
+	} {
+		if err := store.CreateRun(run); err != nil {
+			t.Fatalf("create run failed: %v", err)
+		}
+	}
+	for runID, key := range map[string]string{
+		"run-cancelled":   "comment:1",
+		"run-budget":      "comment:2",
+		"run-timeout":     "comment:3",
+		"run-interrupted": "comment:4",
+	} {
+		if err := store.RecordPRFeedback("sess-1", runID, []string{key}); err != nil {
+			t.Fatalf("record feedback failed: %v", err)
+		}
+	}
+
+	addressed, err := store.AddressedPRFeedback("sess-1")
+	if err != nil {
+		t.Fatalf("addressed feedback failed: %v", err)
+	}
+	for _, key := range []string{"comment:1", "comment:2", "comment:3"} {
+		if !addressed[key] {
+			t.Fatalf("feedback stopped on purpose should not be retried: %s missing from %v", key, addressed)
+		}
+	}
+	if addressed["comment:4"] {
+		t.Fatalf("feedback of an interrupted run should be retried: %v", addressed)
+	}
+
+	for attempt := 1; attempt <= MaxPRFeedbackAttempts; attempt++ {
+		addressed, err := store.AddressedPRFeedback("sess-1")
+		if err != nil {
+			t.Fatalf("addressed feedback failed: %v", err)
+		}
+		if addressed["check:9"] {
+			t.Fatalf("check should be retried before attempt %d", attempt)
+		}
+		if err := store.RecordPRFeedback("sess-1", "run-failed", []string{"check:9"}); err != nil {
+			t.Fatalf("record feedback failed: %v", err)
+		}
+	}
+	addressed, err = store.AddressedPRFeedback("sess-1")
+	if err != nil {
+		t.Fatalf("addressed feedback failed: %v", err)
+	}
+	if !addressed["check:9"] {
+		t.Fatalf("check should stop being retried after %d attempts: %v", MaxPRFeedbackAttempts, addressed)
+	}
+}
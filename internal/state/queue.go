@@ -0,0 +1,347 @@
+package state
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RunStateQueued marks a follow-up run waiting for its session to become idle.
+const RunStateQueued = "QUEUED"
+
+// TryClaimSession atomically marks an idle session busy.
+// claimed=false means the session was already busy.
+func (s *Store) TryClaimSession(id string) (claimed bool, err error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return false, errors.New("session id cannot be empty")
+	}
+
+	res, err := s.db.Exec(
+		`UPDATE sessions
+		    SET busy = 1, updated_at = ?
+		  WHERE id = ? AND busy = 0`,
+		nowRFC3339Nano(),
+		id,
+	)
+	if err != nil {
+		return false, fmt.Errorf("claim session %q: %w", id, err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("rows affected for session %s: %w", id, err)
+	}
+	return rows > 0, nil
+}
+
+// EnqueueRun inserts a run in QUEUED state at the tail of its session queue
+// and returns the assigned 1-based queue position.
+func (s *Store) EnqueueRun(run Run) (int, error) {
+	run.ID = strings.TrimSpace(run.ID)
+	run.SessionID = strings.TrimSpace(run.SessionID)
+	run.Prompt = strings.TrimSpace(run.Prompt)
+	run.WorktreePath = strings.TrimSpace(run.WorktreePath)
+
+	switch {
+	case run.ID == "":
+		return 0, errors.New("run id cannot be empty")
+	case run.SessionID == "":
+		return 0, errors.New("run session_id cannot be empty")
+	case run.Prompt == "":
+		return 0, errors.New("run prompt cannot be empty")
+	case run.WorktreePath == "":
+		return 0, errors.New("run worktree_path cannot be empty")
+	}
+
+	createdAt := run.CreatedAt.UTC()
+	if createdAt.IsZero() {
+		createdAt = time.Now().UTC()
+	}
+	ts := createdAt.Format(time.RFC3339Nano)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin enqueue run %q: %w", run.ID, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var position int
+	if err := tx.QueryRow(
+		`SELECT COALESCE(MAX(queue_position), 0) + 1
+		   FROM runs
+		  WHERE session_id = ? AND state = ?`,
+		run.SessionID,
+		RunStateQueued,
+	).Scan(&position); err != nil {
+		return 0, fmt.Errorf("next queue position for session %q: %w", run.SessionID, err)
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO runs(id, session_id, prompt, worktree_path, state, commit_sha, commit_msg, error, created_at, updated_at, queue_position, priority)
+		 VALUES(?, ?, ?, ?, ?, '', '', '', ?, ?, ?, ?)`,
+		run.ID,
+		run.SessionID,
+		run.Prompt,
+		run.WorktreePath,
+		RunStateQueued,
+		ts,
+		ts,
+		position,
+		run.Priority,
+	); err != nil {
+		return 0, fmt.Errorf("enqueue run %q: %w", run.ID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit enqueue run %q: %w", run.ID, err)
+	}
+	return position, nil
+}
+
+// ListQueuedRuns returns the QUEUED runs of a session in execution order.
+func (s *Store) ListQueuedRuns(sessionID string) ([]Run, error) {
+	sessionID = strings.TrimSpace(sessionID)
+	if sessionID == "" {
+		return nil, errors.New("session id cannot be empty")
+	}
+
+	rows, err := s.db.Query(
+		`SELECT `+runColumns+`
+		   FROM runs
+		  WHERE session_id = ? AND state = ?
+		  ORDER BY queue_position ASC, created_at ASC`,
+		sessionID,
+		RunStateQueued,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list queued runs for session %q: %w", sessionID, err)
+	}
+	return collectRuns(rows)
+}
+
+// NextQueuedRun returns the head of a session queue.
+func (s *Store) NextQueuedRun(sessionID string) (Run, bool, error) {
+	sessionID = strings.TrimSpace(sessionID)
+	if sessionID == "" {
+		return Run{}, false, errors.New("session id cannot be empty")
+	}
+
+	run, err := scanRun(s.db.QueryRow(
+		`SELECT `+runColumns+`
+		   FROM runs
+		  WHERE session_id = ? AND state = ?
+		  ORDER BY queue_position ASC, created_at ASC
+		  LIMIT 1`,
+		sessionID,
+		RunStateQueued,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Run{}, false, nil
+	}
+	if err != nil {
+		return Run{}, false, fmt.Errorf("next queued run for session %q: %w", sessionID, err)
+	}
+	return run, true, nil
+}
+
+// DequeueRun moves a QUEUED run into the given state, clears its queue slot
+// and moves the remaining queued runs up. created_at is reset so run history
+// reflects execution order rather than enqueue order after a reorder.
+func (s *Store) DequeueRun(id, state string) error {
+	id = strings.TrimSpace(id)
+	state = strings.TrimSpace(state)
+	if id == "" {
+		return errors.New("run id cannot be empty")
+	}
+	if state == "" {
+		return errors.New("run state cannot be empty")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin dequeue run %q: %w", id, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var sessionID string
+	var position sql.NullInt64
+	err = tx.QueryRow(
+		`SELECT session_id, queue_position FROM runs WHERE id = ? AND state = ?`,
+		id,
+		RunStateQueued,
+	).Scan(&sessionID, &position)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("queued run %s not found", id)
+	}
+	if err != nil {
+		return fmt.Errorf("lookup queued run %q: %w", id, err)
+	}
+
+	now := nowRFC3339Nano()
+	if _, err := tx.Exec(
+		`UPDATE runs
+		    SET state = ?, queue_position = NULL, created_at = ?, updated_at = ?
+		  WHERE id = ?`,
+		state,
+		now,
+		now,
+		id,
+	); err != nil {
+		return fmt.Errorf("dequeue run %q: %w", id, err)
+	}
+	if err := compactQueue(tx, sessionID, position); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit dequeue run %q: %w", id, err)
+	}
+	return nil
+}
+
+// ReorderQueuedRuns rewrites queue positions for a session. runIDs must list
+// every currently queued run exactly once, in the desired order.
+func (s *Store) ReorderQueuedRuns(sessionID string, runIDs []string) error {
+	sessionID = strings.TrimSpace(sessionID)
+	if sessionID == "" {
+		return errors.New("session id cannot be empty")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin reorder queue %q: %w", sessionID, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.Query(
+		`SELECT id FROM runs WHERE session_id = ? AND state = ?`,
+		sessionID,
+		RunStateQueued,
+	)
+	if err != nil {
+		return fmt.Errorf("list queue %q: %w", sessionID, err)
+	}
+	queued := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("scan queued run: %w", err)
+		}
+		queued[id] = false
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return fmt.Errorf("iterate queued runs: %w", err)
+	}
+	_ = rows.Close()
+
+	if len(runIDs) != len(queued) {
+		return fmt.Errorf("queue for session %q has %d runs, got %d run ids", sessionID, len(queued), len(runIDs))
+	}
+	for _, id := range runIDs {
+		id = strings.TrimSpace(id)
+		seen, ok := queued[id]
+		if !ok {
+			return fmt.Errorf("run %q is not queued in session %q", id, sessionID)
+		}
+		if seen {
+			return fmt.Errorf("run %q listed more than once", id)
+		}
+		queued[id] = true
+	}
+
+	now := nowRFC3339Nano()
+	for i, id := range runIDs {
+		if _, err := tx.Exec(
+			`UPDATE runs SET queue_position = ?, updated_at = ? WHERE id = ?`,
+			i+1,
+			now,
+			strings.TrimSpace(id),
+		); err != nil {
+			return fmt.Errorf("set queue position for %q: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit reorder queue %q: %w", sessionID, err)
+	}
+	return nil
+}
+
+// RemoveQueuedRun drops one run from a session queue, marking it terminal
+// with the given state and reason. Later runs move up one slot.
+func (s *Store) RemoveQueuedRun(sessionID, runID, state, reason string) error {
+	sessionID = strings.TrimSpace(sessionID)
+	runID = strings.TrimSpace(runID)
+	state = strings.TrimSpace(state)
+	switch {
+	case sessionID == "":
+		return errors.New("session id cannot be empty")
+	case runID == "":
+		return errors.New("run id cannot be empty")
+	case state == "":
+		return errors.New("run state cannot be empty")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin remove queued run %q: %w", runID, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var position sql.NullInt64
+	err = tx.QueryRow(
+		`SELECT queue_position FROM runs WHERE id = ? AND session_id = ? AND state = ?`,
+		runID,
+		sessionID,
+		RunStateQueued,
+	).Scan(&position)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("queued run %s not found", runID)
+	}
+	if err != nil {
+		return fmt.Errorf("lookup queued run %q: %w", runID, err)
+	}
+
+	now := nowRFC3339Nano()
+	if _, err := tx.Exec(
+		`UPDATE runs
+		    SET state = ?, error = ?, queue_position = NULL, updated_at = ?, completed_at = ?
+		  WHERE id = ?`,
+		state,
+		strings.TrimSpace(reason),
+		now,
+		now,
+		runID,
+	); err != nil {
+		return fmt.Errorf("remove queued run %q: %w", runID, err)
+	}
+	if err := compactQueue(tx, sessionID, position); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit remove queued run %q: %w", runID, err)
+	}
+	return nil
+}
+
+func compactQueue(tx *sql.Tx, sessionID string, removed sql.NullInt64) error {
+	if !removed.Valid {
+		return nil
+	}
+	if _, err := tx.Exec(
+		`UPDATE runs
+		    SET queue_position = queue_position - 1
+		  WHERE session_id = ? AND state = ? AND queue_position > ?`,
+		sessionID,
+		RunStateQueued,
+		removed.Int64,
+	); err != nil {
+		return fmt.Errorf("compact queue %q: %w", sessionID, err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package state
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunQueueLifecycle(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedQueueSession(t, store)
+
+	claimed, err := store.TryClaimSession("sess-1")
+	if err != nil {
+		t.Fatalf("claim session failed: %v", err)
+	}
+	if !claimed {
+		t.Fatal("expected idle session to be claimed")
+	}
+	claimed, err = store.TryClaimSession("sess-1")
+	if err != nil {
+		t.Fatalf("second claim failed: %v", err)
+	}
+	if claimed {
+		t.Fatal("expected busy session claim to fail")
+	}
+
+	now := time.Now().UTC()
+	for i, id := range []string{"q-1", "q-2", "q-3"} {
+		pos, err := store.EnqueueRun(Run{
+			ID:           id,
+			SessionID:    "sess-1",
+			Prompt:       "follow up " + id,
+			WorktreePath: "/tmp/wt",
+			CreatedAt:    now.Add(time.Duration(i) * time.Second),
+		})
+		if err != nil {
+			t.Fatalf("enqueue %s failed: %v", id, err)
+		}
+		if pos != i+1 {
+			t.Fatalf("unexpected queue position for %s: got %d want %d", id, pos, i+1)
+		}
+	}
+
+	if _, found, err := store.GetLatestRun("sess-1"); err != nil || found {
+		t.Fatalf("queued runs must not count as latest run: found=%v err=%v", found, err)
+	}
+
+	if err := store.ReorderQueuedRuns("sess-1", []string{"q-3", "q-1", "q-2"}); err != nil {
+		t.Fatalf("reorder queue failed: %v", err)
+	}
+	assertQueueOrder(t, store, "q-3", "q-1", "q-2")
+
+	if err := store.ReorderQueuedRuns("sess-1", []string{"q-3", "q-1"}); err == nil {
+		t.Fatal("expected reorder with missing run ids to fail")
+	}
+	if err := store.ReorderQueuedRuns("sess-1", []string{"q-3", "q-3", "q-1"}); err == nil {
+		t.Fatal("expected reorder with duplicate run ids to fail")
+	}
+
+	if err := store.RemoveQueuedRun("sess-1", "q-1", "CANCELLED", "removed from queue"); err != nil {
+		t.Fatalf("remove queued run failed: %v", err)
+	}
+	assertQueueOrder(t, store, "q-3", "q-2")
+	removed, _, err := store.GetRun("q-1")
+	if err != nil {
+		t.Fatalf("get removed run failed: %v", err)
+	}
+	if removed.State != "CANCELLED" || removed.CompletedAt == nil || removed.QueuePosition != 0 {
+		t.Fatalf("unexpected removed run: %+v", removed)
+	}
+
+	next, found, err := store.NextQueuedRun("sess-1")
+	if err != nil || !found {
+		t.Fatalf("next queued run failed: found=%v err=%v", found, err)
+	}
+	if next.ID != "q-3" {
+		t.Fatalf("unexpected queue head: %q", next.ID)
+	}
+	if err := store.DequeueRun(next.ID, "CREATED"); err != nil {
+		t.Fatalf("dequeue run failed: %v", err)
+	}
+	assertQueueOrder(t, store, "q-2")
+
+	latest, found, err := store.GetLatestRun("sess-1")
+	if err != nil || !found {
+		t.Fatalf("get latest run failed: found=%v err=%v", found, err)
+	}
+	if latest.ID != "q-3" || latest.State != "CREATED" {
+		t.Fatalf("dequeued run should be latest: %+v", latest)
+	}
+
+	if err := store.DequeueRun("q-3", "CREATED"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected second dequeue to fail, got %v", err)
+	}
+}
+
+func seedQueueSession(t *testing.T, store *Store) {
+	t.Helper()
+	if _, err := store.UpsertRepo(Repo{
+		Name:             "acme/api",
+		URL:              "https://github.com/acme/api.git",
+		Host:             "github.com",
+		BarePath:         "/tmp/acme-api/repo.git",
+		BaseWorktreePath: "/tmp/acme-api/base",
+	}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	if err := store.CreateSession(Session{
+		ID:           "sess-1",
+		RepoName:     "acme/api",
+		Branch:       "fog/queue",
+		WorktreePath: "/tmp/wt",
+		Tool:         "claude",
+		Status:       "COMPLETED",
+	}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+}
+
+func assertQueueOrder(t *testing.T, store *Store, want ...string) {
+	t.Helper()
+	runs, err := store.ListQueuedRuns("sess-1")
+	if err != nil {
+		t.Fatalf("list queued runs failed: %v", err)
+	}
+	if len(runs) != len(want) {
+		t.Fatalf("unexpected queue length: got %d want %d", len(runs), len(want))
+	}
+	for i, run := range runs {
+		if run.ID != want[i] || run.QueuePosition != i+1 {
+			t.Fatalf("unexpected queue entry %d: got %s@%d want %s@%d", i, run.ID, run.QueuePosition, want[i], i+1)
+		}
+	}
+}
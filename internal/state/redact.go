@@ -0,0 +1,160 @@
+package state
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/redact"
+)
+
+// SettingRedactPatterns holds custom redaction patterns, one regular
+// expression per line, applied on top of redact.DefaultPatterns.
+const SettingRedactPatterns = "redact_patterns"
+
+// redactorTTL bounds how long the cached redactor is used before it is
+// rebuilt, so secrets stored by another process (e.g. the fog CLI) are
+// picked up.
+const redactorTTL = 30 * time.Second
+
+// RedactPatterns returns the configured custom redaction patterns.
+func (s *Store) RedactPatterns() ([]string, error) {
+	raw, found, err := s.GetSetting(SettingRedactPatterns)
+	if err != nil || !found {
+		return nil, err
+	}
+	var patterns []string
+	for _, line := range strings.Split(raw, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns, nil
+}
+
+// SetRedactPatterns validates and stores custom redaction patterns; an
+// empty list removes them.
+func (s *Store) SetRedactPatterns(patterns []string) error {
+	compiled, err := redact.CompilePatterns(patterns)
+	if err != nil {
+		return err
+	}
+	if len(compiled) == 0 {
+		return s.DeleteSetting(SettingRedactPatterns)
+	}
+	exprs := make([]string, 0, len(compiled))
+	for _, pattern := range compiled {
+		exprs = append(exprs, pattern.String())
+	}
+	return s.SetSetting(SettingRedactPatterns, strings.Join(exprs, "\n"))
+}
+
+// SetRunSecrets registers extra values to mask in the run's events and
+// error until ClearRunSecrets is called.
+func (s *Store) SetRunSecrets(runID string, secrets []string) {
+	s.redactMu.Lock()
+	defer s.redactMu.Unlock()
+	if len(secrets) == 0 {
+		delete(s.runRedactors, runID)
+		return
+	}
+	if s.runRedactors == nil {
+		s.runRedactors = make(map[string]*redact.Redactor)
+	}
+	s.runRedactors[runID] = redact.New(secrets, nil)
+}
+
+// ClearRunSecrets forgets the values registered for a run.
+func (s *Store) ClearRunSecrets(runID string) {
+	s.redactMu.Lock()
+	defer s.redactMu.Unlock()
+	delete(s.runRedactors, runID)
+}
+
+// Redact masks stored secrets, secret repo env values, common token formats
+// and custom patterns in text.
+func (s *Store) Redact(text string) string {
+	return s.baseRedactor().Redact(text)
+}
+
+// RedactRunOutput masks everything Redact does plus the run's registered
+// secrets.
+func (s *Store) RedactRunOutput(runID, text string) string {
+	if text == "" {
+		return text
+	}
+	s.redactMu.RLock()
+	run := s.runRedactors[runID]
+	s.redactMu.RUnlock()
+	return s.Redact(run.Redact(text))
+}
+
+func (s *Store) invalidateRedactor() {
+	s.redactMu.Lock()
+	defer s.redactMu.Unlock()
+	s.redactor = nil
+}
+
+// baseRedactor returns the cached store-wide redactor, rebuilding it when
+// stale. Loading errors degrade to pattern-only redaction rather than
+// blocking event writes.
+func (s *Store) baseRedactor() *redact.Redactor {
+	s.redactMu.RLock()
+	cached, loaded := s.redactor, s.redactorLoaded
+	s.redactMu.RUnlock()
+	if cached != nil && time.Since(loaded) < redactorTTL {
+		return cached
+	}
+
+	patterns := append([]*regexp.Regexp(nil), redact.DefaultPatterns...)
+	if custom, err := s.RedactPatterns(); err == nil {
+		if compiled, err := redact.CompilePatterns(custom); err == nil {
+			patterns = append(patterns, compiled...)
+		}
+	}
+	values, _ := s.storedSecretValues()
+	r := redact.New(values, patterns)
+
+	s.redactMu.Lock()
+	s.redactor, s.redactorLoaded = r, time.Now()
+	s.redactMu.Unlock()
+	return r
+}
+
+// storedSecretValues decrypts every stored secret and secret repo env value.
+func (s *Store) storedSecretValues() ([]string, error) {
+	var values []string
+	rows, err := s.db.Query(`SELECT key, ciphertext FROM secrets`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var key string
+		var ciphertext []byte
+		if err := rows.Scan(&key, &ciphertext); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		if plaintext, err := decrypt(key, ciphertext, s.key); err == nil {
+			values = append(values, string(plaintext))
+		}
+	}
+	_ = rows.Close()
+
+	rows, err = s.db.Query(`SELECT repo_name, name, ciphertext FROM repo_env WHERE secret = 1`)
+	if err != nil {
+		return values, err
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var repoName, name string
+		var ciphertext []byte
+		if err := rows.Scan(&repoName, &name, &ciphertext); err != nil {
+			return values, err
+		}
+		if plaintext, err := decrypt(repoEnvAAD(repoName, name), ciphertext, s.key); err == nil {
+			values = append(values, string(plaintext))
+		}
+	}
+	return values, rows.Err()
+}
@@ -0,0 +1,125 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// RepoEnvVar is an environment variable injected into a repo's runs. Values
+// are stored encrypted; Secret values are also masked in run output.
+type RepoEnvVar struct {
+	Name      string    `json:"name"`
+	Value     string    `json:"value,omitempty"`
+	Secret    bool      `json:"secret"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ValidateEnvName reports whether name is a usable environment variable name.
+func ValidateEnvName(name string) error {
+	if !envNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid env var name %q", name)
+	}
+	return nil
+}
+
+// SetRepoEnv encrypts and stores one env var for a repo, replacing any
+// existing value.
+func (s *Store) SetRepoEnv(repoName, name, value string, secret bool) error {
+	repoName = strings.TrimSpace(repoName)
+	name = strings.TrimSpace(name)
+	if repoName == "" {
+		return errors.New("repo name cannot be empty")
+	}
+	if err := ValidateEnvName(name); err != nil {
+		return err
+	}
+	if value == "" {
+		return errors.New("env var value cannot be empty")
+	}
+	ciphertext, err := encrypt(repoEnvAAD(repoName, name), []byte(value), s.key)
+	if err != nil {
+		return fmt.Errorf("encrypt env var %q: %w", name, err)
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO repo_env(repo_name, name, ciphertext, secret, updated_at) VALUES(?, ?, ?, ?, ?)
+		 ON CONFLICT(repo_name, name) DO UPDATE SET ciphertext=excluded.ciphertext, secret=excluded.secret, updated_at=excluded.updated_at`,
+		repoName,
+		name,
+		ciphertext,
+		boolToInt(secret),
+		nowRFC3339Nano(),
+	)
+	if err != nil {
+		return fmt.Errorf("set env var %q for %q: %w", name, repoName, err)
+	}
+	s.invalidateRedactor()
+	return nil
+}
+
+// ListRepoEnv returns a repo's env vars with decrypted values, sorted by name.
+func (s *Store) ListRepoEnv(repoName string) ([]RepoEnvVar, error) {
+	repoName = strings.TrimSpace(repoName)
+	if repoName == "" {
+		return nil, errors.New("repo name cannot be empty")
+	}
+	rows, err := s.db.Query(
+		`SELECT name, ciphertext, secret, updated_at FROM repo_env WHERE repo_name = ? ORDER BY name`,
+		repoName,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list env vars for %q: %w", repoName, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var vars []RepoEnvVar
+	for rows.Next() {
+		var v RepoEnvVar
+		var ciphertext []byte
+		var secret int
+		var updatedAt string
+		if err := rows.Scan(&v.Name, &ciphertext, &secret, &updatedAt); err != nil {
+			return nil, fmt.Errorf("scan env var: %w", err)
+		}
+		plaintext, err := decrypt(repoEnvAAD(repoName, v.Name), ciphertext, s.key)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt env var %q: %w", v.Name, err)
+		}
+		v.Value = string(plaintext)
+		v.Secret = secret != 0
+		if ts, err := time.Parse(time.RFC3339Nano, updatedAt); err == nil {
+			v.UpdatedAt = ts
+		}
+		vars = append(vars, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate env vars: %w", err)
+	}
+	return vars, nil
+}
+
+// UnsetRepoEnv removes one env var from a repo.
+func (s *Store) UnsetRepoEnv(repoName, name string) error {
+	repoName = strings.TrimSpace(repoName)
+	name = strings.TrimSpace(name)
+	if repoName == "" {
+		return errors.New("repo name cannot be empty")
+	}
+	if name == "" {
+		return errors.New("env var name cannot be empty")
+	}
+	res, err := s.db.Exec(`DELETE FROM repo_env WHERE repo_name = ? AND name = ?`, repoName, name)
+	if err != nil {
+		return fmt.Errorf("unset env var %q for %q: %w", name, repoName, err)
+	}
+	s.invalidateRedactor()
+	return ensureRowsAffected(res, "env var "+name)
+}
+
+func repoEnvAAD(repoName, name string) string {
+	return "repo_env:" + repoName + ":" + name
+}
@@ -0,0 +1,118 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRepoEnvRoundTripEncrypted(t *testing.T) {
+	tmp := t.TempDir()
+	store, err := NewStore(tmp)
+	if err != nil {
+		t.Fatalf("new store failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	if err := store.SetRepoEnv("acme/api", "DATABASE_URL", "postgres://localhost/test", false); err != nil {
+		t.Fatalf("set env failed: %v", err)
+	}
+	if err := store.SetRepoEnv("acme/api", "API_KEY", "sk-first-value", true); err != nil {
+		t.Fatalf("set secret failed: %v", err)
+	}
+	if err := store.SetRepoEnv("acme/api", "API_KEY", "sk-second-value", true); err != nil {
+		t.Fatalf("overwrite secret failed: %v", err)
+	}
+	if err := store.SetRepoEnv("acme/web", "API_KEY", "sk-web-value", true); err != nil {
+		t.Fatalf("set other repo secret failed: %v", err)
+	}
+
+	vars, err := store.ListRepoEnv("acme/api")
+	if err != nil {
+		t.Fatalf("list env failed: %v", err)
+	}
+	if len(vars) != 2 {
+		t.Fatalf("expected 2 env vars, got %+v", vars)
+	}
+	if vars[0].Name != "API_KEY" || vars[0].Value != "sk-second-value" || !vars[0].Secret {
+		t.Fatalf("unexpected secret var: %+v", vars[0])
+	}
+	if vars[1].Name != "DATABASE_URL" || vars[1].Value != "postgres://localhost/test" || vars[1].Secret {
+		t.Fatalf("unexpected plain var: %+v", vars[1])
+	}
+
+	dbBytes, err := os.ReadFile(filepath.Join(tmp, defaultDBName))
+	if err != nil {
+		t.Fatalf("read db failed: %v", err)
+	}
+	if strings.Contains(string(dbBytes), "sk-second-value") {
+		t.Fatal("raw env value should not appear in sqlite file")
+	}
+
+	if err := store.UnsetRepoEnv("acme/api", "API_KEY"); err != nil {
+		t.Fatalf("unset env failed: %v", err)
+	}
+	if err := store.UnsetRepoEnv("acme/api", "API_KEY"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found unsetting twice, got %v", err)
+	}
+	if vars, _ := store.ListRepoEnv("acme/web"); len(vars) != 1 {
+		t.Fatalf("other repo env should be untouched, got %+v", vars)
+	}
+}
+
+func TestSetRepoEnvValidatesName(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	for _, name := range []string{"", "1ABC", "MY-VAR", "A B"} {
+		if err := store.SetRepoEnv("acme/api", name, "value", false); err == nil {
+			t.Fatalf("expected invalid name %q to be rejected", name)
+		}
+	}
+	if err := store.SetRepoEnv("acme/api", "EMPTY", "", false); err == nil {
+		t.Fatal("expected empty value to be rejected")
+	}
+}
+
+func TestRunSecretsRedactEventsAndError(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedQueueSession(t, store)
+	if err := store.CreateRun(Run{ID: "run-1", SessionID: "sess-1", Prompt: "p", WorktreePath: "/tmp/wt", State: "AI_RUNNING"}); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+
+	store.SetRunSecrets("run-1", []string{"abc", "sk-live-1234", "sk-live-1234-extended"})
+	if err := store.AppendRunEvent(RunEvent{RunID: "run-1", Type: "ai_stream", Message: "key sk-live-1234", Data: "x=sk-live-1234-extended abc"}); err != nil {
+		t.Fatalf("append event failed: %v", err)
+	}
+	if err := store.CompleteRun("run-1", "FAILED", "", "", "auth failed for sk-live-1234"); err != nil {
+		t.Fatalf("complete run failed: %v", err)
+	}
+	store.ClearRunSecrets("run-1")
+	if err := store.AppendRunEvent(RunEvent{RunID: "run-1", Type: "note", Message: "sk-live-1234"}); err != nil {
+		t.Fatalf("append event failed: %v", err)
+	}
+
+	events, err := store.ListRunEvents("run-1", 10)
+	if err != nil {
+		t.Fatalf("list events failed: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %+v", events)
+	}
+	if events[0].Message != "key [REDACTED]" || events[0].Data != "x=[REDACTED] abc" {
+		t.Fatalf("unexpected redaction: %+v", events[0])
+	}
+	if events[1].Message != "sk-live-1234" {
+		t.Fatalf("cleared secrets should not be redacted: %+v", events[1])
+	}
+	run, found, err := store.GetRun("run-1")
+	if err != nil || !found {
+		t.Fatalf("get run failed: found=%v err=%v", found, err)
+	}
+	if run.Error != "auth failed for [REDACTED]" {
+		t.Fatalf("unexpected run error: %q", run.Error)
+	}
+}
@@ -0,0 +1,413 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/cron"
+)
+
+// Schedule run statuses.
+const (
+	ScheduleRunStarted = "STARTED"
+	ScheduleRunSkipped = "SKIPPED"
+	ScheduleRunFailed  = "FAILED"
+)
+
+// Schedule is a recurring session started by fogd on a cron expression.
+// Each activation creates a new session on a fresh branch.
+type Schedule struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	RepoName string `json:"repo_name"`
+	// Cron is a five-field cron expression evaluated in the daemon's local
+	// time zone.
+	Cron string `json:"cron"`
+	// Prompt is sent as is, or appended to the rendered Template.
+	Prompt    string            `json:"prompt,omitempty"`
+	Template  string            `json:"template,omitempty"`
+	Vars      map[string]string `json:"vars,omitempty"`
+	Tool      string            `json:"tool,omitempty"`
+	Model     string            `json:"model,omitempty"`
+	AutoPR    bool              `json:"autopr"`
+	Enabled   bool              `json:"enabled"`
+	NextRunAt *time.Time        `json:"next_run_at,omitempty"`
+	LastRunAt *time.Time        `json:"last_run_at,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// ScheduleRun records one activation of a schedule.
+type ScheduleRun struct {
+	ID         int64     `json:"id"`
+	ScheduleID string    `json:"schedule_id"`
+	FiredAt    time.Time `json:"fired_at"`
+	Status     string    `json:"status"`
+	SessionID  string    `json:"session_id,omitempty"`
+	RunID      string    `json:"run_id,omitempty"`
+	// RunState is the current state of RunID, joined at read time.
+	RunState string `json:"run_state,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// CreateSchedule validates and inserts a schedule, computing its first
+// activation when it is enabled.
+func (s *Store) CreateSchedule(schedule Schedule) (Schedule, error) {
+	schedule.ID = strings.TrimSpace(schedule.ID)
+	schedule.Name = strings.TrimSpace(schedule.Name)
+	schedule.RepoName = strings.TrimSpace(schedule.RepoName)
+	schedule.Cron = strings.TrimSpace(schedule.Cron)
+	schedule.Prompt = strings.TrimSpace(schedule.Prompt)
+	schedule.Template = strings.TrimSpace(schedule.Template)
+	schedule.Tool = strings.TrimSpace(schedule.Tool)
+	schedule.Model = strings.TrimSpace(schedule.Model)
+
+	switch {
+	case schedule.ID == "":
+		return Schedule{}, errors.New("schedule id cannot be empty")
+	case schedule.Name == "":
+		return Schedule{}, errors.New("schedule name cannot be empty")
+	case schedule.RepoName == "":
+		return Schedule{}, errors.New("schedule repo_name cannot be empty")
+	case schedule.Prompt == "" && schedule.Template == "":
+		return Schedule{}, errors.New("schedule needs a prompt or template")
+	case len(schedule.Vars) > 0 && schedule.Template == "":
+		return Schedule{}, errors.New("schedule vars require a template")
+	}
+	if !templateNamePattern.MatchString(schedule.Name) {
+		return Schedule{}, fmt.Errorf("invalid schedule name %q: use lowercase letters, digits, '.', '_' or '-'", schedule.Name)
+	}
+	expr, err := cron.Parse(schedule.Cron)
+	if err != nil {
+		return Schedule{}, err
+	}
+	if schedule.Template != "" {
+		// Fail now rather than at the first activation.
+		if _, err := s.RenderTemplate(schedule.Template, schedule.Vars, schedule.Prompt); err != nil {
+			return Schedule{}, err
+		}
+	}
+	var vars []byte
+	if len(schedule.Vars) > 0 {
+		if vars, err = json.Marshal(schedule.Vars); err != nil {
+			return Schedule{}, fmt.Errorf("encode schedule vars: %w", err)
+		}
+	}
+	nextRunAt := ""
+	if schedule.Enabled {
+		nextRunAt = formatNextRun(expr.Next(time.Now()))
+	}
+
+	now := nowRFC3339Nano()
+	_, err = s.db.Exec(
+		`INSERT INTO schedules (id, name, repo_name, cron, prompt, template, vars, tool, model, autopr, enabled, next_run_at, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		schedule.ID,
+		schedule.Name,
+		schedule.RepoName,
+		schedule.Cron,
+		nullIfEmpty(schedule.Prompt),
+		nullIfEmpty(schedule.Template),
+		nullIfEmpty(string(vars)),
+		nullIfEmpty(schedule.Tool),
+		nullIfEmpty(schedule.Model),
+		boolToInt(schedule.AutoPR),
+		boolToInt(schedule.Enabled),
+		nullIfEmpty(nextRunAt),
+		now,
+		now,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return Schedule{}, fmt.Errorf("schedule %q already exists", schedule.Name)
+		}
+		return Schedule{}, fmt.Errorf("create schedule %q: %w", schedule.Name, err)
+	}
+	created, _, err := s.GetSchedule(schedule.ID)
+	return created, err
+}
+
+// GetSchedule returns one schedule by ID or name.
+func (s *Store) GetSchedule(idOrName string) (Schedule, bool, error) {
+	idOrName = strings.TrimSpace(idOrName)
+	if idOrName == "" {
+		return Schedule{}, false, errors.New("schedule id cannot be empty")
+	}
+	schedule, err := scanSchedule(s.db.QueryRow(
+		`SELECT `+scheduleColumns+`
+		   FROM schedules
+		  WHERE id = ? OR name = ?`,
+		idOrName,
+		idOrName,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Schedule{}, false, nil
+	}
+	if err != nil {
+		return Schedule{}, false, fmt.Errorf("get schedule %q: %w", idOrName, err)
+	}
+	return schedule, true, nil
+}
+
+// ListSchedules returns all schedules sorted by name.
+func (s *Store) ListSchedules() ([]Schedule, error) {
+	rows, err := s.db.Query(`SELECT ` + scheduleColumns + ` FROM schedules ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("list schedules: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	schedules := []Schedule{}
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan schedule: %w", err)
+		}
+		schedules = append(schedules, schedule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// DueSchedules returns enabled schedules whose next activation is at or
+// before now.
+func (s *Store) DueSchedules(now time.Time) ([]Schedule, error) {
+	schedules, err := s.ListSchedules()
+	if err != nil {
+		return nil, err
+	}
+	due := []Schedule{}
+	for _, schedule := range schedules {
+		if schedule.Enabled && schedule.NextRunAt != nil && !schedule.NextRunAt.After(now) {
+			due = append(due, schedule)
+		}
+	}
+	return due, nil
+}
+
+// SetScheduleEnabled pauses or resumes a schedule. Resuming computes the
+// next activation from now, so missed activations are not replayed.
+func (s *Store) SetScheduleEnabled(id string, enabled bool) (Schedule, error) {
+	schedule, found, err := s.GetSchedule(id)
+	if err != nil {
+		return Schedule{}, err
+	}
+	if !found {
+		return Schedule{}, fmt.Errorf("schedule %s not found", strings.TrimSpace(id))
+	}
+	nextRunAt := ""
+	if enabled {
+		expr, err := cron.Parse(schedule.Cron)
+		if err != nil {
+			return Schedule{}, err
+		}
+		nextRunAt = formatNextRun(expr.Next(time.Now()))
+	}
+	_, err = s.db.Exec(
+		`UPDATE schedules SET enabled = ?, next_run_at = ?, updated_at = ? WHERE id = ?`,
+		boolToInt(enabled),
+		nullIfEmpty(nextRunAt),
+		nowRFC3339Nano(),
+		schedule.ID,
+	)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("update schedule %q: %w", schedule.Name, err)
+	}
+	updated, _, err := s.GetSchedule(schedule.ID)
+	return updated, err
+}
+
+// AdvanceSchedule records an activation at firedAt and moves the next
+// activation to the first cron match after firedAt.
+func (s *Store) AdvanceSchedule(id string, firedAt time.Time) error {
+	schedule, found, err := s.GetSchedule(id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("schedule %s not found", strings.TrimSpace(id))
+	}
+	expr, err := cron.Parse(schedule.Cron)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		`UPDATE schedules SET last_run_at = ?, next_run_at = ?, updated_at = ? WHERE id = ?`,
+		firedAt.UTC().Format(time.RFC3339Nano),
+		nullIfEmpty(formatNextRun(expr.Next(firedAt))),
+		nowRFC3339Nano(),
+		schedule.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("advance schedule %q: %w", schedule.Name, err)
+	}
+	return nil
+}
+
+// DeleteSchedule removes a schedule and its history. Sessions it created
+// are kept.
+func (s *Store) DeleteSchedule(id string) error {
+	schedule, found, err := s.GetSchedule(id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("schedule %s not found", strings.TrimSpace(id))
+	}
+	res, err := s.db.Exec(`DELETE FROM schedules WHERE id = ?`, schedule.ID)
+	if err != nil {
+		return fmt.Errorf("delete schedule %q: %w", schedule.Name, err)
+	}
+	return ensureRowsAffected(res, "schedule "+schedule.Name)
+}
+
+// RecordScheduleRun appends one activation to a schedule's history.
+func (s *Store) RecordScheduleRun(run ScheduleRun) (ScheduleRun, error) {
+	run.ScheduleID = strings.TrimSpace(run.ScheduleID)
+	run.Status = strings.TrimSpace(run.Status)
+	switch {
+	case run.ScheduleID == "":
+		return ScheduleRun{}, errors.New("schedule run schedule_id cannot be empty")
+	case run.Status == "":
+		return ScheduleRun{}, errors.New("schedule run status cannot be empty")
+	}
+	if run.FiredAt.IsZero() {
+		run.FiredAt = time.Now()
+	}
+	res, err := s.db.Exec(
+		`INSERT INTO schedule_runs (schedule_id, fired_at, status, session_id, run_id, error)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		run.ScheduleID,
+		run.FiredAt.UTC().Format(time.RFC3339Nano),
+		run.Status,
+		nullIfEmpty(strings.TrimSpace(run.SessionID)),
+		nullIfEmpty(strings.TrimSpace(run.RunID)),
+		nullIfEmpty(strings.TrimSpace(run.Error)),
+	)
+	if err != nil {
+		return ScheduleRun{}, fmt.Errorf("record schedule run %q: %w", run.ScheduleID, err)
+	}
+	if run.ID, err = res.LastInsertId(); err != nil {
+		return ScheduleRun{}, fmt.Errorf("record schedule run %q: %w", run.ScheduleID, err)
+	}
+	return run, nil
+}
+
+// ListScheduleRuns returns a schedule's activations, newest first.
+func (s *Store) ListScheduleRuns(scheduleID string, limit int) ([]ScheduleRun, error) {
+	scheduleID = strings.TrimSpace(scheduleID)
+	if scheduleID == "" {
+		return nil, errors.New("schedule id cannot be empty")
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := s.db.Query(
+		`SELECT sr.id, sr.schedule_id, sr.fired_at, sr.status, sr.session_id, sr.run_id, r.state, sr.error
+		   FROM schedule_runs sr
+		   LEFT JOIN runs r ON r.id = sr.run_id
+		  WHERE sr.schedule_id = ?
+		  ORDER BY sr.id DESC
+		  LIMIT ?`,
+		scheduleID,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list schedule runs %q: %w", scheduleID, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	runs := []ScheduleRun{}
+	for rows.Next() {
+		var run ScheduleRun
+		var firedAt string
+		var sessionID, runID, runState, errMsg sql.NullString
+		if err := rows.Scan(&run.ID, &run.ScheduleID, &firedAt, &run.Status, &sessionID, &runID, &runState, &errMsg); err != nil {
+			return nil, fmt.Errorf("scan schedule run: %w", err)
+		}
+		if ts, err := time.Parse(time.RFC3339Nano, firedAt); err == nil {
+			run.FiredAt = ts
+		}
+		run.SessionID = sessionID.String
+		run.RunID = runID.String
+		run.RunState = runState.String
+		run.Error = errMsg.String
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate schedule runs: %w", err)
+	}
+	return runs, nil
+}
+
+const scheduleColumns = `id, name, repo_name, cron, prompt, template, vars, tool, model, autopr, enabled, next_run_at, last_run_at, created_at, updated_at`
+
+func scanSchedule(row rowScanner) (Schedule, error) {
+	var schedule Schedule
+	var prompt, template, vars, tool, model, nextRunAt, lastRunAt sql.NullString
+	var autopr, enabled int
+	var createdAt, updatedAt string
+	if err := row.Scan(
+		&schedule.ID,
+		&schedule.Name,
+		&schedule.RepoName,
+		&schedule.Cron,
+		&prompt,
+		&template,
+		&vars,
+		&tool,
+		&model,
+		&autopr,
+		&enabled,
+		&nextRunAt,
+		&lastRunAt,
+		&createdAt,
+		&updatedAt,
+	); err != nil {
+		return Schedule{}, err
+	}
+	schedule.Prompt = prompt.String
+	schedule.Template = template.String
+	schedule.Tool = tool.String
+	schedule.Model = model.String
+	schedule.AutoPR = autopr != 0
+	schedule.Enabled = enabled != 0
+	if vars.String != "" {
+		if err := json.Unmarshal([]byte(vars.String), &schedule.Vars); err != nil {
+			return Schedule{}, fmt.Errorf("decode schedule vars: %w", err)
+		}
+	}
+	schedule.NextRunAt = parseOptionalTime(nextRunAt)
+	schedule.LastRunAt = parseOptionalTime(lastRunAt)
+	if ts, err := time.Parse(time.RFC3339Nano, createdAt); err == nil {
+		schedule.CreatedAt = ts
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, updatedAt); err == nil {
+		schedule.UpdatedAt = ts
+	}
+	return schedule, nil
+}
+
+func formatNextRun(next time.Time) string {
+	if next.IsZero() {
+		return ""
+	}
+	return next.UTC().Format(time.RFC3339Nano)
+}
+
+func parseOptionalTime(raw sql.NullString) *time.Time {
+	if !raw.Valid || raw.String == "" {
+		return nil
+	}
+	ts, err := time.Parse(time.RFC3339Nano, raw.String)
+	if err != nil {
+		return nil
+	}
+	return &ts
+}
@@ -0,0 +1,89 @@
+package state
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScheduleLifecycle(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	if _, err := store.SaveTemplate("bump-dep", "", "Bump {{dep}}."); err != nil {
+		t.Fatalf("save template failed: %v", err)
+	}
+	schedule, err := store.CreateSchedule(Schedule{
+		ID:       "sched-1",
+		Name:     "update-deps",
+		RepoName: "acme/api",
+		Cron:     "0 9 * * mon",
+		Template: "bump-dep",
+		Vars:     map[string]string{"dep": "cobra"},
+		AutoPR:   true,
+		Enabled:  true,
+	})
+	if err != nil {
+		t.Fatalf("create schedule failed: %v", err)
+	}
+	if schedule.NextRunAt == nil || schedule.NextRunAt.Local().Weekday() != time.Monday {
+		t.Fatalf("expected next run on a Monday, got %v", schedule.NextRunAt)
+	}
+	if schedule.Vars["dep"] != "cobra" || !schedule.AutoPR {
+		t.Fatalf("unexpected schedule: %+v", schedule)
+	}
+
+	if _, err := store.CreateSchedule(Schedule{ID: "sched-2", Name: "update-deps", RepoName: "acme/api", Cron: "@daily", Prompt: "p"}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected duplicate name error, got %v", err)
+	}
+	for _, bad := range []Schedule{
+		{ID: "s", Name: "x", RepoName: "acme/api", Cron: "bogus", Prompt: "p"},
+		{ID: "s", Name: "x", RepoName: "acme/api", Cron: "@daily"},
+		{ID: "s", Name: "x", RepoName: "acme/api", Cron: "@daily", Prompt: "p", Vars: map[string]string{"a": "b"}},
+		{ID: "s", Name: "x", RepoName: "acme/api", Cron: "@daily", Template: "bump-dep"},
+		{ID: "s", Name: "x", RepoName: "acme/api", Cron: "@daily", Template: "missing"},
+	} {
+		if _, err := store.CreateSchedule(bad); err == nil {
+			t.Fatalf("expected error for %+v", bad)
+		}
+	}
+
+	byName, found, err := store.GetSchedule("update-deps")
+	if err != nil || !found || byName.ID != schedule.ID {
+		t.Fatalf("lookup by name failed: found=%v err=%v", found, err)
+	}
+
+	if due, err := store.DueSchedules(time.Now()); err != nil || len(due) != 0 {
+		t.Fatalf("expected nothing due yet: %v %v", due, err)
+	}
+	if due, err := store.DueSchedules(schedule.NextRunAt.Add(time.Minute)); err != nil || len(due) != 1 {
+		t.Fatalf("expected schedule due: %v %v", due, err)
+	}
+
+	paused, err := store.SetScheduleEnabled("update-deps", false)
+	if err != nil {
+		t.Fatalf("pause schedule failed: %v", err)
+	}
+	if paused.Enabled || paused.NextRunAt != nil {
+		t.Fatalf("expected paused schedule without next run: %+v", paused)
+	}
+	if due, _ := store.DueSchedules(schedule.NextRunAt.Add(time.Minute)); len(due) != 0 {
+		t.Fatalf("paused schedule should not be due")
+	}
+
+	if _, err := store.RecordScheduleRun(ScheduleRun{ScheduleID: schedule.ID, Status: ScheduleRunSkipped, Error: "busy"}); err != nil {
+		t.Fatalf("record schedule run failed: %v", err)
+	}
+	if err := store.DeleteSchedule("update-deps"); err != nil {
+		t.Fatalf("delete schedule failed: %v", err)
+	}
+	if history, err := store.ListScheduleRuns(schedule.ID, 10); err != nil || len(history) != 0 {
+		t.Fatalf("expected history removed with schedule: %v %v", history, err)
+	}
+	if err := store.DeleteSchedule("update-deps"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
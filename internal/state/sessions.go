@@ -2,6 +2,7 @@ package state
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -10,33 +11,72 @@ import (
 
 // Session represents one long-lived branch/worktree conversation.
 type Session struct {
-	ID           string    `json:"id"`
-	RepoName     string    `json:"repo_name"`
-	Branch       string    `json:"branch"`
-	WorktreePath string    `json:"worktree_path"`
-	Tool         string    `json:"tool"`
-	Model        string    `json:"model,omitempty"`
-	AutoPR       bool      `json:"autopr"`
-	PRURL        string    `json:"pr_url,omitempty"`
-	Status       string    `json:"status"`
-	Busy         bool      `json:"busy"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-}
-
-// Run is one execution step inside a session.
-type Run struct {
 	ID           string     `json:"id"`
-	SessionID    string     `json:"session_id"`
-	Prompt       string     `json:"prompt"`
+	RepoName     string     `json:"repo_name"`
+	Branch       string     `json:"branch"`
 	WorktreePath string     `json:"worktree_path"`
-	State        string     `json:"state"`
-	CommitSHA    string     `json:"commit_sha,omitempty"`
-	CommitMsg    string     `json:"commit_msg,omitempty"`
-	Error        string     `json:"error,omitempty"`
+	Tool         string     `json:"tool"`
+	Model        string     `json:"model,omitempty"`
+	AutoPR       bool       `json:"autopr"`
+	PRURL        string     `json:"pr_url,omitempty"`
+	Status       string     `json:"status"`
+	Busy         bool       `json:"busy"`
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
-	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	ArchivedAt   *time.Time `json:"archived_at,omitempty"`
+	// PR is the last synced state of the session's pull request; nil until
+	// a PR is opened.
+	PR *PRStatus `json:"pr,omitempty"`
+}
+
+// Session statuses set from the pull request lifecycle. Other statuses
+// mirror the state of the session's latest run.
+const (
+	SessionStatusMerged = "MERGED"
+	SessionStatusClosed = "CLOSED"
+)
+
+// PRStatus is the lifecycle state of a session's pull request.
+type PRStatus struct {
+	// State is OPEN, CLOSED or MERGED.
+	State string `json:"state"`
+	Draft bool   `json:"draft"`
+	// Mergeable is MERGEABLE, CONFLICTING or UNKNOWN.
+	Mergeable string `json:"mergeable,omitempty"`
+	// ReviewDecision is APPROVED, CHANGES_REQUESTED or REVIEW_REQUIRED.
+	ReviewDecision string     `json:"review_decision,omitempty"`
+	Checks         PRChecks   `json:"checks"`
+	SyncedAt       *time.Time `json:"synced_at,omitempty"`
+}
+
+// PRChecks summarizes the checks on the pull request head commit. State is
+// PASSING, FAILING, PENDING, or empty when the PR has no checks.
+type PRChecks struct {
+	State   string `json:"state,omitempty"`
+	Passed  int    `json:"passed"`
+	Failed  int    `json:"failed"`
+	Pending int    `json:"pending"`
+}
+
+// Run is one execution step inside a session.
+type Run struct {
+	ID            string     `json:"id"`
+	SessionID     string     `json:"session_id"`
+	Prompt        string     `json:"prompt"`
+	WorktreePath  string     `json:"worktree_path"`
+	State         string     `json:"state"`
+	CommitSHA     string     `json:"commit_sha,omitempty"`
+	CommitMsg     string     `json:"commit_msg,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+	QueuePosition int        `json:"queue_position,omitempty"`
+	Priority      int        `json:"priority,omitempty"`
+	Usage         *RunUsage  `json:"usage,omitempty"`
+	Sandbox       string     `json:"sandbox,omitempty"`
+	// Template names the prompt template Prompt was rendered from.
+	Template string `json:"template,omitempty"`
 }
 
 // RunEvent captures one timeline event for a run.
@@ -112,55 +152,25 @@ func (s *Store) GetSession(id string) (Session, bool, error) {
 		return Session{}, false, errors.New("session id cannot be empty")
 	}
 
-	var session Session
-	var autoPR int
-	var busy int
-	var createdAtRaw string
-	var updatedAtRaw string
-	err := s.db.QueryRow(
-		`SELECT id, repo_name, branch, worktree_path, tool, model, autopr, pr_url, status, busy, created_at, updated_at
+	session, err := scanSession(s.db.QueryRow(
+		`SELECT `+sessionColumns+`
 		   FROM sessions
 		  WHERE id = ?`,
 		id,
-	).Scan(
-		&session.ID,
-		&session.RepoName,
-		&session.Branch,
-		&session.WorktreePath,
-		&session.Tool,
-		&session.Model,
-		&autoPR,
-		&session.PRURL,
-		&session.Status,
-		&busy,
-		&createdAtRaw,
-		&updatedAtRaw,
-	)
+	))
 	if errors.Is(err, sql.ErrNoRows) {
 		return Session{}, false, nil
 	}
 	if err != nil {
 		return Session{}, false, fmt.Errorf("get session %q: %w", id, err)
 	}
-
-	session.AutoPR = autoPR == 1
-	session.Busy = busy == 1
-	session.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAtRaw)
-	if err != nil {
-		return Session{}, false, fmt.Errorf("parse session created_at %q: %w", id, err)
-	}
-	session.UpdatedAt, err = time.Parse(time.RFC3339Nano, updatedAtRaw)
-	if err != nil {
-		return Session{}, false, fmt.Errorf("parse session updated_at %q: %w", id, err)
-	}
-
 	return session, true, nil
 }
 
 // ListSessions returns all sessions sorted by most recently updated first.
 func (s *Store) ListSessions() ([]Session, error) {
 	rows, err := s.db.Query(
-		`SELECT id, repo_name, branch, worktree_path, tool, model, autopr, pr_url, status, busy, created_at, updated_at
+		`SELECT ` + sessionColumns + `
 		   FROM sessions
 		  ORDER BY updated_at DESC`,
 	)
@@ -171,36 +181,9 @@ func (s *Store) ListSessions() ([]Session, error) {
 
 	sessions := make([]Session, 0)
 	for rows.Next() {
-		var session Session
-		var autoPR int
-		var busy int
-		var createdAtRaw string
-		var updatedAtRaw string
-		if err := rows.Scan(
-			&session.ID,
-			&session.RepoName,
-			&session.Branch,
-			&session.WorktreePath,
-			&session.Tool,
-			&session.Model,
-			&autoPR,
-			&session.PRURL,
-			&session.Status,
-			&busy,
-			&createdAtRaw,
-			&updatedAtRaw,
-		); err != nil {
-			return nil, fmt.Errorf("scan session: %w", err)
-		}
-		session.AutoPR = autoPR == 1
-		session.Busy = busy == 1
-		session.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAtRaw)
+		session, err := scanSession(rows)
 		if err != nil {
-			return nil, fmt.Errorf("parse session created_at %q: %w", session.ID, err)
-		}
-		session.UpdatedAt, err = time.Parse(time.RFC3339Nano, updatedAtRaw)
-		if err != nil {
-			return nil, fmt.Errorf("parse session updated_at %q: %w", session.ID, err)
+			return nil, fmt.Errorf("scan session: %w", err)
 		}
 		sessions = append(sessions, session)
 	}
@@ -286,6 +269,74 @@ func (s *Store) SetSessionPRURL(id, prURL string) error {
 	return nil
 }
 
+// SetSessionPRStatus stores the synced state of a session's pull request.
+// It leaves updated_at alone so background syncs do not reorder sessions or
+// reset their inactivity age.
+func (s *Store) SetSessionPRStatus(id string, pr PRStatus) error {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return errors.New("session id cannot be empty")
+	}
+	if strings.TrimSpace(pr.State) == "" {
+		return errors.New("pr state cannot be empty")
+	}
+	checks, err := json.Marshal(pr.Checks)
+	if err != nil {
+		return fmt.Errorf("encode pr checks: %w", err)
+	}
+	syncedAt := nowRFC3339Nano()
+	if pr.SyncedAt != nil {
+		syncedAt = pr.SyncedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	res, err := s.db.Exec(
+		`UPDATE sessions
+		    SET pr_state = ?, pr_draft = ?, pr_mergeable = ?, pr_review_decision = ?, pr_checks = ?, pr_synced_at = ?
+		  WHERE id = ?`,
+		strings.ToUpper(strings.TrimSpace(pr.State)),
+		boolToInt(pr.Draft),
+		nullIfEmpty(pr.Mergeable),
+		nullIfEmpty(pr.ReviewDecision),
+		string(checks),
+		syncedAt,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("set session pr status %q: %w", id, err)
+	}
+	return ensureRowsAffected(res, "session "+id)
+}
+
+// SetSessionArchived archives or unarchives a session. Archived sessions
+// keep their runs and events but are hidden from default listings.
+func (s *Store) SetSessionArchived(id string, archived bool) error {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return errors.New("session id cannot be empty")
+	}
+
+	var archivedAt any
+	now := nowRFC3339Nano()
+	if archived {
+		archivedAt = now
+	}
+	res, err := s.db.Exec(
+		`UPDATE sessions
+		    SET archived_at = ?, updated_at = ?
+		  WHERE id = ?`,
+		archivedAt,
+		now,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("set session archived %q: %w", id, err)
+	}
+	if err := ensureRowsAffected(res, "session "+id); err != nil {
+		return err
+	}
+	return nil
+}
+
 // SetSessionWorktreePath updates the session's latest run worktree path.
 func (s *Store) SetSessionWorktreePath(id, worktreePath string) error {
 	id = strings.TrimSpace(id)
@@ -314,6 +365,43 @@ func (s *Store) SetSessionWorktreePath(id, worktreePath string) error {
 	return nil
 }
 
+// DeleteSession removes a session together with its runs, run events,
+// ensemble memberships and PR feedback tracking.
+func (s *Store) DeleteSession(id string) error {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return errors.New("session id cannot be empty")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin delete session %q: %w", id, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, stmt := range []string{
+		`DELETE FROM run_events WHERE run_id IN (SELECT id FROM runs WHERE session_id = ?)`,
+		`DELETE FROM runs WHERE session_id = ?`,
+		`DELETE FROM ensemble_members WHERE session_id = ?`,
+		`DELETE FROM pr_feedback WHERE session_id = ?`,
+	} {
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return fmt.Errorf("delete session %q: %w", id, err)
+		}
+	}
+	res, err := tx.Exec(`DELETE FROM sessions WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete session %q: %w", id, err)
+	}
+	if err := ensureRowsAffected(res, "session "+id); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit delete session %q: %w", id, err)
+	}
+	return nil
+}
+
 // CreateRun inserts a run under one session.
 func (s *Store) CreateRun(run Run) error {
 	run.ID = strings.TrimSpace(run.ID)
@@ -324,6 +412,7 @@ func (s *Store) CreateRun(run Run) error {
 	run.CommitSHA = strings.TrimSpace(run.CommitSHA)
 	run.CommitMsg = strings.TrimSpace(run.CommitMsg)
 	run.Error = strings.TrimSpace(run.Error)
+	run.Template = strings.TrimSpace(run.Template)
 
 	switch {
 	case run.ID == "":
@@ -353,8 +442,8 @@ func (s *Store) CreateRun(run Run) error {
 	}
 
 	_, err := s.db.Exec(
-		`INSERT INTO runs(id, session_id, prompt, worktree_path, state, commit_sha, commit_msg, error, created_at, updated_at, completed_at)
-		 VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		`INSERT INTO runs(id, session_id, prompt, worktree_path, state, commit_sha, commit_msg, error, created_at, updated_at, completed_at, priority, template)
+		 VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		run.ID,
 		run.SessionID,
 		run.Prompt,
@@ -366,6 +455,8 @@ func (s *Store) CreateRun(run Run) error {
 		createdAt.Format(time.RFC3339Nano),
 		updatedAt.Format(time.RFC3339Nano),
 		nullIfEmpty(completedAtRaw),
+		run.Priority,
+		nullIfEmpty(run.Template),
 	)
 	if err != nil {
 		return fmt.Errorf("create run %q: %w", run.ID, err)
@@ -380,51 +471,18 @@ func (s *Store) GetRun(id string) (Run, bool, error) {
 		return Run{}, false, errors.New("run id cannot be empty")
 	}
 
-	var run Run
-	var createdAtRaw string
-	var updatedAtRaw string
-	var completedAtRaw sql.NullString
-	err := s.db.QueryRow(
-		`SELECT id, session_id, prompt, worktree_path, state, commit_sha, commit_msg, error, created_at, updated_at, completed_at
+	run, err := scanRun(s.db.QueryRow(
+		`SELECT `+runColumns+`
 		   FROM runs
 		  WHERE id = ?`,
 		id,
-	).Scan(
-		&run.ID,
-		&run.SessionID,
-		&run.Prompt,
-		&run.WorktreePath,
-		&run.State,
-		&run.CommitSHA,
-		&run.CommitMsg,
-		&run.Error,
-		&createdAtRaw,
-		&updatedAtRaw,
-		&completedAtRaw,
-	)
+	))
 	if errors.Is(err, sql.ErrNoRows) {
 		return Run{}, false, nil
 	}
 	if err != nil {
 		return Run{}, false, fmt.Errorf("get run %q: %w", id, err)
 	}
-
-	run.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAtRaw)
-	if err != nil {
-		return Run{}, false, fmt.Errorf("parse run created_at %q: %w", id, err)
-	}
-	run.UpdatedAt, err = time.Parse(time.RFC3339Nano, updatedAtRaw)
-	if err != nil {
-		return Run{}, false, fmt.Errorf("parse run updated_at %q: %w", id, err)
-	}
-	if completedAtRaw.Valid {
-		parsed, err := time.Parse(time.RFC3339Nano, completedAtRaw.String)
-		if err != nil {
-			return Run{}, false, fmt.Errorf("parse run completed_at %q: %w", id, err)
-		}
-		run.CompletedAt = &parsed
-	}
-
 	return run, true, nil
 }
 
@@ -436,7 +494,7 @@ func (s *Store) ListRuns(sessionID string) ([]Run, error) {
 	}
 
 	rows, err := s.db.Query(
-		`SELECT id, session_id, prompt, worktree_path, state, commit_sha, commit_msg, error, created_at, updated_at, completed_at
+		`SELECT `+runColumns+`
 		   FROM runs
 		  WHERE session_id = ?
 		  ORDER BY created_at DESC`,
@@ -445,105 +503,54 @@ func (s *Store) ListRuns(sessionID string) ([]Run, error) {
 	if err != nil {
 		return nil, fmt.Errorf("list runs for session %q: %w", sessionID, err)
 	}
-	defer rows.Close()
+	return collectRuns(rows)
+}
 
-	runs := make([]Run, 0)
-	for rows.Next() {
-		var run Run
-		var createdAtRaw string
-		var updatedAtRaw string
-		var completedAtRaw sql.NullString
-		if err := rows.Scan(
-			&run.ID,
-			&run.SessionID,
-			&run.Prompt,
-			&run.WorktreePath,
-			&run.State,
-			&run.CommitSHA,
-			&run.CommitMsg,
-			&run.Error,
-			&createdAtRaw,
-			&updatedAtRaw,
-			&completedAtRaw,
-		); err != nil {
-			return nil, fmt.Errorf("scan run: %w", err)
-		}
-		run.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAtRaw)
-		if err != nil {
-			return nil, fmt.Errorf("parse run created_at %q: %w", run.ID, err)
-		}
-		run.UpdatedAt, err = time.Parse(time.RFC3339Nano, updatedAtRaw)
-		if err != nil {
-			return nil, fmt.Errorf("parse run updated_at %q: %w", run.ID, err)
-		}
-		if completedAtRaw.Valid {
-			parsed, err := time.Parse(time.RFC3339Nano, completedAtRaw.String)
-			if err != nil {
-				return nil, fmt.Errorf("parse run completed_at %q: %w", run.ID, err)
-			}
-			run.CompletedAt = &parsed
-		}
-		runs = append(runs, run)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate runs: %w", err)
+// ListInFlightRuns returns runs across all sessions that have started but not
+// reached a terminal state, oldest first. Queued runs are not included.
+func (s *Store) ListInFlightRuns() ([]Run, error) {
+	rows, err := s.db.Query(
+		`SELECT `+runColumns+`
+		   FROM runs
+		  WHERE state NOT IN (?, ?, ?, ?, ?, ?, ?)
+		  ORDER BY created_at ASC`,
+		RunStateQueued,
+		"COMPLETED",
+		"FAILED",
+		"CANCELLED",
+		"INTERRUPTED",
+		"TIMED_OUT",
+		"BUDGET_EXCEEDED",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list in-flight runs: %w", err)
 	}
-	return runs, nil
+	return collectRuns(rows)
 }
 
 // GetLatestRun returns the most recently created run for a session.
+// Runs still waiting in the session queue are not considered.
 func (s *Store) GetLatestRun(sessionID string) (Run, bool, error) {
 	sessionID = strings.TrimSpace(sessionID)
 	if sessionID == "" {
 		return Run{}, false, errors.New("session id cannot be empty")
 	}
 
-	var run Run
-	var createdAtRaw string
-	var updatedAtRaw string
-	var completedAtRaw sql.NullString
-	err := s.db.QueryRow(
-		`SELECT id, session_id, prompt, worktree_path, state, commit_sha, commit_msg, error, created_at, updated_at, completed_at
+	run, err := scanRun(s.db.QueryRow(
+		`SELECT `+runColumns+`
 		   FROM runs
-		  WHERE session_id = ?
+		  WHERE session_id = ? AND state != ?
 		  ORDER BY created_at DESC
 		  LIMIT 1`,
 		sessionID,
-	).Scan(
-		&run.ID,
-		&run.SessionID,
-		&run.Prompt,
-		&run.WorktreePath,
-		&run.State,
-		&run.CommitSHA,
-		&run.CommitMsg,
-		&run.Error,
-		&createdAtRaw,
-		&updatedAtRaw,
-		&completedAtRaw,
-	)
+		RunStateQueued,
+	))
 	if errors.Is(err, sql.ErrNoRows) {
 		return Run{}, false, nil
 	}
 	if err != nil {
 		return Run{}, false, fmt.Errorf("get latest run for session %q: %w", sessionID, err)
 	}
-
-	run.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAtRaw)
-	if err != nil {
-		return Run{}, false, fmt.Errorf("parse run created_at %q: %w", run.ID, err)
-	}
-	run.UpdatedAt, err = time.Parse(time.RFC3339Nano, updatedAtRaw)
-	if err != nil {
-		return Run{}, false, fmt.Errorf("parse run updated_at %q: %w", run.ID, err)
-	}
-	if completedAtRaw.Valid {
-		parsed, err := time.Parse(time.RFC3339Nano, completedAtRaw.String)
-		if err != nil {
-			return Run{}, false, fmt.Errorf("parse run completed_at %q: %w", run.ID, err)
-		}
-		run.CompletedAt = &parsed
-	}
 	return run, true, nil
 }
 
@@ -575,6 +582,30 @@ func (s *Store) SetRunState(id, state string) error {
 	return nil
 }
 
+// SetRunSandbox records the sandbox a run executes in.
+func (s *Store) SetRunSandbox(id, sandbox string) error {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return errors.New("run id cannot be empty")
+	}
+
+	res, err := s.db.Exec(
+		`UPDATE runs
+		    SET sandbox = ?, updated_at = ?
+		  WHERE id = ?`,
+		nullIfEmpty(strings.TrimSpace(sandbox)),
+		nowRFC3339Nano(),
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("set run sandbox %q: %w", id, err)
+	}
+	if err := ensureRowsAffected(res, "run "+id); err != nil {
+		return err
+	}
+	return nil
+}
+
 // CompleteRun stores terminal run data and sets completed_at.
 func (s *Store) CompleteRun(id, state, commitSHA, commitMsg, runErr string) error {
 	id = strings.TrimSpace(id)
@@ -594,7 +625,7 @@ func (s *Store) CompleteRun(id, state, commitSHA, commitMsg, runErr string) erro
 		state,
 		strings.TrimSpace(commitSHA),
 		strings.TrimSpace(commitMsg),
-		strings.TrimSpace(runErr),
+		s.RedactRunOutput(id, strings.TrimSpace(runErr)),
 		now,
 		now,
 		id,
@@ -612,8 +643,8 @@ func (s *Store) CompleteRun(id, state, commitSHA, commitMsg, runErr string) erro
 func (s *Store) AppendRunEvent(event RunEvent) error {
 	event.RunID = strings.TrimSpace(event.RunID)
 	event.Type = strings.TrimSpace(event.Type)
-	event.Message = strings.TrimSpace(event.Message)
-	event.Data = strings.TrimSpace(event.Data)
+	event.Message = strings.TrimSpace(s.RedactRunOutput(event.RunID, event.Message))
+	event.Data = strings.TrimSpace(s.RedactRunOutput(event.RunID, event.Data))
 	if event.RunID == "" {
 		return errors.New("run event run_id cannot be empty")
 	}
@@ -698,6 +729,220 @@ func (s *Store) ListRunEvents(runID string, limit int) ([]RunEvent, error) {
 	return events, nil
 }
 
+// GetLatestRunEvent returns the most recent event of the given type for a run.
+func (s *Store) GetLatestRunEvent(runID, eventType string) (RunEvent, bool, error) {
+	runID = strings.TrimSpace(runID)
+	eventType = strings.TrimSpace(eventType)
+	switch {
+	case runID == "":
+		return RunEvent{}, false, errors.New("run id cannot be empty")
+	case eventType == "":
+		return RunEvent{}, false, errors.New("event type cannot be empty")
+	}
+
+	var event RunEvent
+	var tsRaw string
+	var message sql.NullString
+	var data sql.NullString
+	err := s.db.QueryRow(
+		`SELECT id, run_id, ts, type, message, data
+		   FROM run_events
+		  WHERE run_id = ? AND type = ?
+		  ORDER BY id DESC
+		  LIMIT 1`,
+		runID,
+		eventType,
+	).Scan(&event.ID, &event.RunID, &tsRaw, &event.Type, &message, &data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return RunEvent{}, false, nil
+	}
+	if err != nil {
+		return RunEvent{}, false, fmt.Errorf("get latest %s event for %q: %w", eventType, runID, err)
+	}
+	event.TS, err = time.Parse(time.RFC3339Nano, tsRaw)
+	if err != nil {
+		return RunEvent{}, false, fmt.Errorf("parse run event ts for %q: %w", runID, err)
+	}
+	event.Message = message.String
+	event.Data = data.String
+	return event, true, nil
+}
+
+// sessionColumns is the column list shared by every query that loads a Session.
+const sessionColumns = `id, repo_name, branch, worktree_path, tool, model, autopr, pr_url, status, busy, created_at, updated_at, archived_at,
+	pr_state, pr_draft, pr_mergeable, pr_review_decision, pr_checks, pr_synced_at`
+
+func scanSession(row rowScanner) (Session, error) {
+	var session Session
+	var autoPR int
+	var busy int
+	var createdAtRaw string
+	var updatedAtRaw string
+	var archivedAtRaw sql.NullString
+	var prState, prMergeable, prReviewDecision, prChecks, prSyncedAt sql.NullString
+	var prDraft int
+	if err := row.Scan(
+		&session.ID,
+		&session.RepoName,
+		&session.Branch,
+		&session.WorktreePath,
+		&session.Tool,
+		&session.Model,
+		&autoPR,
+		&session.PRURL,
+		&session.Status,
+		&busy,
+		&createdAtRaw,
+		&updatedAtRaw,
+		&archivedAtRaw,
+		&prState,
+		&prDraft,
+		&prMergeable,
+		&prReviewDecision,
+		&prChecks,
+		&prSyncedAt,
+	); err != nil {
+		return Session{}, err
+	}
+
+	session.AutoPR = autoPR == 1
+	session.Busy = busy == 1
+	var err error
+	session.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAtRaw)
+	if err != nil {
+		return Session{}, fmt.Errorf("parse session created_at %q: %w", session.ID, err)
+	}
+	session.UpdatedAt, err = time.Parse(time.RFC3339Nano, updatedAtRaw)
+	if err != nil {
+		return Session{}, fmt.Errorf("parse session updated_at %q: %w", session.ID, err)
+	}
+	if archivedAtRaw.Valid {
+		parsed, err := time.Parse(time.RFC3339Nano, archivedAtRaw.String)
+		if err != nil {
+			return Session{}, fmt.Errorf("parse session archived_at %q: %w", session.ID, err)
+		}
+		session.ArchivedAt = &parsed
+	}
+	if prState.Valid && prState.String != "" {
+		pr := &PRStatus{
+			State:          prState.String,
+			Draft:          prDraft == 1,
+			Mergeable:      prMergeable.String,
+			ReviewDecision: prReviewDecision.String,
+		}
+		if prChecks.Valid && prChecks.String != "" {
+			if err := json.Unmarshal([]byte(prChecks.String), &pr.Checks); err != nil {
+				return Session{}, fmt.Errorf("parse session pr_checks %q: %w", session.ID, err)
+			}
+		}
+		if prSyncedAt.Valid {
+			if ts, err := time.Parse(time.RFC3339Nano, prSyncedAt.String); err == nil {
+				pr.SyncedAt = &ts
+			}
+		}
+		session.PR = pr
+	}
+	return session, nil
+}
+
+// runColumns is the column list shared by every query that loads a Run.
+const runColumns = `id, session_id, prompt, worktree_path, state, commit_sha, commit_msg, error, created_at, updated_at, completed_at, queue_position, priority,
+	model, input_tokens, output_tokens, cache_read_tokens, cache_creation_tokens, cost_usd, duration_ms, num_turns, sandbox, template`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRun(row rowScanner) (Run, error) {
+	var run Run
+	var createdAtRaw string
+	var updatedAtRaw string
+	var completedAtRaw sql.NullString
+	var queuePosition sql.NullInt64
+	var model sql.NullString
+	var inputTokens, outputTokens, cacheReadTokens, cacheCreationTokens sql.NullInt64
+	var costUSD sql.NullFloat64
+	var durationMS, numTurns sql.NullInt64
+	var sandbox, template sql.NullString
+	if err := row.Scan(
+		&run.ID,
+		&run.SessionID,
+		&run.Prompt,
+		&run.WorktreePath,
+		&run.State,
+		&run.CommitSHA,
+		&run.CommitMsg,
+		&run.Error,
+		&createdAtRaw,
+		&updatedAtRaw,
+		&completedAtRaw,
+		&queuePosition,
+		&run.Priority,
+		&model,
+		&inputTokens,
+		&outputTokens,
+		&cacheReadTokens,
+		&cacheCreationTokens,
+		&costUSD,
+		&durationMS,
+		&numTurns,
+		&sandbox,
+		&template,
+	); err != nil {
+		return Run{}, err
+	}
+	run.QueuePosition = int(queuePosition.Int64)
+	run.Sandbox = sandbox.String
+	run.Template = template.String
+	if inputTokens.Valid {
+		run.Usage = &RunUsage{
+			Model:               model.String,
+			InputTokens:         inputTokens.Int64,
+			OutputTokens:        outputTokens.Int64,
+			CacheReadTokens:     cacheReadTokens.Int64,
+			CacheCreationTokens: cacheCreationTokens.Int64,
+			CostUSD:             costUSD.Float64,
+			DurationMS:          durationMS.Int64,
+			NumTurns:            int(numTurns.Int64),
+		}
+	}
+
+	var err error
+	run.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAtRaw)
+	if err != nil {
+		return Run{}, fmt.Errorf("parse run created_at %q: %w", run.ID, err)
+	}
+	run.UpdatedAt, err = time.Parse(time.RFC3339Nano, updatedAtRaw)
+	if err != nil {
+		return Run{}, fmt.Errorf("parse run updated_at %q: %w", run.ID, err)
+	}
+	if completedAtRaw.Valid {
+		parsed, err := time.Parse(time.RFC3339Nano, completedAtRaw.String)
+		if err != nil {
+			return Run{}, fmt.Errorf("parse run completed_at %q: %w", run.ID, err)
+		}
+		run.CompletedAt = &parsed
+	}
+	return run, nil
+}
+
+func collectRuns(rows *sql.Rows) ([]Run, error) {
+	defer rows.Close()
+
+	runs := make([]Run, 0)
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate runs: %w", err)
+	}
+	return runs, nil
+}
+
 func boolToInt(v bool) int {
 	if v {
 		return 1
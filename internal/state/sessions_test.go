@@ -92,6 +92,7 @@ func TestSessionAndRunLifecycle(t *testing.T) {
 		Prompt:       "Add tests",
 		WorktreePath: "/tmp/acme-api/branches/fog-add-login-run-2",
 		State:        "CREATED",
+		Priority:     5,
 		CreatedAt:    r2Created,
 		UpdatedAt:    r2Created,
 	}); err != nil {
@@ -111,6 +112,9 @@ func TestSessionAndRunLifecycle(t *testing.T) {
 	if got, want := runs[0].WorktreePath, "/tmp/acme-api/branches/fog-add-login-run-2"; got != want {
 		t.Fatalf("unexpected run worktree path: got %q want %q", got, want)
 	}
+	if runs[0].Priority != 5 || runs[1].Priority != 0 {
+		t.Fatalf("unexpected run priorities: %+v", runs)
+	}
 	latest, found, err := store.GetLatestRun("sess-1")
 	if err != nil {
 		t.Fatalf("get latest run failed: %v", err)
@@ -220,3 +224,93 @@ func TestSessionRunUpdateMissingRows(t *testing.T) {
 		t.Fatal("expected missing run error")
 	}
 }
+
+func TestDeleteSessionRemovesRunsEventsAndMemberships(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedQueueSession(t, store)
+
+	if err := store.CreateRun(Run{ID: "run-1", SessionID: "sess-1", Prompt: "p", WorktreePath: "/tmp/wt", State: "COMPLETED"}); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+	if err := store.AppendRunEvent(RunEvent{RunID: "run-1", Type: "ai_output", Message: "done"}); err != nil {
+		t.Fatalf("append run event failed: %v", err)
+	}
+	if err := store.CreateEnsemble(Ensemble{ID: "ens-1", RepoName: "acme/api", Prompt: "p", Status: EnsembleStatusRunning}); err != nil {
+		t.Fatalf("create ensemble failed: %v", err)
+	}
+	if err := store.AddEnsembleMember("ens-1", EnsembleMember{SessionID: "sess-1", Tool: "claude"}); err != nil {
+		t.Fatalf("add ensemble member failed: %v", err)
+	}
+
+	if err := store.DeleteSession("sess-1"); err != nil {
+		t.Fatalf("delete session failed: %v", err)
+	}
+	if _, found, err := store.GetSession("sess-1"); err != nil || found {
+		t.Fatalf("expected session to be gone, found=%v err=%v", found, err)
+	}
+	if _, found, err := store.GetRun("run-1"); err != nil || found {
+		t.Fatalf("expected run to be gone, found=%v err=%v", found, err)
+	}
+	events, err := store.ListRunEvents("run-1", 10)
+	if err != nil {
+		t.Fatalf("list run events failed: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected run events to be gone, got %+v", events)
+	}
+	ensemble, _, err := store.GetEnsemble("ens-1")
+	if err != nil {
+		t.Fatalf("get ensemble failed: %v", err)
+	}
+	if len(ensemble.Members) != 0 {
+		t.Fatalf("expected ensemble membership to be gone, got %+v", ensemble.Members)
+	}
+
+	if err := store.DeleteSession("sess-1"); err == nil {
+		t.Fatal("expected deleting unknown session to fail")
+	}
+}
+
+func TestSetSessionPRStatusRoundTrip(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedQueueSession(t, store)
+
+	before, _, err := store.GetSession("sess-1")
+	if err != nil {
+		t.Fatalf("get session failed: %v", err)
+	}
+	if before.PR != nil {
+		t.Fatalf("expected no PR status before sync, got %+v", before.PR)
+	}
+
+	if err := store.SetSessionPRStatus("sess-1", PRStatus{
+		State:          "open",
+		Draft:          true,
+		Mergeable:      "CONFLICTING",
+		ReviewDecision: "CHANGES_REQUESTED",
+		Checks:         PRChecks{State: "FAILING", Passed: 3, Failed: 1},
+	}); err != nil {
+		t.Fatalf("set pr status failed: %v", err)
+	}
+
+	session, _, err := store.GetSession("sess-1")
+	if err != nil {
+		t.Fatalf("get session failed: %v", err)
+	}
+	pr := session.PR
+	if pr == nil || pr.State != "OPEN" || !pr.Draft || pr.Mergeable != "CONFLICTING" || pr.ReviewDecision != "CHANGES_REQUESTED" || pr.SyncedAt == nil {
+		t.Fatalf("unexpected pr status: %+v", pr)
+	}
+	if pr.Checks != (PRChecks{State: "FAILING", Passed: 3, Failed: 1}) {
+		t.Fatalf("unexpected checks: %+v", pr.Checks)
+	}
+	if !session.UpdatedAt.Equal(before.UpdatedAt) {
+		t.Fatalf("pr sync should not touch updated_at: %v -> %v", before.UpdatedAt, session.UpdatedAt)
+	}
+
+	if err := store.SetSessionPRStatus("missing", PRStatus{State: "OPEN"}); err == nil {
+		t.Fatal("expected error for unknown session")
+	}
+}
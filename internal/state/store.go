@@ -7,8 +7,10 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/darkLord19/foglet/internal/redact"
 	_ "modernc.org/sqlite"
 )
 
@@ -23,6 +25,12 @@ const (
 type Store struct {
 	db  *sql.DB
 	key []byte
+
+	// Redaction state for persisted run output; see redact.go.
+	redactMu       sync.RWMutex
+	redactor       *redact.Redactor
+	redactorLoaded time.Time
+	runRedactors   map[string]*redact.Redactor
 }
 
 // Repo holds Fog's managed repository metadata.
@@ -52,7 +60,10 @@ func NewStore(fogHome string) (*Store, error) {
 	}
 
 	dbPath := filepath.Join(fogHome, defaultDBName)
-	db, err := sql.Open("sqlite", dbPath)
+	// Pragmas go in the DSN so every pooled connection gets them; a plain
+	// PRAGMA statement only configures whichever connection runs it.
+	dsn := dbPath + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("open sqlite: %w", err)
 	}
@@ -88,6 +99,21 @@ func (s *Store) init() error {
 			ciphertext BLOB NOT NULL,
 			updated_at TEXT NOT NULL
 		);`,
+		`CREATE TABLE IF NOT EXISTS repo_env (
+			repo_name TEXT NOT NULL,
+			name TEXT NOT NULL,
+			ciphertext BLOB NOT NULL,
+			secret INTEGER NOT NULL DEFAULT 0,
+			updated_at TEXT NOT NULL,
+			PRIMARY KEY(repo_name, name)
+		);`,
+		`CREATE TABLE IF NOT EXISTS prompt_templates (
+			name TEXT PRIMARY KEY,
+			description TEXT,
+			body TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL
+		);`,
 		`CREATE TABLE IF NOT EXISTS repos (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			name TEXT NOT NULL UNIQUE,
@@ -133,6 +159,13 @@ func (s *Store) init() error {
 			busy INTEGER NOT NULL DEFAULT 0,
 			created_at TEXT NOT NULL,
 			updated_at TEXT NOT NULL,
+			archived_at TEXT,
+			pr_state TEXT,
+			pr_draft INTEGER NOT NULL DEFAULT 0,
+			pr_mergeable TEXT,
+			pr_review_decision TEXT,
+			pr_checks TEXT,
+			pr_synced_at TEXT,
 			FOREIGN KEY(repo_name) REFERENCES repos(name)
 		);`,
 		`CREATE TABLE IF NOT EXISTS runs (
@@ -147,6 +180,18 @@ func (s *Store) init() error {
 			created_at TEXT NOT NULL,
 			updated_at TEXT NOT NULL,
 			completed_at TEXT,
+			queue_position INTEGER,
+			priority INTEGER NOT NULL DEFAULT 0,
+			model TEXT,
+			input_tokens INTEGER,
+			output_tokens INTEGER,
+			cache_read_tokens INTEGER,
+			cache_creation_tokens INTEGER,
+			cost_usd REAL,
+			duration_ms INTEGER,
+			num_turns INTEGER,
+			sandbox TEXT,
+			template TEXT,
 			FOREIGN KEY(session_id) REFERENCES sessions(id) ON DELETE CASCADE
 		);`,
 		`CREATE TABLE IF NOT EXISTS run_events (
@@ -167,11 +212,81 @@ func (s *Store) init() error {
 			data TEXT,
 			FOREIGN KEY(task_id) REFERENCES tasks(id)
 		);`,
+		`CREATE TABLE IF NOT EXISTS ensembles (
+			id TEXT PRIMARY KEY,
+			repo_name TEXT NOT NULL,
+			source_session_id TEXT,
+			prompt TEXT NOT NULL,
+			status TEXT NOT NULL,
+			winner_session_id TEXT,
+			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS ensemble_members (
+			ensemble_id TEXT NOT NULL,
+			session_id TEXT NOT NULL,
+			tool TEXT NOT NULL,
+			model TEXT,
+			position INTEGER NOT NULL,
+			PRIMARY KEY(ensemble_id, session_id),
+			FOREIGN KEY(ensemble_id) REFERENCES ensembles(id) ON DELETE CASCADE,
+			FOREIGN KEY(session_id) REFERENCES sessions(id) ON DELETE CASCADE
+		);`,
+		`CREATE TABLE IF NOT EXISTS schedules (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL UNIQUE,
+			repo_name TEXT NOT NULL,
+			cron TEXT NOT NULL,
+			prompt TEXT,
+			template TEXT,
+			vars TEXT,
+			tool TEXT,
+			model TEXT,
+			autopr INTEGER NOT NULL DEFAULT 0,
+			enabled INTEGER NOT NULL DEFAULT 1,
+			next_run_at TEXT,
+			last_run_at TEXT,
+			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS schedule_runs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			schedule_id TEXT NOT NULL,
+			fired_at TEXT NOT NULL,
+			status TEXT NOT NULL,
+			session_id TEXT,
+			run_id TEXT,
+			error TEXT,
+			FOREIGN KEY(schedule_id) REFERENCES schedules(id) ON DELETE CASCADE
+		);`,
+		`CREATE TABLE IF NOT EXISTS pr_feedback (
+			session_id TEXT NOT NULL,
+			item_key TEXT NOT NULL,
+			run_id TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 1,
+			created_at TEXT NOT NULL,
+			PRIMARY KEY(session_id, item_key),
+			FOREIGN KEY(session_id) REFERENCES sessions(id) ON DELETE CASCADE
+		);`,
+		`CREATE TABLE IF NOT EXISTS cloud_jobs (
+			job_id TEXT PRIMARY KEY,
+			session_id TEXT,
+			run_id TEXT,
+			message TEXT,
+			created_at TEXT NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS forge_hosts (
+			host TEXT PRIMARY KEY,
+			kind TEXT NOT NULL,
+			base_url TEXT,
+			updated_at TEXT NOT NULL
+		);`,
 		`CREATE INDEX IF NOT EXISTS idx_tasks_repo_created ON tasks(repo_id, created_at DESC);`,
 		`CREATE INDEX IF NOT EXISTS idx_task_events_task_ts ON task_events(task_id, ts DESC);`,
 		`CREATE INDEX IF NOT EXISTS idx_sessions_repo_updated ON sessions(repo_name, updated_at DESC);`,
 		`CREATE INDEX IF NOT EXISTS idx_runs_session_created ON runs(session_id, created_at DESC);`,
 		`CREATE INDEX IF NOT EXISTS idx_run_events_run_ts ON run_events(run_id, ts DESC);`,
+		`CREATE INDEX IF NOT EXISTS idx_schedule_runs_schedule ON schedule_runs(schedule_id, id DESC);`,
 	}
 
 	for _, stmt := range stmts {
@@ -179,6 +294,9 @@ func (s *Store) init() error {
 			return fmt.Errorf("init schema: %w", err)
 		}
 	}
+	if err := s.ensureSessionsSchema(); err != nil {
+		return err
+	}
 	if err := s.ensureRunsSchema(); err != nil {
 		return err
 	}
@@ -206,6 +324,9 @@ func (s *Store) SetSetting(key, value string) error {
 	if err != nil {
 		return fmt.Errorf("set setting %q: %w", key, err)
 	}
+	if key == SettingRedactPatterns {
+		s.invalidateRedactor()
+	}
 	return nil
 }
 
@@ -221,6 +342,17 @@ func (s *Store) GetSetting(key string) (value string, found bool, err error) {
 	return "", false, fmt.Errorf("get setting %q: %w", key, err)
 }
 
+// DeleteSetting removes a setting. Missing keys are not an error.
+func (s *Store) DeleteSetting(key string) error {
+	if _, err := s.db.Exec(`DELETE FROM settings WHERE key = ?`, key); err != nil {
+		return fmt.Errorf("delete setting %q: %w", key, err)
+	}
+	if key == SettingRedactPatterns {
+		s.invalidateRedactor()
+	}
+	return nil
+}
+
 // SaveSecret encrypts and persists a secret value by key.
 func (s *Store) SaveSecret(key, value string) error {
 	key = strings.TrimSpace(key)
@@ -245,6 +377,7 @@ func (s *Store) SaveSecret(key, value string) error {
 	if err != nil {
 		return fmt.Errorf("save secret %q: %w", key, err)
 	}
+	s.invalidateRedactor()
 	return nil
 }
 
@@ -293,6 +426,7 @@ func (s *Store) DeleteSecret(key string) error {
 	if _, err := s.db.Exec(`DELETE FROM secrets WHERE key = ?`, key); err != nil {
 		return fmt.Errorf("delete secret %q: %w", key, err)
 	}
+	s.invalidateRedactor()
 	return nil
 }
 
@@ -454,14 +588,68 @@ func nowRFC3339Nano() string {
 	return time.Now().UTC().Format(time.RFC3339Nano)
 }
 
+func (s *Store) ensureSessionsSchema() error {
+	columns := []struct {
+		name string
+		ddl  string
+	}{
+		{name: "archived_at", ddl: `ALTER TABLE sessions ADD COLUMN archived_at TEXT`},
+		{name: "pr_state", ddl: `ALTER TABLE sessions ADD COLUMN pr_state TEXT`},
+		{name: "pr_draft", ddl: `ALTER TABLE sessions ADD COLUMN pr_draft INTEGER NOT NULL DEFAULT 0`},
+		{name: "pr_mergeable", ddl: `ALTER TABLE sessions ADD COLUMN pr_mergeable TEXT`},
+		{name: "pr_review_decision", ddl: `ALTER TABLE sessions ADD COLUMN pr_review_decision TEXT`},
+		{name: "pr_checks", ddl: `ALTER TABLE sessions ADD COLUMN pr_checks TEXT`},
+		{name: "pr_synced_at", ddl: `ALTER TABLE sessions ADD COLUMN pr_synced_at TEXT`},
+	}
+	for _, column := range columns {
+		exists, err := s.tableColumnExists("sessions", column.name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := s.db.Exec(column.ddl); err != nil {
+			return fmt.Errorf("add sessions.%s column: %w", column.name, err)
+		}
+	}
+	return nil
+}
+
 func (s *Store) ensureRunsSchema() error {
 	const table = "runs"
-	if hasWorktree, err := s.tableColumnExists(table, "worktree_path"); err != nil {
-		return err
-	} else if !hasWorktree {
-		if _, err := s.db.Exec(`ALTER TABLE runs ADD COLUMN worktree_path TEXT`); err != nil {
-			return fmt.Errorf("add runs.worktree_path column: %w", err)
+	columns := []struct {
+		name string
+		ddl  string
+	}{
+		{name: "worktree_path", ddl: `ALTER TABLE runs ADD COLUMN worktree_path TEXT`},
+		{name: "queue_position", ddl: `ALTER TABLE runs ADD COLUMN queue_position INTEGER`},
+		{name: "priority", ddl: `ALTER TABLE runs ADD COLUMN priority INTEGER NOT NULL DEFAULT 0`},
+		{name: "model", ddl: `ALTER TABLE runs ADD COLUMN model TEXT`},
+		{name: "input_tokens", ddl: `ALTER TABLE runs ADD COLUMN input_tokens INTEGER`},
+		{name: "output_tokens", ddl: `ALTER TABLE runs ADD COLUMN output_tokens INTEGER`},
+		{name: "cache_read_tokens", ddl: `ALTER TABLE runs ADD COLUMN cache_read_tokens INTEGER`},
+		{name: "cache_creation_tokens", ddl: `ALTER TABLE runs ADD COLUMN cache_creation_tokens INTEGER`},
+		{name: "cost_usd", ddl: `ALTER TABLE runs ADD COLUMN cost_usd REAL`},
+		{name: "duration_ms", ddl: `ALTER TABLE runs ADD COLUMN duration_ms INTEGER`},
+		{name: "num_turns", ddl: `ALTER TABLE runs ADD COLUMN num_turns INTEGER`},
+		{name: "sandbox", ddl: `ALTER TABLE runs ADD COLUMN sandbox TEXT`},
+		{name: "template", ddl: `ALTER TABLE runs ADD COLUMN template TEXT`},
+	}
+	for _, column := range columns {
+		exists, err := s.tableColumnExists(table, column.name)
+		if err != nil {
+			return err
 		}
+		if exists {
+			continue
+		}
+		if _, err := s.db.Exec(column.ddl); err != nil {
+			return fmt.Errorf("add runs.%s column: %w", column.name, err)
+		}
+	}
+	if _, err := s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_runs_session_queue ON runs(session_id, queue_position)`); err != nil {
+		return fmt.Errorf("create runs queue index: %w", err)
 	}
 	return nil
 }
@@ -1,6 +1,7 @@
 package state
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -25,6 +26,16 @@ func TestStoreSettingsRoundTrip(t *testing.T) {
 	if got != "cursor" {
 		t.Fatalf("setting mismatch: got=%q want=%q", got, "cursor")
 	}
+
+	if err := store.DeleteSetting("default_tool"); err != nil {
+		t.Fatalf("delete setting failed: %v", err)
+	}
+	if _, found, err := store.GetSetting("default_tool"); err != nil || found {
+		t.Fatalf("expected setting to be deleted: found=%v err=%v", found, err)
+	}
+	if err := store.DeleteSetting("default_tool"); err != nil {
+		t.Fatalf("deleting a missing setting should succeed: %v", err)
+	}
 }
 
 func TestStoreGitHubTokenEncryptedAndOverwrite(t *testing.T) {
@@ -238,6 +249,33 @@ func TestGetRepoByName(t *testing.T) {
 	}
 }
 
+func TestNewStoreConfiguresEveryConnection(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	// Hold several connections at once so the pool has to open new ones
+	// after init has run its own PRAGMA statements.
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		conn, err := store.db.Conn(ctx)
+		if err != nil {
+			t.Fatalf("conn %d failed: %v", i, err)
+		}
+		defer func() { _ = conn.Close() }()
+
+		var foreignKeys, busyTimeout int
+		if err := conn.QueryRowContext(ctx, `PRAGMA foreign_keys`).Scan(&foreignKeys); err != nil {
+			t.Fatalf("read foreign_keys on conn %d failed: %v", i, err)
+		}
+		if err := conn.QueryRowContext(ctx, `PRAGMA busy_timeout`).Scan(&busyTimeout); err != nil {
+			t.Fatalf("read busy_timeout on conn %d failed: %v", i, err)
+		}
+		if foreignKeys != 1 || busyTimeout != 5000 {
+			t.Fatalf("conn %d: foreign_keys=%d busy_timeout=%d", i, foreignKeys, busyTimeout)
+		}
+	}
+}
+
 func newTestStore(t *testing.T) *Store {
 	t.Helper()
 	store, err := NewStore(t.TempDir())
@@ -0,0 +1,206 @@
+package state
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	templateNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+	// templateVarPattern matches {{name}} and {{name|default}}.
+	templateVarPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*(?:\|([^}]*))?\}\}`)
+)
+
+// PromptTemplate is a named, reusable prompt with {{var}} placeholders.
+type PromptTemplate struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Body        string    `json:"body"`
+	Vars        []string  `json:"vars"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TemplateVars returns the variable names used in body, in order of first
+// use.
+func TemplateVars(body string) []string {
+	vars := []string{}
+	seen := map[string]bool{}
+	for _, m := range templateVarPattern.FindAllStringSubmatch(body, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			vars = append(vars, m[1])
+		}
+	}
+	return vars
+}
+
+// Render substitutes vars into the template body. Placeholders without a
+// value fall back to their default ({{name|default}}); any still missing are
+// reported together, as are values for variables the template does not use.
+func (t PromptTemplate) Render(vars map[string]string) (string, error) {
+	used := map[string]bool{}
+	var missing []string
+	out := templateVarPattern.ReplaceAllStringFunc(t.Body, func(match string) string {
+		m := templateVarPattern.FindStringSubmatch(match)
+		name := m[1]
+		used[name] = true
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		if strings.Contains(match, "|") {
+			return strings.TrimSpace(m[2])
+		}
+		if !slices.Contains(missing, name) {
+			missing = append(missing, name)
+		}
+		return match
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("template %q: missing value for %s", t.Name, strings.Join(missing, ", "))
+	}
+	var unknown []string
+	for name := range vars {
+		if !used[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return "", fmt.Errorf("template %q: unknown variable %s", t.Name, strings.Join(unknown, ", "))
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// SaveTemplate creates or replaces a prompt template.
+func (s *Store) SaveTemplate(name, description, body string) (PromptTemplate, error) {
+	name = strings.TrimSpace(name)
+	description = strings.TrimSpace(description)
+	body = strings.TrimSpace(body)
+	if name == "" {
+		return PromptTemplate{}, errors.New("template name cannot be empty")
+	}
+	if !templateNamePattern.MatchString(name) {
+		return PromptTemplate{}, fmt.Errorf("invalid template name %q: use lowercase letters, digits, '.', '_' or '-'", name)
+	}
+	if body == "" {
+		return PromptTemplate{}, errors.New("template body cannot be empty")
+	}
+	now := nowRFC3339Nano()
+	_, err := s.db.Exec(
+		`INSERT INTO prompt_templates(name, description, body, created_at, updated_at) VALUES(?, ?, ?, ?, ?)
+		 ON CONFLICT(name) DO UPDATE SET description=excluded.description, body=excluded.body, updated_at=excluded.updated_at`,
+		name,
+		nullIfEmpty(description),
+		body,
+		now,
+		now,
+	)
+	if err != nil {
+		return PromptTemplate{}, fmt.Errorf("save template %q: %w", name, err)
+	}
+	tmpl, _, err := s.GetTemplate(name)
+	return tmpl, err
+}
+
+// GetTemplate returns one prompt template by name.
+func (s *Store) GetTemplate(name string) (PromptTemplate, bool, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return PromptTemplate{}, false, errors.New("template name cannot be empty")
+	}
+	row := s.db.QueryRow(
+		`SELECT name, description, body, created_at, updated_at FROM prompt_templates WHERE name = ?`,
+		name,
+	)
+	tmpl, err := scanTemplate(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return PromptTemplate{}, false, nil
+	}
+	if err != nil {
+		return PromptTemplate{}, false, fmt.Errorf("get template %q: %w", name, err)
+	}
+	return tmpl, true, nil
+}
+
+// ListTemplates returns all prompt templates sorted by name.
+func (s *Store) ListTemplates() ([]PromptTemplate, error) {
+	rows, err := s.db.Query(
+		`SELECT name, description, body, created_at, updated_at FROM prompt_templates ORDER BY name`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list templates: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	templates := []PromptTemplate{}
+	for rows.Next() {
+		tmpl, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan template: %w", err)
+		}
+		templates = append(templates, tmpl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate templates: %w", err)
+	}
+	return templates, nil
+}
+
+// DeleteTemplate removes a prompt template. Runs rendered from it keep
+// their prompt and template name.
+func (s *Store) DeleteTemplate(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("template name cannot be empty")
+	}
+	res, err := s.db.Exec(`DELETE FROM prompt_templates WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("delete template %q: %w", name, err)
+	}
+	return ensureRowsAffected(res, "template "+name)
+}
+
+// RenderTemplate loads a template and renders it with vars. A non-empty
+// extra prompt is appended as additional instructions.
+func (s *Store) RenderTemplate(name string, vars map[string]string, extra string) (string, error) {
+	tmpl, found, err := s.GetTemplate(name)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("template %q not found", strings.TrimSpace(name))
+	}
+	prompt, err := tmpl.Render(vars)
+	if err != nil {
+		return "", err
+	}
+	if extra = strings.TrimSpace(extra); extra != "" {
+		prompt += "\n\n" + extra
+	}
+	return prompt, nil
+}
+
+func scanTemplate(row rowScanner) (PromptTemplate, error) {
+	var tmpl PromptTemplate
+	var description sql.NullString
+	var createdAt, updatedAt string
+	if err := row.Scan(&tmpl.Name, &description, &tmpl.Body, &createdAt, &updatedAt); err != nil {
+		return PromptTemplate{}, err
+	}
+	tmpl.Description = description.String
+	tmpl.Vars = TemplateVars(tmpl.Body)
+	if ts, err := time.Parse(time.RFC3339Nano, createdAt); err == nil {
+		tmpl.CreatedAt = ts
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, updatedAt); err == nil {
+		tmpl.UpdatedAt = ts
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,93 @@
+package state
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplateCRUD(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	tmpl, err := store.SaveTemplate("bump-dep", "Bump a dependency", "Bump {{dep}} to {{version|latest}} and fix breakage.")
+	if err != nil {
+		t.Fatalf("save template failed: %v", err)
+	}
+	if len(tmpl.Vars) != 2 || tmpl.Vars[0] != "dep" || tmpl.Vars[1] != "version" {
+		t.Fatalf("unexpected vars: %v", tmpl.Vars)
+	}
+	if _, err := store.SaveTemplate("add-tests", "", "Add tests for {{pkg}}."); err != nil {
+		t.Fatalf("save second template failed: %v", err)
+	}
+	if _, err := store.SaveTemplate("bump-dep", "Bump a dependency", "Upgrade {{dep}}."); err != nil {
+		t.Fatalf("replace template failed: %v", err)
+	}
+
+	list, err := store.ListTemplates()
+	if err != nil {
+		t.Fatalf("list templates failed: %v", err)
+	}
+	if len(list) != 2 || list[0].Name != "add-tests" || list[1].Body != "Upgrade {{dep}}." {
+		t.Fatalf("unexpected templates: %+v", list)
+	}
+
+	if err := store.DeleteTemplate("add-tests"); err != nil {
+		t.Fatalf("delete template failed: %v", err)
+	}
+	if err := store.DeleteTemplate("add-tests"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if _, found, err := store.GetTemplate("add-tests"); err != nil || found {
+		t.Fatalf("expected template gone: found=%v err=%v", found, err)
+	}
+
+	for _, name := range []string{"", "Has Space", "-lead"} {
+		if _, err := store.SaveTemplate(name, "", "body"); err == nil {
+			t.Fatalf("expected error for name %q", name)
+		}
+	}
+}
+
+func TestTemplateRender(t *testing.T) {
+	tmpl := PromptTemplate{Name: "bump", Body: "Bump {{ dep }} to {{version|latest}}. Keep {{dep}} pinned."}
+
+	got, err := tmpl.Render(map[string]string{"dep": "cobra"})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if got != "Bump cobra to latest. Keep cobra pinned." {
+		t.Fatalf("unexpected render: %q", got)
+	}
+
+	if _, err := tmpl.Render(nil); err == nil || !strings.Contains(err.Error(), "missing value for dep") {
+		t.Fatalf("expected missing var error, got %v", err)
+	}
+	if _, err := tmpl.Render(map[string]string{"dep": "x", "pkg": "y"}); err == nil || !strings.Contains(err.Error(), "unknown variable pkg") {
+		t.Fatalf("expected unknown var error, got %v", err)
+	}
+}
+
+func TestRenderTemplateAppendsExtraPrompt(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	if _, err := store.SaveTemplate("add-tests", "", "Add tests for {{pkg}}."); err != nil {
+		t.Fatalf("save template failed: %v", err)
+	}
+	got, err := store.RenderTemplate("add-tests", map[string]string{"pkg": "internal/api"}, "Use table tests.")
+	if err != nil {
+		t.Fatalf("render template failed: %v", err)
+	}
+	if got != "Add tests for internal/api.\n\nUse table tests." {
+		t.Fatalf("unexpected prompt: %q", got)
+	}
+	if _, err := store.RenderTemplate("missing", nil, ""); err == nil {
+		t.Fatal("expected error for unknown template")
+	}
+}
@@ -0,0 +1,109 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RunUsage is the token, cost and model accounting recorded for one run.
+type RunUsage struct {
+	Model               string  `json:"model,omitempty"`
+	InputTokens         int64   `json:"input_tokens"`
+	OutputTokens        int64   `json:"output_tokens"`
+	CacheReadTokens     int64   `json:"cache_read_tokens"`
+	CacheCreationTokens int64   `json:"cache_creation_tokens"`
+	CostUSD             float64 `json:"cost_usd"`
+	DurationMS          int64   `json:"duration_ms"`
+	NumTurns            int     `json:"num_turns"`
+}
+
+// UsageSummary aggregates recorded run usage for one repo and tool.
+type UsageSummary struct {
+	RepoName            string  `json:"repo_name"`
+	Tool                string  `json:"tool"`
+	Runs                int     `json:"runs"`
+	InputTokens         int64   `json:"input_tokens"`
+	OutputTokens        int64   `json:"output_tokens"`
+	CacheReadTokens     int64   `json:"cache_read_tokens"`
+	CacheCreationTokens int64   `json:"cache_creation_tokens"`
+	CostUSD             float64 `json:"cost_usd"`
+	DurationMS          int64   `json:"duration_ms"`
+	NumTurns            int     `json:"num_turns"`
+}
+
+// SetRunUsage stores the usage reported for a run, replacing any previous value.
+func (s *Store) SetRunUsage(id string, usage RunUsage) error {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return errors.New("run id cannot be empty")
+	}
+
+	res, err := s.db.Exec(
+		`UPDATE runs
+		    SET model = ?, input_tokens = ?, output_tokens = ?, cache_read_tokens = ?,
+		        cache_creation_tokens = ?, cost_usd = ?, duration_ms = ?, num_turns = ?, updated_at = ?
+		  WHERE id = ?`,
+		nullIfEmpty(strings.TrimSpace(usage.Model)),
+		usage.InputTokens,
+		usage.OutputTokens,
+		usage.CacheReadTokens,
+		usage.CacheCreationTokens,
+		usage.CostUSD,
+		usage.DurationMS,
+		usage.NumTurns,
+		nowRFC3339Nano(),
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("set run usage %q: %w", id, err)
+	}
+	if err := ensureRowsAffected(res, "run "+id); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ListUsageSummaries returns recorded usage grouped by repo and tool, most
+// expensive first. Runs without recorded usage are not counted.
+func (s *Store) ListUsageSummaries() ([]UsageSummary, error) {
+	rows, err := s.db.Query(
+		`SELECT s.repo_name, s.tool, COUNT(r.id),
+		        COALESCE(SUM(r.input_tokens), 0), COALESCE(SUM(r.output_tokens), 0),
+		        COALESCE(SUM(r.cache_read_tokens), 0), COALESCE(SUM(r.cache_creation_tokens), 0),
+		        COALESCE(SUM(r.cost_usd), 0), COALESCE(SUM(r.duration_ms), 0), COALESCE(SUM(r.num_turns), 0)
+		   FROM runs r
+		   JOIN sessions s ON s.id = r.session_id
+		  WHERE r.input_tokens IS NOT NULL
+		  GROUP BY s.repo_name, s.tool
+		  ORDER BY SUM(r.cost_usd) DESC, s.repo_name ASC, s.tool ASC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list usage summaries: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := make([]UsageSummary, 0)
+	for rows.Next() {
+		var summary UsageSummary
+		if err := rows.Scan(
+			&summary.RepoName,
+			&summary.Tool,
+			&summary.Runs,
+			&summary.InputTokens,
+			&summary.OutputTokens,
+			&summary.CacheReadTokens,
+			&summary.CacheCreationTokens,
+			&summary.CostUSD,
+			&summary.DurationMS,
+			&summary.NumTurns,
+		); err != nil {
+			return nil, fmt.Errorf("scan usage summary: %w", err)
+		}
+		summaries = append(summaries, summary)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate usage summaries: %w", err)
+	}
+	return summaries, nil
+}
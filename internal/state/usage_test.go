@@ -0,0 +1,63 @@
+package state
+
+import "testing"
+
+func TestRunUsageRecordingAndSummaries(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedQueueSession(t, store)
+
+	for _, id := range []string{"run-1", "run-2", "run-3"} {
+		if err := store.CreateRun(Run{
+			ID:           id,
+			SessionID:    "sess-1",
+			Prompt:       "prompt " + id,
+			WorktreePath: "/tmp/wt",
+			State:        "COMPLETED",
+		}); err != nil {
+			t.Fatalf("create run %s failed: %v", id, err)
+		}
+	}
+
+	run, _, err := store.GetRun("run-1")
+	if err != nil {
+		t.Fatalf("get run failed: %v", err)
+	}
+	if run.Usage != nil {
+		t.Fatalf("expected no usage before recording, got %+v", run.Usage)
+	}
+
+	first := RunUsage{Model: "claude-sonnet-4-5", InputTokens: 100, OutputTokens: 20, CacheReadTokens: 900, CostUSD: 0.5, DurationMS: 1500, NumTurns: 2}
+	if err := store.SetRunUsage("run-1", first); err != nil {
+		t.Fatalf("set run usage failed: %v", err)
+	}
+	if err := store.SetRunUsage("run-2", RunUsage{InputTokens: 50, OutputTokens: 10, CostUSD: 0.25, DurationMS: 500, NumTurns: 1}); err != nil {
+		t.Fatalf("set second run usage failed: %v", err)
+	}
+	if err := store.SetRunUsage("missing", first); err == nil {
+		t.Fatal("expected usage for unknown run to fail")
+	}
+
+	run, _, err = store.GetRun("run-1")
+	if err != nil {
+		t.Fatalf("get run failed: %v", err)
+	}
+	if run.Usage == nil || *run.Usage != first {
+		t.Fatalf("unexpected recorded usage: %+v", run.Usage)
+	}
+
+	summaries, err := store.ListUsageSummaries()
+	if err != nil {
+		t.Fatalf("list usage summaries failed: %v", err)
+	}
+	if len(summaries) != 1 {
+		t.Fatalf("unexpected summaries: %+v", summaries)
+	}
+	got := summaries[0]
+	if got.RepoName != "acme/api" || got.Tool != "claude" || got.Runs != 2 {
+		t.Fatalf("unexpected summary grouping: %+v", got)
+	}
+	if got.InputTokens != 150 || got.OutputTokens != 30 || got.CostUSD != 0.75 || got.DurationMS != 2000 || got.NumTurns != 3 {
+		t.Fatalf("unexpected summary totals: %+v", got)
+	}
+}
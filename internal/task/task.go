@@ -11,6 +11,7 @@ import (
 type State string
 
 const (
+	StateQueued     State = "QUEUED"
 	StateCreated    State = "CREATED"
 	StateSetup      State = "SETUP"
 	StateAIRunning  State = "AI_RUNNING"
@@ -20,6 +21,11 @@ const (
 	StateCompleted  State = "COMPLETED"
 	StateFailed     State = "FAILED"
 	StateCancelled  State = "CANCELLED"
+	// StateInterrupted marks work lost when fogd stopped mid-run.
+	StateInterrupted State = "INTERRUPTED"
+	// StateTimedOut and StateBudgetExceeded mark runs killed by a limit.
+	StateTimedOut       State = "TIMED_OUT"
+	StateBudgetExceeded State = "BUDGET_EXCEEDED"
 )
 
 // Task represents an AI coding task
@@ -63,15 +69,19 @@ type StateTransition struct {
 // CanTransitionTo checks if a state transition is valid
 func (s State) CanTransitionTo(next State) bool {
 	validTransitions := map[State][]State{
-		StateCreated:    {StateSetup, StateFailed},
-		StateSetup:      {StateAIRunning, StateFailed, StateCancelled},
-		StateAIRunning:  {StateValidating, StateCommitted, StateFailed, StateCancelled},
-		StateValidating: {StateCommitted, StateFailed, StateCancelled},
-		StateCommitted:  {StatePRCreated, StateCompleted, StateFailed, StateCancelled},
-		StatePRCreated:  {StateCompleted, StateFailed, StateCancelled},
-		StateCompleted:  {},
-		StateFailed:     {},
-		StateCancelled:  {},
+		StateQueued:         {StateCreated, StateCancelled},
+		StateCreated:        {StateSetup, StateFailed, StateInterrupted, StateCancelled},
+		StateSetup:          {StateAIRunning, StateFailed, StateCancelled, StateInterrupted, StateTimedOut},
+		StateAIRunning:      {StateValidating, StateCommitted, StateFailed, StateCancelled, StateInterrupted, StateTimedOut, StateBudgetExceeded},
+		StateValidating:     {StateCommitted, StateFailed, StateCancelled, StateInterrupted, StateTimedOut},
+		StateCommitted:      {StatePRCreated, StateCompleted, StateFailed, StateCancelled, StateInterrupted, StateTimedOut},
+		StatePRCreated:      {StateCompleted, StateFailed, StateCancelled, StateInterrupted, StateTimedOut},
+		StateCompleted:      {},
+		StateFailed:         {},
+		StateCancelled:      {},
+		StateInterrupted:    {},
+		StateTimedOut:       {},
+		StateBudgetExceeded: {},
 	}
 
 	allowed, ok := validTransitions[s]
@@ -91,7 +101,7 @@ func (t *Task) TransitionTo(newState State) error {
 	t.State = newState
 	t.UpdatedAt = time.Now()
 
-	if newState == StateCompleted || newState == StateFailed || newState == StateCancelled {
+	if newState.IsTerminal() {
 		now := time.Now()
 		t.CompletedAt = &now
 	}
@@ -124,7 +134,17 @@ func FromJSON(data []byte) (*Task, error) {
 
 // IsTerminal returns true if the task is in a terminal state
 func (t *Task) IsTerminal() bool {
-	return t.State == StateCompleted || t.State == StateFailed || t.State == StateCancelled
+	return t.State.IsTerminal()
+}
+
+// IsTerminal reports whether no further transitions are possible.
+func (s State) IsTerminal() bool {
+	switch s {
+	case StateCompleted, StateFailed, StateCancelled, StateInterrupted, StateTimedOut, StateBudgetExceeded:
+		return true
+	default:
+		return false
+	}
 }
 
 // Duration returns the task execution duration